@@ -15,3 +15,21 @@ type Account interface {
 	// NewClusterService create the appropriate ClusterService for the account
 	NewClusterService() common.ClusterService
 }
+
+// NewUncachedAccount wraps an account so that its transient data, such as the auth token,
+// is neither read from nor saved to the cache, e.g. for a one-off command with credentials specified by flags
+func NewUncachedAccount(account Account) Account {
+	return &uncachedAccount{Account: account}
+}
+
+type uncachedAccount struct {
+	Account
+}
+
+// BuildCache returns nil, so that nothing is cached for the account
+func (account *uncachedAccount) BuildCache() map[string]string {
+	return nil
+}
+
+// ApplyCache ignores the cached data, so that a previously cached token is not used
+func (account *uncachedAccount) ApplyCache(c map[string]string) {}
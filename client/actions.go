@@ -0,0 +1,54 @@
+package client
+
+import (
+	"github.com/getcarina/carina/common"
+)
+
+// ListClusterActions returns the backend-specific actions which can be invoked on the account's clusters
+func (client *Client) ListClusterActions(account Account) ([]common.ClusterAction, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.ListActions(), nil
+}
+
+// ParseActionParams converts a set of key=value pairs into a map of action parameters
+func ParseActionParams(values []string) (map[string]string, error) {
+	return parseKeyValuePairs(values, "parameter")
+}
+
+// InvokeClusterAction validates the parameters, then invokes a backend-specific action on a cluster
+func (client *Client) InvokeClusterAction(account Account, name string, action string, params map[string]string) (common.Cluster, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	definition, err := common.LookupClusterAction(svc.ListActions(), action)
+	if err != nil {
+		return nil, err
+	}
+	err = definition.ValidateParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	defer client.endOperation(client.startOperation(name, "Invoke %s on cluster (%s)", action, name))
+
+	unlock, err := client.lockCluster(account, name, action)
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+	defer unlock()
+
+	cluster, err := svc.InvokeAction(name, action, params)
+	if err == nil {
+		client.recordClusterStatus(account, action, cluster)
+	}
+
+	return cluster, wrapClusterError(name, err)
+}
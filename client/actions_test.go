@@ -0,0 +1,37 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvokeClusterAction(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	service.Actions = []common.ClusterAction{
+		{Name: "reboot-node", Params: []common.ClusterActionParam{{Name: "node", Required: true}}},
+	}
+	account := &historyAccount{offlineAccount{service: service}}
+	client := &Client{Cache: &Cache{}}
+
+	_, err := service.CreateCluster("prod", "Kubernetes 1.5.2 on LXC", 1)
+	require.NoError(t, err)
+
+	_, err = client.InvokeClusterAction(account, "prod", "rotate-ca", nil)
+	assert.IsType(t, common.NotFoundError{}, err)
+
+	_, err = client.InvokeClusterAction(account, "prod", "reboot-node", nil)
+	assert.Error(t, err, "The required parameter should be validated")
+
+	_, err = client.InvokeClusterAction(account, "prod", "reboot-node", map[string]string{"node": "1", "force": "true"})
+	assert.Error(t, err, "Unknown parameters should be rejected")
+	assert.Empty(t, service.Invocations)
+
+	cluster, err := client.InvokeClusterAction(account, "prod", "reboot-node", map[string]string{"node": "1"})
+	require.NoError(t, err)
+	assert.Equal(t, "prod", cluster.GetName())
+	assert.Equal(t, []testsupport.FakeInvocation{{ClusterID: cluster.GetID(), Action: "reboot-node", Params: map[string]string{"node": "1"}}}, service.Invocations)
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"github.com/getcarina/carina/common"
+)
+
+// AdoptClusterOptions are the settings applied to a cluster when it is adopted
+type AdoptClusterOptions struct {
+	// Labels are added to the cluster, replacing the value of existing labels
+	Labels map[string]string
+
+	// CredentialsPath is where the credentials are saved, defaulting to CARINA_HOME
+	CredentialsPath string
+}
+
+// AdoptCluster registers a cluster which was created outside of the cli, such as in the control panel,
+// so that it is managed the same as the clusters created by the cli: its credentials are downloaded,
+// its status is recorded in the history, the labels are saved and its name is completed in the shell.
+func (client *Client) AdoptCluster(account Account, token string, options AdoptClusterOptions) (cluster common.Cluster, credentialsPath string, err error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer client.endOperation(client.startOperation(token, "Adopt cluster (%s)", token))
+	defer func() {
+		client.audit(account, "adopt", token, nil, cluster, err)
+	}()
+
+	cluster, err = svc.GetCluster(token)
+	if err != nil {
+		return nil, "", wrapClusterError(token, err)
+	}
+	name := cluster.GetName()
+	client.recordClusterStatus(account, "adopt", cluster)
+
+	// The age of a cluster created outside of the cli is only known when the API reports it
+	if _, ok := client.Cache.getClusterCreated(account, cluster.GetID()); !ok && !cluster.GetCreated().IsZero() {
+		client.Cache.saveClusterCreated(account, cluster.GetID(), cluster.GetCreated())
+	}
+
+	if len(options.Labels) > 0 {
+		err = client.Cache.SaveClusterLabels(account, cluster.GetID(), func(labels map[string]string) {
+			for key, value := range options.Labels {
+				labels[key] = value
+			}
+		})
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if names, ok := client.Cache.getClusterNames(account); ok && !containsClusterName(names, name) {
+		client.Cache.saveClusterNames(account, append(names, name))
+	}
+
+	credentialsPath, err = client.downloadClusterCredentials(svc, account, name, options.CredentialsPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return client.applyLabels(account, cluster), credentialsPath, nil
+}
+
+func containsClusterName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
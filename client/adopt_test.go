@@ -0,0 +1,47 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdoptCluster(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-adopt")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	os.Setenv(CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(CarinaHomeDirEnvVar)
+
+	client := &Client{Cache: newCache(filepath.Join(home, "cache.json"))}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+
+	created := time.Now().Add(-48 * time.Hour)
+	existing := service.AddCluster(testsupport.FakeCluster{Name: "web", Created: created})
+
+	cluster, credentialsPath, err := client.AdoptCluster(account, existing.GetID(), AdoptClusterOptions{Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	assert.Equal(t, "web", cluster.GetName())
+	assert.Equal(t, map[string]string{"env": "prod"}, cluster.GetLabels())
+
+	_, err = os.Stat(filepath.Join(credentialsPath, "ca.pem"))
+	assert.NoError(t, err, "the credentials should be downloaded")
+
+	events, err := client.GetClusterHistory(account, "web")
+	require.NoError(t, err)
+	assert.Equal(t, "adopt", events[0].Source)
+
+	age, ok := client.GetClusterAge(account, cluster)
+	assert.True(t, ok)
+	assert.True(t, age >= 48*time.Hour)
+
+	_, _, err = client.AdoptCluster(account, "missing", AdoptClusterOptions{})
+	assert.Error(t, err)
+}
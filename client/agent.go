@@ -0,0 +1,142 @@
+package client
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// agentServiceName is the name of the systemd unit which runs the agent
+const agentServiceName = "carina-agent.service"
+
+// agentServiceLabel is the label of the launchd job which runs the agent
+const agentServiceLabel = "com.getcarina.carina.agent"
+
+// AgentService describes how the operating system's service manager runs the agent in the background
+type AgentService struct {
+	// Executable is the absolute path to the carina binary
+	Executable string
+
+	// Args are the arguments passed to carina, e.g. credentials refresh --agent --profile work
+	Args []string
+
+	// Environment are the environment variables set for the agent, such as CARINA_HOME
+	Environment map[string]string
+}
+
+// AgentServiceManager installs the agent as a user-level service, so that it starts on login and is restarted when it exits
+type AgentServiceManager interface {
+	// Install writes the service definition and starts the service, returning the path to the definition
+	Install(service AgentService) (string, error)
+
+	// Uninstall stops the service and removes its definition, returning the path which was removed
+	Uninstall() (string, error)
+}
+
+// NewAgentServiceManager returns the service manager for the current operating system: systemd on Linux, and launchd on macOS
+func NewAgentServiceManager() AgentServiceManager {
+	return newSystemAgentServiceManager()
+}
+
+// ErrAgentNotInstalled is returned when uninstalling the agent, and it isn't installed
+var ErrAgentNotInstalled = errors.New("The agent is not installed")
+
+// runServiceCommand runs a service manager command, such as systemctl or launchctl, including its output in the error
+func runServiceCommand(name string, args ...string) error {
+	output, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "%s %s failed: %s", name, strings.Join(args, " "), strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// sortedEnvironment returns the names of the environment variables in a stable order, so that the generated files don't change between runs
+func (service AgentService) sortedEnvironment() []string {
+	names := make([]string, 0, len(service.Environment))
+	for name := range service.Environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// quoteSystemdArg quotes an argument in a systemd ExecStart line, when it contains whitespace or quotes
+func quoteSystemdArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\%$") {
+		return arg
+	}
+
+	arg = strings.Replace(arg, `\`, `\\`, -1)
+	arg = strings.Replace(arg, `"`, `\"`, -1)
+	// systemd expands specifiers and variables, which are escaped by doubling them
+	arg = strings.Replace(arg, "%", "%%", -1)
+	arg = strings.Replace(arg, "$", "$$", -1)
+	return `"` + arg + `"`
+}
+
+// buildSystemdUnit generates a systemd user unit which runs the agent, restarting it if it exits
+func buildSystemdUnit(service AgentService) string {
+	command := []string{quoteSystemdArg(service.Executable)}
+	for _, arg := range service.Args {
+		command = append(command, quoteSystemdArg(arg))
+	}
+
+	var unit bytes.Buffer
+	fmt.Fprintln(&unit, "[Unit]")
+	fmt.Fprintln(&unit, "Description=Carina agent, refreshes cluster credentials before they expire")
+	fmt.Fprintln(&unit, "After=network-online.target")
+	fmt.Fprintln(&unit)
+	fmt.Fprintln(&unit, "[Service]")
+	fmt.Fprintf(&unit, "ExecStart=%s\n", strings.Join(command, " "))
+	for _, name := range service.sortedEnvironment() {
+		fmt.Fprintf(&unit, "Environment=%s\n", quoteSystemdArg(name+"="+service.Environment[name]))
+	}
+	fmt.Fprintln(&unit, "Restart=on-failure")
+	fmt.Fprintln(&unit, "RestartSec=60")
+	fmt.Fprintln(&unit)
+	fmt.Fprintln(&unit, "[Install]")
+	fmt.Fprintln(&unit, "WantedBy=default.target")
+	return unit.String()
+}
+
+// escapeXML escapes a value for use in the text of an XML element
+func escapeXML(value string) string {
+	var escaped bytes.Buffer
+	xml.EscapeText(&escaped, []byte(value))
+	return escaped.String()
+}
+
+// buildLaunchdPlist generates a launchd property list which runs the agent on login, restarting it if it exits
+func buildLaunchdPlist(service AgentService, logPath string) string {
+	var plist bytes.Buffer
+	fmt.Fprintln(&plist, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(&plist, `<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">`)
+	fmt.Fprintln(&plist, `<plist version="1.0">`)
+	fmt.Fprintln(&plist, `<dict>`)
+	fmt.Fprintf(&plist, "\t<key>Label</key>\n\t<string>%s</string>\n", agentServiceLabel)
+	fmt.Fprintln(&plist, "\t<key>ProgramArguments</key>")
+	fmt.Fprintln(&plist, "\t<array>")
+	for _, arg := range append([]string{service.Executable}, service.Args...) {
+		fmt.Fprintf(&plist, "\t\t<string>%s</string>\n", escapeXML(arg))
+	}
+	fmt.Fprintln(&plist, "\t</array>")
+	if len(service.Environment) > 0 {
+		fmt.Fprintln(&plist, "\t<key>EnvironmentVariables</key>")
+		fmt.Fprintln(&plist, "\t<dict>")
+		for _, name := range service.sortedEnvironment() {
+			fmt.Fprintf(&plist, "\t\t<key>%s</key>\n\t\t<string>%s</string>\n", escapeXML(name), escapeXML(service.Environment[name]))
+		}
+		fmt.Fprintln(&plist, "\t</dict>")
+	}
+	fmt.Fprintln(&plist, "\t<key>RunAtLoad</key>\n\t<true/>")
+	fmt.Fprintln(&plist, "\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>")
+	fmt.Fprintf(&plist, "\t<key>StandardErrorPath</key>\n\t<string>%s</string>\n", escapeXML(logPath))
+	fmt.Fprintln(&plist, `</dict>`)
+	fmt.Fprintln(&plist, `</plist>`)
+	return plist.String()
+}
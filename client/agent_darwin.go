@@ -0,0 +1,82 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// launchdAgentServiceManager runs the agent as a launchd user agent
+type launchdAgentServiceManager struct{}
+
+func newSystemAgentServiceManager() AgentServiceManager {
+	return launchdAgentServiceManager{}
+}
+
+// getPlistPath returns where the property list is saved, e.g. ~/Library/LaunchAgents/com.getcarina.carina.agent.plist
+func (launchdAgentServiceManager) getPlistPath() (string, error) {
+	homeDir, err := userHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, "Library", "LaunchAgents", agentServiceLabel+".plist"), nil
+}
+
+// Install writes the property list, and loads it so that the agent starts now and on each login
+func (manager launchdAgentServiceManager) Install(service AgentService) (string, error) {
+	plistPath, err := manager.getPlistPath()
+	if err != nil {
+		return "", err
+	}
+
+	// Unload a previous install, so that the new definition is used
+	if _, err = os.Stat(plistPath); err == nil {
+		err = runServiceCommand("launchctl", "unload", plistPath)
+		if err != nil {
+			common.Log.WriteDebug("Unable to unload the previous agent: %s", err)
+		}
+	}
+
+	homeDir, err := userHomeDir()
+	if err != nil {
+		return "", err
+	}
+	logPath := filepath.Join(homeDir, "Library", "Logs", "carina-agent.log")
+
+	err = os.MkdirAll(filepath.Dir(plistPath), 0755)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to create the LaunchAgents directory")
+	}
+	err = ioutil.WriteFile(plistPath, []byte(buildLaunchdPlist(service, logPath)), 0644)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to write the launchd property list")
+	}
+	common.Log.WriteDebug("Wrote the launchd property list to %s", plistPath)
+
+	return plistPath, runServiceCommand("launchctl", "load", "-w", plistPath)
+}
+
+// Uninstall unloads the agent, and removes its property list
+func (manager launchdAgentServiceManager) Uninstall() (string, error) {
+	plistPath, err := manager.getPlistPath()
+	if err != nil {
+		return "", err
+	}
+	if _, err = os.Stat(plistPath); os.IsNotExist(err) {
+		return plistPath, ErrAgentNotInstalled
+	}
+
+	err = runServiceCommand("launchctl", "unload", "-w", plistPath)
+	if err != nil {
+		common.Log.WriteWarning("Unable to stop the agent: %s", err)
+	}
+
+	err = os.Remove(plistPath)
+	if err != nil {
+		return plistPath, errors.Wrap(err, "Unable to remove the launchd property list")
+	}
+	return plistPath, nil
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// systemdAgentServiceManager runs the agent as a systemd user service
+type systemdAgentServiceManager struct{}
+
+func newSystemAgentServiceManager() AgentServiceManager {
+	return systemdAgentServiceManager{}
+}
+
+// getUnitPath returns where the unit file is saved, e.g. ~/.config/systemd/user/carina-agent.service
+func (systemdAgentServiceManager) getUnitPath() (string, error) {
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		homeDir, err := userHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configDir = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configDir, "systemd", "user", agentServiceName), nil
+}
+
+// Install writes the unit file, then enables and (re)starts the service
+func (manager systemdAgentServiceManager) Install(service AgentService) (string, error) {
+	unitPath, err := manager.getUnitPath()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(filepath.Dir(unitPath), 0755)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to create the systemd user unit directory")
+	}
+	err = ioutil.WriteFile(unitPath, []byte(buildSystemdUnit(service)), 0644)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to write the systemd unit")
+	}
+	common.Log.WriteDebug("Wrote the systemd unit to %s", unitPath)
+
+	err = runServiceCommand("systemctl", "--user", "daemon-reload")
+	if err != nil {
+		return unitPath, err
+	}
+	err = runServiceCommand("systemctl", "--user", "enable", agentServiceName)
+	if err != nil {
+		return unitPath, err
+	}
+	// Restart, instead of start, so that reinstalling picks up the new unit
+	return unitPath, runServiceCommand("systemctl", "--user", "restart", agentServiceName)
+}
+
+// Uninstall stops and disables the service, then removes the unit file
+func (manager systemdAgentServiceManager) Uninstall() (string, error) {
+	unitPath, err := manager.getUnitPath()
+	if err != nil {
+		return "", err
+	}
+	if _, err = os.Stat(unitPath); os.IsNotExist(err) {
+		return unitPath, ErrAgentNotInstalled
+	}
+
+	err = runServiceCommand("systemctl", "--user", "disable", "--now", agentServiceName)
+	if err != nil {
+		common.Log.WriteWarning("Unable to stop the agent: %s", err)
+	}
+
+	err = os.Remove(unitPath)
+	if err != nil {
+		return unitPath, errors.Wrap(err, "Unable to remove the systemd unit")
+	}
+	return unitPath, runServiceCommand("systemctl", "--user", "daemon-reload")
+}
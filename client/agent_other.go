@@ -0,0 +1,26 @@
+// +build !darwin,!linux
+
+package client
+
+import (
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// unsupportedAgentServiceManager is used on platforms without a supported service manager
+type unsupportedAgentServiceManager struct{}
+
+func newSystemAgentServiceManager() AgentServiceManager {
+	return unsupportedAgentServiceManager{}
+}
+
+// Install always fails, because installing the agent is not supported
+func (unsupportedAgentServiceManager) Install(service AgentService) (string, error) {
+	return "", errors.Errorf("Installing the agent is not supported on %s, run carina credentials refresh --agent from a scheduled task instead", runtime.GOOS)
+}
+
+// Uninstall always fails, because installing the agent is not supported
+func (unsupportedAgentServiceManager) Uninstall() (string, error) {
+	return "", errors.Errorf("Installing the agent is not supported on %s", runtime.GOOS)
+}
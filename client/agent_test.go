@@ -0,0 +1,52 @@
+package client
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSystemdUnit(t *testing.T) {
+	service := AgentService{
+		Executable:  "/usr/local/bin/carina",
+		Args:        []string{"credentials", "refresh", "--agent", "--config", "/home/alice/my config.toml", "--profile", "work"},
+		Environment: map[string]string{"CARINA_HOME": "/home/alice/.carina"},
+	}
+
+	unit := buildSystemdUnit(service)
+	assert.Contains(t, unit, `ExecStart=/usr/local/bin/carina credentials refresh --agent --config "/home/alice/my config.toml" --profile work`+"\n")
+	assert.Contains(t, unit, "Environment=CARINA_HOME=/home/alice/.carina\n")
+	assert.Contains(t, unit, "WantedBy=default.target")
+}
+
+func TestQuoteSystemdArg(t *testing.T) {
+	assert.Equal(t, "--agent", quoteSystemdArg("--agent"))
+	assert.Equal(t, `""`, quoteSystemdArg(""))
+	assert.Equal(t, `"100%% \"done\""`, quoteSystemdArg(`100% "done"`))
+	assert.Equal(t, `"$$HOME"`, quoteSystemdArg("$HOME"))
+}
+
+func TestBuildLaunchdPlist(t *testing.T) {
+	service := AgentService{
+		Executable:  "/usr/local/bin/carina",
+		Args:        []string{"credentials", "refresh", "--agent", "--profile", "R&D"},
+		Environment: map[string]string{"CARINA_HOME": "/Users/alice/.carina"},
+	}
+
+	plist := buildLaunchdPlist(service, "/Users/alice/Library/Logs/carina-agent.log")
+	assert.Contains(t, plist, "<string>R&amp;D</string>")
+	assert.Contains(t, plist, "<key>CARINA_HOME</key>\n\t\t<string>/Users/alice/.carina</string>")
+
+	// The property list must be well-formed, so that launchctl accepts it
+	decoder := xml.NewDecoder(strings.NewReader(plist))
+	decoder.Strict = true
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+}
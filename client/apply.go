@@ -0,0 +1,452 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ClusterManifest describes the desired clusters on an account, used by carina apply
+type ClusterManifest struct {
+	Clusters []ClusterSpec `yaml:"clusters" json:"clusters"`
+}
+
+// ClusterSpec describes a desired cluster
+type ClusterSpec struct {
+	Name string `yaml:"name" json:"name"`
+
+	// Template is the name or pattern of the template used to create the cluster. It cannot be changed once the cluster exists.
+	Template string `yaml:"template" json:"template"`
+
+	// Nodes is the desired number of nodes, defaulting to 1
+	Nodes int `yaml:"nodes" json:"nodes"`
+
+	// Labels replace the cluster's labels when specified. When omitted, the cluster's labels are left alone.
+	Labels map[string]string `yaml:"labels" json:"labels"`
+}
+
+// ClusterChangeAction is a change made by carina apply to reconcile a cluster with its spec
+type ClusterChangeAction string
+
+const (
+	// ChangeCreate creates a cluster which is in the manifest but doesn't exist
+	ChangeCreate ClusterChangeAction = "create"
+
+	// ChangeResize resizes a cluster whose number of nodes doesn't match its spec
+	ChangeResize ClusterChangeAction = "resize"
+
+	// ChangeLabel replaces the labels on a cluster whose labels don't match its spec.
+	// Labels are only saved locally, in the cache, because the cluster services don't store them.
+	ChangeLabel ClusterChangeAction = "label"
+
+	// ChangeDelete deletes a cluster which is not in the manifest, when pruning
+	ChangeDelete ClusterChangeAction = "delete"
+)
+
+// ClusterChange is a single change to a cluster
+type ClusterChange struct {
+	Action   ClusterChangeAction
+	Name     string
+	Template string
+	Nodes    int
+	Labels   map[string]string
+
+	// CurrentNodes is the number of nodes in the cluster before it is resized
+	CurrentNodes int
+}
+
+// ApplyOptions controls how a manifest is reconciled against the account's clusters
+type ApplyOptions struct {
+	// Prune deletes clusters which are not in the manifest
+	Prune bool
+
+	// AllowDeprecated permits creating a cluster from a deprecated template, otherwise an error is returned
+	AllowDeprecated bool
+
+	// WaitUntilActive waits for each change to finish before returning
+	WaitUntilActive bool
+}
+
+// ApplyPlan is the set of changes needed to make the account's clusters match a manifest
+type ApplyPlan struct {
+	Changes  []ClusterChange
+	Warnings []string
+
+	hasLocalLabelsWarning bool
+}
+
+// localLabelsWarning explains that applying labels only changes this machine's cache, see ChangeLabel
+const localLabelsWarning = "Labels are local metadata, saved in the carina cache on this machine instead of with the clusters. " +
+	"Applying the manifest from another machine, or after the cache is cleared, labels the clusters again"
+
+// HasChanges returns if the clusters already match the manifest
+func (plan *ApplyPlan) HasChanges() bool {
+	return len(plan.Changes) > 0
+}
+
+// OperationPlan describes the changes, so that they can be previewed before they are applied
+func (plan *ApplyPlan) OperationPlan() *OperationPlan {
+	preview := &OperationPlan{Operation: "apply", Warnings: plan.Warnings}
+	for _, change := range plan.Changes {
+		preview.Steps = append(preview.Steps, change.String())
+	}
+	return preview
+}
+
+// String describes the change, e.g. "+ create cluster (web) with 3 nodes from the template Kubernetes 1.5.2 on LXC"
+func (change ClusterChange) String() string {
+	switch change.Action {
+	case ChangeCreate:
+		s := fmt.Sprintf("+ create cluster (%s) with %d nodes from the template %s", change.Name, change.Nodes, change.Template)
+		if len(change.Labels) > 0 {
+			s += fmt.Sprintf(", labeled %s", formatKeyValuePairs(change.Labels))
+		}
+		return s
+	case ChangeResize:
+		return fmt.Sprintf("~ resize cluster (%s) from %d to %d nodes", change.Name, change.CurrentNodes, change.Nodes)
+	case ChangeLabel:
+		if len(change.Labels) == 0 {
+			return fmt.Sprintf("~ remove the labels from cluster (%s) (local only)", change.Name)
+		}
+		return fmt.Sprintf("~ label cluster (%s) with %s (local only)", change.Name, formatKeyValuePairs(change.Labels))
+	case ChangeDelete:
+		return fmt.Sprintf("- delete cluster (%s)", change.Name)
+	default:
+		return fmt.Sprintf("? %s cluster (%s)", change.Action, change.Name)
+	}
+}
+
+// GetClusterSpec reads the template, number of nodes and labels of an existing cluster, e.g. to create a copy of it
+func (client *Client) GetClusterSpec(account Account, name string) (ClusterSpec, error) {
+	cluster, err := client.GetCluster(account, name, false)
+	if err != nil {
+		return ClusterSpec{}, err
+	}
+
+	template := cluster.GetTemplate()
+	if template == nil || template.GetName() == "" {
+		return ClusterSpec{}, fmt.Errorf("Unable to copy cluster (%s), its template is unknown", name)
+	}
+
+	// Clusters which are still being created may not report their size yet
+	nodes, err := common.ParseNodeCount(cluster.GetNodes())
+	if err != nil || nodes < 1 {
+		return ClusterSpec{}, fmt.Errorf("Unable to copy cluster (%s), its number of nodes is unknown. Try again once the cluster is active", name)
+	}
+
+	labels := make(map[string]string, len(cluster.GetLabels()))
+	for key, value := range cluster.GetLabels() {
+		labels[key] = value
+	}
+
+	return ClusterSpec{Name: cluster.GetName(), Template: template.GetName(), Nodes: nodes, Labels: labels}, nil
+}
+
+// ReadClusterManifest reads a YAML or JSON cluster manifest from a file
+func ReadClusterManifest(path string) (ClusterManifest, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ClusterManifest{}, errors.Wrapf(err, "Unable to read the manifest %s", path)
+	}
+
+	manifest, err := ParseClusterManifest(contents)
+	return manifest, errors.Wrapf(err, "Invalid manifest %s", path)
+}
+
+// ParseClusterManifest parses and validates a YAML or JSON cluster manifest
+func ParseClusterManifest(contents []byte) (ClusterManifest, error) {
+	var manifest ClusterManifest
+
+	// JSON is valid YAML, so one parser handles both formats
+	err := yaml.Unmarshal(contents, &manifest)
+	if err != nil {
+		return manifest, err
+	}
+
+	names := make(map[string]bool, len(manifest.Clusters))
+	for i := range manifest.Clusters {
+		spec := &manifest.Clusters[i]
+		if spec.Name == "" {
+			return manifest, fmt.Errorf("Cluster %d is missing a name", i+1)
+		}
+		if names[spec.Name] {
+			return manifest, fmt.Errorf("The cluster %s is specified more than once", spec.Name)
+		}
+		names[spec.Name] = true
+
+		if spec.Nodes == 0 {
+			spec.Nodes = 1
+		}
+		if spec.Nodes < 0 {
+			return manifest, fmt.Errorf("The cluster %s must have >= 1 nodes", spec.Name)
+		}
+	}
+
+	return manifest, nil
+}
+
+// PlanApply compares the manifest with the account's clusters, and returns the changes needed to make them match
+func (client *Client) PlanApply(account Account, manifest ClusterManifest, options ApplyOptions) (*ApplyPlan, error) {
+	clusters, err := client.ListClusters(account, ListClustersOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+	quotas, err := lookupQuotas(svc)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]common.Cluster, len(clusters))
+	for _, cluster := range clusters {
+		name := cluster.GetName()
+		if _, ok := existing[name]; ok {
+			return nil, fmt.Errorf("Unable to plan the changes, there is more than one cluster named %s. Rename or delete the duplicate clusters, then try again", name)
+		}
+		existing[name] = cluster
+	}
+
+	plan := &ApplyPlan{}
+	clusterCount := len(clusters)
+	for _, spec := range manifest.Clusters {
+		err = checkNodesQuota(quotas, spec.Nodes)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to apply the cluster %s", spec.Name)
+		}
+
+		cluster, ok := existing[spec.Name]
+		if !ok {
+			change, err := client.planCreate(account, spec, options, plan)
+			if err != nil {
+				return nil, err
+			}
+			plan.Changes = append(plan.Changes, change)
+			clusterCount++
+			continue
+		}
+
+		plan.Changes = append(plan.Changes, planUpdate(cluster, spec, plan)...)
+	}
+
+	if options.Prune {
+		var names []string
+		for name := range existing {
+			if !manifestContains(manifest, name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			plan.Changes = append(plan.Changes, ClusterChange{Action: ChangeDelete, Name: name})
+			clusterCount--
+		}
+	}
+
+	err = checkClusterQuota(quotas, clusterCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func (client *Client) planCreate(account Account, spec ClusterSpec, options ApplyOptions, plan *ApplyPlan) (ClusterChange, error) {
+	if spec.Template == "" {
+		return ClusterChange{}, fmt.Errorf("The cluster %s doesn't exist and must specify a template to be created", spec.Name)
+	}
+
+	template, err := client.GetClusterTemplate(account, spec.Template)
+	if err != nil {
+		return ClusterChange{}, errors.Wrapf(err, "Unable to apply the cluster %s", spec.Name)
+	}
+
+	if template.IsDeprecated() {
+		if !options.AllowDeprecated {
+			return ClusterChange{}, common.DeprecatedTemplateError{TemplateName: template.GetName()}
+		}
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("The cluster %s uses the deprecated template %s", spec.Name, template.GetName()))
+	}
+	if availability := template.GetAvailability(); availability != nil && availability.Constrained {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("The cluster %s uses the template %s, which is %s", spec.Name, template.GetName(), availability))
+	}
+
+	return ClusterChange{
+		Action:   ChangeCreate,
+		Name:     spec.Name,
+		Template: template.GetName(),
+		Nodes:    spec.Nodes,
+		Labels:   spec.Labels,
+	}, nil
+}
+
+func planUpdate(cluster common.Cluster, spec ClusterSpec, plan *ApplyPlan) []ClusterChange {
+	var changes []ClusterChange
+
+	if spec.Template != "" {
+		matcher, err := common.NameMatchPolicy.NewMatcher(spec.Template)
+		if err == nil && !matcher.Matches(cluster.GetTemplate().GetName()) {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("The cluster %s uses the template %s instead of %s. The template of an existing cluster cannot be changed, delete the cluster to recreate it",
+				spec.Name, cluster.GetTemplate().GetName(), spec.Template))
+		}
+	}
+
+	nodes, err := common.ParseNodeCount(cluster.GetNodes())
+	if err != nil {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("Skipping resizing the cluster %s, its number of nodes is unknown", spec.Name))
+	} else if nodes != spec.Nodes {
+		changes = append(changes, ClusterChange{Action: ChangeResize, Name: spec.Name, Nodes: spec.Nodes, CurrentNodes: nodes})
+	}
+
+	if spec.Labels != nil && !labelsEqual(cluster.GetLabels(), spec.Labels) {
+		changes = append(changes, ClusterChange{Action: ChangeLabel, Name: spec.Name, Labels: spec.Labels})
+		if !plan.hasLocalLabelsWarning {
+			plan.hasLocalLabelsWarning = true
+			plan.Warnings = append(plan.Warnings, localLabelsWarning)
+		}
+	}
+
+	return changes
+}
+
+func labelsEqual(a map[string]string, b map[string]string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func manifestContains(manifest ClusterManifest, name string) bool {
+	for _, spec := range manifest.Clusters {
+		if spec.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply makes the changes in a plan. Deletes are made first, and when the plan also creates clusters
+// the deletes are waited on, to free up quota for the new clusters. Then each remaining cluster is changed concurrently.
+// Returns the result of each change, in the order of the plan.
+func (client *Client) Apply(account Account, plan *ApplyPlan, options ApplyOptions) ([]ClusterOperationResult, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ClusterOperationResult, len(plan.Changes))
+
+	var deletes, updates []int
+	waitForDeletes := options.WaitUntilActive
+	for i, change := range plan.Changes {
+		switch change.Action {
+		case ChangeDelete:
+			deletes = append(deletes, i)
+		case ChangeCreate:
+			waitForDeletes = true
+			updates = append(updates, i)
+		default:
+			updates = append(updates, i)
+		}
+	}
+
+	// Changes to the same cluster are made in order, e.g. resize and then label
+	var batches [][]int
+	for _, i := range deletes {
+		batches = append(batches, []int{i})
+	}
+	client.applyBatches(batches, func(i int) error {
+		return client.deleteCluster(svc, account, plan.Changes[i].Name, plan.Changes[i].Name, waitForDeletes)
+	}, plan, results)
+
+	batches = nil
+	batchByName := make(map[string]int)
+	for _, i := range updates {
+		name := plan.Changes[i].Name
+		if b, ok := batchByName[name]; ok {
+			batches[b] = append(batches[b], i)
+			continue
+		}
+		batchByName[name] = len(batches)
+		batches = append(batches, []int{i})
+	}
+	client.applyBatches(batches, func(i int) error {
+		return client.applyChange(account, plan.Changes[i], options)
+	}, plan, results)
+
+	return results, nil
+}
+
+// applyBatches makes each batch of changes concurrently, and the changes within a batch one at a time.
+// A failed change skips the rest of its batch, and every remaining change when the batch policy fails fast.
+func (client *Client) applyBatches(batches [][]int, apply func(i int) error, plan *ApplyPlan, results []ClusterOperationResult) {
+	limit := make(chan struct{}, maxConcurrentOperations)
+	var run batchRun
+	var wg sync.WaitGroup
+	for _, batch := range batches {
+		wg.Add(1)
+		go func(batch []int) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			var err error
+			for _, i := range batch {
+				change := plan.Changes[i]
+				result := ClusterOperationResult{Name: change.Name, Message: change.String()}
+				switch {
+				case err != nil:
+					result.Err = errors.Wrapf(err, "Skipped %s", change.Action)
+				case run.shouldSkip():
+					result.Err = ErrSkipped
+				default:
+					err = apply(i)
+					run.recordResult(err)
+					result.Err = err
+				}
+				results[i] = result
+			}
+		}(batch)
+	}
+	wg.Wait()
+}
+
+func (client *Client) applyChange(account Account, change ClusterChange, options ApplyOptions) error {
+	var err error
+	switch change.Action {
+	case ChangeCreate:
+		_, err = client.CreateCluster(account, change.Name, change.Template, change.Nodes, CreateClusterOptions{
+			AllowDeprecated: options.AllowDeprecated,
+			Labels:          change.Labels,
+			WaitUntilActive: options.WaitUntilActive,
+		})
+	case ChangeResize:
+		_, err = client.ResizeCluster(account, change.Name, change.Nodes, options.WaitUntilActive)
+	case ChangeLabel:
+		var cluster common.Cluster
+		cluster, err = client.GetCluster(account, change.Name, false)
+		if err == nil {
+			err = client.Cache.SaveClusterLabels(account, cluster.GetID(), func(labels map[string]string) {
+				for key := range labels {
+					delete(labels, key)
+				}
+				for key, value := range change.Labels {
+					labels[key] = value
+				}
+			})
+		}
+	default:
+		err = fmt.Errorf("Unsupported change: %s", change.Action)
+	}
+	return err
+}
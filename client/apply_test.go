@@ -0,0 +1,156 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseClusterManifest(t *testing.T) {
+	manifest, err := client.ParseClusterManifest([]byte(`
+clusters:
+  - name: web
+    template: Swarm*
+    nodes: 3
+    labels:
+      env: prod
+  - name: api
+`))
+	assert.Nil(t, err)
+	assert.Len(t, manifest.Clusters, 2)
+	assert.Equal(t, map[string]string{"env": "prod"}, manifest.Clusters[0].Labels)
+	assert.Equal(t, 1, manifest.Clusters[1].Nodes, "nodes should default to 1")
+	assert.Nil(t, manifest.Clusters[1].Labels)
+
+	manifest, err = client.ParseClusterManifest([]byte(`{"clusters": [{"name": "web", "template": "Swarm*", "nodes": 2}]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, manifest.Clusters[0].Nodes)
+
+	_, err = client.ParseClusterManifest([]byte("clusters:\n  - name: web\n  - name: web\n"))
+	assert.NotNil(t, err, "duplicate cluster names should be rejected")
+
+	_, err = client.ParseClusterManifest([]byte("clusters:\n  - nodes: 2\n"))
+	assert.NotNil(t, err, "a cluster name is required")
+}
+
+func TestApplyManifest(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("api", "Swarm*", 1)
+	service.CreateCluster("old", "Swarm*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	manifest := client.ClusterManifest{Clusters: []client.ClusterSpec{
+		{Name: "web", Template: "Swarm*", Nodes: 2},
+		{Name: "api", Nodes: 3},
+	}}
+
+	plan, err := c.PlanApply(account, manifest, client.ApplyOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []client.ClusterChange{
+		{Action: client.ChangeCreate, Name: "web", Template: "Swarm 1.11.2 on LXC", Nodes: 2},
+		{Action: client.ChangeResize, Name: "api", Nodes: 3, CurrentNodes: 1},
+	}, plan.Changes)
+
+	plan, err = c.PlanApply(account, manifest, client.ApplyOptions{Prune: true})
+	assert.Nil(t, err)
+	assert.Len(t, plan.Changes, 3)
+	assert.Equal(t, client.ClusterChange{Action: client.ChangeDelete, Name: "old"}, plan.Changes[2])
+
+	results, err := c.Apply(account, plan, client.ApplyOptions{})
+	assert.Nil(t, err)
+	for _, result := range results {
+		assert.Nil(t, result.Err, result.Name)
+	}
+
+	web, err := service.GetCluster("web")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", web.GetNodes())
+	_, err = service.GetCluster("old")
+	assert.NotNil(t, err, "the delete should finish before the new cluster is created")
+
+	// Labels are reconciled against the local cache
+	manifest.Clusters[0].Labels = map[string]string{"env": "prod"}
+	plan, err = c.PlanApply(account, manifest, client.ApplyOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, plan.Changes, client.ClusterChange{Action: client.ChangeLabel, Name: "web", Labels: map[string]string{"env": "prod"}})
+	assert.Contains(t, plan.OperationPlan().Steps, "~ label cluster (web) with env=prod (local only)")
+	if assert.Len(t, plan.Warnings, 1) {
+		assert.Contains(t, plan.Warnings[0], "Labels are local metadata")
+	}
+}
+
+func TestApplyDeleteOnlyDoesNotWait(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("old", "Swarm*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	plan, err := c.PlanApply(account, client.ClusterManifest{}, client.ApplyOptions{Prune: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []client.ClusterChange{{Action: client.ChangeDelete, Name: "old"}}, plan.Changes)
+
+	_, err = c.Apply(account, plan, client.ApplyOptions{})
+	assert.Nil(t, err)
+	old, err := service.GetCluster("old")
+	assert.Nil(t, err)
+	assert.Equal(t, testsupport.StatusDeleting, old.GetStatus(), "without creates, deletes are not waited on")
+}
+
+func TestPlanApplyRejectsDuplicateClusterNames(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("web", "Swarm*", 1)
+	service.CreateCluster("web", "Swarm*", 2)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	_, err := c.PlanApply(account, client.ClusterManifest{Clusters: []client.ClusterSpec{{Name: "web", Nodes: 3}}}, client.ApplyOptions{})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "more than one cluster named web")
+	}
+}
+
+func TestPlanApplyRequiresTemplateForNewClusters(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	_, err := c.PlanApply(account, client.ClusterManifest{Clusters: []client.ClusterSpec{{Name: "web", Nodes: 1}}}, client.ApplyOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestPlanApplyWithUnsupportedQuotas(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(quotalessService{service})
+
+	c := client.NewClient(false)
+	plan, err := c.PlanApply(account, client.ClusterManifest{Clusters: []client.ClusterSpec{{Name: "web", Template: "Swarm*", Nodes: 20}}}, client.ApplyOptions{})
+	assert.Nil(t, err, "Unknown quotas should not be checked")
+	assert.Len(t, plan.Changes, 1)
+}
+
+func TestGetClusterSpec(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	_, err := c.CreateCluster(account, "prod", "Swarm*", 3, client.CreateClusterOptions{})
+	assert.Nil(t, err)
+
+	spec, err := c.GetClusterSpec(account, "prod")
+	assert.Nil(t, err)
+	assert.Equal(t, "Swarm 1.11.2 on LXC", spec.Template)
+	assert.Equal(t, 3, spec.Nodes)
+
+	_, err = c.GetClusterSpec(account, "missing")
+	assert.NotNil(t, err)
+}
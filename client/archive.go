@@ -0,0 +1,207 @@
+package client
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// ExportClusterCredentials writes a cluster's credentials to a zip archive, which can be imported on another machine.
+// The files are stored in a directory named after the cluster, so that the archive can be imported without specifying the cluster name.
+func (client *Client) ExportClusterCredentials(account Account, name string, customPath string, output string) (archivePath string, err error) {
+	if output == "" {
+		output = name + ".zip"
+	}
+
+	// Write to a temporary file first, so that a failed export doesn't replace an existing archive
+	f, err := ioutil.TempFile(filepath.Dir(output), filepath.Base(output)+".tmp")
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to create the credentials archive")
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	err = client.WriteClusterCredentialsArchive(account, name, customPath, f)
+	closeErr := f.Close()
+	if err != nil {
+		return "", err
+	}
+	if closeErr != nil {
+		return "", errors.Wrap(closeErr, "Unable to write to the credentials archive")
+	}
+
+	err = os.Rename(tmpPath, output)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to create the credentials archive")
+	}
+
+	return filepath.Abs(output)
+}
+
+// WriteClusterCredentialsArchive writes a cluster's credentials as a zip archive, downloading them first if necessary
+func (client *Client) WriteClusterCredentialsArchive(account Account, name string, customPath string, w io.Writer) error {
+	credentialsPath, err := client.ensureClusterCredentials(account, name, customPath)
+	if err != nil {
+		return err
+	}
+
+	files, err := client.readCredentialsFiles(credentialsPath)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to read the credentials for %s", name)
+	}
+
+	archive := zip.NewWriter(w)
+	for file, contents := range files {
+		entry, err := archive.Create(path.Join(name, file))
+		if err != nil {
+			return errors.Wrap(err, "Unable to write to the credentials archive")
+		}
+		_, err = entry.Write(contents)
+		if err != nil {
+			return errors.Wrap(err, "Unable to write to the credentials archive")
+		}
+	}
+
+	return errors.Wrap(archive.Close(), "Unable to write to the credentials archive")
+}
+
+// ImportClusterCredentials extracts a credentials archive created by ExportClusterCredentials.
+// The certificates are validated before the credentials are saved, replacing any credentials already on disk for the cluster.
+func (client *Client) ImportClusterCredentials(account Account, archivePath string, customPath string) (name string, credentialsPath string, err error) {
+	name, files, err := readCredentialsArchive(archivePath)
+	if err != nil {
+		return "", "", err
+	}
+
+	credentialsPath, err = buildClusterCredentialsPath(account, name, customPath)
+	if err != nil {
+		return "", "", errors.Wrap(err, "Unable to save the imported cluster credentials")
+	}
+
+	err = client.saveCredentialsFiles(credentialsPath, files, customPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = client.recordCredentialsFingerprint(account, name, files)
+	if err != nil {
+		common.Log.WriteStructuredWarning(common.WarningCredentialsChanged, "%s", err)
+	}
+
+	return name, credentialsPath, nil
+}
+
+// saveCredentialsFiles validates the certificates from a credentials archive, then saves the files,
+// replacing any credentials already on disk for the cluster
+func (client *Client) saveCredentialsFiles(credentialsPath string, files map[string][]byte, customPath string) error {
+	for _, file := range []string{caCertFilename, clientCertFilename} {
+		err := validateCertificateExpiry(file, files[file], time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	err := os.MkdirAll(credentialsPath, 0777)
+	if err != nil {
+		return err
+	}
+
+	for file, contents := range files {
+		err = client.writeSecureFile(filepath.Join(credentialsPath, file), contents, customPath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readCredentialsArchive reads the files from a credentials archive, returning the cluster name and the contents of each file
+func readCredentialsArchive(archivePath string) (name string, files map[string][]byte, err error) {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Unable to open the credentials archive")
+	}
+	defer archive.Close()
+
+	return readCredentialsArchiveEntries(archive.File)
+}
+
+// isArchivePathComponent returns if a directory or file name from a credentials archive is safe to use as a single path component,
+// rejecting names such as .., C:ca.pem or ..\ca.pem which could escape the credentials directory on Windows
+func isArchivePathComponent(p string) bool {
+	if p == "" || p == "." || p == ".." {
+		return false
+	}
+	if strings.ContainsAny(p, `\:`) || strings.ContainsRune(p, os.PathSeparator) {
+		return false
+	}
+	return filepath.Base(p) == p
+}
+
+// readCredentialsArchiveEntries reads the files from the entries of a credentials archive, returning the cluster name and the contents of each file
+func readCredentialsArchiveEntries(entries []*zip.File) (name string, files map[string][]byte, err error) {
+	files = make(map[string][]byte)
+	for _, entry := range entries {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		// Only accept files directly inside the cluster directory, e.g. mycluster/ca.pem
+		parts := strings.Split(entry.Name, "/")
+		if len(parts) != 2 || !isArchivePathComponent(parts[0]) || !isArchivePathComponent(parts[1]) {
+			return "", nil, fmt.Errorf("Invalid credentials archive, unexpected file %s", entry.Name)
+		}
+		if name == "" {
+			name = parts[0]
+		} else if name != parts[0] {
+			return "", nil, fmt.Errorf("Invalid credentials archive, it contains credentials for both %s and %s", name, parts[0])
+		}
+
+		r, err := entry.Open()
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "Unable to read %s from the credentials archive", entry.Name)
+		}
+		contents, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "Unable to read %s from the credentials archive", entry.Name)
+		}
+		files[parts[1]] = contents
+	}
+
+	if name == "" {
+		return "", nil, errors.New("Invalid credentials archive, it is empty")
+	}
+
+	return name, files, nil
+}
+
+// validateCertificateExpiry checks that a PEM encoded certificate is present and has not expired
+func validateCertificateExpiry(file string, contents []byte, now time.Time) error {
+	if contents == nil {
+		return fmt.Errorf("Invalid credentials archive, %s is missing", file)
+	}
+
+	cert, err := parseCertificate(contents)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid credentials archive, unable to parse %s", file)
+	}
+
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("The certificate %s expired on %s. Download fresh credentials with carina credentials", file, cert.NotAfter.Format(time.RFC822))
+	}
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("The certificate %s is not valid until %s", file, cert.NotBefore.Format(time.RFC822))
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"archive/zip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func buildSignedCertificate(t *testing.T, notAfter time.Time) []byte {
+	return buildCertificateValidFrom(t, notAfter.Add(-24*time.Hour), notAfter)
+}
+
+func buildCertificateValidFrom(t *testing.T, notBefore time.Time, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func writeTestArchive(t *testing.T, files map[string][]byte) string {
+	filename := fmt.Sprintf("carina-temp-archive-%s.zip", randomName())
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	archive := zip.NewWriter(f)
+	for name, contents := range files {
+		w, _ := archive.Create(name)
+		w.Write(contents)
+	}
+	archive.Close()
+	return filename
+}
+
+func TestReadCredentialsArchive(t *testing.T) {
+	filename := writeTestArchive(t, map[string][]byte{
+		"mycluster/ca.pem":   []byte("ca"),
+		"mycluster/cert.pem": []byte("cert"),
+	})
+	defer os.Remove(filename)
+
+	name, files, err := readCredentialsArchive(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, "mycluster", name)
+	assert.Equal(t, []byte("ca"), files["ca.pem"])
+	assert.Equal(t, []byte("cert"), files["cert.pem"])
+}
+
+func TestReadCredentialsArchiveRejectsPathsOutsideTheClusterDirectory(t *testing.T) {
+	entries := []string{
+		"mycluster/../../ca.pem",
+		`mycluster/..\..\ca.pem`,
+		"mycluster/C:ca.pem",
+		"C:/ca.pem",
+		`..\mycluster/ca.pem`,
+	}
+	for _, entry := range entries {
+		filename := writeTestArchive(t, map[string][]byte{entry: []byte("ca")})
+		_, _, err := readCredentialsArchive(filename)
+		os.Remove(filename)
+		assert.NotNil(t, err, entry)
+	}
+}
+
+func TestValidateCertificateExpiry(t *testing.T) {
+	now := time.Now()
+
+	err := validateCertificateExpiry("cert.pem", buildSignedCertificate(t, now.Add(time.Hour)), now)
+	assert.Nil(t, err)
+
+	err = validateCertificateExpiry("cert.pem", buildSignedCertificate(t, now.Add(-time.Hour)), now)
+	assert.NotNil(t, err)
+
+	err = validateCertificateExpiry("cert.pem", nil, now)
+	assert.NotNil(t, err)
+}
+
+func TestExportClusterCredentialsKeepsTheExistingArchiveOnFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+	client := &Client{Cache: &Cache{}}
+
+	output := filepath.Join(dir, "prod.zip")
+	require.NoError(t, ioutil.WriteFile(output, []byte("previous export"), 0600))
+
+	_, err = client.ExportClusterCredentials(account, "prod", filepath.Join(dir, "credentials"), output)
+	assert.Error(t, err, "The cluster doesn't exist")
+
+	contents, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "previous export", string(contents))
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "prod.zip.tmp*"))
+	assert.Empty(t, leftovers, "The temporary archive should be removed")
+}
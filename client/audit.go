@@ -0,0 +1,190 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// auditLogFilename is the name of the audit log in CARINA_HOME
+const auditLogFilename = "audit.log"
+
+const (
+	// AuditSuccess is the result of an operation which succeeded
+	AuditSuccess = "success"
+
+	// AuditFailure is the result of an operation which failed
+	AuditFailure = "failure"
+)
+
+// AuditEntry records a change made to a cluster by this client, who made it and from where
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Account   string    `json:"account"`
+	Host      string    `json:"host"`
+	User      string    `json:"user"`
+	Operation string    `json:"operation"`
+	Cluster   string    `json:"cluster"`
+	ClusterID string    `json:"cluster-id,omitempty"`
+
+	// Args are the arguments of the operation, e.g. the template and number of nodes of a new cluster
+	Args map[string]string `json:"args,omitempty"`
+
+	// Result is success or failure, and Error explains why the operation failed
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// AuditFilter selects which entries are returned from the audit log
+type AuditFilter struct {
+	// Cluster matches the cluster name or id, using common.NameMatchPolicy
+	Cluster string
+
+	// Operation is the operation, e.g. delete
+	Operation string
+
+	// Account is the id of the account, e.g. a username
+	Account string
+
+	// Since skips the entries recorded before this time
+	Since time.Time
+
+	// Failed only returns the operations which failed
+	Failed bool
+}
+
+// audit appends a mutating operation to the audit log. Auditing never fails the operation, a warning is printed instead.
+func (client *Client) audit(account Account, operation string, name string, args map[string]string, cluster common.Cluster, err error) {
+	if client.AuditLog == "" {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:      time.Now().UTC(),
+		Account:   account.GetID(),
+		Host:      auditHost(),
+		User:      auditUser(),
+		Operation: operation,
+		Cluster:   name,
+		Args:      args,
+		Result:    AuditSuccess,
+	}
+	if cluster != nil {
+		entry.ClusterID = cluster.GetID()
+	}
+	if err != nil {
+		entry.Result = AuditFailure
+		entry.Error = common.RedactSecrets(err.Error())
+	}
+
+	writeErr := appendAuditEntry(client.AuditLog, entry)
+	if writeErr != nil {
+		common.Log.WriteWarning("Unable to record the %s of %s in the audit log: %s", operation, name, writeErr)
+	}
+}
+
+// appendAuditEntry writes an entry as a single line at the end of the audit log, creating it if necessary
+func appendAuditEntry(path string, entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// ReadAuditLog returns the entries in the audit log which match the filter, oldest first.
+// Lines which can't be read, e.g. from a write interrupted by a crash, are skipped.
+func (client *Client) ReadAuditLog(filter AuditFilter) ([]AuditEntry, error) {
+	if client.AuditLog == "" {
+		return nil, errors.New("The audit log is disabled when the cache is disabled")
+	}
+
+	var clusterMatcher common.Matcher
+	if filter.Cluster != "" {
+		var err error
+		clusterMatcher, err = common.NameMatchPolicy.NewMatcher(filter.Cluster)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.Open(client.AuditLog)
+	if os.IsNotExist(err) {
+		return []AuditEntry{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read the audit log")
+	}
+	defer f.Close()
+
+	entries := []AuditEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		var entry AuditEntry
+		err := json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			common.Log.WriteDebug("Skipping line %d of the audit log: %s", lineNumber, err)
+			continue
+		}
+
+		if clusterMatcher != nil && !clusterMatcher.Matches(entry.Cluster) && !clusterMatcher.Matches(entry.ClusterID) {
+			continue
+		}
+		if filter.Operation != "" && entry.Operation != filter.Operation {
+			continue
+		}
+		if filter.Account != "" && entry.Account != filter.Account {
+			continue
+		}
+		if !filter.Since.IsZero() && entry.Time.Before(filter.Since) {
+			continue
+		}
+		if filter.Failed && entry.Result != AuditFailure {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, errors.Wrap(scanner.Err(), "Unable to read the audit log")
+}
+
+// auditHost returns the name of this computer
+func auditHost() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return host
+}
+
+// auditUser returns the name of the user running carina
+func auditUser() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	for _, key := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(key); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
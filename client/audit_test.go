@@ -0,0 +1,87 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogRecordsChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	client := &Client{Cache: newCache(filepath.Join(dir, "cache.json")), AuditLog: filepath.Join(dir, auditLogFilename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+
+	cluster, err := client.CreateCluster(account, "prod", "Swarm*", 1, CreateClusterOptions{})
+	require.NoError(t, err)
+	_, err = client.ResizeCluster(account, "prod", 2, false)
+	require.NoError(t, err)
+	err = client.DeleteCluster(account, "missing", false)
+	require.Error(t, err)
+
+	entries, err := client.ReadAuditLog(AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	create := entries[0]
+	assert.Equal(t, "create", create.Operation)
+	assert.Equal(t, "prod", create.Cluster)
+	assert.Equal(t, cluster.GetID(), create.ClusterID)
+	assert.Equal(t, account.GetID(), create.Account)
+	assert.Equal(t, map[string]string{"template": "Swarm*", "nodes": "1"}, create.Args)
+	assert.Equal(t, AuditSuccess, create.Result)
+	assert.NotEmpty(t, create.Host)
+	assert.NotEmpty(t, create.User)
+
+	assert.Equal(t, "resize", entries[1].Operation)
+	assert.Equal(t, "2", entries[1].Args["nodes"])
+
+	assert.Equal(t, "delete", entries[2].Operation)
+	assert.Equal(t, AuditFailure, entries[2].Result)
+	assert.NotEmpty(t, entries[2].Error)
+
+	entries, err = client.ReadAuditLog(AuditFilter{Operation: "delete"})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	entries, err = client.ReadAuditLog(AuditFilter{Cluster: "pro*"})
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	entries, err = client.ReadAuditLog(AuditFilter{Failed: true})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	entries, err = client.ReadAuditLog(AuditFilter{Since: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestReadAuditLogSkipsCorruptLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, auditLogFilename)
+	require.NoError(t, appendAuditEntry(path, AuditEntry{Operation: "delete", Cluster: "prod", Result: AuditSuccess}))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	f.WriteString(`{"operation": "cre`)
+	f.Close()
+
+	client := &Client{AuditLog: path}
+	entries, err := client.ReadAuditLog(AuditFilter{})
+	require.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "prod", entries[0].Cluster)
+	}
+}
@@ -0,0 +1,429 @@
+package client
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// maxConcurrentOperations limits how many clusters are modified at the same time by a batch operation
+const maxConcurrentOperations = 5
+
+// ClusterOperationResult is the outcome of an operation on a single cluster in a batch
+type ClusterOperationResult struct {
+	Name string
+	Err  error
+
+	// Message describes the operation, replacing the batch's success message when set
+	Message string
+}
+
+// BatchPolicy controls what happens to the rest of a batch operation when the operation on one cluster fails
+var BatchPolicy = struct {
+	// FailFast skips the clusters which haven't started yet after the first failure, otherwise every cluster is attempted.
+	// Operations which already started are allowed to finish.
+	FailFast bool
+
+	// Parallel limits how many clusters are modified at the same time, defaults to maxConcurrentOperations
+	Parallel int
+}{}
+
+// newBatchLimit returns a semaphore which bounds how many operations in a batch run at the same time
+func newBatchLimit() chan struct{} {
+	parallel := BatchPolicy.Parallel
+	if parallel < 1 {
+		parallel = maxConcurrentOperations
+	}
+	return make(chan struct{}, parallel)
+}
+
+// ErrSkipped is the result of a cluster which wasn't attempted, because another cluster in the batch failed and the batch fails fast
+var ErrSkipped = errors.New("Skipped because another operation failed")
+
+// batchRun records whether an operation in a batch has failed, so that the remaining operations can be skipped
+type batchRun struct {
+	failed int32
+}
+
+// recordResult notes when an operation in the batch failed
+func (run *batchRun) recordResult(err error) {
+	if err != nil {
+		atomic.StoreInt32(&run.failed, 1)
+	}
+}
+
+// shouldSkip returns if the next operation should be skipped, because an operation failed and the batch fails fast
+func (run *batchRun) shouldSkip() bool {
+	return BatchPolicy.FailFast && atomic.LoadInt32(&run.failed) == 1
+}
+
+// MatchClusters retrieves the clusters whose name matches a pattern, using the name match policy, e.g. test-*
+func (client *Client) MatchClusters(account Account, pattern string) ([]common.Cluster, error) {
+	matcher, err := common.NameMatchPolicy.NewMatcher(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters, err := client.ListClusters(account, ListClustersOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []common.Cluster
+	for _, cluster := range clusters {
+		if matcher.Matches(cluster.GetName()) {
+			matches = append(matches, cluster)
+		}
+	}
+	return matches, nil
+}
+
+// GetClusters retrieves the clusters matching each token, which may be a cluster name, id or pattern, e.g. prod-*.
+// The clusters are returned in the order of the tokens, and a cluster matched by more than one token is only returned once.
+// Every token must match at least one cluster.
+func (client *Client) GetClusters(account Account, tokens []string) ([]common.Cluster, error) {
+	var clusters []common.Cluster
+	seen := make(map[string]bool)
+	for _, token := range tokens {
+		var matches []common.Cluster
+		if common.NameMatchPolicy.IsPattern(token) {
+			var err error
+			matches, err = client.MatchClusters(account, token)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, common.NotFoundError{Err: errors.Errorf("No clusters match %s", token)}
+			}
+		} else {
+			cluster, err := client.GetCluster(account, token, false)
+			if err != nil {
+				return nil, err
+			}
+			matches = []common.Cluster{cluster}
+		}
+
+		for _, cluster := range matches {
+			if !seen[cluster.GetID()] {
+				seen[cluster.GetID()] = true
+				clusters = append(clusters, cluster)
+			}
+		}
+	}
+	return clusters, nil
+}
+
+// ResolveClusterNames expands the tokens, which may be cluster names, ids or patterns, to the names of the matching clusters, see GetClusters
+func (client *Client) ResolveClusterNames(account Account, tokens []string) ([]string, error) {
+	clusters, err := client.GetClusters(account, tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		names[i] = cluster.GetName()
+	}
+	return names, nil
+}
+
+// CreateClusters creates multiple clusters concurrently with the same template and number of nodes,
+// returning the result for each cluster in the order specified. The status of each cluster is reported as it changes.
+// See BatchPolicy for how a failure affects the remaining clusters.
+func (client *Client) CreateClusters(account Account, names []string, template string, nodes int, options CreateClusterOptions) ([]ClusterOperationResult, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authenticate once up front, so that the concurrent operations share the same session
+	_, err = svc.ListClusters()
+	if err != nil {
+		return nil, wrapClientError(err)
+	}
+
+	err = checkTemplate(svc, template, options.AllowDeprecated)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ClusterOperationResult, len(names))
+	limit := newBatchLimit()
+	var run batchRun
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			results[i] = ClusterOperationResult{Name: name}
+			if run.shouldSkip() {
+				results[i].Err = ErrSkipped
+				return
+			}
+
+			// Stream the status transitions of each cluster while waiting, not only the final status
+			progress := common.Progress.Track("Create cluster (%s)", name)
+			clusterOptions := options
+			clusterOptions.progress = progress
+			cluster, err := client.createCluster(svc, account, name, template, nodes, clusterOptions)
+			run.recordResult(err)
+			if err != nil {
+				results[i].Err = err
+				progress.Update("failed")
+				return
+			}
+			results[i].Message = cluster.GetStatus()
+			progress.Update(cluster.GetStatus())
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// DownloadAllClusterCredentials downloads the credentials for every cluster in the account concurrently, returning the result
+// for each cluster sorted by name. See DownloadClustersCredentials.
+func (client *Client) DownloadAllClusterCredentials(account Account, customPath string) ([]ClusterOperationResult, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	// Listing the clusters authenticates up front, so that the concurrent downloads share the same session
+	clusters, err := svc.ListClusters()
+	if err != nil {
+		return nil, wrapClientError(err)
+	}
+
+	names := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		names[i] = cluster.GetName()
+	}
+	sort.Strings(names)
+
+	return client.downloadClustersCredentials(svc, account, names, customPath), nil
+}
+
+// DownloadClustersCredentials downloads the credentials for multiple clusters concurrently, returning the result for each cluster
+// in the order specified, with where its credentials were saved as the message. When customPath is specified, each cluster's
+// credentials are saved in a directory named after the cluster inside it. See BatchPolicy for how a failure affects the remaining clusters.
+func (client *Client) DownloadClustersCredentials(account Account, names []string, customPath string) ([]ClusterOperationResult, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authenticate once up front, so that the concurrent downloads share the same session
+	_, err = svc.ListClusters()
+	if err != nil {
+		return nil, wrapClientError(err)
+	}
+
+	return client.downloadClustersCredentials(svc, account, names, customPath), nil
+}
+
+func (client *Client) downloadClustersCredentials(svc common.ClusterService, account Account, names []string, customPath string) []ClusterOperationResult {
+	writer := client.credentialsWriter()
+	results := make([]ClusterOperationResult, len(names))
+	limit := newBatchLimit()
+	var run batchRun
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			results[i] = ClusterOperationResult{Name: name}
+			if run.shouldSkip() {
+				results[i].Err = ErrSkipped
+				return
+			}
+
+			var clusterPath string
+			if customPath != "" {
+				clusterPath = filepath.Join(customPath, name)
+			}
+
+			progress := common.Progress.Track("Download credentials (%s)", name)
+			location, err := client.writeClusterCredentials(svc, writer, account, name, clusterPath)
+			run.recordResult(err)
+			if err != nil {
+				results[i].Err = err
+				progress.Update("failed")
+				return
+			}
+			results[i].Message = location
+			progress.Update("downloaded")
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// ResizeClusters resizes multiple clusters concurrently to the same number of nodes, returning the result for each cluster
+// in the order specified. See BatchPolicy for how a failure affects the remaining clusters.
+func (client *Client) ResizeClusters(account Account, names []string, nodes int, waitUntilActive bool) ([]ClusterOperationResult, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.checkSupported(svc, common.OperationResize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authenticate once up front, so that the concurrent operations share the same session
+	_, err = svc.ListClusters()
+	if err != nil {
+		return nil, wrapClientError(err)
+	}
+
+	results := make([]ClusterOperationResult, len(names))
+	limit := newBatchLimit()
+	var run batchRun
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			results[i] = ClusterOperationResult{Name: name}
+			if run.shouldSkip() {
+				results[i].Err = ErrSkipped
+				return
+			}
+
+			progress := common.Progress.Track("Resize cluster (%s) to %d nodes", name, nodes)
+			cluster, err := client.resizeCluster(svc, account, name, nodes, waitUntilActive)
+			run.recordResult(err)
+			if err != nil {
+				results[i].Err = err
+				progress.Update("failed")
+				return
+			}
+			results[i].Message = cluster.GetStatus()
+			progress.Update(cluster.GetStatus())
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// DeleteClusters deletes multiple clusters concurrently, returning the result for each cluster in the order specified.
+// See BatchPolicy for how a failure affects the remaining clusters.
+func (client *Client) DeleteClusters(account Account, names []string, waitUntilDeleted bool) ([]ClusterOperationResult, error) {
+	return client.deleteClusters(account, names, names, waitUntilDeleted)
+}
+
+// DeleteMatchedClusters deletes clusters which were already retrieved, e.g. by MatchClusters, returning the result for each cluster in the order specified.
+// The clusters are deleted by id, so that a cluster which was replaced by another with the same name since it was matched isn't deleted.
+func (client *Client) DeleteMatchedClusters(account Account, clusters []common.Cluster, waitUntilDeleted bool) ([]ClusterOperationResult, error) {
+	names := make([]string, len(clusters))
+	ids := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		names[i] = cluster.GetName()
+		ids[i] = cluster.GetID()
+	}
+	return client.deleteClusters(account, names, ids, waitUntilDeleted)
+}
+
+// deleteClusters deletes each cluster by its token, an id or name, concurrently
+func (client *Client) deleteClusters(account Account, names []string, tokens []string, waitUntilDeleted bool) ([]ClusterOperationResult, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authenticate once up front, so that the concurrent operations share the same session
+	_, err = svc.ListClusters()
+	if err != nil {
+		return nil, wrapClientError(err)
+	}
+
+	results := make([]ClusterOperationResult, len(names))
+	limit := newBatchLimit()
+	var run batchRun
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			results[i] = ClusterOperationResult{Name: name}
+			if run.shouldSkip() {
+				results[i].Err = ErrSkipped
+				return
+			}
+
+			results[i].Err = client.deleteCluster(svc, account, name, tokens[i], waitUntilDeleted)
+			run.recordResult(results[i].Err)
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// ProfileAccount is the account read from a named profile
+type ProfileAccount struct {
+	Profile string
+	Account Account
+}
+
+// ProfileClusters is the result of listing the clusters in one of several profiles
+type ProfileClusters struct {
+	Profile  string
+	Clusters []common.Cluster
+	Err      error
+}
+
+// ListClustersInProfiles lists the clusters in several accounts concurrently. onListed is called, one at a time, with
+// each profile's clusters as soon as they are listed, so that the slowest account doesn't hold up the others.
+// Returns the result for each profile in the order specified.
+func (client *Client) ListClustersInProfiles(accounts []ProfileAccount, options ListClustersOptions, onListed func(ProfileClusters)) []ProfileClusters {
+	results := make([]ProfileClusters, len(accounts))
+	limit := make(chan struct{}, maxConcurrentOperations)
+	var listed sync.Mutex
+	var wg sync.WaitGroup
+	for i, account := range accounts {
+		wg.Add(1)
+		go func(i int, account ProfileAccount) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			clusters, err := client.ListClusters(account.Account, options)
+			results[i] = ProfileClusters{Profile: account.Profile, Clusters: clusters, Err: err}
+
+			if onListed != nil {
+				listed.Lock()
+				defer listed.Unlock()
+				onListed(results[i])
+			}
+		}(i, account)
+	}
+	wg.Wait()
+
+	return results
+}
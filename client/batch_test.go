@@ -0,0 +1,151 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDownloadAllClusterCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-download")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("web", "Swarm*", 1)
+	service.CreateCluster("db", "Swarm*", 1)
+	account := &historyAccount{offlineAccount{service: service}}
+
+	results, err := client.DownloadAllClusterCredentials(account, dir)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	// The results are sorted by cluster name
+	assert.Equal(t, "db", results[0].Name)
+	assert.Equal(t, "web", results[1].Name)
+	for _, result := range results {
+		assert.NoError(t, result.Err)
+		assert.Equal(t, filepath.Join(dir, result.Name), result.Message)
+
+		contents, err := ioutil.ReadFile(filepath.Join(dir, result.Name, clientCertFilename))
+		assert.NoError(t, err)
+		assert.Equal(t, "fake-cert", string(contents))
+	}
+}
+
+func TestCreateClustersStreamsStatus(t *testing.T) {
+	var progress bytes.Buffer
+	defer func(out io.Writer) { common.Progress.Out = out }(common.Progress.Out)
+	common.Progress.Out = &progress
+
+	client := &Client{Cache: &Cache{}, DisableLocks: true}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.PendingPolls = 2
+	account := &historyAccount{offlineAccount{service: service}}
+
+	results, err := client.CreateClusters(account, []string{"web", "db"}, "Swarm*", 1, CreateClusterOptions{WaitUntilActive: true})
+	require.NoError(t, err)
+	for _, result := range results {
+		require.NoError(t, result.Err)
+		assert.Equal(t, testsupport.StatusActive, result.Message)
+	}
+
+	// Each status transition is reported while waiting, not only the final status
+	for _, name := range []string{"web", "db"} {
+		assert.Contains(t, progress.String(), fmt.Sprintf("Create cluster (%s): %s", name, testsupport.StatusCreating))
+		assert.Contains(t, progress.String(), fmt.Sprintf("Create cluster (%s): %s", name, testsupport.StatusActive))
+	}
+}
+
+func TestResizeClusters(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("web", "Swarm*", 1)
+	service.CreateCluster("db", "Swarm*", 1)
+	account := &historyAccount{offlineAccount{service: service}}
+
+	results, err := client.ResizeClusters(account, []string{"web", "missing", "db"}, 3, false)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// The results are in the order specified, and a missing cluster doesn't stop the others from being resized
+	assert.Equal(t, "web", results[0].Name)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "missing", results[1].Name)
+	assert.Error(t, results[1].Err)
+	assert.Equal(t, "db", results[2].Name)
+	assert.NoError(t, results[2].Err)
+
+	for _, name := range []string{"web", "db"} {
+		cluster, err := service.GetCluster(name)
+		require.NoError(t, err)
+		assert.Equal(t, "3", cluster.GetNodes())
+	}
+}
+
+func TestListClustersInProfiles(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	devService := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	devService.CreateCluster("dev-web", "Swarm*", 1)
+	prodService := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	prodService.CreateCluster("prod-web", "Swarm*", 1)
+	prodService.CreateCluster("prod-db", "Swarm*", 1)
+	accounts := []ProfileAccount{
+		{Profile: "dev", Account: &historyAccount{offlineAccount{service: devService}}},
+		{Profile: "prod", Account: &historyAccount{offlineAccount{service: prodService}}},
+	}
+
+	var listed []string
+	results := client.ListClustersInProfiles(accounts, ListClustersOptions{Sort: "name"}, func(result ProfileClusters) {
+		listed = append(listed, result.Profile)
+	})
+
+	// Every profile is passed to the callback, and the results are in the order specified
+	assert.ElementsMatch(t, []string{"dev", "prod"}, listed)
+	require.Len(t, results, 2)
+	assert.Equal(t, "dev", results[0].Profile)
+	assert.NoError(t, results[0].Err)
+	assert.Len(t, results[0].Clusters, 1)
+	assert.Equal(t, "prod", results[1].Profile)
+	assert.NoError(t, results[1].Err)
+	require.Len(t, results[1].Clusters, 2)
+	assert.Equal(t, "prod-db", results[1].Clusters[0].GetName())
+}
+
+func TestResolveClusterNames(t *testing.T) {
+	client := &Client{Cache: &Cache{}}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("prod-web", "Swarm*", 1)
+	service.CreateCluster("prod-api", "Swarm*", 1)
+	service.CreateCluster("worker", "Swarm*", 1)
+	account := &historyAccount{offlineAccount{service: service}}
+
+	// A cluster matched by more than one token is only returned once
+	names, err := client.ResolveClusterNames(account, []string{"worker", "prod-*", "prod-web"})
+	require.NoError(t, err)
+	require.Len(t, names, 3)
+	assert.Equal(t, "worker", names[0])
+	assert.ElementsMatch(t, []string{"prod-web", "prod-api"}, names[1:])
+
+	_, err = client.ResolveClusterNames(account, []string{"worker", "test-*"})
+	assert.EqualError(t, err, "No clusters match test-*")
+}
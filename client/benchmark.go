@@ -0,0 +1,174 @@
+package client
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/getcarina/carina/common"
+)
+
+// benchmarkPrefix is the prefix of the throwaway clusters created by the benchmark
+const benchmarkPrefix = "carina-benchmark-"
+
+// BenchmarkOptions controls which template is benchmarked, and how many times
+type BenchmarkOptions struct {
+	// Template is the name of the template used to create the clusters
+	Template string
+
+	// Nodes is the number of nodes in each cluster
+	Nodes int
+
+	// Cycles is how many times a cluster is created and deleted
+	Cycles int
+}
+
+// BenchmarkCycle is the outcome of creating and deleting a single cluster. The durations are 0 when the step wasn't run.
+type BenchmarkCycle struct {
+	Cluster string
+	Create  time.Duration
+	Delete  time.Duration
+	Err     error
+}
+
+// BenchmarkResult is the outcome of each create/delete cycle of the benchmark
+type BenchmarkResult struct {
+	Template string
+	Nodes    int
+	Cycles   []BenchmarkCycle
+}
+
+// DurationStats summarizes the durations of an operation
+type DurationStats struct {
+	Count int
+	Min   time.Duration
+	Mean  time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	Max   time.Duration
+}
+
+// Failures returns how many cycles failed
+func (result *BenchmarkResult) Failures() int {
+	var failures int
+	for _, cycle := range result.Cycles {
+		if cycle.Err != nil {
+			failures++
+		}
+	}
+	return failures
+}
+
+// CreateStats summarizes how long it took to create the clusters, excluding the failed cycles
+func (result *BenchmarkResult) CreateStats() DurationStats {
+	return result.stats(func(cycle BenchmarkCycle) time.Duration { return cycle.Create })
+}
+
+// DeleteStats summarizes how long it took to delete the clusters, excluding the failed cycles
+func (result *BenchmarkResult) DeleteStats() DurationStats {
+	return result.stats(func(cycle BenchmarkCycle) time.Duration { return cycle.Delete })
+}
+
+func (result *BenchmarkResult) stats(duration func(BenchmarkCycle) time.Duration) DurationStats {
+	var durations []time.Duration
+	for _, cycle := range result.Cycles {
+		if cycle.Err == nil {
+			durations = append(durations, duration(cycle))
+		}
+	}
+	return NewDurationStats(durations)
+}
+
+// NewDurationStats calculates the min, mean, max and the 50th and 95th percentiles of the durations,
+// using the nearest-rank method for the percentiles
+func NewDurationStats(durations []time.Duration) DurationStats {
+	if len(durations) == 0 {
+		return DurationStats{}
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+
+	percentile := func(p float64) time.Duration {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		return sorted[rank-1]
+	}
+
+	return DurationStats{
+		Count: len(sorted),
+		Min:   sorted[0],
+		Mean:  total / time.Duration(len(sorted)),
+		P50:   percentile(50),
+		P95:   percentile(95),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// Benchmark measures how long it takes to provision clusters: it creates a cluster from the template, waits for it to
+// become active, and then deletes it and waits for it to be deleted, one cycle at a time so that the cycles don't slow
+// each other down. A failed cycle is recorded and the benchmark moves on to the next cycle.
+func (client *Client) Benchmark(account Account, options BenchmarkOptions) (*BenchmarkResult, error) {
+	if options.Template == "" {
+		return nil, errors.New("A template is required to run the benchmark")
+	}
+	if options.Cycles < 1 {
+		return nil, errors.New("The benchmark requires at least 1 cycle")
+	}
+	if options.Nodes < 1 {
+		options.Nodes = 1
+	}
+
+	result := &BenchmarkResult{Template: options.Template, Nodes: options.Nodes}
+	generator := NewNameGenerator(0)
+	for i := 0; i < options.Cycles; i++ {
+		name, err := client.GenerateClusterName(account, benchmarkPrefix, generator)
+		if err != nil {
+			return nil, err
+		}
+
+		progress := common.Progress.Track("Benchmark cycle %d of %d (%s)", i+1, options.Cycles, name)
+		cycle := client.benchmarkCycle(account, name, options)
+		result.Cycles = append(result.Cycles, cycle)
+		if cycle.Err != nil {
+			progress.Update("failed")
+		} else {
+			progress.Update("done")
+		}
+	}
+
+	return result, nil
+}
+
+// benchmarkCycle creates and deletes a single cluster, timing each step
+func (client *Client) benchmarkCycle(account Account, name string, options BenchmarkOptions) BenchmarkCycle {
+	cycle := BenchmarkCycle{Cluster: name}
+
+	start := time.Now()
+	_, err := client.CreateCluster(account, name, options.Template, options.Nodes, CreateClusterOptions{WaitUntilActive: true})
+	cycle.Create = time.Since(start)
+	cycle.Err = err
+
+	// The cluster may exist even when creating it failed, e.g. it ended up in an error state
+	start = time.Now()
+	err = client.DeleteCluster(account, name, true)
+	if err != nil && cycle.Err != nil && isNotFound(err) {
+		return cycle
+	}
+	cycle.Delete = time.Since(start)
+	if cycle.Err == nil {
+		cycle.Err = err
+	}
+	if err != nil {
+		common.Log.WriteWarning("WARNING: Unable to delete the benchmark cluster %s, delete it with carina delete %s: %s", name, name, err)
+	}
+	return cycle
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDurationStats(t *testing.T) {
+	var durations []time.Duration
+	for i := 20; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Minute)
+	}
+
+	stats := NewDurationStats(durations)
+	assert.Equal(t, 20, stats.Count)
+	assert.Equal(t, time.Minute, stats.Min)
+	assert.Equal(t, 20*time.Minute, stats.Max)
+	assert.Equal(t, 10*time.Minute+30*time.Second, stats.Mean)
+	assert.Equal(t, 10*time.Minute, stats.P50)
+	assert.Equal(t, 19*time.Minute, stats.P95)
+
+	assert.Equal(t, DurationStats{}, NewDurationStats(nil))
+}
+
+func TestBenchmark(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.PendingPolls = 0
+	account := &historyAccount{offlineAccount{service: service}}
+
+	result, err := client.Benchmark(account, BenchmarkOptions{Template: "Swarm*", Nodes: 2, Cycles: 3})
+	require.NoError(t, err)
+	require.Len(t, result.Cycles, 3)
+	assert.Equal(t, 0, result.Failures())
+	assert.Equal(t, 3, result.CreateStats().Count)
+	for _, cycle := range result.Cycles {
+		assert.Contains(t, cycle.Cluster, benchmarkPrefix)
+	}
+
+	clusters, err := service.ListClusters()
+	require.NoError(t, err)
+	assert.Empty(t, clusters, "The benchmark clusters should be deleted")
+
+	result, err = client.Benchmark(account, BenchmarkOptions{Template: "missing", Cycles: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Failures())
+	assert.Equal(t, 0, result.CreateStats().Count, "Failed cycles are excluded from the stats")
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sync"
@@ -18,10 +19,82 @@ type cacheItem map[string]string
 type Cache struct {
 	sync.Mutex
 	path            string
-	LastUpdateCheck time.Time            `json:"last-check"`
-	Accounts        map[string]cacheItem `json:"accounts"`
+	cipher          fileCipher
+	home            string
+	loaded          sync.Once
+	err             error
+	SchemaVersion   int                                 `json:"schema-version"`
+	LastUpdateCheck time.Time                           `json:"last-check"`
+	Accounts        map[string]cacheItem                `json:"accounts"`
+	TokensUpdated   map[string]time.Time                `json:"tokens-updated"`
+	Labels          map[string]cacheItem                `json:"labels"`
+	Preferences     map[string]cacheItem                `json:"preferences"`
+	Fingerprints    map[string][]CredentialsFingerprint `json:"fingerprints"`
+	ClusterNames    map[string]cachedClusterNames       `json:"cluster-names"`
+	Created         map[string]time.Time                `json:"created"`
+	Listings        map[string]cachedListing            `json:"listings"`
+	History         map[string][]ClusterEvent           `json:"history"`
+	Capabilities    map[string]cachedCapabilities       `json:"capabilities"`
+	Pending         map[string]PendingOperation         `json:"pending"`
 }
 
+// cacheSchemaVersion is the version of the cache file format, which is increased when the format changes.
+// Caches written before the version was recorded have a version of 0.
+const cacheSchemaVersion = 1
+
+// cacheMigrations upgrade the cache file from each schema version to the next, so that a cache written by an older carina
+// is kept when the format changes. Register a migration when increasing cacheSchemaVersion.
+var cacheMigrations = map[int]func(cache map[string]json.RawMessage) error{
+	// Version 1 only started recording the schema version, the rest of the format is unchanged
+	0: func(cache map[string]json.RawMessage) error { return nil },
+}
+
+// migrateCacheContents upgrades the contents of a cache file written by an older carina to the current schema version.
+// A cache written by a newer carina is refused, instead of being overwritten with an older format.
+func migrateCacheContents(contents []byte) ([]byte, error) {
+	var cache map[string]json.RawMessage
+	err := json.Unmarshal(contents, &cache)
+	if err != nil {
+		// Let the caller decide what to do with an unreadable cache
+		return contents, nil
+	}
+
+	var version int
+	if raw, ok := cache["schema-version"]; ok {
+		json.Unmarshal(raw, &version)
+	}
+	if version == cacheSchemaVersion {
+		return contents, nil
+	}
+	if version > cacheSchemaVersion {
+		return nil, errors.Errorf("The cache was written by a newer version of carina (schema version %d, this version supports %d). Upgrade carina to use the cache", version, cacheSchemaVersion)
+	}
+
+	for ; version < cacheSchemaVersion; version++ {
+		migrate, ok := cacheMigrations[version]
+		if !ok {
+			return nil, errors.Errorf("Unable to upgrade the cache from schema version %d", version)
+		}
+		common.Log.WriteDebug("Upgrading the cache from schema version %d to %d", version, version+1)
+		err = migrate(cache)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to upgrade the cache from schema version %d", version)
+		}
+	}
+
+	cache["schema-version"], _ = json.Marshal(cacheSchemaVersion)
+	return json.Marshal(cache)
+}
+
+// cachedClusterNames is the list of cluster names last retrieved for an account, used for shell completion
+type cachedClusterNames struct {
+	Names   []string  `json:"names"`
+	Updated time.Time `json:"updated"`
+}
+
+// clusterNamesTTL is how long the cached cluster names are used before the clusters are listed again
+const clusterNamesTTL = 5 * time.Minute
+
 // CacheUnavailableError explains why the on-disk cache is unavailable
 type CacheUnavailableError struct {
 	cause error
@@ -39,8 +112,18 @@ func (error CacheUnavailableError) Cause() error {
 
 func newCache(path string) *Cache {
 	return &Cache{
-		path:     path,
-		Accounts: make(map[string]cacheItem),
+		path:          path,
+		Accounts:      make(map[string]cacheItem),
+		TokensUpdated: make(map[string]time.Time),
+		Labels:        make(map[string]cacheItem),
+		Preferences:   make(map[string]cacheItem),
+		Fingerprints:  make(map[string][]CredentialsFingerprint),
+		ClusterNames:  make(map[string]cachedClusterNames),
+		Created:       make(map[string]time.Time),
+		Listings:      make(map[string]cachedListing),
+		History:       make(map[string][]ClusterEvent),
+		Capabilities:  make(map[string]cachedCapabilities),
+		Pending:       make(map[string]PendingOperation),
 	}
 }
 
@@ -53,18 +136,66 @@ func defaultCacheFilename() (string, error) {
 }
 
 func (cache *Cache) isNil() bool {
+	cache.ensureLoaded()
 	return cache.path == ""
 }
 
+// ensureLoaded creates CARINA_HOME and reads the on-disk cache the first time the cache is used,
+// so that commands which don't use the cache, such as carina version, don't pay for loading it.
+// When the cache can't be loaded, it is disabled and the error is returned by Err.
+func (cache *Cache) ensureLoaded() {
+	cache.loaded.Do(func() {
+		if cache.path == "" {
+			return
+		}
+
+		err := cache.open()
+		if err != nil {
+			common.Log.WriteStructuredWarning(common.WarningCacheUnavailable, "Unable to initialize the cache, continuing without it: %s", err)
+			cache.path = ""
+			cache.err = CacheUnavailableError{cause: err}
+		}
+	})
+}
+
+// open creates CARINA_HOME, when the cache is stored there, then loads the cache
+func (cache *Cache) open() error {
+	if cache.home != "" {
+		err := os.MkdirAll(cache.home, 0777)
+		if err != nil {
+			return errors.Wrap(err, "Unable to create cache directory")
+		}
+		restrictAccess(cache.home)
+	}
+
+	return cache.load()
+}
+
+// Err returns why the cache is disabled, or nil when it is available or was disabled with --no-cache
+func (cache *Cache) Err() error {
+	cache.ensureLoaded()
+	return cache.err
+}
+
 // Load reads the on disk cache into memory
 func (cache *Cache) load() error {
-	f, err := os.OpenFile(cache.path, os.O_RDONLY, 0666)
+	contents, err := ioutil.ReadFile(cache.path)
 	if os.IsNotExist(err) {
 		return nil
 	}
-	defer f.Close()
 
-	err = json.NewDecoder(f).Decode(cache)
+	// Refuse to use an encrypted cache without the key, instead of overwriting it
+	contents, err = decryptContents(cache.cipher, contents)
+	if err != nil {
+		return errors.Wrap(err, "Unable to decrypt the cache")
+	}
+
+	contents, err = migrateCacheContents(contents)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(contents, cache)
 	if err != nil {
 		common.Log.WriteDebug(errors.Wrap(err, "Unable to deserialize cache file, starting over with a fresh cache").Error())
 	}
@@ -72,20 +203,39 @@ func (cache *Cache) load() error {
 	return nil
 }
 
-// Save writes the in memory cache to disk
+// Save writes the in memory cache to disk. The cache is written to a temporary file which then replaces the cache,
+// so that another carina process never reads a partially written cache.
 func (cache *Cache) save() error {
-	f, err := os.OpenFile(cache.path, os.O_WRONLY|os.O_CREATE, 0666)
-	defer f.Close()
+	cache.SchemaVersion = cacheSchemaVersion
+	contents, err := json.MarshalIndent(cache, "", "  ")
 	if err != nil {
-		return errors.Wrap(err, "Cannot open on-disk cache")
+		return errors.Wrap(err, "Cannot serialize in-memory cache")
 	}
 
-	contents, err := json.MarshalIndent(cache, "", "  ")
+	if cache.cipher != nil {
+		contents, err = cache.cipher.encrypt(contents)
+		if err != nil {
+			return errors.Wrap(err, "Cannot encrypt in-memory cache")
+		}
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(cache.path), filepath.Base(cache.path)+".tmp")
 	if err != nil {
-		return errors.Wrap(err, "Cannot serialize in-memory cache")
+		return errors.Wrap(err, "Cannot open on-disk cache")
 	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
 
 	_, err = f.Write(contents)
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "Cannot write to on-disk cache")
+	}
+
+	err = os.Rename(tmpPath, cache.path)
 	if err != nil {
 		return errors.Wrap(err, "Cannot write to on-disk cache")
 	}
@@ -93,7 +243,50 @@ func (cache *Cache) save() error {
 	return nil
 }
 
-// update handles locking and loading the on-disk cache before an update
+// cacheLockTimeout is how long to wait for another carina process to finish updating the cache
+const cacheLockTimeout = 10 * time.Second
+
+// cacheLockPollInterval is how often to check if another carina process has released the cache lock
+const cacheLockPollInterval = 50 * time.Millisecond
+
+// staleCacheLockAge is how old a lock is when it is assumed to be abandoned by a carina process which was killed
+const staleCacheLockAge = 30 * time.Second
+
+// lockFile acquires the lock on the on-disk cache shared by all carina processes, returning a function which releases it.
+func (cache *Cache) lockFile() (func(), error) {
+	return acquireFileLock(cache.path+".lock", "updating the cache")
+}
+
+// acquireFileLock waits for an exclusive lock, returning a function which releases it. activity describes what the lock guards in the timeout error.
+// The lock is a file created exclusively, which works the same on every platform.
+func acquireFileLock(lockPath string, activity string) (func(), error) {
+	deadline := time.Now().Add(cacheLockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			fmt.Fprintf(f, "%d", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, errors.Wrapf(err, "Unable to lock %s", lockPath)
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleCacheLockAge {
+			common.Log.WriteDebug("Removing the abandoned lock %s", lockPath)
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.Errorf("Timed out waiting for another carina command to finish %s. If no other carina commands are running, delete %s", activity, lockPath)
+		}
+		time.Sleep(cacheLockPollInterval)
+	}
+}
+
+// update handles locking and loading the on-disk cache before an update, so that changes made by
+// other carina processes since the cache was loaded are merged instead of overwritten
 func (cache *Cache) safeUpdate(action func(*Cache)) error {
 	if cache.isNil() {
 		return nil
@@ -101,7 +294,13 @@ func (cache *Cache) safeUpdate(action func(*Cache)) error {
 
 	cache.Lock()
 	defer cache.Unlock()
-	err := cache.load()
+	unlock, err := cache.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = cache.load()
 	if err != nil {
 		return err
 	}
@@ -112,6 +311,7 @@ func (cache *Cache) safeUpdate(action func(*Cache)) error {
 }
 
 func (cache *Cache) apply(account Account) {
+	cache.ensureLoaded()
 	accountCache, exists := cache.Accounts[account.GetID()]
 	if !exists {
 		return
@@ -120,6 +320,12 @@ func (cache *Cache) apply(account Account) {
 	account.ApplyCache(accountCache)
 }
 
+// GetLastUpdateCheck returns the last time that we checked for updates
+func (cache *Cache) GetLastUpdateCheck() time.Time {
+	cache.ensureLoaded()
+	return cache.LastUpdateCheck
+}
+
 // SaveLastUpdateCheck caches the last time that we checked for updates
 func (cache *Cache) SaveLastUpdateCheck(timestamp time.Time) error {
 	return cache.safeUpdate(func(c *Cache) {
@@ -131,10 +337,226 @@ func (cache *Cache) SaveLastUpdateCheck(timestamp time.Time) error {
 func (cache *Cache) SaveAccount(account Account) error {
 	return cache.safeUpdate(func(c *Cache) {
 		accountCache := account.BuildCache()
-		if account == nil {
+		if accountCache == nil {
 			common.Log.WriteDebug("Skipping updating the account cache because it is empty")
+			return
 		}
 
-		c.Accounts[account.GetID()] = accountCache
+		// Remember when the token changed, so that carina cache info can report how old it is
+		id := account.GetID()
+		if accountCache[cachedTokenKey] != c.Accounts[id][cachedTokenKey] {
+			if c.TokensUpdated == nil {
+				c.TokensUpdated = make(map[string]time.Time)
+			}
+			c.TokensUpdated[id] = time.Now()
+		}
+
+		c.Accounts[id] = accountCache
+	})
+}
+
+// RemoveAccount removes an account's cached auth token and endpoint. Returns if anything was cached for the account.
+func (cache *Cache) RemoveAccount(account Account) (bool, error) {
+	if cache.isNil() {
+		return false, errors.New("The cache is disabled")
+	}
+
+	var found bool
+	err := cache.safeUpdate(func(c *Cache) {
+		_, found = c.Accounts[account.GetID()]
+		delete(c.Accounts, account.GetID())
+		delete(c.TokensUpdated, account.GetID())
+	})
+	return found, err
+}
+
+// clusterCacheKey identifies a cluster, by its id or name, across all accounts in the cache
+func clusterCacheKey(account Account, cluster string) string {
+	return account.GetID() + "/" + cluster
+}
+
+// cachedTokenKey is the key used by every account to cache its auth token, see Account.BuildCache
+const cachedTokenKey = "token"
+
+// cachedEndpointKey is the key used by accounts to cache the API endpoint found in the service catalog
+const cachedEndpointKey = "endpoint"
+
+// cachedTokenExpiresKey is the key used by accounts which know when their auth token expires, in RFC 3339 format
+const cachedTokenExpiresKey = "token-expires"
+
+// InvalidateToken removes an account's cached auth token, keeping its other cached data such as the endpoint.
+// Returns if a token was cached.
+func (cache *Cache) InvalidateToken(account Account) (bool, error) {
+	if cache.isNil() {
+		return false, errors.New("The cache is disabled")
+	}
+
+	var found bool
+	err := cache.safeUpdate(func(c *Cache) {
+		accountCache, ok := c.Accounts[account.GetID()]
+		if !ok {
+			return
+		}
+		found = accountCache[cachedTokenKey] != ""
+		delete(accountCache, cachedTokenKey)
+		delete(c.TokensUpdated, account.GetID())
+	})
+	return found, err
+}
+
+func (cache *Cache) getClusterLabels(account Account, clusterID string) map[string]string {
+	cache.ensureLoaded()
+	return cache.Labels[clusterCacheKey(account, clusterID)]
+}
+
+// SaveClusterLabels updates the labels stored for a cluster
+func (cache *Cache) SaveClusterLabels(account Account, clusterID string, update func(labels map[string]string)) error {
+	if cache.isNil() {
+		return errors.New("Unable to save the cluster labels because the cache is disabled")
+	}
+
+	return cache.safeUpdate(func(c *Cache) {
+		if c.Labels == nil {
+			c.Labels = make(map[string]cacheItem)
+		}
+
+		key := clusterCacheKey(account, clusterID)
+		labels := c.Labels[key]
+		if labels == nil {
+			labels = make(cacheItem)
+		}
+
+		update(labels)
+
+		if len(labels) == 0 {
+			delete(c.Labels, key)
+		} else {
+			c.Labels[key] = labels
+		}
+	})
+}
+
+// DeleteClusterLabels removes the labels stored for a cluster
+func (cache *Cache) DeleteClusterLabels(account Account, clusterID string) error {
+	if cache.isNil() {
+		return nil
+	}
+
+	return cache.safeUpdate(func(c *Cache) {
+		delete(c.Labels, clusterCacheKey(account, clusterID))
+	})
+}
+
+// GetClusterPreference retrieves a remembered setting for a cluster, such as the shell last used to connect to it
+func (cache *Cache) GetClusterPreference(account Account, clusterName string, key string) string {
+	cache.ensureLoaded()
+	return cache.Preferences[clusterCacheKey(account, clusterName)][key]
+}
+
+// SaveClusterPreference remembers a setting for a cluster
+func (cache *Cache) SaveClusterPreference(account Account, clusterName string, key string, value string) error {
+	return cache.safeUpdate(func(c *Cache) {
+		if c.Preferences == nil {
+			c.Preferences = make(map[string]cacheItem)
+		}
+
+		cacheKey := clusterCacheKey(account, clusterName)
+		if c.Preferences[cacheKey] == nil {
+			c.Preferences[cacheKey] = make(cacheItem)
+		}
+		c.Preferences[cacheKey][key] = value
+	})
+}
+
+// GetClusterPreferences returns every setting remembered for a cluster
+func (cache *Cache) GetClusterPreferences(account Account, clusterName string) map[string]string {
+	cache.ensureLoaded()
+	preferences := make(map[string]string)
+	for key, value := range cache.Preferences[clusterCacheKey(account, clusterName)] {
+		preferences[key] = value
+	}
+	return preferences
+}
+
+// DeleteClusterPreference forgets a setting for a cluster
+func (cache *Cache) DeleteClusterPreference(account Account, clusterName string, key string) error {
+	return cache.safeUpdate(func(c *Cache) {
+		cacheKey := clusterCacheKey(account, clusterName)
+		delete(c.Preferences[cacheKey], key)
+		if len(c.Preferences[cacheKey]) == 0 {
+			delete(c.Preferences, cacheKey)
+		}
+	})
+}
+
+func (cache *Cache) getFingerprintHistory(account Account, clusterName string) []CredentialsFingerprint {
+	cache.ensureLoaded()
+	return cache.Fingerprints[clusterCacheKey(account, clusterName)]
+}
+
+// saveFingerprint appends to the fingerprint history for a cluster, discarding the oldest records
+func (cache *Cache) saveFingerprint(account Account, clusterName string, fingerprint CredentialsFingerprint) error {
+	return cache.safeUpdate(func(c *Cache) {
+		if c.Fingerprints == nil {
+			c.Fingerprints = make(map[string][]CredentialsFingerprint)
+		}
+
+		key := clusterCacheKey(account, clusterName)
+		history := append(c.Fingerprints[key], fingerprint)
+		if len(history) > maxFingerprintHistory {
+			history = history[len(history)-maxFingerprintHistory:]
+		}
+		c.Fingerprints[key] = history
+	})
+}
+
+// deleteFingerprints removes the fingerprint history for a cluster, so that a new cluster with the same name is not reported as changed
+func (cache *Cache) deleteFingerprints(account Account, clusterName string) error {
+	return cache.safeUpdate(func(c *Cache) {
+		delete(c.Fingerprints, clusterCacheKey(account, clusterName))
+	})
+}
+
+// getClusterNames returns the cached cluster names for an account, if they haven't expired
+func (cache *Cache) getClusterNames(account Account) ([]string, bool) {
+	cache.ensureLoaded()
+	cached, ok := cache.ClusterNames[account.GetID()]
+	if !ok || time.Since(cached.Updated) > clusterNamesTTL {
+		return nil, false
+	}
+	return cached.Names, true
+}
+
+// saveClusterNames caches the cluster names for an account
+func (cache *Cache) saveClusterNames(account Account, names []string) error {
+	return cache.safeUpdate(func(c *Cache) {
+		if c.ClusterNames == nil {
+			c.ClusterNames = make(map[string]cachedClusterNames)
+		}
+		c.ClusterNames[account.GetID()] = cachedClusterNames{Names: names, Updated: time.Now()}
+	})
+}
+
+// getClusterCreated returns when a cluster was created, if it was created by this client
+func (cache *Cache) getClusterCreated(account Account, clusterID string) (time.Time, bool) {
+	cache.ensureLoaded()
+	created, ok := cache.Created[clusterCacheKey(account, clusterID)]
+	return created, ok
+}
+
+// saveClusterCreated records when a cluster was created, since the APIs don't report it
+func (cache *Cache) saveClusterCreated(account Account, clusterID string, created time.Time) error {
+	return cache.safeUpdate(func(c *Cache) {
+		if c.Created == nil {
+			c.Created = make(map[string]time.Time)
+		}
+		c.Created[clusterCacheKey(account, clusterID)] = created
+	})
+}
+
+// deleteClusterCreated removes the creation time recorded for a cluster
+func (cache *Cache) deleteClusterCreated(account Account, clusterID string) error {
+	return cache.safeUpdate(func(c *Cache) {
+		delete(c.Created, clusterCacheKey(account, clusterID))
 	})
 }
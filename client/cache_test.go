@@ -2,13 +2,17 @@ package client
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/getcarina/carina/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var rand uint32
@@ -68,3 +72,203 @@ func TestLoadCache(t *testing.T) {
 	}
 
 }
+
+// cachingAccount is an account with a fixed set of data to cache
+type cachingAccount struct {
+	Account
+	id    string
+	cache map[string]string
+}
+
+func (account *cachingAccount) GetID() string {
+	return account.id
+}
+
+func (account *cachingAccount) BuildCache() map[string]string {
+	return account.cache
+}
+
+func TestInvalidateTokenKeepsEndpoint(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	account := &cachingAccount{id: "public-alice", cache: map[string]string{"token": "abc123", "endpoint": "https://example.com"}}
+	other := &cachingAccount{id: "public-bob", cache: map[string]string{"token": "def456"}}
+
+	cache := newCache(filename)
+	cache.SaveAccount(account)
+	cache.SaveAccount(other)
+
+	found, err := cache.InvalidateToken(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Error("Expected the cached token to be found")
+	}
+
+	cache = newCache(filename)
+	err = cache.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, ok := cache.Accounts["public-alice"]["token"]; ok {
+		t.Errorf("Expected the token to be removed, got %s", token)
+	}
+	if endpoint := cache.Accounts["public-alice"]["endpoint"]; endpoint != "https://example.com" {
+		t.Errorf("Expected the endpoint to be kept, got %s", endpoint)
+	}
+	if token := cache.Accounts["public-bob"]["token"]; token != "def456" {
+		t.Errorf("Expected the other account's token to be kept, got %s", token)
+	}
+}
+
+func TestUncachedAccountIsNotSaved(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	cache := newCache(filename)
+	cache.SaveAccount(&cachingAccount{id: "public-alice", cache: map[string]string{"token": "abc123"}})
+
+	oneOff := NewUncachedAccount(&cachingAccount{id: "public-alice", cache: map[string]string{"token": "def456"}})
+	cache.SaveAccount(oneOff)
+
+	cache = newCache(filename)
+	err := cache.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token := cache.Accounts["public-alice"]["token"]; token != "abc123" {
+		t.Errorf("Expected the cached token to be kept, got %s", token)
+	}
+}
+
+func TestConcurrentCacheUpdatesAreMerged(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	// Each cache is loaded separately, like parallel carina processes
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			account := &cachingAccount{id: fmt.Sprintf("public-user%d", i), cache: map[string]string{"token": strconv.Itoa(i)}}
+			err := newCache(filename).SaveAccount(account)
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	cache := newCache(filename)
+	err := cache.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cache.Accounts) != 10 {
+		t.Errorf("Expected every account to be saved, got %d", len(cache.Accounts))
+	}
+	if _, err := os.Stat(filename + ".lock"); !os.IsNotExist(err) {
+		t.Error("Expected the cache lock to be released")
+	}
+}
+
+func TestCacheInfoReportsTokenAge(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	account := &cachingAccount{id: "public-alice", cache: map[string]string{"token": "abc123"}}
+	cache := newCache(filename)
+	cache.SaveAccount(account)
+	tokenUpdated := cache.TokensUpdated["public-alice"]
+
+	// Saving the same token again shouldn't reset its age
+	time.Sleep(10 * time.Millisecond)
+	cache.SaveAccount(account)
+
+	info, err := cache.GetInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.SchemaVersion != cacheSchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", cacheSchemaVersion, info.SchemaVersion)
+	}
+	if info.Size == 0 {
+		t.Error("Expected the size of the cache file")
+	}
+	if len(info.Accounts) != 1 {
+		t.Fatalf("Expected 1 account, got %d", len(info.Accounts))
+	}
+	if !info.Accounts[0].HasToken || !info.Accounts[0].TokenUpdated.Equal(tokenUpdated) {
+		t.Errorf("Expected the token to be cached at %s, got %+v", tokenUpdated, info.Accounts[0])
+	}
+
+	cache.InvalidateToken(account)
+	info, _ = cache.GetInfo()
+	if info.Accounts[0].HasToken || !info.Accounts[0].TokenUpdated.IsZero() {
+		t.Errorf("Expected the token to be removed, got %+v", info.Accounts[0])
+	}
+}
+
+func TestCacheIsLoadedOnFirstUse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-cache-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	home := filepath.Join(dir, "home")
+	cache := newCache(filepath.Join(home, "cache.json"))
+	cache.home = home
+
+	_, err = os.Stat(home)
+	assert.True(t, os.IsNotExist(err), "CARINA_HOME should not be created until the cache is used")
+
+	assert.Nil(t, cache.Err())
+	assert.False(t, cache.isNil())
+	_, err = os.Stat(home)
+	assert.NoError(t, err, "CARINA_HOME should be created when the cache is first used")
+}
+
+func TestCacheIsDisabledWhenItCannotBeLoaded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-cache-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// CARINA_HOME can't be created inside of a file
+	home := filepath.Join(dir, "file", "home")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "file"), nil, 0600))
+	cache := newCache(filepath.Join(home, "cache.json"))
+	cache.home = home
+
+	assert.True(t, cache.isNil())
+	assert.IsType(t, CacheUnavailableError{}, cache.Err())
+	assert.NoError(t, cache.SaveLastUpdateCheck(time.Now()), "Updates to a disabled cache should be ignored")
+}
+
+func TestClusterPreferences(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	cache := newCache(filename)
+	account := &stubAccount{}
+	require.NoError(t, cache.SaveClusterPreference(account, "prod", "path", "/home/alice/clusters/prod"))
+	require.NoError(t, cache.SaveClusterPreference(account, "prod", "shell", "fish"))
+
+	reloaded := newCache(filename)
+	assert.Equal(t, map[string]string{"path": "/home/alice/clusters/prod", "shell": "fish"}, reloaded.GetClusterPreferences(account, "prod"))
+	assert.Empty(t, reloaded.GetClusterPreferences(account, "staging"))
+
+	require.NoError(t, reloaded.DeleteClusterPreference(account, "prod", "path"))
+	assert.Equal(t, map[string]string{"shell": "fish"}, newCache(filename).GetClusterPreferences(account, "prod"))
+}
+
+func TestCacheSchemaMigration(t *testing.T) {
+	contents, err := migrateCacheContents([]byte(`{"last-update-check":"2017-01-02T00:00:00Z"}`))
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), fmt.Sprintf(`"schema-version":%d`, cacheSchemaVersion), "A cache without a version should be upgraded")
+	assert.Contains(t, string(contents), "last-update-check", "The cached data should be kept")
+
+	_, err = migrateCacheContents([]byte(fmt.Sprintf(`{"schema-version":%d}`, cacheSchemaVersion+1)))
+	assert.Error(t, err, "A cache written by a newer carina should be refused")
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CacheInfo describes the on-disk cache, to help troubleshoot problems such as re-authenticating on every command
+type CacheInfo struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	Encrypted bool   `json:"encrypted"`
+
+	// SchemaVersion is the version of the cache file format, 0 when the cache was written by an older carina
+	SchemaVersion   int                 `json:"schemaVersion"`
+	LastUpdateCheck time.Time           `json:"lastUpdateCheck"`
+	Accounts        []CachedAccountInfo `json:"accounts"`
+}
+
+// CachedAccountInfo describes the data cached for an account. Times are zero when nothing is cached, or when it is unknown.
+type CachedAccountInfo struct {
+	ID               string    `json:"id"`
+	HasToken         bool      `json:"hasToken"`
+	TokenUpdated     time.Time `json:"tokenUpdated"`
+	ClustersUpdated  time.Time `json:"clustersUpdated"`
+	TemplatesUpdated time.Time `json:"templatesUpdated"`
+}
+
+// GetInfo describes the on-disk cache and the data cached for each account
+func (cache *Cache) GetInfo() (*CacheInfo, error) {
+	if cache.isNil() {
+		return nil, errors.New("The cache is disabled")
+	}
+
+	cache.Lock()
+	defer cache.Unlock()
+
+	info := &CacheInfo{
+		Path:            cache.path,
+		Encrypted:       cache.cipher != nil,
+		SchemaVersion:   cache.SchemaVersion,
+		LastUpdateCheck: cache.LastUpdateCheck,
+	}
+
+	stat, err := os.Stat(cache.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "Unable to read the on-disk cache")
+	}
+	if err == nil {
+		info.Size = stat.Size()
+	}
+
+	ids := make(map[string]bool)
+	for id := range cache.Accounts {
+		ids[id] = true
+	}
+	for id := range cache.Listings {
+		ids[id] = true
+	}
+
+	for id := range ids {
+		listing := cache.Listings[id]
+		info.Accounts = append(info.Accounts, CachedAccountInfo{
+			ID:               id,
+			HasToken:         cache.Accounts[id][cachedTokenKey] != "",
+			TokenUpdated:     cache.TokensUpdated[id],
+			ClustersUpdated:  listing.ClustersUpdated,
+			TemplatesUpdated: listing.TemplatesUpdated,
+		})
+	}
+	sort.Slice(info.Accounts, func(i, j int) bool { return info.Accounts[i].ID < info.Accounts[j].ID })
+
+	return info, nil
+}
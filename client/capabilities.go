@@ -0,0 +1,105 @@
+package client
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// capabilitiesTTL is how long the capabilities of an endpoint are cached before it is probed again
+const capabilitiesTTL = 24 * time.Hour
+
+// cachedCapabilities are the capabilities probed from an endpoint, and when they were probed
+type cachedCapabilities struct {
+	common.APICapabilities
+	Probed time.Time `json:"probed"`
+}
+
+// GetAPICapabilities probes the account's endpoint for its API version, and the operations which it supports.
+// Returns nil when the cloud can't report its capabilities. The result is cached per endpoint unless refresh is set.
+func (client *Client) GetAPICapabilities(account Account, refresh bool) (*common.APICapabilities, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	capabilities, err := client.probeCapabilities(svc, refresh)
+	return capabilities, wrapClientError(err)
+}
+
+// probeCapabilities returns the capabilities of the cluster service's endpoint, using the cached capabilities when possible
+func (client *Client) probeCapabilities(svc common.ClusterService, refresh bool) (*common.APICapabilities, error) {
+	prober, ok := svc.(common.CapabilityProber)
+	if !ok {
+		return nil, nil
+	}
+
+	// Only cache when the endpoint is known before probing
+	var endpoint string
+	if authenticator, ok := svc.(common.Authenticator); ok {
+		var err error
+		endpoint, err = authenticator.Authenticate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if endpoint != "" && !refresh {
+		if cached, ok := client.Cache.getCachedCapabilities(endpoint); ok && time.Since(cached.Probed) < capabilitiesTTL {
+			common.Log.WriteDebug("Using the capabilities of %s probed at %s", endpoint, cached.Probed.Local().Format(time.RFC822))
+			return &cached.APICapabilities, nil
+		}
+	}
+
+	capabilities, err := prober.ProbeCapabilities()
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint != "" {
+		err = client.Cache.saveCachedCapabilities(endpoint, capabilities)
+		if err != nil {
+			common.Log.WriteDebug("Unable to cache the capabilities of %s: %s", endpoint, err)
+		}
+	}
+	return capabilities, nil
+}
+
+// checkSupported fails fast with common.UnsupportedOperationError when the endpoint is known not to support an operation,
+// instead of failing part way through the operation. The operation is attempted when the endpoint can't be probed.
+func (client *Client) checkSupported(svc common.ClusterService, operation string) error {
+	capabilities, err := client.probeCapabilities(svc, false)
+	if apiErr, ok := errors.Cause(err).(common.APIError); ok && apiErr.StatusCode == http.StatusNotAcceptable {
+		// The server no longer accepts the client's API version
+		return wrapClientError(err)
+	}
+	if err != nil {
+		common.Log.WriteDebug("Skipping the capability check for %s, unable to probe the endpoint: %s", operation, err)
+		return nil
+	}
+
+	if !capabilities.Supports(operation) {
+		return common.UnsupportedOperationError{Operation: operation, Endpoint: capabilities.Endpoint}
+	}
+	return nil
+}
+
+// getCachedCapabilities returns the capabilities last probed from an endpoint
+func (cache *Cache) getCachedCapabilities(endpoint string) (cachedCapabilities, bool) {
+	cache.ensureLoaded()
+	cached, ok := cache.Capabilities[endpoint]
+	return cached, ok
+}
+
+// saveCachedCapabilities caches the capabilities probed from an endpoint
+func (cache *Cache) saveCachedCapabilities(endpoint string, capabilities *common.APICapabilities) error {
+	return cache.safeUpdate(func(c *Cache) {
+		if c.Capabilities == nil {
+			c.Capabilities = make(map[string]cachedCapabilities)
+		}
+		c.Capabilities[endpoint] = cachedCapabilities{APICapabilities: *capabilities, Probed: time.Now()}
+	})
+}
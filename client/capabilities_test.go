@@ -0,0 +1,39 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnsupportedOperationsFailFast(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.APIVersion = "1.2"
+	service.Unsupported = []string{common.OperationResize}
+	service.CreateCluster("prod", "Swarm*", 1)
+	account := &historyAccount{offlineAccount{service: service}}
+
+	_, err := client.ResizeCluster(account, "prod", 2, false)
+	assert.Equal(t, common.UnsupportedOperationError{Operation: common.OperationResize, Endpoint: testsupport.FakeEndpoint}, err)
+	cluster, _ := service.GetCluster("prod")
+	assert.Equal(t, "1", cluster.GetNodes(), "The resize shouldn't be attempted")
+
+	_, err = client.SetAutoScale(account, "prod", common.AutoScale{})
+	assert.NotEqual(t, common.UnsupportedOperationError{Operation: common.OperationAutoScale, Endpoint: testsupport.FakeEndpoint}, err)
+	assert.Equal(t, 1, service.Probes, "The capabilities should be cached per endpoint")
+
+	capabilities, err := client.GetAPICapabilities(account, true)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2", capabilities.MaxVersion)
+	assert.False(t, capabilities.Supports(common.OperationResize))
+	assert.Equal(t, 2, service.Probes, "Refreshing should probe the endpoint again")
+}
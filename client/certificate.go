@@ -0,0 +1,90 @@
+package client
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// certificateExpiryWarning is how long before the client certificate expires that the user is warned to download new credentials
+const certificateExpiryWarning = 14 * 24 * time.Hour
+
+// CertificateInfo describes a certificate from a cluster's credentials
+type CertificateInfo struct {
+	File      string
+	Subject   string
+	Issuer    string
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// ExpiresWithin returns if the certificate expires before the specified amount of time has passed
+func (info CertificateInfo) ExpiresWithin(d time.Duration) bool {
+	return time.Now().Add(d).After(info.NotAfter)
+}
+
+// parseCertificate decodes a PEM encoded certificate
+func parseCertificate(contents []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, errors.New("Invalid certificate, unable to decode the PEM block")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// readCertificateInfo reads a certificate from a cluster's credentials directory
+func (client *Client) readCertificateInfo(credentialsPath string, file string) (CertificateInfo, error) {
+	contents, err := client.readSecureFile(filepath.Join(credentialsPath, file))
+	if err != nil {
+		return CertificateInfo{}, err
+	}
+
+	cert, err := parseCertificate(contents)
+	if err != nil {
+		return CertificateInfo{}, errors.Wrapf(err, "Unable to parse %s", file)
+	}
+
+	return CertificateInfo{
+		File:      file,
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}, nil
+}
+
+// InspectClusterCredentials reads the CA and client certificates from a cluster's downloaded credentials
+func (client *Client) InspectClusterCredentials(account Account, name string, customPath string) ([]CertificateInfo, error) {
+	credentialsPath, err := buildClusterCredentialsPath(account, name, customPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []CertificateInfo
+	for _, file := range []string{caCertFilename, clientCertFilename} {
+		info, err := client.readCertificateInfo(credentialsPath, file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to read the credentials for %s, run carina credentials %s to download them", name, name)
+		}
+		certs = append(certs, info)
+	}
+
+	return certs, nil
+}
+
+// CertificateExpiringError warns that a cluster's client certificate expires soon
+type CertificateExpiringError struct {
+	ClusterName string
+	NotAfter    time.Time
+}
+
+// Error returns the warning message
+func (err CertificateExpiringError) Error() string {
+	return fmt.Sprintf("The client certificate for %s expires on %s. Run carina credentials %s to download new credentials.",
+		err.ClusterName, err.NotAfter.Local().Format(time.RFC822), err.ClusterName)
+}
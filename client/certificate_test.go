@@ -0,0 +1,31 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCertificateInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-certificate-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	notAfter := time.Now().Add(7 * 24 * time.Hour).Truncate(time.Second)
+	ioutil.WriteFile(filepath.Join(dir, clientCertFilename), buildSignedCertificate(t, notAfter), 0600)
+
+	client := &Client{}
+	info, err := client.readCertificateInfo(dir, clientCertFilename)
+	assert.Nil(t, err)
+	assert.Equal(t, "CN=test", info.Subject)
+	assert.Equal(t, "CN=test", info.Issuer)
+	assert.True(t, notAfter.Equal(info.NotAfter))
+	assert.True(t, info.ExpiresWithin(certificateExpiryWarning))
+	assert.False(t, info.ExpiresWithin(0))
+}
@@ -1,95 +1,135 @@
 package client
 
 import (
-	"io/ioutil"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/getcarina/carina/common"
 	"github.com/getcarina/libcarina"
 	"github.com/pkg/errors"
-	"github.com/ryanuber/go-glob"
 )
 
 // Client is the multi-cloud Carina client, which coordinates communication with all Carina-esque clouds
 type Client struct {
+	// Cache is loaded the first time it is used, see Cache.Err for why it may be unavailable
 	Cache *Cache
-	Error error
-}
 
-// CarinaHomeDirEnvVar is the environment variable name for carina data, config, etc.
-const CarinaHomeDirEnvVar = "CARINA_HOME"
+	// AuditLog is the file where the clusters created, deleted, resized, grown and rebuilt are recorded, as JSON lines.
+	// It is in CARINA_HOME, and auditing is disabled when it is empty, e.g. when the cache is disabled.
+	AuditLog string
 
-// CloudMakeSwarm is the v1 Carina (make-swarm) cloud type
-const CloudMakeSwarm = "make-swarm"
+	// DisableLocks skips the advisory lock taken on a cluster while it is being changed, see lockCluster
+	DisableLocks bool
 
-// CloudMakeCOE is the v2 Carina (make-coe) cloud type
-const CloudMakeCOE = "public"
+	// lockHome is the directory holding the cluster locks when using a shared CARINA_HOME, see UseSharedHome and lockDir
+	lockHome string
 
-// CloudMagnum is the Rackspace Private Cloud Magnum cloud type
-const CloudMagnum = "private"
+	// cipher encrypts the credentials and token cache stored in CARINA_HOME, and is nil when encryption is disabled
+	cipher fileCipher
 
-// NewClient builds a new Carina client
+	operationsLock  sync.Mutex
+	operations      map[int]operation
+	lastOperationID int
+	clusterStatuses *common.BoundedCache
+	heldLocks       map[string]func()
+	accounts        map[string]Account
+	shutdown        sync.Once
+
+	// ephemeralCredentials are the temporary credentials directories which are wiped on shutdown, see DownloadEphemeralClusterCredentials
+	ephemeralCredentials map[string]bool
+
+	// pendingWaits is the number of waits persisted for carina resume which haven't finished, see trackPendingWait
+	pendingWaits int
+}
+
+// CarinaHomeDirEnvVar is the environment variable name for carina data, config, etc.
+const CarinaHomeDirEnvVar = "CARINA_HOME"
+
+// NewClient builds a new Carina client. The cache is not read until it is first used.
 func NewClient(cacheEnabled bool) *Client {
 	client := &Client{}
 	client.initCache(cacheEnabled)
 	return client
 }
 
+// NewEncryptedClient builds a new Carina client, which encrypts the credentials and token cache
+// stored in CARINA_HOME according to the credentials.encryption setting: none, passphrase or keychain
+func NewEncryptedClient(cacheEnabled bool, encryption string) (*Client, error) {
+	cipher, err := newFileCipher(encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{cipher: cipher}
+	client.initCache(cacheEnabled)
+	return client, nil
+}
+
 func wrapClientError(err error) error {
 	if err == nil {
 		return nil
 	}
 
-	return newClientError(err)
+	return newClientError(common.CategorizeError(err))
 }
 
-func (client *Client) initCache(cacheEnabled bool) {
-	disableCache := func(err error) {
-		common.Log.WriteWarning("Unable to initialize cache. Starting fresh!")
-		common.Log.WriteWarning(err.Error())
-		client.Cache = &Cache{}
-		client.Error = CacheUnavailableError{cause: err}
+// wrapClusterError wraps an error from an operation on a cluster, recording the name of the cluster
+func wrapClusterError(name string, err error) error {
+	if err == nil {
+		return nil
 	}
 
+	return newClientError(common.ClusterError{ClusterName: name, Err: common.CategorizeError(err)})
+}
+
+// initCache sets up the on-disk cache, which isn't read until it is first used
+func (client *Client) initCache(cacheEnabled bool) {
 	if !cacheEnabled {
 		common.Log.WriteDebug("Cache disabled")
 		client.Cache = &Cache{}
 		return
 	}
 
-	bd, err := GetCredentialsDir()
-	if err != nil {
-		disableCache(err)
-		return
-	}
-
-	err = os.MkdirAll(bd, 0777)
-	if err != nil {
-		disableCache(errors.Wrap(err, "Unable to create cache directory"))
-		return
-	}
-
 	path, err := defaultCacheFilename()
+	if err == nil && SharedHomeEnabled() {
+		// Until an account is selected, see UseSharedHome, use the current user's namespace
+		var namespace string
+		namespace, err = openSharedNamespace(userNamespaceKey())
+		path = filepath.Join(namespace, filepath.Base(path))
+	}
 	if err != nil {
-		disableCache(err)
+		common.Log.WriteStructuredWarning(common.WarningCacheUnavailable, "Unable to initialize the cache, continuing without it: %s", err)
+		client.Cache = &Cache{err: CacheUnavailableError{cause: err}}
 		return
 	}
 
 	client.Cache = newCache(path)
-	err = client.Cache.load()
-	if err != nil {
-		disableCache(err)
-	}
+	client.Cache.home = filepath.Dir(path)
+	client.AuditLog = filepath.Join(client.Cache.home, auditLogFilename)
+	client.Cache.cipher = client.cipher
 }
 
 func (client *Client) buildContainerService(account Account) (common.ClusterService, error) {
+	// Remember the account so that its cache can be flushed on shutdown
+	client.operationsLock.Lock()
+	if client.accounts == nil {
+		client.accounts = make(map[string]Account)
+	}
+	client.accounts[account.GetID()] = account
+	client.operationsLock.Unlock()
+
 	client.Cache.apply(account)
 	return account.NewClusterService(), nil
 }
 
 // GetQuotas retrieves the quotas set for the account
-func (client *Client) GetQuotas(account Account) (common.Quotas, error) {
+func (client *Client) GetQuotas(account Account) (*common.Quotas, error) {
 	defer client.Cache.SaveAccount(account)
 	svc, err := client.buildContainerService(account)
 	if err != nil {
@@ -100,64 +140,316 @@ func (client *Client) GetQuotas(account Account) (common.Quotas, error) {
 	return quotas, wrapClientError(err)
 }
 
+// CreateClusterOptions are the optional settings used when creating a cluster
+type CreateClusterOptions struct {
+	// AllowDeprecated permits creating a cluster from a deprecated template, otherwise an error is returned
+	AllowDeprecated bool
+
+	// Labels are the key/value pairs used to organize the cluster
+	Labels map[string]string
+
+	// DriverOptions are passed through to the cluster driver, such as the magnum labels kube_tag or docker_volume_size
+	DriverOptions map[string]string
+
+	// SSHKey is installed on the nodes of the cluster, when supported by the cloud
+	SSHKey common.SSHKey
+
+	// WaitUntilActive waits for the cluster to become active before returning
+	WaitUntilActive bool
+
+	// IfNotExists returns the existing cluster with the same name, instead of an error, so that provisioning scripts can be re-run
+	IfNotExists bool
+
+	// progress reports the status transitions of the cluster while waiting, see CreateClusters
+	progress *common.ProgressTracker
+}
+
 // CreateCluster creates a new cluster and prints the cluster information
-func (client *Client) CreateCluster(account Account, name string, template string, nodes int, waitUntilActive bool) (common.Cluster, error) {
+func (client *Client) CreateCluster(account Account, name string, template string, nodes int, options CreateClusterOptions) (common.Cluster, error) {
 	defer client.Cache.SaveAccount(account)
 	svc, err := client.buildContainerService(account)
 	if err != nil {
 		return nil, err
 	}
 
-	cluster, err := svc.CreateCluster(name, template, nodes)
+	err = checkTemplate(svc, template, options.AllowDeprecated)
+	if err != nil {
+		return nil, err
+	}
 
-	if waitUntilActive && err == nil {
+	return client.createCluster(svc, account, name, template, nodes, options)
+}
+
+func (client *Client) createCluster(svc common.ClusterService, account Account, name string, template string, nodes int, options CreateClusterOptions) (cluster common.Cluster, err error) {
+	defer client.endOperation(client.startOperation(name, "Create cluster (%s)", name))
+	started := time.Now()
+	reused := false
+	defer func() {
+		args := map[string]string{"template": template, "nodes": strconv.Itoa(nodes)}
+		if options.IfNotExists {
+			args["if-not-exists"] = "true"
+		}
+		client.audit(account, "create", name, args, cluster, err)
+		// The hook is for new clusters, so it isn't run when --if-not-exists found an existing cluster
+		if !reused {
+			client.runHook(account, "create", name, cluster, options.WaitUntilActive, err)
+		}
+		if options.WaitUntilActive {
+			client.notifyWaitFinished(account, "create", name, started, cluster, err)
+		}
+	}()
+
+	unlock, err := client.lockCluster(account, name, "create")
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+	defer unlock()
+
+	if options.IfNotExists {
+		existing, err := findExistingCluster(svc, name)
+		if err != nil {
+			return nil, wrapClusterError(name, err)
+		}
+		if existing != nil {
+			reused = true
+			return client.useExistingCluster(svc, account, existing, options)
+		}
+	}
+
+	if options.SSHKey != (common.SSHKey{}) {
+		creator, ok := svc.(common.SSHKeyCreator)
+		if !ok {
+			return nil, errors.New("SSH keys are not supported by this cloud")
+		}
+		cluster, err = creator.CreateClusterWithSSHKey(name, template, nodes, options.SSHKey, options.DriverOptions)
+	} else if len(options.DriverOptions) > 0 {
+		creator, ok := svc.(common.DriverOptionsCreator)
+		if !ok {
+			return nil, errors.New("Driver options are not supported by this cloud")
+		}
+		common.Log.WriteDebug("Driver options: %v", options.DriverOptions)
+		cluster, err = creator.CreateClusterWithDriverOptions(name, template, nodes, options.DriverOptions)
+	} else {
+		cluster, err = svc.CreateCluster(name, template, nodes)
+	}
+
+	if err == nil {
+		client.Cache.saveClusterCreated(account, cluster.GetID(), time.Now())
+		client.recordClusterStatus(account, "create", cluster)
+	}
+
+	if len(options.Labels) > 0 && err == nil {
+		err = client.Cache.SaveClusterLabels(account, cluster.GetID(), func(labels map[string]string) {
+			for key, value := range options.Labels {
+				labels[key] = value
+			}
+		})
+		if err != nil {
+			common.Log.WriteWarning("Unable to label the cluster: %s", err)
+			err = nil
+		}
+	}
+
+	if options.WaitUntilActive && err == nil {
+		defer client.watchClusterStatus(account, cluster, options.progress)()
+		finishWait := client.trackPendingWait(account, "create", cluster, WaitForActive)
 		cluster, err = svc.WaitUntilClusterIsActive(cluster)
+		finishWait(err)
+		if err == nil {
+			client.recordClusterStatus(account, "wait", cluster)
+		}
 	}
 
-	return cluster, wrapClientError(err)
+	return client.applyLabels(account, cluster), wrapClusterError(name, err)
 }
 
-// DownloadClusterCredentials downloads the TLS certificates and configuration scripts for a cluster
-func (client *Client) DownloadClusterCredentials(account Account, name string, customPath string) (credentialsPath string, err error) {
+// findExistingCluster returns the cluster with exactly the specified name, or nil when it doesn't exist
+func findExistingCluster(svc common.ClusterService, name string) (common.Cluster, error) {
+	clusters, err := svc.ListClusters()
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range clusters {
+		if cluster.GetName() == name {
+			return cluster, nil
+		}
+	}
+	return nil, nil
+}
+
+// useExistingCluster returns a cluster which already exists when creating it with IfNotExists,
+// waiting for it to become active when requested. Clusters in an error state, or being deleted, are not reused.
+func (client *Client) useExistingCluster(svc common.ClusterService, account Account, cluster common.Cluster, options CreateClusterOptions) (common.Cluster, error) {
+	name := cluster.GetName()
+	client.recordClusterStatus(account, "get", cluster)
+
+	status := cluster.GetStatus()
+	if IsErrorStatus(status) {
+		return nil, fmt.Errorf("The cluster %s already exists and is %s. Run carina repair %s to recover it", name, status, name)
+	}
+	if strings.Contains(strings.ToLower(status), "delet") {
+		return nil, fmt.Errorf("The cluster %s already exists and is %s. Wait for it to be deleted, then try again", name, status)
+	}
+	common.Log.WriteInfo("The cluster %s already exists, skipping create", name)
+
+	var err error
+	if options.WaitUntilActive {
+		defer client.watchClusterStatus(account, cluster, options.progress)()
+		finishWait := client.trackPendingWait(account, "create", cluster, WaitForActive)
+		cluster, err = svc.WaitUntilClusterIsActive(cluster)
+		finishWait(err)
+		if err == nil {
+			client.recordClusterStatus(account, "wait", cluster)
+		}
+	}
+
+	return client.applyLabels(account, cluster), wrapClusterError(name, err)
+}
+
+// GetClusterAge returns how long ago a cluster was created. When the API doesn't report it,
+// the age is only known for clusters created by this client.
+func (client *Client) GetClusterAge(account Account, cluster common.Cluster) (time.Duration, bool) {
+	if created := cluster.GetCreated(); !created.IsZero() {
+		return time.Since(created), true
+	}
+
+	created, ok := client.Cache.getClusterCreated(account, cluster.GetID())
+	if !ok {
+		return 0, false
+	}
+	return time.Since(created), true
+}
+
+// checkTemplate warns when the template matching the specified pattern is deprecated or constrained,
+// or returns an error when deprecated templates are not allowed
+func checkTemplate(svc common.ClusterService, pattern string, allowDeprecated bool) error {
+	if pattern == "" {
+		return nil
+	}
+
+	templates, err := svc.ListClusterTemplates()
+	if err != nil {
+		common.Log.WriteDebug("Skipping the template checks, unable to list templates: %s", err)
+		return nil
+	}
+
+	matcher, err := common.NameMatchPolicy.NewMatcher(pattern)
+	if err != nil {
+		return err
+	}
+
+	var match common.ClusterTemplate
+	for _, template := range templates {
+		if !matcher.Matches(template.GetName()) {
+			continue
+		}
+
+		// Let the cluster service report ambiguous patterns
+		if match != nil {
+			return nil
+		}
+		match = template
+	}
+
+	if match == nil {
+		return nil
+	}
+
+	if availability := match.GetAvailability(); availability != nil && availability.Constrained {
+		common.Log.WriteStructuredWarning(common.WarningTemplateConstrained, "The template '%s' is %s. The cluster may fail to be created, check carina templates for a template with available capacity.", match.GetName(), availability)
+	}
+
+	if !match.IsDeprecated() {
+		return nil
+	}
+
+	if !allowDeprecated {
+		return common.DeprecatedTemplateError{TemplateName: match.GetName()}
+	}
+
+	common.Log.WriteStructuredWarning(common.WarningTemplateDeprecated, "The template '%s' is deprecated and will be removed in the future. Select a newer template from carina templates for new clusters.", match.GetName())
+	return nil
+}
+
+// DownloadClusterCredentials downloads the TLS certificates and configuration scripts for a cluster, returning where they were saved.
+// The credentials are saved by CredentialsStoragePolicy.Writer, to the credentials directory by default.
+func (client *Client) DownloadClusterCredentials(account Account, name string, customPath string) (location string, err error) {
+	return client.downloadClusterCredentialsTo(client.credentialsWriter(), account, name, customPath)
+}
+
+// downloadLocalClusterCredentials downloads a cluster's credentials to the credentials directory, or customPath,
+// regardless of the credentials backend, for operations which use the credentials afterwards
+func (client *Client) downloadLocalClusterCredentials(account Account, name string, customPath string) (credentialsPath string, err error) {
+	return client.downloadClusterCredentialsTo(&localCredentialsWriter{client: client}, account, name, customPath)
+}
+
+func (client *Client) downloadClusterCredentialsTo(writer CredentialsWriter, account Account, name string, customPath string) (string, error) {
 	defer client.Cache.SaveAccount(account)
 	svc, err := client.buildContainerService(account)
 	if err != nil {
 		return "", err
 	}
 
+	defer client.endOperation(client.startOperation(name, "Download credentials for cluster (%s)", name))
+
+	return client.writeClusterCredentials(svc, writer, account, name, customPath)
+}
+
+// downloadClusterCredentials downloads a cluster's credentials to the credentials directory, or customPath
+func (client *Client) downloadClusterCredentials(svc common.ClusterService, account Account, name string, customPath string) (credentialsPath string, err error) {
+	return client.writeClusterCredentials(svc, &localCredentialsWriter{client: client}, account, name, customPath)
+}
+
+// writeClusterCredentials downloads a cluster's credentials and saves them with the writer, recording their fingerprints
+func (client *Client) writeClusterCredentials(svc common.ClusterService, writer CredentialsWriter, account Account, name string, customPath string) (location string, err error) {
 	creds, err := svc.GetClusterCredentials(name)
 	if err != nil {
-		return "", wrapClientError(err)
+		return "", wrapClusterError(name, err)
 	}
 
-	credentialsPath, err = buildClusterCredentialsPath(account, name, customPath)
+	location, err = writer.WriteCredentials(account, name, creds.Files, customPath)
 	if err != nil {
-		return "", errors.Wrap(err, "Unable to save downloaded cluster credentials")
+		return "", err
 	}
 
-	// Ensure the credentials destination directory exists
-	if credentialsPath != "." {
-		err = os.MkdirAll(credentialsPath, 0777)
-		if err != nil {
-			return "", err
-		}
+	err = client.recordCredentialsFingerprint(account, name, creds.Files)
+	if err != nil {
+		common.Log.WriteStructuredWarning(common.WarningCredentialsChanged, "%s", err)
 	}
 
-	for file, fileContents := range creds.Files {
-		file = filepath.Join(credentialsPath, file)
-		err = ioutil.WriteFile(file, fileContents, 0600)
+	return location, nil
+}
+
+// ensureClusterCredentials returns the path to a cluster's credentials, downloading them if they are missing or invalid.
+// Encrypted credentials are decrypted to a temporary directory, which is wiped when carina exits.
+func (client *Client) ensureClusterCredentials(account Account, name string, customPath string) (credentialsPath string, err error) {
+	return client.ensureCredentials(account, name, customPath, client.decryptClusterCredentials)
+}
+
+// ensureSessionClusterCredentials returns the path to a cluster's credentials, like ensureClusterCredentials,
+// for credentials which are used after carina exits, e.g. by the shell. See decryptSessionCredentials.
+func (client *Client) ensureSessionClusterCredentials(account Account, name string, customPath string) (credentialsPath string, err error) {
+	return client.ensureCredentials(account, name, customPath, client.decryptSessionCredentials)
+}
+
+func (client *Client) ensureCredentials(account Account, name string, customPath string, decrypt func(credentialsPath string, customPath string) (string, error)) (credentialsPath string, err error) {
+	redownload := func() (string, error) {
+		credentialsPath, err := client.downloadLocalClusterCredentials(account, name, customPath)
 		if err != nil {
 			return "", err
 		}
+		return decrypt(credentialsPath, customPath)
 	}
 
-	return credentialsPath, nil
-}
-
-// GetSourceCommand returns the shell command and appropriate help text to load a cluster's credentials
-func (client *Client) GetSourceCommand(account Account, shell string, name string, customPath string) (sourceText string, err error) {
 	// We are ignoring errors here, and checking lower down if the creds are missing
-	credentialsPath, _ := buildClusterCredentialsPath(account, name, customPath)
+	credentialsPath, _ = buildClusterCredentialsPath(account, name, customPath)
+	credentialsPath, err = decrypt(credentialsPath, customPath)
+	if err == errNoSessionDir {
+		return "", err
+	}
+	if err != nil {
+		common.Log.WriteDebug("Unable to decrypt the credentials: %s", err)
+	}
 	creds := libcarina.LoadCredentialsBundle(credentialsPath)
 
 	// Re-download the credentials bundle, if the credentials are invalid
@@ -166,10 +458,31 @@ func (client *Client) GetSourceCommand(account Account, shell string, name strin
 		common.Log.Debug(err)
 		common.Log.Debugln("Re-downloading credentials due to missing or invalid credentials bundle.")
 
-		credentialsPath, err = client.DownloadClusterCredentials(account, name, customPath)
-		if err != nil {
-			return "", err
-		}
+		return redownload()
+	}
+
+	// Re-download the credentials bundle, if the client certificate has expired
+	cert, err := client.readCertificateInfo(credentialsPath, clientCertFilename)
+	if err != nil {
+		common.Log.WriteDebug("Skipping the certificate expiry check: %s", err)
+		return credentialsPath, nil
+	}
+	if cert.ExpiresWithin(0) {
+		common.Log.Debugln("Re-downloading credentials because the client certificate has expired.")
+		return redownload()
+	}
+	if cert.ExpiresWithin(certificateExpiryWarning) {
+		common.Log.WriteStructuredWarning(common.WarningCertificateExpiring, "%s", CertificateExpiringError{ClusterName: name, NotAfter: cert.NotAfter})
+	}
+
+	return credentialsPath, nil
+}
+
+// GetSourceCommand returns the shell command and appropriate help text to load a cluster's credentials
+func (client *Client) GetSourceCommand(account Account, shell string, name string, customPath string) (sourceText string, err error) {
+	credentialsPath, err := client.ensureSessionClusterCredentials(account, name, customPath)
+	if err != nil {
+		return "", err
 	}
 
 	shellScriptPath, err := getCredentialScriptPath(credentialsPath, shell)
@@ -181,41 +494,267 @@ func (client *Client) GetSourceCommand(account Account, shell string, name strin
 	return sourceText, nil
 }
 
-// ListClusters retrieves all clusters
-func (client *Client) ListClusters(account Account) ([]common.Cluster, error) {
+// ListClusters retrieves all clusters, optionally filtered and sorted
+func (client *Client) ListClusters(account Account, options ListClustersOptions) ([]common.Cluster, error) {
+	err := ValidateClusterSort(options.Sort)
+	if err != nil {
+		return nil, err
+	}
+
 	defer client.Cache.SaveAccount(account)
 	svc, err := client.buildContainerService(account)
 	if err != nil {
+		return client.fallbackToCachedClusters(account, options, err)
+	}
+
+	results, err := svc.ListClusters()
+	if err != nil {
+		return client.fallbackToCachedClusters(account, options, wrapClientError(err))
+	}
+
+	names := make([]string, len(results))
+	for i, cluster := range results {
+		results[i] = client.applyLabels(account, cluster)
+		names[i] = cluster.GetName()
+	}
+	client.recordClusterStatus(account, "list", results...)
+	client.Cache.saveClusterNames(account, names)
+	client.Cache.saveCachedClusters(account, results)
+
+	return filterClusters(results, options), nil
+}
+
+// fallbackToCachedClusters uses the last successful cluster listing when the API is unreachable
+func (client *Client) fallbackToCachedClusters(account Account, options ListClustersOptions, err error) ([]common.Cluster, error) {
+	if !isUnreachable(err) {
 		return nil, err
 	}
 
-	clusters, err := svc.ListClusters()
-	return clusters, wrapClientError(err)
+	results, updated, ok := client.Cache.getCachedClusters(account)
+	if !ok {
+		return nil, err
+	}
+	warnCachedListing("clusters", updated, err)
+
+	return filterClusters(results, options), nil
+}
+
+// ListClusterNames retrieves the names of all clusters, using the cached names when they were retrieved recently
+func (client *Client) ListClusterNames(account Account) ([]string, error) {
+	if names, ok := client.Cache.getClusterNames(account); ok {
+		return names, nil
+	}
+
+	clusters, err := client.ListClusters(account, ListClustersOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		names[i] = cluster.GetName()
+	}
+	return names, nil
+}
+
+// FindAmbiguousClusters retrieves the clusters named name when more than one cluster has the name, otherwise nil.
+// The APIs can't tell which of the clusters to use, so they must be referred to by id instead.
+// The clusters are only listed when the cached cluster names are stale or have the name more than once.
+func (client *Client) FindAmbiguousClusters(account Account, name string) ([]common.Cluster, error) {
+	if names, ok := client.Cache.getClusterNames(account); ok && countClusterName(names, name) < 2 {
+		return nil, nil
+	}
+
+	// The service lists the clusters from oldest to newest
+	clusters, err := client.ListClusters(account, ListClustersOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []common.Cluster
+	for _, cluster := range clusters {
+		if strings.EqualFold(cluster.GetName(), name) {
+			matches = append(matches, cluster)
+		}
+	}
+	if len(matches) < 2 {
+		return nil, nil
+	}
+	return matches, nil
+}
+
+// countClusterName returns how many of the cluster names are name, ignoring case like the APIs
+func countClusterName(names []string, name string) int {
+	var count int
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			count++
+		}
+	}
+	return count
 }
 
-// ListClusterTemplates retrieves available templates for creating a new cluster
-func (client *Client) ListClusterTemplates(account Account, nameFilter string) ([]common.ClusterTemplate, error) {
+// ListNodes retrieves the nodes in a cluster, for clouds which report the individual nodes
+func (client *Client) ListNodes(account Account, name string) ([]common.Node, error) {
 	defer client.Cache.SaveAccount(account)
 	svc, err := client.buildContainerService(account)
 	if err != nil {
 		return nil, err
 	}
 
+	nodes, err := listNodes(svc, name)
+	return nodes, wrapClusterError(name, err)
+}
+
+// listNodes retrieves the nodes in a cluster, returning an error when the cloud doesn't report the individual nodes, e.g. make-coe
+func listNodes(svc common.ClusterService, name string) ([]common.Node, error) {
+	lister, ok := svc.(common.NodeLister)
+	if !ok {
+		return nil, errors.New("Listing the nodes in a cluster is not supported by this cloud")
+	}
+	return lister.ListNodes(name)
+}
+
+// ResolveTemplate finds the template matching the COE, host type and version, for clouds which support selecting a template by its attributes
+func (client *Client) ResolveTemplate(account Account, selector common.TemplateSelector) (common.ClusterTemplate, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver, ok := svc.(common.TemplateResolver)
+	if !ok {
+		return nil, errors.New("Selecting a template with --coe, --host-type and --version is not supported by this cloud, use --template instead")
+	}
+
+	if selector.HostType != "" {
+		err = checkHostType(svc, selector.HostType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	template, err := resolver.ResolveTemplate(selector)
+	return template, wrapClientError(err)
+}
+
+// checkHostType returns an error when none of the templates are hosted on the host type, listing the host types which are offered
+func checkHostType(svc common.ClusterService, hostType string) error {
 	templates, err := svc.ListClusterTemplates()
+	if err != nil {
+		common.Log.WriteDebug("Skipping the host type check, unable to list templates: %s", err)
+		return nil
+	}
 
-	// Filter the templates by name, e.g. Kubernetes*
-	if err == nil && nameFilter != "" {
-		common.Log.WriteDebug("Filtering templates by pattern '%s'", nameFilter)
-		var filteredTemplates []common.ClusterTemplate
-		for _, template := range templates {
-			if glob.GlobI(nameFilter, template.GetName()) {
-				filteredTemplates = append(filteredTemplates, template)
-			}
+	hostTypes := listHostTypes(templates)
+	for _, offered := range hostTypes {
+		if strings.EqualFold(offered, hostType) {
+			return nil
+		}
+	}
+	return common.NotFoundError{Err: fmt.Errorf("The host type %s is not offered. Available host types: %s", hostType, strings.Join(hostTypes, ", "))}
+}
+
+// listHostTypes returns the distinct host types of the templates, sorted by name
+func listHostTypes(templates []common.ClusterTemplate) []string {
+	var hostTypes []string
+	seen := make(map[string]bool)
+	for _, template := range templates {
+		hostType := strings.ToLower(template.GetHostType())
+		if hostType == "" || seen[hostType] {
+			continue
+		}
+		seen[hostType] = true
+		hostTypes = append(hostTypes, hostType)
+	}
+	sort.Strings(hostTypes)
+	return hostTypes
+}
+
+// ListClusterTemplates retrieves available templates for creating a new cluster, filtered by name, COE and host type
+func (client *Client) ListClusterTemplates(account Account, options ListTemplatesOptions) ([]common.ClusterTemplate, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	templates, err := svc.ListClusterTemplates()
+	if err == nil {
+		client.Cache.saveCachedTemplates(account, templates)
+	} else if isUnreachable(err) {
+		// Use the last successful template listing when the API is unreachable
+		if cached, updated, ok := client.Cache.getCachedTemplates(account); ok {
+			warnCachedListing("templates", updated, err)
+			templates, err = cached, nil
+		}
+	}
+	if err != nil {
+		return nil, wrapClientError(err)
+	}
+
+	return filterTemplates(templates, options)
+}
+
+// filterTemplates returns the templates matching the name pattern, e.g. Kubernetes*, and the selector
+func filterTemplates(templates []common.ClusterTemplate, options ListTemplatesOptions) ([]common.ClusterTemplate, error) {
+	if options.Name == "" && options.Selector.IsEmpty() {
+		return templates, nil
+	}
+
+	var matcher common.Matcher
+	if options.Name != "" {
+		common.Log.WriteDebug("Filtering templates by %s pattern '%s'", common.NameMatchPolicy.Mode, options.Name)
+		var err error
+		matcher, err = common.NameMatchPolicy.NewMatcher(options.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !options.Selector.IsEmpty() {
+		common.Log.WriteDebug("Filtering templates by %s", options.Selector)
+	}
+
+	var filteredTemplates []common.ClusterTemplate
+	for _, template := range templates {
+		if matcher != nil && !matcher.Matches(template.GetName()) {
+			continue
+		}
+		if !options.Selector.Matches(template) {
+			continue
 		}
-		templates = filteredTemplates
+		filteredTemplates = append(filteredTemplates, template)
+	}
+	return filteredTemplates, nil
+}
+
+// GetClusterTemplate retrieves the template matching a name or pattern, e.g. Kubernetes*.
+// An exact name match is preferred, otherwise the pattern must match exactly one template.
+func (client *Client) GetClusterTemplate(account Account, name string) (common.ClusterTemplate, error) {
+	templates, err := client.ListClusterTemplates(account, ListTemplatesOptions{Name: name})
+	if err != nil {
+		return nil, err
 	}
 
-	return templates, wrapClientError(err)
+	for _, template := range templates {
+		if template.GetName() == name {
+			return template, nil
+		}
+	}
+
+	switch len(templates) {
+	case 0:
+		return nil, fmt.Errorf("No template matches %s. Run carina templates to see the available templates", name)
+	case 1:
+		return templates[0], nil
+	default:
+		names := make([]string, len(templates))
+		for i, template := range templates {
+			names[i] = template.GetName()
+		}
+		return nil, &common.MultipleMatchingTemplatesError{TemplatePattern: name, MatchingTemplates: names}
+	}
 }
 
 // GetCluster retrieves a cluster
@@ -226,33 +765,74 @@ func (client *Client) GetCluster(account Account, name string, waitUntilActive b
 		return nil, err
 	}
 
+	if waitUntilActive {
+		defer client.endOperation(client.startOperation(name, "Wait for cluster (%s) to become active", name))
+	}
+
 	cluster, err := svc.GetCluster(name)
+	if err == nil {
+		client.recordClusterStatus(account, "get", cluster)
+	}
 
 	if waitUntilActive && err == nil {
+		defer client.watchClusterStatus(account, cluster, nil)()
 		cluster, err = svc.WaitUntilClusterIsActive(cluster)
+		if err == nil {
+			client.recordClusterStatus(account, "wait", cluster)
+		}
 	}
 
-	return cluster, wrapClientError(err)
+	return client.applyLabels(account, cluster), wrapClusterError(name, err)
 }
 
 // GrowCluster adds nodes to a cluster
-func (client *Client) GrowCluster(account Account, name string, nodes int, waitUntilActive bool) (common.Cluster, error) {
+func (client *Client) GrowCluster(account Account, name string, nodes int, waitUntilActive bool) (cluster common.Cluster, err error) {
 	defer client.Cache.SaveAccount(account)
 	svc, err := client.buildContainerService(account)
 	if err != nil {
 		return nil, err
 	}
 
-	cluster, err := svc.GrowCluster(name, nodes)
+	err = client.checkSupported(svc, common.OperationGrow)
+	if err != nil {
+		return nil, err
+	}
+
+	defer client.endOperation(client.startOperation(name, "Grow cluster (%s) by %d nodes", name, nodes))
+	started := time.Now()
+	defer func() {
+		client.audit(account, "grow", name, map[string]string{"nodes": strconv.Itoa(nodes)}, cluster, err)
+		if waitUntilActive {
+			client.notifyWaitFinished(account, "grow", name, started, cluster, err)
+		}
+	}()
+
+	unlock, err := client.lockCluster(account, name, "grow")
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+	defer unlock()
+
+	cluster, err = svc.GrowCluster(name, nodes)
+	if err == nil {
+		client.recordClusterStatus(account, "grow", cluster)
+	}
 
 	if waitUntilActive && err == nil {
+		defer client.watchClusterStatus(account, cluster, nil)()
+		finishWait := client.trackPendingWait(account, "grow", cluster, WaitForActive)
 		cluster, err = svc.WaitUntilClusterIsActive(cluster)
+		finishWait(err)
+		if err == nil {
+			client.recordClusterStatus(account, "wait", cluster)
+		}
 	}
 
-	return cluster, wrapClientError(err)
+	return cluster, wrapClusterError(name, err)
 }
 
-// ResizeCluster resizes the cluster to the specified number of nodes
+// ResizeCluster resizes the cluster to the specified number of nodes.
+// When waiting, the cluster must also report the requested number of nodes once it is active.
 func (client *Client) ResizeCluster(account Account, name string, nodes int, waitUntilActive bool) (common.Cluster, error) {
 	defer client.Cache.SaveAccount(account)
 	svc, err := client.buildContainerService(account)
@@ -260,42 +840,117 @@ func (client *Client) ResizeCluster(account Account, name string, nodes int, wai
 		return nil, err
 	}
 
-	cluster, err := svc.ResizeCluster(name, nodes)
+	err = client.checkSupported(svc, common.OperationResize)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.resizeCluster(svc, account, name, nodes, waitUntilActive)
+}
+
+func (client *Client) resizeCluster(svc common.ClusterService, account Account, name string, nodes int, waitUntilActive bool) (cluster common.Cluster, err error) {
+	defer client.endOperation(client.startOperation(name, "Resize cluster (%s) to %d nodes", name, nodes))
+	started := time.Now()
+	defer func() {
+		client.audit(account, "resize", name, map[string]string{"nodes": strconv.Itoa(nodes)}, cluster, err)
+		client.runHook(account, "resize", name, cluster, waitUntilActive, err)
+		if waitUntilActive {
+			client.notifyWaitFinished(account, "resize", name, started, cluster, err)
+		}
+	}()
+
+	unlock, err := client.lockCluster(account, name, "resize")
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+	defer unlock()
+
+	cluster, err = svc.ResizeCluster(name, nodes)
+	if err == nil {
+		client.recordClusterStatus(account, "resize", cluster)
+	}
 
 	if waitUntilActive && err == nil {
+		defer client.watchClusterStatus(account, cluster, nil)()
+		finishWait := client.trackPendingWait(account, "resize", cluster, WaitForActive)
 		cluster, err = svc.WaitUntilClusterIsActive(cluster)
+		finishWait(err)
+		if err == nil {
+			client.recordClusterStatus(account, "wait", cluster)
+			cluster, err = verifyClusterNodes(svc, cluster, nodes)
+		}
 	}
 
-	return cluster, wrapClientError(err)
+	return cluster, wrapClusterError(name, err)
 }
 
 // RebuildCluster destroys and recreates the cluster
-func (client *Client) RebuildCluster(account Account, name string, waitUntilActive bool) (common.Cluster, error) {
+func (client *Client) RebuildCluster(account Account, name string, waitUntilActive bool) (cluster common.Cluster, err error) {
 	defer client.Cache.SaveAccount(account)
 	svc, err := client.buildContainerService(account)
 	if err != nil {
 		return nil, err
 	}
 
-	cluster, err := svc.RebuildCluster(name)
+	err = client.checkSupported(svc, common.OperationRebuild)
+	if err != nil {
+		return nil, err
+	}
+
+	defer client.endOperation(client.startOperation(name, "Rebuild cluster (%s)", name))
+	started := time.Now()
+	defer func() {
+		client.audit(account, "rebuild", name, nil, cluster, err)
+		if waitUntilActive {
+			client.notifyWaitFinished(account, "rebuild", name, started, cluster, err)
+		}
+	}()
+
+	unlock, err := client.lockCluster(account, name, "rebuild")
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+	defer unlock()
+
+	cluster, err = svc.RebuildCluster(name)
+	if err == nil {
+		client.recordClusterStatus(account, "rebuild", cluster)
+	}
 
 	if waitUntilActive && err == nil {
+		defer client.watchClusterStatus(account, cluster, nil)()
+		finishWait := client.trackPendingWait(account, "rebuild", cluster, WaitForActive)
 		cluster, err = svc.WaitUntilClusterIsActive(cluster)
+		finishWait(err)
+		if err == nil {
+			client.recordClusterStatus(account, "wait", cluster)
+		}
 	}
 
-	return cluster, wrapClientError(err)
+	return cluster, wrapClusterError(name, err)
 }
 
-// SetAutoScale adds nodes to a cluster
-func (client *Client) SetAutoScale(account Account, name string, value bool) (common.Cluster, error) {
+// SetAutoScale changes the autoscaling settings on a cluster
+func (client *Client) SetAutoScale(account Account, name string, autoscale common.AutoScale) (common.Cluster, error) {
 	defer client.Cache.SaveAccount(account)
 	svc, err := client.buildContainerService(account)
 	if err != nil {
 		return nil, err
 	}
 
-	cluster, err := svc.SetAutoScale(name, value)
-	return cluster, wrapClientError(err)
+	err = client.checkSupported(svc, common.OperationAutoScale)
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := client.lockCluster(account, name, "autoscale")
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+	defer unlock()
+
+	cluster, err := svc.SetAutoScale(name, autoscale)
+	return cluster, wrapClusterError(name, err)
 }
 
 // DeleteCluster deletes a cluster
@@ -306,17 +961,55 @@ func (client *Client) DeleteCluster(account Account, name string, waitUntilDelet
 		return err
 	}
 
-	cluster, err := svc.DeleteCluster(name)
+	return client.deleteCluster(svc, account, name, name, waitUntilDeleted)
+}
+
+// deleteCluster deletes a cluster and removes everything stored locally for it.
+// The cluster is deleted by token, its id or name, and name is used for the local state, e.g. its credentials.
+func (client *Client) deleteCluster(svc common.ClusterService, account Account, name string, token string, waitUntilDeleted bool) (err error) {
+	defer client.endOperation(client.startOperation(name, "Delete cluster (%s)", name))
+	var cluster common.Cluster
+	started := time.Now()
+	defer func() {
+		client.audit(account, "delete", name, nil, cluster, err)
+		client.runHook(account, "delete", name, cluster, waitUntilDeleted, err)
+		if waitUntilDeleted {
+			client.notifyWaitFinished(account, "delete", name, started, cluster, err)
+		}
+	}()
+
+	unlock, err := client.lockCluster(account, name, "delete")
+	if err != nil {
+		return wrapClusterError(name, err)
+	}
+	defer unlock()
+
+	cluster, err = svc.DeleteCluster(token)
+	if err == nil {
+		client.recordClusterStatus(account, "delete", cluster)
+	}
 
 	if waitUntilDeleted && err == nil {
+		defer client.watchClusterStatus(account, cluster, nil)()
+		finishWait := client.trackPendingWait(account, "delete", cluster, WaitForDeleted)
 		err = svc.WaitUntilClusterIsDeleted(cluster)
+		finishWait(err)
+		if err == nil {
+			client.recordClusterDeleted(account, cluster)
+		}
+	}
+
+	if err == nil && cluster.GetID() != "" {
+		client.Cache.DeleteClusterLabels(account, cluster.GetID())
+		client.Cache.deleteClusterCreated(account, cluster.GetID())
 	}
 
 	if err == nil {
+		client.Cache.deleteFingerprints(account, name)
 		err = client.DeleteClusterCredentials(account, name, "")
 	}
 
-	return wrapClientError(err)
+	return wrapClusterError(name, err)
 }
 
 // DeleteClusterCredentials removes a cluster's downloaded credentials
@@ -349,5 +1042,12 @@ func (client *Client) DeleteClusterCredentials(account Account, name string, cus
 		return errors.Wrap(err, "Unable to delete the credentials on disk")
 	}
 
+	// Remove the decrypted copy used by docker and kubectl
+	if customPath == "" {
+		if runtimePath, err := buildRuntimeCredentialsPath(p); err == nil {
+			os.RemoveAll(runtimePath)
+		}
+	}
+
 	return nil
 }
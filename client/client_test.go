@@ -2,10 +2,13 @@ package client_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/getcarina/carina/client"
 	"github.com/getcarina/carina/common"
 	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -19,8 +22,8 @@ func TestFilterTemplatesByName(t *testing.T) {
 	account := new(testhelpers.MockAccount)
 	account.On("NewClusterService").Return(service, nil)
 
-	client := client.NewClient(false)
-	templates, err := client.ListClusterTemplates(account, "Kubernetes*")
+	c := client.NewClient(false)
+	templates, err := c.ListClusterTemplates(account, client.ListTemplatesOptions{Name: "Kubernetes*"})
 	if err != nil {
 		t.Error(err)
 		return
@@ -38,8 +41,8 @@ func TestFilterTemplatesByNameIsCaseInsensitive(t *testing.T) {
 	account := new(testhelpers.MockAccount)
 	account.On("NewClusterService").Return(service, nil)
 
-	client := client.NewClient(false)
-	templates, err := client.ListClusterTemplates(account, "*noises")
+	c := client.NewClient(false)
+	templates, err := c.ListClusterTemplates(account, client.ListTemplatesOptions{Name: "*noises"})
 	if err != nil {
 		t.Error(err)
 		return
@@ -47,3 +50,384 @@ func TestFilterTemplatesByNameIsCaseInsensitive(t *testing.T) {
 
 	assert.Len(t, templates, 1)
 }
+
+func TestFilterTemplatesByRegex(t *testing.T) {
+	common.NameMatchPolicy.Mode = common.MatchRegex
+	defer func() { common.NameMatchPolicy.Mode = common.MatchGlob }()
+
+	service := new(testhelpers.MockClusterService)
+	service.On("ListClusterTemplates").Return([]common.ClusterTemplate{
+		&testhelpers.StubClusterTemplate{Name: "Kubernetes 1.4.5 on LXC"},
+		&testhelpers.StubClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"},
+		&testhelpers.StubClusterTemplate{Name: "Swarm 1.11.2 on LXC"},
+	})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service, nil)
+
+	c := client.NewClient(false)
+	templates, err := c.ListClusterTemplates(account, client.ListTemplatesOptions{Name: `Kubernetes 1\.5\..*`})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if assert.Len(t, templates, 1) {
+		assert.Equal(t, "Kubernetes 1.5.2 on LXC", templates[0].GetName())
+	}
+}
+
+func TestFilterTemplatesByCOEAndHostType(t *testing.T) {
+
+	service := new(testhelpers.MockClusterService)
+	service.On("ListClusterTemplates").Return([]common.ClusterTemplate{
+		&testhelpers.StubClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes", HostType: "lxc"},
+		&testhelpers.StubClusterTemplate{Name: "Kubernetes 1.5.2 on VM", COE: "kubernetes", HostType: "vm"},
+		&testhelpers.StubClusterTemplate{Name: "Swarm 1.11.2 on LXC", COE: "swarm", HostType: "lxc"},
+	})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service, nil)
+
+	c := client.NewClient(false)
+	templates, err := c.ListClusterTemplates(account, client.ListTemplatesOptions{Selector: common.TemplateSelector{COE: "Kubernetes", HostType: "lxc"}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if assert.Len(t, templates, 1) {
+		assert.Equal(t, "Kubernetes 1.5.2 on LXC", templates[0].GetName())
+	}
+}
+
+func TestGetClusterTemplatePrefersExactName(t *testing.T) {
+
+	service := new(testhelpers.MockClusterService)
+	service.On("ListClusterTemplates").Return([]common.ClusterTemplate{
+		&testhelpers.StubClusterTemplate{Name: "Kubernetes"},
+		&testhelpers.StubClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"},
+	})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service, nil)
+
+	client := client.NewClient(false)
+	template, err := client.GetClusterTemplate(account, "Kubernetes")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Kubernetes", template.GetName())
+	}
+
+	_, err = client.GetClusterTemplate(account, "Kubernetes*")
+	assert.IsType(t, &common.MultipleMatchingTemplatesError{}, err)
+}
+
+func TestCreateClusterWithDeprecatedTemplate(t *testing.T) {
+
+	service := new(testhelpers.MockClusterService)
+	service.On("ListClusterTemplates").Return([]common.ClusterTemplate{
+		&testhelpers.StubClusterTemplate{Name: "Kubernetes 1.4.5 on LXC", Deprecated: true},
+		&testhelpers.StubClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"},
+	})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service, nil)
+
+	options := client.CreateClusterOptions{AllowDeprecated: false}
+	client := client.NewClient(false)
+	_, err := client.CreateCluster(account, "mycluster", "Kubernetes 1.4.5*", 1, options)
+
+	assert.IsType(t, common.DeprecatedTemplateError{}, err)
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := client.ParseLabels([]string{"env=prod", "team = web"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	assert.Equal(t, map[string]string{"env": "prod", "team": "web"}, labels)
+}
+
+func TestParseLabelsRequiresKeyValuePairs(t *testing.T) {
+	_, err := client.ParseLabels([]string{"env"})
+	assert.NotNil(t, err)
+}
+
+func TestGetUnsetCommand(t *testing.T) {
+	for _, shell := range []string{"bash", "fish", "powershell", "cmd"} {
+		unsetText, err := client.GetUnsetCommand(shell)
+		assert.Nil(t, err, shell)
+		assert.Contains(t, unsetText, "DOCKER_HOST", shell)
+		assert.Contains(t, unsetText, "KUBECONFIG", shell)
+	}
+
+	_, err := client.GetUnsetCommand("tcsh")
+	assert.NotNil(t, err)
+}
+
+func TestGetClusterCredentialFile(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("mycluster", "Swarm*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	client := client.NewClient(false)
+	file, contents, err := client.GetClusterCredentialFile(account, "mycluster", "ca")
+	assert.Nil(t, err)
+	assert.Equal(t, "ca.pem", file)
+	assert.Equal(t, "fake-ca", string(contents))
+
+	_, _, err = client.GetClusterCredentialFile(account, "mycluster", "kubeconfig")
+	assert.NotNil(t, err)
+}
+
+func TestDeleteClustersMatchingPattern(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	for _, name := range []string{"test-1", "test-2", "prod"} {
+		service.CreateCluster(name, "Swarm*", 1)
+	}
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	client := client.NewClient(false)
+	matches, err := client.MatchClusters(account, "test-*")
+	assert.Nil(t, err)
+	assert.Len(t, matches, 2)
+
+	results, err := client.DeleteClusters(account, []string{"test-1", "test-2", "missing"}, false)
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	assert.Nil(t, results[0].Err)
+	assert.Nil(t, results[1].Err)
+	assert.NotNil(t, results[2].Err)
+
+	deleted, _ := service.GetCluster("test-1")
+	assert.Equal(t, testsupport.StatusDeleting, deleted.GetStatus())
+}
+
+func TestDeleteMatchedClustersByID(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("test", "Swarm*", 1)
+	service.CreateCluster("test", "Swarm*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	client := client.NewClient(false)
+	matches, err := client.MatchClusters(account, "test")
+	assert.Nil(t, err)
+	assert.Len(t, matches, 2)
+
+	// The names are ambiguous, so each cluster must be deleted by its id
+	results, err := client.DeleteMatchedClusters(account, matches, false)
+	assert.Nil(t, err)
+	if assert.Len(t, results, 2) {
+		assert.Nil(t, results[0].Err)
+		assert.Nil(t, results[1].Err)
+	}
+
+	for _, match := range matches {
+		deleted, _ := service.GetCluster(match.GetID())
+		assert.Equal(t, testsupport.StatusDeleting, deleted.GetStatus())
+	}
+}
+
+func TestCreateClustersConcurrently(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	service.CreateCluster("existing", "Kubernetes*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	names := []string{"workshop-1", "workshop-2", "workshop-3"}
+	results, err := c.CreateClusters(account, names, "Kubernetes*", 2, client.CreateClusterOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+
+	var failed int
+	for i, result := range results {
+		assert.Equal(t, names[i], result.Name, "The results should be in the order specified")
+		if result.Err != nil {
+			failed++
+			continue
+		}
+		assert.Equal(t, testsupport.StatusCreating, result.Message)
+
+		cluster, err := service.GetCluster(result.Name)
+		assert.Nil(t, err)
+		assert.Equal(t, "2", cluster.GetNodes())
+	}
+	assert.Equal(t, 1, failed, "The quota of 3 clusters should be exceeded by one cluster")
+}
+
+func TestCreateClusterWithKeypair(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.Keypairs = []*testsupport.FakeKeypair{{Name: "alice", Fingerprint: "aa:bb:cc"}}
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	keypairs, err := c.ListKeypairs(account)
+	assert.Nil(t, err)
+	if assert.Len(t, keypairs, 1) {
+		assert.Equal(t, "alice", keypairs[0].GetName())
+	}
+
+	cluster, err := c.CreateCluster(account, "mycluster", "Swarm*", 1, client.CreateClusterOptions{SSHKey: common.SSHKey{KeypairName: "alice"}})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "alice", cluster.(*testsupport.FakeCluster).SSHKey.KeypairName)
+	}
+
+	_, err = c.CreateCluster(account, "other", "Swarm*", 1, client.CreateClusterOptions{SSHKey: common.SSHKey{KeypairName: "bob"}})
+	assert.NotNil(t, err, "The keypair does not exist")
+}
+
+func TestSetAutoScale(t *testing.T) {
+	service := testsupport.NewFakeClusterService(
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on VM", SupportsAutoScale: true},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	service.CreateCluster("scalable", "*VM", 3)
+	service.CreateCluster("fixed", "*LXC", 3)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	cluster, err := c.SetAutoScale(account, "scalable", common.AutoScale{Enabled: true, MinNodes: 3, MaxNodes: 10})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "on (3-10 nodes)", cluster.GetAutoScale().String())
+	}
+
+	_, err = c.SetAutoScale(account, "fixed", common.AutoScale{Enabled: true})
+	assert.NotNil(t, err, "The template doesn't support autoscaling")
+
+	fixed, _ := service.GetCluster("fixed")
+	assert.Nil(t, fixed.GetAutoScale())
+}
+
+func TestResizeClusterVerifiesNodes(t *testing.T) {
+	pollInterval := common.ClusterWaitPolicy.PollInterval
+	common.ClusterWaitPolicy.PollInterval = time.Millisecond
+	defer func() { common.ClusterWaitPolicy.PollInterval = pollInterval }()
+
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("mycluster", "Swarm*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	cluster, err := c.ResizeCluster(account, "mycluster", 2, true)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "2", cluster.GetNodes())
+	}
+
+	service.IgnoreResize = true
+	_, err = c.ResizeCluster(account, "mycluster", 3, true)
+	if assert.NotNil(t, err, "A resize which didn't change the cluster should fail") {
+		assert.Contains(t, err.Error(), "requested 3 nodes but the cluster reports 2 nodes")
+	}
+
+	_, err = c.ResizeCluster(account, "mycluster", 3, false)
+	assert.Nil(t, err, "The nodes are only verified when waiting")
+}
+
+func TestCreateClusterIfNotExists(t *testing.T) {
+	pollInterval := common.ClusterWaitPolicy.PollInterval
+	common.ClusterWaitPolicy.PollInterval = time.Millisecond
+	defer func() { common.ClusterWaitPolicy.PollInterval = pollInterval }()
+
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	options := client.CreateClusterOptions{IfNotExists: true, WaitUntilActive: true}
+	created, err := c.CreateCluster(account, "mycluster", "Swarm*", 1, options)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	existing, err := c.CreateCluster(account, "mycluster", "Swarm*", 1, options)
+	if assert.Nil(t, err, "An existing cluster should be returned instead of an error") {
+		assert.Equal(t, created.GetID(), existing.GetID())
+		assert.Equal(t, testsupport.StatusActive, existing.GetStatus())
+	}
+
+	clusters, _ := service.ListClusters()
+	assert.Len(t, clusters, 1, "A second cluster should not be created")
+
+	service.FailCluster("mycluster", "out of capacity")
+	_, err = c.CreateCluster(account, "mycluster", "Swarm*", 1, options)
+	if assert.NotNil(t, err, "A failed cluster should not be reused") {
+		assert.Contains(t, err.Error(), "carina repair mycluster")
+	}
+}
+
+func TestListNodes(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on CoreOS"})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	_, err := c.CreateCluster(account, "mycluster", "Swarm*", 2, client.CreateClusterOptions{})
+	assert.Nil(t, err)
+
+	nodes, err := c.ListNodes(account, "mycluster")
+	assert.Nil(t, err)
+	if assert.Len(t, nodes, 2) {
+		assert.Equal(t, "mycluster-node-0", nodes[0].GetName())
+		assert.Equal(t, "10.0.0.1", nodes[0].GetAddress())
+	}
+
+	_, err = c.ListNodes(account, "missing")
+	assert.NotNil(t, err)
+
+	// make-coe doesn't report the individual nodes in a cluster
+	unsupported := new(testhelpers.MockAccount)
+	unsupported.On("NewClusterService").Return(new(testhelpers.MockClusterService))
+	_, err = c.ListNodes(unsupported, "mycluster")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "not supported by this cloud")
+	}
+}
+
+func TestBuildSSHArgs(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on CoreOS"})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	_, err := c.CreateCluster(account, "mycluster", "Swarm*", 2, client.CreateClusterOptions{})
+	assert.Nil(t, err)
+
+	args, err := c.BuildSSHArgs(account, "mycluster", client.SSHOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"-l", "core", "-t", "10.0.0.1"}, args)
+
+	args, err = c.BuildSSHArgs(account, "mycluster", client.SSHOptions{Node: "mycluster-node-1", User: "alice", IdentityFile: "id_rsa", Command: "docker ps"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"-l", "alice", "-i", "id_rsa", "10.0.0.2", "--", "docker ps"}, args)
+
+	_, err = c.BuildSSHArgs(account, "mycluster", client.SSHOptions{Node: "missing"})
+	assert.Contains(t, err.Error(), "mycluster-node-0", "The error should list the available nodes")
+}
+
+func TestFindAmbiguousClusters(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	for _, name := range []string{"web", "web", "db"} {
+		service.CreateCluster(name, "Swarm*", 1)
+	}
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	matches, err := c.FindAmbiguousClusters(account, "WEB")
+	assert.Nil(t, err)
+	assert.Len(t, matches, 2)
+
+	matches, err = c.FindAmbiguousClusters(account, "db")
+	assert.Nil(t, err)
+	assert.Empty(t, matches, "A unique name is not ambiguous")
+
+	_, err = c.GetCluster(account, "web", false)
+	ambiguous, ok := errors.Cause(err).(*common.MultipleMatchingClustersError)
+	if assert.True(t, ok, "Expected a MultipleMatchingClustersError, got %v", err) {
+		assert.Len(t, ambiguous.MatchingClusters, 2)
+	}
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// The command line clients used to connect to clusters
+const (
+	DockerTool  = "docker"
+	KubectlTool = "kubectl"
+)
+
+// dockerNegotiatesAPIVersion is the first docker client which negotiates the API version with the daemon,
+// older clients fail with "client is newer than server" when connected to an older daemon
+const dockerNegotiatesAPIVersion = "17.05"
+
+// ClientCompatibility is the result of comparing the version of the locally installed docker or kubectl with the cluster's COE version
+type ClientCompatibility struct {
+	Tool          string
+	ClientVersion string
+	ServerVersion string
+	Compatible    bool
+}
+
+// clientVersionArgs are the arguments which print the version of a client
+var clientVersionArgs = map[string][]string{
+	DockerTool:  {"version", "--format", "{{.Client.Version}}"},
+	KubectlTool: {"version", "--client"},
+}
+
+var versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// lookupClientVersion returns the version of the client on the PATH, or an empty string when it isn't installed
+var lookupClientVersion = func(tool string) (string, error) {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return "", nil
+	}
+
+	output, err := exec.Command(path, clientVersionArgs[tool]...).Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to determine the version of %s", path)
+	}
+	return versionPattern.FindString(string(output)), nil
+}
+
+// ToolForCOE returns the client used to connect to clusters with the container orchestration engine, or an empty string when it is unknown
+func ToolForCOE(coe string) string {
+	switch strings.ToLower(coe) {
+	case "kubernetes":
+		return KubectlTool
+	case "swarm", "swarm-mode":
+		return DockerTool
+	default:
+		return ""
+	}
+}
+
+// ClusterClientVersion returns the client used to connect to a cluster, and the version of the client which matches the cluster.
+// The version is read from the cluster's template, or for docker clusters, from DOCKER_VERSION in the credentials script.
+func (client *Client) ClusterClientVersion(account Account, name string, credentialsPath string) (tool string, version string, err error) {
+	cluster, err := client.GetCluster(account, name, false)
+	if err != nil {
+		return "", "", err
+	}
+
+	template := cluster.GetTemplate()
+	if template == nil {
+		return "", "", nil
+	}
+	tool = ToolForCOE(template.GetCOE())
+	version = template.GetCOEVersion()
+
+	if version == "" && tool == DockerTool && credentialsPath != "" {
+		variables, err := readCredentialScriptVariables(credentialsPath)
+		if err != nil {
+			common.Log.WriteDebug("Unable to read DOCKER_VERSION from the credentials: %s", err)
+		}
+		for _, variable := range variables {
+			if variable.Name == "DOCKER_VERSION" {
+				version = versionPattern.FindString(variable.Value)
+			}
+		}
+	}
+
+	return tool, version, nil
+}
+
+// CheckClientCompatibility compares the version of the docker or kubectl client on the PATH with the version of a cluster.
+// Returns nil when the client isn't installed, or either version is unknown.
+func (client *Client) CheckClientCompatibility(account Account, name string, credentialsPath string) (*ClientCompatibility, error) {
+	tool, serverVersion, err := client.ClusterClientVersion(account, name, credentialsPath)
+	if err != nil || tool == "" || serverVersion == "" {
+		return nil, err
+	}
+
+	clientVersion, err := lookupClientVersion(tool)
+	if err != nil || clientVersion == "" {
+		return nil, err
+	}
+
+	return &ClientCompatibility{
+		Tool:          tool,
+		ClientVersion: clientVersion,
+		ServerVersion: serverVersion,
+		Compatible:    isClientCompatible(tool, clientVersion, serverVersion),
+	}, nil
+}
+
+// isClientCompatible applies the version support policy of each client:
+// kubectl supports one minor version older or newer than the cluster, and
+// docker clients older than 17.05 can't connect to an older daemon.
+func isClientCompatible(tool string, clientVersion string, serverVersion string) bool {
+	clientMajor, clientMinor := splitMinorVersion(clientVersion)
+	serverMajor, serverMinor := splitMinorVersion(serverVersion)
+
+	switch tool {
+	case KubectlTool:
+		skew := clientMinor - serverMinor
+		return clientMajor == serverMajor && skew >= -1 && skew <= 1
+	case DockerTool:
+		if common.CompareVersions(clientVersion, dockerNegotiatesAPIVersion) >= 0 {
+			return true
+		}
+		return clientMajor < serverMajor || (clientMajor == serverMajor && clientMinor <= serverMinor)
+	default:
+		return true
+	}
+}
+
+// splitMinorVersion returns the major and minor components of a version, e.g. 1 and 5 for 1.5.2
+func splitMinorVersion(version string) (major int, minor int) {
+	parts := strings.SplitN(version, ".", 3)
+	major, _ = strconv.Atoi(parts[0])
+	if len(parts) > 1 {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	return major, minor
+}
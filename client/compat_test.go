@@ -0,0 +1,87 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsClientCompatible(t *testing.T) {
+	assert.True(t, isClientCompatible(KubectlTool, "1.6.0", "1.5.2"), "kubectl supports one minor version of skew")
+	assert.True(t, isClientCompatible(KubectlTool, "1.4.7", "1.5.2"))
+	assert.False(t, isClientCompatible(KubectlTool, "1.7.0", "1.5.2"))
+	assert.False(t, isClientCompatible(KubectlTool, "1.3.0", "1.5.2"))
+
+	assert.True(t, isClientCompatible(DockerTool, "1.10.3", "1.11.2"), "Older docker clients use an API the daemon supports")
+	assert.False(t, isClientCompatible(DockerTool, "1.12.1", "1.11.2"), "Newer docker clients can't connect to an older daemon")
+	assert.True(t, isClientCompatible(DockerTool, "17.06.0-ce", "1.11.2"), "Newer docker clients negotiate the API version")
+}
+
+func TestCheckClientCompatibility(t *testing.T) {
+	defer func(lookup func(string) (string, error)) { lookupClientVersion = lookup }(lookupClientVersion)
+	lookupClientVersion = func(tool string) (string, error) {
+		return map[string]string{KubectlTool: "1.8.0"}[tool], nil
+	}
+
+	service := testsupport.NewFakeClusterService(
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes", COEVersion: "1.5.2"},
+		&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC", COE: "swarm", COEVersion: "1.11.2"})
+	account := &historyAccount{offlineAccount{service: service}}
+	client := &Client{Cache: &Cache{}}
+	_, err := service.CreateCluster("k8s", "Kubernetes 1.5.2 on LXC", 1)
+	require.NoError(t, err)
+	_, err = service.CreateCluster("swarm", "Swarm 1.11.2 on LXC", 1)
+	require.NoError(t, err)
+
+	result, err := client.CheckClientCompatibility(account, "k8s", "")
+	require.NoError(t, err)
+	assert.Equal(t, &ClientCompatibility{Tool: KubectlTool, ClientVersion: "1.8.0", ServerVersion: "1.5.2", Compatible: false}, result)
+
+	result, err = client.CheckClientCompatibility(account, "swarm", "")
+	require.NoError(t, err)
+	assert.Nil(t, result, "docker isn't installed")
+}
+
+func TestBuildClientDownloadURL(t *testing.T) {
+	url, archived, err := buildClientDownloadURL(KubectlTool, "1.5.2", "windows", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "https://storage.googleapis.com/kubernetes-release/release/v1.5.2/bin/windows/amd64/kubectl.exe", url)
+	assert.False(t, archived)
+
+	url, archived, err = buildClientDownloadURL(DockerTool, "1.11.2", "darwin", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "https://get.docker.com/builds/Darwin/x86_64/docker-1.11.2.tgz", url)
+	assert.True(t, archived)
+
+	url, _, err = buildClientDownloadURL(DockerTool, "17.06.0-ce", "windows", "amd64")
+	require.NoError(t, err)
+	assert.Equal(t, "https://download.docker.com/win/static/stable/x86_64/docker-17.06.0-ce.zip", url)
+
+	_, _, err = buildClientDownloadURL(DockerTool, "1.11.2", "linux", "arm")
+	assert.Error(t, err)
+}
+
+func TestExtractClientBinary(t *testing.T) {
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for name, contents := range map[string]string{"docker/docker": "CLIENT", "docker/dockerd": "DAEMON"} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	contents, err := extractClientBinary(archive.Bytes(), false, "docker")
+	require.NoError(t, err)
+	assert.Equal(t, "CLIENT", string(contents))
+
+	_, err = extractClientBinary(archive.Bytes(), false, "docker.exe")
+	assert.Error(t, err)
+}
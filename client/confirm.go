@@ -0,0 +1,152 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+)
+
+// ConfirmableOperations are the commands which change a cluster, and can be named by a confirm rule.
+// rotate-ca is the cluster action which replaces a cluster's certificate authority, see carina action.
+var ConfirmableOperations = []string{"delete", "grow", "rebuild", "resize", "upgrade", rotateCAActionName}
+
+// ConfirmationRule describes when an operation requires confirmation, for example
+// deleting any cluster, resizing above 10 nodes or rebuilding a cluster labeled env=prod
+type ConfirmationRule struct {
+	// Operation is the name of the command, such as delete, resize, grow, rebuild or upgrade
+	Operation string
+
+	// NodesAbove only requires confirmation when the cluster will have more than the specified number of nodes
+	NodesAbove int
+
+	// Labels only requires confirmation for clusters with all of the key/value labels
+	Labels map[string]string
+}
+
+// ConfirmationPolicy is the set of rules which determine the operations that require confirmation
+type ConfirmationPolicy []ConfirmationRule
+
+// String describes why the rule requires confirmation
+func (rule ConfirmationRule) String() string {
+	conditions := []string{rule.Operation}
+	if rule.NodesAbove > 0 {
+		conditions = append(conditions, fmt.Sprintf("above %d nodes", rule.NodesAbove))
+	}
+	if len(rule.Labels) > 0 {
+		var labels []string
+		for key, value := range rule.Labels {
+			labels = append(labels, key+"="+value)
+		}
+		conditions = append(conditions, "on clusters labeled "+strings.Join(labels, ","))
+	}
+	return strings.Join(conditions, " ")
+}
+
+// Matches returns if the rule requires confirmation for an operation on a cluster,
+// which will have the specified number of nodes afterwards
+func (rule ConfirmationRule) Matches(operation string, cluster common.Cluster, nodes int) bool {
+	if !strings.EqualFold(rule.Operation, operation) {
+		return false
+	}
+	if rule.NodesAbove > 0 && nodes <= rule.NodesAbove {
+		return false
+	}
+	if len(rule.Labels) > 0 && (cluster == nil || !MatchesLabels(cluster, rule.Labels)) {
+		return false
+	}
+	return true
+}
+
+// AppliesTo returns if any rule could require confirmation for the operation
+func (policy ConfirmationPolicy) AppliesTo(operation string) bool {
+	for _, rule := range policy {
+		if strings.EqualFold(rule.Operation, operation) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the first rule which requires confirmation for an operation on a cluster
+func (policy ConfirmationPolicy) Find(operation string, cluster common.Cluster, nodes int) (ConfirmationRule, bool) {
+	for _, rule := range policy {
+		if rule.Matches(operation, cluster, nodes) {
+			return rule, true
+		}
+	}
+	return ConfirmationRule{}, false
+}
+
+// ParseConfirmationPolicy converts the confirm rules from the config file, e.g.
+//
+//	[[confirm]]
+//	operation="resize"
+//	nodes-above=10
+//	label="env=prod"
+func ParseConfirmationPolicy(value interface{}) (ConfirmationPolicy, error) {
+	var rawRules []map[string]interface{}
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []map[string]interface{}:
+		rawRules = v
+	case []interface{}:
+		for _, item := range v {
+			rawRule, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("Invalid confirm rule: %v", item)
+			}
+			rawRules = append(rawRules, rawRule)
+		}
+	default:
+		return nil, fmt.Errorf("Invalid confirm rules, expected a list of [[confirm]] tables: %v", value)
+	}
+
+	var policy ConfirmationPolicy
+	for _, rawRule := range rawRules {
+		var rule ConfirmationRule
+		for key, rawValue := range rawRule {
+			s := fmt.Sprint(rawValue)
+			switch key {
+			case "operation":
+				rule.Operation = s
+			case "nodes-above":
+				n, err := strconv.Atoi(s)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("Invalid confirm rule: nodes-above must be a positive number, got %s", s)
+				}
+				rule.NodesAbove = n
+			case "label":
+				labels, err := ParseLabels(strings.Split(s, ","))
+				if err != nil {
+					return nil, err
+				}
+				rule.Labels = labels
+			default:
+				return nil, fmt.Errorf("Invalid confirm rule: unknown setting %s. Allowed settings: operation, nodes-above, label", key)
+			}
+		}
+		if rule.Operation == "" {
+			return nil, fmt.Errorf("Invalid confirm rule: operation is required")
+		}
+		if !isConfirmableOperation(rule.Operation) {
+			return nil, fmt.Errorf("Invalid confirm rule: unknown operation %s. Allowed operations: %s", rule.Operation, strings.Join(ConfirmableOperations, ", "))
+		}
+		policy = append(policy, rule)
+	}
+
+	return policy, nil
+}
+
+// isConfirmableOperation returns if the operation is one of the commands which change a cluster,
+// so that a misspelled operation doesn't silently disable the confirmation prompt
+func isConfirmableOperation(operation string) bool {
+	for _, o := range ConfirmableOperations {
+		if strings.EqualFold(o, operation) {
+			return true
+		}
+	}
+	return false
+}
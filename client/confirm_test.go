@@ -0,0 +1,57 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmationPolicy(t *testing.T) {
+	policy, err := client.ParseConfirmationPolicy([]map[string]interface{}{
+		{"operation": "delete"},
+		{"operation": "resize", "nodes-above": int64(10)},
+		{"operation": "rebuild", "label": "env=prod"},
+	})
+	assert.Nil(t, err)
+
+	dev := &testsupport.FakeCluster{Name: "dev", Labels: map[string]string{"env": "dev"}}
+	prod := &testsupport.FakeCluster{Name: "prod", Labels: map[string]string{"env": "prod"}}
+
+	_, ok := policy.Find("delete", dev, 0)
+	assert.True(t, ok)
+
+	_, ok = policy.Find("resize", dev, 10)
+	assert.False(t, ok)
+	_, ok = policy.Find("resize", dev, 11)
+	assert.True(t, ok)
+
+	_, ok = policy.Find("rebuild", dev, 0)
+	assert.False(t, ok)
+	_, ok = policy.Find("rebuild", prod, 0)
+	assert.True(t, ok)
+
+	assert.False(t, policy.AppliesTo("grow"))
+}
+
+func TestParseConfirmationPolicyRequiresOperation(t *testing.T) {
+	_, err := client.ParseConfirmationPolicy([]interface{}{
+		map[string]interface{}{"nodes-above": 3},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestParseConfirmationPolicyValidatesOperation(t *testing.T) {
+	_, err := client.ParseConfirmationPolicy([]interface{}{
+		map[string]interface{}{"operation": "delte"},
+	})
+	if assert.Error(t, err, "a misspelled operation should be rejected") {
+		assert.Contains(t, err.Error(), "unknown operation delte")
+	}
+
+	_, err = client.ParseConfirmationPolicy([]interface{}{
+		map[string]interface{}{"operation": "Rotate-CA"},
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,267 @@
+package client
+
+import (
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// conformancePrefix is the prefix of the throwaway clusters created by the conformance checks
+const conformancePrefix = "carina-conformance-"
+
+// ConformanceStatus is the outcome of a conformance check
+type ConformanceStatus string
+
+const (
+	// ConformancePass means that the capability behaved correctly
+	ConformancePass ConformanceStatus = "pass"
+
+	// ConformanceFail means that the capability is supported by the cloud, but didn't behave correctly
+	ConformanceFail ConformanceStatus = "fail"
+
+	// ConformanceUnsupported means that the cloud doesn't support the capability
+	ConformanceUnsupported ConformanceStatus = "unsupported"
+
+	// ConformanceSkipped means that the check wasn't run, because an earlier check failed or --dry-run was specified
+	ConformanceSkipped ConformanceStatus = "skipped"
+)
+
+// ConformanceOptions controls which template the conformance checks use, and whether a cluster is created
+type ConformanceOptions struct {
+	// Template is the name of the template to check, defaults to the recommended template, see RecommendTemplate
+	Template string
+
+	// COE limits the recommended template to a container orchestration engine, e.g. kubernetes
+	COE string
+
+	// DryRun only validates creating a cluster, skipping the checks which need a real cluster
+	DryRun bool
+
+	// Keep skips deleting the cluster, e.g. to troubleshoot a failure
+	Keep bool
+}
+
+// ConformanceCheck is the outcome of checking a single capability of the cloud
+type ConformanceCheck struct {
+	// Capability identifies what was checked, e.g. create or credentials
+	Capability string
+
+	// Description explains what was checked
+	Description string
+
+	Status   ConformanceStatus
+	Duration time.Duration
+
+	// Err is why the check failed, or why it was skipped or unsupported
+	Err error
+}
+
+// ConformanceResult is the outcome of each conformance check, in the order they were run
+type ConformanceResult struct {
+	Cluster  string
+	Template string
+	Checks   []ConformanceCheck
+}
+
+// Failures returns the number of checks which failed
+func (result *ConformanceResult) Failures() int {
+	var failures int
+	for _, check := range result.Checks {
+		if check.Status == ConformanceFail {
+			failures++
+		}
+	}
+	return failures
+}
+
+// Err returns an error when any check failed, or nil when every supported capability behaved correctly
+func (result *ConformanceResult) Err() error {
+	for _, check := range result.Checks {
+		if check.Status == ConformanceFail {
+			return errors.Wrapf(check.Err, "%d of %d conformance checks failed, starting with %s", result.Failures(), len(result.Checks), check.Capability)
+		}
+	}
+	return nil
+}
+
+// conformanceCheck identifies a check run by CheckConformance
+type conformanceCheck struct {
+	capability  string
+	description string
+}
+
+var (
+	checkAuthenticate  = conformanceCheck{"authenticate", "Authenticate with the account's credentials"}
+	checkListClusters  = conformanceCheck{"list-clusters", "List the clusters"}
+	checkListTemplates = conformanceCheck{"list-templates", "List the cluster templates"}
+	checkQuotas        = conformanceCheck{"quotas", "Retrieve the account quotas"}
+	checkKeypairs      = conformanceCheck{"keypairs", "List the SSH keypairs"}
+	checkPlanCreate    = conformanceCheck{"plan-create", "Validate creating a 1 node cluster"}
+	checkCreate        = conformanceCheck{"create", "Create a 1 node cluster and wait for it to become active"}
+	checkCredentials   = conformanceCheck{"credentials", "Download the cluster credentials"}
+	checkCOE           = conformanceCheck{"coe", "Check that the Docker or Kubernetes API responds"}
+	checkDelete        = conformanceCheck{"delete", "Delete the cluster"}
+)
+
+// unsupportedCapabilityError marks that a check failed because the cloud doesn't support the capability
+type unsupportedCapabilityError struct {
+	error
+}
+
+// conformanceRun records the outcome of each check
+type conformanceRun struct {
+	result *ConformanceResult
+}
+
+// check runs a check, recording if it passed, failed, or the capability is unsupported, returning if it passed
+func (run conformanceRun) check(check conformanceCheck, action func() error) bool {
+	start := time.Now()
+	err := action()
+	status := ConformancePass
+	if err != nil {
+		status = ConformanceFail
+		if unsupported, ok := err.(unsupportedCapabilityError); ok {
+			status = ConformanceUnsupported
+			err = unsupported.error
+		}
+	}
+	run.result.Checks = append(run.result.Checks, ConformanceCheck{Capability: check.capability, Description: check.description, Status: status, Duration: time.Since(start), Err: err})
+	return status == ConformancePass
+}
+
+// skip records that the checks weren't run, and why
+func (run conformanceRun) skip(reason string, checks ...conformanceCheck) {
+	for _, check := range checks {
+		run.result.Checks = append(run.result.Checks, ConformanceCheck{Capability: check.capability, Description: check.description, Status: ConformanceSkipped, Err: errors.New(reason)})
+	}
+}
+
+// CheckConformance runs a scripted sequence of operations against the account, reporting which capabilities of the cloud
+// behave correctly: authentication, listing clusters, templates, quotas and keypairs, validating a new cluster, and unless
+// DryRun is set, creating a 1 node cluster, downloading its credentials, checking that the COE responds and deleting the cluster.
+// This is useful for validating a private cloud, such as a Magnum install, against the cli.
+func (client *Client) CheckConformance(account Account, options ConformanceOptions) (*ConformanceResult, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ConformanceResult{Template: options.Template}
+	run := conformanceRun{result: result}
+
+	authenticated := run.check(checkAuthenticate, func() error {
+		authenticator, ok := svc.(common.Authenticator)
+		if !ok {
+			return unsupportedCapabilityError{errors.New("Authenticating without calling the cluster API is not supported by this cloud")}
+		}
+		_, err := authenticator.Authenticate()
+		return wrapClientError(err)
+	})
+
+	listed := run.check(checkListClusters, func() error {
+		_, err := client.ListClusters(account, ListClustersOptions{})
+		return err
+	})
+	if !authenticated && !listed {
+		run.skip("Unable to authenticate", checkListTemplates, checkQuotas, checkKeypairs, checkPlanCreate, checkCreate, checkCredentials, checkCOE, checkDelete)
+		return result, nil
+	}
+
+	var templates []common.ClusterTemplate
+	run.check(checkListTemplates, func() error {
+		var err error
+		templates, err = client.ListClusterTemplates(account, ListTemplatesOptions{})
+		if err == nil && len(templates) == 0 {
+			err = errors.New("The account doesn't have any templates")
+		}
+		return err
+	})
+
+	run.check(checkQuotas, func() error {
+		_, err := client.GetQuotas(account)
+		return err
+	})
+
+	run.check(checkKeypairs, func() error {
+		if _, ok := svc.(common.KeypairLister); !ok {
+			return unsupportedCapabilityError{errors.New("Keypairs are not supported by this cloud")}
+		}
+		_, err := client.ListKeypairs(account)
+		return err
+	})
+
+	if result.Template == "" {
+		template, err := RecommendTemplate(templates, options.COE)
+		if err != nil {
+			run.skip(err.Error(), checkPlanCreate, checkCreate, checkCredentials, checkCOE, checkDelete)
+			return result, nil
+		}
+		result.Template = template.GetName()
+	}
+
+	name, err := client.GenerateClusterName(account, conformancePrefix, NewNameGenerator(0))
+	if err != nil {
+		return nil, err
+	}
+	result.Cluster = name
+
+	planned := run.check(checkPlanCreate, func() error {
+		_, err := client.PlanCreateCluster(account, name, result.Template, 1, CreateClusterOptions{})
+		return err
+	})
+	if options.DryRun {
+		run.skip("--dry-run was specified", checkCreate, checkCredentials, checkCOE, checkDelete)
+		return result, nil
+	}
+	if !planned {
+		run.skip("Validating the cluster failed", checkCreate, checkCredentials, checkCOE, checkDelete)
+		return result, nil
+	}
+
+	var cluster common.Cluster
+	created := run.check(checkCreate, func() error {
+		var err error
+		cluster, err = client.CreateCluster(account, name, result.Template, 1, CreateClusterOptions{WaitUntilActive: true})
+		return err
+	})
+
+	downloaded := false
+	if created {
+		downloaded = run.check(checkCredentials, func() error {
+			_, err := client.downloadLocalClusterCredentials(account, name, "")
+			return err
+		})
+	} else {
+		run.skip("Creating the cluster failed", checkCredentials)
+	}
+
+	if downloaded {
+		run.check(checkCOE, func() error {
+			return client.WaitUntilCOEIsReady(account, cluster)
+		})
+	} else {
+		run.skip("The cluster credentials are unavailable", checkCOE)
+	}
+
+	if options.Keep {
+		common.Log.WriteWarning("Keeping the conformance cluster %s, delete it with carina delete %s", name, name)
+		run.skip("--keep was specified", checkDelete)
+		return result, nil
+	}
+
+	// The cluster may exist even when creating it failed, e.g. it ended up in an error state
+	run.check(checkDelete, func() error {
+		err := client.DeleteCluster(account, name, true)
+		if err != nil && !created && isNotFound(err) {
+			return nil
+		}
+		if err == nil && downloaded {
+			err = client.DeleteClusterCredentials(account, name, "")
+		}
+		return err
+	})
+
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConformanceDryRun(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC", COE: "swarm"})
+	account := &historyAccount{offlineAccount{service: service}}
+
+	result, err := client.CheckConformance(account, ConformanceOptions{DryRun: true})
+	require.NoError(t, err)
+
+	assert.NoError(t, result.Err())
+	assert.Equal(t, "Swarm 1.11.2 on LXC", result.Template)
+	statuses := make(map[string]ConformanceStatus)
+	for _, check := range result.Checks {
+		statuses[check.Capability] = check.Status
+	}
+	assert.Equal(t, ConformancePass, statuses["authenticate"])
+	assert.Equal(t, ConformancePass, statuses["list-templates"])
+	assert.Equal(t, ConformancePass, statuses["plan-create"])
+	assert.Equal(t, ConformanceSkipped, statuses["create"], "A cluster should not be created with --dry-run")
+	assert.Equal(t, ConformanceSkipped, statuses["delete"])
+
+	clusters, _ := service.ListClusters()
+	assert.Empty(t, clusters)
+}
+
+func TestCheckConformanceReportsFailures(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC", COE: "swarm"})
+	account := &historyAccount{offlineAccount{service: service}}
+
+	result, err := client.CheckConformance(account, ConformanceOptions{Template: "missing"})
+	require.NoError(t, err)
+
+	assert.Error(t, result.Err())
+	assert.Equal(t, 1, result.Failures())
+	for _, check := range result.Checks {
+		switch check.Capability {
+		case "plan-create":
+			assert.Equal(t, ConformanceFail, check.Status, "The template doesn't exist")
+		case "create", "credentials", "coe", "delete":
+			assert.Equal(t, ConformanceSkipped, check.Status, "The cluster checks should be skipped when validating the cluster fails")
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/version"
+	"github.com/pkg/errors"
+)
+
+// DefaultContextLogLines is the number of lines from the end of the log file included in a context bundle
+const DefaultContextLogLines = 200
+
+// ContextBundleOptions controls what is gathered into a diagnostic bundle
+type ContextBundleOptions struct {
+	// ConfigFile is the config file in use, if any
+	ConfigFile string
+
+	// LogFile is the file where the logs are written with --log-file, if any
+	LogFile string
+
+	// LogLines is the number of lines from the end of the log file to include
+	LogLines int
+}
+
+// ContextBundle summarizes the environment of the client, for attaching to a support ticket.
+// It is written to the bundle as context.json, alongside the redacted config file and the end of the log.
+type ContextBundle struct {
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit"`
+	GoVersion string    `json:"goVersion"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	Generated time.Time `json:"generated"`
+
+	CarinaHome string `json:"carinaHome,omitempty"`
+	ConfigFile string `json:"configFile,omitempty"`
+	LogFile    string `json:"logFile,omitempty"`
+
+	// Cache describes the on-disk cache, CacheError explains why it couldn't be described
+	Cache      *CacheInfo `json:"cache,omitempty"`
+	CacheError string     `json:"cacheError,omitempty"`
+
+	// FailedRequests are the API calls which failed, found in the log file
+	FailedRequests []FailedRequest `json:"failedRequests"`
+
+	// Files are the files in the bundle
+	Files []string `json:"files"`
+}
+
+// FailedRequest is an API call which failed, identified by the id the API assigned to the request
+type FailedRequest struct {
+	RequestID string `json:"requestID"`
+	Status    int    `json:"status"`
+}
+
+const (
+	contextBundleSummaryFile = "context.json"
+	contextBundleConfigFile  = "config.toml"
+	contextBundleLogFile     = "carina.log"
+)
+
+var logRequestIDPattern = regexp.MustCompile(`requestID\\?"?[=:]\\?"?([A-Za-z0-9_.:-]+)`)
+var logStatusPattern = regexp.MustCompile(`status\\?"?[=:](\d{3})\b`)
+
+// WriteContextBundle writes a zip archive with the client version, operating system, the config file with its secrets redacted,
+// the end of the log file with its secrets redacted, the ids of the API calls which failed and a description of the cache
+func (client *Client) WriteContextBundle(options ContextBundleOptions, w io.Writer) (*ContextBundle, error) {
+	if options.LogLines <= 0 {
+		options.LogLines = DefaultContextLogLines
+	}
+
+	bundle := &ContextBundle{
+		Version:        version.Version,
+		Commit:         version.Commit,
+		GoVersion:      runtime.Version(),
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+		Generated:      time.Now().UTC(),
+		FailedRequests: []FailedRequest{},
+		Files:          []string{contextBundleSummaryFile},
+	}
+
+	home, err := GetCredentialsDir()
+	if err == nil {
+		bundle.CarinaHome = home
+	}
+
+	if client.Cache == nil {
+		bundle.CacheError = "The cache is disabled"
+	} else if bundle.Cache, err = client.Cache.GetInfo(); err != nil {
+		bundle.CacheError = err.Error()
+	}
+
+	files := make(map[string][]byte)
+
+	config, err := readRedactedFile(options.ConfigFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read the config file")
+	}
+	if config != nil {
+		bundle.ConfigFile = options.ConfigFile
+		bundle.Files = append(bundle.Files, contextBundleConfigFile)
+		files[contextBundleConfigFile] = config
+	}
+
+	logLines, err := tailFile(options.LogFile, options.LogLines)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read the log file")
+	}
+	if logLines != nil {
+		bundle.LogFile = options.LogFile
+		bundle.Files = append(bundle.Files, contextBundleLogFile)
+		bundle.FailedRequests = findFailedRequests(logLines)
+		files[contextBundleLogFile] = []byte(common.RedactSecrets(strings.Join(logLines, "\n")) + "\n")
+	}
+
+	summary, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to build the context summary")
+	}
+	files[contextBundleSummaryFile] = summary
+
+	archive := zip.NewWriter(w)
+	for _, file := range bundle.Files {
+		entry, err := archive.Create(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to write to the context bundle")
+		}
+		_, err = entry.Write(files[file])
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to write to the context bundle")
+		}
+	}
+
+	return bundle, errors.Wrap(archive.Close(), "Unable to write to the context bundle")
+}
+
+// readRedactedFile reads a file with the values of its sensitive settings redacted, returning nil when the file doesn't exist
+func readRedactedFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, common.RedactSecrets(scanner.Text()))
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// tailFile returns up to the last n lines of a file, returning nil when the file doesn't exist
+func tailFile(path string, n int) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// findFailedRequests finds the API calls which failed in the log, in the order they were logged, from the requestID and status fields
+func findFailedRequests(lines []string) []FailedRequest {
+	failed := []FailedRequest{}
+	found := make(map[string]bool)
+	for _, line := range lines {
+		requestID := logRequestIDPattern.FindStringSubmatch(line)
+		status := logStatusPattern.FindStringSubmatch(line)
+		if requestID == nil || status == nil || found[requestID[1]] {
+			continue
+		}
+
+		code, _ := strconv.Atoi(status[1])
+		if code < 400 {
+			continue
+		}
+		found[requestID[1]] = true
+		failed = append(failed, FailedRequest{RequestID: requestID[1], Status: code})
+	}
+	return failed
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteContextBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-context")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "config.toml")
+	require.NoError(t, ioutil.WriteFile(configFile, []byte("[profiles.prod]\nusername = \"bob\"\napikey = \"abc123\"\n"), 0600))
+
+	logFile := filepath.Join(dir, "carina.log")
+	require.NoError(t, ioutil.WriteFile(logFile, []byte(`level=debug msg="Dropped from the tail"
+level=debug msg="Request Options: {\"password\": \"ilovepuppies\"}"
+level=debug msg="Request ID: req-1" requestID=req-1 status=200
+level=debug msg="Request ID: req-2" requestID=req-2 status=404
+{"level":"debug","msg":"Request ID: req-3","requestID":"req-3","status":500}
+`), 0600))
+
+	client := &Client{Cache: newCache(filepath.Join(dir, "cache.json"))}
+	var buf bytes.Buffer
+	bundle, err := client.WriteContextBundle(ContextBundleOptions{ConfigFile: configFile, LogFile: logFile, LogLines: 4}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, []FailedRequest{{RequestID: "req-2", Status: 404}, {RequestID: "req-3", Status: 500}}, bundle.FailedRequests)
+	assert.NotNil(t, bundle.Cache)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		contents, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		files[f.Name] = string(contents)
+	}
+
+	assert.Contains(t, files[contextBundleConfigFile], `apikey = "***"`)
+	assert.NotContains(t, files[contextBundleConfigFile], "abc123")
+	assert.NotContains(t, files[contextBundleLogFile], "ilovepuppies")
+	assert.NotContains(t, files[contextBundleLogFile], "Dropped from the tail")
+
+	var summary ContextBundle
+	require.NoError(t, json.Unmarshal([]byte(files[contextBundleSummaryFile]), &summary))
+	assert.Equal(t, []string{contextBundleSummaryFile, contextBundleConfigFile, contextBundleLogFile}, summary.Files)
+}
+
+func TestWriteContextBundleWithoutConfigOrLogs(t *testing.T) {
+	client := &Client{}
+	var buf bytes.Buffer
+	bundle, err := client.WriteContextBundle(ContextBundleOptions{ConfigFile: "missing.toml"}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{contextBundleSummaryFile}, bundle.Files)
+	assert.Empty(t, bundle.FailedRequests)
+	assert.NotEmpty(t, bundle.CacheError)
+}
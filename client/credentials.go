@@ -1,11 +1,16 @@
 package client
 
 import (
-	"errors"
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
 )
 
 const clusterDirName = "clusters"
@@ -13,6 +18,105 @@ const defaultDotDir = ".carina"
 const defaultNonDotDir = "carina"
 const xdgDataHomeEnvVar = "XDG_DATA_HOME"
 
+// CredentialsStoragePolicy controls where cluster credentials are saved
+var CredentialsStoragePolicy = struct {
+	// Dir is where cluster credentials are saved, instead of CARINA_HOME/clusters, e.g. the credentials.path setting
+	Dir string
+
+	// PathTemplate is where a cluster's credentials are saved within Dir, e.g. {{.Account}}/{{.Cloud}}/{{.ClusterName}}.
+	// Defaults to {{.Prefix}}/{{.ClusterName}}. See CredentialsPathData.
+	PathTemplate string
+
+	// Writer saves downloaded credentials, e.g. to Vault, defaults to saving them in Dir. See the credentials.backend setting.
+	Writer CredentialsWriter
+}{}
+
+// DefaultCredentialsPathTemplate saves credentials in a directory per account, named after the account's cluster prefix
+const DefaultCredentialsPathTemplate = "{{.Prefix}}/{{.ClusterName}}"
+
+// CredentialsPathData is the data available to the credentials path template
+type CredentialsPathData struct {
+	// Account is the account id, e.g. public-bob
+	Account string
+
+	// Cloud is the type of cloud, e.g. public or private
+	Cloud string
+
+	// Prefix is the account's cluster prefix, which includes the region or endpoint, e.g. public-dfw-bob
+	Prefix string
+
+	// ClusterName is the name of the cluster, and must be the last element of the path
+	ClusterName string
+}
+
+// credentialsPathPlaceholder stands in for the cluster name when rendering the account's directory from the path template
+const credentialsPathPlaceholder = "{cluster-name}"
+
+// ValidateCredentialsPathTemplate verifies that a credentials path template is valid, relative to the credentials directory,
+// and ends with the cluster name
+func ValidateCredentialsPathTemplate(pathTemplate string) error {
+	_, err := renderCredentialsAccountDir(pathTemplate, CredentialsPathData{Account: "public-bob", Cloud: "public", Prefix: "public-dfw-bob"})
+	return err
+}
+
+// renderCredentialsAccountDir renders the path template, returning the account's directory relative to the credentials directory
+func renderCredentialsAccountDir(pathTemplate string, data CredentialsPathData) (string, error) {
+	if pathTemplate == "" {
+		pathTemplate = DefaultCredentialsPathTemplate
+	}
+
+	tmpl, err := template.New("path-template").Option("missingkey=error").Parse(pathTemplate)
+	if err != nil {
+		return "", errors.Wrapf(err, "Invalid credentials path template %s", pathTemplate)
+	}
+
+	data.ClusterName = credentialsPathPlaceholder
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", errors.Wrapf(err, "Invalid credentials path template %s", pathTemplate)
+	}
+
+	rendered := filepath.Clean(filepath.FromSlash(buf.String()))
+	if filepath.IsAbs(rendered) || rendered == ".." || strings.HasPrefix(rendered, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("Invalid credentials path template %s, it must be relative to the credentials directory. Use credentials.path to change the credentials directory", pathTemplate)
+	}
+	if filepath.Base(rendered) != credentialsPathPlaceholder || strings.Count(rendered, credentialsPathPlaceholder) != 1 {
+		return "", errors.Errorf("Invalid credentials path template %s, it must end with /{{.ClusterName}}", pathTemplate)
+	}
+
+	return filepath.Dir(rendered), nil
+}
+
+// getAccountCredentialsDir returns the directory where the account's credentials are saved, according to the path template
+func getAccountCredentialsDir(account Account) (string, error) {
+	clustersDir, err := getClustersDir()
+	if err != nil {
+		return "", err
+	}
+
+	accountDir, err := renderAccountCredentialsDir(account)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(clustersDir, accountDir), nil
+}
+
+// renderAccountCredentialsDir returns the account's directory relative to the credentials directory, according to the path template
+func renderAccountCredentialsDir(account Account) (string, error) {
+	clusterPrefix, err := account.GetClusterPrefix()
+	if err != nil {
+		return "", err
+	}
+
+	data := CredentialsPathData{
+		Account: account.GetID(),
+		Cloud:   strings.SplitN(account.GetID(), "-", 2)[0],
+		Prefix:  clusterPrefix,
+	}
+	return renderCredentialsAccountDir(CredentialsStoragePolicy.PathTemplate, data)
+}
+
 // GetCredentialsDir gets the carina home directory, e.g. ~/.carina
 func GetCredentialsDir() (string, error) {
 	if os.Getenv(CarinaHomeDirEnvVar) != "" {
@@ -31,26 +135,176 @@ func GetCredentialsDir() (string, error) {
 	return filepath.Join(homeDir, defaultDotDir), nil
 }
 
+// getClustersDir returns the directory where cluster credentials are saved, e.g. ~/.carina/clusters
+func getClustersDir() (string, error) {
+	if CredentialsStoragePolicy.Dir != "" {
+		return CredentialsStoragePolicy.Dir, nil
+	}
+
+	baseDir, err := GetCredentialsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(baseDir, clusterDirName), nil
+}
+
 func buildClusterCredentialsPath(account Account, clusterName string, customPath string) (string, error) {
 	var credentialsPath string
 
 	// Use the default path, if the user didn't specify a special path where the credentials are stored
 	if customPath == "" {
-		var baseDir string
-		baseDir, err := GetCredentialsDir()
+		accountDir, err := getAccountCredentialsDir(account)
 		if err != nil {
 			return "", err
 		}
+		credentialsPath = filepath.Join(accountDir, clusterName)
+	} else {
+		credentialsPath = customPath
+	}
+
+	credentialsPath = filepath.Clean(credentialsPath)
+	return credentialsPath, nil
+}
+
+// runtimeDirEnvVar is the per-user directory for files which must not outlive the login session, which only the user can access
+const runtimeDirEnvVar = "XDG_RUNTIME_DIR"
+
+// errNoSessionDir indicates that encrypted credentials can't be decrypted for use after carina exits, because XDG_RUNTIME_DIR isn't set
+var errNoSessionDir = errors.Errorf("Encrypted credentials can only be loaded into the shell or a kubeconfig when %s is set, so that the decrypted copy is removed at logout. Use carina exec to run a command against the cluster instead, or set credentials.encryption to none", runtimeDirEnvVar)
+
+// buildRuntimeCredentialsPath returns where the decrypted copy of a cluster's credentials, which is used after carina exits,
+// e.g. by the shell or a kubeconfig, is stored. The layout of CARINA_HOME is mirrored under XDG_RUNTIME_DIR.
+func buildRuntimeCredentialsPath(credentialsPath string) (string, error) {
+	clustersDir, err := getClustersDir()
+	if err != nil {
+		return "", err
+	}
+
+	relPath, err := filepath.Rel(clustersDir, credentialsPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return "", errors.Errorf("%s is not in the credentials directory %s", credentialsPath, clustersDir)
+	}
+	relPath = filepath.Join(clusterDirName, relPath)
+
+	runtimeDir := os.Getenv(runtimeDirEnvVar)
+	if runtimeDir == "" {
+		return "", errNoSessionDir
+	}
+	return filepath.Join(runtimeDir, fmt.Sprintf("carina-%d", os.Getuid()), relPath), nil
+}
+
+// decryptClusterCredentials makes a decrypted copy of a cluster's encrypted credentials in a new private temporary directory,
+// so that they can be used by docker and kubectl while carina runs. The copy is wiped when carina exits.
+// Returns the path to the decrypted credentials, or the original path when the credentials are not encrypted.
+func (client *Client) decryptClusterCredentials(credentialsPath string, customPath string) (string, error) {
+	if client.cipher == nil || customPath != "" {
+		return credentialsPath, nil
+	}
+
+	files, err := client.readCredentialsFiles(credentialsPath)
+	if err != nil {
+		return credentialsPath, err
+	}
+
+	tempPath, _, err := client.writeTemporaryCredentials(files)
+	if err != nil {
+		return credentialsPath, err
+	}
+	return tempPath, nil
+}
+
+// decryptSessionCredentials makes a decrypted copy of a cluster's encrypted credentials under XDG_RUNTIME_DIR, for credentials
+// which are used after carina exits, e.g. sourced into the shell. Returns errNoSessionDir when XDG_RUNTIME_DIR isn't set,
+// or the original path when the credentials are not encrypted.
+func (client *Client) decryptSessionCredentials(credentialsPath string, customPath string) (string, error) {
+	if client.cipher == nil || customPath != "" {
+		return credentialsPath, nil
+	}
+
+	files, err := client.readCredentialsFiles(credentialsPath)
+	if err != nil {
+		return credentialsPath, err
+	}
+
+	runtimePath, err := buildRuntimeCredentialsPath(credentialsPath)
+	if err != nil {
+		return credentialsPath, err
+	}
+
+	// Every directory from XDG_RUNTIME_DIR down must be private, so that another user can't have created one in advance
+	runtimeDir := os.Getenv(runtimeDirEnvVar)
+	err = checkPrivateDir(runtimeDir)
+	if err != nil {
+		return credentialsPath, err
+	}
+	rel, _ := filepath.Rel(runtimeDir, runtimePath)
+	dir := runtimeDir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		err = os.Mkdir(dir, 0700)
+		if err != nil && !os.IsExist(err) {
+			return credentialsPath, errors.Wrap(err, "Unable to create the directory for the decrypted credentials")
+		}
+		if err == nil {
+			restrictAccess(dir)
+		}
+		err = checkPrivateDir(dir)
+		if err != nil {
+			return credentialsPath, err
+		}
+	}
 
-		clusterPrefix, err := account.GetClusterPrefix()
+	for file, contents := range files {
+		err = ioutil.WriteFile(filepath.Join(runtimePath, file), contents, 0600)
 		if err != nil {
-			return "", err
+			return credentialsPath, errors.Wrap(err, "Unable to write the decrypted credentials")
 		}
-		credentialsPath = filepath.Join(baseDir, clusterDirName, clusterPrefix, clusterName)
 	}
 
-	credentialsPath = filepath.Clean(credentialsPath)
-	return credentialsPath, nil
+	return runtimePath, nil
+}
+
+// stagedCredentialsSuffix is appended to the name of a credentials file while it is written, before it replaces the existing file
+const stagedCredentialsSuffix = ".new"
+
+// replaceCredentialsFiles writes every file to a temporary file next to its destination first, then renames them into place,
+// so that a failed download leaves the existing credentials untouched instead of a mix of old and new certificates.
+// Credentials in the credentials directory are locked while they are replaced, because jobs sharing CARINA_HOME may download them at the same time.
+func (client *Client) replaceCredentialsFiles(credentialsPath string, files map[string][]byte, customPath string) error {
+	if customPath == "" {
+		unlock, err := acquireFileLock(credentialsPath+".lock", "downloading the credentials")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	var staged []string
+	cleanup := func() {
+		for _, file := range staged {
+			os.Remove(file + stagedCredentialsSuffix)
+		}
+	}
+
+	for file, contents := range files {
+		file = filepath.Join(credentialsPath, file)
+		staged = append(staged, file)
+		err := client.writeSecureFile(file+stagedCredentialsSuffix, contents, customPath)
+		if err != nil {
+			cleanup()
+			return err
+		}
+	}
+
+	for i, file := range staged {
+		err := os.Rename(file+stagedCredentialsSuffix, file)
+		if err != nil {
+			cleanup()
+			return errors.Wrapf(err, "Unable to replace %s, %d of %d credentials files were replaced", file, i, len(staged))
+		}
+	}
+
+	return nil
 }
 
 // getCredentialScriptPrefix looks at a credentials bundle and identifies the
@@ -69,3 +323,137 @@ func getCredentialScriptPrefix(credsPath string) (string, error) {
 
 	return strings.TrimSuffix(bashScriptName, ".env"), nil
 }
+
+// credentialEnvVars are the environment variables set by the credentials bundle scripts
+var credentialEnvVars = []string{"DOCKER_HOST", "DOCKER_TLS_VERIFY", "DOCKER_CERT_PATH", "DOCKER_VERSION", "KUBECONFIG"}
+
+// getCredentialScriptPath returns the script which loads the credentials in a shell, generating it when needed
+func getCredentialScriptPath(basepath string, shell string) (string, error) {
+	scriptPrefix, err := getCredentialScriptPrefix(basepath)
+	if err != nil {
+		return "", err
+	}
+
+	pathPrefix := filepath.Join(basepath, scriptPrefix)
+
+	var scriptPath string
+	switch normalizeShell(shell) {
+	case "bash":
+		scriptPath = pathPrefix + ".env"
+	case "fish":
+		scriptPath = pathPrefix + ".fish"
+	case "powershell":
+		scriptPath = pathPrefix + ".ps1"
+	case "cmd":
+		scriptPath = pathPrefix + ".cmd"
+	default:
+		return "", fmt.Errorf("Invalid shell specified: %s. Allowed values: bash, zsh, fish, powershell, cmd", shell)
+	}
+
+	// Some bundles only include the bash script, so the Windows scripts are generated from it
+	err = ensureCredentialScript(basepath, scriptPrefix, normalizeShell(shell), scriptPath)
+	if err != nil {
+		return "", err
+	}
+	return scriptPath, nil
+}
+
+// GetUnsetCommand returns the shell commands to clear the environment variables set by carina env
+func GetUnsetCommand(shell string) (string, error) {
+	var lines []string
+	switch normalizeShell(shell) {
+	case "bash":
+		lines = append(lines, "unset "+strings.Join(credentialEnvVars, " "))
+		lines = append(lines, "# Run the command below to clear the environment variables for docker and kubectl:")
+		lines = append(lines, "# eval $(carina env --unset)")
+	case "fish":
+		for _, envVar := range credentialEnvVars {
+			lines = append(lines, fmt.Sprintf("set -e %s;", envVar))
+		}
+		lines = append(lines, "# Run the command below to clear the environment variables for docker and kubectl:")
+		lines = append(lines, "# eval (carina env --unset)")
+	case "powershell":
+		for _, envVar := range credentialEnvVars {
+			lines = append(lines, fmt.Sprintf("Remove-Item Env:\\%s -ErrorAction SilentlyContinue", envVar))
+		}
+		lines = append(lines, "# Run the command below to clear the environment variables for docker and kubectl:")
+		lines = append(lines, "# carina env --unset --shell powershell | iex")
+	case "cmd":
+		for _, envVar := range credentialEnvVars {
+			lines = append(lines, fmt.Sprintf("SET %s=", envVar))
+		}
+	default:
+		return "", fmt.Errorf("Invalid shell specified: %s. Allowed values: bash, zsh, fish, powershell, cmd", shell)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// credentialFileAliases maps friendly names to the files in the credentials bundle
+var credentialFileAliases = map[string]string{
+	"kubeconfig": bundleKubeconfigFilename,
+	"ca":         caCertFilename,
+	"cert":       clientCertFilename,
+	"key":        "key.pem",
+}
+
+// GetClusterCredentialFile downloads the credentials bundle for a cluster, and returns a single file from it,
+// such as ca.pem or kubeconfig. Returns the name of the file in the bundle and its contents.
+func (client *Client) GetClusterCredentialFile(account Account, name string, file string) (string, []byte, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return "", nil, err
+	}
+
+	creds, err := svc.GetClusterCredentials(name)
+	if err != nil {
+		return "", nil, wrapClusterError(name, err)
+	}
+
+	if alias, ok := credentialFileAliases[file]; ok {
+		file = alias
+	}
+
+	contents, ok := creds.Files[file]
+	if !ok {
+		var available []string
+		for f := range creds.Files {
+			available = append(available, f)
+		}
+		sort.Strings(available)
+		return "", nil, fmt.Errorf("The credentials for %s do not contain %s. Available files: %s", name, file, strings.Join(available, ", "))
+	}
+
+	return file, contents, nil
+}
+
+// DownloadClusterCredentialFile downloads a single file from the credentials bundle for a cluster, such as ca.pem or kubeconfig
+func (client *Client) DownloadClusterCredentialFile(account Account, name string, file string, customPath string) (filePath string, err error) {
+	file, contents, err := client.GetClusterCredentialFile(account, name, file)
+	if err != nil {
+		return "", err
+	}
+
+	if newName, ok := resolveCredentialsFilenames(CredentialsFilenamePolicy.Names, name)[file]; ok && customPath != "" {
+		file = newName
+	}
+
+	credentialsPath, err := buildClusterCredentialsPath(account, name, customPath)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to save downloaded cluster credentials")
+	}
+
+	err = os.MkdirAll(credentialsPath, 0777)
+	if err != nil {
+		return "", err
+	}
+
+	filePath = filepath.Join(credentialsPath, file)
+	err = client.writeSecureFile(filePath, contents, customPath)
+	if err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 )
 
 // CredentialsNextStepsString returns instructions to load the cluster credentials
@@ -14,31 +13,27 @@ func CredentialsNextStepsString(clusterName string) string {
 	return fmt.Sprintf("# To see how to connect to your cluster, run: carina env %s\n", clusterName)
 }
 
-func getCredentialScriptPath(basepath string, shell string) (string, error) {
-	scriptPrefix, err := getCredentialScriptPrefix(basepath)
-	if err != nil {
-		return "", err
-	}
-
-	pathPrefix := filepath.Join(basepath, scriptPrefix)
-
-	switch shell {
+func sourceHelpString(credentialFile string, clusterName string, shell string) string {
+	quotedName := QuoteShellArg(shell, clusterName)
+	switch normalizeShell(shell) {
+	case "powershell":
+		s := fmt.Sprintf(". %s\n", QuoteShellArg(shell, credentialFile))
+		s += fmt.Sprintf("# Run the command below to load environment variables for docker or kubectl:\n")
+		s += fmt.Sprintf("# carina env %s --shell powershell | iex", quotedName)
+		return s
 	case "fish":
-		return pathPrefix + ".fish", nil
-	case "bash":
-		return pathPrefix + ".env", nil
+		s := fmt.Sprintf("source %s\n", QuoteShellArg(shell, credentialFile))
+		s += fmt.Sprintf("# Run the command below to load environment variables for docker or kubectl:\n")
+		s += fmt.Sprintf("# eval (carina env %s)", quotedName)
+		return s
 	default:
-		return "", fmt.Errorf("Invalid shell specified: %s", shell)
+		s := fmt.Sprintf("source %s\n", QuoteShellArg(shell, credentialFile))
+		s += fmt.Sprintf("# Run the command below to load environment variables for docker or kubectl:\n")
+		s += fmt.Sprintf("# eval $(carina env %s)", quotedName)
+		return s
 	}
 }
 
-func sourceHelpString(credentialFile string, clusterName string, shell string) string {
-	s := fmt.Sprintf("source %s\n", credentialFile)
-	s += fmt.Sprintf("# Run the command below to load environment variables for docker or kubectl:\n")
-	s += fmt.Sprintf("# eval $(carina env %s)", clusterName)
-	return s
-}
-
 func userHomeDir() (string, error) {
 	home := os.Getenv("HOME")
 	if home != "" {
@@ -15,26 +15,6 @@ func CredentialsNextStepsString(clusterName string) string {
 	return fmt.Sprintf("# To see how to connect to your cluster, run: carina env %s --shell cmd|powershell|bash\n", clusterName)
 }
 
-func getCredentialScriptPath(basepath string, shell string) (string, error) {
-	scriptPrefix, err := getCredentialScriptPrefix(basepath)
-	if err != nil {
-		return "", err
-	}
-
-	pathPrefix := filepath.Join(basepath, scriptPrefix)
-
-	switch shell {
-	case "powershell":
-		return pathPrefix + ".ps1", nil
-	case "cmd":
-		return pathPrefix + ".cmd", nil
-	case "bash":
-		return pathPrefix + ".env", nil
-	default:
-		return "", fmt.Errorf("Invalid shell specified: %s", shell)
-	}
-}
-
 func forceUnixPath(winPath string) string {
 	// Convert C:/ --> /C/
 	unixPath := "/" + strings.Replace(winPath, ":\\", "/", 1)
@@ -44,40 +24,42 @@ func forceUnixPath(winPath string) string {
 }
 
 func sourceHelpString(credentialFile string, clusterName string, shell string) string {
-	switch shell {
+	quotedName := QuoteShellArg(shell, clusterName)
+	switch normalizeShell(shell) {
 	case "powershell":
-		s := fmt.Sprintf(". %s\n", credentialFile)
+		s := fmt.Sprintf(". %s\n", QuoteShellArg(shell, credentialFile))
 		s += fmt.Sprintf("# Run the command below to load environment variables for docker or kubectl:\n")
-		s += fmt.Sprintf("# carina env %s --shell powershell | iex", clusterName) // PowerShell bombs if you have an empty line, leaving out
+		s += fmt.Sprintf("# carina env %s --shell powershell | iex", quotedName) // PowerShell bombs if you have an empty line, leaving out
 		return s
 	case "cmd":
 		s := fmt.Sprintf("# Run the command below to load environment variables for docker or kubectl:\n")
-		s += fmt.Sprintf("CALL %s\n", credentialFile)
+		s += fmt.Sprintf("CALL %s\n", QuoteShellArg(shell, credentialFile))
+		return s
+	case "fish":
+		s := fmt.Sprintf("source %s\n", QuoteShellArg(shell, forceUnixPath(credentialFile)))
+		s += fmt.Sprintf("# Run the command below to load environment variables for docker or kubectl:\n")
+		s += fmt.Sprintf("# eval (carina env %s --shell fish)\n", quotedName)
 		return s
 	default: // Windows Bash
-		s := fmt.Sprintf("source %s\n", forceUnixPath(credentialFile))
+		s := fmt.Sprintf("source %s\n", QuoteShellArg(shell, forceUnixPath(credentialFile)))
 		s += fmt.Sprintf("# Run the command below to load environment variables for docker or kubectl:\n")
-		s += fmt.Sprintf("# eval $(carina env %s)\n", clusterName)
+		s += fmt.Sprintf("# eval $(carina env %s)\n", quotedName)
 		return s
 	}
 }
 
+// userHomeDir finds the home directory, preferring one which already contains CARINA_HOME,
+// so that credentials saved by earlier versions, which checked %HOMEDRIVE%%HOMEPATH% first, are still found
 func userHomeDir() (string, error) {
-	home := os.Getenv("HOME")
-	if home != "" {
-		return home, nil
+	dirs := windowsHomeDirs(os.Getenv)
+	if len(dirs) == 0 {
+		return "", errors.New("Unable to locate home directory")
 	}
 
-	homedrive := os.Getenv("HOMEDRIVE")
-	homepath := os.Getenv("HOMEPATH")
-	if homedrive != "" && homepath != "" {
-		return filepath.Join(homedrive, homepath), nil
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, defaultDotDir)); err == nil {
+			return dir, nil
+		}
 	}
-
-	userprofile := os.Getenv("USERPROFILE")
-	if userprofile != "" {
-		return userprofile, nil
-	}
-
-	return "", errors.New("Unable to locate home directory")
+	return dirs[0], nil
 }
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// Credential is a setting needed to authenticate, which can be requested from a CredentialSource when it wasn't specified
+type Credential struct {
+	// Name describes the credential, e.g. Username or API Key
+	Name string
+
+	// Secret is set for credentials which must not be echoed, such as a password or API key
+	Secret bool
+}
+
+// CredentialUsername is the username used to authenticate with every cloud
+var CredentialUsername = Credential{Name: "Username"}
+
+// Credential returns the cloud's secret, either the API key or password, as a credential
+func (secret SecretType) Credential() Credential {
+	return Credential{Name: string(secret), Secret: true}
+}
+
+// CredentialSource provides a credential which wasn't specified with a flag, environment variable or profile,
+// such as by reading it from stdin or prompting for it
+type CredentialSource struct {
+	// Name describes where the credential came from in the debug log, e.g. stdin or prompt
+	Name string
+
+	// Provide returns the credential, ok is false when the source can't provide it
+	Provide func(credential Credential) (value string, ok bool, err error)
+}
+
+// ResolveCredential fills in a missing credential from each of the settings' CredentialSources in turn,
+// returning false when none of them provide it, so that the caller can explain how to specify it instead
+func (settings *AccountSettings) ResolveCredential(credential Credential, value *string) (bool, error) {
+	if *value != "" {
+		return true, nil
+	}
+
+	for _, source := range settings.CredentialSources {
+		resolved, ok, err := source.Provide(credential)
+		if err != nil {
+			return false, errors.Wrapf(err, "Unable to read the %s from %s", credential.Name, source.Name)
+		}
+		if ok && resolved != "" {
+			*value = resolved
+			common.Log.WriteDebug("%s: %s", credential.Name, source.Name)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NewStaticSecretSource provides a secret which was read up front, e.g. from stdin with --password-stdin,
+// for the cloud's API key or password. It never provides the username.
+func NewStaticSecretSource(name string, secret string) CredentialSource {
+	return CredentialSource{
+		Name: name,
+		Provide: func(credential Credential) (string, bool, error) {
+			if !credential.Secret {
+				return "", false, nil
+			}
+			return secret, true, nil
+		},
+	}
+}
@@ -0,0 +1,304 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// The credentials backends, which control where downloaded credentials are saved, see the credentials.backend setting
+const (
+	// CredentialsBackendLocal saves the credentials to the credentials directory, or --path
+	CredentialsBackendLocal = "local"
+
+	// CredentialsBackendTar writes the credentials as a tar stream to stdout, with a directory per cluster
+	CredentialsBackendTar = "tar"
+
+	// CredentialsBackendVault saves the credentials to the HashiCorp Vault KV secrets engine, see VaultPathEnvVar
+	CredentialsBackendVault = "vault"
+)
+
+// CredentialsBackends are the valid values for the credentials.backend setting
+var CredentialsBackends = []string{CredentialsBackendLocal, CredentialsBackendTar, CredentialsBackendVault}
+
+// VaultAddrEnvVar is the address of the Vault server, e.g. https://vault.example.com:8200
+const VaultAddrEnvVar = "VAULT_ADDR"
+
+// VaultTokenEnvVar is the token used to authenticate with Vault
+const VaultTokenEnvVar = "VAULT_TOKEN"
+
+// VaultNamespaceEnvVar is the Vault Enterprise namespace, optional
+const VaultNamespaceEnvVar = "VAULT_NAMESPACE"
+
+// VaultPathEnvVar is where credentials are saved in Vault, the mount of a KV version 2 secrets engine followed by an optional prefix.
+// Each cluster's credentials are saved below it, using the credentials path template, e.g. secret/carina/public-dfw-bob/mycluster.
+const VaultPathEnvVar = "CARINA_VAULT_PATH"
+
+// DefaultVaultPath is where credentials are saved in Vault, when CARINA_VAULT_PATH is not set
+const DefaultVaultPath = "secret/carina"
+
+// CredentialsWriter saves the files in a cluster's credentials bundle
+type CredentialsWriter interface {
+	// WriteCredentials saves a cluster's credentials files, returning where they were saved.
+	// customPath is the directory specified with --path, which only the local backend supports.
+	WriteCredentials(account Account, name string, files map[string][]byte, customPath string) (location string, err error)
+
+	// Close finishes writing after the credentials for every cluster are written, e.g. the end of a tar stream
+	Close() error
+}
+
+// ValidateCredentialsBackend verifies that the credentials backend is supported
+func ValidateCredentialsBackend(backend string) error {
+	for _, b := range CredentialsBackends {
+		if backend == b {
+			return nil
+		}
+	}
+	return errors.Errorf("Invalid credentials backend %s, must be one of: %s", backend, strings.Join(CredentialsBackends, ", "))
+}
+
+// NewCredentialsWriter returns the writer for a credentials backend, the tar stream is written to w.
+// Returns nil for the local backend, which is the default when CredentialsStoragePolicy.Writer isn't set.
+func NewCredentialsWriter(backend string, w io.Writer) (CredentialsWriter, error) {
+	err := ValidateCredentialsBackend(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	switch backend {
+	case CredentialsBackendTar:
+		return newTarCredentialsWriter(w), nil
+	case CredentialsBackendVault:
+		return newVaultCredentialsWriterFromEnv()
+	}
+	return nil, nil
+}
+
+// credentialsWriter returns the writer used to save downloaded credentials, see CredentialsStoragePolicy.Writer
+func (client *Client) credentialsWriter() CredentialsWriter {
+	if CredentialsStoragePolicy.Writer != nil {
+		return CredentialsStoragePolicy.Writer
+	}
+	return &localCredentialsWriter{client: client}
+}
+
+// checkCustomPathSupported returns an error when --path is used with a backend which doesn't save to the local disk
+func checkCustomPathSupported(backend string, customPath string) error {
+	if customPath != "" {
+		return errors.Errorf("A custom path can only be used with the %s credentials backend, not %s", CredentialsBackendLocal, backend)
+	}
+	return nil
+}
+
+// localCredentialsWriter saves credentials in the credentials directory, encrypting them when credentials.encryption is set,
+// or to a custom path with the files renamed according to CredentialsFilenamePolicy
+type localCredentialsWriter struct {
+	client *Client
+}
+
+func (writer *localCredentialsWriter) WriteCredentials(account Account, name string, files map[string][]byte, customPath string) (string, error) {
+	credentialsPath, err := buildClusterCredentialsPath(account, name, customPath)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to save downloaded cluster credentials")
+	}
+
+	// Ensure the credentials destination directory exists
+	if credentialsPath != "." {
+		err = os.MkdirAll(credentialsPath, 0777)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if customPath != "" {
+		files, err = renameCredentialsFiles(files, CredentialsFilenamePolicy.Names, name)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = writer.client.replaceCredentialsFiles(credentialsPath, files, customPath)
+	if err != nil {
+		return "", err
+	}
+	return credentialsPath, nil
+}
+
+func (writer *localCredentialsWriter) Close() error {
+	return nil
+}
+
+// tarCredentialsWriter writes credentials to a single tar stream, in a directory named after each cluster,
+// so that the credentials for several clusters downloaded concurrently can be extracted with tar -x
+type tarCredentialsWriter struct {
+	lock    sync.Mutex
+	archive *tar.Writer
+}
+
+func newTarCredentialsWriter(w io.Writer) *tarCredentialsWriter {
+	return &tarCredentialsWriter{archive: tar.NewWriter(w)}
+}
+
+func (writer *tarCredentialsWriter) WriteCredentials(account Account, name string, files map[string][]byte, customPath string) (string, error) {
+	err := checkCustomPathSupported(CredentialsBackendTar, customPath)
+	if err != nil {
+		return "", err
+	}
+
+	var filenames []string
+	for file := range files {
+		filenames = append(filenames, file)
+	}
+	sort.Strings(filenames)
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	now := time.Now()
+	err = writer.archive.WriteHeader(&tar.Header{Name: name + "/", Typeflag: tar.TypeDir, Mode: 0700, ModTime: now})
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to write the credentials")
+	}
+	for _, file := range filenames {
+		contents := files[file]
+		header := &tar.Header{Name: name + "/" + file, Typeflag: tar.TypeReg, Mode: 0600, Size: int64(len(contents)), ModTime: now}
+		err = writer.archive.WriteHeader(header)
+		if err == nil {
+			_, err = writer.archive.Write(contents)
+		}
+		if err != nil {
+			return "", errors.Wrap(err, "Unable to write the credentials")
+		}
+	}
+
+	// Flush each cluster as it's written, so that a failure later in a batch doesn't lose it
+	return name + "/", errors.Wrap(writer.archive.Flush(), "Unable to write the credentials")
+}
+
+func (writer *tarCredentialsWriter) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+	return errors.Wrap(writer.archive.Close(), "Unable to write the credentials")
+}
+
+// vaultCredentialsWriter saves each cluster's credentials as a secret in a Vault KV version 2 secrets engine,
+// with a key per file in the credentials bundle
+type vaultCredentialsWriter struct {
+	address   string
+	token     string
+	namespace string
+
+	// mount is the path of the KV secrets engine, e.g. secret
+	mount string
+
+	// prefix is where the credentials are saved within the secrets engine, e.g. carina
+	prefix string
+}
+
+func newVaultCredentialsWriterFromEnv() (*vaultCredentialsWriter, error) {
+	address := os.Getenv(VaultAddrEnvVar)
+	token := os.Getenv(VaultTokenEnvVar)
+	if address == "" || token == "" {
+		return nil, errors.Errorf("The %s and %s environment variables are required to save credentials to Vault", VaultAddrEnvVar, VaultTokenEnvVar)
+	}
+
+	vaultPath := os.Getenv(VaultPathEnvVar)
+	if vaultPath == "" {
+		vaultPath = DefaultVaultPath
+	}
+	return newVaultCredentialsWriter(address, token, os.Getenv(VaultNamespaceEnvVar), vaultPath)
+}
+
+func newVaultCredentialsWriter(address string, token string, namespace string, vaultPath string) (*vaultCredentialsWriter, error) {
+	segments := strings.SplitN(strings.Trim(vaultPath, "/"), "/", 2)
+	if segments[0] == "" {
+		return nil, errors.Errorf("Invalid %s %s, it must start with the mount of a KV secrets engine, e.g. %s", VaultPathEnvVar, vaultPath, DefaultVaultPath)
+	}
+
+	writer := &vaultCredentialsWriter{
+		address:   strings.TrimRight(address, "/"),
+		token:     token,
+		namespace: namespace,
+		mount:     segments[0],
+	}
+	if len(segments) == 2 {
+		writer.prefix = segments[1]
+	}
+	return writer, nil
+}
+
+func (writer *vaultCredentialsWriter) WriteCredentials(account Account, name string, files map[string][]byte, customPath string) (string, error) {
+	err := checkCustomPathSupported(CredentialsBackendVault, customPath)
+	if err != nil {
+		return "", err
+	}
+
+	accountDir, err := renderAccountCredentialsDir(account)
+	if err != nil {
+		return "", err
+	}
+	secretPath := path.Join(writer.prefix, filepath.ToSlash(accountDir), name)
+
+	data := make(map[string]string, len(files))
+	for file, contents := range files {
+		data[file] = string(contents)
+	}
+	body, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to save the credentials to Vault")
+	}
+
+	secretURL := fmt.Sprintf("%s/v1/%s/data/%s", writer.address, writer.mount, secretPath)
+	req, err := http.NewRequest("POST", secretURL, bytes.NewReader(body))
+	if err != nil {
+		return "", errors.Wrapf(err, "Invalid %s %s", VaultAddrEnvVar, writer.address)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", writer.token)
+	if writer.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", writer.namespace)
+	}
+
+	common.Log.WriteDebug("Saving the credentials for %s to Vault at %s/%s", name, writer.mount, secretPath)
+	resp, err := common.NewHTTPClient().Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to save the credentials to Vault")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return "", common.NewHTTPError(resp.StatusCode, errors.Errorf("Unable to save the credentials to Vault: %s%s", resp.Status, readVaultErrors(resp.Body)))
+	}
+
+	return fmt.Sprintf("vault:%s/%s", writer.mount, secretPath), nil
+}
+
+func (writer *vaultCredentialsWriter) Close() error {
+	return nil
+}
+
+// readVaultErrors returns the error messages from a Vault error response, e.g. permission denied
+func readVaultErrors(body io.Reader) string {
+	contents, _ := ioutil.ReadAll(body)
+	var response struct {
+		Errors []string `json:"errors"`
+	}
+	if json.Unmarshal(contents, &response) != nil || len(response.Errors) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(response.Errors, ", ")
+}
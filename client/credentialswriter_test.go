@@ -0,0 +1,103 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/getcarina/carina/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarCredentialsWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer := newTarCredentialsWriter(&buf)
+
+	location, err := writer.WriteCredentials(prefixAccount{}, "web", map[string][]byte{"ca.pem": []byte("ca")}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "web/", location)
+	_, err = writer.WriteCredentials(prefixAccount{}, "api", map[string][]byte{"ca.pem": []byte("ca")}, "")
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	var names []string
+	archive := tar.NewReader(&buf)
+	for {
+		header, err := archive.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	assert.Equal(t, []string{"web/", "web/ca.pem", "api/", "api/ca.pem"}, names, "every cluster should be in the same tar stream")
+
+	_, err = writer.WriteCredentials(prefixAccount{}, "web", nil, "/tmp/web")
+	assert.Error(t, err, "a custom path should only be supported by the local backend")
+}
+
+func TestVaultCredentialsWriter(t *testing.T) {
+	var requested, token string
+	var body struct {
+		Data map[string]string `json:"data"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		token = r.Header.Get("X-Vault-Token")
+		contents, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(contents, &body)
+		if token != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		w.Write([]byte(`{"data":{"version":1}}`))
+	}))
+	defer server.Close()
+
+	writer, err := newVaultCredentialsWriter(server.URL+"/", "s.token", "", "kv/teams/ops/")
+	require.NoError(t, err)
+
+	location, err := writer.WriteCredentials(prefixAccount{}, "web", map[string][]byte{"ca.pem": []byte("ca"), "kubeconfig": []byte("config")}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/kv/data/teams/ops/public-dfw-alice/web", requested, "the secret should be saved using the credentials path template")
+	assert.Equal(t, "vault:kv/teams/ops/public-dfw-alice/web", location)
+	assert.Equal(t, map[string]string{"ca.pem": "ca", "kubeconfig": "config"}, body.Data)
+
+	writer.token = "s.expired"
+	_, err = writer.WriteCredentials(prefixAccount{}, "web", map[string][]byte{"ca.pem": []byte("ca")}, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+
+	_, err = newVaultCredentialsWriter(server.URL, "s.token", "", "/")
+	assert.Error(t, err, "the path must include the mount of the secrets engine")
+}
+
+func TestVaultCredentialsWriterTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var trace bytes.Buffer
+	defer func(out io.Writer, level logrus.Level, enabled bool) {
+		common.Log.Out, common.Log.Level, common.HTTPTracePolicy.Enabled = out, level, enabled
+	}(common.Log.Out, common.Log.Level, common.HTTPTracePolicy.Enabled)
+	common.Log.Out, common.Log.Level, common.HTTPTracePolicy.Enabled = &trace, logrus.DebugLevel, true
+
+	writer, err := newVaultCredentialsWriter(server.URL, "s.secret-token", "team-ns", "secret/carina")
+	require.NoError(t, err)
+	_, err = writer.WriteCredentials(prefixAccount{}, "web", map[string][]byte{"key.pem": []byte("private-key")}, "")
+	require.NoError(t, err)
+
+	assert.Contains(t, trace.String(), "HTTP request: POST", "the request should be traced")
+	assert.NotContains(t, trace.String(), "s.secret-token", "the Vault token should be redacted")
+	assert.NotContains(t, trace.String(), "team-ns", "the Vault namespace should be redacted")
+	assert.NotContains(t, trace.String(), "private-key", "the credentials should be redacted")
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// dockerHostPattern finds the address of the Docker API in a credentials bundle's docker.env
+var dockerHostPattern = regexp.MustCompile(`DOCKER_HOST=["']?([^\s"']+)`)
+
+// CredentialsDiff describes how a cluster's downloaded credentials differ from its current credentials
+type CredentialsDiff struct {
+	// Added are the files only in the current credentials
+	Added []string
+
+	// Removed are the files only in the downloaded credentials
+	Removed []string
+
+	// Changed are the files whose contents differ
+	Changed []string
+
+	// Local are the fingerprints of the downloaded credentials
+	Local CredentialsFingerprint
+
+	// Remote are the fingerprints of the current credentials
+	Remote CredentialsFingerprint
+
+	// LocalEndpoint is the cluster address in the downloaded credentials
+	LocalEndpoint string
+
+	// RemoteEndpoint is the cluster address in the current credentials
+	RemoteEndpoint string
+}
+
+// HasChanges returns if the downloaded credentials are out-of-date
+func (diff CredentialsDiff) HasChanges() bool {
+	return len(diff.Added) > 0 || len(diff.Removed) > 0 || len(diff.Changed) > 0
+}
+
+// DiffClusterCredentials compares a cluster's downloaded credentials with its current credentials, without saving the current credentials
+func (client *Client) DiffClusterCredentials(account Account, name string, customPath string) (diff CredentialsDiff, err error) {
+	credentialsPath, err := buildClusterCredentialsPath(account, name, customPath)
+	if err != nil {
+		return diff, err
+	}
+
+	local, err := client.readCredentialsFiles(credentialsPath)
+	if err != nil {
+		return diff, errors.Wrapf(err, "Unable to read the credentials for %s, run carina credentials %s to download them", name, name)
+	}
+
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return diff, err
+	}
+
+	defer client.endOperation(client.startOperation(name, "Download credentials for cluster (%s)", name))
+
+	creds, err := svc.GetClusterCredentials(name)
+	if err != nil {
+		return diff, wrapClusterError(name, err)
+	}
+
+	return diffCredentials(local, creds.Files), nil
+}
+
+// readCredentialsFiles reads the contents of each file in a credentials directory, decrypting them if necessary
+func (client *Client) readCredentialsFiles(credentialsPath string) (map[string][]byte, error) {
+	entries, err := ioutil.ReadDir(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+
+		files[entry.Name()], err = client.readSecureFile(filepath.Join(credentialsPath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func diffCredentials(local map[string][]byte, remote map[string][]byte) CredentialsDiff {
+	var diff CredentialsDiff
+	for file, contents := range remote {
+		localContents, ok := local[file]
+		if !ok {
+			diff.Added = append(diff.Added, file)
+		} else if !bytes.Equal(localContents, contents) {
+			diff.Changed = append(diff.Changed, file)
+		}
+	}
+	for file := range local {
+		if _, ok := remote[file]; !ok {
+			diff.Removed = append(diff.Removed, file)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	diff.Local = fingerprintFiles(local)
+	diff.Remote = fingerprintFiles(remote)
+	diff.LocalEndpoint = findCredentialsEndpoint(local)
+	diff.RemoteEndpoint = findCredentialsEndpoint(remote)
+
+	return diff
+}
+
+// fingerprintFiles calculates the fingerprints of the certificates in a credentials bundle, leaving invalid certificates blank
+func fingerprintFiles(files map[string][]byte) CredentialsFingerprint {
+	var fingerprint CredentialsFingerprint
+	var err error
+
+	fingerprint.CA, err = fingerprintCertificate(files[caCertFilename])
+	if err != nil {
+		common.Log.WriteDebug("Unable to fingerprint %s: %s", caCertFilename, err)
+	}
+	fingerprint.Cert, err = fingerprintCertificate(files[clientCertFilename])
+	if err != nil {
+		common.Log.WriteDebug("Unable to fingerprint %s: %s", clientCertFilename, err)
+	}
+
+	return fingerprint
+}
+
+// findCredentialsEndpoint returns the cluster address from either the docker or kubectl configuration in a credentials bundle
+func findCredentialsEndpoint(files map[string][]byte) string {
+	if contents, ok := files["docker.env"]; ok {
+		if match := dockerHostPattern.FindSubmatch(contents); match != nil {
+			return string(match[1])
+		}
+	}
+
+	if contents, ok := files[bundleKubeconfigFilename]; ok {
+		if server, err := parseBundleServer(contents); err == nil {
+			return server
+		}
+	}
+
+	return ""
+}
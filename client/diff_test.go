@@ -0,0 +1,41 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffCredentials(t *testing.T) {
+	local := map[string][]byte{
+		caCertFilename:     buildCertificate("ca"),
+		clientCertFilename: buildCertificate("cert"),
+		"docker.env":       []byte("export DOCKER_HOST=tcp://10.0.0.1:2376\n"),
+		"docker.ps1":       []byte("$env:DOCKER_HOST=\"tcp://10.0.0.1:2376\"\n"),
+	}
+	remote := map[string][]byte{
+		caCertFilename:     buildCertificate("ca"),
+		clientCertFilename: buildCertificate("new-cert"),
+		"docker.env":       []byte("export DOCKER_HOST=tcp://10.0.0.2:2376\n"),
+		"docker.fish":      []byte("set -x DOCKER_HOST tcp://10.0.0.2:2376\n"),
+	}
+
+	diff := diffCredentials(local, remote)
+
+	assert.True(t, diff.HasChanges())
+	assert.Equal(t, []string{"docker.fish"}, diff.Added)
+	assert.Equal(t, []string{"docker.ps1"}, diff.Removed)
+	assert.Equal(t, []string{clientCertFilename, "docker.env"}, diff.Changed)
+	assert.Equal(t, diff.Local.CA, diff.Remote.CA)
+	assert.NotEqual(t, diff.Local.Cert, diff.Remote.Cert)
+	assert.Equal(t, "tcp://10.0.0.1:2376", diff.LocalEndpoint)
+	assert.Equal(t, "tcp://10.0.0.2:2376", diff.RemoteEndpoint)
+}
+
+func TestDiffCredentialsWithoutChanges(t *testing.T) {
+	files := map[string][]byte{caCertFilename: buildCertificate("ca")}
+
+	diff := diffCredentials(files, files)
+
+	assert.False(t, diff.HasChanges())
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CredentialsIssueKind is a problem found in the credentials directory
+type CredentialsIssueKind string
+
+const (
+	// CredentialsIssueStale means the credentials are for a cluster which no longer exists
+	CredentialsIssueStale CredentialsIssueKind = "stale"
+
+	// CredentialsIssueExpired means the client certificate of an existing cluster has expired
+	CredentialsIssueExpired CredentialsIssueKind = "expired"
+
+	// CredentialsIssueOrphaned means the directory belongs to another account, e.g. one which was renamed,
+	// and none of its credentials have been downloaded recently
+	CredentialsIssueOrphaned CredentialsIssueKind = "orphaned"
+)
+
+// CredentialsIssue is a problem found in the credentials directory, and the result of fixing it
+type CredentialsIssue struct {
+	Kind CredentialsIssueKind
+
+	// Name is the cluster name, or the account directory for orphaned directories
+	Name string
+	Path string
+
+	// Details explains the problem
+	Details string
+
+	// Fixed is set when the problem was fixed
+	Fixed bool
+
+	// Err describes why the problem couldn't be fixed
+	Err error
+}
+
+// DiagnoseCredentialsDir scans the credentials directory for credentials of clusters which no longer exist,
+// expired client certificates, and directories of other accounts which haven't been used within orphanedAfter
+func (client *Client) DiagnoseCredentialsDir(account Account, orphanedAfter time.Duration) ([]CredentialsIssue, error) {
+	clusters, err := client.ListClusters(account, ListClustersOptions{})
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		exists[cluster.GetName()] = true
+	}
+
+	names, err := ListDownloadedCredentials(account)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []CredentialsIssue
+	for _, name := range names {
+		credentialsPath, err := buildClusterCredentialsPath(account, name, "")
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists[name] {
+			issues = append(issues, CredentialsIssue{Kind: CredentialsIssueStale, Name: name, Path: credentialsPath, Details: "The cluster no longer exists"})
+			continue
+		}
+
+		cert, err := client.readCertificateInfo(credentialsPath, clientCertFilename)
+		if err == nil && cert.ExpiresWithin(0) {
+			issues = append(issues, CredentialsIssue{Kind: CredentialsIssueExpired, Name: name, Path: credentialsPath,
+				Details: fmt.Sprintf("The client certificate expired %s", cert.NotAfter.Local().Format(time.RFC3339))})
+		}
+	}
+
+	orphaned, err := findOrphanedAccountDirs(account, orphanedAfter)
+	if err != nil {
+		return nil, err
+	}
+	return append(issues, orphaned...), nil
+}
+
+// findOrphanedAccountDirs returns the account directories, other than the current account's, where nothing has been modified within orphanedAfter.
+// Account directories are only known with the default credentials path template.
+func findOrphanedAccountDirs(account Account, orphanedAfter time.Duration) ([]CredentialsIssue, error) {
+	if CredentialsStoragePolicy.PathTemplate != "" && CredentialsStoragePolicy.PathTemplate != DefaultCredentialsPathTemplate {
+		return nil, nil
+	}
+
+	clusterPrefix, err := account.GetClusterPrefix()
+	if err != nil {
+		return nil, err
+	}
+
+	clustersDir, err := getClustersDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(clustersDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to list the credentials directory")
+	}
+
+	var issues []CredentialsIssue
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == clusterPrefix {
+			continue
+		}
+
+		accountDir := filepath.Join(clustersDir, entry.Name())
+		lastModified, err := findLastModified(accountDir)
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(lastModified) < orphanedAfter {
+			continue
+		}
+
+		issues = append(issues, CredentialsIssue{Kind: CredentialsIssueOrphaned, Name: entry.Name(), Path: accountDir,
+			Details: fmt.Sprintf("Belongs to another account, and hasn't changed since %s", lastModified.Local().Format(time.RFC3339))})
+	}
+
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Name < issues[j].Name })
+	return issues, nil
+}
+
+// findLastModified returns the most recent modification time of a directory or anything in it
+func findLastModified(dir string) (time.Time, error) {
+	var lastModified time.Time
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(lastModified) {
+			lastModified = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return lastModified, errors.Wrapf(err, "Unable to read %s", dir)
+	}
+	return lastModified, nil
+}
+
+// FixCredentialsIssues deletes stale and orphaned credentials, and downloads expired credentials again,
+// returning the issues with the result of each fix
+func (client *Client) FixCredentialsIssues(account Account, issues []CredentialsIssue) []CredentialsIssue {
+	fixed := make([]CredentialsIssue, len(issues))
+	for i, issue := range issues {
+		switch issue.Kind {
+		case CredentialsIssueExpired:
+			issue.Err = client.refreshClusterCredentials(account, issue.Name, 0).Err
+		default:
+			issue.Err = removeCredentialsDir(issue.Path)
+		}
+		issue.Fixed = issue.Err == nil
+		fixed[i] = issue
+	}
+	return fixed
+}
+
+// removeCredentialsDir deletes a directory from the credentials directory, along with its decrypted copy
+func removeCredentialsDir(credentialsPath string) error {
+	clustersDir, err := getClustersDir()
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(clustersDir, credentialsPath); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return errors.Errorf("%s is not in the credentials directory %s, not deleting", credentialsPath, clustersDir)
+	}
+
+	err = os.RemoveAll(credentialsPath)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to delete %s", credentialsPath)
+	}
+
+	if runtimePath, err := buildRuntimeCredentialsPath(credentialsPath); err == nil {
+		os.RemoveAll(runtimePath)
+	}
+	return nil
+}
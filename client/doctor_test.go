@@ -0,0 +1,75 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnoseAndFixCredentialsDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	CredentialsStoragePolicy.Dir = dir
+	defer func() { CredentialsStoragePolicy.Dir = "" }()
+
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("current", "Swarm*", 1)
+	service.CreateCluster("expired", "Swarm*", 1)
+	account := &historyAccount{offlineAccount{service: service}}
+
+	now := time.Now()
+	writeCert := func(path string, notAfter time.Time) {
+		assert.Nil(t, os.MkdirAll(path, 0700))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(path, clientCertFilename), buildSignedCertificate(t, notAfter), 0600))
+	}
+	writeCert(filepath.Join(dir, "stub-user", "current"), now.Add(30*24*time.Hour))
+	writeCert(filepath.Join(dir, "stub-user", "expired"), now.Add(-time.Hour))
+	writeCert(filepath.Join(dir, "stub-user", "deleted"), now.Add(30*24*time.Hour))
+	writeCert(filepath.Join(dir, "public-carol", "recent"), now.Add(30*24*time.Hour))
+
+	// Age another account's credentials, as if the account was renamed long ago
+	oldAccountDir := filepath.Join(dir, "public-bob")
+	writeCert(filepath.Join(oldAccountDir, "old"), now.Add(-time.Hour))
+	longAgo := now.Add(-365 * 24 * time.Hour)
+	for _, path := range []string{filepath.Join(oldAccountDir, "old", clientCertFilename), filepath.Join(oldAccountDir, "old"), oldAccountDir} {
+		assert.Nil(t, os.Chtimes(path, longAgo, longAgo))
+	}
+
+	issues, err := client.DiagnoseCredentialsDir(account, 90*24*time.Hour)
+	if !assert.Nil(t, err) || !assert.Len(t, issues, 3) {
+		return
+	}
+	assert.Equal(t, CredentialsIssueStale, issues[0].Kind)
+	assert.Equal(t, "deleted", issues[0].Name)
+	assert.Equal(t, CredentialsIssueExpired, issues[1].Kind)
+	assert.Equal(t, "expired", issues[1].Name)
+	assert.Equal(t, CredentialsIssueOrphaned, issues[2].Kind)
+	assert.Equal(t, "public-bob", issues[2].Name)
+
+	issues = client.FixCredentialsIssues(account, issues)
+	for _, issue := range issues {
+		assert.True(t, issue.Fixed, "%s %s: %v", issue.Kind, issue.Name, issue.Err)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "stub-user", "deleted"))
+	assert.True(t, os.IsNotExist(err), "The stale credentials should be deleted")
+	_, err = os.Stat(oldAccountDir)
+	assert.True(t, os.IsNotExist(err), "The orphaned account directory should be deleted")
+	_, err = os.Stat(filepath.Join(dir, "public-carol", "recent"))
+	assert.Nil(t, err, "Recently used account directories are not orphaned")
+	contents, _ := ioutil.ReadFile(filepath.Join(dir, "stub-user", "expired", clientCertFilename))
+	assert.Equal(t, "fake-cert", string(contents), "The expired credentials should be downloaded again")
+}
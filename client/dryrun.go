@@ -0,0 +1,296 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// OperationPlan describes what a mutating operation would do, without calling the mutating API. Used by --dry-run.
+type OperationPlan struct {
+	// Operation is the command being simulated, e.g. create or delete
+	Operation string
+
+	// Steps describes each change which would be made
+	Steps []string
+
+	// Warnings are problems which would not stop the operation, such as using a deprecated template
+	Warnings []string
+}
+
+func (plan *OperationPlan) addStep(format string, a ...interface{}) {
+	plan.Steps = append(plan.Steps, fmt.Sprintf(format, a...))
+}
+
+func (plan *OperationPlan) addWarning(format string, a ...interface{}) {
+	plan.Warnings = append(plan.Warnings, fmt.Sprintf(format, a...))
+}
+
+// PlanCreateCluster validates creating a cluster: the name must be available, the template pattern must match
+// a single template, and the account must have enough quota
+func (client *Client) PlanCreateCluster(account Account, name string, template string, nodes int, options CreateClusterOptions) (*OperationPlan, error) {
+	return client.PlanCreateClusters(account, []string{name}, template, nodes, options)
+}
+
+// PlanCreateClusters validates creating multiple clusters with the same template and number of nodes
+func (client *Client) PlanCreateClusters(account Account, names []string, template string, nodes int, options CreateClusterOptions) (*OperationPlan, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &OperationPlan{Operation: "create"}
+
+	clusters, err := svc.ListClusters()
+	if err != nil {
+		return nil, wrapClientError(err)
+	}
+	existing := make(map[string]bool)
+	for _, cluster := range clusters {
+		for _, name := range names {
+			if cluster.GetName() != name {
+				continue
+			}
+			if !options.IfNotExists {
+				return nil, fmt.Errorf("A cluster named %s already exists", name)
+			}
+			existing[name] = true
+		}
+	}
+
+	templateName := template
+	if template != "" {
+		match, err := client.GetClusterTemplate(account, template)
+		if err != nil {
+			return nil, err
+		}
+		templateName = match.GetName()
+
+		if match.IsDeprecated() {
+			if !options.AllowDeprecated {
+				return nil, common.DeprecatedTemplateError{TemplateName: templateName}
+			}
+			plan.addWarning("The template %s is deprecated", templateName)
+		}
+		if availability := match.GetAvailability(); availability != nil && availability.Constrained {
+			plan.addWarning("The template %s is %s", templateName, availability)
+		}
+	}
+
+	quotas, err := lookupQuotas(svc)
+	if err != nil {
+		return nil, err
+	}
+	err = checkClusterQuota(quotas, len(clusters)+len(names)-len(existing))
+	if err != nil {
+		return nil, err
+	}
+	err = checkNodesQuota(quotas, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options.DriverOptions) > 0 {
+		if _, ok := svc.(common.DriverOptionsCreator); !ok {
+			return nil, errors.New("Driver options are not supported by this cloud")
+		}
+	}
+
+	if options.SSHKey != (common.SSHKey{}) {
+		if _, ok := svc.(common.SSHKeyCreator); !ok {
+			return nil, errors.New("SSH keys are not supported by this cloud")
+		}
+	}
+
+	for _, name := range names {
+		if existing[name] {
+			plan.addStep("Use the existing cluster (%s)", name)
+			if options.WaitUntilActive {
+				plan.addStep("Wait for cluster (%s) to become active", name)
+			}
+			continue
+		}
+
+		if templateName == "" {
+			plan.addStep("Create cluster (%s) with %d nodes", name, nodes)
+		} else {
+			plan.addStep("Create cluster (%s) with %d nodes from the template %s", name, nodes, templateName)
+		}
+		if len(options.Labels) > 0 {
+			plan.addStep("Label cluster (%s) with %s", name, formatKeyValuePairs(options.Labels))
+		}
+		if len(options.DriverOptions) > 0 {
+			plan.addStep("Pass the driver options %s", formatKeyValuePairs(options.DriverOptions))
+		}
+		if options.SSHKey.KeypairName != "" {
+			plan.addStep("Install the keypair %s on the nodes", options.SSHKey.KeypairName)
+		} else if options.SSHKey.PublicKey != "" {
+			plan.addStep("Install the SSH public key on the nodes")
+		}
+		if options.WaitUntilActive {
+			plan.addStep("Wait for cluster (%s) to become active", name)
+		}
+	}
+
+	return plan, nil
+}
+
+// PlanResizeCluster validates resizing a cluster to the specified number of nodes
+func (client *Client) PlanResizeCluster(account Account, name string, nodes int) (*OperationPlan, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := svc.GetCluster(name)
+	if err != nil {
+		return nil, wrapClientError(err)
+	}
+
+	quotas, err := lookupQuotas(svc)
+	if err != nil {
+		return nil, err
+	}
+	err = checkNodesQuota(quotas, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &OperationPlan{Operation: "resize"}
+	current, convErr := common.ParseNodeCount(cluster.GetNodes())
+	if convErr != nil {
+		plan.addStep("Resize cluster (%s) to %d nodes", name, nodes)
+	} else if current == nodes {
+		plan.addWarning("Cluster (%s) already has %d nodes", name, nodes)
+	} else {
+		plan.addStep("Resize cluster (%s) from %d to %d nodes", name, current, nodes)
+	}
+	return plan, nil
+}
+
+// PlanGrowCluster validates adding nodes to a cluster
+func (client *Client) PlanGrowCluster(account Account, name string, nodes int) (*OperationPlan, error) {
+	cluster, err := client.GetCluster(account, name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := common.ParseNodeCount(cluster.GetNodes())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to grow cluster (%s), its number of nodes is unknown", name)
+	}
+
+	plan, err := client.PlanResizeCluster(account, name, current+nodes)
+	if plan != nil {
+		plan.Operation = "grow"
+	}
+	return plan, err
+}
+
+// PlanRebuildCluster validates rebuilding a cluster
+func (client *Client) PlanRebuildCluster(account Account, name string) (*OperationPlan, error) {
+	_, err := client.GetCluster(account, name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &OperationPlan{Operation: "rebuild"}
+	plan.addStep("Rebuild cluster (%s), destroying and recreating its nodes", name)
+	return plan, nil
+}
+
+// PlanUpgradeCluster validates upgrading a cluster, running the same compatibility checks as UpgradeCluster
+func (client *Client) PlanUpgradeCluster(account Account, name string, template string) (*OperationPlan, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.checkSupported(svc, common.OperationUpgrade)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := svc.(common.ClusterUpgrader); !ok {
+		return nil, errors.New("Upgrading clusters is not supported by this cloud")
+	}
+
+	cluster, target, err := preflightUpgrade(svc, name, template)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &OperationPlan{Operation: "upgrade"}
+	plan.addStep("Upgrade cluster (%s) from %s to %s", name, cluster.GetTemplate().GetName(), target.GetName())
+	if target.GetCapabilities() == nil {
+		plan.addWarning("The %s template doesn't report its capabilities, so only its COE and version were checked", target.GetName())
+	}
+	return plan, nil
+}
+
+// PlanDeleteClusters validates deleting clusters, and the files which would be removed along with them
+func (client *Client) PlanDeleteClusters(account Account, names []string) (*OperationPlan, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &OperationPlan{Operation: "delete"}
+	for _, name := range names {
+		_, err := svc.GetCluster(name)
+		if err != nil {
+			return nil, wrapClientError(err)
+		}
+		plan.addStep("Delete cluster (%s)", name)
+
+		credentialsPath, err := buildClusterCredentialsPath(account, name, "")
+		if _, statErr := os.Stat(credentialsPath); err == nil && statErr == nil {
+			plan.addStep("Remove the credentials in %s", credentialsPath)
+		}
+	}
+	return plan, nil
+}
+
+// checkClusterQuota returns an error when the account would have more clusters than allowed. A quota of 0 is unknown.
+func checkClusterQuota(quotas *common.Quotas, clusters int) error {
+	if max := quotas.MaxClusters; max > 0 && clusters > max {
+		return fmt.Errorf("The account is limited to %d clusters. Run carina quotas for details", max)
+	}
+	return nil
+}
+
+// lookupQuotas retrieves the account's quotas to check a plan against, treating quotas which the cloud can't report as unknown
+func lookupQuotas(svc common.ClusterService) (*common.Quotas, error) {
+	quotas, err := svc.GetQuotas()
+	if errors.Cause(err) == common.ErrQuotasUnsupported {
+		common.Log.WriteDebug("Skipping the quota checks: %s", err)
+		return &common.Quotas{}, nil
+	}
+	return quotas, wrapClientError(err)
+}
+
+// checkNodesQuota returns an error when a cluster would have more nodes than allowed. A quota of 0 is unknown.
+func checkNodesQuota(quotas *common.Quotas, nodes int) error {
+	if max := quotas.MaxNodesPerCluster; max > 0 && nodes > max {
+		return fmt.Errorf("The account is limited to %d nodes per cluster. Run carina quotas for details", max)
+	}
+	return nil
+}
+
+// formatKeyValuePairs prints a map as a sorted list of key=value pairs
+func formatKeyValuePairs(values map[string]string) string {
+	var pairs []string
+	for key, value := range values {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
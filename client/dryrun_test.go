@@ -0,0 +1,95 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanDoesNotChangeClusters(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("existing", "Swarm*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+
+	plan, err := c.PlanCreateCluster(account, "mycluster", "Swarm*", 2, client.CreateClusterOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Create cluster (mycluster) with 2 nodes from the template Swarm 1.11.2 on LXC"}, plan.Steps)
+
+	plan, err = c.PlanResizeCluster(account, "existing", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Resize cluster (existing) from 1 to 3 nodes"}, plan.Steps)
+
+	plan, err = c.PlanDeleteClusters(account, []string{"existing"})
+	assert.Nil(t, err)
+	assert.Equal(t, "delete", plan.Operation)
+
+	clusters, _ := service.ListClusters()
+	assert.Len(t, clusters, 1)
+	cluster, _ := service.GetCluster("existing")
+	assert.Equal(t, "1", cluster.GetNodes())
+	assert.Equal(t, testsupport.StatusActive, cluster.GetStatus())
+}
+
+func TestPlanCreateClusterChecksQuotas(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+
+	_, err := c.PlanCreateCluster(account, "mycluster", "Swarm*", 20, client.CreateClusterOptions{})
+	assert.NotNil(t, err)
+
+	service.CreateCluster("existing", "Swarm*", 1)
+	_, err = c.PlanCreateCluster(account, "existing", "Swarm*", 1, client.CreateClusterOptions{})
+	assert.NotNil(t, err, "The cluster name is already used")
+
+	_, err = c.PlanCreateCluster(account, "mycluster", "Kubernetes*", 1, client.CreateClusterOptions{})
+	assert.NotNil(t, err, "No template matches the pattern")
+}
+
+// quotalessService is a cloud which can't report the account's quotas, such as magnum
+type quotalessService struct {
+	*testsupport.FakeClusterService
+}
+
+func (svc quotalessService) GetQuotas() (*common.Quotas, error) {
+	return nil, common.ErrQuotasUnsupported
+}
+
+func TestPlanWithUnsupportedQuotas(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("existing", "Swarm*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(quotalessService{service})
+
+	c := client.NewClient(false)
+
+	_, err := c.PlanCreateCluster(account, "mycluster", "Swarm*", 20, client.CreateClusterOptions{})
+	assert.Nil(t, err, "Unknown quotas should not be checked")
+
+	_, err = c.PlanResizeCluster(account, "existing", 20)
+	assert.Nil(t, err, "Unknown quotas should not be checked")
+}
+
+func TestPlanCreateClusterWarnsWhenTemplateIsConstrained(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{
+		Name:         "Swarm 1.11.2 on LXC",
+		Availability: &common.TemplateAvailability{Constrained: true, Reason: "low capacity"},
+	})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+
+	plan, err := c.PlanCreateCluster(account, "mycluster", "Swarm*", 1, client.CreateClusterOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"The template Swarm 1.11.2 on LXC is constrained: low capacity"}, plan.Warnings)
+}
@@ -0,0 +1,303 @@
+package client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// EncryptionNone stores the credentials and token cache in plain text
+	EncryptionNone = "none"
+
+	// EncryptionPassphrase encrypts with a key derived from the CARINA_PASSPHRASE environment variable
+	EncryptionPassphrase = "passphrase"
+
+	// EncryptionKeychain encrypts with a random key, which is stored in the operating system's keychain
+	EncryptionKeychain = "keychain"
+)
+
+// CarinaPassphraseEnvVar is the passphrase used when credentials.encryption is passphrase
+const CarinaPassphraseEnvVar = "CARINA_PASSPHRASE"
+
+// keyringService and keyringUser identify the encryption key in the keychain
+const keyringService = "carina"
+const keyringUser = "credentials-encryption-key"
+
+// encryptedFileHeader identifies an encrypted file, followed by the encryption mode and a newline
+const encryptedFileHeader = "carina-encrypted:v1:"
+
+const saltSize = 16
+
+// ValidateEncryptionMode checks the credentials.encryption setting
+func ValidateEncryptionMode(mode string) error {
+	switch mode {
+	case "", EncryptionNone, EncryptionPassphrase, EncryptionKeychain:
+		return nil
+	default:
+		return fmt.Errorf("Invalid credentials.encryption: %s. Allowed values: %s, %s, %s", mode, EncryptionNone, EncryptionPassphrase, EncryptionKeychain)
+	}
+}
+
+// fileCipher encrypts the files stored in CARINA_HOME
+type fileCipher interface {
+	mode() string
+	encrypt(plaintext []byte) ([]byte, error)
+	decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// newFileCipher builds the cipher for an encryption mode, or returns nil when files are not encrypted
+func newFileCipher(mode string) (fileCipher, error) {
+	switch mode {
+	case "", EncryptionNone:
+		return nil, nil
+	case EncryptionPassphrase:
+		passphrase := os.Getenv(CarinaPassphraseEnvVar)
+		if passphrase == "" {
+			return nil, fmt.Errorf("The %s environment variable is required when credentials.encryption is %s", CarinaPassphraseEnvVar, EncryptionPassphrase)
+		}
+		return &passphraseCipher{passphrase: []byte(passphrase), keys: make(map[string][]byte)}, nil
+	case EncryptionKeychain:
+		key, err := loadKeychainKey(newSystemKeyring())
+		if err != nil {
+			return nil, err
+		}
+		return &keyCipher{key: key}, nil
+	default:
+		return nil, ValidateEncryptionMode(mode)
+	}
+}
+
+// loadKeychainKey retrieves the encryption key from the keyring, generating it on first use
+func loadKeychainKey(keyring Keyring) ([]byte, error) {
+	secret, err := keyring.Get(keyringService, keyringUser)
+	if err == nil {
+		key, err := hex.DecodeString(secret)
+		if err != nil || len(key) != 32 {
+			return nil, errors.New("The credentials encryption key in the keychain is invalid")
+		}
+		return key, nil
+	}
+	if err != ErrKeyringItemNotFound {
+		return nil, errors.Wrap(err, "Unable to retrieve the credentials encryption key from the keychain")
+	}
+
+	key, err := generateEncryptionKey()
+	if err != nil {
+		return nil, err
+	}
+
+	err = keyring.Set(keyringService, keyringUser, hex.EncodeToString(key))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to save the credentials encryption key to the keychain")
+	}
+	return key, nil
+}
+
+// generateEncryptionKey returns a random key for keychain encryption
+func generateEncryptionKey() ([]byte, error) {
+	key := make([]byte, 32)
+	_, err := io.ReadFull(cryptorand.Reader, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to generate the credentials encryption key")
+	}
+	return key, nil
+}
+
+// keyCipher encrypts with a fixed key
+type keyCipher struct {
+	key []byte
+}
+
+func (c *keyCipher) mode() string {
+	return EncryptionKeychain
+}
+
+func (c *keyCipher) encrypt(plaintext []byte) ([]byte, error) {
+	var output bytes.Buffer
+	output.WriteString(encryptedFileHeader + c.mode() + "\n")
+	err := seal(&output, c.key, plaintext)
+	return output.Bytes(), err
+}
+
+func (c *keyCipher) decrypt(ciphertext []byte) ([]byte, error) {
+	body, err := readEncryptedBody(ciphertext, c.mode())
+	if err != nil {
+		return nil, err
+	}
+	return open(c.key, body)
+}
+
+// passphraseCipher encrypts with a key derived from a passphrase, using a random salt for each file
+type passphraseCipher struct {
+	passphrase []byte
+
+	// keys caches the derived key for each salt, since key derivation is deliberately slow
+	keysLock sync.Mutex
+	keys     map[string][]byte
+}
+
+func (c *passphraseCipher) mode() string {
+	return EncryptionPassphrase
+}
+
+func (c *passphraseCipher) deriveKey(salt []byte) ([]byte, error) {
+	c.keysLock.Lock()
+	defer c.keysLock.Unlock()
+
+	if key, ok := c.keys[string(salt)]; ok {
+		return key, nil
+	}
+
+	key, err := scrypt.Key(c.passphrase, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to derive the encryption key from the passphrase")
+	}
+	c.keys[string(salt)] = key
+	return key, nil
+}
+
+func (c *passphraseCipher) encrypt(plaintext []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	_, err := io.ReadFull(cryptorand.Reader, salt)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to generate a salt")
+	}
+
+	key, err := c.deriveKey(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	var output bytes.Buffer
+	output.WriteString(encryptedFileHeader + c.mode() + "\n")
+	output.Write(salt)
+	err = seal(&output, key, plaintext)
+	return output.Bytes(), err
+}
+
+func (c *passphraseCipher) decrypt(ciphertext []byte) ([]byte, error) {
+	body, err := readEncryptedBody(ciphertext, c.mode())
+	if err != nil {
+		return nil, err
+	}
+	if len(body) < saltSize {
+		return nil, errors.New("Unable to decrypt, the file is truncated")
+	}
+
+	key, err := c.deriveKey(body[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	return open(key, body[saltSize:])
+}
+
+// isEncrypted returns if the contents of a file were encrypted by a fileCipher
+func isEncrypted(contents []byte) bool {
+	return bytes.HasPrefix(contents, []byte(encryptedFileHeader))
+}
+
+// readEncryptedBody checks the header of an encrypted file, and returns the encrypted data following it
+func readEncryptedBody(contents []byte, mode string) ([]byte, error) {
+	header := []byte(encryptedFileHeader + mode + "\n")
+	if !bytes.HasPrefix(contents, header) {
+		return nil, fmt.Errorf("Unable to decrypt, the file was not encrypted with credentials.encryption=%s", mode)
+	}
+	return contents[len(header):], nil
+}
+
+// seal encrypts the plaintext with AES-GCM, writing the nonce followed by the ciphertext
+func seal(output *bytes.Buffer, key []byte, plaintext []byte) error {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(cryptorand.Reader, nonce)
+	if err != nil {
+		return errors.Wrap(err, "Unable to generate a nonce")
+	}
+
+	output.Write(nonce)
+	output.Write(gcm.Seal(nil, nonce, plaintext, nil))
+	return nil
+}
+
+// open decrypts data written by seal
+func open(key []byte, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("Unable to decrypt, the file is truncated")
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("Unable to decrypt, the key or passphrase is incorrect")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid encryption key")
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptContents decrypts the contents of a file when it is encrypted, otherwise the contents are returned as-is
+func decryptContents(c fileCipher, contents []byte) ([]byte, error) {
+	if !isEncrypted(contents) {
+		return contents, nil
+	}
+	if c == nil {
+		return nil, errors.New("The file is encrypted, but credentials.encryption is not set")
+	}
+	return c.decrypt(contents)
+}
+
+// readSecureFile reads a file from CARINA_HOME, decrypting it if necessary
+func (client *Client) readSecureFile(path string) ([]byte, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err = decryptContents(client.cipher, contents)
+	return contents, errors.Wrapf(err, "Unable to read %s", path)
+}
+
+// writeSecureFile writes a file to CARINA_HOME, encrypting it when credentials.encryption is enabled.
+// Files written to a custom path are never encrypted, so that they can be used directly.
+func (client *Client) writeSecureFile(path string, contents []byte, customPath string) error {
+	if client.cipher != nil && customPath == "" {
+		var err error
+		contents, err = client.cipher.encrypt(contents)
+		if err != nil {
+			return errors.Wrapf(err, "Unable to encrypt %s", path)
+		}
+	}
+
+	err := ioutil.WriteFile(path, contents, 0600)
+	if err != nil {
+		return err
+	}
+
+	restrictAccess(path)
+	return nil
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubKeyring struct {
+	secrets map[string]string
+}
+
+func (keyring *stubKeyring) Get(service string, user string) (string, error) {
+	secret, ok := keyring.secrets[service+"/"+user]
+	if !ok {
+		return "", ErrKeyringItemNotFound
+	}
+	return secret, nil
+}
+
+func (keyring *stubKeyring) Set(service string, user string, secret string) error {
+	keyring.secrets[service+"/"+user] = secret
+	return nil
+}
+
+func TestKeyCipherRoundTrip(t *testing.T) {
+	keyring := &stubKeyring{secrets: make(map[string]string)}
+	key, err := loadKeychainKey(keyring)
+	assert.Nil(t, err)
+
+	sameKey, err := loadKeychainKey(keyring)
+	assert.Nil(t, err)
+	assert.Equal(t, key, sameKey, "The key should be generated once and then reused")
+
+	c := &keyCipher{key: key}
+	ciphertext, err := c.encrypt([]byte("secret"))
+	assert.Nil(t, err)
+	assert.True(t, isEncrypted(ciphertext))
+	assert.NotContains(t, string(ciphertext), "secret")
+
+	plaintext, err := c.decrypt(ciphertext)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+}
+
+func TestPassphraseCipherRoundTrip(t *testing.T) {
+	c := &passphraseCipher{passphrase: []byte("correct horse"), keys: make(map[string][]byte)}
+	ciphertext, err := c.encrypt([]byte("secret"))
+	assert.Nil(t, err)
+
+	plaintext, err := c.decrypt(ciphertext)
+	assert.Nil(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	wrong := &passphraseCipher{passphrase: []byte("battery staple"), keys: make(map[string][]byte)}
+	_, err = wrong.decrypt(ciphertext)
+	assert.NotNil(t, err)
+}
+
+func TestDecryptContentsReadsPlainText(t *testing.T) {
+	contents, err := decryptContents(nil, []byte("plain"))
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", string(contents))
+}
+
+func TestEncryptedCache(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	keyCipher := &keyCipher{key: make([]byte, 32)}
+	cache := newCache(filename)
+	cache.cipher = keyCipher
+	err := cache.SaveClusterPreference(&stubAccount{}, "mycluster", "shell", "fish")
+	assert.Nil(t, err)
+
+	contents, _ := ioutil.ReadFile(filename)
+	assert.True(t, isEncrypted(contents))
+
+	// An encrypted cache can't be loaded without the key
+	err = newCache(filename).load()
+	assert.NotNil(t, err)
+
+	cache = newCache(filename)
+	cache.cipher = keyCipher
+	err = cache.load()
+	assert.Nil(t, err)
+	assert.Equal(t, "fish", cache.GetClusterPreference(&stubAccount{}, "mycluster", "shell"))
+}
+
+func TestDecryptClusterCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-credentials")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	CredentialsStoragePolicy.Dir = filepath.Join(dir, "clusters")
+	defer func() { CredentialsStoragePolicy.Dir = "" }()
+	defer os.Setenv(runtimeDirEnvVar, os.Getenv(runtimeDirEnvVar))
+	os.Unsetenv(runtimeDirEnvVar)
+
+	client := &Client{Cache: &Cache{}, cipher: &keyCipher{key: make([]byte, 32)}}
+	credentialsPath, err := buildClusterCredentialsPath(prefixAccount{}, "mycluster", "")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(credentialsPath, 0700))
+	require.NoError(t, client.writeSecureFile(filepath.Join(credentialsPath, "ca.pem"), []byte("ca"), ""))
+
+	tempPath, err := client.decryptClusterCredentials(credentialsPath, "")
+	require.NoError(t, err)
+	contents, err := ioutil.ReadFile(filepath.Join(tempPath, "ca.pem"))
+	require.NoError(t, err)
+	assert.Equal(t, "ca", string(contents))
+	client.Shutdown()
+	_, err = os.Stat(tempPath)
+	assert.True(t, os.IsNotExist(err), "the decrypted credentials should be wiped when carina exits")
+
+	_, err = client.decryptSessionCredentials(credentialsPath, "")
+	assert.Equal(t, errNoSessionDir, err, "the decrypted credentials should not outlive carina without XDG_RUNTIME_DIR")
+
+	runtimeDir := filepath.Join(dir, "run")
+	require.NoError(t, os.Mkdir(runtimeDir, 0700))
+	os.Setenv(runtimeDirEnvVar, runtimeDir)
+	sessionPath, err := client.decryptSessionCredentials(credentialsPath, "")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sessionPath, runtimeDir), sessionPath)
+
+	if runtime.GOOS != "windows" {
+		// A directory which other users can access may have been created by one of them to intercept the credentials
+		require.NoError(t, os.Chmod(filepath.Dir(sessionPath), 0777))
+		_, err = client.decryptSessionCredentials(credentialsPath, "")
+		assert.Error(t, err)
+	}
+}
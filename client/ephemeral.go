@@ -0,0 +1,159 @@
+package client
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// DownloadEphemeralClusterCredentials downloads a cluster's credentials to a private temporary directory, instead of CARINA_HOME,
+// for machines where the credentials must not outlive the command, e.g. a shared or ephemeral machine.
+// The credentials must be removed with wipe once they are no longer needed, and are also removed if the cli is interrupted.
+func (client *Client) DownloadEphemeralClusterCredentials(account Account, name string) (credentialsPath string, wipe func(), err error) {
+	files, err := client.getClusterCredentialsFiles(account, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	credentialsPath, wipe, err = client.writeTemporaryCredentials(files)
+	if err != nil {
+		return "", nil, err
+	}
+
+	common.Log.WriteDebug("Credentials for %s written to %s, until the command exits", name, credentialsPath)
+	return credentialsPath, wipe, nil
+}
+
+// writeTemporaryCredentials writes credentials files to a new private temporary directory, which is wiped when carina exits,
+// returning the function which wipes it sooner
+func (client *Client) writeTemporaryCredentials(files map[string][]byte) (credentialsPath string, wipe func(), err error) {
+	credentialsPath, err = ioutil.TempDir("", "carina-credentials-")
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Unable to create a temporary directory for the credentials")
+	}
+	wipe = client.trackEphemeralCredentials(credentialsPath)
+	restrictAccess(credentialsPath)
+
+	err = checkPrivateDir(credentialsPath)
+	if err != nil {
+		wipe()
+		return "", nil, err
+	}
+
+	for file, contents := range files {
+		err = ioutil.WriteFile(filepath.Join(credentialsPath, file), contents, 0600)
+		if err != nil {
+			wipe()
+			return "", nil, errors.Wrapf(err, "Unable to write %s to the temporary directory", file)
+		}
+	}
+	return credentialsPath, wipe, nil
+}
+
+// BuildEphemeralClusterEnvironment returns the environment for running a command against a cluster, like BuildClusterEnvironment,
+// with the credentials downloaded to a temporary directory which must be removed with wipe after the command exits
+func (client *Client) BuildEphemeralClusterEnvironment(account Account, name string, environ []string) (env []string, wipe func(), err error) {
+	credentialsPath, wipe, err := client.DownloadEphemeralClusterCredentials(account, name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	variables, err := readCredentialScriptVariables(credentialsPath)
+	if err != nil {
+		wipe()
+		return nil, nil, err
+	}
+
+	return mergeEnvironment(environ, variables), wipe, nil
+}
+
+// WriteClusterCredentialsTar downloads a cluster's credentials and writes them as a tar stream, without saving them to disk.
+// The files are in a directory named after the cluster, e.g. tar -x extracts mycluster/ca.pem.
+func (client *Client) WriteClusterCredentialsTar(account Account, name string, w io.Writer) error {
+	files, err := client.getClusterCredentialsFiles(account, name)
+	if err != nil {
+		return err
+	}
+
+	writer := newTarCredentialsWriter(w)
+	_, err = writer.WriteCredentials(account, name, files, "")
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// getClusterCredentialsFiles downloads the files in a cluster's credentials bundle, without saving them
+func (client *Client) getClusterCredentialsFiles(account Account, name string) (map[string][]byte, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	defer client.endOperation(client.startOperation(name, "Download credentials for cluster (%s)", name))
+	creds, err := svc.GetClusterCredentials(name)
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+	return creds.Files, nil
+}
+
+// trackEphemeralCredentials remembers a temporary credentials directory, so that it is wiped if the cli is interrupted,
+// returning the function which wipes it
+func (client *Client) trackEphemeralCredentials(credentialsPath string) func() {
+	client.operationsLock.Lock()
+	defer client.operationsLock.Unlock()
+
+	if client.ephemeralCredentials == nil {
+		client.ephemeralCredentials = make(map[string]bool)
+	}
+	client.ephemeralCredentials[credentialsPath] = true
+
+	return func() {
+		client.operationsLock.Lock()
+		delete(client.ephemeralCredentials, credentialsPath)
+		client.operationsLock.Unlock()
+
+		wipeCredentials(credentialsPath)
+	}
+}
+
+// wipeEphemeralCredentials removes the temporary credentials directories which haven't been wiped yet
+func (client *Client) wipeEphemeralCredentials() {
+	client.operationsLock.Lock()
+	var paths []string
+	for path := range client.ephemeralCredentials {
+		paths = append(paths, path)
+	}
+	client.ephemeralCredentials = nil
+	client.operationsLock.Unlock()
+
+	for _, path := range paths {
+		wipeCredentials(path)
+	}
+}
+
+// wipeCredentials overwrites each file in a credentials directory with zeros, then removes the directory,
+// so that the keys can't be recovered from the blocks which were freed
+func wipeCredentials(credentialsPath string) {
+	files, _ := ioutil.ReadDir(credentialsPath)
+	for _, file := range files {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		err := ioutil.WriteFile(filepath.Join(credentialsPath, file.Name()), make([]byte, file.Size()), 0600)
+		if err != nil {
+			common.Log.WriteDebug("Unable to overwrite %s: %s", file.Name(), err)
+		}
+	}
+
+	err := os.RemoveAll(credentialsPath)
+	if err != nil {
+		common.Log.WriteWarning("WARNING: Unable to remove the temporary credentials in %s: %s", credentialsPath, err)
+	}
+}
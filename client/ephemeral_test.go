@@ -0,0 +1,64 @@
+package client
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEphemeralClusterCredentials(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+	client := &Client{Cache: &Cache{}}
+
+	_, err := service.CreateCluster("web", "Swarm 1.11.2 on LXC", 1)
+	require.NoError(t, err)
+
+	credentialsPath, wipe, err := client.DownloadEphemeralClusterCredentials(account, "web")
+	require.NoError(t, err)
+	contents, err := ioutil.ReadFile(filepath.Join(credentialsPath, "ca.pem"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, contents)
+
+	wipe()
+	_, err = os.Stat(credentialsPath)
+	assert.True(t, os.IsNotExist(err), "the credentials should be wiped")
+
+	credentialsPath, _, err = client.DownloadEphemeralClusterCredentials(account, "web")
+	require.NoError(t, err)
+	client.Shutdown()
+	_, err = os.Stat(credentialsPath)
+	assert.True(t, os.IsNotExist(err), "the credentials should be wiped when the cli is interrupted")
+}
+
+func TestWriteClusterCredentialsTar(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+	client := &Client{Cache: &Cache{}}
+
+	_, err := service.CreateCluster("web", "Swarm 1.11.2 on LXC", 1)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, client.WriteClusterCredentialsTar(account, "web", &buf))
+
+	var names []string
+	archive := tar.NewReader(&buf)
+	for {
+		header, err := archive.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	assert.Contains(t, names, "web/")
+	assert.Contains(t, names, "web/ca.pem")
+	assert.Contains(t, names, "web/docker.env")
+}
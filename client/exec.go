@@ -0,0 +1,70 @@
+package client
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BuildClusterEnvironment returns the environment for running a command against a cluster, e.g. docker or kubectl.
+// The variables exported by the cluster's credentials script replace any docker/kubectl variables in environ,
+// so that a command isn't pointed at another cluster by variables left over from the parent shell.
+func (client *Client) BuildClusterEnvironment(account Account, name string, customPath string, environ []string) ([]string, error) {
+	credentialsPath, err := client.ensureClusterCredentials(account, name, customPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The command may change its working directory, so the paths to the credentials must be absolute
+	credentialsPath, err = filepath.Abs(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	variables, err := readCredentialScriptVariables(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeEnvironment(environ, variables), nil
+}
+
+// readCredentialScriptVariables reads the environment variables exported by the bash script in a credentials bundle
+func readCredentialScriptVariables(credentialsPath string) ([]scriptVariable, error) {
+	scriptPrefix, err := getCredentialScriptPrefix(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(credentialsPath, scriptPrefix+".env"))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read the bash script from the credentials bundle")
+	}
+
+	return parseBashScript(contents, credentialsPath)
+}
+
+// mergeEnvironment removes the docker/kubectl variables from environ, then adds the variables from a credentials script
+func mergeEnvironment(environ []string, variables []scriptVariable) []string {
+	cleared := make(map[string]bool)
+	for _, name := range credentialEnvVars {
+		cleared[name] = true
+	}
+	for _, variable := range variables {
+		cleared[variable.Name] = true
+	}
+
+	var env []string
+	for _, entry := range environ {
+		name := strings.SplitN(entry, "=", 2)[0]
+		if !cleared[name] {
+			env = append(env, entry)
+		}
+	}
+	for _, variable := range variables {
+		env = append(env, variable.Name+"="+variable.Value)
+	}
+	return env
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterEnvironment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-exec")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "docker.env"), []byte(testBashScript), 0600))
+
+	variables, err := readCredentialScriptVariables(dir)
+	require.NoError(t, err)
+
+	environ := []string{"PATH=/usr/bin", "DOCKER_HOST=tcp://othercluster:2376", "DOCKER_VERSION=1.10", "EMPTY="}
+	env := mergeEnvironment(environ, variables)
+	assert.Equal(t, []string{
+		"PATH=/usr/bin",
+		"EMPTY=",
+		"DOCKER_HOST=tcp://172.99.65.1:2376",
+		"DOCKER_TLS_VERIFY=1",
+		"DOCKER_CERT_PATH=" + dir,
+		"KUBECONFIG=" + filepath.Join(dir, "kubectl.config"),
+	}, env, "Variables for another cluster should be cleared")
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+)
+
+// CredentialsFilenamePolicy renames the files in a credentials bundle when they are saved to a custom path,
+// for tools which expect specific names. Credentials saved in CARINA_HOME always keep the names from the bundle.
+var CredentialsFilenamePolicy = struct {
+	// Names maps a file in the bundle, or an alias such as kubeconfig, to the name it is saved as.
+	// {cluster} in the name is replaced with the cluster name, e.g. ca.pem={cluster}-ca.pem
+	Names map[string]string
+}{}
+
+// clusterNamePlaceholder is replaced with the cluster name in a credentials filename
+const clusterNamePlaceholder = "{cluster}"
+
+// dockerCertFilenames are the names which docker requires in DOCKER_CERT_PATH
+var dockerCertFilenames = []string{caCertFilename, clientCertFilename, clientKeyFilename}
+
+// ParseCredentialsFilenames converts a set of file=name pairs into the names used when saving credentials, see CredentialsFilenamePolicy
+func ParseCredentialsFilenames(values []string) (map[string]string, error) {
+	names, err := parseKeyValuePairs(values, "credentials filename")
+	if err != nil {
+		return nil, err
+	}
+
+	for file, name := range names {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("Invalid credentials filename for %s: %s. Use a filename without a directory", file, name)
+		}
+	}
+	return names, nil
+}
+
+// resolveCredentialsFilenames maps the files in a credentials bundle to their new names, resolving aliases such as kubeconfig
+// and replacing {cluster} with the cluster name
+func resolveCredentialsFilenames(names map[string]string, cluster string) map[string]string {
+	renames := make(map[string]string, len(names))
+	for file, name := range names {
+		if alias, ok := credentialFileAliases[file]; ok {
+			file = alias
+		}
+		renames[file] = strings.Replace(name, clusterNamePlaceholder, cluster, -1)
+	}
+	return renames
+}
+
+// renameCredentialsFiles applies the names to the files in a credentials bundle. References to a renamed file in the
+// scripts and kubeconfig from the bundle are updated, so that they keep working.
+func renameCredentialsFiles(files map[string][]byte, names map[string]string, cluster string) (map[string][]byte, error) {
+	if len(names) == 0 {
+		return files, nil
+	}
+
+	renames := resolveCredentialsFilenames(names, cluster)
+	for file := range renames {
+		if _, ok := files[file]; !ok {
+			common.Log.WriteDebug("Not renaming %s because it isn't in the credentials bundle", file)
+			delete(renames, file)
+		}
+	}
+
+	for _, file := range dockerCertFilenames {
+		if name, ok := renames[file]; ok && name != file {
+			common.Log.WriteWarning("WARNING: Renaming %s to %s breaks docker, which requires %s in DOCKER_CERT_PATH", file, name, strings.Join(dockerCertFilenames, ", "))
+			break
+		}
+	}
+
+	// Replace the longest names first, so that a name which contains another, e.g. kubectl.config and config, is replaced whole
+	var oldnew []string
+	oldNames := make([]string, 0, len(renames))
+	for file := range renames {
+		oldNames = append(oldNames, file)
+	}
+	sort.Slice(oldNames, func(i, j int) bool { return len(oldNames[i]) > len(oldNames[j]) })
+	for _, file := range oldNames {
+		oldnew = append(oldnew, file, renames[file])
+	}
+	references := strings.NewReplacer(oldnew...)
+
+	renamed := make(map[string][]byte, len(files))
+	for file, contents := range files {
+		name := file
+		if newName, ok := renames[file]; ok {
+			name = newName
+		}
+		if _, exists := renamed[name]; exists {
+			return nil, fmt.Errorf("Unable to rename the credentials files, more than one file would be saved as %s", name)
+		}
+
+		// Certificates and keys don't reference the other files
+		if filepath.Ext(file) != ".pem" {
+			contents = []byte(references.Replace(string(contents)))
+		}
+		renamed[name] = contents
+	}
+
+	return renamed, nil
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameCredentialsFiles(t *testing.T) {
+	files := map[string][]byte{
+		"ca.pem":         []byte("ca"),
+		"kubectl.config": []byte("certificate-authority: ca.pem"),
+		"kubectl.env":    []byte("export KUBECONFIG=$(cd $(dirname \"${BASH_SOURCE}\") && pwd)/kubectl.config"),
+	}
+	names := map[string]string{"kubeconfig": "config", "ca.pem": "{cluster}-ca.pem", "missing.pem": "other.pem"}
+
+	renamed, err := renameCredentialsFiles(files, names, "dev")
+	require.NoError(t, err)
+
+	assert.Len(t, renamed, 3)
+	assert.Equal(t, "ca", string(renamed["dev-ca.pem"]))
+	assert.Equal(t, "certificate-authority: dev-ca.pem", string(renamed["config"]))
+	assert.Contains(t, string(renamed["kubectl.env"]), "pwd)/config")
+}
+
+func TestRenameCredentialsFilesConflict(t *testing.T) {
+	files := map[string][]byte{"ca.pem": []byte("ca"), "cert.pem": []byte("cert")}
+
+	_, err := renameCredentialsFiles(files, map[string]string{"ca": "cert.pem"}, "dev")
+	assert.Error(t, err)
+}
+
+func TestParseCredentialsFilenames(t *testing.T) {
+	names, err := ParseCredentialsFilenames([]string{"kubeconfig=config"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"kubeconfig": "config"}, names)
+
+	_, err = ParseCredentialsFilenames([]string{"kubeconfig=../config"})
+	assert.Error(t, err)
+
+	_, err = ParseCredentialsFilenames([]string{"kubeconfig"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+)
+
+// ListClustersOptions controls which clusters are returned by ListClusters, and in what order
+type ListClustersOptions struct {
+	// Labels only includes clusters with all of the key/value labels
+	Labels map[string]string
+
+	// Filters only includes clusters matching all of the field=pattern filters, in the order specified, see ParseClusterFilters
+	Filters []ClusterFilter
+
+	// Sort orders the clusters by a field: name or nodes
+	Sort string
+}
+
+// ClusterFilter only includes clusters where the field matches the pattern, e.g. name=web*
+type ClusterFilter struct {
+	Field   string
+	Pattern string
+}
+
+// ListTemplatesOptions controls which templates are returned by ListClusterTemplates
+type ListTemplatesOptions struct {
+	// Name only includes templates whose name matches the pattern, e.g. Kubernetes*, using the name match policy
+	Name string
+
+	// Selector only includes templates with the selected COE, host type and version
+	Selector common.TemplateSelector
+}
+
+// clusterFilterFields maps the fields which can be used to filter clusters to the cluster value
+var clusterFilterFields = map[string]func(common.Cluster) string{
+	"name":     func(cluster common.Cluster) string { return cluster.GetName() },
+	"status":   func(cluster common.Cluster) string { return cluster.GetStatus() },
+	"template": func(cluster common.Cluster) string { return cluster.GetTemplate().GetName() },
+	"coe":      func(cluster common.Cluster) string { return cluster.GetTemplate().GetCOE() },
+	"host":     func(cluster common.Cluster) string { return cluster.GetTemplate().GetHostType() },
+}
+
+// clusterSortFields are the fields which can be used to sort clusters
+var clusterSortFields = []string{"name", "nodes"}
+
+// ParseClusterFilters converts a set of field=pattern filters, such as name=web*, into filters in the order specified
+func ParseClusterFilters(values []string) ([]ClusterFilter, error) {
+	filters := make([]ClusterFilter, 0, len(values))
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		field := strings.ToLower(strings.TrimSpace(parts[0]))
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid filter: %s. Filters must be in the format field=pattern", value)
+		}
+		if _, ok := clusterFilterFields[field]; !ok {
+			return nil, fmt.Errorf("Invalid filter: %s. Allowed fields: name, status, template, coe, host, label", value)
+		}
+		pattern := strings.TrimSpace(parts[1])
+		if _, err := common.NameMatchPolicy.NewMatcher(pattern); err != nil {
+			return nil, err
+		}
+		filters = append(filters, ClusterFilter{Field: field, Pattern: pattern})
+	}
+	return filters, nil
+}
+
+// ParseClusterSelection converts a set of filters into the options which select the clusters, where label=key=value
+// filters select by label, e.g. label=env=ci, and the rest are field=pattern filters, see ParseClusterFilters
+func ParseClusterSelection(values []string) (ListClustersOptions, error) {
+	var labels, filters []string
+	for _, value := range values {
+		if parts := strings.SplitN(value, "=", 2); len(parts) == 2 && strings.ToLower(strings.TrimSpace(parts[0])) == "label" {
+			labels = append(labels, parts[1])
+		} else {
+			filters = append(filters, value)
+		}
+	}
+
+	var options ListClustersOptions
+	var err error
+	options.Labels, err = ParseLabels(labels)
+	if err != nil {
+		return options, err
+	}
+	options.Filters, err = ParseClusterFilters(filters)
+	return options, err
+}
+
+// MatchesFilters returns if the cluster matches all of the filters, using the name match policy, e.g. glob patterns such as web*
+func MatchesFilters(cluster common.Cluster, filters []ClusterFilter) bool {
+	for _, filter := range filters {
+		getValue, ok := clusterFilterFields[filter.Field]
+		if !ok {
+			return false
+		}
+		matcher, err := common.NameMatchPolicy.NewMatcher(filter.Pattern)
+		if err != nil || !matcher.Matches(getValue(cluster)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateClusterSort checks that clusters can be sorted by the specified field
+func ValidateClusterSort(field string) error {
+	if field == "" {
+		return nil
+	}
+	for _, allowed := range clusterSortFields {
+		if field == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid sort: %s. Allowed values: %s", field, strings.Join(clusterSortFields, ", "))
+}
+
+// SortClusters orders clusters by the specified field. Without a field, the clusters are left in the order
+// returned by the service, which lists clusters from oldest to newest.
+func SortClusters(clusters []common.Cluster, field string) error {
+	err := ValidateClusterSort(field)
+	if err != nil {
+		return err
+	}
+
+	switch field {
+	case "name":
+		sort.Stable(clustersByName(clusters))
+	case "nodes":
+		sort.Stable(clustersByNodes(clusters))
+	}
+	return nil
+}
+
+type clustersByName []common.Cluster
+
+func (c clustersByName) Len() int      { return len(c) }
+func (c clustersByName) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c clustersByName) Less(i, j int) bool {
+	return strings.ToLower(c[i].GetName()) < strings.ToLower(c[j].GetName())
+}
+
+type clustersByNodes []common.Cluster
+
+func (c clustersByNodes) Len() int      { return len(c) }
+func (c clustersByNodes) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c clustersByNodes) Less(i, j int) bool {
+	// Clusters whose node count is unknown sort first
+	ni, _ := common.ParseNodeCount(c[i].GetNodes())
+	nj, _ := common.ParseNodeCount(c[j].GetNodes())
+	return ni < nj
+}
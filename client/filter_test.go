@@ -0,0 +1,86 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseClusterFiltersRejectsUnknownFields(t *testing.T) {
+	_, err := client.ParseClusterFilters([]string{"flavor=large"})
+	assert.NotNil(t, err)
+}
+
+func TestParseClusterSelection(t *testing.T) {
+	options, err := client.ParseClusterSelection([]string{"label=env=ci", "status=active"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"env": "ci"}, options.Labels)
+	assert.Equal(t, []client.ClusterFilter{{Field: "status", Pattern: "active"}}, options.Filters)
+
+	_, err = client.ParseClusterSelection([]string{"label=env"})
+	assert.NotNil(t, err, "A label filter must be key=value")
+}
+
+func TestMatchesFilters(t *testing.T) {
+	filters, err := client.ParseClusterFilters([]string{"name=web*", "status=ACTIVE"})
+	assert.Nil(t, err)
+
+	web := &testsupport.FakeCluster{Name: "web-01", Status: "active"}
+	db := &testsupport.FakeCluster{Name: "db-01", Status: "active"}
+	building := &testsupport.FakeCluster{Name: "web-02", Status: "creating"}
+
+	assert.True(t, client.MatchesFilters(web, filters))
+	assert.False(t, client.MatchesFilters(db, filters))
+	assert.False(t, client.MatchesFilters(building, filters))
+}
+
+func TestSortClusters(t *testing.T) {
+	clusters := []common.Cluster{
+		&testsupport.FakeCluster{Name: "web", Nodes: 3},
+		&testsupport.FakeCluster{Name: "api", Nodes: 10},
+		&testsupport.FakeCluster{Name: "db", Nodes: 1},
+	}
+
+	err := client.SortClusters(clusters, "name")
+	assert.Nil(t, err)
+	assert.Equal(t, "api", clusters[0].GetName())
+	assert.Equal(t, "web", clusters[2].GetName())
+
+	err = client.SortClusters(clusters, "nodes")
+	assert.Nil(t, err)
+	assert.Equal(t, "db", clusters[0].GetName())
+	assert.Equal(t, "api", clusters[2].GetName())
+
+	err = client.SortClusters(clusters, "age")
+	assert.NotNil(t, err)
+
+	err = client.SortClusters(clusters, "created")
+	assert.NotNil(t, err, "The clusters listed by the services don't have a created time to sort by")
+}
+
+func TestParseClusterFiltersKeepsOrder(t *testing.T) {
+	filters, err := client.ParseClusterFilters([]string{"status=active", "name=web*", "name=*-01"})
+	assert.Nil(t, err)
+	assert.Equal(t, []client.ClusterFilter{
+		{Field: "status", Pattern: "active"},
+		{Field: "name", Pattern: "web*"},
+		{Field: "name", Pattern: "*-01"},
+	}, filters, "The filters should be in the order specified, including repeated fields")
+
+	assert.True(t, client.MatchesFilters(&testsupport.FakeCluster{Name: "web-01", Status: "active"}, filters))
+	assert.False(t, client.MatchesFilters(&testsupport.FakeCluster{Name: "web-02", Status: "active"}, filters))
+}
+
+func TestMatchesFiltersByHostType(t *testing.T) {
+	filters, err := client.ParseClusterFilters([]string{"host=vm"})
+	assert.Nil(t, err)
+
+	vm := &testsupport.FakeCluster{Name: "web", Template: &testsupport.FakeClusterTemplate{HostType: "vm"}}
+	lxc := &testsupport.FakeCluster{Name: "db", Template: &testsupport.FakeClusterTemplate{HostType: "lxc"}}
+
+	assert.True(t, client.MatchesFilters(vm, filters))
+	assert.False(t, client.MatchesFilters(lxc, filters))
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// maxFingerprintHistory is the number of fingerprint records kept for each cluster
+const maxFingerprintHistory = 10
+
+const caCertFilename = "ca.pem"
+const clientCertFilename = "cert.pem"
+const clientKeyFilename = "key.pem"
+
+// CredentialsFingerprint identifies the certificates in a cluster's credentials bundle
+type CredentialsFingerprint struct {
+	CA         string    `json:"ca"`
+	Cert       string    `json:"cert"`
+	Downloaded time.Time `json:"downloaded"`
+}
+
+// Matches returns if both the CA and client certificate fingerprints are the same
+func (fingerprint CredentialsFingerprint) Matches(other CredentialsFingerprint) bool {
+	return fingerprint.CA == other.CA && fingerprint.Cert == other.Cert
+}
+
+// CredentialsChangedError warns that the certificates for a cluster differ from the last known certificates,
+// either because the cluster was rebuilt or the credentials were tampered with.
+type CredentialsChangedError struct {
+	ClusterName string
+	Previous    CredentialsFingerprint
+	Current     CredentialsFingerprint
+}
+
+// Error returns the warning message
+func (err CredentialsChangedError) Error() string {
+	return fmt.Sprintf("The certificates for %s do not match those downloaded on %s. This is expected if the cluster was rebuilt, otherwise the credentials may have been tampered with.",
+		err.ClusterName, err.Previous.Downloaded.Format(time.RFC822))
+}
+
+// fingerprintCertificate calculates the SHA-256 fingerprint of a PEM encoded certificate
+func fingerprintCertificate(contents []byte) (string, error) {
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return "", errors.New("Invalid certificate, unable to decode the PEM block")
+	}
+
+	sum := sha256.Sum256(block.Bytes)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(hex, ":"), nil
+}
+
+// buildCredentialsFingerprint calculates the fingerprints of the certificates in a credentials bundle
+func buildCredentialsFingerprint(files map[string][]byte) (CredentialsFingerprint, error) {
+	var fingerprint CredentialsFingerprint
+	var err error
+
+	fingerprint.CA, err = fingerprintCertificate(files[caCertFilename])
+	if err != nil {
+		return fingerprint, errors.Wrapf(err, "Unable to fingerprint %s", caCertFilename)
+	}
+
+	fingerprint.Cert, err = fingerprintCertificate(files[clientCertFilename])
+	if err != nil {
+		return fingerprint, errors.Wrapf(err, "Unable to fingerprint %s", clientCertFilename)
+	}
+
+	return fingerprint, nil
+}
+
+// recordCredentialsFingerprint saves the fingerprint of a newly downloaded credentials bundle,
+// returning a CredentialsChangedError when it differs from the previous download.
+func (client *Client) recordCredentialsFingerprint(account Account, name string, files map[string][]byte) error {
+	fingerprint, err := buildCredentialsFingerprint(files)
+	if err != nil {
+		common.Log.WriteDebug("Skipping credentials change detection: %s", err)
+		return nil
+	}
+	fingerprint.Downloaded = time.Now().UTC()
+
+	history := client.Cache.getFingerprintHistory(account, name)
+	err = client.Cache.saveFingerprint(account, name, fingerprint)
+	if err != nil {
+		common.Log.WriteDebug("Unable to save the credentials fingerprint: %s", err)
+	}
+
+	if len(history) > 0 {
+		previous := history[len(history)-1]
+		if !previous.Matches(fingerprint) {
+			return CredentialsChangedError{ClusterName: name, Previous: previous, Current: fingerprint}
+		}
+	}
+
+	return nil
+}
+
+// VerifyClusterCredentials calculates the fingerprints of a cluster's downloaded credentials,
+// and returns the fingerprints previously recorded for the cluster, oldest first.
+// A CredentialsChangedError is returned when the credentials on disk do not match the last download.
+func (client *Client) VerifyClusterCredentials(account Account, name string, customPath string) (current CredentialsFingerprint, history []CredentialsFingerprint, err error) {
+	credentialsPath, err := buildClusterCredentialsPath(account, name, customPath)
+	if err != nil {
+		return current, nil, err
+	}
+
+	files := make(map[string][]byte)
+	for _, file := range []string{caCertFilename, clientCertFilename} {
+		files[file], err = client.readSecureFile(filepath.Join(credentialsPath, file))
+		if err != nil {
+			return current, nil, errors.Wrapf(err, "Unable to read the credentials for %s, run carina credentials %s to download them", name, name)
+		}
+	}
+
+	current, err = buildCredentialsFingerprint(files)
+	if err != nil {
+		return current, nil, err
+	}
+
+	history = client.Cache.getFingerprintHistory(account, name)
+	if len(history) > 0 {
+		previous := history[len(history)-1]
+		if !previous.Matches(current) {
+			return current, history, CredentialsChangedError{ClusterName: name, Previous: previous, Current: current}
+		}
+	}
+
+	return current, history, nil
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"encoding/pem"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubAccount struct {
+	Account
+}
+
+func (account *stubAccount) GetID() string {
+	return "stub-user"
+}
+
+func buildCertificate(contents string) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte(contents)})
+}
+
+func TestFingerprintCertificate(t *testing.T) {
+	fingerprint, err := fingerprintCertificate(buildCertificate("abc123"))
+	assert.Nil(t, err)
+	assert.Len(t, fingerprint, 95)
+
+	_, err = fingerprintCertificate([]byte("garbage"))
+	assert.NotNil(t, err)
+}
+
+func TestRecordCredentialsFingerprintDetectsChanges(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	account := &stubAccount{}
+	original := map[string][]byte{
+		caCertFilename:     buildCertificate("ca"),
+		clientCertFilename: buildCertificate("cert"),
+	}
+
+	err := client.recordCredentialsFingerprint(account, "mycluster", original)
+	assert.Nil(t, err)
+
+	err = client.recordCredentialsFingerprint(account, "mycluster", original)
+	assert.Nil(t, err)
+
+	rebuilt := map[string][]byte{
+		caCertFilename:     buildCertificate("new-ca"),
+		clientCertFilename: buildCertificate("cert"),
+	}
+	err = client.recordCredentialsFingerprint(account, "mycluster", rebuilt)
+	assert.IsType(t, CredentialsChangedError{}, err)
+
+	assert.Len(t, client.Cache.getFingerprintHistory(account, "mycluster"), 3)
+}
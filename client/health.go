@@ -0,0 +1,172 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CredentialsStatus summarizes the health of a cluster's downloaded credentials
+type CredentialsStatus string
+
+const (
+	// CredentialsOK means the certificates are valid and the cluster responded
+	CredentialsOK CredentialsStatus = "ok"
+
+	// CredentialsExpiring means the client certificate expires soon
+	CredentialsExpiring CredentialsStatus = "expiring"
+
+	// CredentialsExpired means a certificate has expired
+	CredentialsExpired CredentialsStatus = "expired"
+
+	// CredentialsUnreachable means the cluster did not respond using the credentials
+	CredentialsUnreachable CredentialsStatus = "unreachable"
+
+	// CredentialsInvalid means the credentials are missing files or can't be read
+	CredentialsInvalid CredentialsStatus = "invalid"
+)
+
+// CredentialsHealth is the result of checking a cluster's downloaded credentials
+type CredentialsHealth struct {
+	Name     string
+	Status   CredentialsStatus
+	Endpoint string
+
+	// Expires is when the client certificate expires, and is zero when the certificate couldn't be read
+	Expires time.Time
+
+	// Err describes the problem with the credentials, and is nil when the status is ok
+	Err error
+}
+
+// IsHealthy returns if the credentials can be used, even if they expire soon
+func (health CredentialsHealth) IsHealthy() bool {
+	return health.Status == CredentialsOK || health.Status == CredentialsExpiring
+}
+
+// ListDownloadedCredentials returns the names of the clusters with credentials saved in the credentials directory for the account
+func ListDownloadedCredentials(account Account) ([]string, error) {
+	accountDir, err := getAccountCredentialsDir(account)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(accountDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to list the downloaded credentials")
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// CheckAllClusterCredentials checks every cluster's downloaded credentials concurrently, returning the results sorted by cluster name
+func (client *Client) CheckAllClusterCredentials(account Account) ([]CredentialsHealth, error) {
+	names, err := ListDownloadedCredentials(account)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]CredentialsHealth, len(names))
+	limit := make(chan struct{}, maxConcurrentOperations)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
+			results[i] = client.CheckClusterCredentials(account, name, "")
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// CheckClusterCredentials checks that a cluster's downloaded credentials have not expired, and that the cluster responds when using them
+func (client *Client) CheckClusterCredentials(account Account, name string, customPath string) CredentialsHealth {
+	health := CredentialsHealth{Name: name}
+
+	credentialsPath, err := buildClusterCredentialsPath(account, name, customPath)
+	if err != nil {
+		health.Status, health.Err = CredentialsInvalid, err
+		return health
+	}
+
+	files, err := client.readCredentialsFiles(credentialsPath)
+	if err != nil {
+		health.Status, health.Err = CredentialsInvalid, errors.Wrap(err, "Unable to read the credentials")
+		return health
+	}
+
+	return checkCredentialsFiles(health, files, time.Now(), probeCredentialsEndpoint)
+}
+
+// checkCredentialsFiles checks the certificates in a credentials bundle, and then probes the cluster with them
+func checkCredentialsFiles(health CredentialsHealth, files map[string][]byte, now time.Time, probe func(endpoint string, files map[string][]byte) error) CredentialsHealth {
+	health.Endpoint = findCredentialsEndpoint(files)
+
+	if cert, err := parseCertificate(files[clientCertFilename]); err == nil {
+		health.Expires = cert.NotAfter
+	}
+
+	for _, file := range []string{caCertFilename, clientCertFilename} {
+		err := validateCertificateExpiry(file, files[file], now)
+		if err != nil {
+			health.Status, health.Err = CredentialsInvalid, err
+			if _, parseErr := parseCertificate(files[file]); parseErr == nil {
+				health.Status = CredentialsExpired
+			}
+			return health
+		}
+	}
+
+	err := probe(health.Endpoint, files)
+	if err != nil {
+		health.Status, health.Err = CredentialsUnreachable, err
+		return health
+	}
+
+	health.Status = CredentialsOK
+	if now.Add(certificateExpiryWarning).After(health.Expires) {
+		health.Status = CredentialsExpiring
+		health.Err = fmt.Errorf("The client certificate expires on %s", health.Expires.Local().Format(time.RFC822))
+	}
+	return health
+}
+
+// probeCredentialsEndpoint checks that the cluster's Docker or Kubernetes API responds once, using the credentials
+func probeCredentialsEndpoint(endpoint string, files map[string][]byte) error {
+	coe := "swarm"
+	if _, ok := files[bundleKubeconfigFilename]; ok {
+		coe = "kubernetes"
+	}
+
+	probeURL, err := buildReadyCheckURL(coe, endpoint)
+	if err != nil {
+		return err
+	}
+
+	httpClient, err := buildCredentialsHTTPClient(files)
+	if err != nil {
+		return err
+	}
+
+	return probeCOE(httpClient, probeURL)
+}
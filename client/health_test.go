@@ -0,0 +1,113 @@
+package client
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCredentialsFiles(t *testing.T) {
+	now := time.Now()
+	reachable := func(string, map[string][]byte) error { return nil }
+	unreachable := func(string, map[string][]byte) error { return errors.New("connection refused") }
+	buildFiles := func(notAfter time.Time) map[string][]byte {
+		return map[string][]byte{
+			caCertFilename:     buildCertificateValidFrom(t, now.Add(-time.Hour), now.Add(365*24*time.Hour)),
+			clientCertFilename: buildCertificateValidFrom(t, now.Add(-2*time.Hour), notAfter),
+			"docker.env":       []byte("export DOCKER_HOST=tcp://172.99.65.1:2376"),
+		}
+	}
+
+	health := checkCredentialsFiles(CredentialsHealth{Name: "ok"}, buildFiles(now.Add(60*24*time.Hour)), now, reachable)
+	assert.Equal(t, CredentialsOK, health.Status)
+	assert.Equal(t, "tcp://172.99.65.1:2376", health.Endpoint)
+	assert.Nil(t, health.Err)
+
+	health = checkCredentialsFiles(CredentialsHealth{Name: "expiring"}, buildFiles(now.Add(24*time.Hour)), now, reachable)
+	assert.Equal(t, CredentialsExpiring, health.Status)
+	assert.True(t, health.IsHealthy())
+
+	health = checkCredentialsFiles(CredentialsHealth{Name: "expired"}, buildFiles(now.Add(-time.Hour)), now, reachable)
+	assert.Equal(t, CredentialsExpired, health.Status)
+	assert.False(t, health.IsHealthy())
+
+	health = checkCredentialsFiles(CredentialsHealth{Name: "unreachable"}, buildFiles(now.Add(60*24*time.Hour)), now, unreachable)
+	assert.Equal(t, CredentialsUnreachable, health.Status)
+
+	files := buildFiles(now.Add(60 * 24 * time.Hour))
+	delete(files, caCertFilename)
+	health = checkCredentialsFiles(CredentialsHealth{Name: "invalid"}, files, now, reachable)
+	assert.Equal(t, CredentialsInvalid, health.Status)
+}
+
+type prefixAccount struct {
+	Account
+}
+
+func (account prefixAccount) GetClusterPrefix() (string, error) {
+	return "public-dfw-alice", nil
+}
+
+func (account prefixAccount) GetID() string {
+	return "public-alice"
+}
+
+func TestCredentialsStoragePolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-credentials")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	CredentialsStoragePolicy.Dir = dir
+	defer func() { CredentialsStoragePolicy.Dir = "" }()
+
+	credentialsPath, err := buildClusterCredentialsPath(prefixAccount{}, "mycluster", "")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "public-dfw-alice", "mycluster"), credentialsPath)
+
+	defer os.Setenv(runtimeDirEnvVar, os.Getenv(runtimeDirEnvVar))
+	os.Unsetenv(runtimeDirEnvVar)
+	_, err = buildRuntimeCredentialsPath(credentialsPath)
+	assert.Equal(t, errNoSessionDir, err, "the decrypted credentials should only outlive carina in XDG_RUNTIME_DIR")
+
+	os.Setenv(runtimeDirEnvVar, dir)
+	runtimePath, err := buildRuntimeCredentialsPath(credentialsPath)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(runtimePath, filepath.Join(clusterDirName, "public-dfw-alice", "mycluster")), runtimePath)
+
+	assert.Nil(t, os.MkdirAll(credentialsPath, 0700))
+	names, err := ListDownloadedCredentials(prefixAccount{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mycluster"}, names)
+}
+
+func TestCredentialsPathTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-credentials")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	CredentialsStoragePolicy.Dir = dir
+	CredentialsStoragePolicy.PathTemplate = "{{.Account}}/{{.Cloud}}/{{.ClusterName}}"
+	defer func() { CredentialsStoragePolicy.Dir, CredentialsStoragePolicy.PathTemplate = "", "" }()
+
+	credentialsPath, err := buildClusterCredentialsPath(prefixAccount{}, "mycluster", "")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "public-alice", "public", "mycluster"), credentialsPath)
+
+	assert.Nil(t, os.MkdirAll(credentialsPath, 0700))
+	names, err := ListDownloadedCredentials(prefixAccount{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mycluster"}, names)
+
+	assert.Nil(t, ValidateCredentialsPathTemplate("{{.Cloud}}/{{.Prefix}}/{{.ClusterName}}"))
+	assert.Error(t, ValidateCredentialsPathTemplate("{{.Account}}/{{.ClusterName}}/kube"), "The cluster name must be last")
+	assert.Error(t, ValidateCredentialsPathTemplate("{{.Account}}-{{.ClusterName}}"), "The cluster name must be a separate directory")
+	assert.Error(t, ValidateCredentialsPathTemplate("../{{.ClusterName}}"), "The path must be within the credentials directory")
+	assert.Error(t, ValidateCredentialsPathTemplate("/tmp/{{.ClusterName}}"), "The path must be relative")
+	assert.Error(t, ValidateCredentialsPathTemplate("{{.Region}}/{{.ClusterName}}"), "Unknown fields are invalid")
+}
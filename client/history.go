@@ -0,0 +1,168 @@
+package client
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// maxClusterEvents is the number of events kept in the history of each cluster, older events are dropped
+const maxClusterEvents = 50
+
+// clusterHistoryTTL is how long the history of a cluster is kept after its last event, so that the history
+// of deleted clusters doesn't grow without bound, e.g. when the credentials are refreshed with --agent
+const clusterHistoryTTL = 90 * 24 * time.Hour
+
+// ClusterEvent is a status transition of a cluster, as observed by this client
+type ClusterEvent struct {
+	Time          time.Time `json:"time"`
+	ClusterID     string    `json:"cluster-id"`
+	ClusterName   string    `json:"cluster-name"`
+	Status        string    `json:"status"`
+	StatusDetails string    `json:"status-details,omitempty"`
+
+	// Source is the operation during which the status was observed, e.g. create or wait
+	Source string `json:"source"`
+}
+
+// saveClusterEvents appends events to the history of their clusters, skipping events where the status hasn't changed
+func (cache *Cache) saveClusterEvents(account Account, events []ClusterEvent) error {
+	return cache.safeUpdate(func(c *Cache) {
+		if c.History == nil {
+			c.History = make(map[string][]ClusterEvent)
+		}
+
+		for _, event := range events {
+			key := clusterCacheKey(account, event.ClusterID)
+			history := c.History[key]
+			if len(history) > 0 {
+				last := history[len(history)-1]
+				if last.Status == event.Status && last.StatusDetails == event.StatusDetails {
+					continue
+				}
+			}
+
+			history = append(history, event)
+			if len(history) > maxClusterEvents {
+				history = history[len(history)-maxClusterEvents:]
+			}
+			c.History[key] = history
+		}
+
+		expired := time.Now().Add(-clusterHistoryTTL)
+		for key, history := range c.History {
+			if len(history) == 0 || history[len(history)-1].Time.Before(expired) {
+				delete(c.History, key)
+			}
+		}
+	})
+}
+
+// recordClusterStatus adds the current status of the clusters to their history
+func (client *Client) recordClusterStatus(account Account, source string, clusters ...common.Cluster) {
+	now := time.Now()
+	var events []ClusterEvent
+	for _, cluster := range clusters {
+		if cluster == nil || cluster.GetID() == "" {
+			continue
+		}
+		client.rememberClusterStatus(cluster)
+
+		events = append(events, ClusterEvent{
+			Time:          now,
+			ClusterID:     cluster.GetID(),
+			ClusterName:   cluster.GetName(),
+			Status:        cluster.GetStatus(),
+			StatusDetails: cluster.GetStatusDetails(),
+			Source:        source,
+		})
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	err := client.Cache.saveClusterEvents(account, events)
+	if err != nil {
+		common.Log.WriteDebug("Unable to record the cluster status: %s", err)
+	}
+}
+
+// recordClusterDeleted adds the deletion of a cluster to its history
+func (client *Client) recordClusterDeleted(account Account, cluster common.Cluster) {
+	client.recordClusterStatus(account, "delete", deletedCluster{cluster})
+}
+
+// deletedCluster reports the status of a cluster which is gone
+type deletedCluster struct {
+	common.Cluster
+}
+
+// GetStatus returns deleted
+func (cluster deletedCluster) GetStatus() string {
+	return "deleted"
+}
+
+// GetStatusDetails returns an empty string, there are no details about a deleted cluster
+func (cluster deletedCluster) GetStatusDetails() string {
+	return ""
+}
+
+// watchClusterStatus records each status of a cluster observed while waiting, until the returned function is called.
+// When progress is specified, e.g. the tracker for each cluster in a batch, the status transitions are also reported to it.
+func (client *Client) watchClusterStatus(account Account, cluster common.Cluster, progress *common.ProgressTracker) (stop func()) {
+	if cluster == nil {
+		return func() {}
+	}
+
+	if progress != nil {
+		progress.Update(cluster.GetStatus())
+	}
+	return common.ObserveClusterStatus(cluster.GetID(), func(observed common.Cluster) {
+		client.recordClusterStatus(account, "wait", observed)
+		if progress != nil {
+			progress.Update(observed.GetStatus())
+		}
+	})
+}
+
+// GetClusterHistory returns the status transitions recorded for a cluster, oldest first.
+// The cluster is matched by name or id against the history in the cache, so that the history
+// is available after the cluster is deleted. When a name was reused, the most recent cluster is used.
+func (client *Client) GetClusterHistory(account Account, name string) ([]ClusterEvent, error) {
+	prefix := clusterCacheKey(account, "")
+
+	client.Cache.ensureLoaded()
+	var match []ClusterEvent
+	for key, history := range client.Cache.History {
+		if !strings.HasPrefix(key, prefix) || len(history) == 0 {
+			continue
+		}
+
+		matched := false
+		for _, event := range history {
+			if event.ClusterID == name || event.ClusterName == name {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		if match == nil || history[len(history)-1].Time.After(match[len(match)-1].Time) {
+			match = history
+		}
+	}
+
+	if match == nil {
+		return nil, errors.Errorf("No history has been recorded for %s. The history is recorded while this client creates, changes or waits on a cluster", name)
+	}
+
+	events := make([]ClusterEvent, len(match))
+	copy(events, match)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
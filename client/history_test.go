@@ -0,0 +1,86 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+// historyAccount stores credentials under a cluster prefix, so that deleting a cluster can clean up its credentials
+type historyAccount struct {
+	offlineAccount
+}
+
+func (account *historyAccount) GetClusterPrefix() (string, error) {
+	return "stub-user", nil
+}
+
+func TestClusterHistory(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv(CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(CarinaHomeDirEnvVar)
+
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.PendingPolls = 2
+	account := &historyAccount{offlineAccount{service: service}}
+
+	_, err = client.GetClusterHistory(account, "mycluster")
+	assert.NotNil(t, err, "Nothing has been recorded yet")
+
+	cluster, err := client.CreateCluster(account, "mycluster", "Swarm 1.11.2 on LXC", 1, CreateClusterOptions{WaitUntilActive: true})
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, err = client.ListClusters(account, ListClustersOptions{})
+	assert.Nil(t, err)
+	err = client.DeleteCluster(account, "mycluster", true)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	events, err := client.GetClusterHistory(account, "mycluster")
+	if !assert.Nil(t, err, "The history should be available after the cluster is deleted") {
+		return
+	}
+
+	var statuses []string
+	for _, event := range events {
+		assert.Equal(t, cluster.GetID(), event.ClusterID)
+		statuses = append(statuses, event.Status+"/"+event.Source)
+	}
+	assert.Equal(t, []string{"creating/create", "active/wait", "deleting/delete", "deleted/delete"}, statuses,
+		"Only status transitions should be recorded")
+
+	byID, err := client.GetClusterHistory(account, cluster.GetID())
+	assert.Nil(t, err)
+	assert.Equal(t, events, byID)
+}
+
+func TestClusterHistoryExpires(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	cache := newCache(filename)
+	account := &historyAccount{}
+	deleted := ClusterEvent{Time: time.Now().Add(-2 * clusterHistoryTTL), ClusterID: "123", ClusterName: "old", Status: "deleted"}
+	assert.Nil(t, cache.saveClusterEvents(account, []ClusterEvent{deleted}))
+
+	current := ClusterEvent{Time: time.Now(), ClusterID: "456", ClusterName: "new", Status: "active"}
+	assert.Nil(t, cache.saveClusterEvents(account, []ClusterEvent{current}))
+
+	assert.Len(t, cache.History, 1, "The history of a cluster should be dropped once its last event is older than the TTL")
+	assert.Contains(t, cache.History, clusterCacheKey(account, "456"))
+}
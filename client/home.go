@@ -0,0 +1,66 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/getcarina/carina/common"
+)
+
+// CheckCarinaHome verifies that CARINA_HOME, or XDG_DATA_HOME/carina, exists and is writable, before a command uses the cache or credentials.
+// When create is set, a missing directory is created. The default, ~/.carina, is always created when it is first used.
+// A directory on a network filesystem only causes a warning, because the cache and the cluster locks may not work reliably there.
+// When CARINA_HOME is shared, see SharedHomeEnvVar, a missing directory is created so that every user can write to it.
+func CheckCarinaHome(create bool) error {
+	home, err := GetCredentialsDir()
+	if err != nil {
+		return err
+	}
+
+	envVar := CarinaHomeDirEnvVar
+	if os.Getenv(CarinaHomeDirEnvVar) == "" {
+		if os.Getenv(xdgDataHomeEnvVar) == "" {
+			return nil
+		}
+		envVar = xdgDataHomeEnvVar
+	}
+
+	info, err := os.Stat(home)
+	switch {
+	case os.IsNotExist(err) && create:
+		common.Log.WriteDebug("Creating %s (%s)", envVar, home)
+		if SharedHomeEnabled() {
+			// Each user's files are protected by their namespace instead, see openSharedNamespace
+			err = createSharedDir(home)
+			if err != nil {
+				return err
+			}
+			break
+		}
+		err = os.MkdirAll(home, 0700)
+		if err != nil {
+			return fmt.Errorf("Unable to create %s (%s): %s", envVar, home, err)
+		}
+		restrictAccess(home)
+	case os.IsNotExist(err):
+		return fmt.Errorf("%s (%s) does not exist. Use --create-home to create it, or unset %s to use the default location", envVar, home, envVar)
+	case err != nil:
+		return fmt.Errorf("Unable to access %s (%s): %s", envVar, home, err)
+	case !info.IsDir():
+		return fmt.Errorf("%s (%s) is a file, it must be a directory", envVar, home)
+	}
+
+	probe, err := ioutil.TempFile(home, ".carina-write-check-")
+	if err != nil {
+		return fmt.Errorf("%s (%s) is not writable, so the cache and credentials can't be saved. Fix its permissions, or set %s to a directory that you own: %s", envVar, home, envVar, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	if fsType, remote := networkFilesystem(home); remote {
+		common.Log.WriteStructuredWarning(common.WarningHome, "%s (%s) is on a network filesystem (%s), where the cache and cluster locks may not work reliably. Use a local directory instead", envVar, home, fsType)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"syscall"
+)
+
+// networkFilesystemTypes are the names of the network filesystems reported by statfs
+var networkFilesystemTypes = map[string]bool{
+	"nfs":    true,
+	"smbfs":  true,
+	"afpfs":  true,
+	"webdav": true,
+}
+
+// networkFilesystem returns the type of filesystem which contains a path, when it is a network filesystem
+func networkFilesystem(path string) (string, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", false
+	}
+
+	var name []byte
+	for _, c := range stat.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return string(name), networkFilesystemTypes[string(name)]
+}
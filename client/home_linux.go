@@ -0,0 +1,28 @@
+package client
+
+import (
+	"syscall"
+)
+
+// networkFilesystemTypes are the magic numbers of the network filesystems reported by statfs, see man 2 statfs
+var networkFilesystemTypes = map[uint32]string{
+	0x6969:     "nfs",
+	0x517B:     "smb",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x73757245: "coda",
+	0x5346414F: "afs",
+	0x00C36400: "ceph",
+	0x01021997: "9p",
+}
+
+// networkFilesystem returns the type of filesystem which contains a path, when it is a network filesystem
+func networkFilesystem(path string) (string, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", false
+	}
+
+	fsType, ok := networkFilesystemTypes[uint32(stat.Type)]
+	return fsType, ok
+}
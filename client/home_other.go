@@ -0,0 +1,8 @@
+// +build !darwin,!linux,!windows
+
+package client
+
+// networkFilesystem is not supported on this platform
+func networkFilesystem(path string) (string, bool) {
+	return "", false
+}
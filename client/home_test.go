@@ -0,0 +1,39 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCarinaHome(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-home")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(CarinaHomeDirEnvVar)
+
+	home := filepath.Join(dir, "home")
+	os.Setenv(CarinaHomeDirEnvVar, home)
+	err = CheckCarinaHome(false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--create-home")
+
+	require.NoError(t, CheckCarinaHome(true))
+	info, err := os.Stat(home)
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	require.NoError(t, CheckCarinaHome(false))
+
+	files, err := ioutil.ReadDir(home)
+	require.NoError(t, err)
+	assert.Empty(t, files, "The write check should clean up after itself")
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+	os.Setenv(CarinaHomeDirEnvVar, file)
+	assert.Error(t, CheckCarinaHome(true), "A file can't be used as CARINA_HOME")
+}
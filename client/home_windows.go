@@ -0,0 +1,33 @@
+// +build windows
+
+package client
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var procGetDriveType = kernel32.NewProc("GetDriveTypeW")
+
+// driveRemote is the drive type of a mapped network drive
+const driveRemote = 4
+
+// networkFilesystem returns if a path is on a network share, either a UNC path or a mapped network drive
+func networkFilesystem(path string) (string, bool) {
+	volume := filepath.VolumeName(path)
+	if strings.HasPrefix(volume, `\\`) {
+		return "smb", true
+	}
+	if volume == "" {
+		return "", false
+	}
+
+	root, err := syscall.UTF16PtrFromString(volume + `\`)
+	if err != nil {
+		return "", false
+	}
+	driveType, _, _ := procGetDriveType.Call(uintptr(unsafe.Pointer(root)))
+	return "smb", driveType == driveRemote
+}
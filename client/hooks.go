@@ -0,0 +1,85 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+)
+
+// HookPolicy is the commands run after a cluster operation succeeds, e.g. to register a new cluster in DNS or enroll it in monitoring
+var HookPolicy = struct {
+	// Commands maps an operation, see HookOperations, to a shell command
+	Commands map[string]string
+}{}
+
+// HookOperations are the operations which run a hook
+var HookOperations = []string{"create", "delete", "resize"}
+
+// runHook runs the hook for an operation once it has succeeded, with the cluster's metadata in the environment, see buildHookEnvironment.
+// Hooks only run when carina waited for the operation to complete, otherwise the cluster would still be changing, e.g. creating.
+// The hook's output is written to stderr, so that it doesn't mix with json output. A failed hook only prints a warning,
+// because the operation has already completed.
+func (client *Client) runHook(account Account, operation string, name string, cluster common.Cluster, completed bool, err error) {
+	command := HookPolicy.Commands[operation]
+	if command == "" || err != nil {
+		return
+	}
+	if !completed {
+		common.Log.WriteWarning("WARNING: Skipping the %s hook for cluster (%s), hooks only run after waiting for the operation to complete. Use --wait", operation, name)
+		return
+	}
+
+	common.Log.WriteDebug("Running the %s hook for cluster (%s): %s", operation, name, command)
+	hook := shellCommand(command)
+	hook.Env = append(os.Environ(), buildHookEnvironment(account, operation, name, cluster)...)
+	hook.Stdout = os.Stderr
+	hook.Stderr = os.Stderr
+	hookErr := hook.Run()
+	if hookErr != nil {
+		common.Log.WriteWarning("WARNING: The %s hook for cluster (%s) failed: %s", operation, name, hookErr)
+	}
+}
+
+// shellCommand runs a command with the platform's shell, so that hooks can use pipes and variables
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// buildHookEnvironment describes the cluster to a hook, e.g. CARINA_CLUSTER_NAME=mycluster.
+// The cluster details are omitted when the cloud didn't return the cluster, such as after it is deleted.
+func buildHookEnvironment(account Account, operation string, name string, cluster common.Cluster) []string {
+	env := []string{
+		"CARINA_OPERATION=" + operation,
+		"CARINA_ACCOUNT=" + account.GetID(),
+		"CARINA_CLUSTER_NAME=" + name,
+	}
+	if cluster == nil {
+		return env
+	}
+
+	env = append(env,
+		"CARINA_CLUSTER_ID="+cluster.GetID(),
+		"CARINA_CLUSTER_STATUS="+cluster.GetStatus(),
+		"CARINA_CLUSTER_NODES="+cluster.GetNodes())
+	if template := cluster.GetTemplate(); template != nil {
+		env = append(env,
+			"CARINA_CLUSTER_TEMPLATE="+template.GetName(),
+			"CARINA_CLUSTER_COE="+template.GetCOE())
+	}
+
+	labels := cluster.GetLabels()
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return append(env, "CARINA_CLUSTER_LABELS="+strings.Join(pairs, ","))
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildHookEnvironment(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes"})
+	account := &historyAccount{offlineAccount{service: service}}
+	cluster, err := service.CreateCluster("web", "Kubernetes 1.5.2 on LXC", 2)
+	require.NoError(t, err)
+
+	env := buildHookEnvironment(account, "create", "web", cluster)
+	assert.Contains(t, env, "CARINA_OPERATION=create")
+	assert.Contains(t, env, "CARINA_ACCOUNT=stub-user")
+	assert.Contains(t, env, "CARINA_CLUSTER_NAME=web")
+	assert.Contains(t, env, "CARINA_CLUSTER_ID="+cluster.GetID())
+	assert.Contains(t, env, "CARINA_CLUSTER_NODES=2")
+	assert.Contains(t, env, "CARINA_CLUSTER_TEMPLATE=Kubernetes 1.5.2 on LXC")
+	assert.Contains(t, env, "CARINA_CLUSTER_COE=kubernetes")
+
+	env = buildHookEnvironment(account, "delete", "web", nil)
+	assert.Equal(t, []string{"CARINA_OPERATION=delete", "CARINA_ACCOUNT=stub-user", "CARINA_CLUSTER_NAME=web"}, env)
+}
+
+func TestRunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The hook uses sh")
+	}
+
+	dir, err := ioutil.TempDir("", "carina-hooks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "output")
+
+	HookPolicy.Commands = map[string]string{"create": `echo "$CARINA_OPERATION $CARINA_CLUSTER_NAME" > ` + output}
+	defer func() { HookPolicy.Commands = nil }()
+
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+	client := &Client{Cache: &Cache{}}
+
+	client.runHook(account, "create", "web", nil, true, errors.New("quota exceeded"))
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err), "The hook should only run when the operation succeeds")
+
+	client.runHook(account, "delete", "web", nil, true, nil)
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err), "Only the hook for the operation should run")
+
+	_, err = client.CreateCluster(account, "web", "Kubernetes 1.5.2 on LXC", 1, CreateClusterOptions{})
+	require.NoError(t, err)
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err), "The hook should only run once the cluster is active")
+
+	_, err = client.CreateCluster(account, "api", "Kubernetes 1.5.2 on LXC", 1, CreateClusterOptions{WaitUntilActive: true})
+	require.NoError(t, err)
+	contents, err := ioutil.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "create api\n", string(contents))
+
+	require.NoError(t, os.Remove(output))
+	_, err = client.CreateCluster(account, "api", "Kubernetes 1.5.2 on LXC", 1, CreateClusterOptions{WaitUntilActive: true, IfNotExists: true})
+	require.NoError(t, err)
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err), "The hook should not run for an existing cluster")
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// installDirName is the directory in CARINA_HOME where carina install saves the clients
+const installDirName = "bin"
+
+// InstallOptions controls which client carina install downloads, and where it is saved
+type InstallOptions struct {
+	// Tool is the client to install, docker or kubectl
+	Tool string
+
+	// Version of the client, e.g. 1.11.2
+	Version string
+
+	// Dir is where the client is saved, defaults to CARINA_HOME/bin
+	Dir string
+}
+
+// GetInstallDir returns the directory where carina install saves the clients by default
+func GetInstallDir() (string, error) {
+	home, err := GetCredentialsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, installDirName), nil
+}
+
+// buildClientDownloadURL returns where to download a client for an operating system and architecture,
+// and if the client is packaged in an archive, which is the case for docker
+func buildClientDownloadURL(tool string, version string, goos string, goarch string) (url string, archived bool, err error) {
+	switch tool {
+	case KubectlTool:
+		filename := "kubectl"
+		if goos == "windows" {
+			filename += ".exe"
+		}
+		return fmt.Sprintf("https://storage.googleapis.com/kubernetes-release/release/v%s/bin/%s/%s/%s", strings.TrimPrefix(version, "v"), goos, goarch, filename), false, nil
+	case DockerTool:
+		if goarch != "amd64" {
+			return "", false, fmt.Errorf("Docker clients are only published for amd64, not %s", goarch)
+		}
+
+		ext := ".tgz"
+		if goos == "windows" {
+			ext = ".zip"
+		}
+
+		// Releases after 1.13 moved to download.docker.com
+		if common.CompareVersions(version, "17") < 0 {
+			platforms := map[string]string{"linux": "Linux", "darwin": "Darwin", "windows": "Windows"}
+			if platforms[goos] == "" {
+				return "", false, fmt.Errorf("Docker clients are not published for %s", goos)
+			}
+			return fmt.Sprintf("https://get.docker.com/builds/%s/x86_64/docker-%s%s", platforms[goos], version, ext), true, nil
+		}
+
+		platforms := map[string]string{"linux": "linux", "darwin": "mac", "windows": "win"}
+		if platforms[goos] == "" {
+			return "", false, fmt.Errorf("Docker clients are not published for %s", goos)
+		}
+		return fmt.Sprintf("https://download.docker.com/%s/static/stable/x86_64/docker-%s%s", platforms[goos], version, ext), true, nil
+	default:
+		return "", false, fmt.Errorf("Unable to install %s, only %s and %s are supported", tool, DockerTool, KubectlTool)
+	}
+}
+
+// InstallClientTool downloads a docker or kubectl client, and saves it to the install directory. Returns the path to the client.
+func (client *Client) InstallClientTool(options InstallOptions) (string, error) {
+	if options.Version == "" {
+		return "", errors.New("The version of the client to install is required")
+	}
+
+	dir := options.Dir
+	if dir == "" {
+		var err error
+		dir, err = GetInstallDir()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	url, archived, err := buildClientDownloadURL(options.Tool, options.Version, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+
+	common.Log.WriteDebug("Downloading %s %s from %s", options.Tool, options.Version, url)
+	resp, err := common.NewDownloadHTTPClient().Get(url)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to download %s %s", options.Tool, options.Version)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", common.NotFoundError{Err: fmt.Errorf("%s %s was not found at %s", options.Tool, options.Version, url)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", common.NewHTTPError(resp.StatusCode, fmt.Errorf("Unable to download %s %s: %s", options.Tool, options.Version, resp.Status))
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to download %s %s", options.Tool, options.Version)
+	}
+
+	filename := options.Tool
+	if runtime.GOOS == "windows" {
+		filename += ".exe"
+	}
+	if archived {
+		contents, err = extractClientBinary(contents, strings.HasSuffix(url, ".zip"), filename)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to create %s", dir)
+	}
+
+	// Write to a temporary file first, so that a running client isn't replaced with a partial file
+	binaryPath := filepath.Join(dir, filename)
+	err = ioutil.WriteFile(binaryPath+".download", contents, 0755)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to write %s", binaryPath)
+	}
+	err = os.Rename(binaryPath+".download", binaryPath)
+	if err != nil {
+		os.Remove(binaryPath + ".download")
+		return "", errors.Wrapf(err, "Unable to write %s", binaryPath)
+	}
+
+	return binaryPath, nil
+}
+
+// extractClientBinary finds a client in a release archive, e.g. docker/docker in docker-1.11.2.tgz,
+// where older releases put the client in usr/local/bin
+func extractClientBinary(archive []byte, isZip bool, filename string) ([]byte, error) {
+	if isZip {
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to open the release archive")
+		}
+		for _, file := range reader.File {
+			if file.FileInfo().IsDir() || path.Base(file.Name) != filename {
+				continue
+			}
+			entry, err := file.Open()
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to read the release archive")
+			}
+			defer entry.Close()
+			return ioutil.ReadAll(entry)
+		}
+		return nil, fmt.Errorf("Unable to find %s in the release archive", filename)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to open the release archive")
+	}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("Unable to find %s in the release archive", filename)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to read the release archive")
+		}
+		if header.Typeflag == tar.TypeReg && path.Base(header.Name) == filename {
+			return ioutil.ReadAll(reader)
+		}
+	}
+}
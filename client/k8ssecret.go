@@ -0,0 +1,168 @@
+package client
+
+import (
+	"encoding/base64"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// SecretManifestOptions controls the Kubernetes Secret manifest built from a cluster's credentials
+type SecretManifestOptions struct {
+	// Name of the Secret, defaults to <cluster-name>-credentials
+	Name string
+
+	// Namespace of the Secret, omitted when empty so that kubectl applies it to the current namespace
+	Namespace string
+}
+
+// secretKubeconfigKey is the key of the self-contained kubeconfig added to the Secret for Kubernetes clusters
+const secretKubeconfigKey = "kubeconfig"
+
+// secretKeyPattern is the set of names allowed for the keys of a Secret
+var secretKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// secretNamePattern is a DNS subdomain, which is required for the name of a Secret
+var secretNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// invalidSecretNameChars are replaced when deriving the name of a Secret from the cluster name
+var invalidSecretNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+type secretManifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   secretMetadata    `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	Data       map[string]string `yaml:"data"`
+}
+
+type secretMetadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+// WriteClusterCredentialsSecret writes a cluster's credentials as a Kubernetes Secret manifest, downloading them first if necessary.
+// Each file in the credentials bundle is a key in the Secret. For Kubernetes clusters, a kubeconfig with the certificates
+// embedded is added as well, so that it can be used from wherever the Secret is mounted.
+func (client *Client) WriteClusterCredentialsSecret(account Account, name string, customPath string, options SecretManifestOptions, w io.Writer) error {
+	secretName := options.Name
+	if secretName == "" {
+		secretName = defaultSecretName(name)
+	} else if !secretNamePattern.MatchString(secretName) || len(secretName) > 253 {
+		return errors.Errorf("Invalid secret name %s, it must consist of lowercase letters, numbers, '-' and '.'", secretName)
+	}
+
+	credentialsPath, err := client.ensureClusterCredentials(account, name, customPath)
+	if err != nil {
+		return err
+	}
+
+	files, err := client.readCredentialsFiles(credentialsPath)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to read the credentials for %s", name)
+	}
+
+	manifest, err := buildSecretManifest(name, secretName, options.Namespace, files)
+	if err != nil {
+		return err
+	}
+
+	contents, err := yaml.Marshal(manifest)
+	if err != nil {
+		return errors.Wrap(err, "Unable to build the secret manifest")
+	}
+	_, err = w.Write(contents)
+	return errors.Wrap(err, "Unable to write the secret manifest")
+}
+
+// defaultSecretName derives a valid Secret name from the cluster name, e.g. My_Cluster becomes my-cluster-credentials
+func defaultSecretName(cluster string) string {
+	name := invalidSecretNameChars.ReplaceAllString(strings.ToLower(cluster), "-")
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		return "carina-credentials"
+	}
+	return name + "-credentials"
+}
+
+func buildSecretManifest(cluster string, secretName string, namespace string, files map[string][]byte) (*secretManifest, error) {
+	manifest := &secretManifest{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: secretMetadata{
+			Name:      secretName,
+			Namespace: namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "carina"},
+		},
+		Type: "Opaque",
+		Data: make(map[string]string),
+	}
+
+	var skipped []string
+	for file, contents := range files {
+		if !secretKeyPattern.MatchString(file) {
+			skipped = append(skipped, file)
+			continue
+		}
+		manifest.Data[file] = base64.StdEncoding.EncodeToString(contents)
+	}
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		common.Log.WriteWarning("Skipping %s, which cannot be used as the name of a key in a secret", strings.Join(skipped, ", "))
+	}
+
+	if bundle, ok := files[bundleKubeconfigFilename]; ok {
+		kubeconfig, err := buildEmbeddedKubeconfig(cluster, bundle, files)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Data[secretKubeconfigKey] = base64.StdEncoding.EncodeToString(kubeconfig)
+	}
+
+	return manifest, nil
+}
+
+// buildEmbeddedKubeconfig builds a kubeconfig for the cluster which includes the certificates,
+// instead of referring to files on the local machine
+func buildEmbeddedKubeconfig(cluster string, bundle []byte, files map[string][]byte) ([]byte, error) {
+	server, err := parseBundleServer(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range []string{caCertFilename, clientCertFilename, clientKeyFilename} {
+		if _, ok := files[file]; !ok {
+			return nil, errors.Errorf("Invalid credentials bundle, %s is missing", file)
+		}
+	}
+
+	config := newKubeconfig()
+	config.Clusters = []kubeconfigCluster{{
+		Name: cluster,
+		Cluster: map[string]interface{}{
+			"server":                     server,
+			"certificate-authority-data": base64.StdEncoding.EncodeToString(files[caCertFilename]),
+		},
+	}}
+	config.Users = []kubeconfigUser{{
+		Name: cluster,
+		User: map[string]interface{}{
+			"client-certificate-data": base64.StdEncoding.EncodeToString(files[clientCertFilename]),
+			"client-key-data":         base64.StdEncoding.EncodeToString(files[clientKeyFilename]),
+		},
+	}}
+	config.Contexts = []kubeconfigContext{{
+		Name:    cluster,
+		Context: map[string]interface{}{"cluster": cluster, "user": cluster},
+	}}
+	config.CurrentContext = cluster
+
+	contents, err := yaml.Marshal(config)
+	return contents, errors.Wrap(err, "Unable to build the kubeconfig for the secret")
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestBuildSecretManifest(t *testing.T) {
+	files := map[string][]byte{
+		"ca.pem":   []byte("CA"),
+		"cert.pem": []byte("CERT"),
+		"key.pem":  []byte("KEY"),
+		"kubectl.config": []byte(`apiVersion: v1
+clusters:
+- name: mycluster
+  cluster:
+    server: https://10.0.0.1:6443
+    certificate-authority: ca.pem
+`),
+		"my notes.txt": []byte("not a valid key"),
+	}
+
+	manifest, err := buildSecretManifest("mycluster", "mycluster-credentials", "ci", files)
+	require.NoError(t, err)
+	assert.Equal(t, "Secret", manifest.Kind)
+	assert.Equal(t, "ci", manifest.Metadata.Namespace)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("CA")), manifest.Data["ca.pem"])
+	assert.NotContains(t, manifest.Data, "my notes.txt")
+
+	// The kubeconfig embeds the certificates, so that it works wherever the secret is mounted
+	contents, err := base64.StdEncoding.DecodeString(manifest.Data[secretKubeconfigKey])
+	require.NoError(t, err)
+	config := newKubeconfig()
+	require.NoError(t, yaml.Unmarshal(contents, config))
+	require.Len(t, config.Clusters, 1)
+	assert.Equal(t, "https://10.0.0.1:6443", config.Clusters[0].Cluster["server"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("CA")), config.Clusters[0].Cluster["certificate-authority-data"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("KEY")), config.Users[0].User["client-key-data"])
+	assert.Equal(t, "mycluster", config.CurrentContext)
+}
+
+func TestBuildSecretManifestForSwarm(t *testing.T) {
+	files := map[string][]byte{"ca.pem": []byte("CA"), "docker.env": []byte("export DOCKER_HOST=tcp://10.0.0.1:2376")}
+
+	manifest, err := buildSecretManifest("myswarm", "myswarm-credentials", "", files)
+	require.NoError(t, err)
+	assert.Len(t, manifest.Data, 2)
+	assert.NotContains(t, manifest.Data, secretKubeconfigKey)
+
+	contents, err := yaml.Marshal(manifest)
+	require.NoError(t, err)
+	assert.NotContains(t, string(contents), "namespace:")
+}
+
+func TestDefaultSecretName(t *testing.T) {
+	assert.Equal(t, "my-cluster-credentials", defaultSecretName("My_Cluster"))
+	assert.Equal(t, "carina-credentials", defaultSecretName("__"))
+}
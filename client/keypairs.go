@@ -0,0 +1,49 @@
+package client
+
+import (
+	"io/ioutil"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// sshPublicKeyTypes are the key types accepted in an SSH public key file
+var sshPublicKeyTypes = []string{"ssh-rsa", "ssh-dss", "ssh-ed25519", "ecdsa-sha2-"}
+
+// ListKeypairs retrieves the SSH keypairs registered with the account, which can be installed on the nodes of a new cluster
+func (client *Client) ListKeypairs(account Account) ([]common.Keypair, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := svc.(common.KeypairLister)
+	if !ok {
+		return nil, errors.New("Keypairs are not supported by this cloud")
+	}
+
+	keypairs, err := lister.ListKeypairs()
+	return keypairs, wrapClientError(err)
+}
+
+// ReadSSHPublicKey reads an SSH public key file, such as ~/.ssh/id_rsa.pub
+func ReadSSHPublicKey(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to read the SSH public key %s", path)
+	}
+
+	key := strings.TrimSpace(string(contents))
+	if strings.Contains(key, "PRIVATE KEY") {
+		return "", errors.Errorf("%s is a private key. Specify the public key instead, e.g. %s.pub", path, path)
+	}
+
+	for _, keyType := range sshPublicKeyTypes {
+		if strings.HasPrefix(key, keyType) && !strings.Contains(key, "\n") {
+			return key, nil
+		}
+	}
+	return "", errors.Errorf("%s is not an SSH public key. The key should be a single line in the OpenSSH format, e.g. ssh-rsa AAAA... alice@example.com", path)
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrKeyringItemNotFound is returned by a Keyring when the requested secret does not exist
+var ErrKeyringItemNotFound = errors.New("The secret was not found in the keychain")
+
+// Keyring stores secrets in the operating system's keychain
+type Keyring interface {
+	// Get retrieves a secret, returning ErrKeyringItemNotFound when it does not exist
+	Get(service string, user string) (string, error)
+
+	// Set saves a secret, replacing any existing value
+	Set(service string, user string, secret string) error
+}
+
+// keyringCommandError is returned when a keychain command line tool exits with an error
+type keyringCommandError struct {
+	Command  string
+	ExitCode int
+	Stderr   string
+}
+
+// Error returns the tool's error message
+func (err keyringCommandError) Error() string {
+	return fmt.Sprintf("%s exited with %d: %s", err.Command, err.ExitCode, err.Stderr)
+}
+
+// runKeyringCommand runs a keychain command line tool, such as security or secret-tool, returning its trimmed output
+func runKeyringCommand(stdin string, name string, args ...string) (string, error) {
+	stdout, _, err := runKeyringCommandOutput(stdin, name, args...)
+	return stdout, err
+}
+
+// runKeyringCommandOutput runs a keychain command line tool, returning its trimmed output and error output,
+// for tools which report a failure in their error output instead of their exit code
+func runKeyringCommandOutput(stdin string, name string, args ...string) (string, string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return "", "", keyringCommandError{Command: name, ExitCode: exitErr.ExitCode(), Stderr: strings.TrimSpace(stderr.String())}
+	}
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Unable to run %s", name)
+	}
+
+	return strings.TrimSpace(stdout.String()), strings.TrimSpace(stderr.String()), nil
+}
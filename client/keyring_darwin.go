@@ -0,0 +1,45 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// macKeyring stores secrets in the macOS login keychain, using the security command line tool
+type macKeyring struct{}
+
+func newSystemKeyring() Keyring {
+	return macKeyring{}
+}
+
+// Get retrieves a generic password from the keychain
+func (macKeyring) Get(service string, user string) (string, error) {
+	secret, err := runKeyringCommand("", "security", "find-generic-password", "-s", service, "-a", user, "-w")
+	// security exits with 44 when the item does not exist
+	if cmdErr, ok := err.(keyringCommandError); ok && cmdErr.ExitCode == 44 {
+		return "", ErrKeyringItemNotFound
+	}
+	return secret, err
+}
+
+// Set saves a generic password to the keychain, updating it if it already exists.
+// The command is written to security -i on stdin, so that the secret isn't in the arguments shown by ps.
+func (macKeyring) Set(service string, user string, secret string) error {
+	command := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", quoteSecurityArg(service), quoteSecurityArg(user), quoteSecurityArg(secret))
+	_, stderr, err := runKeyringCommandOutput(command, "security", "-i")
+	if err != nil {
+		return err
+	}
+	// security -i reports a failed command in its error output, instead of its exit code
+	if stderr != "" {
+		return errors.Errorf("Unable to save the secret to the keychain: %s", stderr)
+	}
+	return nil
+}
+
+// quoteSecurityArg quotes an argument for a command read by security -i
+func quoteSecurityArg(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
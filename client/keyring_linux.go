@@ -0,0 +1,24 @@
+package client
+
+// secretServiceKeyring stores secrets with the Secret Service API, e.g. GNOME Keyring or KWallet, using the secret-tool command line tool
+type secretServiceKeyring struct{}
+
+func newSystemKeyring() Keyring {
+	return secretServiceKeyring{}
+}
+
+// Get retrieves a secret from the Secret Service
+func (secretServiceKeyring) Get(service string, user string) (string, error) {
+	secret, err := runKeyringCommand("", "secret-tool", "lookup", "service", service, "user", user)
+	// secret-tool exits with 1 and prints nothing when the secret does not exist
+	if cmdErr, ok := err.(keyringCommandError); ok && cmdErr.ExitCode == 1 && cmdErr.Stderr == "" {
+		return "", ErrKeyringItemNotFound
+	}
+	return secret, err
+}
+
+// Set saves a secret to the Secret Service, replacing any existing value
+func (secretServiceKeyring) Set(service string, user string, secret string) error {
+	_, err := runKeyringCommand(secret, "secret-tool", "store", "--label=carina "+user, "service", service, "user", user)
+	return err
+}
@@ -0,0 +1,26 @@
+// +build !darwin,!linux,!windows
+
+package client
+
+import (
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// unsupportedKeyring is used on platforms without a supported keychain
+type unsupportedKeyring struct{}
+
+func newSystemKeyring() Keyring {
+	return unsupportedKeyring{}
+}
+
+// Get always fails, because the keychain is not supported
+func (unsupportedKeyring) Get(service string, user string) (string, error) {
+	return "", errors.Errorf("The keychain is not supported on %s", runtime.GOOS)
+}
+
+// Set always fails, because the keychain is not supported
+func (unsupportedKeyring) Set(service string, user string, secret string) error {
+	return errors.Errorf("The keychain is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,176 @@
+// +build windows
+
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+var (
+	advapi32       = syscall.NewLazyDLL("advapi32.dll")
+	procCredReadW  = advapi32.NewProc("CredReadW")
+	procCredWriteW = advapi32.NewProc("CredWriteW")
+	procCredFree   = advapi32.NewProc("CredFree")
+
+	crypt32                = syscall.NewLazyDLL("crypt32.dll")
+	procCryptUnprotectData = crypt32.NewProc("CryptUnprotectData")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+	errorNotFound           = syscall.Errno(1168)
+)
+
+// credential is the CREDENTIALW structure used by the Credential Manager API
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credentialManagerKeyring stores secrets as generic credentials in the Windows Credential Manager
+type credentialManagerKeyring struct{}
+
+func newSystemKeyring() Keyring {
+	return credentialManagerKeyring{}
+}
+
+func credentialTarget(service string, user string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(service + ":" + user)
+}
+
+// Get reads a generic credential
+func (credentialManagerKeyring) Get(service string, user string) (string, error) {
+	target, err := credentialTarget(service, user)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	result, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred)))
+	if result == 0 {
+		if err == errorNotFound {
+			return migrateDPAPISecret(service, user)
+		}
+		return "", errors.Wrap(err, "CredRead failed")
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	secret := make([]byte, cred.CredentialBlobSize)
+	if cred.CredentialBlobSize > 0 {
+		copy(secret, (*[1 << 20]byte)(unsafe.Pointer(cred.CredentialBlob))[:cred.CredentialBlobSize:cred.CredentialBlobSize])
+	}
+	return string(secret), nil
+}
+
+// Set saves a generic credential, replacing any existing value
+func (credentialManagerKeyring) Set(service string, user string, secret string) error {
+	target, err := credentialTarget(service, user)
+	if err != nil {
+		return err
+	}
+	userName, err := syscall.UTF16PtrFromString(user)
+	if err != nil {
+		return err
+	}
+
+	blob := []byte(secret)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		UserName:           userName,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            credPersistLocalMachine,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	result, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if result == 0 {
+		return errors.Wrap(err, "CredWrite failed")
+	}
+	return nil
+}
+
+// dataBlob is the DATA_BLOB structure used by the Data Protection API
+type dataBlob struct {
+	size uint32
+	data *byte
+}
+
+func newDataBlob(data []byte) *dataBlob {
+	if len(data) == 0 {
+		return &dataBlob{}
+	}
+	return &dataBlob{size: uint32(len(data)), data: &data[0]}
+}
+
+func (blob *dataBlob) bytes() []byte {
+	result := make([]byte, blob.size)
+	copy(result, (*[1 << 30]byte)(unsafe.Pointer(blob.data))[:blob.size:blob.size])
+	return result
+}
+
+// dpapiSecretPath is where a secret was saved before the Credential Manager was used,
+// in a file in CARINA_HOME encrypted with the Windows Data Protection API
+func dpapiSecretPath(service string, user string) (string, error) {
+	baseDir, err := GetCredentialsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(baseDir, "keyring", service+"."+user), nil
+}
+
+// migrateDPAPISecret moves a secret saved by an earlier version of carina, e.g. the credentials encryption key,
+// from its DPAPI protected file into the Credential Manager. Returns ErrKeyringItemNotFound when there is nothing to migrate.
+func migrateDPAPISecret(service string, user string) (string, error) {
+	path, err := dpapiSecretPath(service, user)
+	if err != nil {
+		return "", ErrKeyringItemNotFound
+	}
+
+	protected, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", ErrKeyringItemNotFound
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to read the secret from %s", path)
+	}
+
+	var output dataBlob
+	result, _, err := procCryptUnprotectData.Call(uintptr(unsafe.Pointer(newDataBlob(protected))), 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(&output)))
+	if result == 0 {
+		return "", errors.Wrapf(err, "Unable to decrypt the secret in %s, CryptUnprotectData failed", path)
+	}
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(output.data)))
+	secret := string(output.bytes())
+
+	common.Log.WriteDebug("Moving the secret in %s to the Credential Manager", path)
+	err = credentialManagerKeyring{}.Set(service, user, secret)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to move the secret in %s to the Credential Manager", path)
+	}
+	os.Remove(path)
+	// Only removed once every secret has been moved
+	os.Remove(filepath.Dir(path))
+
+	return secret, nil
+}
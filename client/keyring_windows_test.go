@@ -0,0 +1,63 @@
+// +build windows
+
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	procCryptProtectData = crypt32.NewProc("CryptProtectData")
+	procCredDeleteW      = advapi32.NewProc("CredDeleteW")
+)
+
+// protectSecret saves a secret the way earlier versions of carina did, in a DPAPI protected file
+func protectSecret(t *testing.T, service string, user string, secret string) string {
+	var output dataBlob
+	result, _, err := procCryptProtectData.Call(uintptr(unsafe.Pointer(newDataBlob([]byte(secret)))), 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(&output)))
+	require.NotZero(t, result, "CryptProtectData failed: %v", err)
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(output.data)))
+
+	path, err := dpapiSecretPath(service, user)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, ioutil.WriteFile(path, output.bytes(), 0600))
+	return path
+}
+
+func TestCredentialManagerMigratesDPAPISecrets(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-keyring")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	os.Setenv(CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(CarinaHomeDirEnvVar)
+
+	service, user := "carina-test-"+randomName(), "encryption-key"
+	defer func() {
+		target, _ := credentialTarget(service, user)
+		procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	}()
+
+	keyring := newSystemKeyring()
+	_, err = keyring.Get(service, user)
+	assert.Equal(t, ErrKeyringItemNotFound, err)
+
+	path := protectSecret(t, service, user, "s3cret")
+	secret, err := keyring.Get(service, user)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", secret, "A secret saved with DPAPI should still be readable")
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "The DPAPI protected file should be removed once it is migrated")
+
+	secret, err = keyring.Get(service, user)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", secret, "The secret should be read from the Credential Manager after it is migrated")
+}
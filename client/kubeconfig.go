@@ -0,0 +1,280 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// bundleKubeconfigFilename is the kubeconfig included in the credentials bundle of Kubernetes clusters
+const bundleKubeconfigFilename = "kubectl.config"
+
+// KubeconfigOptions controls how a cluster is added to a kubeconfig file
+type KubeconfigOptions struct {
+	// Path to the kubeconfig file, defaults to the first file in KUBECONFIG, or ~/.kube/config
+	Path string
+
+	// Merge the cluster into an existing kubeconfig file, instead of requiring a new file
+	Merge bool
+
+	// SetCurrent switches the current context to the cluster
+	SetCurrent bool
+}
+
+// kubeconfig is the subset of the kubectl configuration file that carina manages.
+// Unrecognized fields are preserved when merging.
+type kubeconfig struct {
+	APIVersion     string                 `yaml:"apiVersion,omitempty"`
+	Kind           string                 `yaml:"kind,omitempty"`
+	Clusters       []kubeconfigCluster    `yaml:"clusters"`
+	Contexts       []kubeconfigContext    `yaml:"contexts"`
+	Users          []kubeconfigUser       `yaml:"users"`
+	CurrentContext string                 `yaml:"current-context"`
+	Extra          map[string]interface{} `yaml:",inline"`
+}
+
+type kubeconfigCluster struct {
+	Name    string                 `yaml:"name"`
+	Cluster map[string]interface{} `yaml:"cluster"`
+}
+
+type kubeconfigContext struct {
+	Name    string                 `yaml:"name"`
+	Context map[string]interface{} `yaml:"context"`
+}
+
+type kubeconfigUser struct {
+	Name string                 `yaml:"name"`
+	User map[string]interface{} `yaml:"user"`
+}
+
+func newKubeconfig() *kubeconfig {
+	return &kubeconfig{APIVersion: "v1", Kind: "Config"}
+}
+
+// defaultKubeconfigPath returns the kubeconfig used by kubectl
+func defaultKubeconfigPath() (string, error) {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0], nil
+	}
+
+	homeDir, err := userHomeDir()
+	if err != nil {
+		return "", errors.New("Unable to default the kubeconfig path to ~/.kube/config. Use --path to specify the kubeconfig file")
+	}
+	return filepath.Join(homeDir, ".kube", "config"), nil
+}
+
+// readBundleServer finds the address of the Kubernetes API in the kubeconfig from a credentials bundle
+func readBundleServer(credentialsPath string) (string, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(credentialsPath, bundleKubeconfigFilename))
+	if os.IsNotExist(err) {
+		return "", errors.Errorf("The credentials in %s are not for a Kubernetes cluster", credentialsPath)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to read the kubeconfig from the credentials bundle")
+	}
+
+	return parseBundleServer(contents)
+}
+
+// parseBundleServer finds the address of the Kubernetes API in the contents of a credentials bundle's kubeconfig
+func parseBundleServer(contents []byte) (string, error) {
+	bundle := newKubeconfig()
+	err := yaml.Unmarshal(contents, bundle)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to parse the kubeconfig from the credentials bundle")
+	}
+
+	for _, cluster := range bundle.Clusters {
+		if server, ok := cluster.Cluster["server"].(string); ok && server != "" {
+			return server, nil
+		}
+	}
+	return "", errors.New("Invalid credentials bundle, the kubeconfig does not specify the cluster address")
+}
+
+// mergeCluster adds or replaces the cluster, user and context entries for a carina cluster
+func (config *kubeconfig) mergeCluster(entryName string, server string, credentialsPath string, setCurrent bool) {
+	cluster := kubeconfigCluster{
+		Name: entryName,
+		Cluster: map[string]interface{}{
+			"server":                server,
+			"certificate-authority": filepath.Join(credentialsPath, caCertFilename),
+		},
+	}
+	user := kubeconfigUser{
+		Name: entryName,
+		User: map[string]interface{}{
+			"client-certificate": filepath.Join(credentialsPath, clientCertFilename),
+			"client-key":         filepath.Join(credentialsPath, clientKeyFilename),
+		},
+	}
+	context := kubeconfigContext{
+		Name: entryName,
+		Context: map[string]interface{}{
+			"cluster": entryName,
+			"user":    entryName,
+		},
+	}
+
+	replaced := false
+	for i := range config.Clusters {
+		if config.Clusters[i].Name == entryName {
+			config.Clusters[i] = cluster
+			replaced = true
+		}
+	}
+	if !replaced {
+		config.Clusters = append(config.Clusters, cluster)
+	}
+
+	replaced = false
+	for i := range config.Users {
+		if config.Users[i].Name == entryName {
+			config.Users[i] = user
+			replaced = true
+		}
+	}
+	if !replaced {
+		config.Users = append(config.Users, user)
+	}
+
+	replaced = false
+	for i := range config.Contexts {
+		if config.Contexts[i].Name == entryName {
+			config.Contexts[i] = context
+			replaced = true
+		}
+	}
+	if !replaced {
+		config.Contexts = append(config.Contexts, context)
+	}
+
+	if setCurrent || config.CurrentContext == "" {
+		config.CurrentContext = entryName
+	}
+}
+
+// WriteKubeconfig adds a Kubernetes cluster to a kubeconfig file, downloading its credentials if necessary.
+// Returns the kubeconfig path and the name of the context for the cluster.
+func (client *Client) WriteKubeconfig(account Account, name string, customPath string, options KubeconfigOptions) (kubeconfigPath string, contextName string, err error) {
+	kubeconfigPath = options.Path
+	if kubeconfigPath == "" {
+		kubeconfigPath, err = defaultKubeconfigPath()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	config := newKubeconfig()
+	contents, err := ioutil.ReadFile(kubeconfigPath)
+	switch {
+	case os.IsNotExist(err):
+		// A new kubeconfig will be created
+	case err != nil:
+		return "", "", errors.Wrapf(err, "Unable to read %s", kubeconfigPath)
+	case !options.Merge:
+		return "", "", fmt.Errorf("%s already exists. Use --merge to add the cluster to the existing kubeconfig", kubeconfigPath)
+	default:
+		err = yaml.Unmarshal(contents, config)
+		if err != nil {
+			return "", "", errors.Wrapf(err, "Unable to parse %s", kubeconfigPath)
+		}
+	}
+
+	credentialsPath, err := client.ensureSessionClusterCredentials(account, name, customPath)
+	if err != nil {
+		return "", "", err
+	}
+	credentialsPath, err = filepath.Abs(credentialsPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	server, err := readBundleServer(credentialsPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	contextName = "carina-" + name
+	config.mergeCluster(contextName, server, credentialsPath, options.SetCurrent)
+
+	contents, err = yaml.Marshal(config)
+	if err != nil {
+		return "", "", errors.Wrap(err, "Unable to serialize the kubeconfig")
+	}
+
+	err = os.MkdirAll(filepath.Dir(kubeconfigPath), 0700)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = ioutil.WriteFile(kubeconfigPath, contents, 0600)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Unable to write %s", kubeconfigPath)
+	}
+	restrictAccess(kubeconfigPath)
+
+	return kubeconfigPath, contextName, nil
+}
+
+// refreshKubeconfigEntry updates a cluster's entry in the default kubeconfig, if WriteKubeconfig added it there,
+// so that it uses the cluster's current credentials. Returns the kubeconfig path, or an empty string when it has no entry for the cluster.
+func (client *Client) refreshKubeconfigEntry(name string, credentialsPath string) (string, error) {
+	kubeconfigPath, err := defaultKubeconfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	contents, err := ioutil.ReadFile(kubeconfigPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to read %s", kubeconfigPath)
+	}
+
+	config := newKubeconfig()
+	err = yaml.Unmarshal(contents, config)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to parse %s", kubeconfigPath)
+	}
+
+	entryName := "carina-" + name
+	found := false
+	for _, context := range config.Contexts {
+		if context.Name == entryName {
+			found = true
+		}
+	}
+	if !found {
+		return "", nil
+	}
+
+	credentialsPath, err = filepath.Abs(credentialsPath)
+	if err != nil {
+		return "", err
+	}
+	server, err := readBundleServer(credentialsPath)
+	if err != nil {
+		return "", err
+	}
+	config.mergeCluster(entryName, server, credentialsPath, false)
+
+	contents, err = yaml.Marshal(config)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to serialize the kubeconfig")
+	}
+	err = ioutil.WriteFile(kubeconfigPath, contents, 0600)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to write %s", kubeconfigPath)
+	}
+	restrictAccess(kubeconfigPath)
+
+	return kubeconfigPath, nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMergeClusterIntoKubeconfig(t *testing.T) {
+	existing := `apiVersion: v1
+kind: Config
+preferences: {}
+clusters:
+- name: minikube
+  cluster:
+    server: https://192.168.99.100:8443
+contexts:
+- name: minikube
+  context:
+    cluster: minikube
+    user: minikube
+users:
+- name: minikube
+  user:
+    client-key: /home/alicia/.minikube/client.key
+current-context: minikube
+`
+	config := newKubeconfig()
+	err := yaml.Unmarshal([]byte(existing), config)
+	assert.Nil(t, err)
+
+	config.mergeCluster("carina-mycluster", "https://10.0.0.1:6443", "/carina/mycluster", false)
+	assert.Len(t, config.Clusters, 2)
+	assert.Len(t, config.Contexts, 2)
+	assert.Len(t, config.Users, 2)
+	assert.Equal(t, "minikube", config.CurrentContext)
+	assert.Contains(t, config.Extra, "preferences")
+
+	// Merging again replaces the existing entries
+	config.mergeCluster("carina-mycluster", "https://10.0.0.2:6443", "/carina/mycluster", true)
+	assert.Len(t, config.Clusters, 2)
+	assert.Equal(t, "https://10.0.0.2:6443", config.Clusters[1].Cluster["server"])
+	assert.Equal(t, "carina-mycluster", config.CurrentContext)
+}
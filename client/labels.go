@@ -0,0 +1,117 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+)
+
+// labeledCluster decorates a cluster with the labels stored in the cache
+type labeledCluster struct {
+	common.Cluster
+	labels map[string]string
+}
+
+// GetLabels returns the key/value pairs used to organize the cluster
+func (cluster *labeledCluster) GetLabels() map[string]string {
+	return cluster.labels
+}
+
+// ParseLabels converts a set of key=value pairs into a map of labels
+func ParseLabels(values []string) (map[string]string, error) {
+	return parseKeyValuePairs(values, "label")
+}
+
+// ParseDriverOptions converts a set of key=value pairs into a map of driver options
+func ParseDriverOptions(values []string) (map[string]string, error) {
+	return parseKeyValuePairs(values, "driver option")
+}
+
+func parseKeyValuePairs(values []string, kind string) (map[string]string, error) {
+	pairs := make(map[string]string, len(values))
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || key == "" {
+			return nil, fmt.Errorf("Invalid %s: %s. Use the format key=value", kind, value)
+		}
+		pairs[key] = strings.TrimSpace(parts[1])
+	}
+	return pairs, nil
+}
+
+// MatchesLabels returns if the cluster has all of the specified labels
+func MatchesLabels(cluster common.Cluster, selector map[string]string) bool {
+	labels := cluster.GetLabels()
+	for key, value := range selector {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// applyLabels adds the cached labels to a cluster
+func (client *Client) applyLabels(account Account, cluster common.Cluster) common.Cluster {
+	if cluster == nil {
+		return nil
+	}
+
+	cachedLabels := client.Cache.getClusterLabels(account, cluster.GetID())
+	if len(cachedLabels) == 0 {
+		return cluster
+	}
+
+	labels := make(map[string]string)
+	for key, value := range cluster.GetLabels() {
+		labels[key] = value
+	}
+	for key, value := range cachedLabels {
+		labels[key] = value
+	}
+
+	return &labeledCluster{Cluster: cluster, labels: labels}
+}
+
+// AddClusterLabels adds or updates labels on a cluster
+func (client *Client) AddClusterLabels(account Account, name string, labels map[string]string) (common.Cluster, error) {
+	cluster, err := client.GetCluster(account, name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Cache.SaveClusterLabels(account, cluster.GetID(), func(current map[string]string) {
+		for key, value := range labels {
+			current[key] = value
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return client.applyLabels(account, cluster), nil
+}
+
+// RemoveClusterLabels removes labels from a cluster
+func (client *Client) RemoveClusterLabels(account Account, name string, keys []string) (common.Cluster, error) {
+	cluster, err := client.GetCluster(account, name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.Cache.SaveClusterLabels(account, cluster.GetID(), func(current map[string]string) {
+		for _, key := range keys {
+			delete(current, key)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Rebuild the cluster from the adapter, so that removed labels are not carried over from the decorator
+	if labeled, ok := cluster.(*labeledCluster); ok {
+		cluster = labeled.Cluster
+	}
+	return client.applyLabels(account, cluster), nil
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetClusterSpecCopiesLabels(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+
+	_, err := client.CreateCluster(account, "prod", "Swarm*", 2, CreateClusterOptions{Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+
+	spec, err := client.GetClusterSpec(account, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, spec.Labels)
+
+	// The copy's labels can be changed without changing the original cluster's labels
+	spec.Labels["env"] = "staging"
+	cluster, err := client.GetCluster(account, "prod", false)
+	require.NoError(t, err)
+	assert.Equal(t, "prod", cluster.GetLabels()["env"])
+}
+
+func TestClusterLabelsWithoutCache(t *testing.T) {
+	cache := &Cache{}
+	account := &stubAccount{}
+
+	assert.NoError(t, cache.DeleteClusterLabels(account, "123"), "Deleting labels should be a no-op when the cache is disabled")
+	assert.Error(t, cache.SaveClusterLabels(account, "123", func(labels map[string]string) {}))
+	assert.Empty(t, cache.getClusterLabels(account, "123"))
+}
+
+func TestApplyManifestLabels(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("web", "Swarm*", 1)
+	account := &historyAccount{offlineAccount{service: service}}
+
+	manifest := ClusterManifest{Clusters: []ClusterSpec{{Name: "web", Nodes: 1, Labels: map[string]string{"env": "prod"}}}}
+	plan, err := client.PlanApply(account, manifest, ApplyOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []ClusterChange{{Action: ChangeLabel, Name: "web", Labels: map[string]string{"env": "prod"}}}, plan.Changes)
+
+	results, err := client.Apply(account, plan, ApplyOptions{})
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.NoError(t, result.Err, result.Name)
+	}
+
+	cluster, err := client.GetCluster(account, "web", false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, cluster.GetLabels())
+
+	plan, err = client.PlanApply(account, manifest, ApplyOptions{})
+	require.NoError(t, err)
+	assert.False(t, plan.HasChanges(), "the labels saved in the cache should match the manifest")
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// clusterLockRefreshInterval is how often a held cluster lock is touched, so that other carina processes know it's still in use
+const clusterLockRefreshInterval = 30 * time.Second
+
+// staleClusterLockAge is how long a cluster lock can go without being refreshed before it is assumed to be abandoned
+// by a carina process which was killed
+const staleClusterLockAge = 4 * clusterLockRefreshInterval
+
+// unsafeLockFilenameChars are replaced in the account id and cluster name when building the name of a lock file
+var unsafeLockFilenameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// clusterLockHolder describes the carina process holding a cluster lock, and is stored in the lock file
+type clusterLockHolder struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	Operation string    `json:"operation"`
+	Acquired  time.Time `json:"acquired"`
+}
+
+// ClusterLockedError is returned when another carina command is already changing a cluster
+type ClusterLockedError struct {
+	ClusterName string
+	Operation   string
+	PID         int
+	Host        string
+	Acquired    time.Time
+	Path        string
+}
+
+// Error explains which command holds the lock, and how to override it
+func (err ClusterLockedError) Error() string {
+	return fmt.Sprintf("Another carina command (pid %d on %s) has been running %q on the cluster (%s) since %s. Wait for it to finish, or use --no-lock to override. If no other carina commands are running, delete %s",
+		err.PID, err.Host, err.Operation, err.ClusterName, err.Acquired.Local().Format(time.RFC1123), err.Path)
+}
+
+// lockDir is the directory holding the cluster locks, which is the same directory as the on-disk cache.
+// When the cache is disabled, e.g. --cache=false in CI, the locks are kept in CARINA_HOME instead.
+func (client *Client) lockDir() (string, error) {
+	if client.lockHome != "" {
+		return client.lockHome, nil
+	}
+	if !client.Cache.isNil() {
+		return filepath.Dir(client.Cache.path), nil
+	}
+
+	dir, err := GetCredentialsDir()
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to create %s", dir)
+	}
+	return dir, nil
+}
+
+// lockCluster takes an advisory lock on a cluster for the duration of a change, so that concurrent carina commands,
+// such as a resize racing a delete in CI, don't make conflicting changes to the same cluster. The lock is a file in
+// the cache directory, or CARINA_HOME when the cache is disabled, which is refreshed while it is held, and a lock which
+// hasn't been refreshed recently is assumed to be abandoned and is replaced. Locking is only skipped with --no-lock.
+func (client *Client) lockCluster(account Account, name string, operation string) (unlock func(), err error) {
+	if client.DisableLocks {
+		return func() {}, nil
+	}
+	dir, err := client.lockDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to lock the cluster")
+	}
+
+	filename := "cluster-" + unsafeLockFilenameChars.ReplaceAllString(account.GetID(), "_") + "-" + unsafeLockFilenameChars.ReplaceAllString(name, "_") + ".lock"
+	lockPath := filepath.Join(dir, filename)
+
+	host, _ := os.Hostname()
+	holder := clusterLockHolder{PID: os.Getpid(), Host: host, Operation: operation, Acquired: time.Now().UTC()}
+	contents, err := json.Marshal(holder)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to lock the cluster")
+	}
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, err = f.Write(contents)
+			f.Close()
+			if err != nil {
+				os.Remove(lockPath)
+				return nil, errors.Wrap(err, "Unable to lock the cluster")
+			}
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, errors.Wrap(err, "Unable to lock the cluster")
+		}
+
+		info, statErr := os.Stat(lockPath)
+		if statErr != nil {
+			// The lock was released while we were looking at it
+			continue
+		}
+		if time.Since(info.ModTime()) > staleClusterLockAge {
+			common.Log.WriteWarning("Removing the abandoned lock on the cluster (%s), which hasn't been refreshed since %s", name, info.ModTime().Format(time.RFC1123))
+			os.Remove(lockPath)
+			continue
+		}
+
+		return nil, newClusterLockedError(name, lockPath)
+	}
+
+	common.Log.WriteDebug("Locked the cluster (%s) with %s", name, lockPath)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(clusterLockRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				os.Chtimes(lockPath, now, now)
+			}
+		}
+	}()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			close(done)
+			os.Remove(lockPath)
+			client.releaseLock(lockPath)
+			common.Log.WriteDebug("Unlocked the cluster (%s)", name)
+		})
+	}
+	client.holdLock(lockPath, release)
+	return release, nil
+}
+
+// newClusterLockedError describes the holder of a lock, as much as can be read from the lock file
+func newClusterLockedError(name string, lockPath string) error {
+	lockedErr := ClusterLockedError{ClusterName: name, Path: lockPath}
+
+	contents, err := ioutil.ReadFile(lockPath)
+	if err == nil {
+		var holder clusterLockHolder
+		if json.Unmarshal(contents, &holder) == nil {
+			lockedErr.Operation = holder.Operation
+			lockedErr.PID = holder.PID
+			lockedErr.Host = holder.Host
+			lockedErr.Acquired = holder.Acquired
+		}
+	}
+
+	return lockedErr
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockClusterBlocksConcurrentChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-locks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	client := &Client{Cache: newCache(filepath.Join(dir, "cache.json"))}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+
+	_, err = client.CreateCluster(account, "prod", "Swarm*", 1, CreateClusterOptions{})
+	require.NoError(t, err)
+
+	// Simulate another carina command resizing the cluster
+	unlock, err := client.lockCluster(account, "prod", "resize")
+	require.NoError(t, err)
+
+	err = client.DeleteCluster(account, "prod", false)
+	lockedErr, ok := errors.Cause(err).(ClusterLockedError)
+	if assert.True(t, ok, "Expected a ClusterLockedError, got %v", err) {
+		assert.Equal(t, "resize", lockedErr.Operation)
+		assert.Equal(t, os.Getpid(), lockedErr.PID)
+	}
+
+	// --no-lock overrides the lock
+	client.DisableLocks = true
+	_, err = client.ResizeCluster(account, "prod", 2, false)
+	assert.NoError(t, err)
+	client.DisableLocks = false
+
+	unlock()
+	err = client.DeleteCluster(account, "prod", false)
+	assert.NoError(t, err)
+}
+
+func TestLockClusterReplacesStaleLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-locks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	client := &Client{Cache: newCache(filepath.Join(dir, "cache.json"))}
+	account := &historyAccount{offlineAccount{service: testsupport.NewFakeClusterService()}}
+
+	_, err = client.lockCluster(account, "prod", "delete")
+	require.NoError(t, err)
+
+	matches, err := filepath.Glob(filepath.Join(dir, "cluster-*.lock"))
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	abandoned := time.Now().Add(-2 * staleClusterLockAge)
+	require.NoError(t, os.Chtimes(matches[0], abandoned, abandoned))
+
+	unlock, err := client.lockCluster(account, "prod", "resize")
+	require.NoError(t, err)
+	unlock()
+
+	_, err = os.Stat(matches[0])
+	assert.True(t, os.IsNotExist(err), "The lock should be removed when it is released")
+}
+
+func TestLockClusterWithoutCache(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-locks")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	os.Setenv(CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(CarinaHomeDirEnvVar)
+
+	// --cache=false shouldn't turn off locking
+	client := NewClient(false)
+	account := &historyAccount{offlineAccount{service: testsupport.NewFakeClusterService()}}
+
+	unlock, err := client.lockCluster(account, "prod", "resize")
+	require.NoError(t, err)
+	matches, err := filepath.Glob(filepath.Join(home, "cluster-*.lock"))
+	require.NoError(t, err)
+	assert.Len(t, matches, 1, "The lock should be in CARINA_HOME")
+
+	_, err = client.lockCluster(account, "prod", "delete")
+	_, ok := errors.Cause(err).(ClusterLockedError)
+	assert.True(t, ok, "Expected a ClusterLockedError, got %v", err)
+	unlock()
+}
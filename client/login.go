@@ -0,0 +1,35 @@
+package client
+
+import (
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// Login authenticates with the account's credentials, ignoring any cached token, and caches the new token and endpoint.
+// Returns the endpoint of the cluster API. Rejected credentials are returned as a common.AuthError.
+func (client *Client) Login(account Account) (string, error) {
+	// Don't apply the cache, so that the credentials are validated instead of a cached token
+	svc := account.NewClusterService()
+	authenticator, ok := svc.(common.Authenticator)
+	if !ok {
+		return "", errors.Errorf("Logging in is not supported for %s", account.GetID())
+	}
+
+	endpoint, err := authenticator.Authenticate()
+	if err != nil {
+		return "", wrapClientError(err)
+	}
+
+	err = client.Cache.SaveAccount(account)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to cache the auth token")
+	}
+
+	return endpoint, nil
+}
+
+// Logout removes the account's cached auth token and endpoint, so that the next command authenticates again.
+// Returns if anything was cached for the account.
+func (client *Client) Logout(account Account) (bool, error) {
+	return client.Cache.RemoveAccount(account)
+}
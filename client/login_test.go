@@ -0,0 +1,89 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loginAccount is a caching account which authenticates with a fake cluster service
+type loginAccount struct {
+	cachingAccount
+	service common.ClusterService
+}
+
+func (account *loginAccount) NewClusterService() common.ClusterService {
+	return account.service
+}
+
+func TestLoginCachesToken(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	account := &loginAccount{
+		cachingAccount: cachingAccount{id: "public-alice", cache: map[string]string{"token": "abc123"}},
+		service:        testsupport.NewFakeClusterService(),
+	}
+	client := &Client{Cache: newCache(filename)}
+
+	endpoint, err := client.Login(account)
+	require.NoError(t, err)
+	assert.Equal(t, testsupport.FakeEndpoint, endpoint)
+
+	cache := newCache(filename)
+	require.NoError(t, cache.load())
+	assert.Equal(t, "abc123", cache.Accounts["public-alice"]["token"])
+}
+
+func TestLoginRejectedCredentials(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	service := testsupport.NewFakeClusterService()
+	service.AuthErr = common.AuthError{Err: errors.New("Unauthorized")}
+	account := &loginAccount{
+		cachingAccount: cachingAccount{id: "public-alice", cache: map[string]string{"token": "abc123"}},
+		service:        service,
+	}
+	client := &Client{Cache: newCache(filename)}
+
+	_, err := client.Login(account)
+	require.Error(t, err)
+	if assert.IsType(t, &UserError{}, err) {
+		assert.IsType(t, common.AuthError{}, err.(*UserError).Cause())
+	}
+
+	cache := newCache(filename)
+	require.NoError(t, cache.load())
+	assert.NotContains(t, cache.Accounts, "public-alice")
+}
+
+func TestLogoutRemovesAccount(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	account := &cachingAccount{id: "public-alice", cache: map[string]string{"token": "abc123", "endpoint": "https://example.com"}}
+	other := &cachingAccount{id: "public-bob", cache: map[string]string{"token": "def456"}}
+	client := &Client{Cache: newCache(filename)}
+	client.Cache.SaveAccount(account)
+	client.Cache.SaveAccount(other)
+
+	found, err := client.Logout(account)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = client.Logout(account)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	cache := newCache(filename)
+	require.NoError(t, cache.load())
+	assert.NotContains(t, cache.Accounts, "public-alice")
+	assert.Equal(t, "def456", cache.Accounts["public-bob"]["token"])
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/common"
+)
+
+// FleetMetrics is a snapshot of an account's clusters, exported as Prometheus metrics by carina metrics
+type FleetMetrics struct {
+	Account string
+
+	// Clusters are the clusters listed by the scrape, empty when it failed
+	Clusters []ClusterMetrics
+
+	// Scraped is when the clusters were listed, and Duration is how long it took
+	Scraped  time.Time
+	Duration time.Duration
+
+	// Err is why the clusters couldn't be listed
+	Err error
+}
+
+// ClusterMetrics is the status and size of a single cluster
+type ClusterMetrics struct {
+	Name     string
+	Template string
+	Status   string
+	Nodes    int
+}
+
+// GetFleetMetrics lists the account's clusters for carina metrics. Unlike ListClusters, the cached clusters are never used,
+// so that a failed scrape is reported instead of stale counts. The error is also recorded in the metrics.
+func (client *Client) GetFleetMetrics(account Account) (*FleetMetrics, error) {
+	defer client.Cache.SaveAccount(account)
+	metrics := &FleetMetrics{Account: account.GetID(), Scraped: time.Now()}
+
+	svc, err := client.buildContainerService(account)
+	if err == nil {
+		var clusters []common.Cluster
+		clusters, err = svc.ListClusters()
+		for _, cluster := range clusters {
+			metrics.Clusters = append(metrics.Clusters, newClusterMetrics(cluster))
+		}
+	}
+
+	metrics.Duration = time.Since(metrics.Scraped)
+	metrics.Err = wrapClientError(err)
+	return metrics, metrics.Err
+}
+
+func newClusterMetrics(cluster common.Cluster) ClusterMetrics {
+	metrics := ClusterMetrics{Name: cluster.GetName(), Status: cluster.GetStatus()}
+	if template := cluster.GetTemplate(); template != nil {
+		metrics.Template = template.GetName()
+	}
+	// Clusters which are still being created may not report their nodes yet
+	metrics.Nodes, _ = common.ParseNodeCount(cluster.GetNodes())
+	return metrics
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format
+func (metrics *FleetMetrics) WritePrometheus(w io.Writer) error {
+	account := formatPrometheusLabels("account", metrics.Account)
+
+	clustersByStatus := make(map[string]int)
+	nodesByStatus := make(map[string]int)
+	for _, cluster := range metrics.Clusters {
+		clustersByStatus[cluster.Status]++
+		nodesByStatus[cluster.Status] += cluster.Nodes
+	}
+
+	var buf bytes.Buffer
+	writePrometheusHeader(&buf, "carina_clusters", "Number of clusters by status")
+	for _, status := range sortedKeys(clustersByStatus) {
+		fmt.Fprintf(&buf, "carina_clusters%s %d\n", formatPrometheusLabels("account", metrics.Account, "status", status), clustersByStatus[status])
+	}
+
+	writePrometheusHeader(&buf, "carina_nodes", "Number of nodes in the clusters by status")
+	for _, status := range sortedKeys(nodesByStatus) {
+		fmt.Fprintf(&buf, "carina_nodes%s %d\n", formatPrometheusLabels("account", metrics.Account, "status", status), nodesByStatus[status])
+	}
+
+	writePrometheusHeader(&buf, "carina_cluster_nodes", "Number of nodes in each cluster")
+	for _, cluster := range metrics.Clusters {
+		labels := formatPrometheusLabels("account", metrics.Account, "cluster", cluster.Name, "template", cluster.Template, "status", cluster.Status)
+		fmt.Fprintf(&buf, "carina_cluster_nodes%s %d\n", labels, cluster.Nodes)
+	}
+
+	success := 1
+	if metrics.Err != nil {
+		success = 0
+	}
+	writePrometheusHeader(&buf, "carina_scrape_success", "Whether the clusters were listed by the last scrape")
+	fmt.Fprintf(&buf, "carina_scrape_success%s %d\n", account, success)
+	writePrometheusHeader(&buf, "carina_scrape_duration_seconds", "How long the last scrape took to list the clusters")
+	fmt.Fprintf(&buf, "carina_scrape_duration_seconds%s %g\n", account, metrics.Duration.Seconds())
+	writePrometheusHeader(&buf, "carina_scrape_timestamp_seconds", "When the last scrape listed the clusters, in seconds since the epoch")
+	fmt.Fprintf(&buf, "carina_scrape_timestamp_seconds%s %d\n", account, metrics.Scraped.Unix())
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// writePrometheusHeader describes a gauge
+func writePrometheusHeader(buf *bytes.Buffer, name string, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+}
+
+// prometheusLabelEscaper escapes a label value, see https://prometheus.io/docs/instrumenting/exposition_formats/
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatPrometheusLabels formats name/value pairs as labels, e.g. {account="public-alice",status="active"}
+func formatPrometheusLabels(pairs ...string) string {
+	labels := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, fmt.Sprintf(`%s="%s"`, pairs[i], prometheusLabelEscaper.Replace(pairs[i+1])))
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+func sortedKeys(values map[string]int) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
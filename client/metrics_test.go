@@ -0,0 +1,48 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFleetMetrics(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+	client := &Client{Cache: &Cache{}}
+
+	_, err := service.CreateCluster("web", "Kubernetes 1.5.2 on LXC", 3)
+	require.NoError(t, err)
+	_, err = service.CreateCluster("api", "Kubernetes 1.5.2 on LXC", 2)
+	require.NoError(t, err)
+
+	metrics, err := client.GetFleetMetrics(account)
+	require.NoError(t, err)
+	require.Len(t, metrics.Clusters, 2)
+	status := metrics.Clusters[0].Status
+
+	var buf bytes.Buffer
+	require.NoError(t, metrics.WritePrometheus(&buf))
+	output := buf.String()
+	assert.Contains(t, output, "# TYPE carina_clusters gauge\n")
+	assert.Contains(t, output, `carina_clusters{account="stub-user",status="`+status+`"} 2`+"\n")
+	assert.Contains(t, output, `carina_nodes{account="stub-user",status="`+status+`"} 5`+"\n")
+	assert.Contains(t, output, `carina_cluster_nodes{account="stub-user",cluster="web",template="Kubernetes 1.5.2 on LXC",status="`+status+`"} 3`+"\n")
+	assert.Contains(t, output, `carina_scrape_success{account="stub-user"} 1`+"\n")
+
+	account = &historyAccount{offlineAccount{service: &unreachableClusterService{}}}
+	metrics, err = client.GetFleetMetrics(account)
+	assert.Error(t, err)
+
+	buf.Reset()
+	require.NoError(t, metrics.WritePrometheus(&buf))
+	assert.Contains(t, buf.String(), `carina_scrape_success{account="stub-user"} 0`+"\n")
+	assert.NotContains(t, buf.String(), "carina_cluster_nodes{")
+}
+
+func TestFormatPrometheusLabels(t *testing.T) {
+	assert.Equal(t, `{cluster="say \"hi\"\\n",status="active"}`, formatPrometheusLabels("cluster", `say "hi"\n`, "status", "active"))
+}
@@ -0,0 +1,305 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// currentClusterPrefix matches the credentials directories created by this client, e.g. public-dfw-alice or makeswarm-alice,
+// see Account.GetClusterPrefix
+var currentClusterPrefix = regexp.MustCompile(`^(public|private|makeswarm)-`)
+
+// legacyCache is the cache.json written by the original carina client, which only supported make-swarm
+// and cached a token per username
+type legacyCache struct {
+	Tokens map[string]string `json:"tokens"`
+}
+
+// LegacyCredentials is a credentials bundle stored by the original carina client under CARINA_HOME/clusters/[username]/[cluster]
+type LegacyCredentials struct {
+	UserName    string
+	ClusterName string
+	Path        string
+
+	// Destination is where the credentials are moved to, mirroring the make-swarm account's cluster prefix
+	Destination string
+
+	// Exists indicates that credentials are already stored at the destination, and the legacy bundle is left alone
+	Exists bool
+}
+
+// HomeMigration describes the legacy data found in CARINA_HOME, and how it is imported into the current layout
+type HomeMigration struct {
+	Home string
+
+	// Tokens are the usernames with a legacy cached token
+	Tokens []string
+
+	Credentials []LegacyCredentials
+
+	// BackupPath is where the legacy data was copied before it was migrated
+	BackupPath string
+
+	tokens map[string]string
+}
+
+// IsEmpty returns if there is nothing to migrate
+func (migration *HomeMigration) IsEmpty() bool {
+	return len(migration.Tokens) == 0 && len(migration.Credentials) == 0
+}
+
+// OperationPlan describes the migration, for --dry-run
+func (migration *HomeMigration) OperationPlan() *OperationPlan {
+	plan := &OperationPlan{Operation: "migrate-home"}
+	plan.addStep("back up the legacy files to %s", filepath.Join(migration.Home, "backup-[timestamp]"))
+	for _, username := range migration.Tokens {
+		plan.addStep("import the cached token for %s", username)
+	}
+	for _, legacy := range migration.Credentials {
+		if legacy.Exists {
+			plan.addWarning("Skipping %s because credentials already exist in %s", legacy.Path, legacy.Destination)
+			continue
+		}
+		plan.addStep("move the credentials for %s from %s to %s", legacy.ClusterName, legacy.Path, legacy.Destination)
+	}
+	return plan
+}
+
+// DetectLegacyHome looks for tokens and credentials in CARINA_HOME which were stored by the original carina client
+func (client *Client) DetectLegacyHome() (*HomeMigration, error) {
+	home, err := GetCredentialsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	migration := &HomeMigration{Home: home}
+
+	migration.tokens, err = client.readLegacyTokens(filepath.Join(home, "cache.json"))
+	if err != nil {
+		return nil, err
+	}
+	for username := range migration.tokens {
+		migration.Tokens = append(migration.Tokens, username)
+	}
+	sort.Strings(migration.Tokens)
+
+	clustersDir := filepath.Join(home, clusterDirName)
+	userDirs, err := ioutil.ReadDir(clustersDir)
+	if os.IsNotExist(err) {
+		return migration, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read the credentials in CARINA_HOME")
+	}
+
+	for _, userDir := range userDirs {
+		if !userDir.IsDir() || currentClusterPrefix.MatchString(userDir.Name()) {
+			continue
+		}
+
+		clusterDirs, err := ioutil.ReadDir(filepath.Join(clustersDir, userDir.Name()))
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to read the credentials in CARINA_HOME")
+		}
+
+		for _, clusterDir := range clusterDirs {
+			credentialsPath := filepath.Join(clustersDir, userDir.Name(), clusterDir.Name())
+			if !clusterDir.IsDir() {
+				continue
+			}
+			if _, err := getCredentialScriptPrefix(credentialsPath); err != nil {
+				common.Log.WriteDebug("Skipping %s because it is not a credentials bundle: %s", credentialsPath, err)
+				continue
+			}
+
+			legacy := LegacyCredentials{
+				UserName:    userDir.Name(),
+				ClusterName: clusterDir.Name(),
+				Path:        credentialsPath,
+				Destination: filepath.Join(clustersDir, "makeswarm-"+userDir.Name(), clusterDir.Name()),
+			}
+			_, err = os.Stat(legacy.Destination)
+			legacy.Exists = err == nil
+			migration.Credentials = append(migration.Credentials, legacy)
+		}
+	}
+
+	return migration, nil
+}
+
+// readLegacyTokens reads the tokens cached by the original carina client
+func (client *Client) readLegacyTokens(cachePath string) (map[string]string, error) {
+	contents, err := ioutil.ReadFile(cachePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read the cache")
+	}
+
+	contents, err = decryptContents(client.cipher, contents)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decrypt the cache")
+	}
+
+	var legacy legacyCache
+	err = json.Unmarshal(contents, &legacy)
+	if err != nil {
+		common.Log.WriteDebug("Unable to read legacy tokens from %s: %s", cachePath, err)
+		return nil, nil
+	}
+
+	for username, token := range legacy.Tokens {
+		if token == "" {
+			delete(legacy.Tokens, username)
+		}
+	}
+	return legacy.Tokens, nil
+}
+
+// MigrateHome imports the tokens and credentials stored by the original carina client into the current layout.
+// The legacy data is first copied to a backup directory in CARINA_HOME. Credentials which already exist in the
+// current layout are not overwritten.
+func (client *Client) MigrateHome(migration *HomeMigration) error {
+	if migration.IsEmpty() {
+		return nil
+	}
+
+	migration.BackupPath = filepath.Join(migration.Home, "backup-"+time.Now().Format("20060102150405"))
+	err := migration.backup()
+	if err != nil {
+		return err
+	}
+
+	for _, legacy := range migration.Credentials {
+		if legacy.Exists {
+			common.Log.WriteWarning("Skipping %s because credentials already exist in %s", legacy.Path, legacy.Destination)
+			continue
+		}
+
+		err = client.moveLegacyCredentials(legacy)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(migration.tokens) > 0 && !client.Cache.isNil() {
+		// Rewriting the cache drops the legacy tokens, now that they are stored per account
+		err = client.Cache.safeUpdate(func(c *Cache) {
+			for username, token := range migration.tokens {
+				id := "public-" + username
+				if c.Accounts[id] == nil {
+					c.Accounts[id] = make(cacheItem)
+				}
+				if c.Accounts[id][cachedTokenKey] == "" {
+					c.Accounts[id][cachedTokenKey] = token
+				}
+			}
+		})
+		if err != nil {
+			return errors.Wrap(err, "Unable to import the legacy tokens")
+		}
+	}
+
+	return nil
+}
+
+// backup copies the legacy cache and credentials, preserving their layout relative to CARINA_HOME
+func (migration *HomeMigration) backup() error {
+	var paths []string
+	if len(migration.Tokens) > 0 {
+		paths = append(paths, filepath.Join(migration.Home, "cache.json"))
+	}
+	for _, legacy := range migration.Credentials {
+		paths = append(paths, legacy.Path)
+	}
+
+	for _, path := range paths {
+		relPath, err := filepath.Rel(migration.Home, path)
+		if err != nil {
+			return errors.Wrap(err, "Unable to back up CARINA_HOME")
+		}
+
+		err = copyPath(path, filepath.Join(migration.BackupPath, relPath))
+		if err != nil {
+			return errors.Wrapf(err, "Unable to back up %s", path)
+		}
+	}
+
+	restrictAccess(migration.BackupPath)
+	return nil
+}
+
+// moveLegacyCredentials moves a legacy credentials bundle to its current location, encrypting it when encryption is enabled
+func (client *Client) moveLegacyCredentials(legacy LegacyCredentials) error {
+	files, err := client.readCredentialsFiles(legacy.Path)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to read the credentials in %s", legacy.Path)
+	}
+
+	err = os.MkdirAll(legacy.Destination, 0700)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to create %s", legacy.Destination)
+	}
+	restrictAccess(legacy.Destination)
+
+	for file, contents := range files {
+		err = client.writeSecureFile(filepath.Join(legacy.Destination, file), contents, "")
+		if err != nil {
+			return errors.Wrapf(err, "Unable to migrate the credentials for %s", legacy.ClusterName)
+		}
+	}
+
+	err = os.RemoveAll(legacy.Path)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to remove %s", legacy.Path)
+	}
+
+	// Clean up the legacy username directory once all of its clusters have been moved
+	os.Remove(filepath.Dir(legacy.Path))
+	return nil
+}
+
+// copyPath copies a file, or a directory and its files
+func copyPath(src string, dest string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		err = os.MkdirAll(filepath.Dir(dest), 0700)
+		if err != nil {
+			return err
+		}
+		contents, err := ioutil.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(dest, contents, 0600)
+	}
+
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dest, 0700)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err = copyPath(filepath.Join(src, entry.Name()), filepath.Join(dest, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
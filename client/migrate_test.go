@@ -0,0 +1,67 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateHome(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv(CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(CarinaHomeDirEnvVar)
+
+	writeFile := func(path string, contents string) {
+		os.MkdirAll(filepath.Dir(path), 0700)
+		ioutil.WriteFile(path, []byte(contents), 0600)
+	}
+	writeFile(filepath.Join(home, "cache.json"), `{"last-check": "2016-06-01T00:00:00Z", "tokens": {"alice": "legacy-token"}}`)
+	writeFile(filepath.Join(home, "clusters", "alice", "dev", "docker.env"), "export DOCKER_HOST=tcp://172.99.65.1:2376")
+	writeFile(filepath.Join(home, "clusters", "alice", "dev", "ca.pem"), "ca")
+	writeFile(filepath.Join(home, "clusters", "alice", "prod", "docker.env"), "export DOCKER_HOST=tcp://172.99.65.2:2376")
+	writeFile(filepath.Join(home, "clusters", "makeswarm-alice", "prod", "docker.env"), "export DOCKER_HOST=tcp://172.99.65.3:2376")
+	writeFile(filepath.Join(home, "clusters", "public-dfw-alice", "k8s", "kubectl.env"), "export KUBECONFIG=kubeconfig")
+
+	c := NewClient(true)
+	migration, err := c.DetectLegacyHome()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alice"}, migration.Tokens)
+	if assert.Len(t, migration.Credentials, 2) {
+		assert.Equal(t, "dev", migration.Credentials[0].ClusterName)
+		assert.False(t, migration.Credentials[0].Exists)
+		assert.Equal(t, "prod", migration.Credentials[1].ClusterName)
+		assert.True(t, migration.Credentials[1].Exists, "The prod credentials were already downloaded to the current layout")
+	}
+
+	err = c.MigrateHome(migration)
+	assert.Nil(t, err)
+
+	contents, err := ioutil.ReadFile(filepath.Join(home, "clusters", "makeswarm-alice", "dev", "ca.pem"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ca", string(contents))
+	_, err = os.Stat(filepath.Join(home, "clusters", "alice", "dev"))
+	assert.True(t, os.IsNotExist(err), "The legacy credentials should be moved")
+	_, err = os.Stat(filepath.Join(migration.BackupPath, "clusters", "alice", "dev", "ca.pem"))
+	assert.Nil(t, err, "The legacy credentials should be backed up")
+	_, err = os.Stat(filepath.Join(migration.BackupPath, "cache.json"))
+	assert.Nil(t, err, "The legacy cache should be backed up")
+
+	contents, _ = ioutil.ReadFile(filepath.Join(home, "clusters", "makeswarm-alice", "prod", "docker.env"))
+	assert.Contains(t, string(contents), "172.99.65.3", "Existing credentials should not be overwritten")
+
+	c = NewClient(true)
+	c.Cache.ensureLoaded()
+	assert.Equal(t, "legacy-token", c.Cache.Accounts["public-alice"][cachedTokenKey])
+
+	migration, err = c.DetectLegacyHome()
+	assert.Nil(t, err)
+	assert.Empty(t, migration.Tokens, "The legacy tokens should be removed from the cache")
+	assert.Len(t, migration.Credentials, 1, "Only the skipped credentials should remain")
+}
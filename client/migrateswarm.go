@@ -0,0 +1,142 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// MigrateSwarmClusterOptions are the settings for moving a make-swarm cluster to make-coe
+type MigrateSwarmClusterOptions struct {
+	// Name is the name of the make-coe cluster, defaulting to the name of the make-swarm cluster
+	Name string
+
+	// Template is the make-coe template, defaulting to the Swarm template offered by make-coe
+	Template string
+
+	// CredentialsPath is where the credentials of the new cluster are saved, defaulting to CARINA_HOME
+	CredentialsPath string
+}
+
+// MigrationStep is an item on the checklist printed after a migration
+type MigrationStep struct {
+	Description string
+
+	// Done is false when the step was skipped, or must be completed by the user
+	Done bool
+}
+
+// SwarmMigration is the result of moving a make-swarm cluster to make-coe
+type SwarmMigration struct {
+	Source          common.Cluster
+	Cluster         common.Cluster
+	CredentialsPath string
+	Steps           []MigrationStep
+}
+
+func (migration *SwarmMigration) done(format string, a ...interface{}) {
+	migration.Steps = append(migration.Steps, MigrationStep{Description: fmt.Sprintf(format, a...), Done: true})
+}
+
+func (migration *SwarmMigration) todo(format string, a ...interface{}) {
+	migration.Steps = append(migration.Steps, MigrationStep{Description: fmt.Sprintf(format, a...)})
+}
+
+// MigrateSwarmCluster creates a make-coe cluster equivalent to an existing make-swarm cluster, with the same number of nodes,
+// labels and autoscaling, waits for it to become active and downloads its credentials.
+// The make-swarm cluster isn't modified, so that the workloads can be moved before it is deleted.
+func (client *Client) MigrateSwarmCluster(source Account, target Account, token string, options MigrateSwarmClusterOptions) (*SwarmMigration, error) {
+	sourceCluster, err := client.GetCluster(source, token, false)
+	if err != nil {
+		return nil, err
+	}
+	migration := &SwarmMigration{Source: sourceCluster}
+
+	nodes, err := common.ParseNodeCount(sourceCluster.GetNodes())
+	if err != nil {
+		return nil, errors.Errorf("Unable to read the number of nodes in %s: %s", sourceCluster.GetName(), sourceCluster.GetNodes())
+	}
+
+	name := options.Name
+	if name == "" {
+		name = sourceCluster.GetName()
+	}
+
+	template := options.Template
+	if template == "" {
+		swarmTemplate, err := client.findSwarmTemplate(target)
+		if err != nil {
+			return nil, err
+		}
+		template = swarmTemplate.GetName()
+	}
+
+	createOptions := CreateClusterOptions{Labels: sourceCluster.GetLabels(), WaitUntilActive: true}
+	migration.Cluster, err = client.CreateCluster(target, name, template, nodes, createOptions)
+	if err != nil {
+		return nil, err
+	}
+	migration.done("Created %s from the %s template", name, template)
+	migration.done("Sized %s to %d nodes, the same as %s", name, nodes, sourceCluster.GetName())
+	if len(createOptions.Labels) > 0 {
+		migration.done("Copied %d labels", len(createOptions.Labels))
+	}
+
+	if autoscale := sourceCluster.GetAutoScale(); autoscale != nil && autoscale.Enabled {
+		cluster, err := client.SetAutoScale(target, name, *autoscale)
+		if err != nil {
+			common.Log.WriteDebug("Unable to enable autoscaling on %s: %s", name, err)
+			migration.todo("Enable autoscaling, it couldn't be enabled on the new cluster: %s", err)
+		} else {
+			migration.Cluster = cluster
+			migration.done("Enabled autoscaling")
+		}
+	}
+
+	migration.CredentialsPath, err = client.downloadLocalClusterCredentials(target, name, options.CredentialsPath)
+	if err != nil {
+		return migration, err
+	}
+	migration.done("Downloaded the credentials to %s", migration.CredentialsPath)
+
+	migration.todo("Redeploy the workloads from %s to %s, e.g. with docker-compose or docker stack deploy", sourceCluster.GetName(), name)
+	migration.todo("Delete %s with carina delete --cloud make-swarm %s once it is no longer used", sourceCluster.GetName(), sourceCluster.GetName())
+	return migration, nil
+}
+
+// findSwarmTemplate selects the active Swarm template offered by make-coe
+func (client *Client) findSwarmTemplate(account Account) (common.ClusterTemplate, error) {
+	selector := common.TemplateSelector{COE: "swarm"}
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := svc.(common.TemplateResolver); ok {
+		return client.ResolveTemplate(account, selector)
+	}
+
+	templates, err := client.ListClusterTemplates(account, ListTemplatesOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []common.ClusterTemplate
+	for _, template := range templates {
+		if !template.IsDeprecated() && selector.Matches(template) {
+			matches = append(matches, template)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, common.NotFoundError{Err: errors.New("Could not find a Swarm template. Run carina templates to see the available templates, and specify one with --template")}
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, match := range matches {
+			names[i] = match.GetName()
+		}
+		return nil, &common.MultipleMatchingTemplatesError{TemplatePattern: selector.String(), MatchingTemplates: names}
+	}
+}
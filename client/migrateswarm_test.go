@@ -0,0 +1,53 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateSwarmCluster(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-migrate")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	os.Setenv(CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(CarinaHomeDirEnvVar)
+
+	client := &Client{Cache: newCache(filepath.Join(home, "cache.json"))}
+	swarmService := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "make-swarm"})
+	swarmService.AddCluster(testsupport.FakeCluster{Name: "web", Nodes: 3, AutoScale: &common.AutoScale{Enabled: true}})
+	source := &historyAccount{offlineAccount{service: swarmService}}
+
+	coeService := testsupport.NewFakeClusterService(
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes"},
+		&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC", COE: "swarm", Deprecated: true},
+		&testsupport.FakeClusterTemplate{Name: "Swarm 1.12.3 on LXC", COE: "swarm", SupportsAutoScale: true})
+	target := &historyAccount{offlineAccount{service: coeService}}
+
+	migration, err := client.MigrateSwarmCluster(source, target, "web", MigrateSwarmClusterOptions{Name: "web-coe"})
+	require.NoError(t, err)
+	assert.Equal(t, "web-coe", migration.Cluster.GetName())
+	assert.Equal(t, "Swarm 1.12.3 on LXC", migration.Cluster.GetTemplate().GetName())
+	assert.Equal(t, "3", migration.Cluster.GetNodes())
+	assert.True(t, migration.Cluster.GetAutoScale().Enabled)
+
+	_, err = os.Stat(filepath.Join(migration.CredentialsPath, "ca.pem"))
+	assert.NoError(t, err, "the credentials should be downloaded")
+
+	var todo []string
+	for _, step := range migration.Steps {
+		if !step.Done {
+			todo = append(todo, step.Description)
+		}
+	}
+	assert.Len(t, todo, 2, "only redeploying the workloads and deleting the old cluster should be left")
+
+	_, err = swarmService.GetCluster("web")
+	assert.NoError(t, err, "the make-swarm cluster should not be deleted")
+}
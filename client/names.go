@@ -0,0 +1,60 @@
+package client
+
+import (
+	mathrand "math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// generatedNameAlphabet avoids vowels, so that generated names don't spell words, and characters which are easily confused, such as 0 and o
+const generatedNameAlphabet = "bcdfghjklmnpqrstvwxz2456789"
+
+// generatedNameLength is the number of random characters appended to the prefix
+const generatedNameLength = 5
+
+// maxGenerateNameAttempts is how many names are tried before giving up, when the generated names are already in use
+const maxGenerateNameAttempts = 10
+
+// NameGenerator appends a random suffix to a prefix, like the Kubernetes generateName. The same seed always generates the same names.
+type NameGenerator struct {
+	random *mathrand.Rand
+}
+
+// NewNameGenerator creates a name generator. A seed of 0 uses a random seed.
+func NewNameGenerator(seed int64) *NameGenerator {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &NameGenerator{random: mathrand.New(mathrand.NewSource(seed))}
+}
+
+// Generate returns the prefix followed by a random suffix, e.g. ci-x7bqk
+func (generator *NameGenerator) Generate(prefix string) string {
+	suffix := make([]byte, generatedNameLength)
+	for i := range suffix {
+		suffix[i] = generatedNameAlphabet[generator.random.Intn(len(generatedNameAlphabet))]
+	}
+	return prefix + string(suffix)
+}
+
+// GenerateClusterName generates a name for a new cluster which is not used by any of the account's clusters
+func (client *Client) GenerateClusterName(account Account, prefix string, generator *NameGenerator) (string, error) {
+	clusters, err := client.ListClusters(account, ListClustersOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	used := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		used[cluster.GetName()] = true
+	}
+
+	for i := 0; i < maxGenerateNameAttempts; i++ {
+		name := generator.Generate(prefix)
+		if !used[name] {
+			return name, nil
+		}
+	}
+	return "", errors.Errorf("Unable to generate an unused cluster name starting with %s", prefix)
+}
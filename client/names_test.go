@@ -0,0 +1,36 @@
+package client_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameGeneratorIsSeedable(t *testing.T) {
+	first := client.NewNameGenerator(42).Generate("ci-")
+	second := client.NewNameGenerator(42).Generate("ci-")
+	assert.Equal(t, first, second)
+	assert.True(t, strings.HasPrefix(first, "ci-"))
+	assert.Len(t, first, len("ci-")+5)
+
+	assert.NotEqual(t, first, client.NewNameGenerator(43).Generate("ci-"))
+}
+
+func TestGenerateClusterNameSkipsUsedNames(t *testing.T) {
+	taken := client.NewNameGenerator(42).Generate("ci-")
+
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster(taken, "Swarm*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	name, err := c.GenerateClusterName(account, "ci-", client.NewNameGenerator(42))
+	assert.Nil(t, err)
+	assert.NotEqual(t, taken, name)
+	assert.True(t, strings.HasPrefix(name, "ci-"))
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// NotificationPolicy posts a notification to a webhook when an operation which was waited on finishes or fails,
+// so that a long cluster build started from a laptop can notify a team channel
+var NotificationPolicy = struct {
+	// WebhookURL receives each Notification as JSON, e.g. a Slack incoming webhook. Notifications are disabled when empty.
+	WebhookURL string
+
+	// MinDuration skips notifying about operations which finished sooner, e.g. 5m
+	MinDuration time.Duration
+}{}
+
+// Notification is the JSON payload posted to the webhook. Text is a summary which chat services such as Slack display.
+type Notification struct {
+	Text      string `json:"text"`
+	Account   string `json:"account"`
+	Host      string `json:"host"`
+	User      string `json:"user"`
+	Operation string `json:"operation"`
+	Cluster   string `json:"cluster"`
+	ClusterID string `json:"cluster-id,omitempty"`
+	Status    string `json:"status"`
+
+	// Duration is how long the operation took, in seconds
+	Duration float64 `json:"duration"`
+
+	// Result is success or failure, and Error explains why the operation failed
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// notifyWaitFinished posts the outcome of an operation which was waited on to the webhook. Notifying never fails the operation,
+// a warning is printed instead.
+func (client *Client) notifyWaitFinished(account Account, operation string, name string, started time.Time, cluster common.Cluster, err error) {
+	if NotificationPolicy.WebhookURL == "" {
+		return
+	}
+
+	duration := time.Since(started)
+	if duration < NotificationPolicy.MinDuration {
+		common.Log.WriteDebug("Skipping the notification, %s of cluster (%s) finished in %s", operation, name, duration)
+		return
+	}
+
+	notifyErr := postNotification(NotificationPolicy.WebhookURL, buildNotification(account, operation, name, duration, cluster, err))
+	if notifyErr != nil {
+		common.Log.WriteWarning("WARNING: Unable to send the notification for %s of cluster (%s): %s", operation, name, notifyErr)
+	}
+}
+
+// buildNotification describes the outcome of an operation
+func buildNotification(account Account, operation string, name string, duration time.Duration, cluster common.Cluster, err error) Notification {
+	duration = duration.Round(time.Second)
+	notification := Notification{
+		Account:   account.GetID(),
+		Host:      auditHost(),
+		User:      auditUser(),
+		Operation: operation,
+		Cluster:   name,
+		Status:    "unknown",
+		Duration:  duration.Seconds(),
+		Result:    AuditSuccess,
+	}
+	if cluster != nil {
+		notification.ClusterID = cluster.GetID()
+		notification.Status = cluster.GetStatus()
+	}
+	if err == nil && operation == "delete" {
+		notification.Status = "deleted"
+	}
+
+	if err != nil {
+		notification.Result = AuditFailure
+		notification.Error = err.Error()
+		notification.Text = fmt.Sprintf("carina %s of cluster %s failed after %s: %s", operation, name, duration, err)
+	} else {
+		notification.Text = fmt.Sprintf("carina %s of cluster %s finished in %s, the cluster is %s", operation, name, duration, notification.Status)
+	}
+	return notification
+}
+
+// postNotification posts a notification to a webhook
+func postNotification(webhookURL string, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return errors.Wrap(err, "Unable to serialize the notification")
+	}
+
+	resp, err := common.NewHTTPClient().Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "Unable to post to the webhook")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("The webhook responded with %s", resp.Status)
+	}
+	return nil
+}
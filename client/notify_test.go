@@ -0,0 +1,70 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyWaitFinished(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-notify")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	os.Setenv(CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(CarinaHomeDirEnvVar)
+
+	var notifications []Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification Notification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		notifications = append(notifications, notification)
+	}))
+	defer server.Close()
+
+	NotificationPolicy.WebhookURL = server.URL
+	defer func() { NotificationPolicy.WebhookURL = "" }()
+
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	service.PendingPolls = 1
+	account := &historyAccount{offlineAccount{service: service}}
+	client := &Client{Cache: &Cache{}}
+
+	_, err = client.CreateCluster(account, "web", "Kubernetes 1.5.2 on LXC", 1, CreateClusterOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, notifications, "Only operations which were waited on should notify")
+
+	cluster, err := client.CreateCluster(account, "api", "Kubernetes 1.5.2 on LXC", 1, CreateClusterOptions{WaitUntilActive: true})
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	notification := notifications[0]
+	assert.Equal(t, "create", notification.Operation)
+	assert.Equal(t, "api", notification.Cluster)
+	assert.Equal(t, cluster.GetID(), notification.ClusterID)
+	assert.Equal(t, cluster.GetStatus(), notification.Status)
+	assert.Equal(t, AuditSuccess, notification.Result)
+	assert.Contains(t, notification.Text, "carina create of cluster api finished")
+
+	NotificationPolicy.MinDuration = time.Hour
+	defer func() { NotificationPolicy.MinDuration = 0 }()
+	require.NoError(t, client.DeleteCluster(account, "api", true))
+	assert.Len(t, notifications, 1, "Operations which finished sooner than the minimum duration should not notify")
+}
+
+func TestBuildNotificationFailure(t *testing.T) {
+	account := &historyAccount{}
+	notification := buildNotification(account, "resize", "web", 90*time.Second, nil, errors.New("quota exceeded"))
+	assert.Equal(t, AuditFailure, notification.Result)
+	assert.Equal(t, "unknown", notification.Status)
+	assert.Equal(t, float64(90), notification.Duration)
+	assert.Equal(t, "quota exceeded", notification.Error)
+	assert.Equal(t, "carina resize of cluster web failed after 1m30s: quota exceeded", notification.Text)
+}
@@ -0,0 +1,312 @@
+package client
+
+import (
+	"net"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// cachedListing is the last successful cluster and template listing for an account, used when the API is unreachable
+type cachedListing struct {
+	Clusters         []cachedCluster  `json:"clusters,omitempty"`
+	ClustersUpdated  time.Time        `json:"clusters-updated,omitempty"`
+	Templates        []cachedTemplate `json:"templates,omitempty"`
+	TemplatesUpdated time.Time        `json:"templates-updated,omitempty"`
+}
+
+// cachedCluster is a snapshot of a cluster, which can be serialized to the cache
+type cachedCluster struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Template      cachedTemplate    `json:"template"`
+	Flavor        string            `json:"flavor,omitempty"`
+	Nodes         string            `json:"nodes"`
+	Status        string            `json:"status"`
+	StatusDetails string            `json:"status-details,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+
+	AutoScale *common.AutoScale `json:"autoscale,omitempty"`
+
+	Created time.Time `json:"created,omitempty"`
+	Updated time.Time `json:"updated,omitempty"`
+
+	NodeSize *common.NodeSize `json:"node-size,omitempty"`
+}
+
+func newCachedCluster(cluster common.Cluster) cachedCluster {
+	return cachedCluster{
+		ID:            cluster.GetID(),
+		Name:          cluster.GetName(),
+		Template:      newCachedTemplate(cluster.GetTemplate()),
+		Flavor:        cluster.GetFlavor(),
+		Nodes:         cluster.GetNodes(),
+		Status:        cluster.GetStatus(),
+		StatusDetails: cluster.GetStatusDetails(),
+		Labels:        cluster.GetLabels(),
+		AutoScale:     cluster.GetAutoScale(),
+		Created:       cluster.GetCreated(),
+		Updated:       cluster.GetUpdated(),
+		NodeSize:      cluster.GetNodeSize(),
+	}
+}
+
+// GetID returns the cluster identifier
+func (cluster cachedCluster) GetID() string {
+	return cluster.ID
+}
+
+// GetName returns the cluster name
+func (cluster cachedCluster) GetName() string {
+	return cluster.Name
+}
+
+// GetTemplate returns the template used to create the cluster
+func (cluster cachedCluster) GetTemplate() common.ClusterTemplate {
+	return cluster.Template
+}
+
+// GetFlavor returns the flavor of the nodes in the cluster
+func (cluster cachedCluster) GetFlavor() string {
+	return cluster.Flavor
+}
+
+// GetNodes returns the number of nodes in the cluster
+func (cluster cachedCluster) GetNodes() string {
+	return cluster.Nodes
+}
+
+// GetStatus returns the status of the cluster when it was cached
+func (cluster cachedCluster) GetStatus() string {
+	return cluster.Status
+}
+
+// GetStatusDetails returns additional information about the cluster's status
+func (cluster cachedCluster) GetStatusDetails() string {
+	return cluster.StatusDetails
+}
+
+// GetLabels returns the key/value pairs used to organize the cluster
+func (cluster cachedCluster) GetLabels() map[string]string {
+	return cluster.Labels
+}
+
+// GetAutoScale returns the cluster's autoscaling settings when it was cached
+func (cluster cachedCluster) GetAutoScale() *common.AutoScale {
+	return cluster.AutoScale
+}
+
+// GetCreated returns when the cluster was created, when it was known when the cluster was cached
+func (cluster cachedCluster) GetCreated() time.Time {
+	return cluster.Created
+}
+
+// GetUpdated returns when the cluster was last changed, when it was known when the cluster was cached
+func (cluster cachedCluster) GetUpdated() time.Time {
+	return cluster.Updated
+}
+
+// GetNodeSize returns the resources of each node in the cluster when it was cached
+func (cluster cachedCluster) GetNodeSize() *common.NodeSize {
+	return cluster.NodeSize
+}
+
+// cachedTemplate is a snapshot of a cluster template, which can be serialized to the cache
+type cachedTemplate struct {
+	Name       string            `json:"name"`
+	COE        string            `json:"coe"`
+	HostType   string            `json:"host-type"`
+	Deprecated bool              `json:"deprecated,omitempty"`
+	COEVersion string            `json:"coe-version,omitempty"`
+	NodeFlavor string            `json:"node-flavor,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+
+	Availability *common.TemplateAvailability `json:"availability,omitempty"`
+	Capabilities *common.TemplateCapabilities `json:"capabilities,omitempty"`
+}
+
+func newCachedTemplate(template common.ClusterTemplate) cachedTemplate {
+	if template == nil {
+		return cachedTemplate{}
+	}
+
+	return cachedTemplate{
+		Name:       template.GetName(),
+		COE:        template.GetCOE(),
+		HostType:   template.GetHostType(),
+		Deprecated: template.IsDeprecated(),
+		COEVersion: template.GetCOEVersion(),
+		NodeFlavor: template.GetNodeFlavor(),
+		Details:    template.GetDetails(),
+
+		Availability: template.GetAvailability(),
+		Capabilities: template.GetCapabilities(),
+	}
+}
+
+// GetName returns the unique template name
+func (template cachedTemplate) GetName() string {
+	return template.Name
+}
+
+// GetCOE returns the container orchestration engine used by the cluster
+func (template cachedTemplate) GetCOE() string {
+	return template.COE
+}
+
+// GetHostType returns the underlying type of the host nodes, such as lxc or vm
+func (template cachedTemplate) GetHostType() string {
+	return template.HostType
+}
+
+// IsDeprecated returns if the template is deprecated and should not be used for new clusters
+func (template cachedTemplate) IsDeprecated() bool {
+	return template.Deprecated
+}
+
+// GetCOEVersion returns the version of the container orchestration engine
+func (template cachedTemplate) GetCOEVersion() string {
+	return template.COEVersion
+}
+
+// GetNodeFlavor returns the flavor (size) of the host nodes
+func (template cachedTemplate) GetNodeFlavor() string {
+	return template.NodeFlavor
+}
+
+// GetDetails returns additional backend-specific settings
+func (template cachedTemplate) GetDetails() map[string]string {
+	return template.Details
+}
+
+// GetAvailability returns the capacity hint reported when the template was cached
+func (template cachedTemplate) GetAvailability() *common.TemplateAvailability {
+	return template.Availability
+}
+
+// GetCapabilities returns what clusters created with the template supported when it was cached
+func (template cachedTemplate) GetCapabilities() *common.TemplateCapabilities {
+	return template.Capabilities
+}
+
+// getCachedClusters returns the clusters from the last successful listing for an account, and when they were cached
+func (cache *Cache) getCachedClusters(account Account) ([]common.Cluster, time.Time, bool) {
+	cache.ensureLoaded()
+	listing, ok := cache.Listings[account.GetID()]
+	if !ok || listing.ClustersUpdated.IsZero() {
+		return nil, time.Time{}, false
+	}
+
+	clusters := make([]common.Cluster, len(listing.Clusters))
+	for i, cluster := range listing.Clusters {
+		clusters[i] = cluster
+	}
+	return clusters, listing.ClustersUpdated, true
+}
+
+// saveCachedClusters caches a successful cluster listing for an account
+func (cache *Cache) saveCachedClusters(account Account, clusters []common.Cluster) error {
+	return cache.safeUpdate(func(c *Cache) {
+		if c.Listings == nil {
+			c.Listings = make(map[string]cachedListing)
+		}
+
+		listing := c.Listings[account.GetID()]
+		listing.Clusters = make([]cachedCluster, len(clusters))
+		for i, cluster := range clusters {
+			listing.Clusters[i] = newCachedCluster(cluster)
+		}
+		listing.ClustersUpdated = time.Now()
+		c.Listings[account.GetID()] = listing
+	})
+}
+
+// getCachedTemplates returns the templates from the last successful listing for an account, and when they were cached
+func (cache *Cache) getCachedTemplates(account Account) ([]common.ClusterTemplate, time.Time, bool) {
+	cache.ensureLoaded()
+	listing, ok := cache.Listings[account.GetID()]
+	if !ok || listing.TemplatesUpdated.IsZero() {
+		return nil, time.Time{}, false
+	}
+
+	templates := make([]common.ClusterTemplate, len(listing.Templates))
+	for i, template := range listing.Templates {
+		templates[i] = template
+	}
+	return templates, listing.TemplatesUpdated, true
+}
+
+// saveCachedTemplates caches a successful template listing for an account
+func (cache *Cache) saveCachedTemplates(account Account, templates []common.ClusterTemplate) error {
+	return cache.safeUpdate(func(c *Cache) {
+		if c.Listings == nil {
+			c.Listings = make(map[string]cachedListing)
+		}
+
+		listing := c.Listings[account.GetID()]
+		listing.Templates = make([]cachedTemplate, len(templates))
+		for i, template := range templates {
+			listing.Templates[i] = newCachedTemplate(template)
+		}
+		listing.TemplatesUpdated = time.Now()
+		c.Listings[account.GetID()] = listing
+	})
+}
+
+// isUnreachable returns if an error was caused by being unable to connect to the API, e.g. while offline or during an outage
+func isUnreachable(err error) bool {
+	for err != nil {
+		if _, ok := err.(net.Error); ok {
+			return true
+		}
+
+		cause, ok := err.(interface {
+			Cause() error
+		})
+		if !ok {
+			return false
+		}
+		err = cause.Cause()
+	}
+	return false
+}
+
+// warnCachedListing explains that the results are from the cache, and how old they are
+func warnCachedListing(what string, updated time.Time, err error) {
+	age := time.Since(updated).Truncate(time.Second)
+	if err != nil {
+		common.Log.WriteDebug("Unable to reach the API: %s", err)
+		common.Log.WriteStructuredWarning(common.WarningCachedResults, "Unable to reach the API, showing the %s cached %s ago on %s. They may be out of date.", what, age, updated.Local().Format(time.RFC822))
+		return
+	}
+	common.Log.WriteStructuredWarning(common.WarningCachedResults, "Showing the %s cached %s ago on %s", what, age, updated.Local().Format(time.RFC822))
+}
+
+// ListCachedClusters retrieves the clusters from the last successful listing, without connecting to the API
+func (client *Client) ListCachedClusters(account Account, options ListClustersOptions) ([]common.Cluster, error) {
+	err := ValidateClusterSort(options.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	results, updated, ok := client.Cache.getCachedClusters(account)
+	if !ok {
+		return nil, errors.New("No clusters have been cached for this account. Run carina clusters while connected to cache them")
+	}
+	warnCachedListing("clusters", updated, nil)
+
+	return filterClusters(results, options), nil
+}
+
+// filterClusters applies the label and field filters, then sorts the clusters
+func filterClusters(results []common.Cluster, options ListClustersOptions) []common.Cluster {
+	var clusters []common.Cluster
+	for _, cluster := range results {
+		if MatchesLabels(cluster, options.Labels) && MatchesFilters(cluster, options.Filters) {
+			clusters = append(clusters, cluster)
+		}
+	}
+	SortClusters(clusters, options.Sort)
+	return clusters
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+// offlineAccount is an account whose cluster service can be switched to simulate losing the connection to the API
+type offlineAccount struct {
+	stubAccount
+	service common.ClusterService
+}
+
+func (account *offlineAccount) NewClusterService() common.ClusterService {
+	return account.service
+}
+
+func (account *offlineAccount) BuildCache() map[string]string {
+	return map[string]string{}
+}
+
+func (account *offlineAccount) ApplyCache(c map[string]string) {}
+
+// unreachableClusterService fails every request as if the API could not be reached
+type unreachableClusterService struct {
+	common.ClusterService
+}
+
+var errUnreachable = &url.Error{Op: "Get", URL: "https://api.getcarina.com/clusters", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+
+func (service *unreachableClusterService) ListClusters() ([]common.Cluster, error) {
+	return nil, errUnreachable
+}
+
+func (service *unreachableClusterService) ListClusterTemplates() ([]common.ClusterTemplate, error) {
+	return nil, errUnreachable
+}
+
+func TestListClustersFallsBackToCache(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	account := &offlineAccount{}
+
+	_, err := client.ListCachedClusters(account, ListClustersOptions{})
+	assert.NotNil(t, err, "Nothing has been cached yet")
+
+	account.service = &unreachableClusterService{}
+	_, err = client.ListClusters(account, ListClustersOptions{})
+	assert.NotNil(t, err, "The error should be returned when nothing has been cached")
+
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes"})
+	service.CreateCluster("web", "Kubernetes 1.5.2 on LXC", 2)
+	service.CreateCluster("api", "Kubernetes 1.5.2 on LXC", 1)
+	account.service = service
+	clusters, err := client.ListClusters(account, ListClustersOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, clusters, 2)
+	_, err = client.ListClusterTemplates(account, ListTemplatesOptions{})
+	assert.Nil(t, err)
+
+	account.service = &unreachableClusterService{}
+	clusters, err = client.ListClusters(account, ListClustersOptions{Filters: []ClusterFilter{{Field: "name", Pattern: "web"}}})
+	assert.Nil(t, err)
+	if assert.Len(t, clusters, 1) {
+		assert.Equal(t, "web", clusters[0].GetName())
+		assert.Equal(t, "2", clusters[0].GetNodes())
+		assert.Equal(t, "kubernetes", clusters[0].GetTemplate().GetCOE())
+	}
+
+	templates, err := client.ListClusterTemplates(account, ListTemplatesOptions{Name: "Kubernetes*"})
+	assert.Nil(t, err)
+	assert.Len(t, templates, 1)
+
+	clusters, err = client.ListCachedClusters(account, ListClustersOptions{Sort: "name"})
+	assert.Nil(t, err)
+	if assert.Len(t, clusters, 2) {
+		assert.Equal(t, "api", clusters[0].GetName())
+	}
+}
+
+func TestIsUnreachable(t *testing.T) {
+	assert.True(t, isUnreachable(errUnreachable))
+	assert.True(t, isUnreachable(wrapClientError(errUnreachable)))
+	assert.False(t, isUnreachable(errors.New("Invalid credentials")))
+	assert.False(t, isUnreachable(nil))
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/getcarina/carina/common"
+)
+
+// maxClusterStatuses is the number of cluster statuses remembered, by both name and id, see rememberClusterStatus
+const maxClusterStatuses = 1000
+
+// clusterStatusTTL is how long the last status observed for a cluster is remembered
+const clusterStatusTTL = 24 * time.Hour
+
+// operation is a long-running operation on a cluster, identified by its name or id
+type operation struct {
+	cluster     string
+	description string
+}
+
+// startOperation records that a long-running operation on a cluster has started, so that it can be reported if interrupted
+func (client *Client) startOperation(cluster string, format string, a ...interface{}) int {
+	client.operationsLock.Lock()
+	defer client.operationsLock.Unlock()
+
+	if client.operations == nil {
+		client.operations = make(map[int]operation)
+	}
+
+	client.lastOperationID++
+	client.operations[client.lastOperationID] = operation{cluster: cluster, description: fmt.Sprintf(format, a...)}
+	return client.lastOperationID
+}
+
+// endOperation records that an operation has finished, unless it was interrupted by the cli shutting down
+func (client *Client) endOperation(id int) {
+	if common.IsShuttingDown() {
+		return
+	}
+
+	client.operationsLock.Lock()
+	defer client.operationsLock.Unlock()
+	delete(client.operations, id)
+}
+
+// rememberClusterStatus keeps the last status observed for a cluster, by both its name and id,
+// so that it can be reported if an operation on the cluster is interrupted
+func (client *Client) rememberClusterStatus(cluster common.Cluster) {
+	client.operationsLock.Lock()
+	defer client.operationsLock.Unlock()
+
+	if client.clusterStatuses == nil {
+		client.clusterStatuses = common.NewBoundedCache("cluster statuses", maxClusterStatuses, clusterStatusTTL)
+	}
+	client.clusterStatuses.Set(cluster.GetID(), cluster.GetStatus())
+	client.clusterStatuses.Set(cluster.GetName(), cluster.GetStatus())
+}
+
+// lastClusterStatus returns the last status observed for a cluster, by its name or id, or an empty string when it isn't known.
+// The caller must hold the operations lock.
+func (client *Client) lastClusterStatus(cluster string) string {
+	if client.clusterStatuses == nil {
+		return ""
+	}
+	status, _ := client.clusterStatuses.Get(cluster)
+	value, _ := status.(string)
+	return value
+}
+
+// CacheStats returns the metrics of the in-memory caches, which are bounded so that a long-running
+// process, such as carina credentials refresh --agent, doesn't grow without bound
+func (client *Client) CacheStats() []common.CacheStats {
+	client.operationsLock.Lock()
+	defer client.operationsLock.Unlock()
+
+	if client.clusterStatuses == nil {
+		return nil
+	}
+	return []common.CacheStats{client.clusterStatuses.Stats()}
+}
+
+// IncompleteOperations returns a description of each operation which has not finished, including the last known status of the cluster
+func (client *Client) IncompleteOperations() []string {
+	client.operationsLock.Lock()
+	defer client.operationsLock.Unlock()
+
+	var operations []string
+	for id := 1; id <= client.lastOperationID; id++ {
+		op, ok := client.operations[id]
+		if !ok {
+			continue
+		}
+
+		if status := client.lastClusterStatus(op.cluster); status != "" {
+			operations = append(operations, fmt.Sprintf("%s, last status: %s", op.description, status))
+		} else {
+			operations = append(operations, op.description)
+		}
+	}
+	return operations
+}
+
+// holdLock tracks a cluster lock held by this process, so that it is released if the cli is interrupted
+func (client *Client) holdLock(path string, release func()) {
+	client.operationsLock.Lock()
+	defer client.operationsLock.Unlock()
+
+	if client.heldLocks == nil {
+		client.heldLocks = make(map[string]func())
+	}
+	client.heldLocks[path] = release
+}
+
+// releaseLock stops tracking a cluster lock which has been released
+func (client *Client) releaseLock(path string) {
+	client.operationsLock.Lock()
+	defer client.operationsLock.Unlock()
+	delete(client.heldLocks, path)
+}
+
+// releaseHeldLocks releases every cluster lock still held, so that an interrupted command doesn't block the next one
+func (client *Client) releaseHeldLocks() {
+	client.operationsLock.Lock()
+	var releases []func()
+	for _, release := range client.heldLocks {
+		releases = append(releases, release)
+	}
+	client.operationsLock.Unlock()
+
+	for _, release := range releases {
+		release()
+	}
+}
+
+// Shutdown releases the cluster locks held, wipes temporary credentials, flushes the cache for each account used,
+// and prints a summary of any operations which did not finish. It must only be called once the command has returned,
+// because the accounts and the cache are not safe to use concurrently, see Abort.
+// It is safe to call more than once, only the first call of Shutdown or Abort has an effect.
+func (client *Client) Shutdown() {
+	client.shutdown.Do(func() {
+		client.stop(true)
+	})
+}
+
+// Abort stops the client while the command is still running, e.g. when it didn't stop in time after SIGINT.
+// Like Shutdown, it releases the cluster locks, wipes temporary credentials and reports the operations which did not finish,
+// but it doesn't flush the cache, because the running command still owns the accounts and the cache.
+func (client *Client) Abort() {
+	client.shutdown.Do(func() {
+		client.stop(false)
+	})
+}
+
+func (client *Client) stop(flushCache bool) {
+	client.releaseHeldLocks()
+	client.wipeEphemeralCredentials()
+
+	if flushCache {
+		client.operationsLock.Lock()
+		accounts := client.accounts
+		client.operationsLock.Unlock()
+
+		for _, account := range accounts {
+			err := client.Cache.SaveAccount(account)
+			if err != nil {
+				common.Log.WriteDebug("Unable to save the account cache: %s", err)
+			}
+		}
+	} else {
+		common.Log.WriteDebug("Skipping saving the cache, the command is still running")
+	}
+
+	operations := client.IncompleteOperations()
+	if len(operations) == 0 {
+		return
+	}
+
+	common.Log.WriteWarning("carina was interrupted before the following operations completed:")
+	for _, operation := range operations {
+		common.Log.WriteWarning("  %s", operation)
+	}
+	common.Log.WriteWarning("Operations already submitted to the API may still be in progress. Run carina clusters to check their status.")
+
+	client.operationsLock.Lock()
+	pendingWaits := client.pendingWaits
+	client.operationsLock.Unlock()
+	if pendingWaits > 0 {
+		common.Log.WriteWarning("Run carina resume to continue waiting for the clusters.")
+	}
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncompleteOperationsReportLastStatus(t *testing.T) {
+	client := &Client{Cache: &Cache{}}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+
+	cluster, err := service.CreateCluster("prod", "Swarm 1.11.2 on LXC", 1)
+	require.NoError(t, err)
+
+	client.startOperation("prod", "Resize cluster (%s) to %d nodes", "prod", 2)
+	client.startOperation("staging", "Delete cluster (%s)", "staging")
+	client.recordClusterStatus(account, "wait", cluster)
+
+	assert.Equal(t, []string{
+		"Resize cluster (prod) to 2 nodes, last status: " + cluster.GetStatus(),
+		"Delete cluster (staging)",
+	}, client.IncompleteOperations())
+
+	stats := client.CacheStats()
+	if assert.Len(t, stats, 1) {
+		assert.Equal(t, 2, stats[0].Entries, "The status should be remembered by both the cluster name and id")
+	}
+}
+
+func TestShutdownReleasesClusterLocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-locks")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	client := &Client{Cache: newCache(filepath.Join(dir, "cache.json"))}
+	account := &historyAccount{offlineAccount{service: testsupport.NewFakeClusterService()}}
+
+	unlock, err := client.lockCluster(account, "prod", "resize")
+	require.NoError(t, err)
+
+	client.Shutdown()
+	matches, err := filepath.Glob(filepath.Join(dir, "cluster-*.lock"))
+	require.NoError(t, err)
+	assert.Empty(t, matches, "The cluster locks should be released on shutdown")
+
+	// Releasing the lock again after shutdown is harmless
+	unlock()
+}
+
+func TestAbortDoesNotSaveTheCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-abort")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cacheFile := filepath.Join(dir, "cache.json")
+	client := &Client{Cache: newCache(cacheFile)}
+	account := &historyAccount{offlineAccount{service: testsupport.NewFakeClusterService()}}
+	_, err = client.buildContainerService(account)
+	require.NoError(t, err)
+
+	unlock, err := client.lockCluster(account, "prod", "resize")
+	require.NoError(t, err)
+	defer unlock()
+
+	client.Abort()
+	_, err = os.Stat(cacheFile)
+	assert.True(t, os.IsNotExist(err), "The cache is still owned by the running command, and shouldn't be saved")
+	matches, _ := filepath.Glob(filepath.Join(dir, "cluster-*.lock"))
+	assert.Empty(t, matches, "The cluster locks should be released when aborting")
+
+	client.Shutdown()
+	_, err = os.Stat(cacheFile)
+	assert.True(t, os.IsNotExist(err), "Only the first of Abort and Shutdown should have an effect")
+}
+
+func TestShutdownSavesTheCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-shutdown")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cacheFile := filepath.Join(dir, "cache.json")
+	client := &Client{Cache: newCache(cacheFile)}
+	account := &historyAccount{offlineAccount{service: testsupport.NewFakeClusterService()}}
+	_, err = client.buildContainerService(account)
+	require.NoError(t, err)
+
+	client.Shutdown()
+	_, err = os.Stat(cacheFile)
+	assert.NoError(t, err, "The cache should be saved for each account used")
+}
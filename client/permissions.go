@@ -0,0 +1,15 @@
+package client
+
+import (
+	"github.com/getcarina/carina/common"
+)
+
+// restrictAccess limits a file or directory holding credentials to the current user.
+// On Windows, where file modes such as 0600 are ignored, this replaces the ACL.
+// Failures are only logged, because some filesystems, such as network shares, don't support ACLs.
+func restrictAccess(path string) {
+	err := setOwnerOnlyAccess(path)
+	if err != nil {
+		common.Log.WriteWarning("WARNING: Unable to restrict access to %s to the current user: %s", path, err)
+	}
+}
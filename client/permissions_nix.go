@@ -0,0 +1,34 @@
+// +build !windows
+
+package client
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// setOwnerOnlyAccess does nothing, because the file mode used when the file was created already restricts access
+func setOwnerOnlyAccess(path string) error {
+	return nil
+}
+
+// checkPrivateDir verifies that a directory holding decrypted credentials is a real directory, not a symlink,
+// which belongs to the current user and which other users can't access
+func checkPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to access %s", dir)
+	}
+	if !info.IsDir() {
+		return errors.Errorf("%s is not a directory. Remove it, it may have been created by another user to intercept your credentials", dir)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return errors.Errorf("%s belongs to another user (uid %d). Remove it, it may have been created to intercept your credentials", dir, stat.Uid)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return errors.Errorf("%s can be accessed by other users (%#o). Run chmod 700 %s", dir, perm, dir)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// +build windows
+
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	procConvertStringSecurityDescriptor = advapi32.NewProc("ConvertStringSecurityDescriptorToSecurityDescriptorW")
+	procGetSecurityDescriptorDacl       = advapi32.NewProc("GetSecurityDescriptorDacl")
+	procSetNamedSecurityInfoW           = advapi32.NewProc("SetNamedSecurityInfoW")
+	kernel32                            = syscall.NewLazyDLL("kernel32.dll")
+	procLocalFree                       = kernel32.NewProc("LocalFree")
+)
+
+const (
+	sddlRevision1                    = 1
+	seFileObject                     = 1
+	daclSecurityInformation          = 0x00000004
+	protectedDaclSecurityInformation = 0x80000000
+)
+
+// setOwnerOnlyAccess replaces the ACL on a file or directory, so that only the current user and SYSTEM can access it
+func setOwnerOnlyAccess(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	sid, err := currentUserSID()
+	if err != nil {
+		return errors.Wrap(err, "Unable to look up the current user")
+	}
+
+	sddl, err := syscall.UTF16PtrFromString(ownerOnlySDDL(sid, info.IsDir()))
+	if err != nil {
+		return err
+	}
+
+	var descriptor uintptr
+	result, _, err := procConvertStringSecurityDescriptor.Call(uintptr(unsafe.Pointer(sddl)), sddlRevision1, uintptr(unsafe.Pointer(&descriptor)), 0)
+	if result == 0 {
+		return errors.Wrap(err, "Unable to build the security descriptor")
+	}
+	defer procLocalFree.Call(descriptor)
+
+	var daclPresent, daclDefaulted int32
+	var dacl uintptr
+	result, _, err = procGetSecurityDescriptorDacl.Call(descriptor, uintptr(unsafe.Pointer(&daclPresent)), uintptr(unsafe.Pointer(&dacl)), uintptr(unsafe.Pointer(&daclDefaulted)))
+	if result == 0 {
+		return errors.Wrap(err, "Unable to read the security descriptor")
+	}
+
+	name, err := syscall.UTF16PtrFromString(extendedLengthPath(path))
+	if err != nil {
+		return err
+	}
+
+	result, _, _ = procSetNamedSecurityInfoW.Call(uintptr(unsafe.Pointer(name)), seFileObject, daclSecurityInformation|protectedDaclSecurityInformation, 0, 0, dacl, 0)
+	if result != 0 {
+		return errors.Wrap(syscall.Errno(result), "Unable to set the ACL")
+	}
+
+	return nil
+}
+
+// currentUserSID returns the security identifier of the user running carina, e.g. S-1-5-21-...
+func currentUserSID() (string, error) {
+	token, err := syscall.OpenCurrentProcessToken()
+	if err != nil {
+		return "", err
+	}
+	defer token.Close()
+
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+
+	return user.User.Sid.String()
+}
+
+// checkPrivateDir verifies that a directory holding decrypted credentials is a real directory, not a symlink or junction.
+// The ACL set by restrictAccess prevents other users from accessing it.
+func checkPrivateDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to access %s", dir)
+	}
+	if !info.IsDir() {
+		return errors.Errorf("%s is not a directory. Remove it, it may have been created by another user to intercept your credentials", dir)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// +build windows
+
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetOwnerOnlyAccess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-acl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, setOwnerOnlyAccess(dir))
+
+	file := filepath.Join(dir, "ca.pem")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("fake-ca"), 0600))
+	assert.Nil(t, setOwnerOnlyAccess(file))
+
+	contents, err := ioutil.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, "fake-ca", string(contents))
+}
+
+func TestSetOwnerOnlyAccessLongPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-acl")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	longDir := filepath.Join(dir, strings.Repeat("a", 100), strings.Repeat("b", 100), strings.Repeat("c", 100))
+	assert.Nil(t, os.MkdirAll(longDir, 0700))
+
+	assert.Nil(t, setOwnerOnlyAccess(longDir))
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AccountSettings are the credentials and endpoints used to build an account, resolved from flags, a profile or environment variables
+type AccountSettings struct {
+	Username         string
+	APIKey           string
+	Password         string
+	Project          string
+	Domain           string
+	Region           string
+	AuthEndpoint     string
+	EndpointOverride string
+
+	// Token is a pre-obtained auth token, used by the public cloud instead of the API key, e.g. a short-lived token injected by CI
+	Token string
+
+	// Keystone v3 scoping and application credentials, used by private clouds
+	ProjectID                   string
+	ProjectDomain               string
+	UserDomain                  string
+	ApplicationCredentialID     string
+	ApplicationCredentialName   string
+	ApplicationCredentialSecret string
+
+	// Fixtures is the JSON file of canned clusters and templates served by the fake cloud
+	Fixtures string
+
+	// CredentialSources are asked, in turn, for the username and secret when they weren't specified with flags,
+	// environment variables or a profile, e.g. --password-stdin or an interactive prompt. See ResolveCredential.
+	CredentialSources []CredentialSource
+}
+
+// SecretType is the kind of secret used to authenticate with a cloud
+type SecretType string
+
+const (
+	// SecretAPIKey authenticates with a username and API key
+	SecretAPIKey SecretType = "API Key"
+
+	// SecretPassword authenticates with a username and password
+	SecretPassword SecretType = "Password"
+)
+
+// ProfileReader reads a setting from a profile in the config file, returning the default value when the setting is missing,
+// or an error when a required setting is missing
+type ProfileReader func(key string, defaultValue string, required bool) (string, error)
+
+// CloudProvider is a cloud on which carina manages clusters, such as the Carina public cloud or a Magnum private cloud.
+// Each provider registers itself with RegisterCloudProvider, so that a cloud can be added without changing the client.
+type CloudProvider struct {
+	// Name selects the cloud with --cloud, or the cloud setting in a profile, e.g. public
+	Name string
+
+	// Secret is the kind of secret used to authenticate, which is read from the keychain with --auth-source keychain.
+	// It is empty for clouds which don't authenticate, such as the fake cloud.
+	Secret SecretType
+
+	// Detect selects this cloud when --cloud isn't specified and only its kind of secret is found
+	Detect bool
+
+	// LoadEnvironment fills in the settings which were not specified with flags, from environment variables and defaults
+	LoadEnvironment func(settings *AccountSettings) error
+
+	// LoadProfile reads the settings from a profile. The secret is only required when it isn't read from the keychain.
+	LoadProfile func(settings *AccountSettings, read ProfileReader, secretRequired bool) error
+
+	// NewAccount builds an account from the settings, the account creates the cluster service for the cloud
+	NewAccount func(settings AccountSettings) Account
+
+	// StatusURL is the cloud's status page summary, in the Statuspage format, used by carina service-status.
+	// It is empty when the cloud doesn't have a public status page.
+	StatusURL string
+}
+
+// GetSecret returns the secret used to authenticate from the settings, either the API key or password
+func (provider CloudProvider) GetSecret(settings *AccountSettings) *string {
+	if provider.Secret == SecretPassword {
+		return &settings.Password
+	}
+	return &settings.APIKey
+}
+
+var cloudProviders = struct {
+	sync.RWMutex
+	providers map[string]CloudProvider
+}{providers: make(map[string]CloudProvider)}
+
+// RegisterCloudProvider adds a cloud which can be selected with --cloud, replacing a provider with the same name
+func RegisterCloudProvider(provider CloudProvider) {
+	if provider.Name == "" || provider.NewAccount == nil {
+		panic("A cloud provider must have a name and an account factory")
+	}
+
+	cloudProviders.Lock()
+	defer cloudProviders.Unlock()
+	cloudProviders.providers[provider.Name] = provider
+}
+
+// LookupCloudProvider finds a registered cloud by name, returning an error listing the registered clouds when it isn't found
+func LookupCloudProvider(name string) (CloudProvider, error) {
+	cloudProviders.RLock()
+	provider, ok := cloudProviders.providers[name]
+	cloudProviders.RUnlock()
+
+	if !ok {
+		return CloudProvider{}, fmt.Errorf("Invalid --cloud value: %s. Allowed values are %s", name, strings.Join(CloudProviderNames(), ", "))
+	}
+	return provider, nil
+}
+
+// DetectCloudProvider finds the cloud which is selected automatically for a kind of secret
+func DetectCloudProvider(secret SecretType) (CloudProvider, bool) {
+	cloudProviders.RLock()
+	defer cloudProviders.RUnlock()
+
+	for _, name := range sortedCloudProviderNames() {
+		provider := cloudProviders.providers[name]
+		if provider.Detect && provider.Secret == secret {
+			return provider, true
+		}
+	}
+	return CloudProvider{}, false
+}
+
+// CloudProviderNames returns the names of the registered clouds, sorted alphabetically
+func CloudProviderNames() []string {
+	cloudProviders.RLock()
+	defer cloudProviders.RUnlock()
+	return sortedCloudProviderNames()
+}
+
+func sortedCloudProviderNames() []string {
+	names := make([]string, 0, len(cloudProviders.providers))
+	for name := range cloudProviders.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudProviderRegistry(t *testing.T) {
+	newAccount := func(settings AccountSettings) Account { return nil }
+	RegisterCloudProvider(CloudProvider{Name: "test-apikey", Secret: SecretAPIKey, NewAccount: newAccount})
+	RegisterCloudProvider(CloudProvider{Name: "test-password", Secret: SecretPassword, Detect: true, NewAccount: newAccount})
+	defer func() {
+		cloudProviders.Lock()
+		delete(cloudProviders.providers, "test-apikey")
+		delete(cloudProviders.providers, "test-password")
+		cloudProviders.Unlock()
+	}()
+
+	provider, err := LookupCloudProvider("test-password")
+	assert.Nil(t, err)
+	assert.Equal(t, SecretPassword, provider.Secret)
+
+	settings := &AccountSettings{APIKey: "my-apikey", Password: "my-password"}
+	assert.Equal(t, "my-password", *provider.GetSecret(settings))
+
+	_, err = LookupCloudProvider("bogus")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "test-apikey, test-password")
+
+	detected, ok := DetectCloudProvider(SecretPassword)
+	assert.True(t, ok)
+	assert.Equal(t, "test-password", detected.Name)
+
+	_, ok = DetectCloudProvider(SecretAPIKey)
+	assert.False(t, ok, "Only providers which opt into detection should be detected")
+}
+
+func TestResolveCredential(t *testing.T) {
+	prompted := CredentialSource{
+		Name: "prompt",
+		Provide: func(credential Credential) (string, bool, error) {
+			return "prompted " + credential.Name, true, nil
+		},
+	}
+	settings := &AccountSettings{
+		Username:          "alice",
+		CredentialSources: []CredentialSource{NewStaticSecretSource("stdin", "from-stdin"), prompted},
+	}
+
+	ok, err := settings.ResolveCredential(CredentialUsername, &settings.Username)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "alice", settings.Username, "A specified credential should not be replaced")
+
+	ok, err = settings.ResolveCredential(SecretAPIKey.Credential(), &settings.APIKey)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "from-stdin", settings.APIKey, "The sources should be asked in order")
+
+	settings.Username = ""
+	ok, err = settings.ResolveCredential(CredentialUsername, &settings.Username)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "prompted Username", settings.Username, "The secret source should not provide the username")
+
+	settings.Username = ""
+	settings.CredentialSources = nil
+	ok, err = settings.ResolveCredential(CredentialUsername, &settings.Username)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/getcarina/carina/common"
+)
+
+// GetQuotaUsage counts the clusters and nodes in use on the account, by container orchestration engine
+func (client *Client) GetQuotaUsage(account Account) (common.QuotaUsage, error) {
+	clusters, err := client.ListClusters(account, ListClustersOptions{})
+	if err != nil {
+		return common.QuotaUsage{}, err
+	}
+	return summarizeQuotaUsage(clusters), nil
+}
+
+// summarizeQuotaUsage totals the clusters and nodes, sorting the COEs by name
+func summarizeQuotaUsage(clusters []common.Cluster) common.QuotaUsage {
+	var usage common.QuotaUsage
+	coes := make(map[string]*common.COEUsage)
+	var names []string
+	for _, cluster := range clusters {
+		coe := "unknown"
+		if template := cluster.GetTemplate(); template != nil && template.GetCOE() != "" {
+			coe = template.GetCOE()
+		}
+		// Clusters whose node count is unknown, such as while they are being created, count as 0 nodes
+		nodes, _ := common.ParseNodeCount(cluster.GetNodes())
+
+		coeUsage, ok := coes[coe]
+		if !ok {
+			coeUsage = &common.COEUsage{COE: coe}
+			coes[coe] = coeUsage
+			names = append(names, coe)
+		}
+		coeUsage.Clusters++
+		coeUsage.Nodes += nodes
+		usage.Clusters++
+		usage.Nodes += nodes
+		if nodes > usage.LargestCluster {
+			usage.LargestCluster = nodes
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		usage.COEs = append(usage.COEs, *coes[name])
+	}
+	return usage
+}
+
+// DefaultQuotaThreshold is the percentage of a quota which can be used before carina quotas warns about it
+const DefaultQuotaThreshold = 80
+
+// QuotaWarning is a quota whose usage has reached the warning threshold
+type QuotaWarning struct {
+	// Quota is the name of the quota, e.g. clusters or nodes-per-cluster
+	Quota string
+	Used  int
+	Max   int
+}
+
+// IsExhausted returns if the quota is completely used
+func (warning QuotaWarning) IsExhausted() bool {
+	return warning.Used >= warning.Max
+}
+
+// String describes how much of the quota is used, e.g. 3 of 3 clusters used (100%)
+func (warning QuotaWarning) String() string {
+	return fmt.Sprintf("%d of %d %s used (%d%%)", warning.Used, warning.Max, warning.Quota, warning.Used*100/warning.Max)
+}
+
+// CheckQuotas returns the quotas whose usage is at or above the threshold, a percentage of the limit.
+// Quotas which are unknown are skipped.
+func CheckQuotas(quotas *common.Quotas, usage common.QuotaUsage, threshold int) []QuotaWarning {
+	var warnings []QuotaWarning
+	check := func(quota string, used int, max int) {
+		if max > 0 && used*100 >= max*threshold {
+			warnings = append(warnings, QuotaWarning{Quota: quota, Used: used, Max: max})
+		}
+	}
+
+	check("clusters", usage.Clusters, quotas.MaxClusters)
+	check("nodes-per-cluster", usage.LargestCluster, quotas.MaxNodesPerCluster)
+	return warnings
+}
+
+// ValidateQuotaThreshold checks that a quota threshold is a percentage between 1 and 100
+func ValidateQuotaThreshold(value string) error {
+	threshold, err := strconv.Atoi(value)
+	if err != nil || threshold < 1 || threshold > 100 {
+		return fmt.Errorf("Invalid quota threshold: %s. The threshold is a percentage between 1 and 100", value)
+	}
+	return nil
+}
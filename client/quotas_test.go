@@ -0,0 +1,43 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeQuotaUsage(t *testing.T) {
+	kubernetes := &testsupport.FakeClusterTemplate{Name: "Kubernetes", COE: "kubernetes"}
+	swarm := &testsupport.FakeClusterTemplate{Name: "Swarm", COE: "swarm"}
+	clusters := []common.Cluster{
+		&testsupport.FakeCluster{Name: "a", Template: swarm, Nodes: 1},
+		&testsupport.FakeCluster{Name: "b", Template: kubernetes, Nodes: 3},
+		&testsupport.FakeCluster{Name: "c", Template: kubernetes, Nodes: 2},
+	}
+
+	usage := summarizeQuotaUsage(clusters)
+
+	assert.Equal(t, 3, usage.Clusters)
+	assert.Equal(t, 6, usage.Nodes)
+	assert.Equal(t, 3, usage.LargestCluster)
+	assert.Equal(t, []common.COEUsage{
+		{COE: "kubernetes", Clusters: 2, Nodes: 5},
+		{COE: "swarm", Clusters: 1, Nodes: 1},
+	}, usage.COEs)
+}
+
+func TestCheckQuotas(t *testing.T) {
+	quotas := &common.Quotas{MaxClusters: 5, MaxNodesPerCluster: 10}
+
+	warnings := CheckQuotas(quotas, common.QuotaUsage{Clusters: 4, LargestCluster: 10}, 80)
+	if assert.Len(t, warnings, 2) {
+		assert.Equal(t, "4 of 5 clusters used (80%)", warnings[0].String())
+		assert.False(t, warnings[0].IsExhausted())
+		assert.True(t, warnings[1].IsExhausted())
+	}
+
+	assert.Empty(t, CheckQuotas(quotas, common.QuotaUsage{Clusters: 3, LargestCluster: 7}, 80))
+	assert.Empty(t, CheckQuotas(&common.Quotas{}, common.QuotaUsage{Clusters: 3}, 80), "Unknown quotas should be skipped")
+}
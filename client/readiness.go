@@ -0,0 +1,179 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ReadyCheckNone considers a cluster ready as soon as the API reports that it is active
+	ReadyCheckNone = "none"
+
+	// ReadyCheckCOE also waits for the cluster's Docker or Kubernetes API to respond
+	ReadyCheckCOE = "coe"
+)
+
+// readyCheckInterval is how often the COE endpoint is probed while waiting for it to respond
+const readyCheckInterval = 5 * time.Second
+
+// ValidateReadyCheck checks the --ready-check flag
+func ValidateReadyCheck(check string) error {
+	switch check {
+	case "", ReadyCheckNone, ReadyCheckCOE:
+		return nil
+	default:
+		return fmt.Errorf("Invalid --ready-check: %s. Allowed values: %s, %s", check, ReadyCheckNone, ReadyCheckCOE)
+	}
+}
+
+// WaitUntilCOEIsReady polls an active cluster until its Docker or Kubernetes API responds,
+// because a cluster can be reported active before it is usable
+func (client *Client) WaitUntilCOEIsReady(account Account, cluster common.Cluster) error {
+	credentialsPath, err := client.ensureClusterCredentials(account, cluster.GetName(), "")
+	if err != nil {
+		return err
+	}
+
+	httpClient, probeURL, err := client.buildCOEProbe(cluster, credentialsPath)
+	if err != nil {
+		return err
+	}
+
+	redownloaded := false
+	waiter := common.NewWaiter(readyCheckInterval, "Waiting for the %s API of cluster (%s) to respond", cluster.GetTemplate().GetCOE(), cluster.GetName())
+	for {
+		err = probeCOE(httpClient, probeURL)
+		if err == nil {
+			return nil
+		}
+
+		// The saved credentials are stale when the cluster was rebuilt since they were downloaded, so download them again once
+		if isCertificateError(err) && !redownloaded {
+			common.Log.WriteDebug("Re-downloading the credentials for %s, because the COE rejected them: %s", cluster.GetName(), err)
+			redownloaded = true
+			credentialsPath, err = client.downloadLocalClusterCredentials(account, cluster.GetName(), "")
+			if err == nil {
+				credentialsPath, err = client.decryptClusterCredentials(credentialsPath, "")
+			}
+			if err == nil {
+				httpClient, probeURL, err = client.buildCOEProbe(cluster, credentialsPath)
+			}
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		common.Log.WriteDebug("The COE is not ready: %s", err)
+		waiter.Update("not responding")
+		err = waiter.Wait()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// buildCOEProbe returns the HTTP client and URL used to check if the cluster's COE is ready, using the credentials in credentialsPath
+func (client *Client) buildCOEProbe(cluster common.Cluster, credentialsPath string) (*http.Client, string, error) {
+	files, err := client.readCredentialsFiles(credentialsPath)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "Unable to read the credentials for %s", cluster.GetName())
+	}
+
+	probeURL, err := buildReadyCheckURL(cluster.GetTemplate().GetCOE(), findCredentialsEndpoint(files))
+	if err != nil {
+		return nil, "", err
+	}
+
+	httpClient, err := buildCredentialsHTTPClient(files)
+	if err != nil {
+		return nil, "", err
+	}
+	return httpClient, probeURL, nil
+}
+
+// isCertificateError returns if the COE and the credentials don't trust each other,
+// e.g. the server's certificate isn't signed by the CA in the credentials, or it rejected the client certificate
+func isCertificateError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	switch err.(type) {
+	case x509.UnknownAuthorityError, x509.CertificateInvalidError, x509.HostnameError:
+		return true
+	}
+	// Newer versions of Go wrap the verification errors, and the server rejecting the client certificate is a TLS alert
+	msg := err.Error()
+	return strings.Contains(msg, "x509:") || strings.Contains(msg, "tls:")
+}
+
+// buildReadyCheckURL returns the endpoint which responds once the COE is usable: docker info for swarm, and healthz for kubernetes
+func buildReadyCheckURL(coe string, endpoint string) (string, error) {
+	if endpoint == "" {
+		return "", errors.New("Unable to check if the cluster is ready, the credentials do not contain the cluster address")
+	}
+
+	address, err := url.Parse(endpoint)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to check if the cluster is ready, invalid cluster address %s", endpoint)
+	}
+	// Docker uses tcp://, but always with TLS on Carina
+	address.Scheme = "https"
+
+	switch strings.ToLower(coe) {
+	case "kubernetes":
+		address.Path = "/healthz"
+	case "swarm", "swarm-mode":
+		address.Path = "/info"
+	default:
+		return "", fmt.Errorf("Unable to check if the cluster is ready, --ready-check coe does not support %s", coe)
+	}
+	return address.String(), nil
+}
+
+// buildCredentialsHTTPClient authenticates with the client certificate from a credentials bundle, trusting only the cluster's CA
+func buildCredentialsHTTPClient(files map[string][]byte) (*http.Client, error) {
+	cert, err := tls.X509KeyPair(files[clientCertFilename], files[clientKeyFilename])
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to load the client certificate from the cluster credentials")
+	}
+
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(files[caCertFilename]) {
+		return nil, fmt.Errorf("Unable to load %s from the cluster credentials", caCertFilename)
+	}
+
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				RootCAs:      caPool,
+			},
+		},
+	}, nil
+}
+
+// probeCOE checks that the COE endpoint responds successfully
+func probeCOE(httpClient *http.Client, probeURL string) error {
+	resp, err := httpClient.Get(probeURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d %s", probeURL, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildReadyCheckURL(t *testing.T) {
+	probe, err := buildReadyCheckURL("swarm", "tcp://172.99.65.11:2376")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://172.99.65.11:2376/info", probe)
+
+	probe, err = buildReadyCheckURL("kubernetes", "https://172.99.65.11:6443")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://172.99.65.11:6443/healthz", probe)
+
+	_, err = buildReadyCheckURL("mesos", "https://172.99.65.11")
+	assert.Error(t, err)
+
+	_, err = buildReadyCheckURL("kubernetes", "")
+	assert.Error(t, err)
+}
+
+func TestProbeCOE(t *testing.T) {
+	ready := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	assert.Error(t, probeCOE(server.Client(), server.URL+"/healthz"))
+
+	ready = true
+	assert.NoError(t, probeCOE(server.Client(), server.URL+"/healthz"))
+}
+
+func TestIsCertificateError(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// The default client doesn't trust the test server's certificate, like stale credentials after a rebuild
+	_, err := http.Get(server.URL)
+	assert.True(t, isCertificateError(err), "%v", err)
+
+	assert.False(t, isCertificateError(probeCOE(server.Client(), "https://127.0.0.1:1/healthz")))
+}
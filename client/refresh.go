@@ -0,0 +1,89 @@
+package client
+
+import (
+	"time"
+
+	"github.com/getcarina/carina/common"
+)
+
+// CredentialsRefresh is the result of checking if a cluster's downloaded credentials need to be downloaded again
+type CredentialsRefresh struct {
+	Name string
+
+	// Expires is when the client certificate expires, after any refresh, and is zero when it couldn't be read
+	Expires time.Time
+
+	// Refreshed is set when the credentials were downloaded again
+	Refreshed bool
+
+	// Err describes why the credentials couldn't be refreshed
+	Err error
+}
+
+// RefreshExpiringCredentials downloads the credentials again for each cluster whose client certificate expires within renewBefore,
+// or whose credentials can't be read. The credentials are replaced in place, so kubeconfig entries and docker environments
+// which reference them use the new certificates without being changed. See BatchPolicy for how a failure affects the remaining clusters.
+func (client *Client) RefreshExpiringCredentials(account Account, renewBefore time.Duration) ([]CredentialsRefresh, error) {
+	names, err := ListDownloadedCredentials(account)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]CredentialsRefresh, len(names))
+	var run batchRun
+	for i, name := range names {
+		if common.IsShuttingDown() {
+			return results[:i], common.ErrShuttingDown
+		}
+		if run.shouldSkip() {
+			results[i] = CredentialsRefresh{Name: name, Err: ErrSkipped}
+			continue
+		}
+		results[i] = client.refreshClusterCredentials(account, name, renewBefore)
+		run.recordResult(results[i].Err)
+	}
+
+	return results, nil
+}
+
+// refreshClusterCredentials downloads a cluster's credentials again, if its client certificate expires within renewBefore
+func (client *Client) refreshClusterCredentials(account Account, name string, renewBefore time.Duration) CredentialsRefresh {
+	result := CredentialsRefresh{Name: name}
+
+	credentialsPath, err := buildClusterCredentialsPath(account, name, "")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	cert, err := client.readCertificateInfo(credentialsPath, clientCertFilename)
+	if err == nil && !cert.ExpiresWithin(renewBefore) {
+		result.Expires = cert.NotAfter
+		return result
+	}
+	if err != nil {
+		common.Log.WriteDebug("Refreshing the credentials for %s because the client certificate can't be read: %s", name, err)
+	} else {
+		common.Log.WriteDebug("Refreshing the credentials for %s because the client certificate expires %s", name, cert.NotAfter)
+	}
+
+	credentialsPath, err = client.downloadLocalClusterCredentials(account, name, "")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Refreshed = true
+
+	// Replace the decrypted copy used by docker and kubectl too, when there is one
+	_, err = client.decryptSessionCredentials(credentialsPath, "")
+	if err != nil && err != errNoSessionDir {
+		result.Err = err
+		return result
+	}
+
+	cert, err = client.readCertificateInfo(credentialsPath, clientCertFilename)
+	if err == nil {
+		result.Expires = cert.NotAfter
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshExpiringCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-refresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	CredentialsStoragePolicy.Dir = dir
+	defer func() { CredentialsStoragePolicy.Dir = "" }()
+
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("current", "Swarm*", 1)
+	service.CreateCluster("expiring", "Swarm*", 1)
+	account := &historyAccount{offlineAccount{service: service}}
+
+	now := time.Now()
+	writeCert := func(name string, notAfter time.Time) {
+		credentialsPath := filepath.Join(dir, "stub-user", name)
+		assert.Nil(t, os.MkdirAll(credentialsPath, 0700))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(credentialsPath, clientCertFilename), buildSignedCertificate(t, notAfter), 0600))
+	}
+	writeCert("current", now.Add(30*24*time.Hour))
+	writeCert("expiring", now.Add(24*time.Hour))
+	writeCert("deleted", now.Add(-time.Hour))
+
+	results, err := client.RefreshExpiringCredentials(account, 7*24*time.Hour)
+	if !assert.Nil(t, err) || !assert.Len(t, results, 3) {
+		return
+	}
+
+	// The results are sorted by cluster name
+	current, deleted, expiring := results[0], results[1], results[2]
+	assert.False(t, current.Refreshed)
+	assert.Nil(t, current.Err)
+	assert.False(t, current.Expires.IsZero())
+
+	assert.True(t, expiring.Refreshed)
+	assert.Nil(t, expiring.Err)
+	contents, _ := ioutil.ReadFile(filepath.Join(dir, "stub-user", "expiring", clientCertFilename))
+	assert.Equal(t, "fake-cert", string(contents), "The credentials should be replaced in place")
+
+	assert.False(t, deleted.Refreshed)
+	assert.NotNil(t, deleted.Err, "The cluster no longer exists")
+}
+
+func TestRefreshExpiringCredentialsFailFast(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-refresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	CredentialsStoragePolicy.Dir = dir
+	defer func() { CredentialsStoragePolicy.Dir = "" }()
+	BatchPolicy.FailFast = true
+	defer func() { BatchPolicy.FailFast = false }()
+
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("expiring", "Swarm*", 1)
+	account := &historyAccount{offlineAccount{service: service}}
+
+	for _, name := range []string{"deleted", "expiring"} {
+		credentialsPath := filepath.Join(dir, "stub-user", name)
+		assert.Nil(t, os.MkdirAll(credentialsPath, 0700))
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(credentialsPath, clientCertFilename), buildSignedCertificate(t, time.Now().Add(time.Hour)), 0600))
+	}
+
+	results, err := client.RefreshExpiringCredentials(account, 7*24*time.Hour)
+	if !assert.Nil(t, err) || !assert.Len(t, results, 2) {
+		return
+	}
+	assert.NotNil(t, results[0].Err, "The cluster no longer exists")
+	assert.Equal(t, ErrSkipped, results[1].Err, "The remaining clusters should be skipped after the first failure")
+	assert.False(t, results[1].Refreshed)
+}
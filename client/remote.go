@@ -0,0 +1,166 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/libcarina"
+	"github.com/pkg/errors"
+)
+
+// CredentialsURLEnvVar is the environment variable with the URL of a credentials archive, such as a presigned URL or an internal vault endpoint.
+// The archive is in the format written by carina credentials export. {cluster} in the URL is replaced with the cluster name.
+const CredentialsURLEnvVar = "CARINA_CREDENTIALS_URL"
+
+// CredentialsChecksumEnvVar is the environment variable with the SHA-256 checksum of the archive at CARINA_CREDENTIALS_URL.
+// When the URL is for more than one cluster, it is the path to a checksum file in the format written by sha256sum instead,
+// with a line for each cluster's archive, e.g. <checksum>  mycluster.zip
+const CredentialsChecksumEnvVar = "CARINA_CREDENTIALS_SHA256"
+
+// remoteDirName is the directory in CARINA_HOME/clusters where the credentials fetched from CARINA_CREDENTIALS_URL are stored
+const remoteDirName = "remote"
+
+// GetRemoteSourceCommand returns the shell command and appropriate help text to load a cluster's credentials,
+// fetching the credentials from a URL when they are not already stored locally. The archive is verified against the checksum before it is used.
+func (client *Client) GetRemoteSourceCommand(shell string, name string, credentialsURL string, checksum string, customPath string) (sourceText string, err error) {
+	credentialsPath, err := client.ensureRemoteCredentials(name, credentialsURL, checksum, customPath)
+	if err != nil {
+		return "", err
+	}
+
+	shellScriptPath, err := getCredentialScriptPath(credentialsPath, shell)
+	if err != nil {
+		return "", err
+	}
+
+	return sourceHelpString(shellScriptPath, name, shell), nil
+}
+
+// ensureRemoteCredentials returns the path to a cluster's credentials, fetching them again when they are missing, invalid or expired
+func (client *Client) ensureRemoteCredentials(name string, credentialsURL string, checksum string, customPath string) (string, error) {
+	credentialsPath := customPath
+	if credentialsPath == "" {
+		clustersDir, err := getClustersDir()
+		if err != nil {
+			return "", err
+		}
+		credentialsPath = filepath.Join(clustersDir, remoteDirName, name)
+	}
+	credentialsPath = filepath.Clean(credentialsPath)
+
+	decryptedPath, err := client.decryptSessionCredentials(credentialsPath, customPath)
+	if err == errNoSessionDir {
+		return "", err
+	}
+	if err == nil && libcarina.LoadCredentialsBundle(decryptedPath).Verify() == nil {
+		cert, err := client.readCertificateInfo(decryptedPath, clientCertFilename)
+		if err == nil && !cert.ExpiresWithin(0) {
+			return decryptedPath, nil
+		}
+	}
+
+	files, err := fetchCredentialsArchive(name, credentialsURL, checksum)
+	if err != nil {
+		return "", err
+	}
+
+	err = client.saveCredentialsFiles(credentialsPath, files, customPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to use the credentials from %s", CredentialsURLEnvVar)
+	}
+
+	return client.decryptSessionCredentials(credentialsPath, customPath)
+}
+
+// fetchCredentialsArchive downloads a credentials archive, and reads the files for the cluster after verifying the checksum
+func fetchCredentialsArchive(name string, credentialsURL string, checksum string) (map[string][]byte, error) {
+	if checksum == "" {
+		return nil, fmt.Errorf("%s must be set to verify the credentials from %s", CredentialsChecksumEnvVar, CredentialsURLEnvVar)
+	}
+	checksum, err := resolveArchiveChecksum(name, checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialsURL = strings.Replace(credentialsURL, "{cluster}", url.PathEscape(name), -1)
+	common.Log.WriteDebug("Fetching the credentials for %s from %s", name, CredentialsURLEnvVar)
+	resp, err := common.NewHTTPClient().Get(credentialsURL)
+	if err != nil {
+		// Don't include the URL, it may be presigned
+		return nil, errors.Errorf("Unable to fetch the credentials from %s", CredentialsURLEnvVar)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, common.NewHTTPError(resp.StatusCode, fmt.Errorf("Unable to fetch the credentials from %s: %s", CredentialsURLEnvVar, resp.Status))
+	}
+
+	archive, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to fetch the credentials from %s", CredentialsURLEnvVar)
+	}
+
+	err = verifyChecksum(archive, checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to open the credentials archive from %s", CredentialsURLEnvVar)
+	}
+
+	archiveName, files, err := readCredentialsArchiveEntries(reader.File)
+	if err != nil {
+		return nil, err
+	}
+	if archiveName != name {
+		return nil, fmt.Errorf("The credentials from %s are for %s, not %s", CredentialsURLEnvVar, archiveName, name)
+	}
+	return files, nil
+}
+
+// resolveArchiveChecksum returns the checksum of a cluster's archive from CARINA_CREDENTIALS_SHA256, which is either the checksum
+// of a single archive, or the path to a checksum file with a line for each cluster's archive, e.g. <checksum>  mycluster.zip
+func resolveArchiveChecksum(name string, checksum string) (string, error) {
+	checksum = strings.TrimSpace(checksum)
+	if _, err := hex.DecodeString(checksum); err == nil {
+		return checksum, nil
+	}
+
+	contents, err := ioutil.ReadFile(checksum)
+	if err != nil {
+		return "", errors.Errorf("%s must be a SHA-256 checksum, or the path to a checksum file: %s", CredentialsChecksumEnvVar, err)
+	}
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks files read in binary mode with *
+		file := strings.TrimPrefix(fields[1], "*")
+		if file == name || strings.TrimSuffix(file, filepath.Ext(file)) == name {
+			return fields[0], nil
+		}
+	}
+	return "", errors.Errorf("The checksum file %s from %s doesn't have a checksum for %s", checksum, CredentialsChecksumEnvVar, name)
+}
+
+// verifyChecksum compares the SHA-256 checksum of the contents against the expected hex encoded checksum
+func verifyChecksum(contents []byte, checksum string) error {
+	sum := sha256.Sum256(contents)
+	actual := hex.EncodeToString(sum[:])
+	if !strings.EqualFold(actual, strings.TrimSpace(checksum)) {
+		return fmt.Errorf("The checksum of the credentials from %s, %s, doesn't match %s", CredentialsURLEnvVar, actual, CredentialsChecksumEnvVar)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchCredentialsArchive(t *testing.T) {
+	filename := writeTestArchive(t, map[string][]byte{
+		"mycluster/ca.pem":   []byte("ca"),
+		"mycluster/cert.pem": []byte("cert"),
+	})
+	defer os.Remove(filename)
+	archive, _ := ioutil.ReadFile(filename)
+	sum := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(sum[:])
+
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	files, err := fetchCredentialsArchive("mycluster", server.URL+"/bundles/{cluster}.zip", checksum)
+	assert.Nil(t, err)
+	assert.Equal(t, "/bundles/mycluster.zip", requested)
+	assert.Equal(t, []byte("ca"), files["ca.pem"])
+
+	_, err = fetchCredentialsArchive("mycluster", server.URL, "0000")
+	assert.Contains(t, err.Error(), "doesn't match")
+
+	_, err = fetchCredentialsArchive("mycluster", server.URL, "")
+	assert.Contains(t, err.Error(), CredentialsChecksumEnvVar)
+
+	_, err = fetchCredentialsArchive("other", server.URL, checksum)
+	assert.Contains(t, err.Error(), "are for mycluster, not other")
+
+	// A checksum file verifies the archive for each cluster
+	checksums, _ := ioutil.TempFile("", "carina-checksums")
+	defer os.Remove(checksums.Name())
+	checksums.WriteString(strings.Repeat("0", 64) + "  other.zip\n" + checksum + " *mycluster.zip\n")
+	checksums.Close()
+	files, err = fetchCredentialsArchive("mycluster", server.URL+"/bundles/{cluster}.zip", checksums.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ca"), files["ca.pem"])
+
+	_, err = fetchCredentialsArchive("missing", server.URL, checksums.Name())
+	assert.Contains(t, err.Error(), "doesn't have a checksum for missing")
+
+	_, err = fetchCredentialsArchive("my cluster", server.URL+"/bundles/{cluster}.zip", checksum)
+	assert.Equal(t, "/bundles/my cluster.zip", requested, "the cluster name should be escaped in the URL")
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+)
+
+// RepairAction is a way to recover a cluster which is in an error state
+type RepairAction string
+
+const (
+	// RepairRebuild retries creating the cluster infrastructure
+	RepairRebuild RepairAction = "rebuild"
+
+	// RepairResize shrinks the cluster, which recovers clusters that failed because of a lack of capacity
+	RepairResize RepairAction = "resize"
+
+	// RepairDelete removes the cluster, so that it can be created again
+	RepairDelete RepairAction = "delete"
+)
+
+// ClusterDiagnosis explains why a cluster is in an error state, and the actions which may recover it
+type ClusterDiagnosis struct {
+	Name   string
+	Status string
+
+	// Reason is why the cluster failed, and is empty when the API doesn't report it
+	Reason string
+
+	// Actions are the applicable recovery actions, in the order that they should be tried
+	Actions []RepairAction
+
+	// ResizeNodes is the suggested number of nodes when the cluster is resized down
+	ResizeNodes int
+}
+
+// HasAction returns if the recovery action applies to the cluster
+func (diagnosis *ClusterDiagnosis) HasAction(action RepairAction) bool {
+	for _, a := range diagnosis.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// IsErrorStatus returns if a cluster status is an error state, e.g. error on make-coe or CREATE_FAILED on magnum
+func IsErrorStatus(status string) bool {
+	status = strings.ToLower(status)
+	return status == "error" || strings.HasSuffix(status, "failed")
+}
+
+// DiagnoseCluster inspects a cluster in an error state, returning why it failed and the recovery actions which apply
+func (client *Client) DiagnoseCluster(account Account, name string) (*ClusterDiagnosis, error) {
+	cluster, err := client.GetCluster(account, name, false)
+	if err != nil {
+		return nil, err
+	}
+
+	status := cluster.GetStatus()
+	if !IsErrorStatus(status) {
+		return nil, fmt.Errorf("The cluster %s is %s, only clusters in an error state can be repaired", name, status)
+	}
+
+	diagnosis := &ClusterDiagnosis{
+		Name:   cluster.GetName(),
+		Status: status,
+		Reason: cluster.GetStatusDetails(),
+	}
+
+	// A failed delete can only be retried
+	if strings.Contains(strings.ToLower(status), "delete") {
+		diagnosis.Actions = []RepairAction{RepairDelete}
+		return diagnosis, nil
+	}
+
+	diagnosis.Actions = append(diagnosis.Actions, RepairRebuild)
+	if nodes, err := common.ParseNodeCount(cluster.GetNodes()); err == nil && nodes > 1 {
+		diagnosis.Actions = append(diagnosis.Actions, RepairResize)
+		diagnosis.ResizeNodes = nodes - 1
+	}
+	diagnosis.Actions = append(diagnosis.Actions, RepairDelete)
+
+	return diagnosis, nil
+}
@@ -0,0 +1,37 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnoseCluster(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("healthy", "Swarm*", 1)
+	service.CreateCluster("broken", "Swarm*", 3)
+	service.FailCluster("broken", "No valid host was found")
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+
+	diagnosis, err := c.DiagnoseCluster(account, "broken")
+	assert.Nil(t, err)
+	assert.Equal(t, "No valid host was found", diagnosis.Reason)
+	assert.Equal(t, []client.RepairAction{client.RepairRebuild, client.RepairResize, client.RepairDelete}, diagnosis.Actions)
+	assert.Equal(t, 2, diagnosis.ResizeNodes)
+
+	_, err = c.DiagnoseCluster(account, "healthy")
+	assert.NotNil(t, err, "Only clusters in an error state can be repaired")
+}
+
+func TestIsErrorStatus(t *testing.T) {
+	assert.True(t, client.IsErrorStatus("error"))
+	assert.True(t, client.IsErrorStatus("CREATE_FAILED"))
+	assert.False(t, client.IsErrorStatus("active"))
+	assert.False(t, client.IsErrorStatus("CREATE_IN_PROGRESS"))
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/getcarina/carina/common"
+)
+
+// resizeVerifyAttempts is how many times an active cluster is retrieved after a resize,
+// waiting for the reported number of nodes to catch up, before the resize is considered to have failed
+const resizeVerifyAttempts = 3
+
+// resizeVerifyInterval is how often the number of nodes is checked after a resize
+const resizeVerifyInterval = 5 * time.Second
+
+// verifyClusterNodes checks that a resized cluster reports the requested number of nodes,
+// because the API can report a resize as complete without changing the cluster
+func verifyClusterNodes(svc common.ClusterService, cluster common.Cluster, nodes int) (common.Cluster, error) {
+	var waiter *common.Waiter
+	for attempt := 1; ; attempt++ {
+		actual, err := common.ParseNodeCount(cluster.GetNodes())
+		if err == nil && actual == nodes {
+			return cluster, nil
+		}
+		if attempt >= resizeVerifyAttempts {
+			break
+		}
+
+		common.Log.WriteDebug("The cluster (%s) reports %s nodes instead of %d, checking again (attempt %d of %d)", cluster.GetName(), cluster.GetNodes(), nodes, attempt+1, resizeVerifyAttempts)
+		if waiter == nil {
+			waiter = common.NewWaiter(resizeVerifyInterval, "Waiting for cluster (%s) to report %d nodes", cluster.GetName(), nodes)
+		}
+		waiter.Update(fmt.Sprintf("%s nodes", cluster.GetNodes()))
+		err = waiter.Wait()
+		if err != nil {
+			return cluster, err
+		}
+
+		cluster, err = svc.GetCluster(cluster.GetID())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cluster, fmt.Errorf("The resize of cluster (%s) did not take effect: requested %d nodes but the cluster reports %s nodes and is %s", cluster.GetName(), nodes, cluster.GetNodes(), cluster.GetStatus())
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/common"
+)
+
+// PendingOperation is a wait on a cluster which hasn't finished, persisted in the cache so that it can be continued
+// with carina resume when the cli is interrupted, or the wait times out
+type PendingOperation struct {
+	// Operation is what the cli was waiting for, e.g. create, resize or delete
+	Operation   string `json:"operation"`
+	ClusterID   string `json:"cluster-id"`
+	ClusterName string `json:"cluster-name"`
+
+	// WaitFor is the state the cluster is expected to reach, WaitForActive or WaitForDeleted
+	WaitFor string `json:"wait-for"`
+
+	Started time.Time `json:"started"`
+}
+
+// String describes the operation, e.g. create of mycluster
+func (op PendingOperation) String() string {
+	return fmt.Sprintf("%s of %s", op.Operation, op.ClusterName)
+}
+
+// trackPendingWait persists a wait on a cluster, so that it can be continued with carina resume if the cli is interrupted.
+// The returned function is called with the result of the wait, and forgets the wait once it has finished.
+func (client *Client) trackPendingWait(account Account, operation string, cluster common.Cluster, waitFor string) (finish func(err error)) {
+	if cluster == nil || cluster.GetID() == "" {
+		return func(error) {}
+	}
+
+	op := PendingOperation{
+		Operation:   operation,
+		ClusterID:   cluster.GetID(),
+		ClusterName: cluster.GetName(),
+		WaitFor:     waitFor,
+		Started:     time.Now(),
+	}
+	err := client.Cache.savePendingOperation(account, op)
+	if err != nil {
+		common.Log.WriteDebug("Unable to save the %s so that it can be resumed: %s", op, err)
+	}
+
+	client.operationsLock.Lock()
+	client.pendingWaits++
+	client.operationsLock.Unlock()
+
+	return func(err error) {
+		client.operationsLock.Lock()
+		client.pendingWaits--
+		client.operationsLock.Unlock()
+
+		// Keep the operation when the wait was interrupted or timed out, the cluster hasn't settled yet
+		if err == nil {
+			client.Cache.deletePendingOperation(account, op.ClusterID)
+		}
+	}
+}
+
+// ListPendingOperations returns the waits which were interrupted before the cluster settled, oldest first
+func (client *Client) ListPendingOperations(account Account) []PendingOperation {
+	prefix := clusterCacheKey(account, "")
+
+	client.Cache.ensureLoaded()
+	var pending []PendingOperation
+	for key, op := range client.Cache.Pending {
+		if strings.HasPrefix(key, prefix) {
+			pending = append(pending, op)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Started.Before(pending[j].Started)
+	})
+	return pending
+}
+
+// ResumeOperation continues waiting for an interrupted operation, returning the cluster once it settles,
+// or nil when the cluster was deleted. The operation is forgotten once the cluster has settled.
+func (client *Client) ResumeOperation(account Account, op PendingOperation) (common.Cluster, error) {
+	common.Log.WriteDebug("Resuming the %s, started at %s", op, op.Started)
+	cluster, err := client.WaitForCluster(account, op.ClusterID, op.WaitFor)
+	if err != nil && isNotFound(err) {
+		// The cluster is gone, e.g. deleted after a failed create, so there's nothing left to wait for
+		client.Cache.deletePendingOperation(account, op.ClusterID)
+	}
+	return cluster, err
+}
+
+// savePendingOperation persists a wait which hasn't finished
+func (cache *Cache) savePendingOperation(account Account, op PendingOperation) error {
+	return cache.safeUpdate(func(c *Cache) {
+		if c.Pending == nil {
+			c.Pending = make(map[string]PendingOperation)
+		}
+		c.Pending[clusterCacheKey(account, op.ClusterID)] = op
+	})
+}
+
+// deletePendingOperation forgets the wait on a cluster, by its id or name
+func (cache *Cache) deletePendingOperation(account Account, token string) error {
+	prefix := clusterCacheKey(account, "")
+	return cache.safeUpdate(func(c *Cache) {
+		for key, op := range c.Pending {
+			if strings.HasPrefix(key, prefix) && (op.ClusterID == token || op.ClusterName == token) {
+				delete(c.Pending, key)
+			}
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeOperation(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-resume")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+
+	client := &Client{Cache: newCache(filepath.Join(home, "cache.json"))}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	service.PendingPolls = 2
+	account := &historyAccount{offlineAccount{service: service}}
+
+	_, err = client.CreateCluster(account, "web", "Kubernetes 1.5.2 on LXC", 1, CreateClusterOptions{WaitUntilActive: true})
+	require.NoError(t, err)
+	assert.Empty(t, client.ListPendingOperations(account), "a wait which finished should be forgotten")
+
+	cluster, err := client.CreateCluster(account, "api", "Kubernetes 1.5.2 on LXC", 1, CreateClusterOptions{})
+	require.NoError(t, err)
+	finishWait := client.trackPendingWait(account, "create", cluster, WaitForActive)
+	finishWait(common.ErrShuttingDown)
+
+	pending := client.ListPendingOperations(account)
+	require.Len(t, pending, 1, "an interrupted wait should be remembered")
+	assert.Equal(t, "create of api", pending[0].String())
+
+	cluster, err = client.ResumeOperation(account, pending[0])
+	require.NoError(t, err)
+	assert.Equal(t, testsupport.StatusActive, cluster.GetStatus())
+	assert.Empty(t, client.ListPendingOperations(account))
+}
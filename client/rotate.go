@@ -0,0 +1,102 @@
+package client
+
+import (
+	"time"
+
+	"github.com/getcarina/carina/common"
+)
+
+// rotateCAActionName is the cluster action which replaces a cluster's certificate authority, on the clouds which support it
+const rotateCAActionName = "rotate-ca"
+
+// CredentialsRotation is the result of rotating a cluster's credentials
+type CredentialsRotation struct {
+	Name string
+
+	// CredentialsPath is where the new credentials were saved
+	CredentialsPath string
+
+	// CARotated is set when the cloud issued a new certificate authority, which invalidates every copy of the previous credentials.
+	// Otherwise only new client certificates were downloaded, and the previous credentials remain valid until they expire.
+	CARotated bool
+
+	// Kubeconfig is the kubeconfig whose entry for the cluster was updated, if any
+	Kubeconfig string
+
+	// Expires is when the new client certificate expires, and is zero when it couldn't be read
+	Expires time.Time
+}
+
+// RotateClusterCredentials requests new certificates for a cluster, when the cloud supports it, then downloads the new credentials,
+// replacing the existing files only once every file has been downloaded, and updates the cluster's entry in the default kubeconfig.
+func (client *Client) RotateClusterCredentials(account Account, name string, customPath string) (*CredentialsRotation, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	defer client.endOperation(client.startOperation(name, "Rotate credentials for cluster (%s)", name))
+
+	result := &CredentialsRotation{Name: name}
+	if _, err = common.LookupClusterAction(svc.ListActions(), rotateCAActionName); err == nil {
+		err = client.rotateClusterCA(svc, account, name)
+		if err != nil {
+			return nil, err
+		}
+		result.CARotated = true
+	} else {
+		common.Log.WriteWarning("The cloud can't issue a new certificate authority, so the previous credentials for %s remain valid until they expire. Downloading new credentials anyway", name)
+	}
+
+	result.CredentialsPath, err = client.downloadClusterCredentials(svc, account, name, customPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if cert, err := client.readCertificateInfo(result.CredentialsPath, clientCertFilename); err == nil {
+		result.Expires = cert.NotAfter
+	}
+
+	// Replace the decrypted copy used by docker and kubectl too. Without XDG_RUNTIME_DIR there is no decrypted copy, or kubeconfig entry, to replace.
+	runtimePath, err := client.decryptSessionCredentials(result.CredentialsPath, customPath)
+	if err == errNoSessionDir {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if customPath == "" {
+		result.Kubeconfig, err = client.refreshKubeconfigEntry(name, runtimePath)
+		if err != nil {
+			common.Log.WriteWarning("Unable to update the kubeconfig entry for %s: %s", name, err)
+		}
+	}
+
+	return result, nil
+}
+
+// rotateClusterCA replaces the certificate authority of a cluster, then waits for the cluster to become active again
+func (client *Client) rotateClusterCA(svc common.ClusterService, account Account, name string) error {
+	unlock, err := client.lockCluster(account, name, rotateCAActionName)
+	if err != nil {
+		return wrapClusterError(name, err)
+	}
+	defer unlock()
+
+	cluster, err := svc.InvokeAction(name, rotateCAActionName, nil)
+	if err != nil {
+		return wrapClusterError(name, err)
+	}
+	client.recordClusterStatus(account, rotateCAActionName, cluster)
+
+	defer client.watchClusterStatus(account, cluster, nil)()
+	cluster, err = svc.WaitUntilClusterIsActive(cluster)
+	if err != nil {
+		return wrapClusterError(name, err)
+	}
+	client.recordClusterStatus(account, "wait", cluster)
+
+	return nil
+}
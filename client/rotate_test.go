@@ -0,0 +1,44 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateClusterCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+	client := &Client{Cache: &Cache{}}
+
+	cluster, err := service.CreateCluster("prod", "Kubernetes 1.5.2 on LXC", 1)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cert.pem"), []byte("leaked-cert"), 0600))
+
+	result, err := client.RotateClusterCredentials(account, "prod", dir)
+	require.NoError(t, err)
+	assert.False(t, result.CARotated, "The cloud doesn't support rotate-ca")
+	assert.Empty(t, service.Invocations)
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "cert.pem"))
+	require.NoError(t, err)
+	assert.Equal(t, "fake-cert", string(contents))
+	staged, _ := filepath.Glob(filepath.Join(dir, "*"+stagedCredentialsSuffix))
+	assert.Empty(t, staged, "The staged files should be renamed into place")
+
+	service.Actions = []common.ClusterAction{{Name: "rotate-ca", Destructive: true}}
+	result, err = client.RotateClusterCredentials(account, "prod", dir)
+	require.NoError(t, err)
+	assert.True(t, result.CARotated)
+	assert.Equal(t, []testsupport.FakeInvocation{{ClusterID: cluster.GetID(), Action: "rotate-ca"}}, service.Invocations)
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// CarinaNewPassphraseEnvVar is the passphrase which carina cache rotate-key re-encrypts with, when credentials.encryption is passphrase
+const CarinaNewPassphraseEnvVar = "CARINA_NEW_PASSPHRASE"
+
+// KeyRotation is the result of re-encrypting CARINA_HOME with a new key
+type KeyRotation struct {
+	// Mode is the credentials.encryption setting
+	Mode string
+
+	// Files are the re-encrypted files, relative to CARINA_HOME
+	Files []string
+}
+
+// RotateEncryptionKey re-encrypts the token cache and the credentials in CARINA_HOME with a new key, upgrading the cache
+// to the current schema version. With keychain encryption, a new random key is generated and saved to the keychain.
+// With passphrase encryption, the files are re-encrypted with the passphrase in CARINA_NEW_PASSPHRASE, which must then
+// replace CARINA_PASSPHRASE. Every file is decrypted before any is replaced, so a file which can't be decrypted leaves them untouched.
+func (client *Client) RotateEncryptionKey() (*KeyRotation, error) {
+	if client.cipher == nil {
+		return nil, errors.New("The files in CARINA_HOME are not encrypted. Set credentials.encryption to passphrase or keychain first")
+	}
+
+	home, err := GetCredentialsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var newCipher fileCipher
+	commit := func() error { return nil }
+	switch client.cipher.mode() {
+	case EncryptionKeychain:
+		key, err := generateEncryptionKey()
+		if err != nil {
+			return nil, err
+		}
+		newCipher = &keyCipher{key: key}
+		commit = func() error {
+			err := newSystemKeyring().Set(keyringService, keyringUser, hex.EncodeToString(key))
+			return errors.Wrap(err, "Unable to save the new credentials encryption key to the keychain")
+		}
+	case EncryptionPassphrase:
+		passphrase := os.Getenv(CarinaNewPassphraseEnvVar)
+		if passphrase == "" {
+			return nil, errors.Errorf("The %s environment variable is required to rotate the key when credentials.encryption is %s", CarinaNewPassphraseEnvVar, EncryptionPassphrase)
+		}
+		newCipher = &passphraseCipher{passphrase: []byte(passphrase), keys: make(map[string][]byte)}
+	}
+
+	// Keep other carina processes from writing the cache with the old key while it is re-encrypted
+	if !client.Cache.isNil() {
+		unlock, err := client.Cache.lockFile()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	files, err := reencryptFiles(home, client.cipher, newCipher, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	client.cipher = newCipher
+	client.Cache.cipher = newCipher
+	return &KeyRotation{Mode: newCipher.mode(), Files: files}, nil
+}
+
+// reencryptFiles re-encrypts every encrypted file in a directory, and its subdirectories, with a new cipher, returning the relative paths.
+// The re-encrypted files are staged next to the originals, and only replace them once commit succeeds, e.g. saving the new key.
+func reencryptFiles(dir string, oldCipher fileCipher, newCipher fileCipher, commit func() error) ([]string, error) {
+	var paths []string
+	staged := make(map[string]string)
+	defer func() {
+		for _, tmpPath := range staged {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !isEncrypted(contents) {
+			return nil
+		}
+
+		contents, err = oldCipher.decrypt(contents)
+		if err == nil {
+			contents, err = migrateEncryptedContents(path, contents)
+		}
+		if err == nil {
+			contents, err = newCipher.encrypt(contents)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Unable to re-encrypt %s", path)
+		}
+
+		tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+		if err != nil {
+			return err
+		}
+		staged[path] = tmp.Name()
+		_, err = tmp.Write(contents)
+		closeErr := tmp.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Unable to re-encrypt %s", path)
+		}
+
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = commit()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, path := range paths {
+		err = os.Rename(staged[path], path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to replace %s with the re-encrypted file", path)
+		}
+		delete(staged, path)
+		restrictAccess(path)
+
+		relPath, _ := filepath.Rel(dir, path)
+		common.Log.WriteDebug("Re-encrypted %s", relPath)
+		files = append(files, relPath)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// migrateEncryptedContents upgrades the cache to the current schema version while it is decrypted, leaving other files as-is
+func migrateEncryptedContents(path string, contents []byte) ([]byte, error) {
+	if filepath.Base(path) != "cache.json" {
+		return contents, nil
+	}
+	return migrateCacheContents(contents)
+}
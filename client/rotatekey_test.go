@@ -0,0 +1,62 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReencryptFiles(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-rotate-key")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+
+	oldCipher := &keyCipher{key: make([]byte, 32)}
+	newCipher := &keyCipher{key: []byte("0123456789abcdef0123456789abcdef")}
+
+	writeEncrypted := func(path string, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		encrypted, err := oldCipher.encrypt([]byte(contents))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(path, encrypted, 0600))
+	}
+	writeEncrypted(filepath.Join(home, "cache.json"), `{"last-update-check":"2017-01-02T00:00:00Z"}`)
+	writeEncrypted(filepath.Join(home, "clusters", "public", "mycluster", "ca.pem"), "ca")
+	plainPath := filepath.Join(home, "config.toml")
+	require.NoError(t, ioutil.WriteFile(plainPath, []byte("plain"), 0600))
+
+	// Nothing is replaced when the new key can't be saved
+	_, err = reencryptFiles(home, oldCipher, newCipher, func() error { return errors.New("keychain locked") })
+	require.Error(t, err)
+	contents, _ := ioutil.ReadFile(filepath.Join(home, "clusters", "public", "mycluster", "ca.pem"))
+	contents, err = oldCipher.decrypt(contents)
+	require.NoError(t, err)
+	assert.Equal(t, "ca", string(contents))
+
+	files, err := reencryptFiles(home, oldCipher, newCipher, func() error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cache.json", filepath.Join("clusters", "public", "mycluster", "ca.pem")}, files)
+
+	contents, _ = ioutil.ReadFile(filepath.Join(home, "clusters", "public", "mycluster", "ca.pem"))
+	_, err = oldCipher.decrypt(contents)
+	assert.Error(t, err, "The files should no longer be readable with the old key")
+	contents, err = newCipher.decrypt(contents)
+	require.NoError(t, err)
+	assert.Equal(t, "ca", string(contents))
+
+	cache := newCache(filepath.Join(home, "cache.json"))
+	cache.cipher = newCipher
+	require.NoError(t, cache.load())
+	assert.Equal(t, cacheSchemaVersion, cache.SchemaVersion, "The cache should be upgraded while it is re-encrypted")
+
+	contents, _ = ioutil.ReadFile(plainPath)
+	assert.Equal(t, "plain", string(contents), "Files which aren't encrypted should be left alone")
+
+	entries, _ := ioutil.ReadDir(home)
+	assert.Len(t, entries, 3, "The staged files should be cleaned up")
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// scriptVariable is an environment variable exported by a credentials bundle script
+type scriptVariable struct {
+	Name  string
+	Value string
+}
+
+// parseBashScript reads the environment variables exported by a credentials bundle's bash script.
+// The bundles locate their directory with a command substitution, e.g. $(cd "$(dirname "${BASH_SOURCE[0]}")"; pwd),
+// which is replaced with the directory containing the script.
+func parseBashScript(contents []byte, scriptDir string) ([]scriptVariable, error) {
+	var variables []scriptVariable
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "export ") {
+			return nil, fmt.Errorf("Unable to convert the bash script, unsupported line: %s", line)
+		}
+
+		assignment := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "export ")), "=", 2)
+		if len(assignment) != 2 {
+			return nil, fmt.Errorf("Unable to convert the bash script, unsupported line: %s", line)
+		}
+
+		value, err := expandScriptValue(assignment[1], scriptDir)
+		if err != nil {
+			return nil, err
+		}
+		variables = append(variables, scriptVariable{Name: assignment[0], Value: value})
+	}
+
+	return variables, scanner.Err()
+}
+
+// expandScriptValue removes the quotes around a value, and replaces a command substitution with the script's directory
+func expandScriptValue(value string, scriptDir string) (string, error) {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+
+	if start := strings.Index(value, "$("); start >= 0 {
+		end := findClosingParen(value, start+2)
+		if end < 0 {
+			return "", fmt.Errorf("Unable to convert the bash script, unsupported value: %s", value)
+		}
+		value = value[:start] + scriptDir + filepath.FromSlash(value[end+1:])
+	}
+
+	if strings.ContainsAny(value, "$`") {
+		return "", fmt.Errorf("Unable to convert the bash script, unsupported value: %s", value)
+	}
+	return value, nil
+}
+
+// findClosingParen returns the index of the parenthesis which closes a command substitution, or -1 when it isn't closed
+func findClosingParen(value string, start int) int {
+	depth := 1
+	for i := start; i < len(value); i++ {
+		switch value[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// buildPowerShellScript generates a PowerShell script which sets the environment variables
+func buildPowerShellScript(variables []scriptVariable) string {
+	var script bytes.Buffer
+	for _, variable := range variables {
+		fmt.Fprintf(&script, "$env:%s='%s'\r\n", variable.Name, strings.Replace(variable.Value, "'", "''", -1))
+	}
+	return script.String()
+}
+
+// buildCmdScript generates a cmd.exe batch file which sets the environment variables
+func buildCmdScript(variables []scriptVariable) string {
+	var script bytes.Buffer
+	script.WriteString("@ECHO OFF\r\n")
+	for _, variable := range variables {
+		// Quoting the whole assignment handles special characters, except for %, which is escaped by doubling it
+		fmt.Fprintf(&script, "SET \"%s=%s\"\r\n", variable.Name, strings.Replace(variable.Value, "%", "%%", -1))
+	}
+	return script.String()
+}
+
+// generateCredentialScript writes a PowerShell or cmd.exe script to the credentials bundle,
+// converted from the bundle's bash script, for bundles which only include the bash script
+func generateCredentialScript(basepath string, scriptPrefix string, shell string, scriptPath string) error {
+	contents, err := ioutil.ReadFile(filepath.Join(basepath, scriptPrefix+".env"))
+	if err != nil {
+		return errors.Wrap(err, "Unable to read the bash script from the credentials bundle")
+	}
+
+	variables, err := parseBashScript(contents, basepath)
+	if err != nil {
+		return err
+	}
+
+	var script string
+	switch shell {
+	case "powershell":
+		script = buildPowerShellScript(variables)
+	case "cmd":
+		script = buildCmdScript(variables)
+	default:
+		return fmt.Errorf("Unable to generate a %s script", shell)
+	}
+
+	common.Log.WriteDebug("Generating %s from the bash script in the credentials bundle", scriptPath)
+	err = ioutil.WriteFile(scriptPath, []byte(script), 0600)
+	return errors.Wrapf(err, "Unable to write %s", scriptPath)
+}
+
+// ensureCredentialScript generates the PowerShell or cmd.exe script when the credentials bundle doesn't include it
+func ensureCredentialScript(basepath string, scriptPrefix string, shell string, scriptPath string) error {
+	if shell != "powershell" && shell != "cmd" {
+		return nil
+	}
+	if _, err := os.Stat(scriptPath); !os.IsNotExist(err) {
+		return nil
+	}
+	return generateCredentialScript(basepath, scriptPrefix, shell, scriptPath)
+}
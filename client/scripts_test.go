@@ -0,0 +1,71 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testBashScript = `# Docker environment
+export DOCKER_HOST=tcp://172.99.65.1:2376
+export DOCKER_TLS_VERIFY=1
+export DOCKER_CERT_PATH=$(cd "$(dirname "${BASH_SOURCE[0]}")"; pwd)
+export KUBECONFIG="$(cd "$(dirname "${BASH_SOURCE[0]}")"; pwd)/kubectl.config"
+`
+
+func TestParseBashScript(t *testing.T) {
+	dir := filepath.Join("clusters", "mycluster")
+	variables, err := parseBashScript([]byte(testBashScript), dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []scriptVariable{
+		{Name: "DOCKER_HOST", Value: "tcp://172.99.65.1:2376"},
+		{Name: "DOCKER_TLS_VERIFY", Value: "1"},
+		{Name: "DOCKER_CERT_PATH", Value: dir},
+		{Name: "KUBECONFIG", Value: filepath.Join(dir, "kubectl.config")},
+	}, variables)
+
+	_, err = parseBashScript([]byte("docker-machine env"), dir)
+	assert.NotNil(t, err, "Only exported variables are supported")
+
+	_, err = parseBashScript([]byte("export DOCKER_HOST=$HOST"), dir)
+	assert.NotNil(t, err, "Variable references are not supported")
+}
+
+func TestBuildWindowsScripts(t *testing.T) {
+	variables := []scriptVariable{
+		{Name: "DOCKER_CERT_PATH", Value: `C:\Users\O'Brien\100%\mycluster`},
+	}
+
+	assert.Equal(t, "$env:DOCKER_CERT_PATH='C:\\Users\\O''Brien\\100%\\mycluster'\r\n", buildPowerShellScript(variables))
+	assert.Equal(t, "@ECHO OFF\r\nSET \"DOCKER_CERT_PATH=C:\\Users\\O'Brien\\100%%\\mycluster\"\r\n", buildCmdScript(variables))
+}
+
+func TestGetCredentialScriptPathGeneratesWindowsScripts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-scripts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "docker.env"), []byte(testBashScript), 0600))
+
+	scriptPath, err := getCredentialScriptPath(dir, "powershell")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "docker.ps1"), scriptPath)
+	script, _ := ioutil.ReadFile(scriptPath)
+	assert.Contains(t, string(script), "$env:DOCKER_HOST='tcp://172.99.65.1:2376'")
+
+	scriptPath, err = getCredentialScriptPath(dir, "cmd")
+	assert.Nil(t, err)
+	script, _ = ioutil.ReadFile(scriptPath)
+	assert.Contains(t, string(script), `SET "DOCKER_CERT_PATH=`+dir+`"`)
+
+	// Scripts included in the bundle are used as-is
+	assert.Nil(t, ioutil.WriteFile(scriptPath, []byte("REM from the bundle"), 0600))
+	scriptPath, err = getCredentialScriptPath(dir, "cmd")
+	assert.Nil(t, err)
+	script, _ = ioutil.ReadFile(scriptPath)
+	assert.Equal(t, "REM from the bundle", string(script))
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// AuthSourceEnv reads the API key or password from flags, environment variables and profiles
+	AuthSourceEnv = "env"
+
+	// AuthSourceKeychain reads the API key or password from the operating system's keychain
+	AuthSourceKeychain = "keychain"
+)
+
+// authKeyringService identifies the API keys and passwords saved in the keychain
+const authKeyringService = "carina-auth"
+
+// ValidateAuthSource checks the --auth-source flag
+func ValidateAuthSource(source string) error {
+	switch source {
+	case "", AuthSourceEnv, AuthSourceKeychain:
+		return nil
+	default:
+		return fmt.Errorf("Invalid --auth-source: %s. Allowed values: %s, %s", source, AuthSourceEnv, AuthSourceKeychain)
+	}
+}
+
+// SecretsProvider stores the secret used to authenticate an account, i.e. the API key or password
+type SecretsProvider interface {
+	// GetSecret retrieves the secret for a user on a cloud type, e.g. public or private
+	GetSecret(cloud string, username string) (string, error)
+
+	// SetSecret saves the secret for a user on a cloud type, replacing any existing value
+	SetSecret(cloud string, username string, secret string) error
+}
+
+// KeychainSecretsProvider stores secrets in the operating system's keychain:
+// Keychain Access on macOS, Credential Manager on Windows and the Secret Service (libsecret) on Linux
+type KeychainSecretsProvider struct {
+	Keyring Keyring
+}
+
+// NewKeychainSecretsProvider builds a SecretsProvider backed by the operating system's keychain
+func NewKeychainSecretsProvider() *KeychainSecretsProvider {
+	return &KeychainSecretsProvider{Keyring: newSystemKeyring()}
+}
+
+// GetSecret retrieves the secret for a user from the keychain
+func (provider *KeychainSecretsProvider) GetSecret(cloud string, username string) (string, error) {
+	secret, err := provider.Keyring.Get(authKeyringService, keychainAccountName(cloud, username))
+	if err == ErrKeyringItemNotFound {
+		return "", fmt.Errorf("No secret for %s was found in the keychain. Save it with carina keychain store --cloud %s %s", username, cloud, username)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to retrieve the secret for %s from the keychain", username)
+	}
+	return secret, nil
+}
+
+// SetSecret saves the secret for a user to the keychain
+func (provider *KeychainSecretsProvider) SetSecret(cloud string, username string, secret string) error {
+	err := provider.Keyring.Set(authKeyringService, keychainAccountName(cloud, username), secret)
+	return errors.Wrapf(err, "Unable to save the secret for %s to the keychain", username)
+}
+
+// keychainAccountName identifies a user's secret in the keychain.
+// The cloud type is included because the public and private clouds have separate users.
+func keychainAccountName(cloud string, username string) string {
+	return cloud + ":" + username
+}
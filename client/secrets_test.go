@@ -0,0 +1,23 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeychainSecretsProvider(t *testing.T) {
+	provider := &KeychainSecretsProvider{Keyring: &stubKeyring{secrets: make(map[string]string)}}
+
+	err := provider.SetSecret("public", "alice", "my-apikey")
+	assert.NoError(t, err)
+
+	secret, err := provider.GetSecret("public", "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "my-apikey", secret)
+
+	_, err = provider.GetSecret("private", "alice")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "carina keychain store --cloud private alice")
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// SharedHomeEnvVar is the environment variable which enables sharing CARINA_HOME between multiple users or jobs, e.g. on a build server
+const SharedHomeEnvVar = "CARINA_SHARED_HOME"
+
+// sharedHomeDirName is the directory in CARINA_HOME holding a namespace for each account, when CARINA_HOME is shared
+const sharedHomeDirName = "shared"
+
+// sharedHomeMode lets every user create their own namespace in a shared directory, without being able to remove anyone else's, like /tmp
+const sharedHomeMode = 0777 | os.ModeSticky
+
+// SharedHomeEnabled returns if CARINA_HOME is shared between users, see SharedHomeEnvVar
+func SharedHomeEnabled() bool {
+	shared, _ := strconv.ParseBool(os.Getenv(SharedHomeEnvVar))
+	return shared
+}
+
+// sharedNamespaceName returns the name of the directory holding the cache and credentials for a key, such as an account id.
+// The key is hashed, so that other users of a shared CARINA_HOME can't see which accounts are used.
+func sharedNamespaceName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return fmt.Sprintf("%x", hash[:8])
+}
+
+// userNamespaceKey identifies the namespace used by the current user before an account is selected, e.g. by carina version
+func userNamespaceKey() string {
+	return "user:" + currentUserID()
+}
+
+// accountNamespaceKey identifies the namespace of an account, for the current user, so that several users on a build server
+// can use the same service account without sharing a namespace. The API endpoint, when overridden, is included
+// so that a staging environment or API mock doesn't share the production tokens.
+func accountNamespaceKey(account Account, endpoint string, userID string) string {
+	return fmt.Sprintf("account:%s|endpoint:%s|user:%s", account.GetID(), endpoint, userID)
+}
+
+// openSharedNamespace creates the namespace for a key in the shared CARINA_HOME, and checks that only the current user can access it.
+// A namespace which belongs to another user, or which other users can modify, is never used.
+func openSharedNamespace(key string) (string, error) {
+	home, err := GetCredentialsDir()
+	if err != nil {
+		return "", err
+	}
+
+	sharedDir := filepath.Join(home, sharedHomeDirName)
+	err = createSharedDir(sharedDir)
+	if err != nil {
+		return "", err
+	}
+
+	namespace := filepath.Join(sharedDir, sharedNamespaceName(key))
+	err = os.Mkdir(namespace, 0700)
+	switch {
+	case err == nil:
+		common.Log.WriteDebug("Created the shared namespace %s", namespace)
+		restrictAccess(namespace)
+	case !os.IsExist(err):
+		return "", errors.Wrapf(err, "Unable to create the shared namespace %s", namespace)
+	}
+
+	err = checkNamespaceOwner(namespace)
+	if err != nil {
+		return "", err
+	}
+	return namespace, nil
+}
+
+// createSharedDir creates the directory holding the namespaces, so that every user can add their own namespace to it
+func createSharedDir(sharedDir string) error {
+	err := os.MkdirAll(filepath.Dir(sharedDir), 0777)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to create %s", filepath.Dir(sharedDir))
+	}
+
+	err = os.Mkdir(sharedDir, 0777)
+	if os.IsExist(err) {
+		checkSharedDirMode(sharedDir)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "Unable to create %s", sharedDir)
+	}
+
+	// Set the mode explicitly, because the umask usually removes write access for other users
+	err = os.Chmod(sharedDir, sharedHomeMode)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to allow other users to write to %s", sharedDir)
+	}
+	return nil
+}
+
+// UseSharedHome moves the cache, audit log, cluster locks and credentials into the account's namespace, when CARINA_HOME is shared,
+// so that users and jobs sharing CARINA_HOME don't overwrite each other's tokens and credentials.
+// Must be called before the cache is used. The credentials are only moved when credentials.path isn't set.
+// endpoint is the API endpoint override, e.g. --endpoint, if any.
+func (client *Client) UseSharedHome(account Account, endpoint string) error {
+	if !SharedHomeEnabled() {
+		return nil
+	}
+
+	namespace, err := openSharedNamespace(accountNamespaceKey(account, endpoint, currentUserID()))
+	if err != nil {
+		return err
+	}
+	common.Log.WriteDebug("Using the shared namespace %s for %s", namespace, account.GetID())
+
+	client.lockHome = namespace
+
+	if client.Cache.path != "" {
+		client.Cache.path = filepath.Join(namespace, filepath.Base(client.Cache.path))
+		client.Cache.home = namespace
+		client.AuditLog = filepath.Join(namespace, auditLogFilename)
+	}
+	if CredentialsStoragePolicy.Dir == "" {
+		CredentialsStoragePolicy.Dir = filepath.Join(namespace, clusterDirName)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// +build !windows
+
+package client
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/getcarina/carina/common"
+)
+
+// currentUserID identifies the current OS user, which owns their namespaces in the shared CARINA_HOME
+func currentUserID() string {
+	return strconv.Itoa(os.Getuid())
+}
+
+// checkNamespaceOwner verifies that a namespace in the shared CARINA_HOME belongs to the current user, and that other users can't access it
+func checkNamespaceOwner(namespace string) error {
+	info, err := os.Lstat(namespace)
+	if err != nil {
+		return fmt.Errorf("Unable to access the shared namespace %s: %s", namespace, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("The shared namespace %s is not a directory. Remove it, it may have been created by another user to intercept your credentials", namespace)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("The shared namespace %s belongs to another user (uid %d). Use a different CARINA_HOME, or ask the owner to remove it", namespace, stat.Uid)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Errorf("The shared namespace %s can be accessed by other users (%#o). Run chmod 700 %s", namespace, perm, namespace)
+	}
+	return nil
+}
+
+// checkSharedDirMode warns when other users can remove or replace the namespaces in the shared directory
+func checkSharedDirMode(sharedDir string) {
+	info, err := os.Stat(sharedDir)
+	if err != nil {
+		return
+	}
+
+	if info.Mode().Perm()&0022 != 0 && info.Mode()&os.ModeSticky == 0 {
+		common.Log.WriteWarning("WARNING: Other users can remove or replace the namespaces in %s. Run chmod +t %s", sharedDir, sharedDir)
+	}
+}
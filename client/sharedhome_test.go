@@ -0,0 +1,91 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseSharedHome(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-shared")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	os.Setenv(CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(CarinaHomeDirEnvVar)
+	defer func() { CredentialsStoragePolicy.Dir = "" }()
+
+	client := NewClient(true)
+	require.NoError(t, client.UseSharedHome(&stubAccount{}, ""))
+	assert.Equal(t, filepath.Join(home, "cache.json"), client.Cache.path, "CARINA_HOME isn't shared by default")
+	assert.Empty(t, CredentialsStoragePolicy.Dir)
+
+	os.Setenv(SharedHomeEnvVar, "true")
+	defer os.Unsetenv(SharedHomeEnvVar)
+
+	client = NewClient(true)
+	userNamespace := filepath.Dir(client.Cache.path)
+	assert.Equal(t, filepath.Join(home, sharedHomeDirName), filepath.Dir(userNamespace), "The cache should be in the user's namespace until an account is selected")
+
+	require.NoError(t, client.UseSharedHome(&stubAccount{}, ""))
+	namespace := filepath.Join(home, sharedHomeDirName, sharedNamespaceName(accountNamespaceKey(&stubAccount{}, "", currentUserID())))
+	assert.Equal(t, filepath.Join(namespace, "cache.json"), client.Cache.path)
+	assert.Equal(t, filepath.Join(namespace, auditLogFilename), client.AuditLog)
+	assert.Equal(t, filepath.Join(namespace, clusterDirName), CredentialsStoragePolicy.Dir)
+	assert.NotEqual(t, userNamespace, namespace)
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	info, err := os.Stat(namespace)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+	info, err = os.Stat(filepath.Join(home, sharedHomeDirName))
+	require.NoError(t, err)
+	assert.True(t, info.Mode()&os.ModeSticky != 0, "Other users should be able to create their namespace, but not remove someone else's")
+
+	require.NoError(t, os.Chmod(namespace, 0770))
+	err = client.UseSharedHome(&stubAccount{}, "")
+	require.Error(t, err, "A namespace which other users can access should be rejected")
+	assert.Contains(t, err.Error(), "chmod 700")
+}
+
+func TestAccountNamespaceKey(t *testing.T) {
+	key := accountNamespaceKey(&stubAccount{}, "", "1000")
+	assert.NotEqual(t, key, accountNamespaceKey(&stubAccount{}, "", "1001"), "Each user should have their own namespace for a shared service account")
+	assert.NotEqual(t, key, accountNamespaceKey(&stubAccount{}, "https://api.staging.example.com", "1000"), "An overridden endpoint should have its own namespace")
+	assert.Equal(t, key, accountNamespaceKey(&stubAccount{}, "", "1000"))
+}
+
+func TestUseSharedHomeWithAnotherUsersNamespace(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() != 0 {
+		t.Skip("Creating a namespace owned by another user requires root")
+	}
+
+	home, err := ioutil.TempDir("", "carina-shared")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	os.Setenv(CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(CarinaHomeDirEnvVar)
+	os.Setenv(SharedHomeEnvVar, "true")
+	defer os.Unsetenv(SharedHomeEnvVar)
+	defer func() { CredentialsStoragePolicy.Dir = "" }()
+
+	// Another user on the build server already uses the same service account
+	const otherUID = 12345
+	sharedDir := filepath.Join(home, sharedHomeDirName)
+	require.NoError(t, createSharedDir(sharedDir))
+	otherNamespace := filepath.Join(sharedDir, sharedNamespaceName(accountNamespaceKey(&stubAccount{}, "", strconv.Itoa(otherUID))))
+	require.NoError(t, os.Mkdir(otherNamespace, 0700))
+	require.NoError(t, os.Chown(otherNamespace, otherUID, otherUID))
+
+	client := NewClient(true)
+	require.NoError(t, client.UseSharedHome(&stubAccount{}, ""), "The other user's namespace should not lock out the current user")
+	assert.NotEqual(t, otherNamespace, filepath.Dir(client.Cache.path))
+}
@@ -0,0 +1,33 @@
+// +build windows
+
+package client
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+)
+
+// currentUserID identifies the current OS user, by their SID, which owns their namespaces in the shared CARINA_HOME
+func currentUserID() string {
+	if current, err := user.Current(); err == nil && current.Uid != "" {
+		return current.Uid
+	}
+	return auditUser()
+}
+
+// checkNamespaceOwner verifies that a namespace in the shared CARINA_HOME is a directory. The ACL set when the namespace
+// was created, see restrictAccess, prevents other users from accessing it.
+func checkNamespaceOwner(namespace string) error {
+	info, err := os.Lstat(namespace)
+	if err != nil {
+		return fmt.Errorf("Unable to access the shared namespace %s: %s", namespace, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("The shared namespace %s is not a directory. Remove it, it may have been created by another user to intercept your credentials", namespace)
+	}
+	return nil
+}
+
+// checkSharedDirMode does nothing, because the file mode doesn't control access on Windows
+func checkSharedDirMode(sharedDir string) {}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"strings"
+)
+
+// posixShells run the bash credentials script
+var posixShells = map[string]bool{
+	"bash": true,
+	"zsh":  true,
+	"sh":   true,
+	"ksh":  true,
+	"dash": true,
+}
+
+// normalizeShell returns the shell whose credentials script is used by a shell, e.g. zsh uses the bash script
+func normalizeShell(shell string) string {
+	if posixShells[shell] {
+		return "bash"
+	}
+	return shell
+}
+
+// ValidateShell checks that carina env supports a shell, e.g. zsh or powershell
+func ValidateShell(shell string) error {
+	_, err := GetUnsetCommand(shell)
+	return err
+}
+
+// isSafeShellWord returns if a value can be used unquoted in every supported shell
+func isSafeShellWord(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("_-.,:/+=@", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// QuoteShellArg quotes a value, such as a path, so that the shell reads it as a single word,
+// even when it contains spaces, quotes or non-ASCII characters. Values which don't need quoting are returned unchanged.
+func QuoteShellArg(shell string, value string) string {
+	if isSafeShellWord(value) {
+		return value
+	}
+
+	switch normalizeShell(shell) {
+	case "fish":
+		// fish only interprets \\ and \' inside single quotes
+		value = strings.Replace(value, `\`, `\\`, -1)
+		value = strings.Replace(value, `'`, `\'`, -1)
+		return "'" + value + "'"
+	case "powershell":
+		// PowerShell doesn't interpret anything inside single quotes, a quote is escaped by doubling it
+		return "'" + strings.Replace(value, "'", "''", -1) + "'"
+	case "cmd":
+		// Windows paths can't contain double quotes, so there is nothing to escape
+		return `"` + value + `"`
+	default:
+		// POSIX shells don't interpret anything inside single quotes, so a quote ends the quoted string, is escaped, and starts a new one
+		return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+	}
+}
+
+// maxShellSearchDepth is how many parent processes are checked when detecting the shell, e.g. when carina is run by a wrapper script
+const maxShellSearchDepth = 5
+
+// DetectShell returns the shell which ran carina, by looking at its parent processes, or an empty string when it can't be detected.
+// This is only supported on Windows, where SHELL isn't set by PowerShell or cmd.
+func DetectShell() string {
+	return detectParentShell()
+}
+
+// shellFromExecutable returns the shell name for a shell's executable, e.g. pwsh.exe is powershell, or an empty string when it isn't a shell
+func shellFromExecutable(executable string) string {
+	// Windows paths are split on either separator, so that they are handled the same on every platform
+	name := executable[strings.LastIndexAny(executable, `\/`)+1:]
+	name = strings.TrimSuffix(strings.ToLower(name), ".exe")
+	switch name {
+	case "powershell", "pwsh":
+		return "powershell"
+	case "cmd", "fish":
+		return name
+	default:
+		if posixShells[name] {
+			return name
+		}
+		return ""
+	}
+}
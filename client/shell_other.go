@@ -0,0 +1,8 @@
+// +build !windows
+
+package client
+
+// detectParentShell isn't needed outside of Windows, where the shell sets SHELL
+func detectParentShell() string {
+	return ""
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteShellArg(t *testing.T) {
+	testcases := []struct {
+		shell string
+		value string
+		want  string
+	}{
+		{"bash", "/home/alice/.carina/clusters/mycluster/docker.env", "/home/alice/.carina/clusters/mycluster/docker.env"},
+		{"bash", "/home/alice smith/docker.env", "'/home/alice smith/docker.env'"},
+		{"bash", "/home/o'brien/docker.env", `'/home/o'\''brien/docker.env'`},
+		{"bash", "/home/zoë/docker.env", "'/home/zoë/docker.env'"},
+		{"bash", "/home/$USER/`id`/docker.env", "'/home/$USER/`id`/docker.env'"},
+		{"bash", "", "''"},
+		{"zsh", "/home/alice smith/docker.env", "'/home/alice smith/docker.env'"},
+		{"fish", "/home/alice smith/docker.fish", "'/home/alice smith/docker.fish'"},
+		{"fish", "/home/o'brien/docker.fish", `'/home/o\'brien/docker.fish'`},
+		{"fish", `/home/back\slash/docker.fish`, `'/home/back\\slash/docker.fish'`},
+		{"powershell", `C:\Users\Alice Smith\docker.ps1`, `'C:\Users\Alice Smith\docker.ps1'`},
+		{"powershell", `C:\Users\O'Brien\docker.ps1`, `'C:\Users\O''Brien\docker.ps1'`},
+		{"powershell", `C:\Users\$env:USERNAME\docker.ps1`, `'C:\Users\$env:USERNAME\docker.ps1'`},
+		{"powershell", `C:\Users\Zoë\docker.ps1`, `'C:\Users\Zoë\docker.ps1'`},
+		{"cmd", `C:\Users\Alice Smith\docker.cmd`, `"C:\Users\Alice Smith\docker.cmd"`},
+		{"cmd", `C:\Users\Alice&Bob\docker.cmd`, `"C:\Users\Alice&Bob\docker.cmd"`},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.want, QuoteShellArg(tc.shell, tc.value), "%s: %s", tc.shell, tc.value)
+	}
+}
+
+// TestQuoteShellArgRoundTrip checks that the installed shells read a quoted value back unchanged
+func TestQuoteShellArgRoundTrip(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The POSIX shells are not available on Windows")
+	}
+
+	values := []string{
+		"/home/alice smith/docker.env",
+		"/home/o'brien/docker.env",
+		"/home/zoë/クラスター/docker.env",
+		`/home/$USER/"quoted"/back\slash/docker.env`,
+		"/home/`id`/*/docker.env",
+	}
+
+	shells := map[string][]string{
+		"bash": {"bash", "-c"},
+		"zsh":  {"zsh", "-c"},
+		"fish": {"fish", "-c"},
+	}
+	for shell, command := range shells {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+
+		for _, value := range values {
+			script := "printf '%s' " + QuoteShellArg(shell, value)
+			output, err := exec.Command(command[0], append(command[1:], script)...).Output()
+			assert.Nil(t, err, "%s: %s", shell, script)
+			assert.Equal(t, value, strings.TrimRight(string(output), "\n"), "%s: %s", shell, script)
+		}
+	}
+}
+
+func TestSourceHelpStringQuotesThePath(t *testing.T) {
+	help := sourceHelpString("/home/alice smith/.carina/clusters/my cluster/docker.env", "my cluster", "zsh")
+	assert.Contains(t, help, "source '/home/alice smith/.carina/clusters/my cluster/docker.env'")
+	assert.Contains(t, help, "carina env 'my cluster'")
+}
+
+func TestShellFromExecutable(t *testing.T) {
+	assert.Equal(t, "powershell", shellFromExecutable("powershell.exe"))
+	assert.Equal(t, "powershell", shellFromExecutable("PWSH.EXE"))
+	assert.Equal(t, "cmd", shellFromExecutable(`C:\Windows\System32\cmd.exe`))
+	assert.Equal(t, "bash", shellFromExecutable("bash.exe"))
+	assert.Equal(t, "", shellFromExecutable("explorer.exe"))
+}
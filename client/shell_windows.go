@@ -0,0 +1,45 @@
+// +build windows
+
+package client
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/getcarina/carina/common"
+)
+
+// detectParentShell walks up the process tree to find the shell which ran carina
+func detectParentShell() string {
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		common.Log.WriteDebug("Unable to list the processes to detect the shell: %s", err)
+		return ""
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	parents := make(map[uint32]uint32)
+	executables := make(map[uint32]string)
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = syscall.Process32First(snapshot, &entry); err == nil; err = syscall.Process32Next(snapshot, &entry) {
+		parents[entry.ProcessID] = entry.ParentProcessID
+		executables[entry.ProcessID] = syscall.UTF16ToString(entry.ExeFile[:])
+	}
+
+	pid := uint32(os.Getpid())
+	for i := 0; i < maxShellSearchDepth; i++ {
+		parent, ok := parents[pid]
+		if !ok || parent == 0 || parent == pid {
+			break
+		}
+		pid = parent
+
+		if shell := shellFromExecutable(executables[pid]); shell != "" {
+			common.Log.WriteDebug("Detected the shell from the parent process %s", executables[pid])
+			return shell
+		}
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// smokeTestPrefix is the prefix of the throwaway clusters created by the smoke test
+const smokeTestPrefix = "carina-smoke-test-"
+
+// SmokeTestOptions controls which template the smoke test uses, and whether the cluster is kept afterwards
+type SmokeTestOptions struct {
+	// Template is the name of the template to test, defaults to the recommended template, see RecommendTemplate
+	Template string
+
+	// COE limits the recommended template to a container orchestration engine, e.g. kubernetes
+	COE string
+
+	// Keep skips deleting the cluster, e.g. to troubleshoot a failure
+	Keep bool
+}
+
+// SmokeTestStep is the outcome of a single step of the smoke test
+type SmokeTestStep struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// SmokeTestResult is the outcome of each step of the smoke test, the steps after a failed step are not run
+type SmokeTestResult struct {
+	Cluster  string
+	Template string
+	Steps    []SmokeTestStep
+}
+
+// Err returns the error of the first step which failed, or nil when the smoke test passed
+func (result *SmokeTestResult) Err() error {
+	for _, step := range result.Steps {
+		if step.Err != nil {
+			return errors.Wrapf(step.Err, "The smoke test failed at step: %s", step.Name)
+		}
+	}
+	return nil
+}
+
+// RecommendTemplate picks the template for a new cluster: a template which is neither deprecated nor low on capacity,
+// preferring the newest COE version. When coe is specified, only templates for that COE are considered.
+func RecommendTemplate(templates []common.ClusterTemplate, coe string) (common.ClusterTemplate, error) {
+	var candidates []common.ClusterTemplate
+	for _, template := range templates {
+		if template.IsDeprecated() {
+			continue
+		}
+		if availability := template.GetAvailability(); availability != nil && availability.Constrained {
+			continue
+		}
+		if coe != "" && !strings.EqualFold(template.GetCOE(), coe) {
+			continue
+		}
+		candidates = append(candidates, template)
+	}
+
+	if len(candidates) == 0 {
+		if coe != "" {
+			return nil, fmt.Errorf("There isn't an available %s template, select one with --template", coe)
+		}
+		return nil, errors.New("There isn't an available template, select one with --template")
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if c := common.CompareVersions(candidates[i].GetCOEVersion(), candidates[j].GetCOEVersion()); c != 0 {
+			return c > 0
+		}
+		return candidates[i].GetName() < candidates[j].GetName()
+	})
+	return candidates[0], nil
+}
+
+// SmokeTest validates that clusters can be used end-to-end on the account: it creates a single node cluster, waits for it,
+// downloads its credentials, checks that the COE responds, and then deletes the cluster.
+func (client *Client) SmokeTest(account Account, options SmokeTestOptions) (*SmokeTestResult, error) {
+	result := &SmokeTestResult{Template: options.Template}
+	run := func(name string, step func() error) bool {
+		start := time.Now()
+		err := step()
+		result.Steps = append(result.Steps, SmokeTestStep{Name: name, Duration: time.Since(start), Err: err})
+		return err == nil
+	}
+
+	if result.Template == "" {
+		ok := run("Select the recommended template", func() error {
+			templates, err := client.ListClusterTemplates(account, ListTemplatesOptions{})
+			if err != nil {
+				return err
+			}
+			template, err := RecommendTemplate(templates, options.COE)
+			if err != nil {
+				return err
+			}
+			result.Template = template.GetName()
+			return nil
+		})
+		if !ok {
+			return result, nil
+		}
+	}
+
+	name, err := client.GenerateClusterName(account, smokeTestPrefix, NewNameGenerator(0))
+	if err != nil {
+		return nil, err
+	}
+	result.Cluster = name
+
+	var cluster common.Cluster
+	created := run("Create a 1 node cluster", func() error {
+		var err error
+		cluster, err = client.CreateCluster(account, name, result.Template, 1, CreateClusterOptions{WaitUntilActive: true})
+		return err
+	})
+
+	// The cluster may exist even when creating it failed, e.g. it ended up in an error state
+	defer func() {
+		if options.Keep {
+			common.Log.WriteWarning("Keeping the smoke test cluster %s, delete it with carina delete %s", name, name)
+			return
+		}
+		run("Delete the cluster", func() error {
+			err := client.DeleteCluster(account, name, true)
+			if err != nil && !created && isNotFound(err) {
+				return nil
+			}
+			if err == nil {
+				err = client.DeleteClusterCredentials(account, name, "")
+			}
+			return err
+		})
+	}()
+
+	if !created {
+		return result, nil
+	}
+
+	ok := run("Download the credentials", func() error {
+		_, err := client.downloadLocalClusterCredentials(account, name, "")
+		return err
+	})
+	if !ok {
+		return result, nil
+	}
+
+	run(fmt.Sprintf("Check that the %s API responds", cluster.GetTemplate().GetCOE()), func() error {
+		return client.WaitUntilCOEIsReady(account, cluster)
+	})
+
+	return result, nil
+}
+
+// isNotFound returns if an error, or one of its causes, is a common.NotFoundError
+func isNotFound(err error) bool {
+	for err != nil {
+		if _, ok := err.(common.NotFoundError); ok {
+			return true
+		}
+
+		cause, ok := err.(interface {
+			Cause() error
+		})
+		if !ok {
+			return false
+		}
+		err = cause.Cause()
+	}
+	return false
+}
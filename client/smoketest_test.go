@@ -0,0 +1,52 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecommendTemplate(t *testing.T) {
+	templates := []common.ClusterTemplate{
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.9.0 on LXC", COE: "kubernetes", COEVersion: "1.9.0"},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.10.0 on LXC", COE: "kubernetes", COEVersion: "1.10.0"},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.11.0 on LXC", COE: "kubernetes", COEVersion: "1.11.0", Deprecated: true},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.12.0 on LXC", COE: "kubernetes", COEVersion: "1.12.0", Availability: &common.TemplateAvailability{Constrained: true}},
+		&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC", COE: "swarm", COEVersion: "1.11.2"},
+	}
+
+	template, err := RecommendTemplate(templates, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Swarm 1.11.2 on LXC", template.GetName())
+
+	template, err = RecommendTemplate(templates, "Kubernetes")
+	require.NoError(t, err)
+	assert.Equal(t, "Kubernetes 1.10.0 on LXC", template.GetName())
+
+	_, err = RecommendTemplate(templates, "mesos")
+	assert.Error(t, err)
+}
+
+func TestSmokeTestDeletesNothingWhenCreateFails(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	client := &Client{Cache: newCache(filename)}
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+
+	result, err := client.SmokeTest(account, SmokeTestOptions{Template: "missing"})
+	require.NoError(t, err)
+
+	assert.Error(t, result.Err())
+	assert.Contains(t, result.Cluster, smokeTestPrefix)
+	if assert.Len(t, result.Steps, 2) {
+		assert.Error(t, result.Steps[0].Err, "The template doesn't exist")
+		assert.NoError(t, result.Steps[1].Err, "The cluster was never created, so there is nothing to delete")
+	}
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// defaultSSHUser is used when the login user can't be determined from the cluster template
+const defaultSSHUser = "root"
+
+// sshUsers maps the operating system in a template name or image to its default login user
+var sshUsers = []struct {
+	os   string
+	user string
+}{
+	{"coreos", "core"},
+	{"atomic", "fedora"},
+	{"fedora", "fedora"},
+	{"ubuntu", "ubuntu"},
+	{"centos", "centos"},
+	{"debian", "debian"},
+}
+
+// SSHOptions selects the node and login used by carina ssh
+type SSHOptions struct {
+	// Node is the name or address of the node, defaults to the first master, or the first node when the cluster doesn't report masters
+	Node string
+
+	// User is the login user, defaults to the user of the operating system in the cluster template
+	User string
+
+	// IdentityFile is the private key used to authenticate, defaults to the keys selected by ssh
+	IdentityFile string
+
+	// Command is run on the node instead of starting an interactive session
+	Command string
+}
+
+// BuildSSHArgs resolves the address of a node in a cluster, returning the arguments for ssh to connect to the node
+func (client *Client) BuildSSHArgs(account Account, name string, options SSHOptions) ([]string, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := svc.GetCluster(name)
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+
+	nodes, err := listNodes(svc, name)
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+
+	node, err := selectNode(nodes, options.Node)
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+
+	user := options.User
+	if user == "" {
+		user = guessSSHUser(cluster.GetTemplate())
+		common.Log.WriteDebug("Connecting to %s as %s, use --user to login as a different user", node.GetName(), user)
+	}
+
+	args := []string{"-l", user}
+	if options.IdentityFile != "" {
+		args = append(args, "-i", options.IdentityFile)
+	}
+	if options.Command == "" {
+		// Allocate a terminal for the interactive session
+		args = append(args, "-t")
+	}
+	args = append(args, node.GetAddress())
+	if options.Command != "" {
+		args = append(args, "--", options.Command)
+	}
+	return args, nil
+}
+
+// selectNode finds a node by its name or address, defaulting to the first master
+func selectNode(nodes []common.Node, token string) (common.Node, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("The cluster doesn't have any nodes with an address")
+	}
+
+	if token == "" {
+		for _, node := range nodes {
+			if node.GetRole() == "master" && node.GetAddress() != "" {
+				return node, nil
+			}
+		}
+		for _, node := range nodes {
+			if node.GetAddress() != "" {
+				return node, nil
+			}
+		}
+		return nil, errors.New("The cluster doesn't have any nodes with an address")
+	}
+
+	var names []string
+	for _, node := range nodes {
+		if node.GetName() == token || node.GetAddress() == token {
+			if node.GetAddress() == "" {
+				return nil, errors.Errorf("The node %s doesn't have an address yet", node.GetName())
+			}
+			return node, nil
+		}
+		names = append(names, node.GetName())
+	}
+	return nil, common.NotFoundError{Err: fmt.Errorf("Could not find node %s. Available nodes: %s", token, strings.Join(names, ", "))}
+}
+
+// guessSSHUser returns the default login user for the operating system of the cluster's nodes
+func guessSSHUser(template common.ClusterTemplate) string {
+	if template == nil {
+		return defaultSSHUser
+	}
+
+	hints := strings.ToLower(template.GetName() + " " + template.GetDetails()["Image"])
+	for _, candidate := range sshUsers {
+		if strings.Contains(hints, candidate.os) {
+			return candidate.user
+		}
+	}
+	return defaultSSHUser
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// serviceStatusKeywords identify the status page components which affect carina: the container service and identity
+var serviceStatusKeywords = []string{"carina", "container", "cluster", "magnum", "identity", "keystone", "auth"}
+
+// ServiceStatus is the status of a cloud's services, as reported by its status page
+type ServiceStatus struct {
+	// Page is the name of the status page
+	Page string
+
+	// Description summarizes the overall status, e.g. All Systems Operational
+	Description string
+
+	// Indicator is the severity of the overall status: none, minor, major or critical
+	Indicator string
+
+	Components []ServiceComponent
+	Incidents  []ServiceIncident
+}
+
+// ServiceComponent is a service listed on the status page
+type ServiceComponent struct {
+	Name string
+
+	// Status is operational, degraded_performance, partial_outage, major_outage or under_maintenance
+	Status string
+}
+
+// IsOperational returns if the component is working normally
+func (component ServiceComponent) IsOperational() bool {
+	return component.Status == "operational"
+}
+
+// ServiceIncident is an unresolved incident on the status page
+type ServiceIncident struct {
+	Name string
+
+	// Status is investigating, identified or monitoring
+	Status string
+
+	// Impact is none, minor, major or critical
+	Impact  string
+	URL     string
+	Updated time.Time
+
+	// Components are the names of the affected components
+	Components []string
+}
+
+// statusPageSummary is the summary returned by the Statuspage API, e.g. https://example.statuspage.io/api/v2/summary.json
+type statusPageSummary struct {
+	Page struct {
+		Name string `json:"name"`
+	} `json:"page"`
+	Status struct {
+		Indicator   string `json:"indicator"`
+		Description string `json:"description"`
+	} `json:"status"`
+	Components []struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+		Group  bool   `json:"group"`
+	} `json:"components"`
+	Incidents []struct {
+		Name       string    `json:"name"`
+		Status     string    `json:"status"`
+		Impact     string    `json:"impact"`
+		Shortlink  string    `json:"shortlink"`
+		UpdatedAt  time.Time `json:"updated_at"`
+		Components []struct {
+			Name string `json:"name"`
+		} `json:"components"`
+	} `json:"incidents"`
+}
+
+// GetServiceStatus retrieves the status of a cloud's services from a status page summary in the Statuspage format
+func GetServiceStatus(statusURL string) (*ServiceStatus, error) {
+	common.Log.WriteDebug("Fetching the service status from %s", statusURL)
+	req, err := http.NewRequest("GET", statusURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid status page URL %s", statusURL)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", common.BuildUserAgent())
+
+	resp, err := common.NewHTTPClient().Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to reach the status page")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, common.NewHTTPError(resp.StatusCode, fmt.Errorf("Unable to fetch the service status from %s: %s", statusURL, resp.Status))
+	}
+
+	var summary statusPageSummary
+	err = json.NewDecoder(resp.Body).Decode(&summary)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read the service status, the status page didn't return a Statuspage summary")
+	}
+
+	status := &ServiceStatus{
+		Page:        summary.Page.Name,
+		Description: summary.Status.Description,
+		Indicator:   summary.Status.Indicator,
+	}
+	for _, component := range summary.Components {
+		// Groups only contain other components, which are listed separately
+		if component.Group {
+			continue
+		}
+		status.Components = append(status.Components, ServiceComponent{Name: component.Name, Status: component.Status})
+	}
+	for _, incident := range summary.Incidents {
+		result := ServiceIncident{
+			Name:    incident.Name,
+			Status:  incident.Status,
+			Impact:  incident.Impact,
+			URL:     incident.Shortlink,
+			Updated: incident.UpdatedAt,
+		}
+		for _, component := range incident.Components {
+			result.Components = append(result.Components, component.Name)
+		}
+		status.Incidents = append(status.Incidents, result)
+	}
+
+	return status, nil
+}
+
+// isRelevantComponent returns if a status page component affects carina
+func isRelevantComponent(name string) bool {
+	name = strings.ToLower(name)
+	for _, keyword := range serviceStatusKeywords {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// Relevant returns the status filtered to the components which affect carina, the container service and identity,
+// and the incidents affecting them. Incidents which don't list their components are always included.
+func (status *ServiceStatus) Relevant() *ServiceStatus {
+	relevant := &ServiceStatus{
+		Page:        status.Page,
+		Description: status.Description,
+		Indicator:   status.Indicator,
+	}
+
+	for _, component := range status.Components {
+		if isRelevantComponent(component.Name) {
+			relevant.Components = append(relevant.Components, component)
+		}
+	}
+
+	for _, incident := range status.Incidents {
+		affected := len(incident.Components) == 0
+		for _, name := range incident.Components {
+			if isRelevantComponent(name) {
+				affected = true
+				break
+			}
+		}
+		if affected {
+			relevant.Incidents = append(relevant.Incidents, incident)
+		}
+	}
+
+	return relevant
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testStatusSummary = `{
+  "page": {"name": "Carina"},
+  "status": {"indicator": "major", "description": "Partial System Outage"},
+  "components": [
+    {"name": "Carina API", "status": "major_outage", "group": false},
+    {"name": "Identity", "status": "operational", "group": false},
+    {"name": "Cloud Servers", "status": "degraded_performance", "group": false},
+    {"name": "Core Services", "status": "operational", "group": true}
+  ],
+  "incidents": [
+    {"name": "Cluster creation failing", "status": "investigating", "impact": "major", "shortlink": "https://stspg.io/abc",
+     "updated_at": "2016-10-16T12:00:00.000Z", "components": [{"name": "Carina API"}]},
+    {"name": "Slow server builds", "status": "identified", "impact": "minor", "shortlink": "https://stspg.io/def",
+     "updated_at": "2016-10-16T11:00:00.000Z", "components": [{"name": "Cloud Servers"}]}
+  ]
+}`
+
+func TestGetServiceStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testStatusSummary)
+	}))
+	defer server.Close()
+
+	status, err := GetServiceStatus(server.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, "Partial System Outage", status.Description)
+	assert.Len(t, status.Components, 3, "Component groups should be skipped")
+	assert.Len(t, status.Incidents, 2)
+
+	relevant := status.Relevant()
+	if assert.Len(t, relevant.Components, 2) {
+		assert.Equal(t, "Carina API", relevant.Components[0].Name)
+		assert.False(t, relevant.Components[0].IsOperational())
+		assert.Equal(t, "Identity", relevant.Components[1].Name)
+	}
+	if assert.Len(t, relevant.Incidents, 1, "Only incidents affecting the container service and identity are relevant") {
+		assert.Equal(t, "Cluster creation failing", relevant.Incidents[0].Name)
+		assert.Equal(t, []string{"Carina API"}, relevant.Incidents[0].Components)
+	}
+}
+
+func TestGetServiceStatusRequiresStatuspageSummary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html>Status</html>")
+	}))
+	defer server.Close()
+
+	_, err := GetServiceStatus(server.URL)
+	assert.NotNil(t, err)
+}
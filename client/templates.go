@@ -0,0 +1,42 @@
+package client
+
+import (
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// CreateClusterTemplate creates a new template, for clouds which let the account manage its own templates, such as magnum
+func (client *Client) CreateClusterTemplate(account Account, options common.ClusterTemplateOptions) (common.ClusterTemplate, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	templateSvc, ok := svc.(common.ClusterTemplateService)
+	if !ok {
+		return nil, errors.New("Creating templates is not supported by this cloud")
+	}
+
+	defer client.endOperation(client.startOperation(options.Name, "Create template (%s)", options.Name))
+	template, err := templateSvc.CreateClusterTemplate(options)
+	return template, wrapClientError(err)
+}
+
+// DeleteClusterTemplate deletes a template by its id or name, for clouds which let the account manage its own templates, such as magnum
+func (client *Client) DeleteClusterTemplate(account Account, name string) (common.ClusterTemplate, error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	templateSvc, ok := svc.(common.ClusterTemplateService)
+	if !ok {
+		return nil, errors.New("Deleting templates is not supported by this cloud")
+	}
+
+	defer client.endOperation(client.startOperation(name, "Delete template (%s)", name))
+	template, err := templateSvc.DeleteClusterTemplate(name)
+	return template, wrapClientError(err)
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckHostType(t *testing.T) {
+	service := testsupport.NewFakeClusterService(
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", HostType: "lxc"},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on VM", HostType: "VM"},
+	)
+
+	assert.NoError(t, checkHostType(service, "lxc"))
+	assert.NoError(t, checkHostType(service, "vm"), "The host type should be case insensitive")
+
+	err := checkHostType(service, "baremetal")
+	assert.IsType(t, common.NotFoundError{}, err)
+	assert.Contains(t, err.Error(), "Available host types: lxc, vm")
+}
+
+func TestManageClusterTemplates(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC"})
+	account := &historyAccount{offlineAccount{service: service}}
+	client := &Client{Cache: &Cache{}}
+
+	template, err := client.CreateClusterTemplate(account, common.ClusterTemplateOptions{Name: "k8s-small", COE: "kubernetes", Image: "fedora-atomic", ExternalNetwork: "public"})
+	require.NoError(t, err)
+	assert.Equal(t, "k8s-small", template.GetName())
+
+	_, err = client.CreateCluster(account, "web", "k8s-small", 1, CreateClusterOptions{})
+	require.NoError(t, err)
+	_, err = client.DeleteClusterTemplate(account, "k8s-small")
+	assert.Error(t, err, "a template used by a cluster should not be deleted")
+
+	require.NoError(t, client.DeleteCluster(account, "web", true))
+	_, err = client.DeleteClusterTemplate(account, "k8s-small")
+	require.NoError(t, err)
+
+	templates, err := client.ListClusterTemplates(account, ListTemplatesOptions{})
+	require.NoError(t, err)
+	assert.Len(t, templates, 1)
+}
@@ -0,0 +1,278 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/version"
+	"github.com/pkg/errors"
+)
+
+// releaseChecksumsAsset is the file attached to each release with the SHA-256 checksums of the binaries, in the sha256sum format
+const releaseChecksumsAsset = "SHA256SUMS"
+
+// releaseSignatureAsset is the file attached to each release with the signature of releaseChecksumsAsset
+const releaseSignatureAsset = releaseChecksumsAsset + ".sig"
+
+// lookupLatestRelease finds the most recent release of carina, tests replace it to avoid calling GitHub
+var lookupLatestRelease = version.LatestRelease
+
+// UpdateOptions controls how carina update replaces the running executable
+type UpdateOptions struct {
+	// CheckOnly reports if a newer release is available, without downloading it
+	CheckOnly bool
+
+	// Executable is the path to the binary to replace, defaults to the running executable
+	Executable string
+}
+
+// UpdateResult describes the outcome of carina update
+type UpdateResult struct {
+	// Current is the installed version
+	Current string
+
+	// Latest is the version of the most recent release
+	Latest string
+
+	// Available is set when the latest release is newer than the installed version
+	Available bool
+
+	// Updated is set when the executable was replaced with the latest release
+	Updated bool
+
+	// Path is the executable which was, or would be, replaced
+	Path string
+}
+
+// UpdateCarina checks the GitHub releases for a newer carina, and unless CheckOnly is set, replaces the executable with it
+// after verifying its checksum and signature. Builds without the release signing key can't install an update.
+func (client *Client) UpdateCarina(options UpdateOptions) (*UpdateResult, error) {
+	path := options.Executable
+	if path == "" {
+		executable, err := os.Executable()
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to find the carina executable")
+		}
+		path, err = filepath.EvalSymlinks(executable)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Unable to find the carina executable %s", executable)
+		}
+	}
+
+	common.Log.WriteDebug("Checking for newer releases of the carina cli...")
+	rel, err := lookupLatestRelease()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to fetch the latest release of carina")
+	}
+
+	result, err := compareRelease(version.Version, rel)
+	if err != nil {
+		return nil, err
+	}
+	result.Path = path
+	if !result.Available || options.CheckOnly {
+		return result, nil
+	}
+
+	err = installRelease(rel, runtime.GOOS, runtime.GOARCH, path, version.ReleaseSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	result.Updated = true
+	return result, nil
+}
+
+// compareRelease checks if a release is newer than the installed version
+func compareRelease(installed string, rel *version.Release) (*UpdateResult, error) {
+	result := &UpdateResult{Current: installed, Latest: rel.TagName}
+
+	latest, err := semver.NewVersion(rel.TagName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to parse the latest release version (%s)", rel.TagName)
+	}
+	current, err := semver.NewVersion(installed)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse the installed version (%s). Development builds can't be updated, download a release from https://github.com/getcarina/carina/releases instead", installed)
+	}
+
+	result.Available = latest.GreaterThan(current)
+	return result, nil
+}
+
+// releaseAssetName returns the name of the binary attached to a release for an operating system and architecture, e.g. carina-linux-amd64
+func releaseAssetName(goos string, goarch string) string {
+	name := fmt.Sprintf("carina-%s-%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// installRelease downloads the binary for the platform from a release, verifies it, and replaces the executable with it
+func installRelease(rel *version.Release, goos string, goarch string, path string, signingKey string) error {
+	assetName := releaseAssetName(goos, goarch)
+	binaryAsset, ok := rel.FindAsset(assetName)
+	if !ok {
+		return fmt.Errorf("The %s release doesn't include a binary for %s/%s (%s)", rel.TagName, goos, goarch, assetName)
+	}
+	checksumsAsset, ok := rel.FindAsset(releaseChecksumsAsset)
+	if !ok {
+		return fmt.Errorf("The %s release doesn't include the %s checksums, so the binary can't be verified", rel.TagName, releaseChecksumsAsset)
+	}
+
+	if signingKey == "" {
+		return fmt.Errorf("This build of carina doesn't include the release signing key, so the %s release can't be verified. Download it from https://github.com/getcarina/carina/releases instead", rel.TagName)
+	}
+	signatureAsset, ok := rel.FindAsset(releaseSignatureAsset)
+	if !ok {
+		return fmt.Errorf("The %s release isn't signed, %s is missing, so the binary can't be verified", rel.TagName, releaseSignatureAsset)
+	}
+
+	checksums, err := downloadReleaseAsset(checksumsAsset)
+	if err != nil {
+		return err
+	}
+	signature, err := downloadReleaseAsset(signatureAsset)
+	if err != nil {
+		return err
+	}
+	err = verifyReleaseSignature(checksums, signature, signingKey)
+	if err != nil {
+		return err
+	}
+
+	expected, err := findReleaseChecksum(checksums, assetName)
+	if err != nil {
+		return err
+	}
+
+	binary, err := downloadReleaseAsset(binaryAsset)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(binary)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("The checksum of %s, %s, doesn't match %s in %s. The download may be corrupt, try again", assetName, actual, expected, releaseChecksumsAsset)
+	}
+
+	return replaceExecutable(path, binary)
+}
+
+// downloadReleaseAsset downloads a file attached to a release, always verifying the server's certificate
+func downloadReleaseAsset(asset *version.ReleaseAsset) ([]byte, error) {
+	common.Log.WriteDebug("Downloading %s from %s", asset.Name, asset.BrowserDownloadURL)
+	resp, err := common.NewVerifiedDownloadHTTPClient().Get(asset.BrowserDownloadURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to download %s", asset.Name)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, common.NewHTTPError(resp.StatusCode, fmt.Errorf("Unable to download %s: %s", asset.Name, resp.Status))
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to download %s", asset.Name)
+	}
+	return contents, nil
+}
+
+// findReleaseChecksum finds the checksum of a file in the sha256sum format, e.g. "<checksum>  carina-linux-amd64"
+func findReleaseChecksum(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sha256sum marks binary files with a leading *
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("%s doesn't include a checksum for %s", releaseChecksumsAsset, name)
+}
+
+// verifyReleaseSignature checks the ASN.1 encoded ECDSA signature of the checksums against the base64 encoded public key
+func verifyReleaseSignature(checksums []byte, signature []byte, signingKey string) error {
+	der, err := base64.StdEncoding.DecodeString(signingKey)
+	if err != nil {
+		return errors.Wrap(err, "Invalid release signing key")
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return errors.Wrap(err, "Invalid release signing key")
+	}
+	publicKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return errors.New("Invalid release signing key, only ECDSA keys are supported")
+	}
+
+	var sig struct {
+		R, S *big.Int
+	}
+	_, err = asn1.Unmarshal(signature, &sig)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid signature of %s", releaseChecksumsAsset)
+	}
+
+	sum := sha256.Sum256(checksums)
+	if !ecdsa.Verify(publicKey, sum[:], sig.R, sig.S) {
+		return fmt.Errorf("The signature of %s is invalid, the release may have been tampered with", releaseChecksumsAsset)
+	}
+	return nil
+}
+
+// replaceExecutable swaps the executable for the new binary. The running executable is moved aside first,
+// because Windows doesn't allow replacing a running executable, and is removed when possible.
+func replaceExecutable(path string, binary []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to update %s", path)
+	}
+
+	// Write next to the executable, so that the rename doesn't cross filesystems
+	downloadPath := path + ".download"
+	oldPath := path + ".old"
+	os.Remove(oldPath)
+
+	err = ioutil.WriteFile(downloadPath, binary, info.Mode().Perm()|0111)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to update %s. Check that you have permission to write to %s", path, filepath.Dir(path))
+	}
+
+	err = os.Rename(path, oldPath)
+	if err != nil {
+		os.Remove(downloadPath)
+		return errors.Wrapf(err, "Unable to update %s", path)
+	}
+
+	err = os.Rename(downloadPath, path)
+	if err != nil {
+		// Put the original executable back
+		os.Rename(oldPath, path)
+		os.Remove(downloadPath)
+		return errors.Wrapf(err, "Unable to update %s", path)
+	}
+
+	err = os.Remove(oldPath)
+	if err != nil {
+		common.Log.WriteDebug("Unable to remove the previous executable %s: %s", oldPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/getcarina/carina/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareRelease(t *testing.T) {
+	result, err := compareRelease("v2.1.0", &version.Release{TagName: "v2.2.0"})
+	require.NoError(t, err)
+	assert.True(t, result.Available)
+
+	result, err = compareRelease("v2.2.0", &version.Release{TagName: "v2.2.0"})
+	require.NoError(t, err)
+	assert.False(t, result.Available)
+
+	_, err = compareRelease("", &version.Release{TagName: "v2.2.0"})
+	assert.Error(t, err, "Development builds can't be updated")
+}
+
+func TestInstallReleaseVerifiesTheDownload(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho carina v2.2.0\n")
+	sum := sha256.Sum256(binary)
+	checksums := []byte(fmt.Sprintf("%s  carina-linux-amd64\n0000  carina-darwin-amd64\n", hex.EncodeToString(sum[:])))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	signingKey := base64.StdEncoding.EncodeToString(der)
+	checksumsSum := sha256.Sum256(checksums)
+	r, s, err := ecdsa.Sign(cryptorand.Reader, key, checksumsSum[:])
+	require.NoError(t, err)
+	signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	require.NoError(t, err)
+
+	assets := map[string][]byte{
+		"carina-linux-amd64":  binary,
+		"carina-darwin-amd64": []byte("tampered"),
+		"SHA256SUMS":          checksums,
+		"SHA256SUMS.sig":      signature,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(assets[r.URL.Path[1:]])
+	}))
+	defer server.Close()
+
+	rel := &version.Release{TagName: "v2.2.0"}
+	for name := range assets {
+		rel.Assets = append(rel.Assets, version.ReleaseAsset{Name: name, BrowserDownloadURL: server.URL + "/" + name})
+	}
+
+	dir, err := ioutil.TempDir("", "carina-update")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "carina")
+	require.NoError(t, ioutil.WriteFile(path, []byte("old"), 0755))
+
+	err = installRelease(rel, "darwin", "amd64", path, signingKey)
+	assert.Error(t, err, "A binary which doesn't match the checksum should be rejected")
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+	otherDER, _ := x509.MarshalPKIXPublicKey(&otherKey.PublicKey)
+	err = installRelease(rel, "linux", "amd64", path, base64.StdEncoding.EncodeToString(otherDER))
+	assert.Error(t, err, "Checksums signed by another key should be rejected")
+
+	err = installRelease(rel, "linux", "amd64", path, "")
+	assert.Error(t, err, "A build without the release signing key shouldn't install an unverified binary")
+
+	unsigned := &version.Release{TagName: "v2.2.0"}
+	for _, asset := range rel.Assets {
+		if asset.Name != "SHA256SUMS.sig" {
+			unsigned.Assets = append(unsigned.Assets, asset)
+		}
+	}
+	err = installRelease(unsigned, "linux", "amd64", path, signingKey)
+	assert.Error(t, err, "A release without a signature should be rejected")
+
+	contents, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "old", string(contents), "The executable shouldn't change when the verification fails")
+
+	err = installRelease(rel, "linux", "amd64", path, signingKey)
+	require.NoError(t, err)
+	contents, _ = ioutil.ReadFile(path)
+	assert.Equal(t, binary, contents)
+	_, err = os.Stat(path + ".old")
+	assert.True(t, os.IsNotExist(err), "The previous executable should be removed")
+}
+
+func TestUpdateCarinaCheckOnly(t *testing.T) {
+	defer func(installed string, lookup func() (*version.Release, error)) {
+		version.Version = installed
+		lookupLatestRelease = lookup
+	}(version.Version, lookupLatestRelease)
+	version.Version = "v2.1.0"
+	client := NewClient(false)
+	options := UpdateOptions{CheckOnly: true, Executable: "/usr/local/bin/carina"}
+
+	lookupLatestRelease = func() (*version.Release, error) { return &version.Release{TagName: "v2.2.0"}, nil }
+	result, err := client.UpdateCarina(options)
+	require.NoError(t, err)
+	assert.True(t, result.Available)
+	assert.False(t, result.Updated, "--check should not install the update")
+	assert.Equal(t, "v2.2.0", result.Latest)
+	assert.Equal(t, "/usr/local/bin/carina", result.Path)
+
+	lookupLatestRelease = func() (*version.Release, error) { return &version.Release{TagName: "v2.1.0"}, nil }
+	result, err = client.UpdateCarina(options)
+	require.NoError(t, err)
+	assert.False(t, result.Available)
+	assert.Equal(t, "v2.1.0", result.Latest)
+
+	lookupLatestRelease = func() (*version.Release, error) { return nil, fmt.Errorf("rate limit exceeded") }
+	_, err = client.UpdateCarina(options)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to fetch the latest release of carina")
+	assert.Contains(t, err.Error(), "rate limit exceeded")
+}
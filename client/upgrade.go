@@ -0,0 +1,187 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// UpgradeCluster moves a cluster to another template, e.g. a newer COE version. When template is empty, the newest
+// template with the same COE and host type is used. The template is checked for compatibility with the cluster first.
+func (client *Client) UpgradeCluster(account Account, name string, template string, waitUntilActive bool) (cluster common.Cluster, err error) {
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.checkSupported(svc, common.OperationUpgrade)
+	if err != nil {
+		return nil, err
+	}
+
+	upgrader, ok := svc.(common.ClusterUpgrader)
+	if !ok {
+		return nil, errors.New("Upgrading clusters is not supported by this cloud")
+	}
+
+	defer client.endOperation(client.startOperation(name, "Upgrade cluster (%s)", name))
+	args := map[string]string{"template": template}
+	started := time.Now()
+	defer func() {
+		client.audit(account, "upgrade", name, args, cluster, err)
+		if waitUntilActive {
+			client.notifyWaitFinished(account, "upgrade", name, started, cluster, err)
+		}
+	}()
+
+	unlock, err := client.lockCluster(account, name, "upgrade")
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+	defer unlock()
+
+	current, target, err := preflightUpgrade(svc, name, template)
+	if err != nil {
+		return nil, err
+	}
+	args["template"] = target.GetName()
+
+	common.Log.WriteDebug("Upgrading cluster (%s) from %s to %s", name, current.GetTemplate().GetName(), target.GetName())
+	cluster, err = upgrader.UpgradeCluster(name, target.GetName())
+	if err == nil {
+		client.recordClusterStatus(account, "upgrade", cluster)
+	}
+
+	if waitUntilActive && err == nil {
+		defer client.watchClusterStatus(account, cluster, nil)()
+		cluster, err = svc.WaitUntilClusterIsActive(cluster)
+		if err == nil {
+			client.recordClusterStatus(account, "wait", cluster)
+		}
+	}
+
+	return cluster, wrapClusterError(name, err)
+}
+
+// preflightUpgrade looks up the cluster and the template it would be upgraded to, and checks that they are compatible
+func preflightUpgrade(svc common.ClusterService, name string, template string) (common.Cluster, common.ClusterTemplate, error) {
+	cluster, err := svc.GetCluster(name)
+	if err != nil {
+		return nil, nil, wrapClusterError(name, err)
+	}
+
+	templates, err := svc.ListClusterTemplates()
+	if err != nil {
+		return nil, nil, wrapClientError(err)
+	}
+
+	target, err := selectUpgradeTemplate(cluster, templates, template)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = checkUpgrade(cluster, target)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cluster, target, nil
+}
+
+// selectUpgradeTemplate finds the template matching a name or pattern, preferring an exact name match.
+// When the pattern is empty, the newest template with the same COE and host type as the cluster is selected.
+func selectUpgradeTemplate(cluster common.Cluster, templates []common.ClusterTemplate, pattern string) (common.ClusterTemplate, error) {
+	current := cluster.GetTemplate()
+	if pattern == "" {
+		var candidates []common.ClusterTemplate
+		for _, template := range templates {
+			if template.IsDeprecated() || !strings.EqualFold(template.GetCOE(), current.GetCOE()) || !strings.EqualFold(template.GetHostType(), current.GetHostType()) {
+				continue
+			}
+			if common.CompareVersions(template.GetCOEVersion(), current.GetCOEVersion()) > 0 {
+				candidates = append(candidates, template)
+			}
+		}
+
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("Cluster (%s) already uses the newest %s template (%s). Select another template with --to-template", cluster.GetName(), current.GetCOE(), current.GetName())
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if c := common.CompareVersions(candidates[i].GetCOEVersion(), candidates[j].GetCOEVersion()); c != 0 {
+				return c > 0
+			}
+			return candidates[i].GetName() < candidates[j].GetName()
+		})
+		return candidates[0], nil
+	}
+
+	matcher, err := common.NameMatchPolicy.NewMatcher(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []common.ClusterTemplate
+	for _, template := range templates {
+		if template.GetName() == pattern {
+			return template, nil
+		}
+		if matcher.Matches(template.GetName()) {
+			matches = append(matches, template)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("No template matches %s. Run carina templates to see the available templates", pattern)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, template := range matches {
+			names[i] = template.GetName()
+		}
+		return nil, &common.MultipleMatchingTemplatesError{TemplatePattern: pattern, MatchingTemplates: names}
+	}
+}
+
+// checkUpgrade returns an error when a cluster can't be moved to the target template: it must use the same COE,
+// must not be older or deprecated, and must support the cluster's number of nodes, autoscaling and GPUs
+func checkUpgrade(cluster common.Cluster, target common.ClusterTemplate) error {
+	name := cluster.GetName()
+	current := cluster.GetTemplate()
+
+	if target.GetName() == current.GetName() {
+		return fmt.Errorf("Cluster (%s) already uses the %s template", name, target.GetName())
+	}
+	if !strings.EqualFold(target.GetCOE(), current.GetCOE()) {
+		return fmt.Errorf("Unable to upgrade cluster (%s) from %s to %s, the template must use the same COE (%s)", name, current.GetName(), target.GetName(), current.GetCOE())
+	}
+	if target.IsDeprecated() {
+		return common.DeprecatedTemplateError{TemplateName: target.GetName()}
+	}
+	if common.CompareVersions(target.GetCOEVersion(), current.GetCOEVersion()) < 0 {
+		return fmt.Errorf("Unable to upgrade cluster (%s) from %s to %s, the COE version %s is older than %s", name, current.GetName(), target.GetName(), target.GetCOEVersion(), current.GetCOEVersion())
+	}
+
+	capabilities := target.GetCapabilities()
+	if capabilities == nil {
+		common.Log.WriteDebug("Skipping the capability checks, the %s template doesn't report its capabilities", target.GetName())
+		return nil
+	}
+
+	if nodes, err := common.ParseNodeCount(cluster.GetNodes()); err == nil && capabilities.MaxNodes > 0 && nodes > capabilities.MaxNodes {
+		return fmt.Errorf("Unable to upgrade cluster (%s) to %s, the template supports at most %d nodes and the cluster has %d. Resize the cluster first", name, target.GetName(), capabilities.MaxNodes, nodes)
+	}
+	if autoscale := cluster.GetAutoScale(); autoscale != nil && autoscale.Enabled && !capabilities.AutoScale {
+		return fmt.Errorf("Unable to upgrade cluster (%s) to %s, the template doesn't support autoscaling. Disable autoscaling on the cluster first", name, target.GetName())
+	}
+	if currentCapabilities := current.GetCapabilities(); currentCapabilities != nil && currentCapabilities.GPU && !capabilities.GPU {
+		return fmt.Errorf("Unable to upgrade cluster (%s) to %s, the template's nodes don't have GPUs", name, target.GetName())
+	}
+	return nil
+}
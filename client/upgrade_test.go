@@ -0,0 +1,116 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpgradeClusterToNewestTemplate(t *testing.T) {
+	service := testsupport.NewFakeClusterService(
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes", HostType: "lxc", COEVersion: "1.5.2"},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.6.1 on LXC", COE: "kubernetes", HostType: "lxc", COEVersion: "1.6.1"},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.7.0 on LXC", COE: "kubernetes", HostType: "lxc", COEVersion: "1.7.0", Deprecated: true},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.8.0 on VM", COE: "kubernetes", HostType: "vm", COEVersion: "1.8.0"},
+		&testsupport.FakeClusterTemplate{Name: "Swarm 1.12.0 on LXC", COE: "swarm", HostType: "lxc", COEVersion: "1.12.0"})
+	service.CreateCluster("prod", "Kubernetes 1.5.2*", 2)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	plan, err := c.PlanUpgradeCluster(account, "prod", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Upgrade cluster (prod) from Kubernetes 1.5.2 on LXC to Kubernetes 1.6.1 on LXC"}, plan.Steps)
+
+	cluster, err := c.UpgradeCluster(account, "prod", "", true)
+	require.NoError(t, err)
+	assert.Equal(t, "Kubernetes 1.6.1 on LXC", cluster.GetTemplate().GetName())
+	assert.Equal(t, testsupport.StatusActive, cluster.GetStatus())
+
+	_, err = c.UpgradeCluster(account, "prod", "", false)
+	if assert.Error(t, err, "There isn't a newer template with the same COE and host type") {
+		assert.Contains(t, err.Error(), "already uses the newest kubernetes template")
+	}
+
+	_, err = c.UpgradeCluster(account, "prod", "Swarm*", false)
+	if assert.Error(t, err, "The COE can't change") {
+		assert.Contains(t, err.Error(), "must use the same COE")
+	}
+
+	_, err = c.UpgradeCluster(account, "prod", "Kubernetes 1.5.2*", false)
+	if assert.Error(t, err, "Downgrades aren't allowed") {
+		assert.Contains(t, err.Error(), "is older than")
+	}
+
+	_, err = c.UpgradeCluster(account, "prod", "Kubernetes 1.8.0*", false)
+	assert.NoError(t, err, "Upgrading to a template with another host type is allowed when it's selected")
+}
+
+func TestUpgradeClusterChecksCapabilities(t *testing.T) {
+	service := testsupport.NewFakeClusterService(
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on VM", COE: "kubernetes", COEVersion: "1.5.2", SupportsAutoScale: true},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.6.1 Small", COE: "kubernetes", COEVersion: "1.6.1",
+			Capabilities: &common.TemplateCapabilities{MaxNodes: 3, AutoScale: true}},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.6.1 Fixed", COE: "kubernetes", COEVersion: "1.6.1",
+			Capabilities: &common.TemplateCapabilities{MaxNodes: 10}})
+	service.CreateCluster("prod", "*VM", 5)
+	service.SetAutoScale("prod", common.AutoScale{Enabled: true})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	_, err := c.UpgradeCluster(account, "prod", "*Small", false)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "supports at most 3 nodes and the cluster has 5")
+	}
+
+	_, err = c.PlanUpgradeCluster(account, "prod", "*Fixed")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "doesn't support autoscaling")
+	}
+
+	_, err = c.UpgradeCluster(account, "prod", "Kubernetes 1.6.1*", false)
+	assert.IsType(t, &common.MultipleMatchingTemplatesError{}, errors.Cause(err))
+
+	cluster, _ := service.GetCluster("prod")
+	assert.Equal(t, "Kubernetes 1.5.2 on VM", cluster.GetTemplate().GetName(), "A failed pre-flight check shouldn't change the cluster")
+}
+
+func TestUpgradeClusterUnsupported(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.CreateCluster("prod", "Swarm*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(struct{ common.ClusterService }{service})
+
+	_, err := client.NewClient(false).UpgradeCluster(account, "prod", "", false)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not supported")
+	}
+}
+
+func TestUpgradeClusterUsesMatchMode(t *testing.T) {
+	service := testsupport.NewFakeClusterService(
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes", HostType: "lxc", COEVersion: "1.5.2"},
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.6.1 on LXC", COE: "kubernetes", HostType: "lxc", COEVersion: "1.6.1"})
+	service.CreateCluster("prod", "Kubernetes 1.5.2*", 2)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+	c := client.NewClient(false)
+
+	defer func() { common.NameMatchPolicy.Mode = common.MatchGlob }()
+	common.NameMatchPolicy.Mode = common.MatchRegex
+
+	plan, err := c.PlanUpgradeCluster(account, "prod", `Kubernetes 1\.6\..*`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Upgrade cluster (prod) from Kubernetes 1.5.2 on LXC to Kubernetes 1.6.1 on LXC"}, plan.Steps)
+
+	common.NameMatchPolicy.Mode = common.MatchExact
+	_, err = c.PlanUpgradeCluster(account, "prod", "Kubernetes 1.6*")
+	assert.Error(t, err, "A glob shouldn't match a template using the exact match mode")
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// Cluster states which WaitForCluster can wait for
+const (
+	// WaitForActive waits until the cluster is active, failing if it ends up in an error state instead
+	WaitForActive = "active"
+
+	// WaitForDeleted waits until the cluster is gone
+	WaitForDeleted = "deleted"
+
+	// WaitForError waits until the cluster is in an error state, failing if it becomes active instead
+	WaitForError = "error"
+)
+
+// ValidateWaitForState checks that a state can be waited for with WaitForCluster
+func ValidateWaitForState(state string) error {
+	switch state {
+	case WaitForActive, WaitForDeleted, WaitForError:
+		return nil
+	default:
+		return fmt.Errorf("Invalid state: %s. Allowed values: %s, %s, %s", state, WaitForActive, WaitForDeleted, WaitForError)
+	}
+}
+
+// WaitForCluster blocks until a cluster, which may have been created earlier or elsewhere, reaches a state: active, deleted or error.
+// The returned cluster is nil when waiting for the cluster to be deleted. An error is returned when the cluster settles in
+// another state, such as failing while waiting for it to become active, or when common.ClusterWaitPolicy times out.
+func (client *Client) WaitForCluster(account Account, name string, state string) (common.Cluster, error) {
+	err := ValidateWaitForState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	defer client.Cache.SaveAccount(account)
+	svc, err := client.buildContainerService(account)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := svc.GetCluster(name)
+	if err != nil {
+		if state == WaitForDeleted && isNotFound(common.CategorizeError(err)) {
+			common.Log.WriteDebug("Cluster (%s) is already deleted", name)
+			client.Cache.deletePendingOperation(account, name)
+			return nil, nil
+		}
+		return nil, wrapClusterError(name, err)
+	}
+
+	defer client.watchClusterStatus(account, cluster, nil)()
+
+	if state == WaitForDeleted {
+		err = svc.WaitUntilClusterIsDeleted(cluster)
+		if err != nil {
+			return nil, wrapClusterError(name, err)
+		}
+		client.recordClusterDeleted(account, cluster)
+		client.Cache.deletePendingOperation(account, cluster.GetID())
+		return nil, nil
+	}
+
+	cluster, err = svc.WaitUntilClusterIsActive(cluster)
+	if err != nil {
+		return nil, wrapClusterError(name, err)
+	}
+	client.recordClusterStatus(account, "wait", cluster)
+	client.Cache.deletePendingOperation(account, cluster.GetID())
+
+	failed := IsErrorStatus(cluster.GetStatus())
+	switch {
+	case state == WaitForActive && failed:
+		message := fmt.Sprintf("The cluster is in an error state (%s) instead of active", cluster.GetStatus())
+		if details := cluster.GetStatusDetails(); details != "" {
+			message += ": " + details
+		}
+		return cluster, wrapClusterError(name, errors.New(message))
+	case state == WaitForError && !failed:
+		return cluster, wrapClusterError(name, fmt.Errorf("The cluster is %s instead of in an error state", strings.ToLower(cluster.GetStatus())))
+	}
+	return cluster, nil
+}
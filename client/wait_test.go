@@ -0,0 +1,45 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForCluster(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC"})
+	service.PendingPolls = 3
+	service.CreateCluster("mycluster", "Swarm*", 1)
+	service.CreateCluster("broken", "Swarm*", 1)
+	service.FailCluster("broken", "out of capacity")
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	c := client.NewClient(false)
+	cluster, err := c.WaitForCluster(account, "mycluster", client.WaitForActive)
+	require.NoError(t, err)
+	assert.Equal(t, testsupport.StatusActive, cluster.GetStatus())
+
+	_, err = c.WaitForCluster(account, "mycluster", client.WaitForError)
+	assert.Error(t, err, "An active cluster isn't in an error state")
+
+	_, err = c.WaitForCluster(account, "broken", client.WaitForActive)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "out of capacity")
+	}
+
+	cluster, err = c.WaitForCluster(account, "broken", client.WaitForError)
+	require.NoError(t, err)
+	assert.Equal(t, testsupport.StatusError, cluster.GetStatus())
+
+	cluster, err = c.WaitForCluster(account, "missing", client.WaitForDeleted)
+	assert.NoError(t, err, "A cluster which doesn't exist is already deleted")
+	assert.Nil(t, cluster)
+
+	_, err = c.WaitForCluster(account, "mycluster", "resized")
+	assert.Error(t, err)
+}
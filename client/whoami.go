@@ -0,0 +1,70 @@
+package client
+
+import (
+	"time"
+)
+
+// AccountInfo describes the account used by a command, to troubleshoot which account and endpoint are actually used
+type AccountInfo struct {
+	ID           string
+	Cloud        string
+	Profile      string
+	Username     string
+	Project      string
+	Region       string
+	AuthEndpoint string
+
+	// Endpoint is the --endpoint override, or the API endpoint cached when the account last authenticated
+	Endpoint string
+
+	TokenCached  bool
+	TokenUpdated time.Time
+
+	// TokenExpires is zero when the cloud doesn't report when its tokens expire
+	TokenExpires time.Time
+
+	// CachePath is empty when the cache is disabled
+	CachePath string
+}
+
+// DescribeAccount describes the account built from the settings, using the token and endpoint cached for it.
+// The account is not authenticated, see Login.
+func (client *Client) DescribeAccount(account Account, cloud string, profile string, settings AccountSettings) AccountInfo {
+	info := AccountInfo{
+		ID:           account.GetID(),
+		Cloud:        cloud,
+		Profile:      profile,
+		Username:     settings.Username,
+		Project:      settings.Project,
+		Region:       settings.Region,
+		AuthEndpoint: settings.AuthEndpoint,
+		Endpoint:     settings.EndpointOverride,
+	}
+	if info.Username == "" {
+		info.Username = settings.ApplicationCredentialID
+	}
+	if info.Project == "" {
+		info.Project = settings.ProjectID
+	}
+
+	// One-off accounts don't use the cache
+	if client.Cache.isNil() || account.BuildCache() == nil {
+		return info
+	}
+	info.CachePath = client.Cache.path
+
+	client.Cache.Lock()
+	defer client.Cache.Unlock()
+
+	cached := client.Cache.Accounts[info.ID]
+	info.TokenCached = cached[cachedTokenKey] != ""
+	if info.TokenCached {
+		info.TokenUpdated = client.Cache.TokensUpdated[info.ID]
+		info.TokenExpires, _ = time.Parse(time.RFC3339, cached[cachedTokenExpiresKey])
+	}
+	if info.Endpoint == "" {
+		info.Endpoint = cached[cachedEndpointKey]
+	}
+
+	return info
+}
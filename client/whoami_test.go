@@ -0,0 +1,41 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeAccountUsesCache(t *testing.T) {
+	filename := fmt.Sprintf("carina-temp-cache-%s.json", randomName())
+	defer os.Remove(filename)
+
+	account := &cachingAccount{id: "private-alice", cache: map[string]string{
+		"token":         "abc123",
+		"endpoint":      "https://magnum.example.com",
+		"token-expires": "2017-02-01T12:00:00Z",
+	}}
+	client := &Client{Cache: newCache(filename)}
+	client.Cache.SaveAccount(account)
+
+	settings := AccountSettings{Username: "alice", ProjectID: "1234", Region: "RegionOne", AuthEndpoint: "https://keystone.example.com/v3"}
+	info := client.DescribeAccount(account, "private", "prod", settings)
+
+	assert.Equal(t, "private-alice", info.ID)
+	assert.Equal(t, "alice", info.Username)
+	assert.Equal(t, "1234", info.Project)
+	assert.Equal(t, "https://magnum.example.com", info.Endpoint)
+	assert.True(t, info.TokenCached)
+	assert.False(t, info.TokenUpdated.IsZero())
+	assert.Equal(t, time.Date(2017, 2, 1, 12, 0, 0, 0, time.UTC), info.TokenExpires.UTC())
+	assert.Equal(t, filename, info.CachePath)
+
+	settings.EndpointOverride = "https://localhost:9511"
+	info = client.DescribeAccount(NewUncachedAccount(account), "private", "", settings)
+	assert.Equal(t, "https://localhost:9511", info.Endpoint)
+	assert.False(t, info.TokenCached)
+	assert.Empty(t, info.CachePath)
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxWindowsPath is the longest path, excluding the 12 characters reserved for an 8.3 filename,
+// which the Windows APIs accept without the \\?\ extended-length prefix
+const maxWindowsPath = 248
+
+// extendedLengthPrefix lets Windows APIs, such as SetNamedSecurityInfo, accept paths longer than MAX_PATH
+const extendedLengthPrefix = `\\?\`
+
+// msysPathPattern matches a drive path set by MSYS, Git Bash or Cygwin, e.g. /c/Users/me or /cygdrive/c/Users/me
+var msysPathPattern = regexp.MustCompile(`^/(?:cygdrive/)?([a-zA-Z])(?:/(.*))?$`)
+
+// windowsDrivePathPattern matches an absolute path with a drive letter, e.g. C:\Users\me or C:/Users/me
+var windowsDrivePathPattern = regexp.MustCompile(`^[a-zA-Z]:[\\/]`)
+
+// extendedLengthPath adds the \\?\ prefix to an absolute Windows path when it is too long for the Windows APIs
+func extendedLengthPath(path string) string {
+	if len(path) < maxWindowsPath || strings.HasPrefix(path, extendedLengthPrefix) {
+		return path
+	}
+
+	// Extended-length paths are not normalized by Windows, so they must only use backslashes
+	path = strings.Replace(path, "/", `\`, -1)
+	if strings.HasPrefix(path, `\\`) {
+		return extendedLengthPrefix + `UNC\` + strings.TrimPrefix(path, `\\`)
+	}
+	return extendedLengthPrefix + path
+}
+
+// msysToWindowsPath converts a path set by MSYS, Git Bash or Cygwin, such as /c/Users/me, to C:\Users\me.
+// Other paths are returned unchanged.
+func msysToWindowsPath(path string) string {
+	match := msysPathPattern.FindStringSubmatch(path)
+	if match == nil {
+		return path
+	}
+	return fmt.Sprintf(`%s:\%s`, strings.ToUpper(match[1]), strings.Replace(match[2], "/", `\`, -1))
+}
+
+// windowsHomeDirs lists the possible home directories on Windows, in the same order used by kubectl:
+// HOME (which may be set by Git Bash or Cygwin), %USERPROFILE%, then %HOMEDRIVE%%HOMEPATH%.
+// %HOMEDRIVE%%HOMEPATH% is a last resort, because on managed machines it is often a network share.
+func windowsHomeDirs(getenv func(string) string) []string {
+	var dirs []string
+
+	if home := msysToWindowsPath(getenv("HOME")); windowsDrivePathPattern.MatchString(home) || strings.HasPrefix(home, `\\`) {
+		dirs = append(dirs, filepath.Clean(home))
+	}
+
+	if userProfile := getenv("USERPROFILE"); userProfile != "" {
+		dirs = append(dirs, userProfile)
+	}
+
+	homeDrive := getenv("HOMEDRIVE")
+	homePath := getenv("HOMEPATH")
+	if homeDrive != "" && homePath != "" {
+		dirs = append(dirs, homeDrive+homePath)
+	}
+
+	return dirs
+}
+
+// ownerOnlySDDL builds a security descriptor which only grants access to the owner and the SYSTEM account.
+// The DACL is protected, so that permissions inherited from parent directories no longer apply.
+// Directories pass the same access down to the files created inside them.
+func ownerOnlySDDL(ownerSID string, isDir bool) string {
+	inherit := ""
+	if isDir {
+		inherit = "OICI"
+	}
+	return fmt.Sprintf("D:P(A;%s;FA;;;%s)(A;%s;FA;;;SY)", inherit, ownerSID, inherit)
+}
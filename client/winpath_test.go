@@ -0,0 +1,52 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtendedLengthPath(t *testing.T) {
+	assert.Equal(t, `C:\Users\me\.carina`, extendedLengthPath(`C:\Users\me\.carina`))
+
+	long := `C:\Users\me\.carina\clusters\` + strings.Repeat("a", 250)
+	assert.Equal(t, `\\?\`+long, extendedLengthPath(long))
+	assert.Equal(t, `\\?\`+long, extendedLengthPath(`\\?\`+long))
+	assert.Equal(t, `\\?\`+long, extendedLengthPath(strings.Replace(long, `\`, "/", -1)))
+
+	share := `\\server\share\` + strings.Repeat("a", 250)
+	assert.Equal(t, `\\?\UNC\server\share\`+strings.Repeat("a", 250), extendedLengthPath(share))
+}
+
+func TestMsysToWindowsPath(t *testing.T) {
+	assert.Equal(t, `C:\Users\me`, msysToWindowsPath("/c/Users/me"))
+	assert.Equal(t, `D:\home`, msysToWindowsPath("/cygdrive/d/home"))
+	assert.Equal(t, `C:\`, msysToWindowsPath("/c"))
+	assert.Equal(t, `C:\Users\me`, msysToWindowsPath(`C:\Users\me`))
+	assert.Equal(t, "/home/me", msysToWindowsPath("/home/me"))
+}
+
+func TestWindowsHomeDirs(t *testing.T) {
+	env := map[string]string{
+		"HOME":        "/c/Users/me",
+		"USERPROFILE": `C:\Users\me`,
+		"HOMEDRIVE":   `H:`,
+		"HOMEPATH":    `\`,
+	}
+	getenv := func(key string) string { return env[key] }
+	assert.Equal(t, []string{`C:\Users\me`, `C:\Users\me`, `H:\`}, windowsHomeDirs(getenv))
+
+	// Cygwin's own home directory isn't usable by Windows programs
+	env["HOME"] = "/home/me"
+	assert.Equal(t, []string{`C:\Users\me`, `H:\`}, windowsHomeDirs(getenv))
+
+	delete(env, "HOME")
+	delete(env, "USERPROFILE")
+	assert.Equal(t, []string{`H:\`}, windowsHomeDirs(getenv))
+}
+
+func TestOwnerOnlySDDL(t *testing.T) {
+	assert.Equal(t, "D:P(A;OICI;FA;;;S-1-5-21-1)(A;OICI;FA;;;SY)", ownerOnlySDDL("S-1-5-21-1", true))
+	assert.Equal(t, "D:P(A;;FA;;;S-1-5-21-1)(A;;FA;;;SY)", ownerOnlySDDL("S-1-5-21-1", false))
+}
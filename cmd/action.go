@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newActionCommand() *cobra.Command {
+	var options struct {
+		name   string
+		action string
+		params []string
+		list   bool
+		wait   bool
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "action <cluster-name> <action-name>",
+		Short: "Invoke a cloud specific action on a cluster",
+		Long: `Invoke an action on a cluster which is specific to the cloud, such as rotating the certificate authority
+of a private cloud cluster. Use --list to see the actions supported by the cloud and their parameters.`,
+		Example: `  # List the actions supported by the cloud
+  carina action --list
+
+  # Rotate the certificate authority of a private cloud cluster, then download the new credentials
+  carina action mycluster rotate-ca
+  carina credentials download mycluster`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if options.list {
+				return nil
+			}
+			if len(args) < 2 {
+				return errors.New("A cluster name and an action name are required. Run carina action --list to see the supported actions")
+			}
+			options.action = args[1]
+
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			actions, err := cxt.Client.ListClusterActions(cxt.Account)
+			if err != nil {
+				return err
+			}
+
+			if options.list {
+				console.WriteClusterActions(actions)
+				return nil
+			}
+
+			params, err := client.ParseActionParams(options.params)
+			if err != nil {
+				return err
+			}
+
+			action, err := common.LookupClusterAction(actions, options.action)
+			if err != nil {
+				return err
+			}
+			if action.Destructive {
+				destructiveOperations[action.Name] = true
+			}
+			err = confirmOperation(action.Name, options.name, nil)
+			if err != nil {
+				return err
+			}
+
+			cluster, err := cxt.Client.InvokeClusterAction(cxt.Account, options.name, options.action, params)
+			if err != nil {
+				return err
+			}
+
+			if options.wait {
+				cluster, err = cxt.Client.GetCluster(cxt.Account, cluster.GetID(), true)
+				if err != nil {
+					return err
+				}
+			}
+
+			console.WriteCluster(cluster)
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringArrayVar(&options.params, "param", nil, "Pass a key=value parameter to the action. May be specified multiple times")
+	cmd.Flags().BoolVar(&options.list, "list", false, "List the actions supported by the cloud")
+	addWaitFlags(cmd, &options.wait, "Wait for the cluster to become active")
+	addForceFlag(cmd)
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newAdoptCommand() *cobra.Command {
+	var options struct {
+		token  string
+		labels []string
+		path   string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "adopt <cluster-id>",
+		Short: "Manage a cluster which was created outside of the cli",
+		Long: `Manage a cluster which was created outside of the cli, such as in the control panel or by another tool. The cluster's credentials are downloaded, its status is recorded in carina history, and it is completed in the shell, so that carina env, delete, resize and the other commands work on it the same as on the clusters created by carina create.
+
+The cluster can be specified by its id or name. Labels can be added with --label, because only the clusters created with carina create --label have labels.`,
+		Example:           `  carina adopt 6f0c2b5e-0d1a-4c3f-9b6e-2c4f1a7d8e90 --label env=prod`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A cluster id is required")
+			}
+			options.token = args[0]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			labels, err := client.ParseLabels(options.labels)
+			if err != nil {
+				return err
+			}
+
+			cluster, credentialsPath, err := cxt.Client.AdoptCluster(cxt.Account, options.token, client.AdoptClusterOptions{Labels: labels, CredentialsPath: options.path})
+			if err != nil {
+				return err
+			}
+
+			console.WriteCluster(cluster)
+			console.WriteValue(credentialsPath,
+				"#",
+				fmt.Sprintf("# Credentials written to \"%s\"", credentialsPath),
+				client.CredentialsNextStepsString(cluster.GetName()),
+				"#")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&options.labels, "label", nil, "Add a key=value label to the cluster, e.g. env=prod. May be specified multiple times")
+	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory where the credentials should be saved")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newAgentCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "agent",
+		Short: "Run carina credentials refresh --agent in the background",
+		Long:  "Install the agent as a user-level service, systemd on Linux and launchd on macOS, so that the cluster credentials are refreshed in the background before their certificates expire",
+	}
+
+	cmd.AddCommand(newAgentInstallCommand())
+	cmd.AddCommand(newAgentUninstallCommand())
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newAgentInstallCommand() *cobra.Command {
+	var options struct {
+		interval    time.Duration
+		renewBefore time.Duration
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "install",
+		Short: "Install and start the agent as a user-level service",
+		Long: `Install and start the agent as a user-level service, which runs carina credentials refresh --agent with the current profile. The service starts on login and is restarted if it fails. Running install again replaces the service with the new settings.
+
+The service doesn't inherit the environment variables or flags of the current shell, so the account must be saved in a profile in the config file.`,
+		Example: `  carina agent install
+  carina agent install --profile work --interval 30m`,
+		PersistentPreRunE: unauthenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if options.interval <= 0 {
+				return errors.New("--interval must be > 0")
+			}
+			if options.renewBefore < 0 {
+				return errors.New("--renew-before must be >= 0")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service, err := buildAgentService(options.interval, options.renewBefore)
+			if err != nil {
+				return err
+			}
+
+			path, err := client.NewAgentServiceManager().Install(service)
+			if err != nil {
+				return err
+			}
+
+			console.WriteValue(path, fmt.Sprintf("Installed the agent to %s", path))
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&options.interval, "interval", defaultRefreshInterval, "How often the agent checks the credentials, e.g. 30m")
+	cmd.Flags().DurationVar(&options.renewBefore, "renew-before", defaultRenewBefore, "Download the credentials again when the client certificate expires within this amount of time, e.g. 72h")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newAgentUninstallCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:               "uninstall",
+		Short:             "Stop the agent and remove the user-level service",
+		PersistentPreRunE: unauthenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := client.NewAgentServiceManager().Uninstall()
+			if err != nil {
+				return err
+			}
+
+			console.Write("Removed the agent from %s", path)
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+// buildAgentService describes the service which runs carina credentials refresh --agent with the current profile
+func buildAgentService(interval time.Duration, renewBefore time.Duration) (client.AgentService, error) {
+	var service client.AgentService
+
+	profile, err := resolveAgentProfile()
+	if err != nil {
+		return service, err
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return service, fmt.Errorf("Unable to locate the carina executable: %s", err)
+	}
+	executable, err = filepath.EvalSymlinks(executable)
+	if err != nil {
+		return service, fmt.Errorf("Unable to locate the carina executable: %s", err)
+	}
+
+	service.Executable = executable
+	service.Args = []string{"credentials", "refresh", "--agent",
+		"--config", viper.ConfigFileUsed(),
+		"--profile", profile,
+		"--interval", interval.String(),
+		"--renew-before", renewBefore.String(),
+	}
+
+	// The credentials are saved relative to these, so the agent must see the same values as the current shell
+	service.Environment = make(map[string]string)
+	for _, envVar := range []string{client.CarinaHomeDirEnvVar, "XDG_DATA_HOME"} {
+		if value := os.Getenv(envVar); value != "" {
+			service.Environment[envVar] = value
+		}
+	}
+
+	return service, nil
+}
+
+// resolveAgentProfile returns the profile used by the agent: --profile, CARINA_PROFILE or the default profile.
+// A profile is required because the service doesn't inherit the account flags or environment variables.
+func resolveAgentProfile() (string, error) {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		return "", errors.New("The agent requires a profile, but no config file was found. See carina --help for how to create a profile")
+	}
+
+	profile := cxt.Profile
+	if profile == "" {
+		profile = os.Getenv(CarinaProfileEnvVar)
+	}
+	if profile == "" && viper.InConfig("default") {
+		profile = "default"
+	}
+	if profile == "" {
+		return "", fmt.Errorf("The agent requires a profile, because it doesn't inherit the account flags or environment variables. Use --profile with a profile from %s", configFile)
+	}
+
+	if !viper.InConfig(profile) {
+		return "", fmt.Errorf("Profile, %s, not found in %s", profile, configFile)
+	}
+	return profile, nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newApplyCommand() *cobra.Command {
+	var options struct {
+		filename        string
+		prune           bool
+		allowDeprecated bool
+		wait            bool
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "apply -f <manifest>",
+		Short: "Create, resize and delete clusters to match a manifest",
+		Long: `Create, resize and delete clusters to match a YAML or JSON manifest. The changes are printed and confirmed before they are made, use --dry-run to only print them.
+
+Example manifest:
+
+  clusters:
+    - name: web
+      template: Kubernetes*
+      nodes: 3
+      labels:
+        env: prod
+
+Existing clusters are resized and relabeled to match. Labels are local metadata, saved in the carina cache on this machine instead of with the clusters. Clusters which are not in the manifest are only deleted with --prune.`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if options.filename == "" {
+				return errors.New("A manifest is required, e.g. carina apply -f clusters.yaml")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifest, err := client.ReadClusterManifest(options.filename)
+			if err != nil {
+				return err
+			}
+
+			applyOpts := client.ApplyOptions{
+				Prune: options.prune,
+				// Deprecated templates are only blocked in strict mode
+				AllowDeprecated: options.allowDeprecated || !cxt.Strict,
+				WaitUntilActive: options.wait,
+			}
+			plan, err := cxt.Client.PlanApply(cxt.Account, manifest, applyOpts)
+			if err != nil {
+				return err
+			}
+
+			console.WritePlan(plan.OperationPlan(), cxt.DryRun)
+			if cxt.DryRun || !plan.HasChanges() {
+				return nil
+			}
+
+			err = confirmApply(plan)
+			if err != nil {
+				return err
+			}
+
+			results, err := cxt.Client.Apply(cxt.Account, plan, applyOpts)
+			if err != nil {
+				return err
+			}
+			console.WriteClusterOperationResults(results, "")
+			return checkOperationResults(results, "apply", "changes")
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.filename, "filename", "f", "", "The YAML or JSON manifest describing the desired clusters")
+	cmd.Flags().BoolVar(&options.prune, "prune", false, "Delete clusters which are not in the manifest")
+	cmd.Flags().BoolVar(&options.allowDeprecated, "allow-deprecated", false, "Allow a deprecated template to be used when --strict is specified")
+	addWaitFlags(cmd, &options.wait, "Wait for each change to finish")
+	addForceFlag(cmd)
+	addDryRunFlag(cmd, "Print the changes which would be made to match the manifest, without making them")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+// confirmApply prompts the user before applying the changes in a plan
+func confirmApply(plan *client.ApplyPlan) error {
+	if cxt.AssumeYes {
+		return nil
+	}
+
+	if !console.IsInteractive() {
+		return fmt.Errorf("Applying %d changes requires confirmation. Use --yes to skip the confirmation", len(plan.Changes))
+	}
+	if !console.Confirm(fmt.Sprintf("Are you sure you want to apply %d changes?", len(plan.Changes))) {
+		return errors.New("Canceled apply")
+	}
+	return nil
+}
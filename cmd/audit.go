@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newAuditCommand() *cobra.Command {
+	var options struct {
+		client.AuditFilter
+		since time.Duration
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Show the clusters created, deleted, resized, grown, rebuilt and upgraded from this computer",
+		Long: `Show the audit log of the changes made to clusters from this computer, oldest first.
+
+Every create, delete, resize, grow, rebuild and upgrade, including those made by carina apply and batch operations,
+is appended to CARINA_HOME/audit.log with the time, account, user, host, arguments and result.
+Each line of the audit log is a JSON document. The audit log is not written when the cache is disabled.`,
+		Example: `  # Who deleted the prod cluster?
+  carina audit --cluster prod --operation delete
+
+  # Show the operations which failed in the last day
+  carina audit --failed --since 24h`,
+		PersistentPreRunE: unauthenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			common.NameMatchPolicy.Mode, err = common.ParseMatchMode(cxt.MatchMode)
+			if err != nil {
+				return err
+			}
+
+			if options.since > 0 {
+				options.Since = time.Now().Add(-options.since)
+			}
+
+			entries, err := cxt.Client.ReadAuditLog(options.AuditFilter)
+			if err != nil {
+				return err
+			}
+
+			if len(entries) == 0 && console.WriteEmptyState("No changes have been recorded in the audit log.") {
+				return nil
+			}
+			console.WriteAuditLog(entries)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Cluster, "cluster", "", "Only show the changes to clusters with a matching name or id, e.g. prod*. See --match-mode")
+	cmd.Flags().StringVar(&options.Operation, "operation", "", "Only show an operation: create, delete, resize, grow, rebuild or upgrade")
+	cmd.Flags().StringVar(&options.Account, "account", "", "Only show the changes made with an account, e.g. a username")
+	cmd.Flags().DurationVar(&options.since, "since", 0, "Only show the changes made recently, e.g. 24h")
+	cmd.Flags().BoolVar(&options.Failed, "failed", false, "Only show the operations which failed")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/getcarina/carina/common"
 	"github.com/getcarina/carina/console"
 	"github.com/spf13/cobra"
 )
@@ -12,27 +13,45 @@ import (
 func newAutoScaleCommand() *cobra.Command {
 	var options struct {
 		name      string
-		autoscale bool
+		autoscale common.AutoScale
 	}
 
 	cmd := &cobra.Command{
-		Use:               "autoscale <cluster-name> off/on",
-		Short:             "Change the autoscaling setting on a cluster",
-		Long:              "Change the autoscaling setting on a cluster",
-		Hidden:            true,
+		Use:   "autoscale <cluster-name> [off/on]",
+		Short: "Change the autoscaling setting on a cluster",
+		Long:  "Change the autoscaling setting on a cluster. Autoscaling is turned on when --min or --max is specified. Only clusters whose template supports autoscaling can be autoscaled",
+		Example: `  carina autoscale mycluster on
+  carina autoscale mycluster --min 3 --max 10
+  carina autoscale mycluster off`,
 		PersistentPreRunE: authenticatedPreRunE,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 2 {
-				return errors.New("A cluster name and the autoscale value (off/on) is required")
+			limited := cmd.Flags().Changed("min") || cmd.Flags().Changed("max")
+			if len(args) < 2 && !limited {
+				return errors.New("A cluster name and the autoscale value (off/on), or --min and --max, are required")
 			}
 
-			switch strings.ToLower(args[1]) {
-			case "off", "false", "0":
-				options.autoscale = false
-			case "on", "true", "1":
-				options.autoscale = true
-			default:
-				return fmt.Errorf("Invalid autoscale value: %s. Allowed values are off and on", args[1])
+			options.autoscale.Enabled = true
+			if len(args) >= 2 {
+				switch strings.ToLower(args[1]) {
+				case "off", "false", "0":
+					options.autoscale.Enabled = false
+				case "on", "true", "1":
+				default:
+					return fmt.Errorf("Invalid autoscale value: %s. Allowed values are off and on", args[1])
+				}
+			}
+
+			if limited && !options.autoscale.Enabled {
+				return errors.New("--min and --max can't be used when turning autoscaling off")
+			}
+			if cmd.Flags().Changed("min") && options.autoscale.MinNodes < 1 {
+				return errors.New("--min must be >= 1")
+			}
+			if cmd.Flags().Changed("max") && options.autoscale.MaxNodes < 1 {
+				return errors.New("--max must be >= 1")
+			}
+			if options.autoscale.MinNodes > 0 && options.autoscale.MaxNodes > 0 && options.autoscale.MinNodes > options.autoscale.MaxNodes {
+				return errors.New("--min must be <= --max")
 			}
 
 			return bindClusterNameArg(args, &options.name)
@@ -49,7 +68,9 @@ func newAutoScaleCommand() *cobra.Command {
 		},
 	}
 
-	cmd.ValidArgs = []string{"cluster-name"}
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().IntVar(&options.autoscale.MinNodes, "min", 0, "The minimum number of nodes when autoscaling")
+	cmd.Flags().IntVar(&options.autoscale.MaxNodes, "max", 0, "The maximum number of nodes when autoscaling")
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
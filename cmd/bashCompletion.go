@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"os"
-
 	"github.com/spf13/cobra"
 )
 
@@ -12,9 +10,10 @@ func newBashCompletionCmd() *cobra.Command {
 		Short:             "Generate a bash completion file for the carina cli",
 		Long:              "Generate a bash completion file for the carina cli",
 		Hidden:            true,
+		Deprecated:        "use carina completion bash instead",
 		PersistentPreRunE: unauthenticatedPreRunE,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return cmd.Parent().GenBashCompletion(os.Stdout)
+			return writeCompletion(cmd.Root(), "bash")
 		},
 	}
 
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newBenchmarksCommand() *cobra.Command {
+	var options client.BenchmarkOptions
+
+	var cmd = &cobra.Command{
+		Use:   "benchmarks",
+		Short: "Measure how long it takes to create and delete clusters",
+		Long: `Measure the provisioning performance of a template: create a throwaway cluster, wait for it to become active, then delete it and wait for it to be deleted, repeated for --cycles. The clusters are created one at a time so that the cycles don't slow each other down.
+
+Prints the duration of each cycle, and the min, mean, p50, p95 and max durations of the successful cycles. Use --format json to record the results over time. The command fails when any of the cycles failed.`,
+		Example: `  carina benchmarks --template "Kubernetes 1.5.2 on LXC" --cycles 5
+  carina benchmarks --template "Swarm*" --nodes 3 --format json >> benchmarks.jsonl`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if options.Template == "" {
+				return errors.New("--template is required")
+			}
+			if options.Cycles < 1 {
+				return errors.New("--cycles must be >= 1")
+			}
+			if options.Nodes < 1 {
+				return errors.New("--nodes must be >= 1")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := cxt.Client.Benchmark(cxt.Account, options)
+			if err != nil {
+				return err
+			}
+
+			console.WriteBenchmarkResult(result)
+			if failures := result.Failures(); failures > 0 {
+				return fmt.Errorf("%d of %d benchmark cycles failed", failures, len(result.Cycles))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.Template, "template", "t", "", "Name of the template to benchmark")
+	cmd.RegisterFlagCompletionFunc("template", completeTemplateNames)
+	cmd.Flags().IntVar(&options.Nodes, "nodes", 1, "Number of nodes in each cluster")
+	cmd.Flags().IntVar(&options.Cycles, "cycles", 3, "Number of times to create and delete a cluster")
+	addNoProgressFlag(cmd)
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
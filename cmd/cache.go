@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newCacheCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the cached API tokens",
+		Long:  "Manage the API tokens and other data cached in CARINA_HOME",
+	}
+
+	cmd.AddCommand(newCacheInfoCommand())
+	cmd.AddCommand(newCacheInvalidateCommand())
+	cmd.AddCommand(newCacheRotateKeyCommand())
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newCacheInvalidateCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:               "invalidate",
+		Short:             "Remove the cached API token for an account",
+		Long:              "Remove the cached API token for the account selected with --profile or the auth flags, so that the next command authenticates again, e.g. after rotating a password or API key. The account's other cached data is kept.",
+		Example:           "  carina cache invalidate --profile prod",
+		PersistentPreRunE: authenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			found, err := cxt.Client.Cache.InvalidateToken(cxt.Account)
+			if err != nil {
+				return err
+			}
+
+			if found {
+				console.Write("Removed the cached token for %s", cxt.Account.GetID())
+			} else {
+				console.Write("No token was cached for %s", cxt.Account.GetID())
+			}
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newCacheInfoCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:               "info",
+		Short:             "Show the cache location and what is cached",
+		Long:              "Show the cache file location, size and schema version, and for each account whether a token is cached, how old it is, and when the clusters and templates were last cached. Useful when troubleshooting why a command authenticates every time.",
+		PersistentPreRunE: unauthenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := cxt.Client.Cache.Err(); err != nil {
+				return err
+			}
+
+			info, err := cxt.Client.Cache.GetInfo()
+			if err != nil {
+				return err
+			}
+
+			console.WriteCacheInfo(info)
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newCacheRotateKeyCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "rotate-key",
+		Short: "Re-encrypt the cache and credentials with a new key",
+		Long: `Re-encrypt the token cache and the cluster credentials in CARINA_HOME with a new key, upgrading the cache to the current schema version.
+
+When credentials.encryption is keychain, a new key is generated and saved to the keychain. When it is passphrase, the files are re-encrypted with the passphrase in CARINA_NEW_PASSPHRASE, which replaces CARINA_PASSPHRASE for subsequent commands.
+
+Every file is decrypted before any is replaced, so nothing is changed when a file can't be decrypted with the current key.`,
+		Example: `  carina cache rotate-key
+  CARINA_NEW_PASSPHRASE=... carina cache rotate-key`,
+		PersistentPreRunE: unauthenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rotation, err := cxt.Client.RotateEncryptionKey()
+			if err != nil {
+				return err
+			}
+
+			for _, file := range rotation.Files {
+				console.Write("Re-encrypted %s", file)
+			}
+			console.Write("Re-encrypted %d files with a new %s key", len(rotation.Files), rotation.Mode)
+			if rotation.Mode == client.EncryptionPassphrase {
+				console.Write("Set CARINA_PASSPHRASE to the value of %s for subsequent commands", client.CarinaNewPassphraseEnvVar)
+			}
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
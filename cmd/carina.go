@@ -3,9 +3,15 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/getcarina/carina/client"
 	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/getcarina/carina/make-coe"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -27,7 +33,7 @@ func newCarinaCommand() *cobra.Command {
 		Long:  "Create and interact with clusters on both Rackspace Public and Private Clouds",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if opts.Version {
-				writeVersion()
+				writeVersion(false)
 				return nil
 			}
 			fmt.Print(cmd.UsageString())
@@ -44,31 +50,67 @@ func newCarinaCommand() *cobra.Command {
 	//
 	cmd.PersistentFlags().StringVar(&cxt.ConfigFile, "config", "", "config file (default is CARINA_HOME/config.toml)")
 	cmd.PersistentFlags().BoolVar(&cxt.CacheEnabled, "cache", true, "Cache API tokens and update times")
-	cmd.PersistentFlags().BoolVar(&cxt.Debug, "debug", false, "Print additional debug messages to stdout")
+	cmd.PersistentFlags().BoolVar(&cxt.Debug, "debug", false, "Log additional debug messages, same as --log-level debug")
+	cmd.PersistentFlags().BoolVar(&cxt.DebugHTTP, "debug-http", false, "Log the method, URL, status, latency, headers and body of every API call, with credentials redacted")
+	cmd.PersistentFlags().BoolVar(&cxt.Timing, "timing", false, "Print how many API calls were made and how long was spent authenticating, calling the API and polling, to stderr")
+	cmd.PersistentFlags().StringVar(&cxt.LogLevel, "log-level", "", "Minimum level of the messages to log: debug, info, warn or error. Defaults to warn")
+	cmd.PersistentFlags().StringVar(&cxt.LogFormat, "log-format", common.LogFormatText, "Format of the log entries, which are printed to stderr: text or json. Warnings include a code field, e.g. cache-unavailable")
+	cmd.PersistentFlags().StringVar(&cxt.LogFile, "log-file", "", "Append the logs to a file, instead of printing them to stderr")
 	cmd.PersistentFlags().BoolVar(&cxt.Silent, "silent", false, "Do not print to stdout")
+	cmd.PersistentFlags().BoolVarP(&cxt.Quiet, "quiet", "q", false, "Only print the essential values, such as the ID of the cluster or the path to the credentials, without informational messages or the cluster status while waiting. Listings are printed in full, see carina clusters --ids")
+	cmd.PersistentFlags().BoolVar(&cxt.NoColor, "no-color", false, fmt.Sprintf("Do not highlight values in color, same as setting the %s environment variable or the color setting to never", console.NoColorEnvVar))
+	cmd.PersistentFlags().BoolVar(&cxt.Strict, "strict", false, "Treat warnings, such as using a deprecated template, as errors")
+	cmd.PersistentFlags().DurationVar(&cxt.PollInterval, "poll-interval", 0, "How often to check the cluster status when waiting, e.g. 30s. Defaults to the cloud's recommended interval")
+	cmd.PersistentFlags().DurationVar(&cxt.WaitTimeout, "wait-timeout", 0, "Maximum amount of time to wait for a cluster operation, e.g. 20m. Defaults to waiting forever")
+	cmd.PersistentFlags().StringVar(&cxt.PathTemplate, "path-template", "", "Where credentials are saved within the credentials directory, e.g. {{.Account}}/{{.Cloud}}/{{.ClusterName}}. Available fields: Account, Cloud, Prefix and ClusterName. Defaults to the credentials.path-template setting or {{.Prefix}}/{{.ClusterName}}")
+	cmd.PersistentFlags().BoolVar(&cxt.CreateHome, "create-home", false, "Create CARINA_HOME when it doesn't exist, instead of failing")
+	cmd.PersistentFlags().BoolVar(&cxt.Refresh, "refresh", false, "Ignore the cached templates and list them from the API. Templates are cached for the template-cache-ttl setting, which defaults to 1h")
+	cmd.PersistentFlags().StringVar(&cxt.MatchMode, "match-mode", string(common.MatchGlob), "How name filters and patterns are matched: glob (case-insensitive, * wildcards), regex (matches the whole name) or exact")
+	cmd.PersistentFlags().StringVar(&cxt.Format, "format", string(console.FormatTable), "Output format: table or json. See carina schema for the json output schemas")
+	cmd.PersistentFlags().BoolVar(&cxt.FailFast, "fail-fast", false, "When an operation on multiple clusters fails, skip the clusters which haven't started yet")
+	cmd.PersistentFlags().BoolVarP(&cxt.KeepGoing, "keep-going", "k", false, "When an operation on multiple clusters fails, keep going and attempt every cluster. This is the default")
+	cmd.PersistentFlags().BoolVar(&cxt.NoLock, "no-lock", false, "Change a cluster even when another carina command holds its lock, e.g. a resize racing a delete")
+	cmd.PersistentFlags().BoolVarP(&cxt.AssumeYes, "yes", "y", false, "Skip confirmation prompts, such as when deleting a cluster")
+	cmd.PersistentFlags().IntVar(&cxt.Retries, "retries", common.HTTPRetryPolicy.MaxRetries, "Number of times to retry a request after a transient API error, such as 503 Service Unavailable")
+	cmd.PersistentFlags().StringVar(&cxt.Proxy, "proxy", "", "Send API requests through a proxy, e.g. http://proxy.example.com:3128. Defaults to HTTPS_PROXY/HTTP_PROXY, excluding NO_PROXY")
+	cmd.PersistentFlags().StringVar(&cxt.CACert, "cacert", "", "Trust the PEM encoded certificate authorities in a file when connecting to the API, such as a TLS-intercepting proxy's CA")
+	cmd.PersistentFlags().BoolVar(&cxt.Insecure, "insecure-skip-verify", false, "Do not verify the API's certificate. Prefer --cacert")
+	cmd.PersistentFlags().DurationVar(&cxt.RetryMaxWait, "retry-max-wait", common.HTTPRetryPolicy.MaxWait, "Maximum amount of time to wait between retries")
 
 	// Account flags
 	cmd.PersistentFlags().StringVar(&cxt.Profile, "profile", "", "Use saved credentials from a profile [CARINA_PROFILE]")
 	cmd.PersistentFlags().BoolVar(&cxt.ProfileDisabled, "no-profile", false, "Ignore profiles and use flags and/or environment variables only")
 	cmd.PersistentFlags().StringVar(&cxt.Username, "username", "", "Username [CARINA_USERNAME/RS_USERNAME/OS_USERNAME]")
 	cmd.PersistentFlags().StringVar(&cxt.APIKey, "apikey", "", "Public Cloud API Key [CARINA_APIKEY/RS_API_KEY]")
-	cmd.PersistentFlags().StringVar(&cxt.APIKey, "api-key", "", "OBSOLETE. Use --apikey")
+	cmd.PersistentFlags().StringVar(&cxt.Token, "token", "", "Public Cloud auth token, used instead of the API key, e.g. a short-lived token injected by CI. Requires --endpoint when the API key isn't specified [CARINA_TOKEN]")
 	cmd.PersistentFlags().StringVar(&cxt.Password, "password", "", "Private Cloud Password [OS_PASSWORD]")
+	cmd.PersistentFlags().BoolVar(&cxt.PasswordStdin, "password-stdin", false, "Read the Private Cloud Password, or Public Cloud API Key, from stdin, e.g. in CI. Missing credentials are prompted for when stdin is a terminal")
 	cmd.PersistentFlags().StringVar(&cxt.Project, "project", "", "Private Cloud Project Name [OS_PROJECT_NAME]")
 	cmd.PersistentFlags().StringVar(&cxt.Domain, "domain", "", "Private Cloud Domain Name [OS_DOMAIN_NAME]")
+	cmd.PersistentFlags().StringVar(&cxt.ProjectID, "project-id", "", "Private Cloud Project ID, instead of the project name and domain [OS_PROJECT_ID]")
+	cmd.PersistentFlags().StringVar(&cxt.ProjectDomain, "project-domain", "", "Private Cloud Project Domain Name, defaults to --domain [OS_PROJECT_DOMAIN_NAME]")
+	cmd.PersistentFlags().StringVar(&cxt.UserDomain, "user-domain", "", "Private Cloud User Domain Name, defaults to --domain [OS_USER_DOMAIN_NAME]")
+	cmd.PersistentFlags().StringVar(&cxt.ApplicationCredentialID, "application-credential-id", "", "Private Cloud Application Credential ID [OS_APPLICATION_CREDENTIAL_ID]")
+	cmd.PersistentFlags().StringVar(&cxt.ApplicationCredentialName, "application-credential-name", "", "Private Cloud Application Credential Name, used with --username [OS_APPLICATION_CREDENTIAL_NAME]")
+	cmd.PersistentFlags().StringVar(&cxt.ApplicationCredentialSecret, "application-credential-secret", "", "Private Cloud Application Credential Secret, used instead of --password [OS_APPLICATION_CREDENTIAL_SECRET]")
 	cmd.PersistentFlags().StringVar(&cxt.Region, "region", "", "Region [CARINA_REGION/RS_REGION_NAME/OS_REGION_NAME]")
 	cmd.PersistentFlags().StringVar(&cxt.AuthEndpoint, "auth-endpoint", "", "Private Cloud Authentication endpoint [OS_AUTH_URL]")
-	cmd.PersistentFlags().StringVar(&cxt.EndpointOverride, "endpoint", "", "Custom API endpoint [CARINA_ENDPOINT/OS_ENDPOINT]")
-	cmd.PersistentFlags().StringVar(&cxt.CloudType, "cloud", "", "The cloud type: public or private")
+	cmd.PersistentFlags().StringVar(&cxt.AuthEndpoint, "identity-endpoint", "", fmt.Sprintf("Public Cloud Identity endpoint: a URL or one of %s. Defaults to %s. See carina identity-endpoints [RS_AUTH_URL]", strings.Join(makecoe.IdentityEndpointAliases(), ", "), makecoe.DefaultIdentityEndpoint))
+	cmd.PersistentFlags().StringVar(&cxt.AuthEndpoint, "auth-url", "", "Authentication endpoint, same as --auth-endpoint [OS_AUTH_URL/RS_AUTH_URL]")
+	cmd.PersistentFlags().StringVar(&cxt.EndpointOverride, "endpoint", "", "Use a different API endpoint than the one from the service catalog, e.g. a staging environment or an API mock. Overrides the profile [CARINA_ENDPOINT/OS_ENDPOINT]")
+	cmd.PersistentFlags().StringVar(&cxt.CloudType, "cloud", "", fmt.Sprintf("The cloud type: %s", strings.Join(client.CloudProviderNames(), ", ")))
+	cmd.PersistentFlags().StringVar(&cxt.Fixtures, "fixtures", "", "JSON file of the clusters and templates served by --cloud fake, for testing scripts without an account [CARINA_FIXTURES]")
+	cmd.PersistentFlags().BoolVar(&cxt.SaveAccount, "save", false, "Cache the auth token when the credentials are specified with flags. By default, credential flags are used for a single command")
+	cmd.PersistentFlags().StringVar(&cxt.AuthSource, "auth-source", "", "Where to read the API key or password: env (flags, environment variables and profiles) or keychain. See carina keychain store")
 
 	// Hide local development flags
-	cmd.PersistentFlags().MarkHidden("api-key")
 	cmd.PersistentFlags().MarkHidden("config")
 	cmd.PersistentFlags().MarkHidden("cache")
 	cmd.PersistentFlags().MarkHidden("endpoint")
 
-	// Don't show usage on errors
+	// Don't show usage on errors, and print errors with console.WriteError so that they can be formatted as json
 	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
 
 	authHelp := `Authentication:
 The user credentials are used to automatically detect the cloud with which the cli should communicate. First, it looks for the Rackspace Public Cloud environment variables, such as CARINA_USERNAME/CARINA_APIKEY or RS_USERNAME/RS_API_KEY. Then it looks for Rackspace Private Cloud environment variables, such as OS_USERNAME/OS_PASSWORD. Use --cloud flag to explicitly select a cloud.
@@ -82,6 +124,9 @@ In the following example, the detected cloud is 'public' because --apikey is spe
 In the following example, 'private' will be used, even when the Rackspace Public Cloud environment variables are present, because --cloud is specified:
     carina --cloud private ls
 
+Credentials specified with flags override profiles and environment variables for a single command. The auth token is not cached, unless --save is specified, so that a one-off command against another account doesn't change your configuration:
+    carina --username alice --apikey abc123 ls
+
 Profiles:
 Credentials can be saved under a profile name in CARINA_HOME/config.toml, and then used with the --profile flag. If --profile is not specified, and the config file contains a profile named 'default', it will be used when no credential flags are provided.
 
@@ -92,6 +137,12 @@ Below is a sample config file:
     username="alicia"
     apikey="abc123"
 
+    [uk]
+    cloud="public"
+    username="alicia"
+    apikey="abc123"
+    identity-endpoint="uk"
+
     [dev]
     cloud="private"
     username-var="OS_USERNAME"
@@ -107,6 +158,15 @@ In the following example, the default profile is used because no other credentia
 In the following example, the dev profile is used:
     carina --profile dev ls
 
+A profile can also override the format, color, columns, list-columns, wait, poll-interval and wait-timeout settings, e.g. json output for a CI profile:
+
+    [ci]
+    cloud="public"
+    username-var="CI_USERNAME"
+    apikey-var="CI_APIKEY"
+    format="json"
+    color="never"
+
 See https://getcarina.com/docs/reference/carina-cli for additional documentation, FAQ and examples.
 `
 
@@ -118,35 +178,158 @@ See https://getcarina.com/docs/reference/carina-cli for additional documentation
   CARINA_HOME
     directory that stores your cluster tokens and credentials
     current setting: %s
+  CARINA_SHARED_HOME
+    set to true when CARINA_HOME is shared by multiple users or jobs, e.g. on a build server,
+    to keep the cache and credentials of each account in a separate directory which only its owner can access
+  HTTPS_PROXY, HTTP_PROXY, NO_PROXY
+    proxy used to connect to the API, unless --proxy is specified
 `, carinaHome)
-	cmd.SetUsageTemplate(fmt.Sprintf("%s\n%s\n\n%s", cmd.UsageTemplate(), envHelp, authHelp))
+	cmd.SetUsageTemplate(fmt.Sprintf("%s\n%s\n%s\n\n%s", cmd.UsageTemplate(), envHelp, exitCodesHelp, authHelp))
 
 	cobra.OnInitialize(initConfig)
 
 	cmd.AddCommand(
+		newActionCommand(),
+		newAdoptCommand(),
+		newAgentCommand(),
+		newApplyCommand(),
+		newAuditCommand(),
 		newAutoScaleCommand(),
+		newCacheCommand(),
 		newBashCompletionCmd(),
+		newBenchmarksCommand(),
+		newCompletionCommand(),
+		newConformanceCommand(),
+		newContextCommand(),
+		newConfigCommand(),
 		newCreateCommand(),
 		newCredentialsCommand(),
 		newDeleteCommand(),
+		newDeprecationsCommand(),
+		newDiffCommand(),
 		newEnvCommand(),
+		newExecCommand(),
 		newGetCommand(),
 		newGrowCommand(),
+		newHistoryCommand(),
+		newIdentityEndpointsCommand(),
+		newInstallCommand(),
+		newKeychainCommand(),
+		newKeypairsCommand(),
+		newKubeconfigCommand(),
+		newLabelCommand(),
+		newLoginCommand(),
+		newLogoutCommand(),
+		newMetricsCommand(),
+		newMigrateCommand(),
+		newMigrateHomeCommand(),
 		newResizeCommand(),
 		newClustersCommand(),
+		newNodesCommand(),
+		newTemplateCommand(),
 		newTemplatesCommand(),
 		newQuotasCommand(),
 		newRebuildCommand(),
+		newRepairCommand(),
+		newResumeCommand(),
+		newSchemaCommand(),
+		newServeCommand(),
+		newServiceStatusCommand(),
+		newSmokeTestCommand(),
+		newSSHCommand(),
+		newUpdateCommand(),
+		newUpgradeCommand(),
 		newVersionCommand(),
+		newWaitCommand(),
+		newWhoAmICommand(),
 	)
 	return cmd
 }
 
+// shutdownTimeout is how long to wait for pending operations to stop after receiving a signal, before exiting anyway
+const shutdownTimeout = 5 * time.Second
+
 // Execute the root carina command
 func Execute() {
 	rootCmd := newCarinaCommand()
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(-1)
+	done := make(chan struct{})
+	handleSignals(done)
+
+	args, warnings, err := translateLegacyArgs(rootCmd, os.Args[1:])
+	if err != nil {
+		console.WriteError(err)
+		os.Exit(exitCode(err))
+	}
+	cxt.LegacyWarnings = warnings
+	rootCmd.SetArgs(args)
+
+	err = rootCmd.Execute()
+	close(done)
+	if common.IsShuttingDown() {
+		shutdown()
+		os.Exit(exitCodeInterrupted)
+	}
+	// Wipe the decrypted credentials used while the command, or the command run by carina exec, was running
+	shutdown()
+	if common.Timing.Enabled {
+		console.WriteTimingSummary(common.Timing.Summary())
+	}
+	if status, ok := err.(exitStatusError); ok {
+		// The command has already reported why it failed
+		os.Exit(exitCode(status))
+	}
+	if err != nil {
+		// Errors returned before the command initialized, such as an invalid flag, should still respect --format
+		if cxt.Format == string(console.FormatJSON) {
+			console.SetFormat(cxt.Format)
+		}
+		console.WriteError(err)
+		os.Exit(exitCode(common.CategorizeError(err)))
+	}
+}
+
+// handleSignals cancels pending operations on SIGINT/SIGTERM, giving them a chance to stop cleanly before exiting.
+// The command is done when it has returned, and then Execute shuts down the client, so that the cache isn't saved
+// while the command is still using it. When the command doesn't stop in time, the client is aborted instead.
+func handleSignals(done <-chan struct{}) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-signals
+		common.Log.WriteDebug("Received %s, cancelling pending operations", sig)
+		common.BeginShutdown()
+
+		if waitForCommand(done, signals, shutdownTimeout) {
+			return
+		}
+
+		if cxt.Client != nil {
+			cxt.Client.Abort()
+		}
+		os.Exit(exitCodeInterrupted)
+	}()
+}
+
+// waitForCommand waits for the cancelled command to return, returning false when it didn't stop in time,
+// or a second signal was received
+func waitForCommand(done <-chan struct{}, signals <-chan os.Signal, timeout time.Duration) bool {
+	select {
+	case <-done:
+		return true
+	case <-signals:
+		common.Log.WriteDebug("Received a second signal, exiting immediately")
+	case <-time.After(timeout):
+		common.Log.WriteDebug("Timed out waiting for pending operations to stop")
+	}
+	return false
+}
+
+// shutdown flushes the cache, wipes temporary credentials and prints a summary of the operations which did not complete.
+// It must only be called once the command has returned.
+func shutdown() {
+	if cxt.Client != nil {
+		cxt.Client.Shutdown()
 	}
 }
 
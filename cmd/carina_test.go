@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForCommand(t *testing.T) {
+	done := make(chan struct{})
+	close(done)
+	assert.True(t, waitForCommand(done, make(chan os.Signal), time.Hour), "The command returned, so Execute shuts down")
+
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGINT
+	assert.False(t, waitForCommand(make(chan struct{}), signals, time.Hour), "A second signal should exit immediately")
+
+	assert.False(t, waitForCommand(make(chan struct{}), make(chan os.Signal), time.Millisecond), "The command didn't stop in time")
+}
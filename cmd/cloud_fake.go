@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/fake"
+)
+
+func init() {
+	client.RegisterCloudProvider(client.CloudProvider{
+		Name:            "fake",
+		Detect:          false,
+		LoadEnvironment: loadFakeEnvironment,
+		LoadProfile:     loadFakeProfile,
+		NewAccount: func(settings client.AccountSettings) client.Account {
+			return &fake.Account{Fixtures: settings.Fixtures}
+		},
+	})
+}
+
+// loadFakeEnvironment fills in the settings for the fake cloud from environment variables
+func loadFakeEnvironment(settings *client.AccountSettings) error {
+	// fixtures = --fixtures -> CARINA_FIXTURES
+	if settings.Fixtures == "" {
+		settings.Fixtures = os.Getenv(CarinaFixturesEnvVar)
+		if settings.Fixtures == "" {
+			return fmt.Errorf("Fixtures were not specified via --fixtures or %s", CarinaFixturesEnvVar)
+		}
+		common.Log.WriteDebug("Fixtures: %s", CarinaFixturesEnvVar)
+	} else {
+		common.Log.WriteDebug("Fixtures: --fixtures")
+	}
+
+	_, err := fake.ReadFixtures(settings.Fixtures)
+	return err
+}
+
+// loadFakeProfile reads the settings for the fake cloud from a profile
+func loadFakeProfile(settings *client.AccountSettings, read client.ProfileReader, secretRequired bool) (err error) {
+	settings.Fixtures, err = read("fixtures", "", true)
+	if err != nil {
+		return err
+	}
+
+	_, err = fake.ReadFixtures(settings.Fixtures)
+	return err
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/magnum"
+)
+
+func init() {
+	client.RegisterCloudProvider(client.CloudProvider{
+		Name:            "private",
+		Secret:          client.SecretPassword,
+		Detect:          true,
+		LoadEnvironment: loadMagnumEnvironment,
+		LoadProfile:     loadMagnumProfile,
+		NewAccount: func(settings client.AccountSettings) client.Account {
+			return &magnum.Account{
+				AuthEndpoint:     settings.AuthEndpoint,
+				EndpointOverride: settings.EndpointOverride,
+				UserName:         settings.Username,
+				Password:         settings.Password,
+				Project:          settings.Project,
+				Domain:           settings.Domain,
+
+				ProjectID:                   settings.ProjectID,
+				ProjectDomain:               settings.ProjectDomain,
+				UserDomain:                  settings.UserDomain,
+				ApplicationCredentialID:     settings.ApplicationCredentialID,
+				ApplicationCredentialName:   settings.ApplicationCredentialName,
+				ApplicationCredentialSecret: settings.ApplicationCredentialSecret,
+			}
+		},
+	})
+}
+
+// loadMagnumEnvironment fills in the settings for a private cloud from environment variables and defaults
+func loadMagnumEnvironment(settings *client.AccountSettings) error {
+	// auth-endpoint = --auth-endpoint -> OS_AUTH_URL
+	if settings.AuthEndpoint == "" {
+		settings.AuthEndpoint = os.Getenv(OpenStackAuthURLEnvVar)
+		if settings.AuthEndpoint == "" {
+			return fmt.Errorf("AuthEndpoint was not specified via --auth-endpoint or %s", OpenStackAuthURLEnvVar)
+		}
+		common.Log.WriteDebug("AuthEndpoint: %s", OpenStackAuthURLEnvVar)
+	} else {
+		common.Log.WriteDebug("AuthEndpoint: --auth-endpoint")
+	}
+
+	// endpoint = --endpoint -> CARINA_ENDPOINT -> OS_ENDPOINT -> service catalog endpoint
+	if settings.EndpointOverride == "" {
+		settings.EndpointOverride = os.Getenv(CarinaEndpointEnvVar)
+		if settings.EndpointOverride != "" {
+			common.Log.WriteDebug("Endpoint: %s", CarinaEndpointEnvVar)
+		} else {
+			settings.EndpointOverride = os.Getenv(OpenStackEndpointEnvVar)
+			if settings.EndpointOverride == "" {
+				common.Log.WriteDebug("Endpoint: default")
+			} else {
+				common.Log.WriteDebug("Endpoint: %s", OpenStackEndpointEnvVar)
+			}
+		}
+	} else {
+		common.Log.WriteDebug("Endpoint: --endpoint")
+	}
+
+	// application credential = --application-credential-* -> OS_APPLICATION_CREDENTIAL_*
+	loadEnvironmentSetting(&settings.ApplicationCredentialID, "ApplicationCredentialID", "--application-credential-id", OpenStackApplicationCredentialIDEnvVar)
+	loadEnvironmentSetting(&settings.ApplicationCredentialName, "ApplicationCredentialName", "--application-credential-name", OpenStackApplicationCredentialNameEnvVar)
+	loadEnvironmentSetting(&settings.ApplicationCredentialSecret, "ApplicationCredentialSecret", "--application-credential-secret", OpenStackApplicationCredentialSecretEnvVar)
+	useApplicationCredential := settings.ApplicationCredentialSecret != ""
+	if useApplicationCredential && settings.ApplicationCredentialID == "" && settings.ApplicationCredentialName == "" {
+		return fmt.Errorf("ApplicationCredentialID was not specified via --application-credential-id or %s", OpenStackApplicationCredentialIDEnvVar)
+	}
+
+	// username = --username -> OS_USERNAME, an application credential id doesn't need a username
+	if settings.Username == "" {
+		settings.Username = os.Getenv(OpenStackUserNameEnvVar)
+		if settings.Username == "" {
+			if settings.ApplicationCredentialID == "" {
+				err := resolveCredential(settings, client.CredentialUsername, &settings.Username,
+					fmt.Errorf("UserName was not specified via --username or %s", OpenStackUserNameEnvVar))
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			common.Log.WriteDebug("UserName: %s", OpenStackUserNameEnvVar)
+		}
+	} else {
+		common.Log.WriteDebug("UserName: --username")
+	}
+
+	// password = --password -> keychain (--auth-source keychain) -> OS_PASSWORD, unless an application credential is used
+	if settings.Password == "" {
+		settings.Password = os.Getenv(OpenStackPasswordEnvVar)
+		if settings.Password == "" {
+			if !useApplicationCredential {
+				err := resolveCredential(settings, client.SecretPassword.Credential(), &settings.Password,
+					fmt.Errorf("Password was not specified via --password, --password-stdin or %s", OpenStackPasswordEnvVar))
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			common.Log.WriteDebug("Password: %s", OpenStackPasswordEnvVar)
+		}
+	} else {
+		common.Log.WriteDebug("Password: --password")
+	}
+
+	// project = --project -> OS_PROJECT_NAME
+	if settings.Project == "" {
+		settings.Project = os.Getenv(OpenStackProjectEnvVar)
+		if settings.Project == "" {
+			common.Log.WriteDebug("Project was not specified. Either use --project or set %s.", OpenStackProjectEnvVar)
+		} else {
+			common.Log.WriteDebug("Project: %s", OpenStackProjectEnvVar)
+		}
+	} else {
+		common.Log.WriteDebug("Project: --project")
+	}
+
+	// project-id = --project-id -> OS_PROJECT_ID
+	loadEnvironmentSetting(&settings.ProjectID, "ProjectID", "--project-id", OpenStackProjectIDEnvVar)
+
+	// project-domain = --project-domain -> OS_PROJECT_DOMAIN_NAME, user-domain = --user-domain -> OS_USER_DOMAIN_NAME
+	loadEnvironmentSetting(&settings.ProjectDomain, "ProjectDomain", "--project-domain", OpenStackProjectDomainEnvVar)
+	loadEnvironmentSetting(&settings.UserDomain, "UserDomain", "--user-domain", OpenStackUserDomainEnvVar)
+
+	// domain = --domain -> OS_PROJECT_DOMAIN_NAME -> OS_USER_DOMAIN_NAME -> OS_DOMAIN_NAME -> "default"
+	if settings.Domain == "" {
+		domainVar := OpenStackProjectDomainEnvVar
+		settings.Domain = os.Getenv(OpenStackProjectDomainEnvVar)
+		if settings.Domain == "" {
+			domainVar = OpenStackUserDomainEnvVar
+			settings.Domain = os.Getenv(OpenStackUserDomainEnvVar)
+		}
+		if settings.Domain == "" {
+			domainVar = OpenStackDomainEnvVar
+			settings.Domain = os.Getenv(OpenStackDomainEnvVar)
+		}
+
+		if settings.Domain == "" {
+			settings.Domain = "default"
+			common.Log.WriteDebug("Domain: default. Either use --domain or set %s/%s/%s.", OpenStackProjectDomainEnvVar, OpenStackUserDomainEnvVar, OpenStackDomainEnvVar)
+		} else {
+			common.Log.WriteDebug("Domain: %s", domainVar)
+		}
+	} else {
+		common.Log.WriteDebug("Domain: --domain")
+	}
+
+	// region = --region -> OS_REGION_NAME -> "RegionOne"
+	if settings.Region == "" {
+		settings.Region = os.Getenv(OpenStackRegionEnvVar)
+		if settings.Region == "" {
+			settings.Region = "RegionOne"
+			common.Log.WriteDebug("Region: RegionOne. Either use --region or set %s.", OpenStackRegionEnvVar)
+		} else {
+			common.Log.WriteDebug("Region: %s", OpenStackRegionEnvVar)
+		}
+	} else {
+		common.Log.WriteDebug("Region: --region")
+	}
+
+	return nil
+}
+
+// loadEnvironmentSetting reads an optional setting from an environment variable, when it wasn't specified with a flag
+func loadEnvironmentSetting(value *string, name string, flag string, envVar string) {
+	if *value != "" {
+		common.Log.WriteDebug("%s: %s", name, flag)
+		return
+	}
+
+	*value = os.Getenv(envVar)
+	if *value != "" {
+		common.Log.WriteDebug("%s: %s", name, envVar)
+	}
+}
+
+// loadMagnumProfile reads the settings for a private cloud from a profile
+func loadMagnumProfile(settings *client.AccountSettings, read client.ProfileReader, secretRequired bool) (err error) {
+	settings.AuthEndpoint, err = read("auth-endpoint", "", true)
+	if err != nil {
+		return err
+	}
+
+	settings.EndpointOverride, err = read("endpoint", "", false)
+	if err != nil {
+		return err
+	}
+
+	settings.ApplicationCredentialID, err = read("application-credential-id", "", false)
+	if err != nil {
+		return err
+	}
+
+	settings.ApplicationCredentialName, err = read("application-credential-name", "", false)
+	if err != nil {
+		return err
+	}
+
+	settings.ApplicationCredentialSecret, err = read("application-credential-secret", "", false)
+	if err != nil {
+		return err
+	}
+	useApplicationCredential := settings.ApplicationCredentialSecret != ""
+
+	settings.Username, err = read("username", "", settings.ApplicationCredentialID == "")
+	if err != nil {
+		return err
+	}
+
+	settings.Password, err = read("password", "", false)
+	if err != nil {
+		return err
+	}
+	if secretRequired && !useApplicationCredential {
+		err = resolveCredential(settings, client.SecretPassword.Credential(), &settings.Password, errors.New("Invalid Profile: password is missing"))
+		if err != nil {
+			return err
+		}
+	}
+
+	settings.ProjectID, err = read("project-id", "", false)
+	if err != nil {
+		return err
+	}
+
+	// The project is scoped by its id, or by the application credential
+	settings.Project, err = read("project", "", settings.ProjectID == "" && !useApplicationCredential)
+	if err != nil {
+		return err
+	}
+
+	settings.ProjectDomain, err = read("project-domain", "", false)
+	if err != nil {
+		return err
+	}
+
+	settings.UserDomain, err = read("user-domain", "", false)
+	if err != nil {
+		return err
+	}
+
+	settings.Domain, err = read("domain", "default", false)
+	if err != nil {
+		return err
+	}
+
+	settings.Region, err = read("region", "RegionOne", false)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
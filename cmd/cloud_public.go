@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/make-coe"
+	"github.com/getcarina/carina/makeswarm"
+)
+
+// defaultCloudProvider is the cloud used when --cloud isn't specified and the cloud can't be detected from the credentials
+const defaultCloudProvider = "public"
+
+// publicStatusURL is the summary of the Carina status page
+const publicStatusURL = "https://status.getcarina.com/api/v2/summary.json"
+
+func init() {
+	client.RegisterCloudProvider(client.CloudProvider{
+		Name:            defaultCloudProvider,
+		Secret:          client.SecretAPIKey,
+		Detect:          true,
+		LoadEnvironment: loadCarinaEnvironment,
+		LoadProfile:     loadCarinaProfile,
+		NewAccount: func(settings client.AccountSettings) client.Account {
+			return &makecoe.Account{
+				EndpointOverride: settings.EndpointOverride,
+				UserName:         settings.Username,
+				APIKey:           settings.APIKey,
+				Token:            settings.Token,
+				Region:           settings.Region,
+				IdentityEndpoint: settings.AuthEndpoint,
+			}
+		},
+		StatusURL: publicStatusURL,
+	})
+
+	client.RegisterCloudProvider(client.CloudProvider{
+		Name:            "make-swarm",
+		Secret:          client.SecretAPIKey,
+		LoadEnvironment: loadCarinaEnvironment,
+		LoadProfile:     loadCarinaProfile,
+		NewAccount: func(settings client.AccountSettings) client.Account {
+			return &makeswarm.Account{
+				UserName: settings.Username,
+				APIKey:   settings.APIKey,
+			}
+		},
+		StatusURL: publicStatusURL,
+	})
+}
+
+// loadCarinaEnvironment fills in the settings for the public cloud from environment variables and defaults
+func loadCarinaEnvironment(settings *client.AccountSettings) error {
+	// identity-endpoint = --identity-endpoint/--auth-endpoint -> RS_AUTH_URL -> rackspace US identity endpoint
+	if settings.AuthEndpoint == "" {
+		settings.AuthEndpoint = os.Getenv(RackspaceAuthURLEnvVar)
+		if settings.AuthEndpoint == "" {
+			common.Log.WriteDebug("IdentityEndpoint: default")
+		} else {
+			common.Log.WriteDebug("IdentityEndpoint: %s", RackspaceAuthURLEnvVar)
+		}
+	} else {
+		common.Log.WriteDebug("IdentityEndpoint: --identity-endpoint")
+	}
+	err := resolveIdentityEndpoint(settings)
+	if err != nil {
+		return err
+	}
+
+	// endpoint = --endpoint -> CARINA_ENDPOINT
+	if settings.EndpointOverride == "" {
+		settings.EndpointOverride = os.Getenv(CarinaEndpointEnvVar)
+		if settings.EndpointOverride == "" {
+			common.Log.WriteDebug("Endpoint: default")
+		} else {
+			common.Log.WriteDebug("Endpoint: %s", CarinaEndpointEnvVar)
+		}
+	} else {
+		common.Log.WriteDebug("Endpoint: --endpoint")
+	}
+
+	// username = --username -> CARINA_USERNAME -> RS_USERNAME
+	if settings.Username == "" {
+		settings.Username = os.Getenv(CarinaUserNameEnvVar)
+		if settings.Username == "" {
+			settings.Username = os.Getenv(RackspaceUserNameEnvVar)
+			if settings.Username == "" {
+				err = resolveCredential(settings, client.CredentialUsername, &settings.Username,
+					fmt.Errorf("UserName was not specified. Either use --username or set %s, or %s.", CarinaUserNameEnvVar, RackspaceUserNameEnvVar))
+				if err != nil {
+					return err
+				}
+			} else {
+				common.Log.WriteDebug("UserName: %s", RackspaceUserNameEnvVar)
+			}
+		} else {
+			common.Log.WriteDebug("UserName: %s", CarinaUserNameEnvVar)
+		}
+	} else {
+		common.Log.WriteDebug("UserName: --username")
+	}
+
+	// token = --token -> CARINA_TOKEN
+	if settings.Token == "" {
+		settings.Token = os.Getenv(CarinaTokenEnvVar)
+		if settings.Token != "" {
+			common.Log.WriteDebug("Token: %s", CarinaTokenEnvVar)
+		}
+	} else {
+		common.Log.WriteDebug("Token: --token")
+	}
+
+	// apikey = --apikey -> keychain (--auth-source keychain) -> CARINA_APIKEY -> RS_API_KEY, optional with a token
+	if settings.APIKey == "" {
+		settings.APIKey = os.Getenv(CarinaAPIKeyEnvVar)
+		if settings.APIKey == "" {
+			settings.APIKey = os.Getenv(RackspaceAPIKeyEnvVar)
+			if settings.APIKey == "" {
+				if settings.Token == "" {
+					err = resolveCredential(settings, client.SecretAPIKey.Credential(), &settings.APIKey,
+						fmt.Errorf("API Key was not specified. Either use --apikey, --password-stdin or --token, or set %s, %s or %s", CarinaAPIKeyEnvVar, RackspaceAPIKeyEnvVar, CarinaTokenEnvVar))
+					if err != nil {
+						return err
+					}
+				} else {
+					common.Log.WriteDebug("API Key: not specified, using the token")
+				}
+			} else {
+				common.Log.WriteDebug("API Key: %s", RackspaceAPIKeyEnvVar)
+			}
+		} else {
+			common.Log.WriteDebug("API Key: %s", CarinaAPIKeyEnvVar)
+		}
+	} else {
+		common.Log.WriteDebug("API Key: --apikey")
+	}
+
+	// region = --region -> CARINA_REGION -> RS_REGION_NAME
+	if settings.Region == "" {
+		settings.Region = os.Getenv(CarinaRegionEnvVar)
+		if settings.Region == "" {
+			settings.Region = os.Getenv(RackspaceRegionEnvVar)
+			if settings.Region == "" {
+				common.Log.WriteDebug("Region: not specified. Either use --region, or set %s or %s.", CarinaRegionEnvVar, RackspaceRegionEnvVar)
+			} else {
+				common.Log.WriteDebug("Region: %s", RackspaceRegionEnvVar)
+			}
+		} else {
+			common.Log.WriteDebug("Region: %s", CarinaRegionEnvVar)
+		}
+	} else {
+		common.Log.WriteDebug("Region: --region")
+	}
+
+	return nil
+}
+
+// loadCarinaProfile reads the settings for the public cloud from a profile
+func loadCarinaProfile(settings *client.AccountSettings, read client.ProfileReader, secretRequired bool) (err error) {
+	settings.AuthEndpoint, err = read("identity-endpoint", "", false)
+	if err != nil {
+		return err
+	}
+	if settings.AuthEndpoint == "" {
+		settings.AuthEndpoint, err = read("auth-endpoint", "", false)
+		if err != nil {
+			return err
+		}
+	}
+	err = resolveIdentityEndpoint(settings)
+	if err != nil {
+		return err
+	}
+
+	settings.EndpointOverride, err = read("endpoint", "", false)
+	if err != nil {
+		return err
+	}
+
+	settings.Username, err = read("username", "", true)
+	if err != nil {
+		return err
+	}
+
+	settings.APIKey, err = read("apikey", "", false)
+	if err != nil {
+		return err
+	}
+	if secretRequired {
+		err = resolveCredential(settings, client.SecretAPIKey.Credential(), &settings.APIKey, errors.New("Invalid Profile: apikey is missing"))
+		if err != nil {
+			return err
+		}
+	}
+
+	settings.Region, err = read("region", "", false)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// resolveIdentityEndpoint replaces an identity endpoint alias, such as uk, with its URL
+func resolveIdentityEndpoint(settings *client.AccountSettings) error {
+	if settings.AuthEndpoint == "" {
+		return nil
+	}
+
+	endpoint, err := makecoe.ResolveIdentityEndpoint(settings.AuthEndpoint)
+	if err != nil {
+		return err
+	}
+	settings.AuthEndpoint = endpoint
+	return nil
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+// clusterSetting is a setting remembered for a single cluster, which is used instead of repeating a flag on every command for the cluster
+type clusterSetting struct {
+	description string
+	validate    func(value string) error
+
+	// flags are the flags defaulted by the setting, by command name, where "" is the flag used by any other command
+	flags map[string]string
+}
+
+// clusterSettings are the settings which can be remembered for a cluster with carina config cluster
+var clusterSettings = map[string]clusterSetting{
+	"path": {
+		description: "Directory where the cluster's credentials are saved and loaded from. Defaults --path, and --credentials-path on carina kubeconfig",
+		validate:    validatePathSetting,
+		flags:       map[string]string{"": "path", "kubeconfig": "credentials-path"},
+	},
+	"shell": {
+		description: "Shell used by carina env to connect to the cluster: bash, zsh, fish, powershell or cmd. Defaults --shell, and is remembered when --shell is specified",
+		validate:    client.ValidateShell,
+		flags:       map[string]string{"": "shell"},
+	},
+	"wait": {
+		description: "Wait for create, delete, resize, grow, rebuild and upgrade of the cluster to finish: true or false. Defaults --wait, overriding the wait setting in the config file",
+		validate:    validateBoolSetting,
+		flags:       map[string]string{"": "wait"},
+	},
+}
+
+// clusterSettingNames returns the sorted names of the settings which can be remembered for a cluster
+func clusterSettingNames() []string {
+	names := make([]string, 0, len(clusterSettings))
+	for name := range clusterSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describeClusterSettings lists the settings which can be remembered for a cluster, for the help text
+func describeClusterSettings() string {
+	var lines []string
+	for _, name := range clusterSettingNames() {
+		lines = append(lines, fmt.Sprintf("  %s: %s", name, clusterSettings[name].description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// applyClusterSettings defaults the flags of a command from the settings remembered for the cluster, which is the first argument.
+// Flags specified on the command line, including their --no- variant such as --no-wait, take precedence.
+func applyClusterSettings(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 || cxt.Account == nil {
+		return nil
+	}
+
+	name := args[0]
+	preferences := cxt.Client.Cache.GetClusterPreferences(cxt.Account, name)
+	for _, key := range clusterSettingNames() {
+		value, ok := preferences[key]
+		if !ok {
+			continue
+		}
+
+		setting := clusterSettings[key]
+		flagName, ok := setting.flags[cmd.Name()]
+		if !ok {
+			flagName = setting.flags[""]
+		}
+		if cmd.Flags().Lookup(flagName) == nil || cmd.Flags().Changed(flagName) || cmd.Flags().Changed("no-"+flagName) {
+			continue
+		}
+
+		common.Log.WriteDebug("%s: %s=%s, remembered for %s", flagName, key, value, name)
+		err := cmd.Flags().Set(flagName, value)
+		if err != nil {
+			return fmt.Errorf("Invalid %s setting remembered for %s: %s. Change it with carina config cluster %s set %s=<value>", key, name, err, name, key)
+		}
+	}
+	return nil
+}
+
+func newConfigClusterCommand() *cobra.Command {
+	var options struct {
+		name   string
+		action string
+		key    string
+		value  string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "cluster <cluster-name> [set <key>=<value> | unset <key>]",
+		Short: "View and change the settings remembered for a cluster",
+		Long: `View and change the settings remembered for a cluster, which are used on every command for the cluster instead of repeating a flag.
+Flags specified on the command line take precedence. The settings are stored in the cache, for the current account.
+
+Settings:
+` + describeClusterSettings(),
+		Example: `  # Always load the credentials for prod from a custom directory
+  carina config cluster prod set path=/home/alice/clusters/prod
+
+  # List the settings remembered for prod
+  carina config cluster prod
+
+  # Forget the directory
+  carina config cluster prod unset path`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A cluster name is required")
+			}
+			options.name = args[0]
+			if len(args) == 1 {
+				return nil
+			}
+
+			options.action = args[1]
+			switch {
+			case options.action == "set" && len(args) == 3:
+				parts := strings.SplitN(args[2], "=", 2)
+				if len(parts) != 2 {
+					return fmt.Errorf("Invalid setting: %s. Use the format key=value", args[2])
+				}
+				options.key = strings.TrimSpace(parts[0])
+				options.value = strings.TrimSpace(parts[1])
+			case options.action == "unset" && len(args) == 3:
+				options.key = args[2]
+			default:
+				return errors.New("Usage: carina config cluster <cluster-name> [set <key>=<value> | unset <key>]")
+			}
+
+			setting, ok := clusterSettings[options.key]
+			if !ok {
+				return fmt.Errorf("Unknown setting: %s. Allowed settings: %s", options.key, strings.Join(clusterSettingNames(), ", "))
+			}
+			if options.action == "set" {
+				return setting.validate(options.value)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cache := cxt.Client.Cache
+			switch options.action {
+			case "set":
+				err := cache.SaveClusterPreference(cxt.Account, options.name, options.key, options.value)
+				if err != nil {
+					return err
+				}
+				console.Write("Set %s=%s for %s", options.key, options.value, options.name)
+			case "unset":
+				err := cache.DeleteClusterPreference(cxt.Account, options.name, options.key)
+				if err != nil {
+					return err
+				}
+				console.Write("Removed %s for %s", options.key, options.name)
+			default:
+				preferences := cache.GetClusterPreferences(cxt.Account, options.name)
+				if len(preferences) == 0 && console.WriteEmptyState(fmt.Sprintf("No settings are remembered for %s.", options.name),
+					fmt.Sprintf("Remember a setting with carina config cluster %s set <key>=<value>.", options.name)) {
+					return nil
+				}
+
+				var keys []string
+				for key := range preferences {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				items := make([]console.Tuple, len(keys))
+				for i, key := range keys {
+					items[i] = console.Tuple{Key: key, Value: preferences[key]}
+				}
+				console.WriteMap(items)
+			}
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
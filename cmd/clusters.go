@@ -1,30 +1,149 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
 	"github.com/getcarina/carina/console"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func newClustersCommand() *cobra.Command {
+	var options struct {
+		labels   []string
+		filters  []string
+		sort     string
+		ids      bool
+		columns  []string
+		utc      bool
+		cached   bool
+		watch    bool
+		interval time.Duration
+		profiles []string
+	}
+
 	var cmd = &cobra.Command{
 		Use:               "clusters",
 		Aliases:           []string{"list", "ls"},
 		Short:             "List clusters",
-		Long:              "List clusters",
+		Long:              "List clusters. Use --profiles to list the clusters in several profiles at once: each profile's clusters are printed as soon as they are listed, and the json format prints a single document sorted by profile once every profile is listed.",
 		PersistentPreRunE: authenticatedPreRunE,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			clusters, err := cxt.Client.ListClusters(cxt.Account)
+			// columns = --columns -> profile -> config file -> default columns
+			if !cmd.Flags().Changed("columns") {
+				options.columns = splitConfigList(viper.GetString(configKey("list-columns")))
+			}
+			err := console.SetColumns(options.columns)
 			if err != nil {
 				return err
 			}
+			console.SetClusterCreated(clusterCreated)
+			console.UTC = options.utc
+
+			labelSelector, err := client.ParseLabels(options.labels)
+			if err != nil {
+				return err
+			}
+
+			listOptions, err := client.ParseClusterSelection(options.filters)
+			if err != nil {
+				return err
+			}
+			for key, value := range labelSelector {
+				listOptions.Labels[key] = value
+			}
+			listOptions.Sort = options.sort
+			if len(options.profiles) > 0 {
+				if options.cached || options.ids {
+					return errors.New("--profiles cannot be used with --cached or --ids")
+				}
 
-			console.WriteClusters(clusters)
+				accounts := make([]client.ProfileAccount, len(options.profiles))
+				for i, profile := range options.profiles {
+					account, err := cxt.buildProfileAccount(profile)
+					if err != nil {
+						return err
+					}
+					accounts[i] = client.ProfileAccount{Profile: profile, Account: account}
+				}
 
-			return nil
+				return runWatched(options.watch, options.interval, func() error {
+					return listClustersInProfiles(accounts, listOptions)
+				})
+			}
+
+			return runWatched(options.watch, options.interval, func() error {
+				var clusters []common.Cluster
+				if options.cached {
+					clusters, err = cxt.Client.ListCachedClusters(cxt.Account, listOptions)
+				} else {
+					clusters, err = cxt.Client.ListClusters(cxt.Account, listOptions)
+				}
+				if err != nil {
+					return err
+				}
+
+				filtered := len(options.labels) > 0 || len(options.filters) > 0
+				switch {
+				case options.ids:
+					console.WriteClusterIDs(clusters)
+				case len(clusters) == 0 && writeNoClusters(filtered):
+					// The empty state was printed instead of an empty table
+				default:
+					console.WriteClusters(clusters)
+				}
+
+				return nil
+			})
 		},
 	}
 
+	cmd.Flags().StringSliceVar(&options.labels, "label", nil, "Only list clusters with the key=value label, e.g. env=prod. Labels are local metadata stored in CARINA_HOME. May be specified multiple times")
+	cmd.Flags().StringArrayVar(&options.filters, "filter", nil, "Only list clusters where the field matches the pattern, e.g. name=web*, status=active or label=env=prod. Allowed fields: name, status, template, coe, host, label. Patterns use --match-mode. May be specified multiple times")
+	cmd.Flags().StringVar(&options.sort, "sort", "", "Sort the clusters by a field. Allowed values: name, nodes")
+	cmd.Flags().BoolVar(&options.ids, "ids", false, "Only print the cluster IDs, one per line, e.g. to pipe to xargs or the --stdin batch commands")
+	cmd.Flags().StringSliceVar(&options.columns, "columns", nil, "The columns to print, e.g. name,status,nodes. Allowed values: id, name, status, template, coe, host, nodes, labels, details, created, updated, and the custom columns defined in the [columns] section of the config file")
+	cmd.Flags().BoolVar(&options.utc, "utc", false, "Print the created and updated times in UTC, instead of the local time zone")
+	cmd.Flags().BoolVar(&options.cached, "cached", false, "List the clusters from the last successful listing, without connecting to the API. The cached clusters are used automatically when the API is unreachable")
+	cmd.Flags().StringSliceVar(&options.profiles, "profiles", nil, "List the clusters in each of the profiles concurrently, e.g. dev,prod")
+	addWatchFlags(cmd, &options.watch, &options.interval)
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
 }
+
+// writeNoClusters explains why no clusters were listed, and what to do next, returning if it was printed
+func writeNoClusters(filtered bool) bool {
+	if filtered {
+		return console.WriteEmptyState("No clusters match the filters.", "Run carina clusters without --filter or --label to list every cluster.")
+	}
+	return console.WriteEmptyState("No clusters found.", "Create a cluster with carina create <cluster-name> --template <template>. Run carina templates to list the available templates.")
+}
+
+// listClustersInProfiles prints the clusters in each profile as soon as they are listed, and fails when a profile can't be listed
+func listClustersInProfiles(accounts []client.ProfileAccount, options client.ListClustersOptions) error {
+	stream := console.NewClusterStream()
+	results := cxt.Client.ListClustersInProfiles(accounts, options, func(result client.ProfileClusters) {
+		if result.Err != nil {
+			common.Log.WriteWarning("WARNING: Unable to list the clusters in the %s profile: %s", result.Profile, result.Err)
+			return
+		}
+		stream.Write(result)
+	})
+	stream.Close()
+
+	var failed int
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("Unable to list the clusters in %d of %d profiles", failed, len(results))
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -9,11 +12,87 @@ import (
 	"github.com/Masterminds/semver"
 	"github.com/getcarina/carina/client"
 	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
 	"github.com/getcarina/carina/version"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+// bindClusterNameArg binds the name of an existing cluster. When the name is missing and stdin is a terminal,
+// the user picks one of the account's clusters instead. When more than one cluster has the name, the user picks
+// which one, and the name is replaced with the cluster's id.
 func bindClusterNameArg(args []string, name *string) error {
+	if len(args) < 1 && cxt.Account != nil && console.IsInteractive() {
+		return pickClusterName(name)
+	}
+
+	err := bindNewClusterNameArg(args, name)
+	if err != nil {
+		return err
+	}
+
+	matches := findAmbiguousClusters(*name)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	ambiguous := &common.MultipleMatchingClustersError{ClusterName: *name, MatchingClusters: matches}
+	if !console.IsInteractive() {
+		return ambiguous
+	}
+	cluster, err := console.PickAmbiguousCluster(ambiguous, clusterCreated)
+	if err != nil {
+		return ambiguous
+	}
+	*name = cluster.GetID()
+	return nil
+}
+
+// addMatchAllFlag adds --match-all to a command which can operate on multiple clusters
+func addMatchAllFlag(cmd *cobra.Command, matchAll *bool) {
+	cmd.Flags().BoolVar(matchAll, "match-all", false, "When more than one cluster has the name, use every one of them instead of picking one")
+}
+
+// bindMatchingClusterNamesArg binds the name of an existing cluster, like bindClusterNameArg. With --match-all,
+// a name which more than one cluster has selects every one of those clusters by id.
+func bindMatchingClusterNamesArg(args []string, matchAll bool, name *string, names *[]string) error {
+	if !matchAll || len(args) < 1 {
+		return bindClusterNameArg(args, name)
+	}
+
+	*name = args[0]
+	for _, cluster := range findAmbiguousClusters(*name) {
+		*names = append(*names, cluster.GetID())
+	}
+	return nil
+}
+
+// findAmbiguousClusters returns the clusters with the name when more than one cluster has it. The check is skipped when
+// the clusters can't be listed, leaving the command to report the problem.
+func findAmbiguousClusters(name string) []common.Cluster {
+	if cxt.Account == nil {
+		return nil
+	}
+
+	matches, err := cxt.Client.FindAmbiguousClusters(cxt.Account, name)
+	if err != nil {
+		common.Log.WriteDebug("Unable to check if more than one cluster is named %s: %s", name, err)
+		return nil
+	}
+	return matches
+}
+
+// clusterCreated returns when a cluster was created, which is only known for clusters created by this client
+func clusterCreated(cluster common.Cluster) (time.Time, bool) {
+	age, ok := cxt.Client.GetClusterAge(cxt.Account, cluster)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Now().Add(-age), true
+}
+
+// bindNewClusterNameArg binds the name of a cluster which doesn't exist yet
+func bindNewClusterNameArg(args []string, name *string) error {
 	if len(args) < 1 {
 		return errors.New("A cluster name is required")
 	}
@@ -21,17 +100,277 @@ func bindClusterNameArg(args []string, name *string) error {
 	return nil
 }
 
+// stdinFilename is the cluster name, or --file, which reads the cluster names from stdin
+const stdinFilename = "-"
+
+// addClusterNamesFileFlag adds --file, which reads the names of the clusters to operate on from a file
+func addClusterNamesFileFlag(cmd *cobra.Command, file *string) {
+	cmd.Flags().StringVar(file, "file", "", "Read the cluster names from a file, one per line, instead of the arguments. Use - to read them from stdin")
+}
+
+// readClusterNames reads the cluster names from --file, or from stdin when the cluster name is -, e.g.
+// carina clusters --format json | jq -r .clusters[].name | carina delete -
+// Returns false when the names are not read from a list, e.g. a single cluster name was specified.
+func readClusterNames(args []string, file string) ([]string, bool, error) {
+	if file == "" {
+		if len(args) != 1 || args[0] != stdinFilename {
+			return nil, false, nil
+		}
+		file = stdinFilename
+	} else if len(args) > 0 {
+		return nil, true, errors.New("A cluster name cannot be specified with --file")
+	}
+
+	in := os.Stdin
+	source := "stdin"
+	if file != stdinFilename {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, true, fmt.Errorf("Unable to read the cluster names from %s: %s", file, err)
+		}
+		defer f.Close()
+		in, source = f, file
+	}
+
+	names, err := parseClusterNames(in)
+	if err != nil {
+		return nil, true, fmt.Errorf("Unable to read the cluster names from %s: %s", source, err)
+	}
+	if len(names) == 0 {
+		return nil, true, fmt.Errorf("No cluster names were read from %s", source)
+	}
+	return names, true, nil
+}
+
+// parseClusterNames reads one cluster name per line, ignoring blank lines, # comments and duplicate names
+func parseClusterNames(in io.Reader) ([]string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, scanner.Err()
+}
+
+// pickClusterName prompts the user to pick one of the account's clusters
+func pickClusterName(name *string) error {
+	clusters, err := cxt.Client.ListClusters(cxt.Account, client.ListClustersOptions{Sort: "name"})
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		return errors.New("A cluster name is required, and the account doesn't have any clusters")
+	}
+
+	rows := make([][]string, len(clusters))
+	for i, cluster := range clusters {
+		var coe string
+		if template := cluster.GetTemplate(); template != nil {
+			coe = template.GetCOE()
+		}
+		rows[i] = []string{cluster.GetName(), cluster.GetStatus(), coe}
+	}
+
+	picked, err := console.Pick("cluster", []string{"Name", "Status", "COE"}, rows)
+	if err != nil {
+		return errors.New("A cluster name is required")
+	}
+	*name = clusters[picked].GetName()
+	return nil
+}
+
 func authenticatedPreRunE(cmd *cobra.Command, args []string) error {
-	err := cxt.initialize()
+	err := applyConfigSettings(cmd)
+	if err != nil {
+		return err
+	}
+
+	err = cxt.initialize()
+	if err != nil {
+		return err
+	}
+
+	err = checkDeprecations(cmd)
+	if err != nil {
+		return err
+	}
+
+	// The settings remembered for the cluster take precedence over the config file
+	err = applyClusterSettings(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	err = applyWaitSetting(cmd)
 	if err != nil {
 		return err
 	}
 
+	err = validateReadyCheck(cmd)
+	if err != nil {
+		return err
+	}
+
+	warnPendingOperations(cmd)
+
 	return checkIsLatest()
 }
 
+// addWaitFlags adds --wait and --no-wait to a command, which override the wait setting in the config file
+func addWaitFlags(cmd *cobra.Command, wait *bool, usage string) {
+	cmd.Flags().BoolVar(wait, "wait", false, usage+". Defaults to the wait setting in the config file")
+	cmd.Flags().Bool("no-wait", false, "Do not wait, even when wait=true in the config file")
+	addNoProgressFlag(cmd)
+}
+
+// addNoProgressFlag adds --no-progress to a command which can wait on an operation
+func addNoProgressFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&cxt.NoProgress, "no-progress", false, "Do not print the cluster status while waiting, without hiding the rest of the output like --quiet")
+}
+
+// addOnSuccessFlag adds --on-success to a command named after its operation, e.g. create, which overrides the hook from the config file
+func addOnSuccessFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().String("on-success", "", usage+", with the cluster's metadata in CARINA_* environment variables, e.g. CARINA_CLUSTER_NAME. Implies --wait. Overrides the hooks."+cmd.Name()+" setting in the config file")
+}
+
+// defaultWatchInterval is how often --watch refreshes the output
+const defaultWatchInterval = 5 * time.Second
+
+// addWatchFlags adds --watch and --watch-interval to a command, which repeat the command until it is interrupted
+func addWatchFlags(cmd *cobra.Command, watch *bool, interval *time.Duration) {
+	cmd.Flags().BoolVarP(watch, "watch", "w", false, "Refresh the output on an interval until interrupted. The json format streams each refresh as a line-delimited JSON document")
+	cmd.Flags().DurationVar(interval, "watch-interval", defaultWatchInterval, "How often to refresh the output with --watch, e.g. 10s")
+}
+
+// runWatched runs a command once, or repeatedly until interrupted when --watch is specified
+func runWatched(watch bool, interval time.Duration, run func() error) error {
+	if !watch {
+		return run()
+	}
+	if interval <= 0 {
+		return errors.New("--watch-interval must be > 0")
+	}
+	return console.Watch(interval, run)
+}
+
+// applyWaitSetting sets --wait from the wait setting in the config file, unless --wait or --no-wait is specified
+func applyWaitSetting(cmd *cobra.Command) error {
+	if cmd.Flags().Lookup("no-wait") == nil {
+		return nil
+	}
+
+	if noWait, _ := cmd.Flags().GetBool("no-wait"); noWait {
+		if wait, _ := cmd.Flags().GetBool("wait"); wait {
+			return errors.New("--wait and --no-wait cannot be used together")
+		}
+		if cmd.Flags().Changed("on-success") {
+			return errors.New("--on-success runs once the operation completes, and cannot be used with --no-wait")
+		}
+		return nil
+	}
+
+	// Hooks only run once the operation completes, so --on-success implies --wait
+	if cmd.Flags().Changed("on-success") && !cmd.Flags().Changed("wait") {
+		common.Log.WriteDebug("Waiting because --on-success was specified")
+		return cmd.Flags().Set("wait", "true")
+	}
+
+	if !cmd.Flags().Changed("wait") && viper.GetBool(configKey("wait")) {
+		common.Log.WriteDebug("Waiting because %s=true in the config file", configKey("wait"))
+		return cmd.Flags().Set("wait", "true")
+	}
+	return nil
+}
+
+// addReadyCheckFlag adds --ready-check, and its shorthand --probe, to a command which waits for a cluster to become active
+func addReadyCheckFlag(cmd *cobra.Command, readyCheck *string) {
+	cmd.Flags().StringVar(readyCheck, "ready-check", client.ReadyCheckNone, "When waiting, how to decide that the cluster is ready: none (the cluster is active) or coe (the Docker or Kubernetes API also responds)")
+	cmd.Flags().Bool("probe", false, "When waiting, also wait until the Docker or Kubernetes API accepts connections with the cluster's credentials, same as --ready-check coe")
+}
+
+// validateReadyCheck checks --ready-check and --probe, which only apply when waiting
+func validateReadyCheck(cmd *cobra.Command) error {
+	flag := cmd.Flags().Lookup("ready-check")
+	if flag == nil {
+		return nil
+	}
+
+	if probe, _ := cmd.Flags().GetBool("probe"); probe {
+		if flag.Changed && flag.Value.String() != client.ReadyCheckCOE {
+			return fmt.Errorf("--probe cannot be used with --ready-check %s", flag.Value.String())
+		}
+		err := cmd.Flags().Set("ready-check", client.ReadyCheckCOE)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := client.ValidateReadyCheck(flag.Value.String())
+	if err != nil {
+		return err
+	}
+
+	// carina wait always waits, so it doesn't have --wait
+	if cmd.Flags().Lookup("wait") == nil {
+		return nil
+	}
+	if wait, _ := cmd.Flags().GetBool("wait"); !wait && flag.Value.String() == client.ReadyCheckCOE {
+		return errors.New("--ready-check coe and --probe require --wait")
+	}
+	return nil
+}
+
+// waitUntilReady runs the --ready-check once the cluster is active
+func waitUntilReady(cluster common.Cluster, wait bool, readyCheck string) error {
+	if !wait || readyCheck != client.ReadyCheckCOE {
+		return nil
+	}
+	return cxt.Client.WaitUntilCOEIsReady(cxt.Account, cluster)
+}
+
+// addDryRunFlag adds --dry-run to a command which can print what it would change instead of changing it.
+// Only commands which honor cxt.DryRun have the flag, so that a command can't be mistakenly run for real with --dry-run.
+func addDryRunFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().BoolVar(&cxt.DryRun, "dry-run", false, usage)
+}
+
+// writePlan prints the result of a --dry-run
+func writePlan(plan *client.OperationPlan, err error) error {
+	if err != nil {
+		return err
+	}
+	console.WritePlan(plan, true)
+	return nil
+}
+
 func unauthenticatedPreRunE(cmd *cobra.Command, args []string) error {
-	cxt.Client = client.NewClient(cxt.CacheEnabled)
+	err := applyConfigSettings(cmd)
+	if err != nil {
+		return err
+	}
+
+	err = checkDeprecations(cmd)
+	if err != nil {
+		return err
+	}
+
+	// Commands which don't use an account may not need CARINA_HOME either, e.g. carina version
+	err = client.CheckCarinaHome(cxt.CreateHome)
+	if err != nil {
+		common.Log.WriteStructuredWarning(common.WarningHome, "%s", err)
+	}
+
+	cxt.Client, err = client.NewEncryptedClient(cxt.CacheEnabled, viper.GetString("credentials.encryption"))
+	if err != nil {
+		return err
+	}
+	cxt.Client.DisableLocks = cxt.NoLock
 
 	return checkIsLatest()
 }
@@ -70,14 +409,14 @@ func checkIsLatest() error {
 	if latest.GreaterThan(current) {
 		common.Log.WriteWarning("# A new version of the Carina client is out, go get it!")
 		common.Log.WriteWarning("# You're on %v and the latest is %v", current, latest)
-		common.Log.WriteWarning("# https://getcarina.com/docs/reference/carina-cli#upgrade")
+		common.Log.WriteWarning("# Run carina update, or see https://getcarina.com/docs/reference/carina-cli#upgrade")
 	}
 
 	return nil
 }
 
 func shouldCheckForUpdate() (bool, error) {
-	lastCheck := cxt.Client.Cache.LastUpdateCheck
+	lastCheck := cxt.Client.Cache.GetLastUpdateCheck()
 
 	// If we last checked recently, don't check again
 	if lastCheck.Add(12 * time.Hour).After(time.Now()) {
@@ -97,3 +436,27 @@ func shouldCheckForUpdate() (bool, error) {
 
 	return true, nil
 }
+
+// checkOperationResults returns an error summarizing the failed operations in a batch, e.g. Unable to delete 2 of 5 clusters,
+// counting the clusters skipped by --fail-fast separately
+func checkOperationResults(results []client.ClusterOperationResult, action string, noun string) error {
+	var failed, skipped int
+	for _, result := range results {
+		switch result.Err {
+		case nil:
+		case client.ErrSkipped:
+			skipped++
+		default:
+			failed++
+		}
+	}
+
+	switch {
+	case failed == 0 && skipped == 0:
+		return nil
+	case skipped == 0:
+		return fmt.Errorf("Unable to %s %d of %d %s", action, failed, len(results), noun)
+	default:
+		return fmt.Errorf("Unable to %s %d of %d %s, skipped %d after the first failure", action, failed, len(results), noun, skipped)
+	}
+}
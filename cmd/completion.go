@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/spf13/cobra"
+)
+
+func newCompletionCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Generate a shell completion script for the carina cli",
+		Long: `Generate a shell completion script for the carina cli. Cluster and template names are completed dynamically.
+
+To load completions in the current bash session:
+    source <(carina completion bash)
+
+To load completions in fish:
+    carina completion fish | source
+
+To load completions in PowerShell:
+    carina completion powershell | Out-String | Invoke-Expression`,
+		PersistentPreRunE: unauthenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("A shell is required: bash, zsh, fish or powershell")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return writeCompletion(cmd.Root(), args[0])
+		},
+	}
+
+	cmd.ValidArgs = []string{"bash", "zsh", "fish", "powershell"}
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+// writeCompletion prints the completion script for a shell
+func writeCompletion(root *cobra.Command, shell string) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletion(os.Stdout)
+	case "zsh":
+		return root.GenZshCompletion(os.Stdout)
+	case "fish":
+		return root.GenFishCompletion(os.Stdout, true)
+	case "powershell":
+		return root.GenPowerShellCompletion(os.Stdout)
+	default:
+		return fmt.Errorf("Invalid shell specified: %s. Allowed values: bash, zsh, fish, powershell", shell)
+	}
+}
+
+// initializeCompletion authenticates without writing to stdout, which would corrupt the completion results
+func initializeCompletion() bool {
+	common.Log.SetSilent()
+	cxt.DisablePrompts = true
+	err := cxt.initialize()
+	return err == nil
+}
+
+// completeClusterNames completes the cluster name argument, using the cached cluster names when available
+func completeClusterNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeClusterNameFlag(cmd, args, toComplete)
+}
+
+// completeClusterNameFlag completes a flag which is the name of an existing cluster, such as --from
+func completeClusterNameFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if !initializeCompletion() {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	names, err := cxt.Client.ListClusterNames(cxt.Account)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return filterCompletions(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeTemplateNames completes the --template flag
+func completeTemplateNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if !initializeCompletion() {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	templates, err := cxt.Client.ListClusterTemplates(cxt.Account, client.ListTemplatesOptions{})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, template := range templates {
+		names = append(names, template.GetName())
+	}
+	return filterCompletions(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func filterCompletions(values []string, toComplete string) []string {
+	var matches []string
+	for _, value := range values {
+		if strings.HasPrefix(strings.ToLower(value), strings.ToLower(toComplete)) {
+			matches = append(matches, value)
+		}
+	}
+	return matches
+}
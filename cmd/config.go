@@ -0,0 +1,506 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configSetting is a top-level setting in the config file which can be changed with carina config set
+type configSetting struct {
+	description string
+	validate    func(value string) error
+
+	// quote string values, so that they are written as TOML strings
+	quote bool
+}
+
+var configSettings = map[string]configSetting{
+	"cloud": {
+		description: "The cloud used when --cloud isn't specified and a profile isn't used, instead of detecting it from the credentials",
+		validate:    validateCloudSetting,
+		quote:       true,
+	},
+	"format": {
+		description: "Output format: table or json. Override with --format",
+		validate:    validateFormatSetting,
+		quote:       true,
+	},
+	"credentials.path": {
+		description: "Directory where cluster credentials are saved, instead of CARINA_HOME/clusters. Override for a single cluster with --path",
+		validate:    validatePathSetting,
+		quote:       true,
+	},
+	"credentials.path-template": {
+		description: "Where credentials are saved within the credentials directory, e.g. {{.Account}}/{{.Cloud}}/{{.ClusterName}}. Defaults to {{.Prefix}}/{{.ClusterName}}. Override with --path-template",
+		validate:    client.ValidateCredentialsPathTemplate,
+		quote:       true,
+	},
+	"credentials.encryption": {
+		description: "Encrypt the credentials and token cache in CARINA_HOME: none, passphrase (uses CARINA_PASSPHRASE) or keychain",
+		validate:    client.ValidateEncryptionMode,
+		quote:       true,
+	},
+	"credentials.backend": {
+		description: "Where carina credentials saves downloaded credentials: local, tar (a tar stream on stdout) or vault (uses VAULT_ADDR, VAULT_TOKEN and CARINA_VAULT_PATH). Override with --credentials-backend",
+		validate:    client.ValidateCredentialsBackend,
+		quote:       true,
+	},
+	"credentials.filenames": {
+		description: "Comma separated names used when saving credentials to --path, e.g. kubeconfig=config,ca.pem={cluster}-ca.pem. Override with --rename",
+		validate:    validateCredentialsFilenamesSetting,
+		quote:       true,
+	},
+	"wait": {
+		description: "Wait for create, delete, resize, grow, rebuild and upgrade to finish by default: true or false. Override with --wait or --no-wait",
+		validate:    validateBoolSetting,
+	},
+	"poll-interval": {
+		description: "How often to check the cluster status when waiting, e.g. 30s",
+		validate:    validateDurationSetting,
+		quote:       true,
+	},
+	"wait-timeout": {
+		description: "Maximum amount of time to wait for a cluster operation, e.g. 20m",
+		validate:    validateDurationSetting,
+		quote:       true,
+	},
+	"template-cache-ttl": {
+		description: "How long the templates are cached before they are listed again, e.g. 1h, or 0 to always list them. Bypass with --refresh",
+		validate:    validateDurationSetting,
+		quote:       true,
+	},
+	"color": {
+		description: "When to highlight values in color: auto (when the output is a terminal), always or never",
+		validate:    validateColorSetting,
+		quote:       true,
+	},
+	"list-columns": {
+		description: "Comma separated columns printed by carina clusters, e.g. name,status,nodes. Override with --columns",
+		validate:    validateListColumnsSetting,
+		quote:       true,
+	},
+	"template-columns": {
+		description: "Comma separated columns printed by carina templates, e.g. name,coe,version. Override with --columns",
+		validate:    validateTemplateColumnsSetting,
+		quote:       true,
+	},
+	"hooks.create": {
+		description: "Shell command run after a cluster is created, with the cluster's metadata in CARINA_* environment variables, e.g. to register it in DNS. Only runs with --wait. Override with --on-success",
+		validate:    validateHookSetting,
+		quote:       true,
+	},
+	"hooks.delete": {
+		description: "Shell command run after a cluster is deleted, with the cluster's metadata in CARINA_* environment variables. Only runs with --wait. Override with --on-success",
+		validate:    validateHookSetting,
+		quote:       true,
+	},
+	"hooks.resize": {
+		description: "Shell command run after a cluster is resized, with the cluster's metadata in CARINA_* environment variables. Only runs with --wait. Override with --on-success",
+		validate:    validateHookSetting,
+		quote:       true,
+	},
+	"notify.webhook": {
+		description: "URL which receives a JSON notification when an operation with --wait finishes or fails, e.g. a Slack incoming webhook",
+		validate:    validateWebhookSetting,
+		quote:       true,
+	},
+	"notify.min-duration": {
+		description: "Only notify the webhook about operations which took at least this long, e.g. 5m",
+		validate:    validateDurationSetting,
+		quote:       true,
+	},
+	"quota-threshold": {
+		description: "Percentage of a quota which can be used before carina quotas highlights it, e.g. 80",
+		validate:    client.ValidateQuotaThreshold,
+	},
+}
+
+func validateBoolSetting(value string) error {
+	if value != "true" && value != "false" {
+		return fmt.Errorf("Invalid value: %s. Allowed values: true, false", value)
+	}
+	return nil
+}
+
+// validateListColumnsSetting checks that columns are listed, the names are validated when clusters are listed,
+// because custom columns may be defined in a profile
+func validateListColumnsSetting(value string) error {
+	if len(splitConfigList(value)) == 0 {
+		return errors.New("Invalid value: at least one column is required, e.g. name,status,nodes")
+	}
+	return nil
+}
+
+// validateTemplateColumnsSetting checks that the columns are known template columns
+func validateTemplateColumnsSetting(value string) error {
+	names := splitConfigList(value)
+	if len(names) == 0 {
+		return errors.New("Invalid value: at least one column is required, e.g. name,coe,version")
+	}
+
+	allowed := console.TemplateColumnNames()
+	for _, name := range names {
+		known := false
+		for _, column := range allowed {
+			if strings.ToLower(name) == column {
+				known = true
+			}
+		}
+		if !known {
+			return fmt.Errorf("Invalid value: unknown column %s. Allowed values: %s", name, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+func validateHookSetting(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New("Invalid value: a command is required. Remove the setting from the config file to disable the hook")
+	}
+	return nil
+}
+
+func validateWebhookSetting(value string) error {
+	webhook, err := url.Parse(value)
+	if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+		return fmt.Errorf("Invalid value: %s. The webhook must be an http or https URL", value)
+	}
+	return nil
+}
+
+func validateColorSetting(value string) error {
+	_, err := console.ParseColorMode(value)
+	return err
+}
+
+func validateDurationSetting(value string) error {
+	_, err := time.ParseDuration(value)
+	return err
+}
+
+func validateCloudSetting(value string) error {
+	_, err := client.LookupCloudProvider(value)
+	return err
+}
+
+func validateFormatSetting(value string) error {
+	switch console.OutputFormat(value) {
+	case console.FormatTable, console.FormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("Invalid value: %s. Allowed values: %s, %s", value, console.FormatTable, console.FormatJSON)
+	}
+}
+
+func validatePathSetting(value string) error {
+	if !filepath.IsAbs(value) {
+		return fmt.Errorf("Invalid value: %s. The path must be absolute", value)
+	}
+	return nil
+}
+
+func validateCredentialsFilenamesSetting(value string) error {
+	_, err := client.ParseCredentialsFilenames(splitConfigList(value))
+	return err
+}
+
+// splitConfigList splits a comma separated setting, ignoring empty values
+func splitConfigList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// profileSettings are the settings which can also be set in a profile, overriding the top-level setting when the profile is used,
+// e.g. format = "json" in a profile for CI
+var profileSettings = map[string]bool{
+	"format":        true,
+	"color":         true,
+	"columns":       true,
+	"list-columns":  true,
+	"wait":          true,
+	"poll-interval": true,
+	"wait-timeout":  true,
+}
+
+// configKey returns the key used to read a setting: the setting in the current profile, when the profile sets it, otherwise the top-level setting
+func configKey(key string) string {
+	if !profileSettings[key] {
+		return key
+	}
+
+	profile := cxt.preferencesProfile()
+	if profile != "" && viper.IsSet(profile+"."+key) {
+		return profile + "." + key
+	}
+	return key
+}
+
+// applyConfigSettings applies the defaults from the config file which aren't overridden by flags
+func applyConfigSettings(cmd *cobra.Command) error {
+	// format = --format -> profile -> config file -> table
+	if !cmd.Flags().Changed("format") && viper.IsSet(configKey("format")) {
+		cxt.Format = viper.GetString(configKey("format"))
+	}
+
+	// color = --no-color -> profile -> config file -> auto
+	if viper.IsSet(configKey("color")) {
+		color, err := console.ParseColorMode(viper.GetString(configKey("color")))
+		if err != nil {
+			return err
+		}
+		console.Color = color
+	}
+	if cxt.NoColor {
+		console.Color = console.ColorNever
+	}
+
+	console.Quiet = cxt.Quiet
+
+	// credentials directory = config file -> CARINA_HOME/clusters
+	client.CredentialsStoragePolicy.Dir = viper.GetString("credentials.path")
+
+	// hooks = --on-success -> config file
+	client.HookPolicy.Commands = make(map[string]string)
+	for _, operation := range client.HookOperations {
+		if command := viper.GetString("hooks." + operation); command != "" {
+			client.HookPolicy.Commands[operation] = command
+		}
+	}
+	if cmd.Flags().Changed("on-success") {
+		// The commands with --on-success are named after their operation, e.g. create
+		command, _ := cmd.Flags().GetString("on-success")
+		client.HookPolicy.Commands[cmd.Name()] = command
+	}
+
+	// notifications = config file
+	client.NotificationPolicy.WebhookURL = viper.GetString("notify.webhook")
+	client.NotificationPolicy.MinDuration = viper.GetDuration("notify.min-duration")
+
+	// credentials path template = --path-template -> config file -> {{.Prefix}}/{{.ClusterName}}
+	client.CredentialsStoragePolicy.PathTemplate = cxt.PathTemplate
+	if cxt.PathTemplate == "" {
+		client.CredentialsStoragePolicy.PathTemplate = viper.GetString("credentials.path-template")
+	}
+
+	return nil
+}
+
+func newConfigCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "config",
+		Short: "View and change settings in the config file",
+		Long:  "View and change settings in the config file, which are used as defaults instead of repeating flags on every command. Profiles must be edited by hand.\n\nThe format, color, columns, list-columns, wait, poll-interval and wait-timeout settings can also be set in a profile, which overrides the top-level setting when the profile is used.\n\nSettings for a single cluster, such as where its credentials are saved, are managed with carina config cluster.",
+	}
+
+	cmd.AddCommand(newConfigSetCommand())
+	cmd.AddCommand(newConfigGetCommand())
+	cmd.AddCommand(newConfigListCommand())
+	cmd.AddCommand(newConfigClusterCommand())
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newConfigSetCommand() *cobra.Command {
+	var options struct {
+		key   string
+		value string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "set <key>=<value>",
+		Short: "Change a setting in the config file",
+		Long:  "Change a setting in the config file, e.g. carina config set credentials.encryption=keychain\n\nSettings:\n" + describeConfigSettings(),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A setting is required, e.g. credentials.encryption=keychain")
+			}
+
+			parts := strings.SplitN(args[0], "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("Invalid setting: %s. Use the format key=value", args[0])
+			}
+			options.key = strings.TrimSpace(parts[0])
+			options.value = strings.TrimSpace(parts[1])
+
+			setting, ok := configSettings[options.key]
+			if !ok {
+				return fmt.Errorf("Unknown setting: %s. Allowed settings: %s", options.key, strings.Join(configSettingNames(), ", "))
+			}
+			return setting.validate(options.value)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFile, err := configFilePath()
+			if err != nil {
+				return err
+			}
+
+			err = setConfigValue(configFile, options.key, options.value)
+			if err != nil {
+				return err
+			}
+
+			console.Write("Set %s=%s in %s", options.key, options.value, configFile)
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newConfigGetCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:       "get <key>",
+		Short:     "Show a setting from the config file",
+		Long:      "Show a setting from the config file",
+		ValidArgs: configSettingNames(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A setting is required, e.g. credentials.encryption")
+			}
+
+			console.WriteValue(fmt.Sprint(viper.Get(args[0])))
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newConfigListCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the settings and their values from the config file",
+		Long:  "List the settings which can be changed with carina config set, and their values from the config file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rows := [][]string{{"Setting", "Value", "Description"}}
+			for _, name := range configSettingNames() {
+				var value string
+				if viper.IsSet(name) {
+					value = fmt.Sprint(viper.Get(name))
+				}
+				rows = append(rows, []string{name, value, configSettings[name].description})
+			}
+			console.WriteTable(rows)
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func configSettingNames() []string {
+	var names []string
+	for name := range configSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func describeConfigSettings() string {
+	var lines []string
+	for _, name := range configSettingNames() {
+		lines = append(lines, fmt.Sprintf("  %s\n    %s", name, configSettings[name].description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// configFilePath returns the config file in use, or where it should be created
+func configFilePath() (string, error) {
+	if configFile := viper.ConfigFileUsed(); configFile != "" {
+		return configFile, nil
+	}
+	if cxt.ConfigFile != "" {
+		return cxt.ConfigFile, nil
+	}
+
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", errors.New("Unable to locate the config file. Use --config to specify it")
+	}
+	return filepath.Join(home, ".carina", "config.toml"), nil
+}
+
+var tomlTableHeader = regexp.MustCompile(`^\s*\[`)
+var tomlKeyValue = regexp.MustCompile(`^\s*[A-Za-z0-9_.-]+\s*=`)
+
+// setConfigValue changes a top-level setting in a TOML config file, without disturbing the rest of the file.
+// Top-level settings must be defined before the first profile, so a new setting is added after the last
+// top-level setting, or before the first profile and its comments.
+func setConfigValue(configFile string, key string, value string) error {
+	contents, err := ioutil.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if configSettings[key].quote {
+		value = strconv.Quote(value)
+	}
+	line := fmt.Sprintf("%s = %s", key, value)
+
+	var lines []string
+	if len(contents) > 0 {
+		lines = strings.Split(strings.TrimRight(string(contents), "\n"), "\n")
+	}
+
+	existingKey := regexp.MustCompile(`^\s*` + regexp.QuoteMeta(key) + `\s*=`)
+	insertAt := len(lines)
+	lastSetting := -1
+	for i, l := range lines {
+		if tomlTableHeader.MatchString(l) {
+			insertAt = i
+			// Keep the comments which describe the profile with it
+			for insertAt > 0 && strings.HasPrefix(strings.TrimSpace(lines[insertAt-1]), "#") {
+				insertAt--
+			}
+			break
+		}
+		if existingKey.MatchString(l) {
+			lines[i] = line
+			return writeConfigFile(configFile, lines)
+		}
+		if tomlKeyValue.MatchString(l) {
+			lastSetting = i
+		}
+	}
+	if lastSetting >= 0 {
+		insertAt = lastSetting + 1
+	}
+
+	lines = append(lines[:insertAt], append([]string{line}, lines[insertAt:]...)...)
+	return writeConfigFile(configFile, lines)
+}
+
+func writeConfigFile(configFile string, lines []string) error {
+	err := os.MkdirAll(filepath.Dir(configFile), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(configFile, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+// destructiveOperations always require confirmation, regardless of the confirm rules in the config file
+var destructiveOperations = map[string]bool{
+	"delete":  true,
+	"rebuild": true,
+}
+
+// confirmOperation prompts the user before performing an operation which requires confirmation,
+// either because it is destructive or according to the confirm rules in the config file. The nodes function calculates
+// how many nodes the cluster will have after the operation, and may be nil when the operation doesn't change the cluster size.
+func confirmOperation(operation string, name string, nodes func(cluster common.Cluster) int) error {
+	destructive := destructiveOperations[operation]
+	if cxt.AssumeYes || (!destructive && !cxt.ConfirmationPolicy.AppliesTo(operation)) {
+		return nil
+	}
+
+	cluster, err := cxt.Client.GetCluster(cxt.Account, name, false)
+	if err != nil {
+		return err
+	}
+
+	var nodeCount int
+	if nodes != nil {
+		nodeCount = nodes(cluster)
+	}
+
+	question := fmt.Sprintf("Are you sure you want to %s %s?", operation, describeCluster(cluster))
+	rule, ok := cxt.ConfirmationPolicy.Find(operation, cluster, nodeCount)
+	if ok {
+		common.Log.WriteDebug("Confirmation required by rule: %s", rule)
+		question = fmt.Sprintf("Confirmation is required to %s. %s", rule, question)
+	} else if !destructive {
+		return nil
+	}
+
+	if !console.IsInteractive() {
+		return fmt.Errorf("Confirmation is required to %s %s. Use --yes to skip the confirmation", operation, name)
+	}
+
+	if !console.Confirm(question) {
+		return fmt.Errorf("Canceled %s of %s", operation, name)
+	}
+	return nil
+}
+
+// describeCluster summarizes a cluster for a confirmation prompt, e.g. mycluster (3 nodes, created 2 days ago)
+func describeCluster(cluster common.Cluster) string {
+	description := cluster.GetName() + " ("
+	if nodes, err := common.ParseNodeCount(cluster.GetNodes()); err == nil && nodes == 1 {
+		description += "1 node"
+	} else {
+		description += cluster.GetNodes() + " nodes"
+	}
+	if age, ok := cxt.Client.GetClusterAge(cxt.Account, cluster); ok {
+		description += ", created " + console.FormatAge(age) + " ago"
+	}
+	return description + ")"
+}
+
+// addForceFlag adds --force, an alias of --yes, to a destructive command
+func addForceFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&cxt.AssumeYes, "force", false, "Skip the confirmation prompt, same as --yes")
+}
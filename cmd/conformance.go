@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newConformanceCommand() *cobra.Command {
+	var options client.ConformanceOptions
+
+	var cmd = &cobra.Command{
+		Use:   "conformance",
+		Short: "Check which capabilities of the cloud behave correctly with the carina cli",
+		Long: `Check which capabilities of the cloud behave correctly with the carina cli, such as when validating a private Magnum install. A scripted sequence of operations is run against the account: authenticate, list the clusters, templates, quotas and keypairs, validate creating a cluster, create a throwaway 1 node cluster, download its credentials, check that its Docker or Kubernetes API responds, and then delete it.
+
+Each capability passes, fails, is unsupported by the cloud, or is skipped when an earlier check failed. Use --dry-run to only validate creating a cluster, without creating one. The template defaults to the newest template which is neither deprecated nor low on capacity.`,
+		Example: `  carina conformance --profile private-magnum
+  carina conformance --profile private-magnum --dry-run --format json`,
+		PersistentPreRunE: authenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.DryRun = cxt.DryRun
+			result, err := cxt.Client.CheckConformance(cxt.Account, options)
+			if err != nil {
+				return err
+			}
+
+			console.WriteConformanceResult(result)
+			return result.Err()
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Template, "template", "", "Name of the template to check. Defaults to the recommended template")
+	cmd.RegisterFlagCompletionFunc("template", completeTemplateNames)
+	cmd.Flags().StringVar(&options.COE, "coe", "", "Only recommend a template for the container orchestration engine, e.g. kubernetes or swarm")
+	cmd.Flags().BoolVar(&options.Keep, "keep", false, "Keep the cluster instead of deleting it")
+	addDryRunFlag(cmd, "Only validate creating a cluster, without creating one")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -3,14 +3,16 @@ package cmd
 import (
 	"errors"
 	"fmt"
-
+	"io/ioutil"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/getcarina/carina/client"
 	"github.com/getcarina/carina/common"
-	"github.com/getcarina/carina/magnum"
+	"github.com/getcarina/carina/console"
 	"github.com/getcarina/carina/make-coe"
-	"github.com/getcarina/carina/makeswarm"
 	"github.com/getcarina/carina/version"
 	"github.com/spf13/viper"
 )
@@ -33,6 +35,9 @@ const CarinaAPIKeyEnvVar = "CARINA_APIKEY"
 // RackspaceAPIKeyEnvVar is the Rackspace API key environment variable (2nd)
 const RackspaceAPIKeyEnvVar = "RS_API_KEY"
 
+// CarinaTokenEnvVar is a pre-obtained Carina auth token, used instead of the API key
+const CarinaTokenEnvVar = "CARINA_TOKEN"
+
 // OpenStackPasswordEnvVar is OpenStack password environment variable
 const OpenStackPasswordEnvVar = "OS_PASSWORD"
 
@@ -42,7 +47,7 @@ const RackspaceAuthURLEnvVar = "RS_AUTH_URL"
 // OpenStackAuthURLEnvVar is the OpenStack Identity URL (v2 and v3 supported)
 const OpenStackAuthURLEnvVar = "OS_AUTH_URL"
 
-// CarinaEndpointEnvVar overrides the default Carina endpoint
+// CarinaEndpointEnvVar overrides the API endpoint for both public and private clouds
 const CarinaEndpointEnvVar = "CARINA_ENDPOINT"
 
 // OpenStackEndpointEnvVar overrides the default endpoint from the service catalog
@@ -69,29 +74,105 @@ const RackspaceRegionEnvVar = "RS_REGION_NAME"
 // OpenStackRegionEnvVar is the OpenStack region name
 const OpenStackRegionEnvVar = "OS_REGION_NAME"
 
+// OpenStackProjectIDEnvVar is the OpenStack project id, used instead of the project name and domain for identity v3
+const OpenStackProjectIDEnvVar = "OS_PROJECT_ID"
+
+// OpenStackApplicationCredentialIDEnvVar is the id of an OpenStack identity v3 application credential
+const OpenStackApplicationCredentialIDEnvVar = "OS_APPLICATION_CREDENTIAL_ID"
+
+// OpenStackApplicationCredentialNameEnvVar is the name of an OpenStack identity v3 application credential, used with the username
+const OpenStackApplicationCredentialNameEnvVar = "OS_APPLICATION_CREDENTIAL_NAME"
+
+// OpenStackApplicationCredentialSecretEnvVar is the secret of an OpenStack identity v3 application credential, used instead of the password
+const OpenStackApplicationCredentialSecretEnvVar = "OS_APPLICATION_CREDENTIAL_SECRET"
+
+// CarinaFixturesEnvVar is the JSON fixtures file served by the fake cloud
+const CarinaFixturesEnvVar = "CARINA_FIXTURES"
+
 type context struct {
 	// Values built from flags
 	Client  *client.Client
 	Account client.Account
 
+	// ProfileLoaded is set when the account was read from a profile, instead of flags and environment variables
+	ProfileLoaded bool
+
+	// LegacyWarnings are the deprecations of the legacy flags used, see translateLegacyArgs
+	LegacyWarnings []common.Deprecation
+
 	// Global Flags
 	CacheEnabled bool
 	ConfigFile   string
 	Debug        bool
 	Silent       bool
+	Strict       bool
+	Retries      int
+	RetryMaxWait time.Duration
+	AssumeYes    bool
+	Quiet        bool
+	NoProgress   bool
+	NoColor      bool
+	PollInterval time.Duration
+	WaitTimeout  time.Duration
+	Refresh      bool
+	PathTemplate string
+	CreateHome   bool
+	Format       string
+	AuthSource   string
+	MatchMode    string
+	DryRun       bool
+	FailFast     bool
+	KeepGoing    bool
+	LogLevel     string
+	LogFormat    string
+	LogFile      string
+	DebugHTTP    bool
+	Timing       bool
+	NoLock       bool
+	Proxy        string
+	CACert       string
+	Insecure     bool
+	SaveAccount  bool
+
+	// PasswordStdin reads the password or API key from stdin, instead of a flag or environment variable
+	PasswordStdin bool
+
+	// DisablePrompts stops missing credentials from being prompted for, e.g. while completing a command
+	DisablePrompts bool
+
+	// CredentialsBackend is where downloaded credentials are saved, set by the credentials commands, see bindCredentialsBackend
+	CredentialsBackend string
 
 	// Account Flags
-	Profile          string
-	ProfileDisabled  bool
-	CloudType        string
-	Username         string
-	APIKey           string
-	Password         string
-	Project          string
-	Domain           string
-	Region           string
-	AuthEndpoint     string
-	EndpointOverride string
+	Profile         string
+	ProfileDisabled bool
+	CloudType       string
+	client.AccountSettings
+
+	// Profile Preferences
+	Shell string
+
+	// Config Settings
+	ConfirmationPolicy client.ConfirmationPolicy
+}
+
+// preferencesProfile returns the profile whose settings, such as format, override the top-level settings.
+// This is the profile used for the account, which is resolved the same way as loadProfile, without logging.
+func (cxt *context) preferencesProfile() string {
+	if cxt.ProfileDisabled || cxt.userSpecifiedAuthFlagsExist() || viper.ConfigFileUsed() == "" {
+		return ""
+	}
+
+	if cxt.Profile != "" {
+		return cxt.Profile
+	}
+	if profile := os.Getenv(CarinaProfileEnvVar); profile != "" {
+		return profile
+	}
+	if viper.InConfig("default") {
+		return "default"
+	}
+	return ""
 }
 
 func (cxt *context) shouldTryProfile() bool {
@@ -115,57 +196,198 @@ func (cxt *context) shouldTryProfile() bool {
 
 func (cxt *context) userSpecifiedAuthFlagsExist() bool {
 	return cxt.CloudType != "" ||
+		cxt.PasswordStdin ||
 		cxt.Username != "" ||
 		cxt.Password != "" ||
 		cxt.APIKey != "" ||
+		cxt.Token != "" ||
 		cxt.Domain != "" ||
 		cxt.Project != "" ||
-		cxt.Region != ""
+		cxt.ProjectID != "" ||
+		cxt.ProjectDomain != "" ||
+		cxt.UserDomain != "" ||
+		cxt.ApplicationCredentialID != "" ||
+		cxt.ApplicationCredentialName != "" ||
+		cxt.ApplicationCredentialSecret != "" ||
+		cxt.Region != "" ||
+		cxt.AuthEndpoint != ""
+}
+
+// userSpecifiedCredentialFlagsExist returns if the account was specified with flags, e.g. for a one-off command against another account
+func (cxt *context) userSpecifiedCredentialFlagsExist() bool {
+	return cxt.Username != "" ||
+		cxt.PasswordStdin ||
+		cxt.Password != "" ||
+		cxt.APIKey != "" ||
+		cxt.Token != "" ||
+		cxt.ApplicationCredentialSecret != "" ||
+		cxt.AuthEndpoint != ""
 }
 
 func (cxt *context) buildAccount() client.Account {
-	switch cxt.CloudType {
-	case client.CloudMakeCOE:
-		return &makecoe.Account{
-			EndpointOverride: cxt.EndpointOverride,
-			UserName:         cxt.Username,
-			APIKey:           cxt.APIKey,
-			Region:           cxt.Region,
-		}
-	case client.CloudMakeSwarm:
-		return &makeswarm.Account{
-			UserName: cxt.Username,
-			APIKey:   cxt.APIKey,
+	provider, err := client.LookupCloudProvider(cxt.CloudType)
+	if err != nil {
+		panic(err)
+	}
+	return provider.NewAccount(cxt.AccountSettings)
+}
+
+// initializeLogging applies --log-level, --log-format and --log-file. --debug is shorthand for --log-level debug.
+func (cxt *context) initializeLogging() error {
+	if cxt.LogFile != "" {
+		err := common.Log.SetLogFile(cxt.LogFile)
+		if err != nil {
+			return err
 		}
-	case client.CloudMagnum:
-		return &magnum.Account{
-			AuthEndpoint:     cxt.AuthEndpoint,
-			EndpointOverride: cxt.EndpointOverride,
-			UserName:         cxt.Username,
-			Password:         cxt.Password,
-			Project:          cxt.Project,
-			Domain:           cxt.Domain,
+	}
+
+	err := common.Log.SetLogFormat(cxt.LogFormat)
+	if err != nil {
+		return err
+	}
+
+	if cxt.LogLevel != "" {
+		err = common.Log.SetLogLevel(cxt.LogLevel)
+		if err != nil {
+			return err
 		}
-	default:
-		panic(fmt.Sprintf("Unsupported cloud type: %s", cxt.CloudType))
 	}
-}
 
-func (cxt *context) initialize() error {
 	if cxt.Silent {
 		common.Log.SetSilent()
 	} else if cxt.Debug {
 		common.Log.SetDebug()
+	}
+
+	if cxt.DebugHTTP {
+		common.HTTPTracePolicy.Enabled = true
+
+		// The traces are logged at the info level
+		if !common.Log.InfoEnabled() {
+			common.Log.SetLogLevel("info")
+		}
+	}
+
+	if cxt.Timing {
+		common.Timing.Start()
+	}
+
+	if common.Log.DebugEnabled() {
 		common.Log.WriteDebug("Version: %s (%s)", version.Version, version.Commit)
 	}
+	return nil
+}
+
+func (cxt *context) initialize() error {
+	err := cxt.initializeLogging()
+	if err != nil {
+		return err
+	}
+	if cxt.Silent || cxt.Quiet || cxt.NoProgress {
+		common.Progress.SetQuiet()
+	}
+
+	err = console.SetFormat(cxt.Format)
+	if err != nil {
+		return err
+	}
+
+	err = client.ValidateAuthSource(cxt.AuthSource)
+	if err != nil {
+		return err
+	}
+
+	common.NameMatchPolicy.Mode, err = common.ParseMatchMode(cxt.MatchMode)
+	if err != nil {
+		return err
+	}
+
+	if cxt.FailFast && cxt.KeepGoing {
+		return errors.New("--fail-fast and --keep-going cannot be used together")
+	}
+	client.BatchPolicy.FailFast = cxt.FailFast
+
+	if cxt.Retries < 0 {
+		return errors.New("--retries must be >= 0")
+	}
+	common.HTTPRetryPolicy.MaxRetries = cxt.Retries
+	common.HTTPRetryPolicy.MaxWait = cxt.RetryMaxWait
+
+	err = common.HTTPTransportPolicy.SetProxy(cxt.Proxy)
+	if err != nil {
+		return err
+	}
+	if cxt.CACert != "" {
+		err = common.HTTPTransportPolicy.AddCACert(cxt.CACert)
+		if err != nil {
+			return err
+		}
+	}
+	if cxt.Insecure {
+		common.Log.WriteStructuredWarning(common.WarningInsecure, "--insecure-skip-verify disables verifying the API's certificate. Use --cacert to trust your proxy's CA instead.")
+	}
+	common.HTTPTransportPolicy.InsecureSkipVerify = cxt.Insecure
+
+	for _, deprecation := range cxt.LegacyWarnings {
+		if cxt.Strict {
+			return fmt.Errorf("%s. Legacy flags are not allowed when --strict is specified", deprecation)
+		}
+		common.Log.WriteDeprecation(deprecation)
+	}
+
+	// poll-interval = --poll-interval -> profile -> config file -> backend default
+	if cxt.PollInterval == 0 {
+		cxt.PollInterval = viper.GetDuration(configKey("poll-interval"))
+	}
+	// wait-timeout = --wait-timeout -> profile -> config file -> wait forever
+	if cxt.WaitTimeout == 0 {
+		cxt.WaitTimeout = viper.GetDuration(configKey("wait-timeout"))
+	}
+	if cxt.PollInterval < 0 {
+		return errors.New("--poll-interval must be >= 0")
+	}
+	if cxt.WaitTimeout < 0 {
+		return errors.New("--wait-timeout must be >= 0")
+	}
+	common.ClusterWaitPolicy.PollInterval = cxt.PollInterval
+	common.ClusterWaitPolicy.Timeout = cxt.WaitTimeout
+
+	// template-cache-ttl = config file -> 1h
+	if viper.IsSet("template-cache-ttl") {
+		makecoe.ClusterTypeCachePolicy.TTL = viper.GetDuration("template-cache-ttl")
+	}
+	makecoe.ClusterTypeCachePolicy.Refresh = cxt.Refresh
+
+	cxt.ConfirmationPolicy, err = client.ParseConfirmationPolicy(viper.Get("confirm"))
+	if err != nil {
+		return err
+	}
+
+	// The profile's custom columns are added to the top-level columns, replacing a column with the same name
+	for _, key := range []string{"columns", configKey("columns")} {
+		for name, text := range viper.GetStringMapString(key) {
+			err = console.RegisterColumn(name, text)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Check before the profile and environment variables are loaded into the same fields
+	oneOffAccount := cxt.userSpecifiedCredentialFlagsExist()
+
+	cxt.CredentialSources, err = cxt.buildCredentialSources()
+	if err != nil {
+		return err
+	}
 
 	var profileLoaded bool
-	var err error
 	if cxt.shouldTryProfile() {
 		profileLoaded, err = cxt.loadProfile()
 		if err != nil {
 			return err
 		}
+		cxt.ProfileLoaded = profileLoaded
 	} else {
 		common.Log.WriteDebug("Ignoring profiles")
 	}
@@ -179,21 +401,43 @@ func (cxt *context) initialize() error {
 		}
 
 		// Initialize the remaining flags based on the cloud provider
-		switch cxt.CloudType {
-		case client.CloudMakeSwarm, client.CloudMakeCOE:
-			err = cxt.initCarinaFlags()
-		case client.CloudMagnum:
-			err = cxt.initMagnumFlags()
+		provider, err := client.LookupCloudProvider(cxt.CloudType)
+		if err != nil {
+			return err
+		}
+		err = cxt.loadSecretFromKeychain(provider)
+		if err != nil {
+			return err
 		}
+		err = provider.LoadEnvironment(&cxt.AccountSettings)
 		if err != nil {
 			return err
 		}
 	}
 
-	cxt.Client = client.NewClient(cxt.CacheEnabled)
+	err = checkEndpointOverride(&cxt.AccountSettings)
+	if err != nil {
+		return err
+	}
+
+	// Check before the cache is read, so that a bad CARINA_HOME doesn't surface as a cache or credentials error
+	err = client.CheckCarinaHome(cxt.CreateHome)
+	if err != nil {
+		return err
+	}
+
+	cxt.Client, err = client.NewEncryptedClient(cxt.CacheEnabled, viper.GetString("credentials.encryption"))
+	if err != nil {
+		return err
+	}
+	cxt.Client.DisableLocks = cxt.NoLock
 	cxt.Account = cxt.buildAccount()
+	if oneOffAccount && !cxt.SaveAccount {
+		common.Log.WriteDebug("Not caching the auth token because the credentials were specified with flags, use --save to cache it")
+		cxt.Account = client.NewUncachedAccount(cxt.Account)
+	}
 
-	return nil
+	return cxt.Client.UseSharedHome(cxt.Account, cxt.EndpointOverride)
 }
 
 func (cxt *context) loadProfile() (ok bool, err error) {
@@ -223,287 +467,203 @@ func (cxt *context) loadProfile() (ok bool, err error) {
 	}
 	common.Log.WriteDebug("Reading %s profile from %s", cxt.Profile, configFile)
 
-	cxt.CloudType = profile["cloud"]
-	switch cxt.CloudType {
-	case client.CloudMakeSwarm, client.CloudMakeCOE:
-		err = cxt.loadCarinaProfile(profile)
-	case client.CloudMagnum:
-		err = cxt.loadMagnumProfile(profile)
-	case "":
-		err = fmt.Errorf("Invalid profile: cloud is missing")
-	default:
-		err = fmt.Errorf("Invalid profile: %s is not a valid cloud type", cxt.CloudType)
-	}
-
-	return err == nil, err
-}
-
-func (cxt *context) detectCloud() error {
-	// Verify that we have enough information: apikey or password
-	apikeyFound := cxt.APIKey != "" || os.Getenv(CarinaAPIKeyEnvVar) != "" || os.Getenv(RackspaceAPIKeyEnvVar) != ""
-	passwordFound := cxt.Password != "" || os.Getenv(OpenStackPasswordEnvVar) != ""
-	if !apikeyFound && !passwordFound {
-		return errors.New("No credentials provided. A --profile, --apikey or --password must be specified or the equivalent environment variables set. Run carina --help for more information.")
-	}
-
-	switch cxt.CloudType {
-	case client.CloudMakeCOE, client.CloudMagnum, client.CloudMakeSwarm:
-		break
-	case "":
-		common.Log.WriteDebug("No cloud type specified, detecting with the provided credentials. Use --cloud or --profile to skip detection.")
-		if apikeyFound {
-			cxt.CloudType = client.CloudMakeCOE
-			common.Log.WriteDebug("Cloud: public")
-		} else {
-			cxt.CloudType = client.CloudMagnum
-			common.Log.WriteDebug("Cloud: private")
+	// auth-source = --auth-source -> profile
+	if cxt.AuthSource == "" {
+		cxt.AuthSource, err = cxt.getProfileSetting(profile, "auth-source", "", false)
+		if err != nil {
+			return false, err
+		}
+		err = client.ValidateAuthSource(cxt.AuthSource)
+		if err != nil {
+			return false, fmt.Errorf("Invalid profile: %s", err)
 		}
-	default:
-		return fmt.Errorf("Invalid --cloud value: %s. Allowed values are public, private and make-swarm", cxt.CloudType)
 	}
 
-	return nil
-}
+	cxt.CloudType = profile["cloud"]
+	if cxt.CloudType == "" {
+		return false, fmt.Errorf("Invalid profile: cloud is missing")
+	}
+	provider, err := client.LookupCloudProvider(cxt.CloudType)
+	if err != nil {
+		return false, fmt.Errorf("Invalid profile: %s is not a valid cloud type", cxt.CloudType)
+	}
 
-func (cxt *context) initCarinaFlags() error {
-	// auth-endpoint = --auth-endpoint -> RS_AUTH_URL -> rackspace identity endpoint
-	if cxt.AuthEndpoint == "" {
-		cxt.AuthEndpoint = os.Getenv(RackspaceAuthURLEnvVar)
-		if cxt.AuthEndpoint == "" {
-			common.Log.WriteDebug("AuthEndpoint: %s", RackspaceAuthURLEnvVar)
-		} else {
-			common.Log.WriteDebug("AuthEndpoint: default")
-		}
-	} else {
-		common.Log.WriteDebug("AuthEndpoint: --auth-endpoint")
+	read := func(key string, defaultValue string, required bool) (string, error) {
+		return cxt.getProfileSetting(profile, key, defaultValue, required)
+	}
+	endpointFlag := cxt.EndpointOverride
+	err = provider.LoadProfile(&cxt.AccountSettings, read, !cxt.useKeychain())
+	if err != nil {
+		return false, err
 	}
 
-	// endpoint = --endpoint -> CARINA_ENDPOINT
-	if cxt.EndpointOverride == "" {
-		cxt.EndpointOverride = os.Getenv(CarinaEndpointEnvVar)
-		if cxt.EndpointOverride == "" {
-			common.Log.WriteDebug("Endpoint: default")
-		} else {
-			common.Log.WriteDebug("Endpoint: %s", CarinaEndpointEnvVar)
-		}
-	} else {
+	// --endpoint overrides the profile for a single command, e.g. to try a profile against an API mock
+	if endpointFlag != "" {
+		cxt.EndpointOverride = endpointFlag
 		common.Log.WriteDebug("Endpoint: --endpoint")
 	}
-
-	// username = --username -> CARINA_USERNAME -> RS_USERNAME
-	if cxt.Username == "" {
-		cxt.Username = os.Getenv(CarinaUserNameEnvVar)
-		if cxt.Username == "" {
-			cxt.Username = os.Getenv(RackspaceUserNameEnvVar)
-			if cxt.Username == "" {
-				return fmt.Errorf("UserName was not specified. Either use --username or set %s, or %s.", CarinaUserNameEnvVar, RackspaceUserNameEnvVar)
-			}
-			common.Log.WriteDebug("UserName: %s", RackspaceUserNameEnvVar)
-		} else {
-			common.Log.WriteDebug("UserName: %s", CarinaUserNameEnvVar)
-		}
-	} else {
-		common.Log.WriteDebug("UserName: --username")
+	err = cxt.loadSecretFromKeychain(provider)
+	if err != nil {
+		return false, err
 	}
 
-	// apikey = --apikey -> CARINA_APIKEY -> RS_API_KEY
-	if cxt.APIKey == "" {
-		cxt.APIKey = os.Getenv(CarinaAPIKeyEnvVar)
-		if cxt.APIKey == "" {
-			cxt.APIKey = os.Getenv(RackspaceAPIKeyEnvVar)
-			if cxt.APIKey == "" {
-				return fmt.Errorf("API Key was not specified. Either use --apikey or set %s or %s", CarinaAPIKeyEnvVar, RackspaceAPIKeyEnvVar)
-			}
-			common.Log.WriteDebug("API Key: %s", RackspaceAPIKeyEnvVar)
-		} else {
-			common.Log.WriteDebug("API Key: %s", CarinaAPIKeyEnvVar)
-		}
-	} else {
-		common.Log.WriteDebug("API Key: --apikey")
-	}
-
-	// region = --region -> CARINA_REGION -> RS_REGION_NAME
-	if cxt.Region == "" {
-		cxt.Region = os.Getenv(CarinaRegionEnvVar)
-		if cxt.Region == "" {
-			cxt.Region = os.Getenv(RackspaceRegionEnvVar)
-			if cxt.Region == "" {
-				common.Log.WriteDebug("Region: not specified. Either use --region, or set %s or %s.", CarinaRegionEnvVar, RackspaceRegionEnvVar)
-			} else {
-				common.Log.WriteDebug("Region: %s", RackspaceRegionEnvVar)
-			}
-		} else {
-			common.Log.WriteDebug("Region: %s", CarinaRegionEnvVar)
-		}
-	} else {
-		common.Log.WriteDebug("Region: --region")
-	}
+	cxt.Shell, err = cxt.getProfileSetting(profile, "shell", "", false)
 
-	return nil
+	return err == nil, err
 }
 
-func (cxt *context) initMagnumFlags() error {
-	// auth-endpoint = --auth-endpoint -> OS_AUTH_URL
-	if cxt.AuthEndpoint == "" {
-		cxt.AuthEndpoint = os.Getenv(OpenStackAuthURLEnvVar)
-		if cxt.AuthEndpoint == "" {
-			return fmt.Errorf("AuthEndpoint was not specified via --auth-endpoint or %s", OpenStackAuthURLEnvVar)
-		}
-		common.Log.WriteDebug("AuthEndpoint: %s", OpenStackAuthURLEnvVar)
-	} else {
-		common.Log.WriteDebug("AuthEndpoint: --auth-endpoint")
-	}
+// buildProfileAccount builds the account for another profile in the config file, without changing the current account
+func (cxt *context) buildProfileAccount(name string) (client.Account, error) {
+	profileCxt := *cxt
+	profileCxt.Profile = name
+	profileCxt.CloudType = ""
+	profileCxt.AccountSettings = client.AccountSettings{}
 
-	// endpoint = --endpoint -> OS_ENDPOINT -> service catalog endpoint
-	if cxt.EndpointOverride == "" {
-		cxt.EndpointOverride = os.Getenv(OpenStackEndpointEnvVar)
-		if cxt.EndpointOverride == "" {
-			common.Log.WriteDebug("Endpoint: default")
-		} else {
-			common.Log.WriteDebug("Endpoint: %s", OpenStackEndpointEnvVar)
-		}
-	} else {
-		common.Log.WriteDebug("Endpoint: --endpoint")
+	_, err := profileCxt.loadProfile()
+	if err != nil {
+		return nil, err
 	}
+	return profileCxt.buildAccount(), nil
+}
 
-	// username = --username -> OS_USERNAME
-	if cxt.Username == "" {
-		cxt.Username = os.Getenv(OpenStackUserNameEnvVar)
-		if cxt.Username == "" {
-			return fmt.Errorf("UserName was not specified via --username or %s", OpenStackUserNameEnvVar)
+func (cxt *context) detectCloud() error {
+	// Clouds without a secret, such as the fake cloud, don't need credentials
+	if cxt.CloudType != "" {
+		provider, err := client.LookupCloudProvider(cxt.CloudType)
+		if err != nil {
+			return err
 		}
-		common.Log.WriteDebug("UserName: %s", OpenStackUserNameEnvVar)
-	} else {
-		common.Log.WriteDebug("UserName: --username")
-	}
-
-	// password = --password -> OS_PASSWORD
-	if cxt.Password == "" {
-		cxt.Password = os.Getenv(OpenStackPasswordEnvVar)
-		if cxt.Password == "" {
-			return fmt.Errorf("Password was not specified via --password or %s", OpenStackPasswordEnvVar)
+		if provider.Secret == "" {
+			return nil
 		}
-		common.Log.WriteDebug("Password: %s", OpenStackPasswordEnvVar)
-	} else {
-		common.Log.WriteDebug("Password: --password")
 	}
 
-	// project = --project -> OS_PROJECT_NAME
-	if cxt.Project == "" {
-		cxt.Project = os.Getenv(OpenStackProjectEnvVar)
-		if cxt.Project == "" {
-			common.Log.WriteDebug("Project was not specified. Either use --project or set %s.", OpenStackProjectEnvVar)
-		} else {
-			common.Log.WriteDebug("Project: %s", OpenStackProjectEnvVar)
-		}
-	} else {
-		common.Log.WriteDebug("Project: --project")
+	// Verify that we have enough information: apikey or password
+	apikeyFound := cxt.APIKey != "" || os.Getenv(CarinaAPIKeyEnvVar) != "" || os.Getenv(RackspaceAPIKeyEnvVar) != "" ||
+		cxt.Token != "" || os.Getenv(CarinaTokenEnvVar) != ""
+	passwordFound := cxt.Password != "" || os.Getenv(OpenStackPasswordEnvVar) != "" ||
+		cxt.ApplicationCredentialSecret != "" || os.Getenv(OpenStackApplicationCredentialSecretEnvVar) != ""
+	if !apikeyFound && !passwordFound && !cxt.useKeychain() && len(cxt.CredentialSources) == 0 {
+		return errors.New("No credentials provided. A --profile, --apikey, --token, --password or --password-stdin must be specified or the equivalent environment variables set. Run carina --help for more information.")
 	}
 
-	// domain = --domain -> OS_PROJECT_DOMAIN_NAME -> OS_USER_DOMAIN_NAME -> OS_DOMAIN_NAME -> "default"
-	if cxt.Domain == "" {
-		domainVar := OpenStackProjectDomainEnvVar
-		cxt.Domain = os.Getenv(OpenStackProjectDomainEnvVar)
-		if cxt.Domain == "" {
-			domainVar = OpenStackUserDomainEnvVar
-			cxt.Domain = os.Getenv(OpenStackUserDomainEnvVar)
-		}
-		if cxt.Domain == "" {
-			domainVar = OpenStackDomainEnvVar
-			cxt.Domain = os.Getenv(OpenStackDomainEnvVar)
-		}
+	if cxt.CloudType != "" {
+		return nil
+	}
 
-		if cxt.Domain == "" {
-			cxt.Domain = "default"
-			common.Log.WriteDebug("Domain: default. Either use --domain or set %s/%s/%s.", OpenStackProjectDomainEnvVar, OpenStackUserDomainEnvVar, OpenStackDomainEnvVar)
-		} else {
-			common.Log.WriteDebug("Domain: %s", domainVar)
-		}
-	} else {
-		common.Log.WriteDebug("Domain: --domain")
+	// cloud = --cloud -> config file -> detected from the credentials
+	if cloud := viper.GetString("cloud"); cloud != "" {
+		common.Log.WriteDebug("Cloud: %s, from the config file", cloud)
+		cxt.CloudType = cloud
+		_, err := client.LookupCloudProvider(cxt.CloudType)
+		return err
 	}
 
-	// region = --region -> OS_REGION_NAME -> "RegionOne"
-	if cxt.Region == "" {
-		cxt.Region = os.Getenv(OpenStackRegionEnvVar)
-		if cxt.Region == "" {
-			cxt.Region = "RegionOne"
-			common.Log.WriteDebug("Region: RegionOne. Either use --region or set %s.", OpenStackRegionEnvVar)
-		} else {
-			common.Log.WriteDebug("Region: %s", OpenStackRegionEnvVar)
-		}
-	} else {
-		common.Log.WriteDebug("Region: --region")
+	common.Log.WriteDebug("No cloud type specified, detecting with the provided credentials. Use --cloud or --profile to skip detection.")
+	// An API key is assumed when only the keychain, --password-stdin or a prompt can provide the secret
+	secret := client.SecretPassword
+	if apikeyFound || !passwordFound {
+		secret = client.SecretAPIKey
+	}
+	provider, ok := client.DetectCloudProvider(secret)
+	if !ok {
+		return fmt.Errorf("Unable to detect the cloud from the %s. Use --cloud to select the cloud", strings.ToLower(string(secret)))
 	}
+	cxt.CloudType = provider.Name
+	common.Log.WriteDebug("Cloud: %s", cxt.CloudType)
 
 	return nil
 }
 
-func (cxt *context) loadCarinaProfile(profile map[string]string) (err error) {
-	cxt.AuthEndpoint, err = cxt.getProfileSetting(profile, "auth-endpoint", "", false)
-	if err != nil {
-		return err
-	}
+// buildCredentialSources returns where a username or secret which wasn't specified with flags, environment variables
+// or a profile is read from: the secret piped to stdin with --password-stdin, otherwise a prompt when stdin is a terminal
+func (cxt *context) buildCredentialSources() ([]client.CredentialSource, error) {
+	if cxt.PasswordStdin {
+		if cxt.Password != "" || cxt.APIKey != "" {
+			return nil, errors.New("--password-stdin cannot be used with --password or --apikey")
+		}
 
-	cxt.EndpointOverride, err = cxt.getProfileSetting(profile, "endpoint", "", false)
-	if err != nil {
-		return err
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read the password from stdin: %s", err)
+		}
+		secret := strings.TrimRight(string(contents), "\r\n")
+		if secret == "" {
+			return nil, errors.New("--password-stdin was specified, but nothing was read from stdin")
+		}
+		return []client.CredentialSource{client.NewStaticSecretSource("--password-stdin", secret)}, nil
 	}
 
-	cxt.Username, err = cxt.getProfileSetting(profile, "username", "", true)
-	if err != nil {
-		return err
+	if cxt.DisablePrompts || !console.IsInteractive() {
+		return nil, nil
 	}
+	return []client.CredentialSource{{Name: "prompt", Provide: promptForCredential}}, nil
+}
 
-	cxt.APIKey, err = cxt.getProfileSetting(profile, "apikey", "", true)
+// resolveCredential fills in a missing credential from the credential sources, e.g. a prompt, otherwise returns the error
+// which explains how to specify it
+func resolveCredential(settings *client.AccountSettings, credential client.Credential, value *string, missing error) error {
+	ok, err := settings.ResolveCredential(credential, value)
 	if err != nil {
 		return err
 	}
-
-	cxt.Region, err = cxt.getProfileSetting(profile, "region", "", false)
-	if err != nil {
-		return err
+	if !ok {
+		return missing
 	}
-
 	return nil
 }
 
-func (cxt *context) loadMagnumProfile(profile map[string]string) (err error) {
-	cxt.AuthEndpoint, err = cxt.getProfileSetting(profile, "auth-endpoint", "", true)
-	if err != nil {
-		return err
+// promptForCredential asks for a missing credential on the terminal, without echoing secrets
+func promptForCredential(credential client.Credential) (string, bool, error) {
+	var value string
+	var err error
+	if credential.Secret {
+		value, err = console.ReadSecret(credential.Name)
+	} else {
+		value, err = console.ReadValue(credential.Name)
 	}
+	return value, err == nil, err
+}
 
-	cxt.EndpointOverride, err = cxt.getProfileSetting(profile, "endpoint", "", false)
-	if err != nil {
-		return err
-	}
+func (cxt *context) useKeychain() bool {
+	return cxt.AuthSource == client.AuthSourceKeychain
+}
 
-	cxt.Username, err = cxt.getProfileSetting(profile, "username", "", true)
-	if err != nil {
-		return err
+// loadSecretFromKeychain reads the API key or password from the keychain when --auth-source is keychain,
+// unless it was already specified with a flag or in the profile
+func (cxt *context) loadSecretFromKeychain(provider client.CloudProvider) error {
+	if provider.Secret == "" {
+		return nil
 	}
 
-	cxt.Password, err = cxt.getProfileSetting(profile, "password", "", true)
-	if err != nil {
-		return err
+	secret := provider.GetSecret(&cxt.AccountSettings)
+	if !cxt.useKeychain() || *secret != "" {
+		return nil
 	}
 
-	cxt.Project, err = cxt.getProfileSetting(profile, "project", "", true)
+	value, err := client.NewKeychainSecretsProvider().GetSecret(cxt.CloudType, cxt.Username)
 	if err != nil {
 		return err
 	}
+	*secret = value
+	common.Log.WriteDebug("%s: keychain", provider.Secret)
+	return nil
+}
 
-	cxt.Domain, err = cxt.getProfileSetting(profile, "domain", "default", false)
-	if err != nil {
-		return err
+// checkEndpointOverride validates the endpoint which replaces the API endpoint from the service catalog,
+// e.g. to use a staging environment or an API mock
+func checkEndpointOverride(settings *client.AccountSettings) error {
+	if settings.EndpointOverride == "" {
+		return nil
 	}
 
-	cxt.Region, err = cxt.getProfileSetting(profile, "region", "RegionOne", false)
-	if err != nil {
-		return err
+	endpoint, err := url.Parse(settings.EndpointOverride)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("Invalid endpoint %s, it must be an http or https URL, e.g. https://api.staging.example.com", settings.EndpointOverride)
 	}
 
+	// The API paths are appended to the endpoint
+	settings.EndpointOverride = strings.TrimSuffix(settings.EndpointOverride, "/")
 	return nil
 }
 
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newContextCommand() *cobra.Command {
+	var options struct {
+		output   string
+		logLines int
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "context",
+		Short: "Gather diagnostic information into a zip file for a support ticket",
+		Long: `Gather diagnostic information into a zip file, which can be attached to a support ticket.
+
+The bundle contains the client version and operating system, the config file, the end of the log file
+from --log-file, the request ids of the API calls which failed and a description of the cache.
+API keys, passwords, tokens and other secrets are redacted from the config file and the logs.`,
+		Example: `  # Reproduce the problem with the logs saved to a file, then gather them into a bundle
+  carina --log-level debug --log-file carina.log create mycluster
+  carina context --log-file carina.log`,
+		PersistentPreRunE: unauthenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.output == "" {
+				options.output = fmt.Sprintf("carina-context-%s.zip", time.Now().Format("20060102-150405"))
+			}
+
+			f, err := os.OpenFile(options.output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return fmt.Errorf("Unable to create the context bundle: %s", err)
+			}
+			defer f.Close()
+
+			bundle, err := cxt.Client.WriteContextBundle(client.ContextBundleOptions{
+				ConfigFile: viper.ConfigFileUsed(),
+				LogFile:    cxt.LogFile,
+				LogLines:   options.logLines,
+			}, f)
+			if err != nil {
+				return err
+			}
+
+			output, err := filepath.Abs(options.output)
+			if err != nil {
+				output = options.output
+			}
+			console.WriteValue(output, fmt.Sprintf("# Context bundle written to \"%s\"", output))
+			for _, file := range bundle.Files {
+				console.Write("#   %s", file)
+			}
+			if bundle.LogFile == "" {
+				console.Write("# No logs were included, use --log-file to include the logs of a previous command")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.output, "output", "o", "", "Path to the zip file, defaults to carina-context-<timestamp>.zip")
+	cmd.Flags().IntVar(&options.logLines, "log-lines", client.DefaultContextLogLines, "Number of lines from the end of the log file to include")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -2,48 +2,288 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
 	"github.com/getcarina/carina/console"
 	"github.com/spf13/cobra"
 )
 
+// defaultCredentialsPath is the value of --download-credentials when a directory isn't specified
+const defaultCredentialsPath = "default"
+
 func newCreateCommand() *cobra.Command {
 	var options struct {
-		name     string
-		template string
-		nodes    int
-		wait     bool
+		name            string
+		template        string
+		nodes           int
+		allowDeprecated bool
+		labels          []string
+		driverOptions   []string
+		keypair         string
+		sshKey          string
+		wait            bool
+		readyCheck      string
+		generateName    string
+		nameSeed        int64
+		count           int
+		names           []string
+		downloadPath    string
+		selector        common.TemplateSelector
+		from            string
+		ifNotExists     bool
 	}
 
 	var cmd = &cobra.Command{
-		Use:               "create <cluster-name>",
-		Short:             "Create a cluster",
-		Long:              "Create a cluster",
+		Use:   "create <cluster-name>...",
+		Short: "Create a cluster",
+		Long:  "Create a cluster. Use --generate-name instead of a cluster name to create a cluster with a unique name, such as in parallel CI jobs.\n\nWhen multiple cluster names or --count are specified, the clusters are created concurrently and a summary is printed at the end.",
+		Example: `  carina create mycluster --template "Kubernetes 1.5.2 on LXC"
+  carina create mycluster --coe kubernetes --host-type lxc --version 1.x
+  carina create workshop-{1..5} --template "Kubernetes*" --wait
+  carina create workshop --count 5 --template "Kubernetes*"
+  carina create staging --from production --label env=staging
+  carina create ci --template "Kubernetes*" --if-not-exists --wait`,
 		PersistentPreRunE: authenticatedPreRunE,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if options.nodes < 1 {
 				return errors.New("--nodes must be >= 1")
 			}
+			if options.count < 1 {
+				return errors.New("--count must be >= 1")
+			}
+			if options.keypair != "" && options.sshKey != "" {
+				return errors.New("--keypair and --ssh-key cannot be specified together")
+			}
+			if options.from != "" && (options.template != "" || !options.selector.IsEmpty()) {
+				return errors.New("--from cannot be used with --template, --coe, --host-type or --version")
+			}
+			if options.template != "" && !options.selector.IsEmpty() {
+				return errors.New("--template cannot be used with --coe, --host-type or --version")
+			}
+			if options.selector.COE == "" && !options.selector.IsEmpty() {
+				return errors.New("--coe is required when selecting the template with --host-type or --version")
+			}
+			if cmd.Flags().Changed("download-credentials") && (options.count > 1 || len(args) > 1) {
+				return errors.New("--download-credentials cannot be used when creating multiple clusters")
+			}
+
+			if options.generateName != "" {
+				if len(args) > 0 {
+					return errors.New("A cluster name cannot be specified with --generate-name")
+				}
+				return nil
+			}
+			if cmd.Flags().Changed("name-seed") {
+				return errors.New("--name-seed can only be specified with --generate-name")
+			}
+
+			err := bindNewClusterNameArg(args, &options.name)
+			if err != nil {
+				return err
+			}
 
-			return bindClusterNameArg(args, &options.name)
+			options.names = args
+			if options.count > 1 {
+				if len(args) > 1 {
+					return errors.New("--count cannot be used with multiple cluster names")
+				}
+				options.names = numberClusterNames(options.name, options.count)
+			}
+			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cluster, err := cxt.Client.CreateCluster(cxt.Account, options.name, options.template, options.nodes, options.wait)
+			labels, err := client.ParseLabels(options.labels)
+			if err != nil {
+				return err
+			}
+
+			driverOptions, err := client.ParseDriverOptions(options.driverOptions)
+			if err != nil {
+				return err
+			}
+
+			var sshKey common.SSHKey
+			sshKey.KeypairName = options.keypair
+			if options.sshKey != "" {
+				sshKey.PublicKey, err = client.ReadSSHPublicKey(options.sshKey)
+				if err != nil {
+					return err
+				}
+			}
+
+			// The credentials are only available once the cluster is active
+			download := cmd.Flags().Changed("download-credentials")
+			if download {
+				options.wait = true
+			}
+
+			if options.from != "" {
+				spec, err := cxt.Client.GetClusterSpec(cxt.Account, options.from)
+				if err != nil {
+					return err
+				}
+				common.Log.WriteDebug("Copying cluster (%s): template %s, %d nodes, labels %v", options.from, spec.Template, spec.Nodes, spec.Labels)
+
+				options.template = spec.Template
+				if !cmd.Flags().Changed("nodes") {
+					options.nodes = spec.Nodes
+				}
+				// --label adds to, or overrides, the labels of the cluster being copied
+				for key, value := range labels {
+					spec.Labels[key] = value
+				}
+				labels = spec.Labels
+			}
+
+			if !options.selector.IsEmpty() {
+				template, err := cxt.Client.ResolveTemplate(cxt.Account, options.selector)
+				if err != nil {
+					return err
+				}
+				common.Log.WriteDebug("Using the %s template for %s", template.GetName(), options.selector)
+				options.template = template.GetName()
+			}
+
+			if options.generateName != "" {
+				options.names, err = generateClusterNames(options.generateName, options.nameSeed, options.count)
+				if err != nil {
+					return err
+				}
+				options.name = options.names[0]
+			}
+
+			createOpts := client.CreateClusterOptions{
+				// Deprecated templates are only blocked in strict mode
+				AllowDeprecated: options.allowDeprecated || !cxt.Strict,
+				Labels:          labels,
+				DriverOptions:   driverOptions,
+				SSHKey:          sshKey,
+				WaitUntilActive: options.wait,
+				IfNotExists:     options.ifNotExists,
+			}
+			if len(options.names) > 1 {
+				return createClusters(options.names, options.template, options.nodes, createOpts, options.readyCheck)
+			}
+
+			if cxt.DryRun {
+				return writePlan(cxt.Client.PlanCreateCluster(cxt.Account, options.name, options.template, options.nodes, createOpts))
+			}
+
+			cluster, err := cxt.Client.CreateCluster(cxt.Account, options.name, options.template, options.nodes, createOpts)
+			if err != nil && console.IsInteractive() {
+				// Offer the closest templates instead of a dead end when the template wasn't found, or is ambiguous
+				if template, ok := console.PickSuggestedTemplate(err); ok {
+					options.template = template.GetName()
+					cluster, err = cxt.Client.CreateCluster(cxt.Account, options.name, options.template, options.nodes, createOpts)
+				}
+			}
+			if err != nil {
+				return err
+			}
+
+			err = waitUntilReady(cluster, options.wait, options.readyCheck)
 			if err != nil {
 				return err
 			}
 
 			console.WriteCluster(cluster)
 
+			if download {
+				path := options.downloadPath
+				if path == defaultCredentialsPath {
+					path = ""
+				}
+				return downloadCredentials(options.name, path)
+			}
+
 			return nil
 		},
 	}
 
 	cmd.ValidArgs = []string{"cluster-name"}
 	cmd.Flags().StringVarP(&options.template, "template", "t", "", "Name of the template, defining the cluster topology and configuration")
+	cmd.RegisterFlagCompletionFunc("template", completeTemplateNames)
+	cmd.Flags().StringVar(&options.selector.COE, "coe", "", "Select the template by its container orchestration engine, e.g. kubernetes, instead of by name with --template. Only supported on the public cloud")
+	cmd.Flags().StringVar(&options.selector.HostType, "host-type", "", "Select the template by the type of host the nodes run on, e.g. lxc, vm or baremetal, when selecting the template with --coe. Run carina templates to see the host types offered")
+	cmd.Flags().StringVar(&options.selector.Version, "version", "", "Select the template by its COE version, e.g. 1.5.2 or 1.x, when selecting the template with --coe")
 	cmd.Flags().IntVar(&options.nodes, "nodes", 1, "Number of nodes for the initial cluster")
-	cmd.Flags().BoolVar(&options.wait, "wait", false, "Wait for the cluster to become active")
+	cmd.Flags().StringVar(&options.from, "from", "", "Copy the template, number of nodes and labels of an existing cluster, e.g. to create a staging copy of a production cluster. --nodes and --label override the copied values")
+	cmd.RegisterFlagCompletionFunc("from", completeClusterNameFlag)
+	cmd.Flags().BoolVar(&options.allowDeprecated, "allow-deprecated", false, "Allow a deprecated template to be used when --strict is specified")
+	cmd.Flags().StringSliceVar(&options.labels, "label", nil, "Label the cluster with a key=value pair, e.g. env=prod. Labels are local metadata stored in CARINA_HOME. May be specified multiple times")
+	cmd.Flags().StringArrayVar(&options.driverOptions, "driver-opt", nil, "Pass a key=value option to the cluster driver, e.g. kube_tag=v1.9.3. Only supported on the private cloud. May be specified multiple times")
+	cmd.Flags().StringVar(&options.keypair, "keypair", "", "Name of an SSH keypair registered with the account to install on the nodes, see carina keypairs. Only supported on the private cloud")
+	cmd.Flags().StringVar(&options.sshKey, "ssh-key", "", "Path to an SSH public key to install on the nodes, e.g. ~/.ssh/id_rsa.pub")
+	cmd.Flags().StringVar(&options.generateName, "generate-name", "", "Create the cluster with a unique name, made by appending a random suffix to the prefix, e.g. ci-")
+	cmd.Flags().IntVar(&options.count, "count", 1, "Number of clusters to create concurrently. The clusters are numbered, e.g. workshop-1, unless --generate-name is specified")
+	cmd.Flags().Int64Var(&options.nameSeed, "name-seed", 0, "Seed for the random suffix used by --generate-name, so that the same name is generated each time. Defaults to a random seed")
+	cmd.Flags().StringVar(&options.downloadPath, "download-credentials", "", "Wait for the cluster to become active, then download its credentials, optionally to the specified directory. Defaults to the same directory as carina credentials")
+	cmd.Flags().Lookup("download-credentials").NoOptDefVal = defaultCredentialsPath
+	cmd.Flags().BoolVar(&options.ifNotExists, "if-not-exists", false, "Return the cluster when one with the same name already exists, instead of an error. Combine with --wait so that provisioning scripts can be safely re-run")
+	addWaitFlags(cmd, &options.wait, "Wait for the cluster to become active")
+	addOnSuccessFlag(cmd, "Shell command to run after each cluster is created")
+	addReadyCheckFlag(cmd, &options.readyCheck)
+	addDryRunFlag(cmd, "Validate the cluster and print what would be created, without creating it")
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
 }
+
+// numberClusterNames builds the names for --count, e.g. workshop-1, workshop-2
+func numberClusterNames(name string, count int) []string {
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s-%d", name, i+1)
+	}
+	return names
+}
+
+// generateClusterNames generates unique cluster names for --generate-name
+func generateClusterNames(prefix string, seed int64, count int) ([]string, error) {
+	generator := client.NewNameGenerator(seed)
+	var names []string
+	generated := make(map[string]bool)
+	for len(names) < count {
+		name, err := cxt.Client.GenerateClusterName(cxt.Account, prefix, generator)
+		if err != nil {
+			return nil, err
+		}
+		if generated[name] {
+			continue
+		}
+		generated[name] = true
+		names = append(names, name)
+
+		if console.Format != console.FormatJSON {
+			console.Write("Generated cluster name: %s", name)
+		}
+	}
+	return names, nil
+}
+
+// createClusters creates multiple clusters concurrently, printing a summary and returning an error if any fail
+func createClusters(names []string, template string, nodes int, createOpts client.CreateClusterOptions, readyCheck string) error {
+	if cxt.DryRun {
+		return writePlan(cxt.Client.PlanCreateClusters(cxt.Account, names, template, nodes, createOpts))
+	}
+
+	results, err := cxt.Client.CreateClusters(cxt.Account, names, template, nodes, createOpts)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		if result.Err == nil && createOpts.WaitUntilActive && readyCheck == client.ReadyCheckCOE {
+			var cluster common.Cluster
+			cluster, results[i].Err = cxt.Client.GetCluster(cxt.Account, result.Name, false)
+			if results[i].Err == nil {
+				results[i].Err = waitUntilReady(cluster, true, readyCheck)
+			}
+		}
+	}
+
+	console.WriteClusterOperationResults(results, "Created")
+	return checkOperationResults(results, "create", "clusters")
+}
@@ -1,43 +1,846 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
 	"github.com/getcarina/carina/console"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func newCredentialsCommand() *cobra.Command {
+	var options struct {
+		name       string
+		path       string
+		only       string
+		rename     []string
+		as         string
+		namespace  string
+		secretName string
+		noStore    bool
+		backend    string
+		verify     bool
+		all        bool
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "credentials <cluster-name>",
+		Short: "Download a cluster's credentials",
+		Long:  "Download a cluster's credentials.\n\nWhen saving to --path, the files can be renamed for tools which expect specific names with --rename or the credentials.filenames setting, e.g. --rename kubeconfig=config --rename ca.pem={cluster}-ca.pem. References to a renamed file in the scripts and kubeconfig are updated. Docker requires ca.pem, cert.pem and key.pem, so renaming them breaks docker.env.\n\nUse --as k8s-secret to print a Kubernetes Secret manifest containing the credentials instead, so that other workloads can be given access to the cluster. For Kubernetes clusters, the Secret also has a kubeconfig key with the certificates embedded.\n\nUse --no-store on shared or ephemeral machines to print the credentials as a tar stream, without saving them to disk.\n\nUse --verify to show the fingerprints of the downloaded credentials instead, and check them against the fingerprints recorded when the credentials were last downloaded. Use --verify --all to check that every downloaded credentials bundle has not expired and can reach its cluster.\n\nUse --credentials-backend, or the credentials.backend setting, to keep the credentials for every cluster in one place instead of on each workstation: tar prints them as a tar stream, with a directory per cluster, and vault saves them to a HashiCorp Vault KV version 2 secrets engine. Vault is configured with VAULT_ADDR, VAULT_TOKEN, and optionally VAULT_NAMESPACE, and the credentials are saved below CARINA_VAULT_PATH, which defaults to secret/carina, using the credentials path template, e.g. secret/carina/public-dfw-bob/mycluster.",
+		Example: `  carina credentials mycluster
+  carina credentials mycluster --path ~/.kube/mycluster --rename kubeconfig=config
+  carina credentials mycluster --as k8s-secret --namespace ci | kubectl apply -f -
+  carina credentials mycluster --no-store | tar -x -C /dev/shm
+  carina credentials mycluster --verify
+  carina credentials --verify --all
+  carina credentials download --all --credentials-backend vault`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if options.verify {
+				return validateVerifyCredentialsFlags(cmd, args, options.all, &options.name)
+			}
+			if options.all {
+				return errors.New("--all requires --verify, use carina credentials download --all to download every cluster's credentials")
+			}
+
+			err := bindClusterNameArg(args, &options.name)
+			if err != nil {
+				return err
+			}
+
+			if options.noStore && (options.path != "" || options.only != "" || options.as != credentialsAsFiles || cmd.Flags().Changed("rename")) {
+				return errors.New("--no-store cannot be used with --path, --only, --rename or --as")
+			}
+			if cmd.Flags().Changed("credentials-backend") && (options.noStore || options.only != "" || options.as != credentialsAsFiles) {
+				return errors.New("--credentials-backend cannot be used with --no-store, --only or --as")
+			}
+
+			switch options.as {
+			case credentialsAsFiles:
+				if cmd.Flags().Changed("namespace") || cmd.Flags().Changed("secret-name") {
+					return errors.New("--namespace and --secret-name require --as k8s-secret")
+				}
+			case credentialsAsK8sSecret:
+				if options.only != "" || cmd.Flags().Changed("rename") {
+					return errors.New("--only and --rename cannot be used with --as k8s-secret")
+				}
+			default:
+				return fmt.Errorf("Invalid --as %s, must be %s or %s", options.as, credentialsAsFiles, credentialsAsK8sSecret)
+			}
+
+			// filenames = --rename -> config file -> the names from the bundle
+			if !cmd.Flags().Changed("rename") {
+				options.rename = splitConfigList(viper.GetString("credentials.filenames"))
+			} else if options.path == "" {
+				return errors.New("--rename requires --path, credentials saved in CARINA_HOME keep the names from the bundle")
+			}
+			client.CredentialsFilenamePolicy.Names, err = client.ParseCredentialsFilenames(options.rename)
+			if err != nil {
+				return err
+			}
+
+			if options.noStore || options.only != "" || options.as != credentialsAsFiles {
+				return nil
+			}
+			return bindCredentialsBackend(options.backend, options.path)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.verify {
+				if options.all {
+					return verifyAllCredentials()
+				}
+				return verifyCredentials(options.name, options.path)
+			}
+			if options.as == credentialsAsK8sSecret {
+				secret := client.SecretManifestOptions{Name: options.secretName, Namespace: options.namespace}
+				return cxt.Client.WriteClusterCredentialsSecret(cxt.Account, options.name, options.path, secret, os.Stdout)
+			}
+			if options.only != "" {
+				return downloadCredentialFile(options.name, options.only, options.path)
+			}
+			if options.noStore {
+				return cxt.Client.WriteClusterCredentialsTar(cxt.Account, options.name, os.Stdout)
+			}
+
+			return downloadCredentials(options.name, options.path)
+		},
+	}
+
+	cmd.AddCommand(newCredentialsDownloadCommand())
+	cmd.AddCommand(newCredentialsDiffCommand())
+	cmd.AddCommand(newCredentialsExportCommand())
+	cmd.AddCommand(newCredentialsInspectCommand())
+	cmd.AddCommand(newCredentialsImportCommand())
+	cmd.AddCommand(newCredentialsRefreshCommand())
+	cmd.AddCommand(newCredentialsRotateCommand())
+	cmd.AddCommand(newCredentialsDoctorCommand())
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory where the credentials should be saved")
+	cmd.Flags().StringVar(&options.only, "only", "", "Only retrieve a single file from the credentials, e.g. kubeconfig, ca.pem or cert.pem. The file is printed, unless --path is specified")
+	cmd.Flags().StringVar(&options.as, "as", credentialsAsFiles, "How to output the credentials: files, saved to CARINA_HOME or --path, or k8s-secret, a Kubernetes Secret manifest printed to stdout")
+	cmd.Flags().StringVar(&options.namespace, "namespace", "", "Namespace of the Secret with --as k8s-secret, defaults to the current namespace when the manifest is applied")
+	cmd.Flags().StringVar(&options.secretName, "secret-name", "", "Name of the Secret with --as k8s-secret, defaults to <cluster-name>-credentials")
+	cmd.Flags().BoolVar(&options.noStore, "no-store", false, "Print the credentials as a tar stream, instead of saving them to disk")
+	addCredentialsBackendFlag(cmd, &options.backend)
+	cmd.Flags().BoolVar(&options.verify, "verify", false, "Show the fingerprints of the downloaded credentials, and check them against the fingerprints recorded when they were last downloaded, instead of downloading the credentials")
+	cmd.Flags().BoolVar(&options.all, "all", false, "With --verify, check the expiry and reachability of every downloaded credentials bundle in parallel, and print a summary")
+	cmd.Flags().StringSliceVar(&options.rename, "rename", nil, "Save a file with another name when --path is specified, e.g. kubeconfig=config. {cluster} is replaced with the cluster name. May be specified multiple times. Defaults to the credentials.filenames setting")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+// The output formats for carina credentials --as
+const (
+	credentialsAsFiles     = "files"
+	credentialsAsK8sSecret = "k8s-secret"
+)
+
+// addCredentialsBackendFlag adds --credentials-backend, which overrides the credentials.backend setting
+func addCredentialsBackendFlag(cmd *cobra.Command, backend *string) {
+	cmd.Flags().StringVar(backend, "credentials-backend", "", "Where to save the credentials: local, to CARINA_HOME or --path, tar, printed as a tar stream, or vault. Defaults to the credentials.backend setting or local")
+}
+
+// bindCredentialsBackend selects where downloaded credentials are saved: --credentials-backend -> config file -> local
+func bindCredentialsBackend(backend string, path string) error {
+	if backend == "" {
+		backend = viper.GetString("credentials.backend")
+	}
+	if backend == "" {
+		backend = client.CredentialsBackendLocal
+	}
+	if backend != client.CredentialsBackendLocal && path != "" {
+		return fmt.Errorf("--path cannot be used with the %s credentials backend", backend)
+	}
+
+	writer, err := client.NewCredentialsWriter(backend, os.Stdout)
+	if err != nil {
+		return err
+	}
+	cxt.CredentialsBackend = backend
+	client.CredentialsStoragePolicy.Writer = writer
+	return nil
+}
+
+// closeCredentialsWriter finishes saving the downloaded credentials, e.g. the end of the tar stream
+func closeCredentialsWriter(err error) error {
+	writer := client.CredentialsStoragePolicy.Writer
+	if writer == nil {
+		return err
+	}
+
+	closeErr := writer.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// downloadCredentials saves a cluster's credentials bundle, and prints how to connect to the cluster
+func downloadCredentials(name string, path string) error {
+	location, err := cxt.Client.DownloadClusterCredentials(cxt.Account, name, path)
+	err = closeCredentialsWriter(err)
+	if err != nil {
+		return err
+	}
+
+	switch cxt.CredentialsBackend {
+	case client.CredentialsBackendTar:
+		// stdout is the tar stream
+		return nil
+	case client.CredentialsBackendVault:
+		console.WriteValue(location, fmt.Sprintf("# Credentials saved to %s", location))
+		return nil
+	}
+
+	console.WriteValue(location,
+		"#",
+		fmt.Sprintf("# Credentials written to \"%s\"", location),
+		client.CredentialsNextStepsString(name),
+		"#")
+
+	warnIncompatibleClient(name, location)
+
+	return nil
+}
+
+// warnIncompatibleClient warns when the docker or kubectl client on the PATH can't be used with the cluster
+func warnIncompatibleClient(name string, credentialsPath string) {
+	result, err := cxt.Client.CheckClientCompatibility(cxt.Account, name, credentialsPath)
+	if err != nil {
+		common.Log.WriteDebug("Unable to check the client version: %s", err)
+		return
+	}
+	if result == nil || result.Compatible {
+		return
+	}
+
+	common.Log.WriteWarning("%s %s is not compatible with %s, which is running %s. Run carina install %s %s to download a matching client",
+		result.Tool, result.ClientVersion, name, result.ServerVersion, result.Tool, name)
+}
+
+func newCredentialsDownloadCommand() *cobra.Command {
+	var options struct {
+		name     string
+		names    []string
+		patterns []string
+		file     string
+		path     string
+		all      bool
+		matchAll bool
+		backend  string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "download <cluster-name>...",
+		Short: "Download a cluster's credentials, or the credentials for every cluster",
+		Long:  "Download a cluster's credentials, same as carina credentials <cluster-name>. Specify more than one cluster name, or a pattern such as 'prod-*', to download the credentials for each matching cluster concurrently and print the result for each cluster. Use --all to download the credentials for every cluster in the account, e.g. when setting up a new workstation. The names of the clusters can also be read from a file with --file, or from stdin with - as the cluster name. With more than one cluster, --path is the directory in which a directory is created for each cluster.",
+		Example: `  carina credentials download mycluster
+  carina credentials download web api worker
+  carina credentials download 'prod-*'
+  carina credentials download --all
+  carina clusters --format json | jq -r .clusters[].name | carina credentials download -`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			client.CredentialsFilenamePolicy.Names, err = client.ParseCredentialsFilenames(splitConfigList(viper.GetString("credentials.filenames")))
+			if err != nil {
+				return err
+			}
+
+			err = bindCredentialsBackend(options.backend, options.path)
+			if err != nil {
+				return err
+			}
+
+			names, fromList, err := readClusterNames(args, options.file)
+			if fromList {
+				if options.all {
+					return errors.New("--all cannot be used with a list of cluster names")
+				}
+				options.names = names
+				return err
+			}
+
+			if options.all {
+				if len(args) > 0 {
+					return errors.New("A cluster name cannot be specified with --all")
+				}
+				return nil
+			}
+
+			if len(args) > 1 || (len(args) == 1 && common.NameMatchPolicy.IsPattern(args[0])) {
+				options.patterns = args
+				return nil
+			}
+
+			return bindMatchingClusterNamesArg(args, options.matchAll, &options.name, &options.names)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.patterns) > 0 {
+				var err error
+				options.names, err = cxt.Client.ResolveClusterNames(cxt.Account, options.patterns)
+				if err != nil {
+					return err
+				}
+			}
+
+			if !options.all && len(options.names) == 0 {
+				return downloadCredentials(options.name, options.path)
+			}
+
+			var results []client.ClusterOperationResult
+			var err error
+			if options.all {
+				results, err = cxt.Client.DownloadAllClusterCredentials(cxt.Account, options.path)
+			} else {
+				results, err = cxt.Client.DownloadClustersCredentials(cxt.Account, options.names, options.path)
+			}
+			err = closeCredentialsWriter(err)
+			if err != nil {
+				return err
+			}
+
+			if cxt.CredentialsBackend == client.CredentialsBackendTar {
+				// stdout is the tar stream, so only the failures are reported
+				for _, result := range results {
+					if result.Err != nil {
+						common.Log.WriteWarning("Unable to download the credentials for %s: %s", result.Name, result.Err)
+					}
+				}
+			} else {
+				console.WriteClusterOperationResults(results, "downloaded")
+			}
+			return checkOperationResults(results, "download the credentials for", "clusters")
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory where the credentials should be saved")
+	cmd.Flags().BoolVar(&options.all, "all", false, "Download the credentials for every cluster in the account")
+	addCredentialsBackendFlag(cmd, &options.backend)
+	addClusterNamesFileFlag(cmd, &options.file)
+	addMatchAllFlag(cmd, &options.matchAll)
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+// validateVerifyCredentialsFlags binds the cluster name for --verify, which only checks the downloaded credentials,
+// so the flags which control how the credentials are downloaded can't be used with it
+func validateVerifyCredentialsFlags(cmd *cobra.Command, args []string, all bool, name *string) error {
+	for _, flag := range []string{"only", "rename", "as", "namespace", "secret-name", "no-store", "credentials-backend", "file", "match-all"} {
+		if cmd.Flags().Changed(flag) {
+			return fmt.Errorf("--%s cannot be used with --verify", flag)
+		}
+	}
+
+	if all {
+		if len(args) > 0 {
+			return errors.New("A cluster name cannot be specified with --all")
+		}
+		if cmd.Flags().Changed("path") {
+			return errors.New("--path cannot be specified with --all")
+		}
+		return nil
+	}
+
+	return bindClusterNameArg(args, name)
+}
+
+// verifyCredentials prints the fingerprints of a cluster's downloaded credentials, and the fingerprints of previous downloads,
+// warning when they changed since the credentials were last downloaded
+func verifyCredentials(name string, path string) error {
+	current, history, err := cxt.Client.VerifyClusterCredentials(cxt.Account, name, path)
+	if _, changed := err.(client.CredentialsChangedError); err != nil && !changed {
+		return err
+	}
+
+	console.Write("CA:   %s", current.CA)
+	console.Write("Cert: %s", current.Cert)
+
+	if len(history) > 0 {
+		console.Write("")
+		console.Write("Download history:")
+		for _, fingerprint := range history {
+			console.Write("%s  CA %s  Cert %s", fingerprint.Downloaded.Local().Format(time.RFC822), fingerprint.CA, fingerprint.Cert)
+		}
+	}
+
+	if err != nil {
+		common.Log.WriteStructuredWarning(common.WarningCredentialsChanged, "%s", err)
+	}
+
+	return nil
+}
+
+// verifyAllCredentials checks every downloaded credentials bundle, and fails when any of them can't be used
+func verifyAllCredentials() error {
+	results, err := cxt.Client.CheckAllClusterCredentials(cxt.Account)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		console.Write("No credentials have been downloaded")
+		return nil
+	}
+
+	console.WriteCredentialsHealth(results)
+
+	var broken int
+	for _, result := range results {
+		if !result.IsHealthy() {
+			broken++
+		}
+	}
+	if broken > 0 {
+		return fmt.Errorf("%d of %d credentials bundles have problems", broken, len(results))
+	}
+	return nil
+}
+
+func newCredentialsDiffCommand() *cobra.Command {
+	var options struct {
+		name string
+		path string
+	}
+
+	var cmd = &cobra.Command{
+		Use:               "diff <cluster-name>",
+		Short:             "Compare a cluster's downloaded credentials with its current credentials",
+		Long:              "Compare a cluster's downloaded credentials with its current credentials, such as after the cluster is rebuilt or its certificates are rotated. The downloaded credentials are not changed.",
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			diff, err := cxt.Client.DiffClusterCredentials(cxt.Account, options.name, options.path)
+			if err != nil {
+				return err
+			}
+
+			if !diff.HasChanges() {
+				console.Write("The downloaded credentials for %s are up-to-date", options.name)
+				return nil
+			}
+
+			for _, file := range diff.Added {
+				console.Write("+ %s", file)
+			}
+			for _, file := range diff.Removed {
+				console.Write("- %s", file)
+			}
+			for _, file := range diff.Changed {
+				console.Write("~ %s", file)
+			}
+
+			console.Write("")
+			console.WriteTable([][]string{
+				{"", "Downloaded", "Current"},
+				{"CA", diff.Local.CA, diff.Remote.CA},
+				{"Cert", diff.Local.Cert, diff.Remote.Cert},
+				{"Endpoint", diff.LocalEndpoint, diff.RemoteEndpoint},
+			})
+			console.Write("")
+			console.Write("# To download the current credentials, run: carina credentials %s", options.name)
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory from which the credentials should be loaded")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newCredentialsExportCommand() *cobra.Command {
+	var options struct {
+		name   string
+		path   string
+		output string
+	}
+
+	var cmd = &cobra.Command{
+		Use:               "export <cluster-name>",
+		Short:             "Export a cluster's credentials to a zip file",
+		Long:              "Export a cluster's credentials to a zip file, which can be imported on another machine with carina credentials import",
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			archivePath, err := cxt.Client.ExportClusterCredentials(cxt.Account, options.name, options.path, options.output)
+			if err != nil {
+				return err
+			}
+
+			console.WriteValue(archivePath, fmt.Sprintf("# Credentials exported to \"%s\"", archivePath))
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVarP(&options.output, "output", "o", "", "Path to the zip file, defaults to <cluster-name>.zip")
+	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory from which the credentials should be loaded")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newCredentialsInspectCommand() *cobra.Command {
 	var options struct {
 		name string
 		path string
 	}
 
 	var cmd = &cobra.Command{
-		Use:               "credentials <cluster-name>",
-		Short:             "Download a cluster's credentials",
-		Long:              "Download a cluster's credentials",
+		Use:               "inspect <cluster-name>",
+		Short:             "Show the certificates in a cluster's downloaded credentials",
+		Long:              "Show the issuer, subject and expiration of the certificates in a cluster's downloaded credentials",
 		PersistentPreRunE: authenticatedPreRunE,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			return bindClusterNameArg(args, &options.name)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			credentialsPath, err := cxt.Client.DownloadClusterCredentials(cxt.Account, options.name, options.path)
+			certs, err := cxt.Client.InspectClusterCredentials(cxt.Account, options.name, options.path)
 			if err != nil {
 				return err
 			}
 
-			console.Write("#")
-			console.Write("# Credentials written to \"%s\"", credentialsPath)
-			console.Write(client.CredentialsNextStepsString(options.name))
-			console.Write("#")
+			data := [][]string{{"File", "Subject", "Issuer", "Expires"}}
+			for _, cert := range certs {
+				data = append(data, []string{cert.File, cert.Subject, cert.Issuer, cert.NotAfter.Local().Format(time.RFC822)})
+			}
+			console.WriteTable(data)
+
+			for _, cert := range certs {
+				if cert.ExpiresWithin(0) {
+					common.Log.WriteWarning("WARNING: %s has expired. Run carina credentials %s to download new credentials.", cert.File, options.name)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory from which the credentials should be loaded")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newCredentialsImportCommand() *cobra.Command {
+	var options struct {
+		archive string
+		path    string
+	}
+
+	var cmd = &cobra.Command{
+		Use:               "import <zip-file>",
+		Short:             "Import a cluster's credentials from a zip file",
+		Long:              "Import a cluster's credentials from a zip file created by carina credentials export. The certificates are checked for expiry before the credentials are saved.",
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A zip file is required")
+			}
+			options.archive = args[0]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, credentialsPath, err := cxt.Client.ImportClusterCredentials(cxt.Account, options.archive, options.path)
+			if err != nil {
+				return err
+			}
 
+			console.WriteValue(credentialsPath,
+				"#",
+				fmt.Sprintf("# Credentials for %s written to \"%s\"", name, credentialsPath),
+				client.CredentialsNextStepsString(name),
+				"#")
 			return nil
 		},
 	}
 
-	cmd.ValidArgs = []string{"cluster-name"}
 	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory where the credentials should be saved")
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
 }
+
+// downloadCredentialFile prints a single file from the credentials bundle, or saves it when a path is specified
+func downloadCredentialFile(name string, file string, path string) error {
+	if path != "" {
+		filePath, err := cxt.Client.DownloadClusterCredentialFile(cxt.Account, name, file, path)
+		if err != nil {
+			return err
+		}
+
+		console.WriteValue(filePath, fmt.Sprintf("# Credentials written to \"%s\"", filePath))
+		return nil
+	}
+
+	_, contents, err := cxt.Client.GetClusterCredentialFile(cxt.Account, name, file)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(contents)
+	return err
+}
+
+// defaultRefreshInterval is how often the credentials are checked in agent mode
+const defaultRefreshInterval = time.Hour
+
+// defaultRenewBefore is how long before the client certificate expires that the credentials are downloaded again
+const defaultRenewBefore = 7 * 24 * time.Hour
+
+func newCredentialsRefreshCommand() *cobra.Command {
+	var options struct {
+		agent       bool
+		interval    time.Duration
+		renewBefore time.Duration
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "refresh",
+		Short: "Download the credentials again before their certificates expire",
+		Long: `Download the credentials again for each cluster whose client certificate expires soon, see --renew-before. The credentials are replaced in place, so kubeconfig entries and docker environments which use them keep working.
+
+Use --agent to keep running and check the credentials on an interval, e.g. from a login item or a systemd user service, so that the certificates never expire. The agent's caches are limited in size and age, so that it stays lean, and their metrics are logged with --debug.`,
+		Example: `  carina credentials refresh
+  carina credentials refresh --agent --interval 1h --renew-before 72h`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if options.interval <= 0 {
+				return errors.New("--interval must be > 0")
+			}
+			if options.renewBefore < 0 {
+				return errors.New("--renew-before must be >= 0")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !options.agent {
+				results, err := cxt.Client.RefreshExpiringCredentials(cxt.Account, options.renewBefore)
+				if err != nil {
+					return err
+				}
+				console.WriteCredentialsRefresh(results)
+
+				var failed int
+				for _, result := range results {
+					if result.Err != nil {
+						failed++
+					}
+				}
+				if failed > 0 {
+					return fmt.Errorf("Unable to refresh the credentials for %d of %d clusters", failed, len(results))
+				}
+				return nil
+			}
+
+			console.Write("Refreshing credentials which expire within %s, checking every %s. Press Ctrl+C to stop", options.renewBefore, options.interval)
+			for {
+				results, err := cxt.Client.RefreshExpiringCredentials(cxt.Account, options.renewBefore)
+				if err == common.ErrShuttingDown {
+					return nil
+				}
+				if err != nil {
+					common.Log.WriteWarning("Unable to refresh the credentials, trying again in %s: %s", options.interval, err)
+				}
+				writeRefreshedCredentials(results)
+				for _, stats := range cxt.Client.CacheStats() {
+					common.Log.WriteDebug("Cache %s", stats)
+				}
+
+				if common.Sleep(options.interval) != nil {
+					return nil
+				}
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&options.agent, "agent", false, "Keep running, and refresh the credentials on an interval until interrupted")
+	cmd.Flags().DurationVar(&options.interval, "interval", defaultRefreshInterval, "How often to check the credentials with --agent, e.g. 30m")
+	cmd.Flags().DurationVar(&options.renewBefore, "renew-before", defaultRenewBefore, "Download the credentials again when the client certificate expires within this amount of time, e.g. 72h")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newCredentialsRotateCommand() *cobra.Command {
+	var options struct {
+		name  string
+		path  string
+		shell string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "rotate <cluster-name>",
+		Short: "Replace a cluster's certificates, e.g. after the credentials are leaked",
+		Long: `Request new certificates for a cluster, then replace the downloaded credentials with them. The existing files are only replaced once every new file has been downloaded, and the cluster's entry in the kubeconfig added by carina kubeconfig is updated.
+
+When the cloud supports rotating the certificate authority, e.g. the private cloud, every copy of the previous credentials stops working, so the rotation must be confirmed. Otherwise new credentials are downloaded, but the previous credentials remain valid until they expire.
+
+Afterwards, the command to load the new credentials into the current shell is printed, see carina env.`,
+		Example: `  carina credentials rotate mycluster
+  carina credentials rotate mycluster --path ~/.kube/mycluster --force`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			err := bindClusterNameArg(args, &options.name)
+			if err != nil {
+				return err
+			}
+
+			if options.shell == "" {
+				options.shell, err = resolveShell()
+			}
+			return err
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			actions, err := cxt.Client.ListClusterActions(cxt.Account)
+			if err != nil {
+				return err
+			}
+			if action, err := common.LookupClusterAction(actions, "rotate-ca"); err == nil {
+				destructiveOperations[action.Name] = true
+				err = confirmOperation(action.Name, options.name, nil)
+				if err != nil {
+					return err
+				}
+			}
+
+			result, err := cxt.Client.RotateClusterCredentials(cxt.Account, options.name, options.path)
+			if err != nil {
+				return err
+			}
+
+			if result.CARotated {
+				console.Write("Rotated the certificate authority of %s, the previous credentials no longer work", options.name)
+			}
+			console.WriteValue(result.CredentialsPath, fmt.Sprintf("New credentials written to \"%s\"", result.CredentialsPath))
+			if !result.Expires.IsZero() {
+				console.Write("The client certificate expires %s", result.Expires.Local().Format(time.RFC822))
+			}
+			if result.Kubeconfig != "" {
+				console.Write("Updated the carina-%s context in %s", options.name, result.Kubeconfig)
+			}
+
+			sourceText, err := cxt.Client.GetSourceCommand(cxt.Account, options.shell, options.name, result.CredentialsPath)
+			if err != nil {
+				return err
+			}
+			console.Write("%s", sourceText)
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory where the credentials were saved")
+	cmd.Flags().StringVar(&options.shell, "shell", "", "The shell type of the printed source command. Allowed values: bash, zsh, fish, powershell, cmd [SHELL]")
+	addForceFlag(cmd)
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+// writeRefreshedCredentials logs the credentials which were refreshed or failed in agent mode, skipping the credentials which are current
+func writeRefreshedCredentials(results []client.CredentialsRefresh) {
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			common.Log.WriteWarning("%s Unable to refresh the credentials for %s: %s", time.Now().Format(time.RFC3339), result.Name, result.Err)
+		case result.Refreshed:
+			console.Write("%s Refreshed the credentials for %s, which now expire %s", time.Now().Format(time.RFC3339), result.Name, result.Expires.Local().Format(time.RFC822))
+		}
+	}
+}
+
+// defaultOrphanedAfter is how long another account's credentials directory must be unused before it is considered orphaned
+const defaultOrphanedAfter = 90 * 24 * time.Hour
+
+func newCredentialsDoctorCommand() *cobra.Command {
+	var options struct {
+		fix           bool
+		orphanedAfter time.Duration
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Find stale, expired and orphaned credentials in the credentials directory",
+		Long: `Scan the credentials directory for problems:
+  stale:    The credentials are for a cluster which no longer exists
+  expired:  The client certificate of an existing cluster has expired
+  orphaned: The directory belongs to another account, such as one which was renamed, and hasn't been used recently. See --orphaned-after
+
+Use --fix to delete the stale and orphaned credentials, and download the expired credentials again.`,
+		Example: `  carina credentials doctor
+  carina credentials doctor --fix --orphaned-after 720h`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if options.orphanedAfter < 0 {
+				return errors.New("--orphaned-after must be >= 0")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			issues, err := cxt.Client.DiagnoseCredentialsDir(cxt.Account, options.orphanedAfter)
+			if err != nil {
+				return err
+			}
+			if len(issues) == 0 {
+				console.Write("No problems found in the credentials directory")
+				return nil
+			}
+
+			if !options.fix {
+				console.WriteCredentialsIssues(issues, false)
+				console.Write("")
+				console.Write("Run carina credentials doctor --fix to clean up the credentials directory")
+				return nil
+			}
+
+			if !cxt.AssumeYes {
+				if !console.IsInteractive() {
+					return fmt.Errorf("Fixing %d problems requires confirmation. Use --yes to skip the confirmation", len(issues))
+				}
+				console.WriteCredentialsIssues(issues, false)
+				if !console.Confirm(fmt.Sprintf("Are you sure you want to fix %d problems?", len(issues))) {
+					return errors.New("Canceled credentials doctor --fix")
+				}
+			}
+
+			issues = cxt.Client.FixCredentialsIssues(cxt.Account, issues)
+			console.WriteCredentialsIssues(issues, true)
+
+			var failed int
+			for _, issue := range issues {
+				if !issue.Fixed {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("Unable to fix %d of %d problems", failed, len(issues))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&options.fix, "fix", false, "Delete the stale and orphaned credentials, and download the expired credentials again")
+	cmd.Flags().DurationVar(&options.orphanedAfter, "orphaned-after", defaultOrphanedAfter, "How long another account's credentials must be unused before they are considered orphaned, e.g. 720h")
+	addForceFlag(cmd)
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
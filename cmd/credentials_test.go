@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCredentialsVerifyFlags(t *testing.T) {
+	testcases := []struct {
+		args  []string
+		error string
+	}{
+		{[]string{"--all"}, "--all requires --verify"},
+		{[]string{"--verify", "--all", "mycluster"}, "A cluster name cannot be specified with --all"},
+		{[]string{"--verify", "--only", "ca.pem", "mycluster"}, "--only cannot be used with --verify"},
+		{[]string{"--verify", "--no-store", "mycluster"}, "--no-store cannot be used with --verify"},
+	}
+
+	cxt = &context{}
+	defer func() { cxt = nil }()
+
+	for _, tc := range testcases {
+		cmd := newCredentialsCommand()
+		require.NoError(t, cmd.ParseFlags(tc.args))
+
+		err := cmd.PreRunE(cmd, cmd.Flags().Args())
+		if assert.Error(t, err, "%v should be rejected", tc.args) {
+			assert.Contains(t, err.Error(), tc.error)
+		}
+	}
+}
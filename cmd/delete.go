@@ -1,45 +1,158 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
 	"github.com/spf13/cobra"
 )
 
 func newDeleteCommand() *cobra.Command {
 	var options struct {
-		name string
-		wait bool
+		name     string
+		names    []string
+		file     string
+		wait     bool
+		all      bool
+		matchAll bool
 	}
 
 	var cmd = &cobra.Command{
-		Use:               "delete <cluster-name>",
-		Aliases:           []string{"rm"},
-		Short:             "Delete a cluster",
-		Long:              "Delete a cluster",
+		Use:     "delete <cluster-name>",
+		Aliases: []string{"rm"},
+		Short:   "Delete a cluster",
+		Long:    "Delete a cluster. Multiple clusters can be deleted by using a pattern for the name, e.g. 'test-*', or with --all. Use --match-mode regex to use a regular expression for the pattern.\n\nThe names of the clusters to delete can also be read from a file with --file, or from stdin with - as the cluster name. The result for each cluster is printed, and the command fails if any of the clusters could not be deleted.",
+		Example: `  carina delete mycluster
+  carina delete 'test-*'
+  carina clusters --format json | jq -r '.clusters[] | select(.status == "error") | .name' | carina delete - --yes`,
 		PersistentPreRunE: authenticatedPreRunE,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			return bindClusterNameArg(args, &options.name)
+			names, fromList, err := readClusterNames(args, options.file)
+			if fromList {
+				if options.all {
+					return errors.New("--all cannot be used with a list of cluster names")
+				}
+				options.names = names
+				return err
+			}
+
+			if options.all {
+				if len(args) > 0 {
+					return errors.New("A cluster name cannot be specified with --all")
+				}
+				pattern, err := common.NameMatchPolicy.MatchAllPattern()
+				if err != nil {
+					return errors.New("--all cannot be used with --match-mode exact, use --match-mode glob or regex instead")
+				}
+				options.name = pattern
+				return nil
+			}
+
+			return bindMatchingClusterNamesArg(args, options.matchAll, &options.name, &options.names)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := cxt.Client.DeleteCluster(cxt.Account, options.name, options.wait)
+			if len(options.names) > 0 {
+				return deleteClusters(options.names, options.wait)
+			}
+			if options.all || common.NameMatchPolicy.IsPattern(options.name) {
+				return deleteMatchingClusters(options.name, options.wait)
+			}
+
+			if cxt.DryRun {
+				return writePlan(cxt.Client.PlanDeleteClusters(cxt.Account, []string{options.name}))
+			}
+
+			err := confirmOperation("delete", options.name, nil)
+			if err != nil {
+				return err
+			}
+
+			err = cxt.Client.DeleteCluster(cxt.Account, options.name, options.wait)
 			if err != nil {
 				return err
 			}
 
-			if options.wait {
-				fmt.Printf("Deleted cluster (%s)\n", options.name)
-			} else {
-				fmt.Printf("Deleting cluster (%s)\n", options.name)
+			if console.Format != console.FormatJSON {
+				if options.wait {
+					console.Write("Deleted cluster (%s)", options.name)
+				} else {
+					console.Write("Deleting cluster (%s)", options.name)
+				}
 			}
 
 			return nil
 		},
 	}
 
-	cmd.ValidArgs = []string{"cluster-name"}
-	cmd.Flags().BoolVar(&options.wait, "wait", false, "Wait for the cluster to be deleted")
+	cmd.ValidArgsFunction = completeClusterNames
+	addWaitFlags(cmd, &options.wait, "Wait for the cluster to be deleted")
+	addOnSuccessFlag(cmd, "Shell command to run after each cluster is deleted")
+	cmd.Flags().BoolVar(&options.all, "all", false, "Delete all clusters")
+	addClusterNamesFileFlag(cmd, &options.file)
+	addMatchAllFlag(cmd, &options.matchAll)
+	addForceFlag(cmd)
+	addDryRunFlag(cmd, "Print the clusters which would be deleted, without deleting them")
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
 }
+
+// deleteMatchingClusters deletes every cluster whose name matches the pattern, after confirming with the user.
+// The matched clusters are deleted by id, instead of looking up each name again.
+func deleteMatchingClusters(pattern string, wait bool) error {
+	clusters, err := cxt.Client.MatchClusters(cxt.Account, pattern)
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		return fmt.Errorf("No clusters match %s", pattern)
+	}
+
+	names := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		names[i] = cluster.GetName()
+	}
+
+	return confirmAndDeleteClusters(names, wait, func() ([]client.ClusterOperationResult, error) {
+		return cxt.Client.DeleteMatchedClusters(cxt.Account, clusters, wait)
+	})
+}
+
+// deleteClusters deletes multiple clusters, after confirming with the user, and prints the result for each cluster
+func deleteClusters(names []string, wait bool) error {
+	return confirmAndDeleteClusters(names, wait, func() ([]client.ClusterOperationResult, error) {
+		return cxt.Client.DeleteClusters(cxt.Account, names, wait)
+	})
+}
+
+// confirmAndDeleteClusters confirms deleting the clusters with the user, then deletes them and prints the result for each cluster
+func confirmAndDeleteClusters(names []string, wait bool, deleteClusters func() ([]client.ClusterOperationResult, error)) error {
+	if cxt.DryRun {
+		return writePlan(cxt.Client.PlanDeleteClusters(cxt.Account, names))
+	}
+
+	if !cxt.AssumeYes {
+		if !console.IsInteractive() {
+			return fmt.Errorf("Deleting %d clusters (%s) requires confirmation. Use --yes to skip the confirmation", len(names), strings.Join(names, ", "))
+		}
+		if !console.Confirm(fmt.Sprintf("Are you sure you want to delete %d clusters (%s)?", len(names), strings.Join(names, ", "))) {
+			return errors.New("Canceled delete")
+		}
+	}
+
+	results, err := deleteClusters()
+	if err != nil {
+		return err
+	}
+
+	successMessage := "Deleting"
+	if wait {
+		successMessage = "Deleted"
+	}
+	console.WriteClusterOperationResults(results, successMessage)
+	return checkOperationResults(results, "delete", "clusters")
+}
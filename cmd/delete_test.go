@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runDeleteCommand deletes a new cluster named mycluster, skipping authentication and confirmation, and returns the output
+func runDeleteCommand(t *testing.T) string {
+	home, err := ioutil.TempDir("", "carina-delete")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	os.Setenv(client.CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(client.CarinaHomeDirEnvVar)
+
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on CoreOS"})
+	service.CreateCluster("mycluster", "Swarm*", 1)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	cxt = &context{Client: client.NewClient(false), Account: account}
+	defer func() { cxt = nil }()
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	cmd := newDeleteCommand()
+	require.NoError(t, cmd.ParseFlags([]string{"mycluster", "--force"}))
+	args := cmd.Flags().Args()
+	err = cmd.PreRunE(cmd, args)
+	if err == nil {
+		err = cmd.RunE(cmd, args)
+	}
+	w.Close()
+	require.NoError(t, err)
+
+	output, _ := ioutil.ReadAll(r)
+	return string(output)
+}
+
+func TestDeleteCommandOutput(t *testing.T) {
+	assert.Equal(t, "Deleting cluster (mycluster)\n", runDeleteCommand(t))
+
+	console.Quiet = true
+	assert.Empty(t, runDeleteCommand(t), "Nothing should be printed with --quiet")
+	console.Quiet = false
+
+	console.Format = console.FormatJSON
+	assert.Empty(t, runDeleteCommand(t), "Only JSON should be printed with --format json")
+	console.Format = console.FormatTable
+}
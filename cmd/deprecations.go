@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+// nextMajorVersion is the release in which the deprecated commands and flags are removed
+const nextMajorVersion = "3.0.0"
+
+// deprecations are the commands and flags which will be removed. Add an entry here, instead of removing a command or flag,
+// so that scripts which use it are warned in advance, and it is listed by carina deprecations.
+var deprecations = []common.Deprecation{
+	{Command: "carina grow", Sunset: nextMajorVersion, Replacement: "carina resize <cluster-name> --nodes <count>"},
+}
+
+// allDeprecations returns the deprecated commands and flags, including the legacy flag spellings which are still translated
+func allDeprecations() []common.Deprecation {
+	all := append([]common.Deprecation{}, deprecations...)
+	for _, flag := range legacyFlags {
+		if flag.removed == "" {
+			all = append(all, flag.deprecation())
+		}
+	}
+	return all
+}
+
+// checkDeprecations warns when the command, or one of the flags specified, is deprecated.
+// When --strict is specified, using a deprecated command or flag is an error.
+func checkDeprecations(cmd *cobra.Command) error {
+	for _, deprecation := range deprecations {
+		if deprecation.Command != cmd.CommandPath() {
+			continue
+		}
+		if deprecation.Flag != "" {
+			flag := cmd.Flags().Lookup(deprecation.Flag[2:])
+			if flag == nil || !flag.Changed {
+				continue
+			}
+		}
+
+		if cxt.Strict {
+			return fmt.Errorf("%s. Deprecated commands and flags are not allowed when --strict is specified", deprecation)
+		}
+		common.Log.WriteDeprecation(deprecation)
+	}
+	return nil
+}
+
+func newDeprecationsCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "deprecations",
+		Short: "List the deprecated commands and flags, and when they will be removed",
+		Long: `List the deprecated commands and flags, the version in which they will be removed, and what to use instead.
+
+A warning is logged whenever a deprecated command or flag is used. Use --log-format json to find them in scripts, each warning includes the deprecated, sunset and replacement fields. Use --strict to fail instead.`,
+		PersistentPreRunE: unauthenticatedPreRunE,
+		Run: func(cmd *cobra.Command, args []string) {
+			console.WriteDeprecations(allDeprecations())
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newDiffCommand() *cobra.Command {
+	var options struct {
+		filename        string
+		prune           bool
+		allowDeprecated bool
+		exitCode        bool
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "diff -f <manifest>",
+		Short: "Show how the clusters differ from a manifest",
+		Long: `Compare a YAML or JSON manifest against the clusters and print the changes carina apply would make, without making them.
+
+Clusters to create are prefixed with +, changes to the node count or labels of existing clusters with ~ and clusters to delete with -.
+Differences which apply cannot fix, such as the template of an existing cluster, are prefixed with !.
+Clusters which are not in the manifest are only shown as deleted with --prune, the same as carina apply.
+
+Use --exit-code to fail when there are changes, e.g. to check that a manifest under review matches the clusters.`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if options.filename == "" {
+				return errors.New("A manifest is required, e.g. carina diff -f clusters.yaml")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifest, err := client.ReadClusterManifest(options.filename)
+			if err != nil {
+				return err
+			}
+
+			applyOpts := client.ApplyOptions{
+				Prune: options.prune,
+				// Deprecated templates are only blocked in strict mode
+				AllowDeprecated: options.allowDeprecated || !cxt.Strict,
+			}
+			plan, err := cxt.Client.PlanApply(cxt.Account, manifest, applyOpts)
+			if err != nil {
+				return err
+			}
+
+			console.WriteDiff(plan)
+			if options.exitCode && plan.HasChanges() {
+				return fmt.Errorf("The clusters differ from %s by %d changes", options.filename, len(plan.Changes))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.filename, "filename", "f", "", "The YAML or JSON manifest describing the desired clusters")
+	cmd.Flags().BoolVar(&options.prune, "prune", false, "Show clusters which are not in the manifest as deleted")
+	cmd.Flags().BoolVar(&options.allowDeprecated, "allow-deprecated", false, "Allow a deprecated template to be used when --strict is specified")
+	cmd.Flags().BoolVar(&options.exitCode, "exit-code", false, "Exit with an error when the clusters differ from the manifest")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
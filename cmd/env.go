@@ -5,64 +5,135 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-
 	"runtime"
+	"strings"
 
+	"github.com/getcarina/carina/client"
 	"github.com/getcarina/carina/common"
 	"github.com/spf13/cobra"
 )
 
+// shellPreference is the cache key for the shell last used to connect to a cluster
+const shellPreference = "shell"
+
 func newEnvCommand() *cobra.Command {
 	var options struct {
 		name  string
 		shell string
 		path  string
+		unset bool
+
+		// rememberShell is set when the shell was specified, and is saved for the cluster once the command succeeds
+		rememberShell bool
 	}
 
 	var cmd = &cobra.Command{
-		Use:               "env <cluster-name>",
-		Short:             "Show the command to connect docker/kubectl to a cluster",
-		Long:              "Show the command to connect docker/kubectl to a cluster by setting environment variables in the current shell session",
-		PersistentPreRunE: authenticatedPreRunE,
+		Use:   "env <cluster-name>",
+		Short: "Show the command to connect docker/kubectl to a cluster",
+		Long:  "Show the command to connect docker/kubectl to a cluster by setting environment variables in the current shell session. The shell specified with --shell is remembered for the cluster, see carina config cluster. Use --unset to show the command to disconnect.\n\nOn machines without the account's credentials, set CARINA_CREDENTIALS_URL to the URL of an archive created by carina credentials export, such as a presigned URL, and CARINA_CREDENTIALS_SHA256 to its checksum. {cluster} in the URL is replaced with the cluster name. With {cluster}, set CARINA_CREDENTIALS_SHA256 to the path of a checksum file with a line for each cluster's archive instead, as written by sha256sum *.zip. The credentials are fetched when they are not already stored locally.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Clearing the environment variables, or using credentials from CARINA_CREDENTIALS_URL, doesn't require account credentials
+			if options.unset || os.Getenv(client.CredentialsURLEnvVar) != "" {
+				return unauthenticatedPreRunE(cmd, args)
+			}
+			return authenticatedPreRunE(cmd, args)
+		},
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			if options.shell == "" {
-				shell := os.Getenv("SHELL")
-				if shell != "" {
-					options.shell = filepath.Base(shell)
-					common.Log.WriteDebug("Shell: SHELL (%s)", options.shell)
-				} else {
-					options.shell = detectShell()
-					if options.shell != "" {
-						common.Log.WriteDebug("Shell: detected (%s)", options.shell)
-					} else {
-						return errors.New("Shell was not specified. Either use --shell or set SHELL")
-					}
+			if !options.unset {
+				err := bindClusterNameArg(args, &options.name)
+				if err != nil {
+					return err
 				}
-			} else {
+			}
+
+			// shell = --shell -> shell setting remembered for the cluster, see applyClusterSettings -> profile -> parent process -> SHELL -> detected
+			if options.shell != "" {
+				options.shell = strings.ToLower(strings.TrimSpace(options.shell))
 				common.Log.WriteDebug("Shell: --shell (%s)", options.shell)
+				err := client.ValidateShell(options.shell)
+				if err != nil {
+					return err
+				}
+				options.rememberShell = options.name != "" && cxt.Account != nil
+				return nil
 			}
 
-			return bindClusterNameArg(args, &options.name)
+			var err error
+			options.shell, err = resolveShell()
+			return err
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			sourceText, err := cxt.Client.GetSourceCommand(cxt.Account, options.shell, options.name, options.path)
+			if options.unset {
+				unsetText, err := client.GetUnsetCommand(options.shell)
+				if err != nil {
+					return err
+				}
+
+				fmt.Println(unsetText)
+				return nil
+			}
+
+			var sourceText string
+			var err error
+			if credentialsURL := os.Getenv(client.CredentialsURLEnvVar); credentialsURL != "" {
+				sourceText, err = cxt.Client.GetRemoteSourceCommand(options.shell, options.name, credentialsURL, os.Getenv(client.CredentialsChecksumEnvVar), options.path)
+			} else {
+				sourceText, err = cxt.Client.GetSourceCommand(cxt.Account, options.shell, options.name, options.path)
+			}
 			if err != nil {
 				return err
 			}
 
 			fmt.Println(sourceText)
+
+			// Only remember a shell which worked, so that a typo isn't applied to every later carina env for the cluster
+			if options.rememberShell {
+				err = cxt.Client.Cache.SaveClusterPreference(cxt.Account, options.name, shellPreference, options.shell)
+				if err != nil {
+					common.Log.WriteDebug("Unable to remember the shell for the cluster: %s", err)
+				}
+			}
 			return nil
 		},
 	}
 
-	cmd.ValidArgs = []string{"cluster-name"}
-	cmd.Flags().StringVar(&options.shell, "shell", "", "The parent shell type. Allowed values: bash, fish, powershell, cmd [SHELL]")
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.shell, "shell", "", "The parent shell type. Allowed values: bash, zsh, fish, powershell, cmd [SHELL]")
+	cmd.Flags().BoolVar(&options.unset, "unset", false, "Show the command to clear the docker/kubectl environment variables instead")
 	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory from which the credentials should be loaded")
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
 }
 
+// resolveShell finds the shell when --shell isn't specified: profile -> parent process -> SHELL -> detected
+func resolveShell() (string, error) {
+	if cxt.Shell != "" {
+		common.Log.WriteDebug("Shell: profile (%s)", cxt.Shell)
+		return cxt.Shell, nil
+	}
+
+	// On Windows, PowerShell and cmd can inherit SHELL from Git Bash, so the parent process is checked first
+	shell := client.DetectShell()
+	if shell != "" {
+		common.Log.WriteDebug("Shell: parent process (%s)", shell)
+		return shell, nil
+	}
+
+	if shell = os.Getenv("SHELL"); shell != "" {
+		shell = filepath.Base(shell)
+		common.Log.WriteDebug("Shell: SHELL (%s)", shell)
+		return shell, nil
+	}
+
+	shell = detectShell()
+	if shell == "" {
+		return "", errors.New("Shell was not specified. Either use --shell or set SHELL")
+	}
+	common.Log.WriteDebug("Shell: detected (%s)", shell)
+	return shell, nil
+}
+
 func detectShell() string {
 	if runtime.GOOS != "windows" {
 		return ""
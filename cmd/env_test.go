@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runEnvCommand runs carina env against an account, skipping authentication
+func runEnvCommand(t *testing.T, account client.Account, args ...string) error {
+	stdout := os.Stdout
+	devnull, err := os.Open(os.DevNull)
+	require.NoError(t, err)
+	defer devnull.Close()
+	os.Stdout = devnull
+	defer func() { os.Stdout = stdout }()
+
+	cmd := newEnvCommand()
+	require.NoError(t, cmd.ParseFlags(args))
+	args = cmd.Flags().Args()
+	err = cmd.PreRunE(cmd, args)
+	if err == nil {
+		err = cmd.RunE(cmd, args)
+	}
+	return err
+}
+
+func TestEnvRemembersValidShell(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-env")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+	os.Setenv(client.CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(client.CarinaHomeDirEnvVar)
+
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on CoreOS"})
+	service.CreateCluster("prod", "Swarm*", 1)
+	mockAccount := new(testhelpers.MockAccount)
+	mockAccount.On("NewClusterService").Return(service)
+	// The mock account has nothing to cache, but the cluster preferences are still saved
+	account := client.NewUncachedAccount(mockAccount)
+
+	cxt = &context{Client: client.NewClient(true), Account: account}
+	defer func() { cxt = nil }()
+
+	_, err = cxt.Client.DownloadClusterCredentials(account, "prod", "")
+	require.NoError(t, err)
+
+	err = runEnvCommand(t, account, "prod", "--shell", "bsh")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Invalid shell specified: bsh")
+	}
+	_, ok := cxt.Client.Cache.GetClusterPreferences(account, "prod")[shellPreference]
+	assert.False(t, ok, "An invalid shell shouldn't be remembered")
+
+	err = runEnvCommand(t, account, "staging", "--shell", "bash")
+	assert.Error(t, err, "The staging cluster doesn't exist")
+	_, ok = cxt.Client.Cache.GetClusterPreferences(account, "staging")[shellPreference]
+	assert.False(t, ok, "The shell shouldn't be remembered when carina env fails")
+
+	err = runEnvCommand(t, account, "prod", "--shell", "Fish")
+	require.NoError(t, err)
+	assert.Equal(t, "fish", cxt.Client.Cache.GetClusterPreferences(account, "prod")[shellPreference], "The shell should be remembered once carina env succeeds")
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+
+	"github.com/getcarina/carina/common"
+	"github.com/spf13/cobra"
+)
+
+func newExecCommand() *cobra.Command {
+	var options struct {
+		name    string
+		path    string
+		noStore bool
+		command []string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "exec <cluster-name> -- <command> [args...]",
+		Short: "Run a command, such as docker or kubectl, connected to a cluster",
+		Long: `Run a command with the environment variables from the cluster's credentials, e.g. DOCKER_HOST, DOCKER_CERT_PATH and KUBECONFIG, without changing the current shell session.
+The credentials are downloaded when necessary, and docker/kubectl variables already set in the shell are replaced. carina exits with the exit status of the command.
+
+Use --no-store on shared or ephemeral machines to download the credentials to a temporary directory instead of CARINA_HOME, which is wiped when the command exits.`,
+		Example: `  carina exec mycluster -- docker ps
+  carina exec mycluster -- kubectl get nodes
+  carina exec mycluster --no-store -- kubectl apply -f app.yaml`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 0 || dash == len(args) {
+				return errors.New("A command is required after --, e.g. carina exec mycluster -- docker ps")
+			}
+			if dash > 1 {
+				return errors.New("Too many arguments before --, expected only the cluster name")
+			}
+			options.command = args[dash:]
+			if options.noStore && options.path != "" {
+				return errors.New("--no-store cannot be used with --path")
+			}
+
+			return bindClusterNameArg(args[:dash], &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var env []string
+			var err error
+			if options.noStore {
+				var wipe func()
+				env, wipe, err = cxt.Client.BuildEphemeralClusterEnvironment(cxt.Account, options.name, os.Environ())
+				if err != nil {
+					return err
+				}
+				defer wipe()
+			} else {
+				env, err = cxt.Client.BuildClusterEnvironment(cxt.Account, options.name, options.path, os.Environ())
+				if err != nil {
+					return err
+				}
+			}
+
+			common.Log.WriteDebug("Running %v", options.command)
+			child := exec.Command(options.command[0], options.command[1:]...)
+			child.Env = env
+			child.Stdin = os.Stdin
+			child.Stdout = os.Stdout
+			child.Stderr = os.Stderr
+			err = child.Run()
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				status := exitCodeError
+				if exitStatus, ok := exitErr.Sys().(interface {
+					ExitStatus() int
+				}); ok && exitStatus.ExitStatus() > 0 {
+					// The status is -1 when the command was killed by a signal
+					status = exitStatus.ExitStatus()
+				}
+				return exitStatusError{status: status}
+			}
+			return err
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory from which the credentials should be loaded")
+	cmd.Flags().BoolVar(&options.noStore, "no-store", false, "Download the credentials to a temporary directory, which is wiped when the command exits, instead of saving them in CARINA_HOME")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/getcarina/carina/common"
+)
+
+// Exit codes returned by carina, so that scripts can react to a failure without parsing the error message.
+// These are part of the cli's contract, and are listed in the help text; don't change existing values.
+const (
+	exitCodeError    = 1
+	exitCodeAuth     = 2
+	exitCodeNotFound = 3
+	exitCodeQuota    = 4
+	exitCodeTimeout  = 5
+	exitCodeAPI      = 6
+
+	// exitCodeInterrupted is the conventional exit code for a process terminated by SIGINT
+	exitCodeInterrupted = 130
+)
+
+const exitCodesHelp = `Exit Codes:
+  0    success
+  1    error which doesn't fall into one of the categories below, such as invalid flags
+  2    the credentials were rejected, or the account is not allowed to perform the operation
+  3    the cluster, template or other resource was not found
+  4    the operation would exceed the account's quotas
+  5    timed out waiting for the operation to complete, see --wait-timeout
+  6    the API failed the request for another reason, such as an outage
+  130  interrupted`
+
+// exitStatusError passes through the exit status of a command run by carina, e.g. carina exec, instead of an exit code above
+type exitStatusError struct {
+	status int
+}
+
+func (err exitStatusError) Error() string {
+	return fmt.Sprintf("The command exited with status %d", err.status)
+}
+
+// exitCode returns the exit code for an error, based on the category of the error or one of its causes
+func exitCode(err error) int {
+	if status, ok := err.(exitStatusError); ok {
+		return status.status
+	}
+
+	for err != nil {
+		switch err.(type) {
+		case common.AuthError:
+			return exitCodeAuth
+		case common.NotFoundError:
+			return exitCodeNotFound
+		case common.QuotaError:
+			return exitCodeQuota
+		case common.TimeoutError:
+			return exitCodeTimeout
+		case common.APIError:
+			return exitCodeAPI
+		}
+
+		cause, ok := err.(interface {
+			Cause() error
+		})
+		if !ok || cause.Cause() == err {
+			break
+		}
+		err = cause.Cause()
+	}
+	return exitCodeError
+}
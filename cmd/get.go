@@ -1,39 +1,99 @@
 package cmd
 
 import (
+	"time"
+
+	"github.com/getcarina/carina/common"
 	"github.com/getcarina/carina/console"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func newGetCommand() *cobra.Command {
 	var options struct {
-		name string
-		wait bool
+		name       string
+		names      []string
+		wait       bool
+		readyCheck string
+		watch      bool
+		interval   time.Duration
 	}
 
 	var cmd = &cobra.Command{
-		Use:               "get <cluster-name>",
-		Short:             "Show information about a cluster",
-		Long:              "Show information about a cluster",
+		Use:   "get <cluster-name>...",
+		Short: "Show information about a cluster",
+		Long:  "Show information about a cluster. When more than one cluster name, or a pattern such as 'prod-*', is specified, the matching clusters are shown in a table like carina clusters. Use --match-mode regex to use a regular expression for the pattern.",
+		Example: `  carina get mycluster
+  carina get 'prod-*'
+  carina get web api worker`,
 		PersistentPreRunE: authenticatedPreRunE,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 || (len(args) == 1 && common.NameMatchPolicy.IsPattern(args[0])) {
+				options.names = args
+				return nil
+			}
 			return bindClusterNameArg(args, &options.name)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cluster, err := cxt.Client.GetCluster(cxt.Account, options.name, options.wait)
-			if err != nil {
-				return err
+			if len(options.names) > 0 {
+				return runWatched(options.watch, options.interval, func() error {
+					return getClusters(options.names, options.wait, options.readyCheck)
+				})
 			}
 
-			console.WriteCluster(cluster)
+			return runWatched(options.watch, options.interval, func() error {
+				cluster, err := cxt.Client.GetCluster(cxt.Account, options.name, options.wait)
+				if err != nil {
+					return err
+				}
+
+				err = waitUntilReady(cluster, options.wait, options.readyCheck)
+				if err != nil {
+					return err
+				}
 
-			return nil
+				console.WriteCluster(cluster)
+
+				return nil
+			})
 		},
 	}
 
-	cmd.ValidArgs = []string{"cluster-name"}
-	cmd.Flags().BoolVar(&options.wait, "wait", false, "Wait for the cluster to become active")
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().BoolVar(&options.wait, "wait", false, "Wait for the clusters to become active")
+	addReadyCheckFlag(cmd, &options.readyCheck)
+	addWatchFlags(cmd, &options.watch, &options.interval)
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
 }
+
+// getClusters prints the clusters matching the names and patterns in one table, waiting for each of them to become active when requested
+func getClusters(tokens []string, wait bool, readyCheck string) error {
+	err := console.SetColumns(splitConfigList(viper.GetString(configKey("list-columns"))))
+	if err != nil {
+		return err
+	}
+	console.SetClusterCreated(clusterCreated)
+
+	clusters, err := cxt.Client.GetClusters(cxt.Account, tokens)
+	if err != nil {
+		return err
+	}
+
+	if wait {
+		for i, cluster := range clusters {
+			clusters[i], err = cxt.Client.GetCluster(cxt.Account, cluster.GetID(), true)
+			if err != nil {
+				return err
+			}
+			err = waitUntilReady(clusters[i], true, readyCheck)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	console.WriteClusters(clusters)
+	return nil
+}
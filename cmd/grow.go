@@ -3,22 +3,24 @@ package cmd
 import (
 	"errors"
 
+	"github.com/getcarina/carina/common"
 	"github.com/getcarina/carina/console"
 	"github.com/spf13/cobra"
 )
 
 func newGrowCommand() *cobra.Command {
 	var options struct {
-		name     string
-		template string
-		nodes    int
-		wait     bool
+		name       string
+		template   string
+		nodes      int
+		wait       bool
+		readyCheck string
 	}
 
 	var cmd = &cobra.Command{
 		Use:               "grow <cluster-name>",
 		Short:             "Add nodes to a cluster",
-		Long:              "Add nodes to a cluster",
+		Long:              "Add nodes to a cluster. Deprecated, use carina resize instead",
 		Hidden:            true,
 		PersistentPreRunE: authenticatedPreRunE,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -29,20 +31,39 @@ func newGrowCommand() *cobra.Command {
 			return bindClusterNameArg(args, &options.name)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if cxt.DryRun {
+				return writePlan(cxt.Client.PlanGrowCluster(cxt.Account, options.name, options.nodes))
+			}
+
+			err := confirmOperation("grow", options.name, func(cluster common.Cluster) int {
+				current, _ := common.ParseNodeCount(cluster.GetNodes())
+				return current + options.nodes
+			})
+			if err != nil {
+				return err
+			}
+
 			cluster, err := cxt.Client.GrowCluster(cxt.Account, options.name, options.nodes, options.wait)
 			if err != nil {
 				return err
 			}
 
+			err = waitUntilReady(cluster, options.wait, options.readyCheck)
+			if err != nil {
+				return err
+			}
+
 			console.WriteCluster(cluster)
 
 			return nil
 		},
 	}
 
-	cmd.ValidArgs = []string{"cluster-name"}
+	cmd.ValidArgsFunction = completeClusterNames
 	cmd.Flags().IntVar(&options.nodes, "nodes", 1, "Number of nodes to add to the cluster")
-	cmd.Flags().BoolVar(&options.wait, "wait", false, "Wait for the cluster to become active")
+	addWaitFlags(cmd, &options.wait, "Wait for the cluster to become active")
+	addReadyCheckFlag(cmd, &options.readyCheck)
+	addDryRunFlag(cmd, "Validate the cluster and print what would change, without growing it")
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
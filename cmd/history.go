@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newHistoryCommand() *cobra.Command {
+	var options struct {
+		name string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "history <cluster-name>",
+		Short: "Show the status history of a cluster",
+		Long: `Show a timeline of the status changes of a cluster.
+
+The history is recorded locally whenever this client creates, changes, lists or waits on a cluster,
+and is kept after the cluster is deleted.`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			events, err := cxt.Client.GetClusterHistory(cxt.Account, options.name)
+			if err != nil {
+				return err
+			}
+
+			console.WriteClusterHistory(events)
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/console"
+	"github.com/getcarina/carina/make-coe"
+	"github.com/spf13/cobra"
+)
+
+func newIdentityEndpointsCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:               "identity-endpoints",
+		Short:             "List the well-known Rackspace Identity endpoints",
+		Long:              "List the well-known Rackspace Identity endpoints. Use an alias, or any identity URL, with --identity-endpoint or the identity-endpoint profile setting",
+		Example:           "  carina --identity-endpoint uk ls",
+		PersistentPreRunE: unauthenticatedPreRunE,
+		Run: func(cmd *cobra.Command, args []string) {
+			rows := [][]string{{"Alias", "URL"}}
+			for _, alias := range makecoe.IdentityEndpointAliases() {
+				if alias == makecoe.DefaultIdentityEndpoint {
+					rows = append(rows, []string{alias + " (default)", makecoe.IdentityEndpoints[alias]})
+				} else {
+					rows = append(rows, []string{alias, makecoe.IdentityEndpoints[alias]})
+				}
+			}
+			console.WriteTable(rows)
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
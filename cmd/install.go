@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newInstallCommand() *cobra.Command {
+	var options struct {
+		name string
+		client.InstallOptions
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "install <docker|kubectl> [cluster-name]",
+		Short: "Download the docker or kubectl client which matches a cluster",
+		Long: `Download the docker or kubectl client which matches the version of a cluster, or the version specified with --version.
+The client is saved to CARINA_HOME/bin, unless --dir is specified. Add the directory to your PATH to use the client.`,
+		Example: `  carina install kubectl mycluster
+  carina install docker --version 1.11.2 --dir /usr/local/bin`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Installing a specific version doesn't require account credentials
+			if len(args) < 2 {
+				return unauthenticatedPreRunE(cmd, args)
+			}
+			return authenticatedPreRunE(cmd, args)
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return fmt.Errorf("The client to install is required, either %s or %s", client.DockerTool, client.KubectlTool)
+			}
+			if len(args) > 2 {
+				return errors.New("Too many arguments, expected the client and an optional cluster name")
+			}
+			options.Tool = args[0]
+
+			if len(args) == 2 {
+				if options.Version != "" {
+					return errors.New("Specify either a cluster name or --version, not both")
+				}
+				return bindClusterNameArg(args[1:], &options.name)
+			}
+			if options.Version == "" {
+				return errors.New("A cluster name or --version is required")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.name != "" {
+				tool, version, err := cxt.Client.ClusterClientVersion(cxt.Account, options.name, "")
+				if err != nil {
+					return err
+				}
+				if tool != "" && tool != options.Tool {
+					return fmt.Errorf("%s is not used to connect to %s, install %s instead", options.Tool, options.name, tool)
+				}
+				if tool == "" || version == "" {
+					return fmt.Errorf("Unable to determine the version of %s, use --version to specify the version of %s", options.name, options.Tool)
+				}
+				options.Version = version
+			}
+
+			path, err := cxt.Client.InstallClientTool(options.InstallOptions)
+			if err != nil {
+				return err
+			}
+
+			console.WriteValue(path, fmt.Sprintf("Installed %s %s to %s", options.Tool, options.Version, path))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Version, "version", "", "The version of the client to install, defaults to the version of the cluster")
+	cmd.Flags().StringVar(&options.Dir, "dir", "", "The directory where the client is saved, defaults to CARINA_HOME/bin")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
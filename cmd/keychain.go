@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newKeychainCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "keychain",
+		Short: "Manage the API keys and passwords saved in the keychain",
+		Long:  "Manage the API keys and passwords saved in the operating system's keychain, which are used with --auth-source keychain",
+	}
+
+	cmd.AddCommand(newKeychainStoreCommand())
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newKeychainStoreCommand() *cobra.Command {
+	var options struct {
+		username string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "store <username>",
+		Short: "Save an API key or password to the keychain",
+		Long:  "Save an API key or password to the keychain, so that it is not stored in environment variables or the shell history. The secret is prompted for, or read from stdin. Use it with --auth-source keychain, or auth-source = \"keychain\" in a profile.",
+		Example: `  carina keychain store alice
+  carina keychain store --cloud private bob`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A username is required")
+			}
+			options.username = args[0]
+
+			if cxt.CloudType == "" {
+				cxt.CloudType = defaultCloudProvider
+			}
+			provider, err := client.LookupCloudProvider(cxt.CloudType)
+			if err != nil {
+				return err
+			}
+			if provider.Secret == "" {
+				return fmt.Errorf("The %s cloud doesn't use an API key or password", provider.Name)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, err := client.LookupCloudProvider(cxt.CloudType)
+			if err != nil {
+				return err
+			}
+			prompt := string(provider.Secret)
+
+			secret, err := console.ReadSecret(fmt.Sprintf("%s for %s", prompt, options.username))
+			if err != nil {
+				return err
+			}
+			if secret == "" {
+				return fmt.Errorf("The %s is empty", prompt)
+			}
+
+			err = client.NewKeychainSecretsProvider().SetSecret(cxt.CloudType, options.username, secret)
+			if err != nil {
+				return err
+			}
+
+			console.Write("Saved the %s for %s to the keychain. Use it with --auth-source keychain --username %s", prompt, options.username, options.username)
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newKeypairsCommand() *cobra.Command {
+	var names bool
+
+	var cmd = &cobra.Command{
+		Use:               "keypairs",
+		Short:             "List the SSH keypairs registered with the account",
+		Long:              "List the SSH keypairs registered with the account, which can be installed on the nodes of a new cluster with carina create --keypair",
+		PersistentPreRunE: authenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keypairs, err := cxt.Client.ListKeypairs(cxt.Account)
+			if err != nil {
+				return err
+			}
+
+			if names {
+				console.WriteKeypairNames(keypairs)
+				return nil
+			}
+			console.WriteKeypairs(keypairs)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&names, "names", false, "Only print the keypair names, one per line")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
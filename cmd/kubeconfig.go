@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newKubeconfigCommand() *cobra.Command {
+	var options struct {
+		name            string
+		credentialsPath string
+		client.KubeconfigOptions
+	}
+
+	var cmd = &cobra.Command{
+		Use:               "kubeconfig <cluster-name>",
+		Short:             "Add a Kubernetes cluster to your kubeconfig",
+		Long:              "Add a context for a Kubernetes cluster to your kubeconfig, using the cluster's credentials bundle",
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeconfigPath, contextName, err := cxt.Client.WriteKubeconfig(cxt.Account, options.name, options.credentialsPath, options.KubeconfigOptions)
+			if err != nil {
+				return err
+			}
+
+			message := []string{"#", fmt.Sprintf("# Added the %s context to \"%s\"", contextName, kubeconfigPath)}
+			if !options.SetCurrent {
+				message = append(message, fmt.Sprintf("# To switch to the cluster, run: kubectl config use-context %s", contextName))
+			}
+			console.WriteValue(kubeconfigPath, append(message, "#")...)
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.Path, "path", "", "Full path to the kubeconfig file [KUBECONFIG or ~/.kube/config]")
+	cmd.Flags().BoolVar(&options.Merge, "merge", false, "Merge the cluster into an existing kubeconfig file")
+	cmd.Flags().BoolVar(&options.SetCurrent, "set-current", false, "Switch the current context to the cluster")
+	cmd.Flags().StringVar(&options.credentialsPath, "credentials-path", "", "Full path to the directory from which the credentials should be loaded")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newLabelCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "label",
+		Short: "Manage cluster labels",
+		Long:  "Manage the key/value labels used to organize clusters, e.g. by team or environment. Labels are local metadata: they are stored in the cache in CARINA_HOME, are not sent to the cloud, and are not shared with other machines. They are unavailable when the cache is disabled.",
+	}
+
+	cmd.AddCommand(
+		newLabelAddCommand(),
+		newLabelRemoveCommand(),
+	)
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newLabelAddCommand() *cobra.Command {
+	var options struct {
+		name   string
+		labels map[string]string
+	}
+
+	var cmd = &cobra.Command{
+		Use:               "add <cluster-name> <key=value>...",
+		Short:             "Add labels to a cluster",
+		Long:              "Add labels to a cluster, replacing the value of existing labels. Labels are local metadata stored in the cache in CARINA_HOME, and are not sent to the cloud.",
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return errors.New("A cluster name and at least one key=value label is required")
+			}
+
+			var err error
+			options.labels, err = client.ParseLabels(args[1:])
+			if err != nil {
+				return err
+			}
+
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cluster, err := cxt.Client.AddClusterLabels(cxt.Account, options.name, options.labels)
+			if err != nil {
+				return err
+			}
+
+			console.WriteCluster(cluster)
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newLabelRemoveCommand() *cobra.Command {
+	var options struct {
+		name string
+		keys []string
+	}
+
+	var cmd = &cobra.Command{
+		Use:               "remove <cluster-name> <key>...",
+		Aliases:           []string{"rm"},
+		Short:             "Remove labels from a cluster",
+		Long:              "Remove labels from a cluster. Labels are local metadata stored in the cache in CARINA_HOME, and are not sent to the cloud.",
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return errors.New("A cluster name and at least one label key is required")
+			}
+			options.keys = args[1:]
+
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cluster, err := cxt.Client.RemoveClusterLabels(cxt.Account, options.name, options.keys)
+			if err != nil {
+				return err
+			}
+
+			console.WriteCluster(cluster)
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/spf13/cobra"
+)
+
+// legacyFlag is a flag spelling from the make-swarm era cli, and its current equivalent.
+// The legacy command names, such as list, ls and rm, are still supported as aliases and don't need to be translated.
+type legacyFlag struct {
+	// command is the command which accepted the flag, or empty for a global flag
+	command string
+	name    string
+
+	// replacement is the current flag, including the value for a legacy boolean flag, e.g. cache=false
+	replacement string
+
+	// removed explains how to migrate a flag which doesn't have an equivalent
+	removed string
+}
+
+var legacyFlags = []legacyFlag{
+	{name: "api-key", replacement: "apikey"},
+	{name: "no-cache", replacement: "cache=false"},
+	{command: "grow", name: "by", replacement: "nodes"},
+	{command: "create", name: "autoscale", removed: "Create the cluster, then enable autoscaling with carina autoscale <cluster-name> on"},
+}
+
+// translateLegacyArgs rewrites the legacy flag spellings in the arguments to their current equivalents,
+// so that scripts written for the original cli keep working. A deprecation is returned for each legacy flag,
+// and an error is returned for legacy flags which have been removed.
+func translateLegacyArgs(root *cobra.Command, args []string) ([]string, []common.Deprecation, error) {
+	var warnings []common.Deprecation
+
+	// Translate the global flags first, so that the command can be found without tripping over unknown flags
+	args, warnings, err := translateLegacyFlags(args, "", warnings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd, _, err := root.Find(args)
+	if err != nil || cmd == root {
+		return args, warnings, nil
+	}
+	return translateLegacyFlags(args, cmd.Name(), warnings)
+}
+
+func translateLegacyFlags(args []string, command string, warnings []common.Deprecation) ([]string, []common.Deprecation, error) {
+	translated := make([]string, 0, len(args))
+	for i, arg := range args {
+		// Leave everything after the -- terminator alone
+		if arg == "--" {
+			translated = append(translated, args[i:]...)
+			break
+		}
+
+		flag, ok := findLegacyFlag(arg, command)
+		if !ok {
+			translated = append(translated, arg)
+			continue
+		}
+
+		if flag.removed != "" {
+			return nil, nil, fmt.Errorf("--%s is no longer supported. %s", flag.name, flag.removed)
+		}
+
+		replacement, err := flag.translate(strings.TrimPrefix(arg, "--"+flag.name))
+		if err != nil {
+			return nil, nil, err
+		}
+		warnings = append(warnings, flag.deprecation())
+		translated = append(translated, replacement)
+	}
+	return translated, warnings, nil
+}
+
+// translate returns the current flag for the legacy flag with its value, if any, e.g. =2 for --by=2.
+// A legacy boolean flag with an explicit value, e.g. --no-cache=false, is translated to the opposite value of its replacement, --cache=true.
+func (flag legacyFlag) translate(value string) (string, error) {
+	parts := strings.SplitN(flag.replacement, "=", 2)
+	if len(parts) == 1 {
+		return "--" + flag.replacement + value, nil
+	}
+	if value == "" {
+		return "--" + flag.replacement, nil
+	}
+
+	legacyValue, err := strconv.ParseBool(strings.TrimPrefix(value, "="))
+	if err != nil {
+		return "", fmt.Errorf("Invalid value for --%s: %s, must be true or false", flag.name, strings.TrimPrefix(value, "="))
+	}
+	replacementValue, err := strconv.ParseBool(parts[1])
+	if err != nil {
+		return "", err
+	}
+	if !legacyValue {
+		replacementValue = !replacementValue
+	}
+	return fmt.Sprintf("--%s=%t", parts[0], replacementValue), nil
+}
+
+// deprecation describes the legacy flag, and the flag to use instead
+func (flag legacyFlag) deprecation() common.Deprecation {
+	command := "carina"
+	if flag.command != "" {
+		command += " " + flag.command
+	}
+	return common.Deprecation{
+		Command:     command,
+		Flag:        "--" + flag.name,
+		Sunset:      nextMajorVersion,
+		Replacement: "--" + flag.replacement,
+	}
+}
+
+// findLegacyFlag returns the legacy flag matching an argument, e.g. --by or --by=2
+func findLegacyFlag(arg string, command string) (legacyFlag, bool) {
+	if !strings.HasPrefix(arg, "--") {
+		return legacyFlag{}, false
+	}
+
+	name := strings.SplitN(strings.TrimPrefix(arg, "--"), "=", 2)[0]
+	for _, flag := range legacyFlags {
+		if flag.command == command && flag.name == name {
+			return flag, true
+		}
+	}
+	return legacyFlag{}, false
+}
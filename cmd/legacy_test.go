@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslateLegacyArgs(t *testing.T) {
+	testcases := []struct {
+		name       string
+		args       []string
+		translated []string
+		warnings   []string
+		error      string
+	}{
+		{
+			name:       "current flags are unchanged",
+			args:       []string{"grow", "mycluster", "--nodes", "2"},
+			translated: []string{"grow", "mycluster", "--nodes", "2"},
+		},
+		{
+			name:       "global flag",
+			args:       []string{"--api-key", "secret", "clusters"},
+			translated: []string{"--apikey", "secret", "clusters"},
+			warnings:   []string{"--api-key"},
+		},
+		{
+			name:       "global flag with an inline value",
+			args:       []string{"clusters", "--api-key=secret"},
+			translated: []string{"clusters", "--apikey=secret"},
+			warnings:   []string{"--api-key"},
+		},
+		{
+			name:       "global boolean flag",
+			args:       []string{"clusters", "--no-cache"},
+			translated: []string{"clusters", "--cache=false"},
+			warnings:   []string{"--no-cache"},
+		},
+		{
+			name:       "global boolean flag set to true",
+			args:       []string{"clusters", "--no-cache=true"},
+			translated: []string{"clusters", "--cache=false"},
+			warnings:   []string{"--no-cache"},
+		},
+		{
+			name:       "global boolean flag set to false",
+			args:       []string{"clusters", "--no-cache=false"},
+			translated: []string{"clusters", "--cache=true"},
+			warnings:   []string{"--no-cache"},
+		},
+		{
+			name:  "global boolean flag with an invalid value",
+			args:  []string{"clusters", "--no-cache=maybe"},
+			error: "Invalid value for --no-cache: maybe",
+		},
+		{
+			name:       "command flag with a separate value",
+			args:       []string{"grow", "mycluster", "--by", "2"},
+			translated: []string{"grow", "mycluster", "--nodes", "2"},
+			warnings:   []string{"--by"},
+		},
+		{
+			name:       "command flag with an inline value",
+			args:       []string{"grow", "mycluster", "--by=2"},
+			translated: []string{"grow", "mycluster", "--nodes=2"},
+			warnings:   []string{"--by"},
+		},
+		{
+			name:       "command flag on another command",
+			args:       []string{"resize", "mycluster", "--by", "2"},
+			translated: []string{"resize", "mycluster", "--by", "2"},
+		},
+		{
+			name:  "removed command flag",
+			args:  []string{"create", "mycluster", "--autoscale"},
+			error: "--autoscale is no longer supported",
+		},
+		{
+			name:       "removed command flag on another command",
+			args:       []string{"grow", "mycluster", "--autoscale"},
+			translated: []string{"grow", "mycluster", "--autoscale"},
+		},
+		{
+			name:       "arguments after the terminator",
+			args:       []string{"grow", "mycluster", "--by", "2", "--", "--by", "--no-cache"},
+			translated: []string{"grow", "mycluster", "--nodes", "2", "--", "--by", "--no-cache"},
+			warnings:   []string{"--by"},
+		},
+	}
+
+	root := newCarinaCommand()
+	defer func() { cxt = nil }()
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			translated, warnings, err := translateLegacyArgs(root, tc.args)
+			if tc.error != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.error)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.translated, translated)
+			var flags []string
+			for _, warning := range warnings {
+				flags = append(flags, warning.Flag)
+			}
+			assert.Equal(t, tc.warnings, flags)
+		})
+	}
+}
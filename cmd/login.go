@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newLoginCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "login",
+		Short: "Validate the account's credentials and cache the auth token",
+		Long: `Authenticate with the account selected with --profile, the auth flags or environment variables, ignoring any cached token, and cache the new auth token and API endpoint for the commands which follow.
+
+Nothing is prompted for, so in CI run carina login first: when the credentials are rejected, it fails with exit code 2 before any other command runs. Credentials specified with flags are cached, as if --save was specified.`,
+		Example: `  carina login --profile prod
+  CARINA_USERNAME=bob CARINA_APIKEY=abc123 carina login`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Logging in is pointless unless the token is cached
+			cxt.SaveAccount = true
+			return authenticatedPreRunE(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			endpoint, err := cxt.Client.Login(cxt.Account)
+			if err != nil {
+				return err
+			}
+
+			if !cxt.CacheEnabled {
+				common.Log.WriteWarning("The credentials are valid, but the auth token was not cached because the cache is disabled")
+			}
+			console.Write("Logged in as %s (%s)", cxt.Account.GetID(), endpoint)
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newLogoutCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:               "logout",
+		Short:             "Remove the cached auth token for an account",
+		Long:              "Remove the cached auth token and API endpoint for the account selected with --profile, the auth flags or environment variables, so that the next command authenticates again. Unlike carina cache invalidate, the cached endpoint is removed too. Downloaded cluster credentials are kept.",
+		Example:           "  carina logout --profile prod",
+		PersistentPreRunE: authenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			found, err := cxt.Client.Logout(cxt.Account)
+			if err != nil {
+				return err
+			}
+
+			if found {
+				console.Write("Logged out %s", cxt.Account.GetID())
+			} else {
+				console.Write("%s was not logged in", cxt.Account.GetID())
+			}
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+// defaultMetricsInterval is how often carina metrics lists the clusters
+const defaultMetricsInterval = time.Minute
+
+func newMetricsCommand() *cobra.Command {
+	var options struct {
+		listen   string
+		once     bool
+		interval time.Duration
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "metrics",
+		Short: "Export the number and status of the account's clusters as Prometheus metrics",
+		Long: `Export the number of clusters and nodes by status, and the size of each cluster, as Prometheus metrics. The clusters are listed on an interval, and the latest metrics are served on /metrics for Prometheus to scrape. Use --once to print the metrics instead, e.g. for the node exporter's textfile collector.
+
+When the clusters can't be listed, carina_scrape_success is 0 and the cluster metrics are omitted.`,
+		Example: `  carina metrics --listen :9100
+  carina metrics --once > /var/lib/node_exporter/carina.prom`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if options.interval <= 0 {
+				return errors.New("--interval must be greater than 0")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.once {
+				metrics, err := cxt.Client.GetFleetMetrics(cxt.Account)
+				if err != nil {
+					return err
+				}
+				return metrics.WritePrometheus(os.Stdout)
+			}
+
+			exporter := &metricsExporter{}
+			go exporter.scrape(options.interval)
+
+			http.Handle("/metrics", exporter)
+			console.Write("Serving metrics on http://%s/metrics, listing the clusters every %s. Press Ctrl+C to stop", options.listen, options.interval)
+			return http.ListenAndServe(options.listen, nil)
+		},
+	}
+
+	cmd.Flags().StringVar(&options.listen, "listen", ":9100", "The address to serve the metrics on, e.g. :9100")
+	cmd.Flags().BoolVar(&options.once, "once", false, "Print the metrics once, instead of serving them")
+	cmd.Flags().DurationVar(&options.interval, "interval", defaultMetricsInterval, "How often to list the clusters, e.g. 5m")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+// metricsExporter serves the metrics from the most recent scrape
+type metricsExporter struct {
+	sync.Mutex
+	latest *client.FleetMetrics
+}
+
+// scrape lists the clusters on an interval until carina shuts down
+func (exporter *metricsExporter) scrape(interval time.Duration) {
+	for {
+		metrics, err := cxt.Client.GetFleetMetrics(cxt.Account)
+		if err != nil {
+			common.Log.WriteWarning("Unable to list the clusters, trying again in %s: %s", interval, err)
+		}
+
+		exporter.Lock()
+		exporter.latest = metrics
+		exporter.Unlock()
+
+		if common.Sleep(interval) != nil {
+			return
+		}
+	}
+}
+
+// ServeHTTP writes the latest metrics, or 503 before the first scrape has finished
+func (exporter *metricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	exporter.Lock()
+	metrics := exporter.latest
+	exporter.Unlock()
+
+	if metrics == nil {
+		http.Error(w, "The clusters haven't been listed yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	err := metrics.WritePrometheus(w)
+	if err != nil {
+		common.Log.WriteDebug("Unable to write the metrics: %s", err)
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateHomeCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:               "migrate-home",
+		Short:             "Import the tokens and credentials saved by older versions of the carina cli",
+		Long:              "Import the tokens and credentials saved in CARINA_HOME by the original carina cli into the current layout. The legacy files are copied to a backup directory in CARINA_HOME first. Use --dry-run to see what would be imported.",
+		Example:           "  carina migrate-home --dry-run\n  carina migrate-home",
+		PersistentPreRunE: unauthenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			migration, err := cxt.Client.DetectLegacyHome()
+			if err != nil {
+				return err
+			}
+
+			if migration.IsEmpty() {
+				console.Write("Nothing to migrate in %s", migration.Home)
+				return nil
+			}
+
+			if cxt.DryRun {
+				return writePlan(migration.OperationPlan(), nil)
+			}
+
+			err = cxt.Client.MigrateHome(migration)
+			if err != nil {
+				return err
+			}
+
+			for _, username := range migration.Tokens {
+				console.Write("Imported the cached token for %s", username)
+			}
+			for _, legacy := range migration.Credentials {
+				if !legacy.Exists {
+					console.Write("Moved the credentials for %s to %s", legacy.ClusterName, legacy.Destination)
+				}
+			}
+			console.Write("The legacy files were backed up to %s", migration.BackupPath)
+			return nil
+		},
+	}
+
+	addDryRunFlag(cmd, "Print what would be imported, without importing anything")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCommand() *cobra.Command {
+	var options struct {
+		name     string
+		to       string
+		template string
+		path     string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "migrate <name>",
+		Short: "Move a make-swarm cluster to make-coe",
+		Long: `Create a make-coe cluster equivalent to a make-swarm cluster, because make-swarm is deprecated. The new cluster has the same number of nodes, labels and autoscaling as the make-swarm cluster, and uses the Swarm template offered by make-coe unless --template is specified. Once the new cluster is active, its credentials are downloaded and a checklist of what was migrated is printed.
+
+The make-swarm cluster isn't modified, so that the workloads can be moved before it is deleted. The same credentials are used for both clouds.`,
+		Example: `  carina migrate mycluster
+  carina migrate mycluster --to mycluster-coe`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A cluster name is required")
+			}
+			options.name = args[0]
+
+			if cxt.CloudType != defaultCloudProvider && cxt.CloudType != "make-swarm" {
+				return fmt.Errorf("carina migrate moves make-swarm clusters to make-coe, which isn't available with --cloud %s", cxt.CloudType)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source, err := buildCloudAccount("make-swarm")
+			if err != nil {
+				return err
+			}
+			target, err := buildCloudAccount(defaultCloudProvider)
+			if err != nil {
+				return err
+			}
+
+			migration, err := cxt.Client.MigrateSwarmCluster(source, target, options.name, client.MigrateSwarmClusterOptions{
+				Name:            options.to,
+				Template:        options.template,
+				CredentialsPath: options.path,
+			})
+			if migration != nil && migration.Cluster != nil {
+				console.WriteCluster(migration.Cluster)
+			}
+			if err != nil {
+				return err
+			}
+
+			console.Write("")
+			for _, step := range migration.Steps {
+				check := "[ ]"
+				if step.Done {
+					check = "[x]"
+				}
+				console.Write("%s %s", check, step.Description)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.to, "to", "", "The name of the make-coe cluster, defaulting to the name of the make-swarm cluster")
+	cmd.Flags().StringVar(&options.template, "template", "", "The make-coe template, defaulting to the Swarm template")
+	cmd.Flags().StringVar(&options.path, "path", "", "Full path to the directory where the credentials should be saved")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+// buildCloudAccount builds an account for another cloud with the current credentials, reusing the current account when it's for the same cloud
+func buildCloudAccount(cloud string) (client.Account, error) {
+	if cxt.CloudType == cloud {
+		return cxt.Account, nil
+	}
+
+	provider, err := client.LookupCloudProvider(cloud)
+	if err != nil {
+		return nil, err
+	}
+	return provider.NewAccount(cxt.AccountSettings), nil
+}
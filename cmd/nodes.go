@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newNodesCommand() *cobra.Command {
+	var options struct {
+		name string
+	}
+
+	var cmd = &cobra.Command{
+		Use:               "nodes <cluster-name>",
+		Short:             "List the nodes in a cluster",
+		Long:              "List the nodes in a cluster, including their address, role and status. Only supported by clouds which report the individual nodes.",
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodes, err := cxt.Client.ListNodes(cxt.Account, options.name)
+			if err != nil {
+				return err
+			}
+
+			console.WriteNodes(nodes)
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runNodesCommand runs carina nodes against an account, skipping authentication, and returns its output
+func runNodesCommand(t *testing.T, account client.Account, args ...string) (string, error) {
+	cxt = &context{Client: client.NewClient(false), Account: account}
+	defer func() { cxt = nil }()
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	cmd := newNodesCommand()
+	err = cmd.PreRunE(cmd, args)
+	if err == nil {
+		err = cmd.RunE(cmd, args)
+	}
+
+	w.Close()
+	output, _ := ioutil.ReadAll(r)
+	return string(output), err
+}
+
+func TestNodesCommand(t *testing.T) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on CoreOS"})
+	service.CreateCluster("mycluster", "Swarm*", 2)
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	output, err := runNodesCommand(t, account, "mycluster")
+	require.NoError(t, err)
+	assert.Contains(t, output, "mycluster-node-0")
+	assert.Contains(t, output, "10.0.0.2")
+}
+
+// nodelessClusterService is a cloud which doesn't report the individual nodes in a cluster, like make-coe
+type nodelessClusterService struct {
+	common.ClusterService
+}
+
+func TestNodesCommandUnsupported(t *testing.T) {
+	account := new(testhelpers.MockAccount)
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Swarm 1.11.2 on CoreOS"})
+	service.CreateCluster("mycluster", "Swarm*", 2)
+	account.On("NewClusterService").Return(&nodelessClusterService{service})
+
+	_, err := runNodesCommand(t, account, "mycluster")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not supported by this cloud")
+	}
+}
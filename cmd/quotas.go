@@ -1,37 +1,60 @@
 package cmd
 
 import (
+	"fmt"
 	"strconv"
 
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
 	"github.com/getcarina/carina/console"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func newQuotasCommand() *cobra.Command {
+	var options struct {
+		threshold int
+		check     bool
+	}
+
 	var cmd = &cobra.Command{
-		Use:               "quotas",
-		Short:             "Show the user's quotas",
-		Long:              "Show the user's quotas",
+		Use:     "quotas",
+		Aliases: []string{"quota"},
+		Short:   "Show the user's quotas",
+		Long:    "Show the user's quotas, and how many clusters and nodes are in use for each container orchestration engine (COE). Quotas whose usage has reached the warning threshold are highlighted.",
+		Example: `  carina quotas --format json
+  carina quotas --check --threshold 90`,
 		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// threshold = --threshold -> config file -> default
+			if !cmd.Flags().Changed("threshold") && viper.IsSet("quota-threshold") {
+				options.threshold = viper.GetInt("quota-threshold")
+			}
+			return client.ValidateQuotaThreshold(strconv.Itoa(options.threshold))
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			quotas, err := cxt.Client.GetQuotas(cxt.Account)
 			if err != nil {
 				return err
 			}
 
-			maxClusters := strconv.Itoa(quotas.GetMaxClusters())
-			maxNodesPerCluster := strconv.Itoa(quotas.GetMaxNodesPerCluster())
-
-			data := []console.Tuple{
-				{Key: "Max Clusters", Value: maxClusters},
-				{Key: "Max Nodes per Cluster", Value: maxNodesPerCluster},
+			usage, err := cxt.Client.GetQuotaUsage(cxt.Account)
+			if err != nil {
+				return err
 			}
-			console.WriteMap(data)
 
+			warnings := client.CheckQuotas(quotas, usage, options.threshold)
+			console.WriteQuotas(quotas, usage, warnings)
+
+			if options.check && len(warnings) > 0 {
+				return common.QuotaError{Err: fmt.Errorf("%d of the account's quotas are at or above the %d%% warning threshold", len(warnings), options.threshold)}
+			}
 			return nil
 		},
 	}
 
+	cmd.Flags().IntVar(&options.threshold, "threshold", client.DefaultQuotaThreshold, "Percentage of a quota which can be used before it is highlighted. Defaults to the quota-threshold config setting")
+	cmd.Flags().BoolVar(&options.check, "check", false, "Exit with a non-zero exit code when a quota is at or above the threshold")
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
@@ -7,8 +7,9 @@ import (
 
 func newRebuildCommand() *cobra.Command {
 	var options struct {
-		name string
-		wait bool
+		name       string
+		wait       bool
+		readyCheck string
 	}
 
 	var cmd = &cobra.Command{
@@ -21,19 +22,36 @@ func newRebuildCommand() *cobra.Command {
 			return bindClusterNameArg(args, &options.name)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if cxt.DryRun {
+				return writePlan(cxt.Client.PlanRebuildCluster(cxt.Account, options.name))
+			}
+
+			err := confirmOperation("rebuild", options.name, nil)
+			if err != nil {
+				return err
+			}
+
 			cluster, err := cxt.Client.RebuildCluster(cxt.Account, options.name, options.wait)
 			if err != nil {
 				return err
 			}
 
+			err = waitUntilReady(cluster, options.wait, options.readyCheck)
+			if err != nil {
+				return err
+			}
+
 			console.WriteCluster(cluster)
 
 			return nil
 		},
 	}
 
-	cmd.ValidArgs = []string{"cluster-name"}
-	cmd.Flags().BoolVar(&options.wait, "wait", false, "Wait for the cluster to become active")
+	cmd.ValidArgsFunction = completeClusterNames
+	addWaitFlags(cmd, &options.wait, "Wait for the cluster to become active")
+	addReadyCheckFlag(cmd, &options.readyCheck)
+	addForceFlag(cmd)
+	addDryRunFlag(cmd, "Validate the cluster and print what would change, without rebuilding it")
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newRepairCommand() *cobra.Command {
+	var options struct {
+		name       string
+		action     string
+		nodes      int
+		wait       bool
+		readyCheck string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "repair <cluster-name>",
+		Short: "Recover a cluster in an error state",
+		Long: `Recover a cluster in an error state. The reason the cluster failed is printed, along with the recovery actions which apply:
+  rebuild: Retry creating the cluster infrastructure
+  resize:  Shrink the cluster, which helps when there wasn't enough capacity. See --nodes
+  delete:  Delete the cluster, so that it can be created again
+
+The action is prompted for, or selected with --action.`,
+		Example: `  carina repair mycluster
+  carina repair mycluster --action resize --nodes 1 --wait`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch client.RepairAction(options.action) {
+			case "", client.RepairRebuild, client.RepairResize, client.RepairDelete:
+			default:
+				return fmt.Errorf("Invalid --action %s. Allowed values: %s, %s, %s", options.action, client.RepairRebuild, client.RepairResize, client.RepairDelete)
+			}
+			if cmd.Flags().Changed("nodes") && options.nodes < 1 {
+				return fmt.Errorf("--nodes must be >= 1")
+			}
+
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			diagnosis, err := cxt.Client.DiagnoseCluster(cxt.Account, options.name)
+			if err != nil {
+				return err
+			}
+			if !cmd.Flags().Changed("nodes") {
+				options.nodes = diagnosis.ResizeNodes
+			}
+			writeDiagnosis(diagnosis)
+
+			action := client.RepairAction(options.action)
+			if action == "" {
+				var ok bool
+				action, ok = chooseRepairAction(diagnosis)
+				if !ok {
+					return nil
+				}
+			}
+			if !diagnosis.HasAction(action) {
+				return fmt.Errorf("The %s action doesn't apply to the cluster %s, use one of: %s", action, options.name, formatRepairActions(diagnosis.Actions))
+			}
+
+			switch action {
+			case client.RepairRebuild:
+				if cxt.DryRun {
+					return writePlan(cxt.Client.PlanRebuildCluster(cxt.Account, options.name))
+				}
+
+				err = confirmOperation("rebuild", options.name, nil)
+				if err != nil {
+					return err
+				}
+
+				cluster, err := cxt.Client.RebuildCluster(cxt.Account, options.name, options.wait)
+				if err != nil {
+					return err
+				}
+				return writeRepairedCluster(cluster, options.wait, options.readyCheck)
+			case client.RepairResize:
+				if cxt.DryRun {
+					return writePlan(cxt.Client.PlanResizeCluster(cxt.Account, options.name, options.nodes))
+				}
+
+				err = confirmOperation("resize", options.name, func(common.Cluster) int { return options.nodes })
+				if err != nil {
+					return err
+				}
+
+				cluster, err := cxt.Client.ResizeCluster(cxt.Account, options.name, options.nodes, options.wait)
+				if err != nil {
+					return err
+				}
+				return writeRepairedCluster(cluster, options.wait, options.readyCheck)
+			default:
+				if cxt.DryRun {
+					return writePlan(cxt.Client.PlanDeleteClusters(cxt.Account, []string{options.name}))
+				}
+
+				err = confirmOperation("delete", options.name, nil)
+				if err != nil {
+					return err
+				}
+
+				err = cxt.Client.DeleteCluster(cxt.Account, options.name, options.wait)
+				if err != nil {
+					return err
+				}
+				if options.wait {
+					fmt.Printf("Deleted cluster (%s)\n", options.name)
+				} else {
+					fmt.Printf("Deleting cluster (%s)\n", options.name)
+				}
+				return nil
+			}
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.action, "action", "", "The recovery action: rebuild, resize or delete. Prompts for the action when not specified")
+	cmd.Flags().IntVar(&options.nodes, "nodes", 0, "The number of nodes when resizing, defaults to one fewer node than the cluster has")
+	addWaitFlags(cmd, &options.wait, "Wait for the recovery action to complete")
+	addReadyCheckFlag(cmd, &options.readyCheck)
+	addForceFlag(cmd)
+	addDryRunFlag(cmd, "Print the repairs which would be made, without making them")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+// writeDiagnosis prints why a cluster failed, and the recovery actions which apply
+func writeDiagnosis(diagnosis *client.ClusterDiagnosis) {
+	reason := diagnosis.Reason
+	if reason == "" {
+		reason = "Unknown, the API didn't report why the cluster failed"
+	}
+
+	console.WriteMap([]console.Tuple{
+		{Key: "Cluster", Value: diagnosis.Name},
+		{Key: "Status", Value: diagnosis.Status},
+		{Key: "Reason", Value: reason},
+		{Key: "Actions", Value: formatRepairActions(diagnosis.Actions)},
+	})
+}
+
+// chooseRepairAction prompts for the recovery action, returning false when the user cancels or can't be prompted
+func chooseRepairAction(diagnosis *client.ClusterDiagnosis) (client.RepairAction, bool) {
+	if !console.IsInteractive() {
+		common.Log.WriteWarning("Run carina repair %s --action <action> to recover the cluster", diagnosis.Name)
+		return "", false
+	}
+
+	choices := make([]string, len(diagnosis.Actions))
+	for i, action := range diagnosis.Actions {
+		choices[i] = string(action)
+	}
+	choice, ok := console.Choose(fmt.Sprintf("How do you want to repair %s?", diagnosis.Name), append(choices, "cancel"))
+	if !ok || choice == "cancel" {
+		common.Log.WriteWarning("Canceled repair of %s", diagnosis.Name)
+		return "", false
+	}
+	return client.RepairAction(choice), true
+}
+
+// formatRepairActions lists the recovery actions, e.g. rebuild, resize, delete
+func formatRepairActions(actions []client.RepairAction) string {
+	names := make([]string, len(actions))
+	for i, action := range actions {
+		names[i] = string(action)
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeRepairedCluster waits for a rebuilt or resized cluster, and prints it
+func writeRepairedCluster(cluster common.Cluster, wait bool, readyCheck string) error {
+	err := waitUntilReady(cluster, wait, readyCheck)
+	if err != nil {
+		return err
+	}
+
+	console.WriteCluster(cluster)
+	return nil
+}
@@ -2,46 +2,162 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
 	"github.com/getcarina/carina/console"
 	"github.com/spf13/cobra"
 )
 
 func newResizeCommand() *cobra.Command {
 	var options struct {
-		name  string
-		nodes int
-		wait  bool
+		name       string
+		names      []string
+		file       string
+		nodes      int
+		wait       bool
+		readyCheck string
+		matchAll   bool
+		filters    []string
+		parallel   int
 	}
 
 	var cmd = &cobra.Command{
-		Use:               "resize <cluster-name>",
-		Short:             "Resize a cluster",
-		Long:              "Resize a cluster by setting the number of cluster nodes",
+		Use:   "resize <cluster-name>",
+		Short: "Resize a cluster",
+		Long: `Resize a cluster by setting the number of cluster nodes. The names of the clusters to resize can also be read from a file with --file, or from stdin with - as the cluster name. The result for each cluster is printed, and the command fails if any of the clusters could not be resized.
+
+Use --filter instead of a cluster name to resize every matching cluster, e.g. to scale a fleet down overnight. The clusters are resized concurrently, at most --parallel at a time, and clusters which already have the number of nodes are skipped.`,
+		Example: `  carina resize mycluster --nodes 3
+  carina resize --filter label=env=ci --nodes 1 --parallel 10`,
 		PersistentPreRunE: authenticatedPreRunE,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if options.nodes < 1 {
 				return errors.New("--nodes must be >= 1")
 			}
+			if options.parallel < 1 {
+				return errors.New("--parallel must be >= 1")
+			}
+			client.BatchPolicy.Parallel = options.parallel
+
+			if len(options.filters) > 0 {
+				if len(args) > 0 || options.file != "" {
+					return errors.New("A cluster name or --file cannot be specified with --filter")
+				}
+				names, err := selectClustersToResize(options.filters, options.nodes)
+				options.names = names
+				return err
+			}
 
-			return bindClusterNameArg(args, &options.name)
+			names, fromList, err := readClusterNames(args, options.file)
+			if fromList {
+				options.names = names
+				return err
+			}
+
+			return bindMatchingClusterNamesArg(args, options.matchAll, &options.name, &options.names)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.filters) > 0 && len(options.names) == 0 {
+				console.Write("Every cluster matching the filters already has %d nodes", options.nodes)
+				return nil
+			}
+			if len(options.names) > 0 {
+				return resizeClusters(options.names, options.nodes, options.wait)
+			}
+
+			if cxt.DryRun {
+				return writePlan(cxt.Client.PlanResizeCluster(cxt.Account, options.name, options.nodes))
+			}
+
+			err := confirmOperation("resize", options.name, func(common.Cluster) int { return options.nodes })
+			if err != nil {
+				return err
+			}
+
 			cluster, err := cxt.Client.ResizeCluster(cxt.Account, options.name, options.nodes, options.wait)
 			if err != nil {
 				return err
 			}
 
+			err = waitUntilReady(cluster, options.wait, options.readyCheck)
+			if err != nil {
+				return err
+			}
+
 			console.WriteCluster(cluster)
 
 			return nil
 		},
 	}
 
-	cmd.ValidArgs = []string{"cluster-name"}
+	cmd.ValidArgsFunction = completeClusterNames
 	cmd.Flags().IntVar(&options.nodes, "nodes", 1, "The desired number of nodes in the cluster")
-	cmd.Flags().BoolVar(&options.wait, "wait", false, "Wait for cluster to finish resizing and return to active")
+	addWaitFlags(cmd, &options.wait, "Wait for cluster to finish resizing and return to active")
+	addOnSuccessFlag(cmd, "Shell command to run after each cluster is resized")
+	addReadyCheckFlag(cmd, &options.readyCheck)
+	addClusterNamesFileFlag(cmd, &options.file)
+	addMatchAllFlag(cmd, &options.matchAll)
+	cmd.Flags().StringArrayVar(&options.filters, "filter", nil, "Resize every cluster where the field matches the pattern, e.g. label=env=ci or name=web*. Allowed fields: name, status, template, coe, host, label. May be specified multiple times")
+	cmd.Flags().IntVar(&options.parallel, "parallel", 5, "The maximum number of clusters to resize at the same time")
+	addDryRunFlag(cmd, "Validate the clusters and print what would change, without resizing them")
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
 }
+
+// selectClustersToResize returns the names of the clusters matching the filters which don't already have the number of nodes
+func selectClustersToResize(filters []string, nodes int) ([]string, error) {
+	listOptions, err := client.ParseClusterSelection(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters, err := cxt.Client.ListClusters(cxt.Account, listOptions)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("No clusters match %s", strings.Join(filters, ", "))
+	}
+
+	var names []string
+	for _, cluster := range clusters {
+		if current, err := common.ParseNodeCount(cluster.GetNodes()); err == nil && current == nodes {
+			common.Log.WriteDebug("Skipping %s, it already has %d nodes", cluster.GetName(), nodes)
+			continue
+		}
+		names = append(names, cluster.GetName())
+	}
+	return names, nil
+}
+
+// resizeClusters resizes multiple clusters, after confirming each one with the user, and prints the result for each cluster
+func resizeClusters(names []string, nodes int, wait bool) error {
+	if cxt.DryRun {
+		for _, name := range names {
+			err := writePlan(cxt.Client.PlanResizeCluster(cxt.Account, name, nodes))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		err := confirmOperation("resize", name, func(common.Cluster) int { return nodes })
+		if err != nil {
+			return err
+		}
+	}
+
+	results, err := cxt.Client.ResizeClusters(cxt.Account, names, nodes, wait)
+	if err != nil {
+		return err
+	}
+
+	console.WriteClusterOperationResults(results, "Resizing")
+	return checkOperationResults(results, "resize", "clusters")
+}
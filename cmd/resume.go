@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newResumeCommand() *cobra.Command {
+	var options struct {
+		name string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "resume [<cluster-name>]",
+		Short: "Continue waiting for operations which were interrupted",
+		Long: `Continue waiting for the create, grow, resize, rebuild and delete operations which were interrupted, or timed out, while waiting with --wait. The operations are remembered in the cache until the cluster becomes active, fails or is deleted.
+
+Specify a cluster name to only resume the operation on that cluster.`,
+		Example: `  carina resume
+  carina resume mycluster`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				options.name = args[0]
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var pending []client.PendingOperation
+			for _, op := range cxt.Client.ListPendingOperations(cxt.Account) {
+				if options.name == "" || op.ClusterName == options.name || op.ClusterID == options.name {
+					pending = append(pending, op)
+				}
+			}
+
+			if len(pending) == 0 {
+				if options.name != "" {
+					return fmt.Errorf("There are no interrupted operations on %s", options.name)
+				}
+				console.Write("There are no interrupted operations")
+				return nil
+			}
+
+			for _, op := range pending {
+				console.Write("Resuming the %s, started %s", op, console.FormatTimestamp(op.Started))
+				cluster, err := cxt.Client.ResumeOperation(cxt.Account, op)
+				if err != nil {
+					return err
+				}
+
+				if cluster == nil {
+					console.Write("Cluster (%s) is deleted", op.ClusterName)
+					continue
+				}
+				console.WriteCluster(cluster)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+// warnPendingOperations reminds the user of the operations which were interrupted, so that they aren't lost track of
+func warnPendingOperations(cmd *cobra.Command) {
+	if cmd.Name() == "resume" || cmd.Name() == "wait" {
+		return
+	}
+
+	for _, op := range cxt.Client.ListPendingOperations(cxt.Account) {
+		common.Log.WriteStructuredWarning(common.WarningInterrupted, "The %s was interrupted before the cluster was ready. Run carina resume %s to continue waiting.", op, op.ClusterName)
+	}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newSchemaCommand() *cobra.Command {
+	var options struct {
+		name string
+	}
+
+	var cmd = &cobra.Command{
+		Use:               "schema <name>",
+		Short:             "Show the JSON schema of the --format json output",
+		Long:              "Show the JSON schema of the --format json output. The schemaVersion field in each document is incremented whenever a field is removed or changed.",
+		ValidArgs:         console.SchemaNames(),
+		PersistentPreRunE: unauthenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A schema name is required")
+			}
+			options.name = args[0]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return console.WriteSchema(options.name)
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
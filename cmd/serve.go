@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/getcarina/carina/web"
+	"github.com/spf13/cobra"
+)
+
+func newServeCommand() *cobra.Command {
+	var options struct {
+		listen string
+	}
+
+	var cmd = &cobra.Command{
+		Use:               "serve",
+		Short:             "Serve a web dashboard for managing clusters",
+		Long:              "Serve a web dashboard for listing, creating and deleting clusters, and downloading their credentials. Deleting a cluster is confirmed by typing its name. The dashboard uses the same account as the rest of the CLI, so only listen on addresses which you trust.",
+		PersistentPreRunE: authenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			server, err := web.NewServer(cxt.Client, cxt.Account, options.listen)
+			if err != nil {
+				return err
+			}
+			server.ConfirmationPolicy = cxt.ConfirmationPolicy
+
+			if !web.IsLoopback(options.listen) {
+				common.Log.WriteWarning("WARNING: The dashboard is listening on %s and can manage your clusters without a login. Use --listen localhost:<port> unless it is protected by a firewall", options.listen)
+			}
+
+			console.Write("Serving the dashboard on http://%s, press Ctrl+C to stop", options.listen)
+			return http.ListenAndServe(options.listen, server)
+		},
+	}
+
+	cmd.Flags().StringVar(&options.listen, "listen", "localhost:8080", "The address for the dashboard to listen on, e.g. localhost:8080")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
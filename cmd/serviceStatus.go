@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newServiceStatusCommand() *cobra.Command {
+	var options struct {
+		url string
+		all bool
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "service-status",
+		Short: "Show the cloud's status page, and any ongoing incidents",
+		Long:  "Show the status of the cloud's container service and identity from its status page, and any ongoing incidents affecting them, to tell an outage apart from a problem with your account or configuration. Use --all to include every service on the status page.",
+		Example: `  carina service-status
+  carina service-status --url https://status.example.com/api/v2/summary.json`,
+		PersistentPreRunE: unauthenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			statusURL := options.url
+			if statusURL == "" {
+				// cloud = --cloud -> cloud setting in the config file -> default
+				cloud := cxt.CloudType
+				if cloud == "" {
+					cloud = viper.GetString("cloud")
+				}
+				if cloud == "" {
+					cloud = defaultCloudProvider
+				}
+
+				provider, err := client.LookupCloudProvider(cloud)
+				if err != nil {
+					return err
+				}
+				if provider.StatusURL == "" {
+					return fmt.Errorf("The %s cloud doesn't have a known status page. Use --url to specify the status page summary", provider.Name)
+				}
+				statusURL = provider.StatusURL
+			}
+
+			status, err := client.GetServiceStatus(statusURL)
+			if err != nil {
+				return err
+			}
+			if !options.all {
+				status = status.Relevant()
+			}
+
+			console.WriteServiceStatus(status)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.url, "url", "", "The status page summary, in the Statuspage format, e.g. https://status.example.com/api/v2/summary.json. Defaults to the cloud's status page")
+	cmd.Flags().BoolVar(&options.all, "all", false, "Show every service and incident on the status page, instead of only the container service and identity")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
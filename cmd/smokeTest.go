@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newSmokeTestCommand() *cobra.Command {
+	var options client.SmokeTestOptions
+
+	var cmd = &cobra.Command{
+		Use:   "smoke-test",
+		Short: "Check that clusters can be created and used on the account",
+		Long: `Check that the account, region and template are fully functional: create a throwaway 1 node cluster, wait for it to become active, download its credentials, check that its Docker or Kubernetes API responds, and then delete it.
+
+The template defaults to the newest template which is neither deprecated nor low on capacity. Use --keep to troubleshoot a failure, the cluster must then be deleted with carina delete. Use --wait-timeout to limit how long each step waits.`,
+		Example: `  carina smoke-test
+  carina smoke-test --coe kubernetes --wait-timeout 20m`,
+		PersistentPreRunE: authenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := cxt.Client.SmokeTest(cxt.Account, options)
+			if err != nil {
+				return err
+			}
+
+			console.WriteSmokeTestResult(result)
+			return result.Err()
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Template, "template", "", "Name of the template to test. Defaults to the recommended template")
+	cmd.Flags().StringVar(&options.COE, "coe", "", "Only recommend a template for the container orchestration engine, e.g. kubernetes or swarm")
+	cmd.Flags().BoolVar(&options.Keep, "keep", false, "Keep the cluster instead of deleting it")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newSSHCommand() *cobra.Command {
+	var options struct {
+		name string
+		client.SSHOptions
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "ssh <cluster-name> [node]",
+		Short: "Connect to a node in a cluster over SSH",
+		Long: `Connect to a node in a cluster over SSH, using the ssh client on your PATH.
+The node is selected by its name or address, as listed by carina nodes <cluster-name>, and defaults to the first master.`,
+		Example: `  carina ssh mycluster
+  carina ssh mycluster node-1 --user core
+  carina ssh mycluster --command "docker ps"`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 2 {
+				return errors.New("Too many arguments, expected a cluster name and an optional node")
+			}
+			if len(args) == 2 {
+				options.Node = args[1]
+			}
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ssh, err := exec.LookPath("ssh")
+			if err != nil {
+				return errors.New("Unable to find ssh on your PATH. Install an OpenSSH client, then try again")
+			}
+
+			sshArgs, err := cxt.Client.BuildSSHArgs(cxt.Account, options.name, options.SSHOptions)
+			if err != nil {
+				return err
+			}
+
+			common.Log.WriteDebug("Running %s %v", ssh, sshArgs)
+			session := exec.Command(ssh, sshArgs...)
+			session.Stdin = os.Stdin
+			session.Stdout = os.Stdout
+			session.Stderr = os.Stderr
+			err = session.Run()
+			return errors.Wrap(err, "The ssh session failed")
+		},
+	}
+
+	cmd.Flags().StringVar(&options.User, "user", "", "The login user, defaults to the user for the operating system of the cluster's nodes")
+	cmd.Flags().StringVarP(&options.IdentityFile, "identity", "i", "", "The private key used to authenticate, defaults to the keys selected by ssh")
+	cmd.Flags().StringVar(&options.Command, "command", "", "Run the command on the node and exit, instead of starting an interactive session")
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
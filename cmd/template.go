@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newTemplateCommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "template",
+		Short: "Show a cluster template",
+		Long:  "Show a cluster template. Use carina templates to list the templates.",
+	}
+
+	cmd.AddCommand(newTemplateGetCommand())
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newTemplateGetCommand() *cobra.Command {
+	var options struct {
+		name string
+	}
+
+	var cmd = &cobra.Command{
+		Use:               "get <template-name>",
+		Short:             "Show the details of a cluster template",
+		Long:              "Show the details of a cluster template, such as its COE version, node flavor and network settings. The name may be a pattern, e.g. Kubernetes*, which must match a single template.",
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A template name is required")
+			}
+			options.name = args[0]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			template, err := cxt.Client.GetClusterTemplate(cxt.Account, options.name)
+			if err != nil {
+				return err
+			}
+
+			console.WriteTemplate(template)
+
+			return nil
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -1,38 +1,166 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
 	"github.com/getcarina/carina/console"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 func newTemplatesCommand() *cobra.Command {
+	var options client.ListTemplatesOptions
+	var columns []string
+	var names bool
+
+	var cmd = &cobra.Command{
+		Use:   "templates",
+		Short: "List cluster templates",
+		Long: `List cluster templates. The Host column is the type of host the cluster nodes run on, such as lxc or vm, which can be selected with carina create --host-type.
+
+When the cloud reports what the templates support, the maximum number of nodes, and if autoscale and GPUs are supported, are listed too. Pick other columns with --columns, or the template-columns setting.`,
+		Example: `  # List the Kubernetes templates hosted on LXC
+  carina templates --coe kubernetes --host lxc
+
+  # List the COE version and node flavor of each template
+  carina templates --columns name,version,flavor`,
+		PersistentPreRunE: authenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// columns = --columns -> config file -> default columns
+			if !cmd.Flags().Changed("columns") {
+				columns = splitConfigList(viper.GetString("template-columns"))
+			}
+			err := console.SetTemplateColumns(columns)
+			if err != nil {
+				return err
+			}
+
+			templates, err := cxt.Client.ListClusterTemplates(cxt.Account, options)
+			if err != nil {
+				return err
+			}
+
+			if names {
+				console.WriteTemplateNames(templates)
+				return nil
+			}
+			if len(templates) == 0 && writeNoTemplates(options.Name != "" || !options.Selector.IsEmpty()) {
+				return nil
+			}
+			console.WriteTemplates(templates)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Name, "name", "", "Filter by name, e.g. Kubernetes*. See --match-mode")
+	cmd.Flags().StringVar(&options.Selector.COE, "coe", "", "Only list the templates for the container orchestration engine, e.g. kubernetes or swarm")
+	cmd.Flags().StringVar(&options.Selector.HostType, "host", "", "Only list the templates hosted on the host type, e.g. lxc or vm")
+	cmd.Flags().StringVar(&options.Selector.HostType, "host-type", "", "Only list the templates hosted on the host type, e.g. lxc or vm")
+	cmd.Flags().MarkHidden("host-type")
+	cmd.Flags().BoolVar(&names, "names", false, "Only print the template names, one per line")
+	cmd.Flags().StringSliceVar(&columns, "columns", nil, "The columns to print, e.g. name,coe,version. Allowed values: "+strings.Join(console.TemplateColumnNames(), ", "))
+	cmd.AddCommand(newTemplatesCreateCommand())
+	cmd.AddCommand(newTemplatesDeleteCommand())
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newTemplatesCreateCommand() *cobra.Command {
+	var options common.ClusterTemplateOptions
+
+	var cmd = &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a cluster template",
+		Long: `Create a cluster template, for clouds which let the account manage its own templates, such as magnum. The template can then be used with carina create --template.
+
+The image, flavors and networks are the ids or names in the OpenStack cloud. The master flavor defaults to the node flavor.`,
+		Example: `  carina templates create k8s-small --coe kubernetes --image fedora-atomic --flavor m1.small --external-network public`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A template name is required")
+			}
+			options.Name = args[0]
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			template, err := cxt.Client.CreateClusterTemplate(cxt.Account, options)
+			if err != nil {
+				return err
+			}
+
+			console.WriteTemplate(template)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.COE, "coe", "", "The container orchestration engine, e.g. kubernetes or swarm")
+	cmd.Flags().StringVar(&options.Image, "image", "", "The image the nodes are booted from")
+	cmd.Flags().StringVar(&options.Flavor, "flavor", "", "The flavor of the nodes")
+	cmd.Flags().StringVar(&options.MasterFlavor, "master-flavor", "", "The flavor of the masters, defaulting to --flavor")
+	cmd.Flags().StringVar(&options.ExternalNetwork, "external-network", "", "The network used for the floating IP addresses of the nodes")
+	cmd.Flags().StringVar(&options.NetworkDriver, "network-driver", "", "The container network driver, e.g. flannel")
+	cmd.Flags().StringVar(&options.DNSNameServer, "dns-nameserver", "", "The DNS server used by the nodes, e.g. 8.8.8.8")
+	cmd.Flags().IntVar(&options.DockerVolumeSize, "docker-volume-size", 0, "The size in GB of the docker volume on each node")
+	cmd.Flags().StringVar(&options.ServerType, "server-type", "", "The type of the host nodes, e.g. vm or bm")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
+
+func newTemplatesDeleteCommand() *cobra.Command {
 	var options struct {
 		name string
 	}
 
 	var cmd = &cobra.Command{
-		Use:               "templates",
-		Short:             "List cluster templates",
-		Long:              "List cluster templates",
-		PersistentPreRunE: authenticatedPreRunE,
+		Use:   "delete <name>",
+		Short: "Delete a cluster template",
+		Long:  "Delete a cluster template, for clouds which let the account manage its own templates, such as magnum. The clusters created from the template must be deleted first.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("A template name is required")
+			}
+			options.name = args[0]
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			templates, err := cxt.Client.ListClusterTemplates(cxt.Account, options.name)
+			if !cxt.AssumeYes {
+				if !console.IsInteractive() {
+					return fmt.Errorf("Confirmation is required to delete the template %s. Use --yes to skip the confirmation", options.name)
+				}
+				if !console.Confirm(fmt.Sprintf("Are you sure you want to delete the template %s?", options.name)) {
+					return fmt.Errorf("Canceled delete of %s", options.name)
+				}
+			}
+
+			template, err := cxt.Client.DeleteClusterTemplate(cxt.Account, options.name)
 			if err != nil {
 				return err
 			}
 
-			data := [][]string{[]string{"Name", "COE", "Host"}}
-			for _, template := range templates {
-				data = append(data, []string{template.GetName(), template.GetCOE(), template.GetHostType()})
-			}
-			console.WriteTable(data)
+			console.Write("Deleted the template %s", template.GetName())
 
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVar(&options.name, "name", "", "Filter by name, e.g. Kubernetes*")
+	addForceFlag(cmd)
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
 }
+
+// writeNoTemplates explains why no templates were listed, and what to do next, returning if it was printed
+func writeNoTemplates(filtered bool) bool {
+	if filtered {
+		return console.WriteEmptyState("No templates match the filters.", "Run carina templates without --name, --coe or --host to list every template.")
+	}
+	return console.WriteEmptyState("No templates found.", "The account may not have access to cluster templates in this region. Try another --region, or contact your cloud administrator.")
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newUpdateCommand() *cobra.Command {
+	var options client.UpdateOptions
+
+	var cmd = &cobra.Command{
+		Use:   "update",
+		Short: "Update carina to the latest release",
+		Long: `Update carina to the latest release on GitHub, replacing the carina executable in place.
+
+The downloaded binary is verified against the release checksums, and the checksums are verified against the
+release signature. Development builds, which don't include the release signing key, can't be updated.
+Use --check-only to see if an update is available without installing it.`,
+		Example: `  # Is there a newer release?
+  carina update --check-only
+
+  # Install the latest release
+  carina update`,
+		PersistentPreRunE: unauthenticatedPreRunE,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result, err := cxt.Client.UpdateCarina(options)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case result.Updated:
+				console.Write("Updated %s from %s to %s", result.Path, result.Current, result.Latest)
+			case result.Available:
+				console.WriteValue(result.Latest, "carina "+result.Latest+" is available, you are on "+result.Current+". Run carina update to install it.")
+			default:
+				console.Write("carina %s is the latest release", result.Current)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&options.CheckOnly, "check-only", false, "Only check if a newer release is available, without installing it")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
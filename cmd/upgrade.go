@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newUpgradeCommand() *cobra.Command {
+	var options struct {
+		name       string
+		template   string
+		wait       bool
+		readyCheck string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "upgrade <cluster-name>",
+		Short: "Upgrade a cluster to a newer template",
+		Long: `Upgrade a cluster to a newer template, e.g. a newer COE version, keeping its nodes and workloads.
+By default the newest template with the same COE and host type is used. Select a template with --to-template.
+
+The template must use the same COE, must not be older or deprecated, and must support the cluster's number of nodes,
+autoscaling and GPUs. Use --dry-run to check a template without upgrading. Upgrading requires a cloud which supports it.`,
+		Example: `  # Upgrade prod to the newest template
+  carina upgrade prod --wait
+
+  # Check if prod can be moved to a specific template
+  carina upgrade prod --to-template "Kubernetes 1.6*" --dry-run`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cxt.DryRun {
+				return writePlan(cxt.Client.PlanUpgradeCluster(cxt.Account, options.name, options.template))
+			}
+
+			err := confirmOperation("upgrade", options.name, nil)
+			if err != nil {
+				return err
+			}
+
+			cluster, err := cxt.Client.UpgradeCluster(cxt.Account, options.name, options.template, options.wait)
+			if err != nil {
+				return err
+			}
+
+			err = waitUntilReady(cluster, options.wait, options.readyCheck)
+			if err != nil {
+				return err
+			}
+
+			console.WriteCluster(cluster)
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.template, "to-template", "", "Name of the template to upgrade to, e.g. Kubernetes*. Defaults to the newest template with the same COE and host type")
+	cmd.RegisterFlagCompletionFunc("to-template", completeTemplateNames)
+	addWaitFlags(cmd, &options.wait, "Wait for the cluster to become active")
+	addReadyCheckFlag(cmd, &options.readyCheck)
+	addForceFlag(cmd)
+	addDryRunFlag(cmd, "Check the template and print what would change, without upgrading the cluster")
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -1,27 +1,102 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/getcarina/carina/client"
 	"github.com/getcarina/carina/console"
 	"github.com/getcarina/carina/version"
 	"github.com/spf13/cobra"
 )
 
 func newVersionCommand() *cobra.Command {
+	var options struct {
+		remote bool
+		check  bool
+	}
+
 	var cmd = &cobra.Command{
-		Use:               "version",
-		Short:             "Show the application version",
-		Long:              "Show the application version",
-		PersistentPreRunE: unauthenticatedPreRunE,
-		Run: func(cmd *cobra.Command, args []string) {
-			writeVersion()
+		Use:   "version",
+		Short: "Show the application version",
+		Long: `Show the application version, and the commit, build date, Go version and platform of the build.
+
+With --check, also check if a newer release is available. With --remote, show the API version of the account's endpoint, and the operations which it doesn't support, instead.
+
+Use --format json for a document which packaging automation can parse, see carina schema version.`,
+		Example: `  carina version
+  carina version --check --format json`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Only the remote version requires account credentials
+			if options.remote {
+				return authenticatedPreRunE(cmd, args)
+			}
+			return unauthenticatedPreRunE(cmd, args)
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if options.check && options.remote {
+				return errors.New("--check can't be used with --remote")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !options.remote {
+				writeVersion(options.check)
+				return nil
+			}
+			return writeRemoteVersion()
 		},
 	}
 
+	cmd.Flags().BoolVar(&options.check, "check", false, "Also check if a newer release of carina is available")
+	cmd.Flags().BoolVar(&options.remote, "remote", false, "Show the API version of the account's endpoint, and the operations which it doesn't support, instead of the build information")
 	cmd.SetUsageTemplate(cmd.UsageTemplate())
 
 	return cmd
 }
 
-func writeVersion() {
-	console.Write("%s (%s)", version.Version, version.Commit)
+func writeVersion(check bool) {
+	var update *client.UpdateResult
+	var updateErr error
+	if check {
+		update, updateErr = cxt.Client.UpdateCarina(client.UpdateOptions{CheckOnly: true})
+	}
+	console.WriteVersion(version.GetBuildInfo(), update, updateErr)
+}
+
+func writeRemoteVersion() error {
+	capabilities, err := cxt.Client.GetAPICapabilities(cxt.Account, true)
+	if err != nil {
+		return err
+	}
+
+	items := []console.Tuple{{Key: "Client", Value: fmt.Sprintf("%s (%s)", version.Version, version.Commit)}}
+	if capabilities == nil {
+		items = append(items, console.Tuple{Key: "Server", Value: "unknown, the cloud doesn't report its API version"})
+		console.WriteMap(items)
+		return nil
+	}
+
+	serverVersion := capabilities.MaxVersion
+	if capabilities.MinVersion != "" && capabilities.MinVersion != capabilities.MaxVersion {
+		serverVersion = capabilities.MinVersion + " - " + capabilities.MaxVersion
+	}
+	if serverVersion == "" {
+		serverVersion = "unknown"
+	}
+	unsupported := "none"
+	if len(capabilities.Unsupported) > 0 {
+		unsupported = strings.Join(capabilities.Unsupported, ", ")
+	}
+
+	items = append(items,
+		console.Tuple{Key: "Endpoint", Value: capabilities.Endpoint},
+		console.Tuple{Key: "Server API", Value: serverVersion})
+	if capabilities.ClientVersion != "" {
+		items = append(items, console.Tuple{Key: "Client API", Value: capabilities.ClientVersion})
+	}
+	items = append(items, console.Tuple{Key: "Unsupported", Value: unsupported})
+	console.WriteMap(items)
+	return nil
 }
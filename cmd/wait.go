@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newWaitCommand() *cobra.Command {
+	var options struct {
+		name       string
+		state      string
+		timeout    time.Duration
+		readyCheck string
+	}
+
+	var cmd = &cobra.Command{
+		Use:   "wait <cluster-name>",
+		Short: "Wait for a cluster to become active, be deleted or fail",
+		Long: `Wait for a cluster to reach a state, e.g. a cluster created earlier with --no-wait or by someone else.
+
+States:
+  active: the cluster is active. Fails if the cluster ends up in an error state instead
+  deleted: the cluster is gone. Succeeds immediately if the cluster doesn't exist
+  error: the cluster is in an error state. Fails if the cluster becomes active instead
+
+Use --probe to also wait until the Docker or Kubernetes API accepts connections, because a cluster can be reported active before it is usable. Use the exit code in scripts, 5 means that --timeout was reached.`,
+		Example: `  # Block until mycluster is ready, for up to 10 minutes
+  carina wait mycluster --for active --timeout 10m && carina env mycluster
+
+  # Block until the Kubernetes API of mycluster responds
+  carina wait mycluster --probe && kubectl apply -f app.yaml`,
+		PersistentPreRunE: authenticatedPreRunE,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			err := client.ValidateWaitForState(options.state)
+			if err != nil {
+				return err
+			}
+			if options.timeout < 0 {
+				return errors.New("--timeout must be >= 0")
+			}
+			if options.readyCheck == client.ReadyCheckCOE && options.state != client.WaitForActive {
+				return errors.New("--ready-check coe and --probe require --for active")
+			}
+			return bindClusterNameArg(args, &options.name)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("timeout") {
+				common.ClusterWaitPolicy.Timeout = options.timeout
+			}
+
+			cluster, err := cxt.Client.WaitForCluster(cxt.Account, options.name, options.state)
+			if err != nil {
+				return err
+			}
+
+			if cluster == nil {
+				console.Write("Cluster (%s) is deleted", options.name)
+				return nil
+			}
+			err = waitUntilReady(cluster, true, options.readyCheck)
+			if err != nil {
+				return err
+			}
+			console.WriteCluster(cluster)
+
+			return nil
+		},
+	}
+
+	cmd.ValidArgsFunction = completeClusterNames
+	cmd.Flags().StringVar(&options.state, "for", client.WaitForActive, "State to wait for: active, deleted or error")
+	cmd.Flags().DurationVar(&options.timeout, "timeout", 0, "Maximum amount of time to wait, e.g. 10m. Defaults to --wait-timeout")
+	addReadyCheckFlag(cmd, &options.readyCheck)
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/getcarina/carina/console"
+	"github.com/spf13/cobra"
+)
+
+func newWhoAmICommand() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:               "whoami",
+		Short:             "Show which account and endpoint are used",
+		Long:              "Show the account selected with --profile, the auth flags or environment variables: the profile, cloud, username, project, region and endpoints, whether an auth token is cached and when it expires, and where the cache is stored. Nothing is sent to the API, use carina login to check that the credentials are valid.",
+		Example:           "  carina whoami --profile prod",
+		PersistentPreRunE: authenticatedPreRunE,
+		Run: func(cmd *cobra.Command, args []string) {
+			var profile string
+			if cxt.ProfileLoaded {
+				profile = cxt.Profile
+			}
+
+			info := cxt.Client.DescribeAccount(cxt.Account, cxt.CloudType, profile, cxt.AccountSettings)
+			console.WriteAccountInfo(info)
+		},
+	}
+
+	cmd.SetUsageTemplate(cmd.UsageTemplate())
+
+	return cmd
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ClusterAction is a backend-specific operation which can be invoked on a cluster with carina action,
+// such as rotating the certificate authority of a Magnum cluster
+type ClusterAction struct {
+	// Name identifies the action, e.g. rotate-ca
+	Name string
+
+	// Description explains what the action does
+	Description string
+
+	// Params are the parameters accepted by the action, specified with --param key=value
+	Params []ClusterActionParam
+
+	// Destructive actions, such as those which invalidate the cluster credentials, always require confirmation
+	Destructive bool
+}
+
+// ClusterActionParam is a parameter accepted by a cluster action
+type ClusterActionParam struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// LookupClusterAction finds an action by its name
+func LookupClusterAction(actions []ClusterAction, name string) (ClusterAction, error) {
+	var names []string
+	for _, action := range actions {
+		if action.Name == name {
+			return action, nil
+		}
+		names = append(names, action.Name)
+	}
+
+	if len(names) == 0 {
+		return ClusterAction{}, NotFoundError{Err: fmt.Errorf("Unknown action %s, no actions are supported by this cloud", name)}
+	}
+	sort.Strings(names)
+	return ClusterAction{}, NotFoundError{Err: fmt.Errorf("Unknown action %s. Supported actions: %s", name, strings.Join(names, ", "))}
+}
+
+// ValidateParams verifies that the required parameters are specified, and that every parameter is accepted by the action
+func (action ClusterAction) ValidateParams(params map[string]string) error {
+	accepted := make(map[string]bool, len(action.Params))
+	for _, param := range action.Params {
+		accepted[param.Name] = true
+		if param.Required && params[param.Name] == "" {
+			return fmt.Errorf("The %s action requires --param %s=<value>", action.Name, param.Name)
+		}
+	}
+
+	var unknown []string
+	for name := range params {
+		if !accepted[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("Invalid parameters for the %s action: %s. Run carina action --list to see the supported parameters", action.Name, strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package common
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BoundedCache is an in-memory cache limited by both its number of entries and their age, so that a long-running
+// process, such as carina credentials refresh --agent, doesn't grow without bound. When the cache is full, the
+// least recently used entry is evicted, and entries older than the TTL are evicted when they are next read.
+type BoundedCache struct {
+	name       string
+	maxEntries int
+	ttl        time.Duration
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	stats   CacheStats
+}
+
+type boundedCacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// CacheStats are the metrics of a BoundedCache
+type CacheStats struct {
+	Name      string
+	Entries   int
+	Hits      int
+	Misses    int
+	Evictions int
+}
+
+// String summarizes the metrics, e.g. for debug output
+func (stats CacheStats) String() string {
+	return fmt.Sprintf("%s: %d entries, %d hits, %d misses, %d evictions", stats.Name, stats.Entries, stats.Hits, stats.Misses, stats.Evictions)
+}
+
+// NewBoundedCache builds an empty cache, which holds up to maxEntries entries for up to the ttl. A ttl of 0 disables expiry.
+func NewBoundedCache(name string, maxEntries int, ttl time.Duration) *BoundedCache {
+	return &BoundedCache{
+		name:       name,
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Get retrieves an entry, returning false when it isn't cached or has expired
+func (cache *BoundedCache) Get(key string) (interface{}, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		cache.stats.Misses++
+		return nil, false
+	}
+
+	entry := element.Value.(*boundedCacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		cache.remove(element)
+		cache.stats.Misses++
+		return nil, false
+	}
+
+	cache.order.MoveToFront(element)
+	cache.stats.Hits++
+	return entry.value, true
+}
+
+// Set adds or replaces an entry, evicting the least recently used entry when the cache is full
+func (cache *BoundedCache) Set(key string, value interface{}) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	var expires time.Time
+	if cache.ttl > 0 {
+		expires = time.Now().Add(cache.ttl)
+	}
+
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*boundedCacheEntry)
+		entry.value = value
+		entry.expires = expires
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(&boundedCacheEntry{key: key, value: value, expires: expires})
+	for cache.maxEntries > 0 && cache.order.Len() > cache.maxEntries {
+		cache.remove(cache.order.Back())
+	}
+}
+
+// Stats returns the current metrics of the cache
+func (cache *BoundedCache) Stats() CacheStats {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	stats := cache.stats
+	stats.Name = cache.name
+	stats.Entries = cache.order.Len()
+	return stats
+}
+
+func (cache *BoundedCache) remove(element *list.Element) {
+	cache.order.Remove(element)
+	delete(cache.entries, element.Value.(*boundedCacheEntry).key)
+	cache.stats.Evictions++
+}
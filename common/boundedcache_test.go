@@ -0,0 +1,42 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundedCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewBoundedCache("test", 2, 0)
+	cache.Set("a", 1)
+	cache.Set("b", 2)
+
+	// Reading a makes b the least recently used
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	cache.Set("c", 3)
+	_, ok = cache.Get("b")
+	assert.False(t, ok, "The least recently used entry should be evicted when the cache is full")
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+
+	// Replacing an entry doesn't evict another entry
+	cache.Set("c", 4)
+	value, _ = cache.Get("c")
+	assert.Equal(t, 4, value)
+
+	assert.Equal(t, CacheStats{Name: "test", Entries: 2, Hits: 3, Misses: 1, Evictions: 1}, cache.Stats())
+}
+
+func TestBoundedCacheExpiresEntries(t *testing.T) {
+	cache := NewBoundedCache("test", 0, time.Millisecond)
+	cache.Set("a", 1)
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok := cache.Get("a")
+	assert.False(t, ok, "An entry older than the TTL should be evicted")
+	assert.Equal(t, CacheStats{Name: "test", Entries: 0, Hits: 0, Misses: 1, Evictions: 1}, cache.Stats())
+}
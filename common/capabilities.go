@@ -0,0 +1,58 @@
+package common
+
+import "fmt"
+
+// Operations which an API endpoint may not support, see APICapabilities
+const (
+	OperationAutoScale = "autoscale"
+	OperationGrow      = "grow"
+	OperationRebuild   = "rebuild"
+	OperationResize    = "resize"
+	OperationUpgrade   = "upgrade"
+)
+
+// APICapabilities describes the API version of a cluster service's endpoint, and the operations which it doesn't support
+type APICapabilities struct {
+	// Endpoint is the URL of the API
+	Endpoint string `json:"endpoint"`
+
+	// MinVersion and MaxVersion are the range of API versions supported by the server, empty when the server doesn't report them
+	MinVersion string `json:"min-version,omitempty"`
+	MaxVersion string `json:"max-version,omitempty"`
+
+	// ClientVersion is the API version requested by carina, empty when carina doesn't request a version
+	ClientVersion string `json:"client-version,omitempty"`
+
+	// Unsupported are the operations which can't be performed against the endpoint, e.g. autoscale or rebuild
+	Unsupported []string `json:"unsupported,omitempty"`
+}
+
+// Supports returns if the endpoint supports an operation. Everything is supported when the capabilities are unknown.
+func (capabilities *APICapabilities) Supports(operation string) bool {
+	if capabilities == nil {
+		return true
+	}
+	for _, unsupported := range capabilities.Unsupported {
+		if unsupported == operation {
+			return false
+		}
+	}
+	return true
+}
+
+// CapabilityProber is implemented by cluster services which can detect the API version of their endpoint, and which operations it supports
+type CapabilityProber interface {
+	// ProbeCapabilities asks the endpoint for its API version, returning the operations which can't be performed against it
+	ProbeCapabilities() (*APICapabilities, error)
+}
+
+// UnsupportedOperationError is returned before calling the API, when the endpoint is known not to support an operation
+type UnsupportedOperationError struct {
+	Operation string
+	Endpoint  string
+}
+
+// Error describes the operation which isn't supported
+func (err UnsupportedOperationError) Error() string {
+	return fmt.Sprintf("%s is not supported by this endpoint (%s). Run carina version --remote to see what the endpoint supports", err.Operation, err.Endpoint)
+}
@@ -1,7 +1,13 @@
 package common
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/getcarina/libcarina"
 )
@@ -9,7 +15,7 @@ import (
 // ClusterService is a common interface over multiple container orchestration engine APIs (magnum, make-swarm and make-coe)
 type ClusterService interface {
 	// GetQuotas retrieves the quotas set for the account
-	GetQuotas() (Quotas, error)
+	GetQuotas() (*Quotas, error)
 
 	// CreateCluster creates a new cluster
 	CreateCluster(name string, template string, nodes int) (Cluster, error)
@@ -39,7 +45,14 @@ type ClusterService interface {
 	GrowCluster(token string, nodes int) (Cluster, error)
 
 	// SetAutoScale enables or disables autoscaling on a cluster by its id or name (if unique)
-	SetAutoScale(token string, value bool) (Cluster, error)
+	SetAutoScale(token string, autoscale AutoScale) (Cluster, error)
+
+	// ListActions returns the backend-specific actions which can be invoked on a cluster with InvokeAction
+	ListActions() []ClusterAction
+
+	// InvokeAction invokes a backend-specific action on a cluster by its id or name (if unique).
+	// The parameters have been validated against the action returned by ListActions.
+	InvokeAction(token string, action string, params map[string]string) (Cluster, error)
 
 	// WaitUntilClusterIsActive polls the cluster status until either an active or error state is hit
 	WaitUntilClusterIsActive(cluster Cluster) (Cluster, error)
@@ -48,6 +61,158 @@ type ClusterService interface {
 	WaitUntilClusterIsDeleted(cluster Cluster) error
 }
 
+// DriverOptionsCreator is implemented by cluster services which accept driver specific options when creating a cluster,
+// such as the magnum labels kube_tag or docker_volume_size
+type DriverOptionsCreator interface {
+	// CreateClusterWithDriverOptions creates a new cluster, passing the driver options through to the API
+	CreateClusterWithDriverOptions(name string, template string, nodes int, driverOptions map[string]string) (Cluster, error)
+}
+
+// SSHKey selects the SSH key installed on the nodes of a new cluster
+type SSHKey struct {
+	// KeypairName is the name of a keypair registered with the account
+	KeypairName string
+
+	// PublicKey is the contents of an SSH public key, such as ~/.ssh/id_rsa.pub
+	PublicKey string
+}
+
+// SSHKeyCreator is implemented by cluster services which can install an SSH key on the nodes when creating a cluster
+type SSHKeyCreator interface {
+	// CreateClusterWithSSHKey creates a new cluster with the SSH key installed on its nodes, passing the driver options through to the API
+	CreateClusterWithSSHKey(name string, template string, nodes int, key SSHKey, driverOptions map[string]string) (Cluster, error)
+}
+
+// NodeLister is implemented by cluster services which report the individual nodes in a cluster
+type NodeLister interface {
+	// ListNodes retrieves the nodes in a cluster by its id or name (if unique)
+	ListNodes(token string) ([]Node, error)
+}
+
+// KeypairLister is implemented by cluster services which manage the SSH keypairs registered with the account
+type KeypairLister interface {
+	// ListKeypairs retrieves the SSH keypairs registered with the account
+	ListKeypairs() ([]Keypair, error)
+}
+
+// ClusterUpgrader is implemented by cluster services which can move an existing cluster to another template, e.g. a newer COE version
+type ClusterUpgrader interface {
+	// UpgradeCluster upgrades a cluster by its id or name (if unique) to the template
+	UpgradeCluster(token string, template string) (Cluster, error)
+}
+
+// ClusterTemplateService is implemented by cluster services which can create and delete templates, e.g. magnum cluster templates,
+// instead of only using the templates offered by the cloud
+type ClusterTemplateService interface {
+	// CreateClusterTemplate creates a new template
+	CreateClusterTemplate(options ClusterTemplateOptions) (ClusterTemplate, error)
+
+	// DeleteClusterTemplate permanently deletes a template by its id or name, returning the deleted template
+	DeleteClusterTemplate(token string) (ClusterTemplate, error)
+}
+
+// ClusterTemplateOptions are the settings of a new template
+type ClusterTemplateOptions struct {
+	Name string
+
+	// COE is the container orchestration engine, e.g. kubernetes or swarm
+	COE string
+
+	// Image is the id or name of the image the nodes are booted from
+	Image string
+
+	// Flavor and MasterFlavor are the flavors of the nodes and masters, MasterFlavor defaults to Flavor
+	Flavor       string
+	MasterFlavor string
+
+	// ExternalNetwork is the id of the network used for the floating IP addresses of the nodes
+	ExternalNetwork string
+
+	// NetworkDriver is the container network driver, e.g. flannel. Empty uses the COE's default driver
+	NetworkDriver string
+
+	// DNSNameServer is the DNS server used by the nodes, e.g. 8.8.8.8
+	DNSNameServer string
+
+	// DockerVolumeSize is the size in GB of the docker volume on each node, 0 uses the image's disk
+	DockerVolumeSize int
+
+	// ServerType is the type of the host nodes, e.g. vm or bm
+	ServerType string
+}
+
+// Authenticator is implemented by cluster services which can verify the account's credentials without calling the cluster API
+type Authenticator interface {
+	// Authenticate authenticates with the account's credentials, returning the endpoint of the cluster API
+	Authenticate() (endpoint string, err error)
+}
+
+// TemplateSelector selects a template by its attributes instead of its name, which can differ between environments
+type TemplateSelector struct {
+	// COE is the container orchestration engine, e.g. kubernetes or swarm
+	COE string
+
+	// HostType is the underlying type of the host nodes, e.g. lxc or vm. Empty matches any host type
+	HostType string
+
+	// Version is the COE version, e.g. 1.5.2, or a prefix such as 1.x or 1.5. Empty matches any version
+	Version string
+}
+
+// IsEmpty returns if no attributes are selected
+func (selector TemplateSelector) IsEmpty() bool {
+	return selector.COE == "" && selector.HostType == "" && selector.Version == ""
+}
+
+// String describes the selected attributes, e.g. coe=kubernetes host-type=lxc version=1.x
+func (selector TemplateSelector) String() string {
+	var fields []string
+	for _, field := range []struct{ name, value string }{
+		{"coe", selector.COE},
+		{"host-type", selector.HostType},
+		{"version", selector.Version},
+	} {
+		if field.value != "" {
+			fields = append(fields, field.name+"="+field.value)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// Matches returns if a template has the selected COE, host type and version
+func (selector TemplateSelector) Matches(template ClusterTemplate) bool {
+	if selector.COE != "" && !strings.EqualFold(selector.COE, template.GetCOE()) {
+		return false
+	}
+	if selector.HostType != "" && !strings.EqualFold(selector.HostType, template.GetHostType()) {
+		return false
+	}
+	if selector.Version != "" {
+		// 1.x and 1.* match any 1.y.z version
+		prefix := strings.TrimSuffix(strings.TrimSuffix(selector.Version, ".x"), ".*")
+		version := template.GetCOEVersion()
+		if version != prefix && !strings.HasPrefix(version, prefix+".") {
+			return false
+		}
+	}
+	return true
+}
+
+// TemplateResolver is implemented by cluster services which can find a template by its attributes, see TemplateSelector
+type TemplateResolver interface {
+	// ResolveTemplate finds the single active template matching the selector
+	ResolveTemplate(selector TemplateSelector) (ClusterTemplate, error)
+}
+
+// Keypair is a common interface for SSH keypairs registered with an account
+type Keypair interface {
+	// GetName returns the unique keypair name
+	GetName() string
+
+	// GetFingerprint returns the fingerprint of the public key
+	GetFingerprint() string
+}
+
 // Cluster is a common interface for clusters over multiple container orchestration engine APIs (magnum, make-swarm and make-coe)
 type Cluster interface {
 	// GetID returns the cluster identifier
@@ -62,7 +227,7 @@ type Cluster interface {
 	// GetFlavor returns the flavor of the nodes in the cluster
 	GetFlavor() string
 
-	// GetNodes returns the number of nodes in the cluster
+	// GetNodes returns the number of nodes in the cluster, see ParseNodeCount
 	GetNodes() string
 
 	// GetStatus returns the status of the cluster
@@ -71,6 +236,109 @@ type Cluster interface {
 	// GetStatusDetails returns additional information about the cluster's status.
 	// For example, why the cluster is in a failed state.
 	GetStatusDetails() string
+
+	// GetLabels returns the key/value pairs used to organize the cluster, such as env=prod
+	GetLabels() map[string]string
+
+	// GetAutoScale returns the cluster's autoscaling settings, or nil when the cluster doesn't support autoscaling
+	GetAutoScale() *AutoScale
+
+	// GetCreated returns when the cluster was created, or the zero time when the API doesn't report it
+	GetCreated() time.Time
+
+	// GetUpdated returns when the cluster was last changed, such as resized, or the zero time when the API doesn't report it
+	GetUpdated() time.Time
+
+	// GetNodeSize returns the resources of each node in the cluster, or nil when the API doesn't report them
+	GetNodeSize() *NodeSize
+}
+
+// NodeSize is the resources of each node in a cluster, and what it costs when the API reports it
+type NodeSize struct {
+	// RAM is the memory in MB, VCPUs the number of virtual CPUs and Disk the root disk in GB, and are 0 when unknown
+	RAM   int
+	VCPUs int
+	Disk  int
+
+	// HourlyCost is the estimated cost of a node per hour in Currency, e.g. USD, and is 0 when the API doesn't report it
+	HourlyCost float64
+	Currency   string
+}
+
+// String returns a short summary of the resources, e.g. "2 vCPUs, 4 GB RAM, 40 GB disk"
+func (size *NodeSize) String() string {
+	if size == nil {
+		return ""
+	}
+
+	var resources []string
+	if size.VCPUs > 0 {
+		resources = append(resources, fmt.Sprintf("%d vCPUs", size.VCPUs))
+	}
+	if size.RAM > 0 {
+		if size.RAM%1024 == 0 {
+			resources = append(resources, fmt.Sprintf("%d GB RAM", size.RAM/1024))
+		} else {
+			resources = append(resources, fmt.Sprintf("%d MB RAM", size.RAM))
+		}
+	}
+	if size.Disk > 0 {
+		resources = append(resources, fmt.Sprintf("%d GB disk", size.Disk))
+	}
+	return strings.Join(resources, ", ")
+}
+
+// EstimateCost returns the estimated hourly cost of a number of nodes, e.g. "0.36 USD/hour", or an empty string when the cost is unknown
+func (size *NodeSize) EstimateCost(nodes int) string {
+	if size == nil || size.HourlyCost <= 0 || nodes <= 0 {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%.2f %s", size.HourlyCost*float64(nodes), size.Currency)) + "/hour"
+}
+
+// AutoScale is a cluster's autoscaling settings
+type AutoScale struct {
+	Enabled bool
+
+	// MinNodes and MaxNodes bound the number of nodes when autoscaling, and are 0 when not limited
+	MinNodes int
+	MaxNodes int
+}
+
+// String returns a short summary of the settings, e.g. "on (3-10 nodes)"
+func (autoscale *AutoScale) String() string {
+	if autoscale == nil {
+		return ""
+	}
+	if !autoscale.Enabled {
+		return "off"
+	}
+
+	switch {
+	case autoscale.MinNodes > 0 && autoscale.MaxNodes > 0:
+		return fmt.Sprintf("on (%d-%d nodes)", autoscale.MinNodes, autoscale.MaxNodes)
+	case autoscale.MinNodes > 0:
+		return fmt.Sprintf("on (at least %d nodes)", autoscale.MinNodes)
+	case autoscale.MaxNodes > 0:
+		return fmt.Sprintf("on (at most %d nodes)", autoscale.MaxNodes)
+	default:
+		return "on"
+	}
+}
+
+// Node is a common interface for the nodes in a cluster over multiple container orchestration engine APIs (magnum, make-swarm and make-coe)
+type Node interface {
+	// GetName returns the node name
+	GetName() string
+
+	// GetAddress returns the IP address of the node
+	GetAddress() string
+
+	// GetRole returns the role of the node in the cluster, such as master or node
+	GetRole() string
+
+	// GetStatus returns the status of the node
+	GetStatus() string
 }
 
 // ClusterTemplate is a common interface for templates over multiple container orchestration engine APIs (magnum, make-swarm and make-coe)
@@ -83,23 +351,195 @@ type ClusterTemplate interface {
 
 	// GetHostType returns the underlying type of the host nodes, such as lxc or vm
 	GetHostType() string
+
+	// IsDeprecated returns if the template is deprecated and should not be used for new clusters
+	IsDeprecated() bool
+
+	// GetCOEVersion returns the version of the container orchestration engine, or an empty string when it is unknown
+	GetCOEVersion() string
+
+	// GetNodeFlavor returns the flavor (size) of the host nodes, or an empty string when it is unknown
+	GetNodeFlavor() string
+
+	// GetDetails returns additional backend-specific settings, such as the image and network settings
+	GetDetails() map[string]string
+
+	// GetAvailability returns the capacity and availability zone hints reported by the API, or nil when the API doesn't report them
+	GetAvailability() *TemplateAvailability
+
+	// GetCapabilities returns what clusters created with the template support, or nil when the API doesn't report them
+	GetCapabilities() *TemplateCapabilities
+}
+
+// TemplateCapabilities describes what clusters created with a template support
+type TemplateCapabilities struct {
+	// MaxNodes is the maximum number of nodes in a cluster, or 0 when it is only limited by the account's quotas
+	MaxNodes int `json:"maxNodes,omitempty"`
+
+	// AutoScale is set when the number of nodes can be scaled automatically, see carina autoscale
+	AutoScale bool `json:"autoscale"`
+
+	// GPU is set when the nodes have GPUs
+	GPU bool `json:"gpu"`
+}
+
+// TemplateAvailability is a hint from the API about whether new clusters can be created with a template
+type TemplateAvailability struct {
+	// Constrained is set when the API reports that creating a cluster with the template is likely to fail, e.g. it is low on capacity
+	Constrained bool
+
+	// Reason explains why the template is constrained
+	Reason string
+
+	// AvailabilityZones are the zones in which clusters using the template are created
+	AvailabilityZones []string
+}
+
+// String returns a short summary of the hint, e.g. "constrained: low capacity"
+func (availability *TemplateAvailability) String() string {
+	if availability == nil {
+		return ""
+	}
+
+	status := "available"
+	if availability.Constrained {
+		status = "constrained"
+		if availability.Reason != "" {
+			status += ": " + availability.Reason
+		}
+	}
+	return status
 }
 
-// Quotas is a common interface for cluster quotas over multiple container orchestration engine APIs (magnum, make-swarm and make-coe)
-type Quotas interface {
-	// GetMaxClusters returns the maximum number of clusters allowed on the account
-	GetMaxClusters() int
+// templateVersionPattern finds the version in a template name, e.g. 1.5.2 in "Kubernetes 1.5.2 on LXC"
+var templateVersionPattern = regexp.MustCompile(`\b[0-9]+\.[0-9]+(\.[0-9]+)?\b`)
 
-	// GetMaxNodesPerCluster returns the maximum number of nodes allowed in a cluster on the account
-	GetMaxNodesPerCluster() int
+// ParseTemplateVersion finds the COE version in a template name, such as "Kubernetes 1.5.2 on LXC"
+func ParseTemplateVersion(name string) string {
+	return templateVersionPattern.FindString(name)
+}
+
+// CompareVersions compares dotted version numbers, e.g. 1.10 is newer than 1.9. Unknown versions are the oldest.
+func CompareVersions(a string, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart int
+		if i < len(aParts) {
+			aPart, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			bPart, _ = strconv.Atoi(bParts[i])
+		}
+		if aPart != bPart {
+			if aPart > bPart {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// ErrQuotasUnsupported is returned by GetQuotas when the cloud can't report the account's quotas
+var ErrQuotasUnsupported = errors.New("Retrieving the account quotas is not supported by this cloud")
+
+// Quotas are the limits set on an account by the cluster service (magnum, make-swarm and make-coe).
+// A limit of 0 means that the limit is unknown.
+type Quotas struct {
+	// MaxClusters is the maximum number of clusters allowed on the account
+	MaxClusters int
+
+	// MaxNodesPerCluster is the maximum number of nodes allowed in a cluster on the account
+	MaxNodesPerCluster int
+}
+
+// QuotaUsage is the number of clusters and nodes in use on the account, to compare against its Quotas
+type QuotaUsage struct {
+	Clusters int
+	Nodes    int
+
+	// LargestCluster is the number of nodes in the largest cluster, to compare against the nodes per cluster quota
+	LargestCluster int
+
+	// COEs breaks down the usage by container orchestration engine, e.g. kubernetes or swarm
+	COEs []COEUsage
+}
+
+// COEUsage is the number of clusters and nodes using a container orchestration engine
+type COEUsage struct {
+	COE      string
+	Clusters int
+	Nodes    int
 }
 
 // MultipleMatchingTemplatesError indicates when a template search was too broad and matched multiple templates
 type MultipleMatchingTemplatesError struct {
 	TemplatePattern string
+
+	// MatchingTemplates are the names of the templates which matched the pattern
+	MatchingTemplates []string
+
+	// Candidates are the templates which matched the pattern, with their COE and host type, when the backend provides them
+	Candidates []ClusterTemplate
 }
 
 // Error returns the underlying error message
 func (error MultipleMatchingTemplatesError) Error() string {
-	return fmt.Sprintf("Multiple matching templates found for '%s'. Run carina templates --name %s to refine the search pattern to only match a single template.", error.TemplatePattern, error.TemplatePattern)
+	if len(error.MatchingTemplates) == 0 {
+		return fmt.Sprintf("Multiple matching templates found for '%s'. Run carina templates --name %s to refine the search pattern to only match a single template.", error.TemplatePattern, error.TemplatePattern)
+	}
+
+	sorted := append([]string(nil), error.MatchingTemplates...)
+	if len(error.Candidates) > 0 {
+		sorted = make([]string, len(error.Candidates))
+		for i, template := range error.Candidates {
+			sorted[i] = DescribeTemplate(template)
+		}
+	}
+	sort.Strings(sorted)
+	return fmt.Sprintf("Multiple matching templates found for '%s'. Refine the search pattern to only match a single template:\n  %s", error.TemplatePattern, strings.Join(sorted, "\n  "))
+}
+
+// MultipleMatchingClustersError indicates when more than one cluster has the same name, so the cluster must be selected by its id
+type MultipleMatchingClustersError struct {
+	ClusterName string
+
+	// MatchingClusters are the clusters with the name
+	MatchingClusters []Cluster
+}
+
+// Error returns the underlying error message
+func (error MultipleMatchingClustersError) Error() string {
+	if len(error.MatchingClusters) == 0 {
+		return fmt.Sprintf("Multiple clusters named %s were found, use the cluster id instead", error.ClusterName)
+	}
+
+	ids := make([]string, len(error.MatchingClusters))
+	for i, cluster := range error.MatchingClusters {
+		ids[i] = fmt.Sprintf("%s (%s)", cluster.GetID(), cluster.GetStatus())
+	}
+	return fmt.Sprintf("Multiple clusters named %s were found, use the cluster id instead:\n  %s", error.ClusterName, strings.Join(ids, "\n  "))
+}
+
+// DeprecatedTemplateError indicates when a deprecated template was selected and deprecated templates are not allowed
+type DeprecatedTemplateError struct {
+	TemplateName string
+}
+
+// Error returns the underlying error message
+func (error DeprecatedTemplateError) Error() string {
+	return fmt.Sprintf("The template '%s' is deprecated. Select a newer template, or use --allow-deprecated to create the cluster anyway.", error.TemplateName)
+}
+
+// ParseNodeCount parses the number of nodes reported by Cluster.GetNodes, e.g. 3, or masters/nodes, e.g. 1/3, for magnum.
+// The number of nodes excludes the masters, matching the number of nodes requested when creating or resizing a cluster.
+func ParseNodeCount(nodes string) (int, error) {
+	if i := strings.Index(nodes, "/"); i >= 0 {
+		nodes = nodes[i+1:]
+	}
+	count, err := strconv.Atoi(nodes)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid number of nodes: %s", nodes)
+	}
+	return count, nil
 }
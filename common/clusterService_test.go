@@ -0,0 +1,63 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTemplateVersion(t *testing.T) {
+	assert.Equal(t, "1.5.2", ParseTemplateVersion("Kubernetes 1.5.2 on LXC"))
+	assert.Equal(t, "1.11", ParseTemplateVersion("Swarm 1.11 on VM"))
+	assert.Equal(t, "", ParseTemplateVersion("swarm-dev"))
+}
+
+func TestTemplateSelectorMatches(t *testing.T) {
+	template := &selectorTemplate{name: "Kubernetes 1.5.2 on LXC", coe: "kubernetes", host: "lxc"}
+
+	assert.True(t, TemplateSelector{COE: "Kubernetes"}.Matches(template))
+	assert.True(t, TemplateSelector{COE: "kubernetes", HostType: "lxc", Version: "1.x"}.Matches(template))
+	assert.True(t, TemplateSelector{Version: "1.5"}.Matches(template))
+	assert.True(t, TemplateSelector{Version: "1.5.2"}.Matches(template))
+	assert.False(t, TemplateSelector{Version: "1.50"}.Matches(template))
+	assert.False(t, TemplateSelector{COE: "swarm"}.Matches(template))
+	assert.False(t, TemplateSelector{HostType: "vm"}.Matches(template))
+	assert.Equal(t, "coe=kubernetes version=1.x", TemplateSelector{COE: "kubernetes", Version: "1.x"}.String())
+}
+
+type selectorTemplate struct {
+	ClusterTemplate
+	name string
+	coe  string
+	host string
+}
+
+func (template *selectorTemplate) GetCOE() string        { return template.coe }
+func (template *selectorTemplate) GetHostType() string   { return template.host }
+func (template *selectorTemplate) GetCOEVersion() string { return ParseTemplateVersion(template.name) }
+
+func TestNodeSize(t *testing.T) {
+	size := &NodeSize{RAM: 4096, VCPUs: 2, Disk: 40, HourlyCost: 0.12, Currency: "USD"}
+	assert.Equal(t, "2 vCPUs, 4 GB RAM, 40 GB disk", size.String())
+	assert.Equal(t, "0.36 USD/hour", size.EstimateCost(3))
+
+	size = &NodeSize{RAM: 1536}
+	assert.Equal(t, "1536 MB RAM", size.String())
+	assert.Equal(t, "", size.EstimateCost(3), "the cost is unknown")
+
+	size = nil
+	assert.Equal(t, "", size.String())
+}
+
+func TestParseNodeCount(t *testing.T) {
+	nodes, err := ParseNodeCount("3")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, nodes)
+
+	nodes, err = ParseNodeCount("1/3")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, nodes, "magnum's masters should be excluded")
+
+	_, err = ParseNodeCount("")
+	assert.Error(t, err, "clusters which are still being created may not report their size")
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Deprecation describes a command or flag which will be removed, and what to use instead
+type Deprecation struct {
+	// Command is the full command, e.g. carina grow
+	Command string
+
+	// Flag is the deprecated flag, e.g. --by, and is empty when the whole command is deprecated
+	Flag string
+
+	// Sunset is the version in which the command or flag will be removed
+	Sunset string
+
+	// Replacement is what to use instead
+	Replacement string
+}
+
+// Subject returns what is deprecated, e.g. carina grow or --by for carina grow
+func (deprecation Deprecation) Subject() string {
+	if deprecation.Flag == "" {
+		return deprecation.Command
+	}
+	if !strings.Contains(deprecation.Command, " ") {
+		// Global flags apply to every command
+		return deprecation.Flag
+	}
+	return fmt.Sprintf("%s for %s", deprecation.Flag, deprecation.Command)
+}
+
+// String returns the deprecation warning
+func (deprecation Deprecation) String() string {
+	message := fmt.Sprintf("%s is deprecated and will be removed in %s", deprecation.Subject(), deprecation.Sunset)
+	if deprecation.Replacement != "" {
+		message += ", use " + deprecation.Replacement + " instead"
+	}
+	return message
+}
+
+// WriteDeprecation logs a deprecation warning. The deprecation is attached to the log entry as fields,
+// so that scripts can find the deprecations they use with --log-format json.
+func (log *consoleLogger) WriteDeprecation(deprecation Deprecation) {
+	log.WithFields(logrus.Fields{
+		"deprecated":  deprecation.Subject(),
+		"sunset":      deprecation.Sunset,
+		"replacement": deprecation.Replacement,
+		"code":        WarningDeprecated,
+	}).Warnf("WARNING: %s", deprecation)
+}
@@ -0,0 +1,18 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecationString(t *testing.T) {
+	command := Deprecation{Command: "carina grow", Sunset: "3.0.0", Replacement: "carina resize"}
+	assert.Equal(t, "carina grow is deprecated and will be removed in 3.0.0, use carina resize instead", command.String())
+
+	flag := Deprecation{Command: "carina grow", Flag: "--by", Sunset: "3.0.0"}
+	assert.Equal(t, "--by for carina grow is deprecated and will be removed in 3.0.0", flag.String())
+
+	global := Deprecation{Command: "carina", Flag: "--cache-dir", Sunset: "3.0.0", Replacement: "--data-dir"}
+	assert.Equal(t, "--cache-dir", global.Subject())
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// AuthError is returned when the API rejects the account's credentials, or the account is not allowed to perform the operation
+type AuthError struct {
+	Err error
+}
+
+// Error returns the underlying error message
+func (err AuthError) Error() string {
+	return err.Err.Error()
+}
+
+// Cause returns the underlying cause of the error
+func (err AuthError) Cause() error {
+	return err.Err
+}
+
+// QuotaError is returned when an operation would exceed the account's quotas
+type QuotaError struct {
+	Err error
+}
+
+// Error returns the underlying error message
+func (err QuotaError) Error() string {
+	return err.Err.Error()
+}
+
+// Cause returns the underlying cause of the error
+func (err QuotaError) Cause() error {
+	return err.Err
+}
+
+// NotFoundError is returned when a cluster, template or other resource does not exist
+type NotFoundError struct {
+	Err error
+}
+
+// Error returns the underlying error message
+func (err NotFoundError) Error() string {
+	return err.Err.Error()
+}
+
+// Cause returns the underlying cause of the error
+func (err NotFoundError) Cause() error {
+	return err.Err
+}
+
+// APIError is returned when the API fails a request for any other reason, such as an outage
+type APIError struct {
+	StatusCode int
+	Err        error
+}
+
+// Error returns the underlying error message
+func (err APIError) Error() string {
+	return err.Err.Error()
+}
+
+// Cause returns the underlying cause of the error
+func (err APIError) Cause() error {
+	return err.Err
+}
+
+// ClusterError records the cluster on which an operation failed
+type ClusterError struct {
+	ClusterName string
+	Err         error
+}
+
+// Error returns the underlying error message
+func (err ClusterError) Error() string {
+	return err.Err.Error()
+}
+
+// Cause returns the underlying cause of the error
+func (err ClusterError) Cause() error {
+	return err.Err
+}
+
+// NewHTTPError categorizes an error returned by the API, based on the status code of the response
+func NewHTTPError(statusCode int, err error) error {
+	switch {
+	case statusCode == http.StatusRequestEntityTooLarge,
+		statusCode == http.StatusForbidden && strings.Contains(strings.ToLower(err.Error()), "quota"):
+		return QuotaError{Err: err}
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return AuthError{Err: err}
+	case statusCode == http.StatusNotFound:
+		return NotFoundError{Err: err}
+	default:
+		return APIError{StatusCode: statusCode, Err: err}
+	}
+}
+
+// lastHTTPStatus is the status code of the most recent API response, or 0 when the request failed without a response
+var lastHTTPStatus int64
+
+func recordHTTPStatus(statusCode int) {
+	atomic.StoreInt64(&lastHTTPStatus, int64(statusCode))
+}
+
+// CategorizeError returns an error caused by a failed API response as an AuthError, QuotaError, NotFoundError or APIError.
+// Errors which are already categorized, or which were not caused by the API, are returned unchanged.
+func CategorizeError(err error) error {
+	if err == nil || IsCategorized(err) {
+		return err
+	}
+
+	statusCode := int(atomic.LoadInt64(&lastHTTPStatus))
+	if statusCode < 400 {
+		return err
+	}
+	return NewHTTPError(statusCode, err)
+}
+
+// IsCategorized returns if an error, or one of its causes, is an AuthError, QuotaError, NotFoundError, TimeoutError, APIError
+// or MultipleMatchingClustersError
+func IsCategorized(err error) bool {
+	for err != nil {
+		switch err.(type) {
+		case AuthError, QuotaError, NotFoundError, TimeoutError, APIError, *MultipleMatchingClustersError:
+			return true
+		}
+
+		cause, ok := err.(interface {
+			Cause() error
+		})
+		if !ok || cause.Cause() == err {
+			return false
+		}
+		err = cause.Cause()
+	}
+	return false
+}
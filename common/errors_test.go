@@ -0,0 +1,37 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPError(t *testing.T) {
+	err := errors.New("Request failed")
+	assert.IsType(t, AuthError{}, NewHTTPError(401, err))
+	assert.IsType(t, AuthError{}, NewHTTPError(403, err))
+	assert.IsType(t, QuotaError{}, NewHTTPError(403, errors.New("Quota exceeded for clusters")))
+	assert.IsType(t, QuotaError{}, NewHTTPError(413, err))
+	assert.IsType(t, NotFoundError{}, NewHTTPError(404, err))
+	assert.Equal(t, APIError{StatusCode: 503, Err: err}, NewHTTPError(503, err))
+	assert.Equal(t, "Request failed", NewHTTPError(404, err).Error(), "The message should not change")
+}
+
+func TestCategorizeError(t *testing.T) {
+	defer recordHTTPStatus(0)
+	err := errors.New("Unable to retrieve cluster (web)")
+
+	recordHTTPStatus(200)
+	assert.Equal(t, err, CategorizeError(err), "Errors which weren't caused by the API should not be categorized")
+
+	recordHTTPStatus(404)
+	assert.IsType(t, NotFoundError{}, CategorizeError(err))
+
+	timeout := pkgerrors.Wrap(TimeoutError{}, "Unable to create the cluster")
+	assert.Equal(t, timeout, CategorizeError(timeout), "Categorized errors should be returned unchanged")
+
+	recordHTTPStatus(0)
+	assert.Equal(t, err, CategorizeError(err), "Errors without a response should not be categorized")
+}
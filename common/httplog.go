@@ -2,12 +2,13 @@ package common
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"net"
@@ -23,27 +24,69 @@ type HTTPLog struct {
 	rt     http.RoundTripper
 }
 
+// lastCallID numbers each API call, so that the log entries for a request and its response can be correlated
+var lastCallID int64
+
 // NewHTTPClient return a custom HTTP client that allows for logging relevant
-// information before and after the HTTP request.
+// information before and after the HTTP request, and retries transient errors.
 func NewHTTPClient() *http.Client {
+	return newHTTPClient(HTTPTransportPolicy.tlsConfig())
+}
+
+func newHTTPClient(tlsConfig *tls.Config) *http.Client {
+	// The transport's timeouts apply to each attempt, and the client's timeout bounds the whole request,
+	// including its retries and reading the response body, so that a stalled response can't hang the cli
 	timeout := 10 * time.Second
 	return &http.Client{
-		Timeout: timeout,
-		Transport: &HTTPLog{
-			rt: &http.Transport{
-				Proxy:             http.ProxyFromEnvironment,
-				DisableKeepAlives: true, // KeepAlive was causing "connection reset by peer" errors when issuing multiple requests
-				Dial: (&net.Dialer{
-					Timeout: timeout,
-				}).Dial,
-				TLSHandshakeTimeout:   timeout,
-				ExpectContinueTimeout: 1 * time.Second,
+		Timeout: HTTPRetryPolicy.requestTimeout(timeout),
+		Transport: &retryTransport{
+			policy: HTTPRetryPolicy,
+			// Record the rate limit reported by each attempt, so that polling is paced to stay within it
+			rt: &rateLimitTransport{
+				tracker: APIRateLimits,
+				rt: &HTTPLog{
+					// Trace each attempt separately, so that the latency of a retried request is accurate
+					rt: &traceTransport{
+						policy: HTTPTracePolicy,
+						logger: Log.Logger,
+						rt: &http.Transport{
+							Proxy:             HTTPTransportPolicy.proxy,
+							TLSClientConfig:   tlsConfig,
+							DisableKeepAlives: true, // KeepAlive was causing "connection reset by peer" errors when issuing multiple requests
+							Dial: (&net.Dialer{
+								Timeout: timeout,
+							}).Dial,
+							TLSHandshakeTimeout:   timeout,
+							ResponseHeaderTimeout: timeout,
+							ExpectContinueTimeout: 1 * time.Second,
+						},
+					},
+					Logger: Log.Logger,
+				},
 			},
-			Logger: Log.Logger,
 		},
 	}
 }
 
+// downloadTimeout is how long a download, such as a release binary, may take to finish
+const downloadTimeout = 10 * time.Minute
+
+// NewDownloadHTTPClient returns an HTTP client like NewHTTPClient for downloading large files,
+// which allows longer to read the response body
+func NewDownloadHTTPClient() *http.Client {
+	client := NewHTTPClient()
+	client.Timeout = downloadTimeout
+	return client
+}
+
+// NewVerifiedDownloadHTTPClient returns an HTTP client like NewDownloadHTTPClient which always verifies the server's certificate,
+// ignoring --insecure-skip-verify, for downloads which are trusted such as a release of carina
+func NewVerifiedDownloadHTTPClient() *http.Client {
+	client := newHTTPClient(HTTPTransportPolicy.verifiedTLSConfig())
+	client.Timeout = downloadTimeout
+	return client
+}
+
 // RoundTrip performs a round-trip HTTP request and logs relevant information about it.
 func (hl *HTTPLog) RoundTrip(request *http.Request) (*http.Response, error) {
 	defer func() {
@@ -53,38 +96,48 @@ func (hl *HTTPLog) RoundTrip(request *http.Request) (*http.Response, error) {
 	}()
 
 	var err error
+	entry := hl.Logger.WithField("call", atomic.AddInt64(&lastCallID, 1))
 
 	// Inject user agent
 	request.Header.Add("User-Agent", "getcarina/carina "+version.Version)
 
 	if hl.Logger.Level == logrus.DebugLevel && request.Body != nil {
-		request.Body, err = hl.logRequestBody(request.Body, request.Header)
+		request.Body, err = hl.logRequestBody(entry, request.Body, request.Header)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	// Don't log the token embedded in a cached auth token check
-	url := request.URL.String()
-	if strings.Contains(url, "tokens") {
-		url = fmt.Sprintf("%s://%s/***", request.URL.Scheme, request.URL.Host)
-	}
+	url := redactURL(request.URL)
 
-	hl.Logger.Debugf("Request: %s %s", request.Method, url)
+	entry.WithFields(logrus.Fields{"method": request.Method, "url": url}).Debugf("Request: %s %s", request.Method, url)
 
+	start := time.Now()
 	response, err := hl.rt.RoundTrip(request)
 	if response == nil {
+		recordHTTPStatus(0)
+		Timing.RecordRequest(request, 0, time.Since(start))
 		return nil, err
 	}
+	recordHTTPStatus(response.StatusCode)
+	Timing.RecordRequest(request, response.StatusCode, time.Since(start))
+
+	entry = entry.WithField("status", response.StatusCode)
+	if requestID := findRequestID(response.Header); requestID != "" {
+		entry = entry.WithField("requestID", requestID)
+		entry.Debugf("Request ID: %s", requestID)
+		Log.ErrorContext["Request ID"] = requestID
+	}
 
-	responseBody, _ := hl.logResponseBody(response.Body, response.Header)
+	responseBody, _ := hl.logResponseBody(entry, response.Body, response.Header)
 	response.Body = responseBody
 
 	if response.StatusCode >= 400 {
-		hl.Logger.Debugf("Response Code: %d %s", response.StatusCode, response.Status)
+		entry.Debugf("Response Code: %d %s", response.StatusCode, response.Status)
 		buf := bytes.NewBuffer([]byte{})
 		body, _ := ioutil.ReadAll(io.TeeReader(response.Body, buf))
-		hl.Logger.Debugf("Response Error: %+v", string(body))
+		entry.Debugf("Response Error: %+v", string(body))
 		bufWithClose := ioutil.NopCloser(buf)
 		response.Body = bufWithClose
 	}
@@ -92,7 +145,7 @@ func (hl *HTTPLog) RoundTrip(request *http.Request) (*http.Response, error) {
 	return response, err
 }
 
-func (hl *HTTPLog) logRequestBody(original io.ReadCloser, headers http.Header) (io.ReadCloser, error) {
+func (hl *HTTPLog) logRequestBody(entry *logrus.Entry, original io.ReadCloser, headers http.Header) (io.ReadCloser, error) {
 	defer original.Close()
 
 	var bs bytes.Buffer
@@ -104,27 +157,17 @@ func (hl *HTTPLog) logRequestBody(original io.ReadCloser, headers http.Header) (
 	contentType := headers.Get("Content-Type")
 	if strings.HasPrefix(contentType, "application/json") {
 		debugInfo := hl.formatJSON(bs.Bytes())
-		hl.Logger.Debugf("Request Options: %s", debugInfo)
+		entry.Debugf("Request Options: %s", debugInfo)
 	} else {
-		hl.Logger.Debugf("Request Options: %s", bs.String())
+		entry.Debugf("Request Options: %s", bs.String())
 	}
 
 	return ioutil.NopCloser(strings.NewReader(bs.String())), nil
 }
 
-func (hl *HTTPLog) logResponseBody(original io.ReadCloser, headers http.Header) (io.ReadCloser, error) {
+func (hl *HTTPLog) logResponseBody(entry *logrus.Entry, original io.ReadCloser, headers http.Header) (io.ReadCloser, error) {
 	defer original.Close()
 
-	// Log the request id, if present
-	for key, value := range headers {
-		if strings.Contains(strings.ToLower(key), "request-id") {
-			requestID := value[0]
-			hl.Logger.Debugf("Request ID: %s", requestID)
-			Log.ErrorContext["Request ID"] = requestID
-			break
-		}
-	}
-
 	var bs bytes.Buffer
 	_, err := io.Copy(&bs, original)
 	if err != nil {
@@ -135,15 +178,25 @@ func (hl *HTTPLog) logResponseBody(original io.ReadCloser, headers http.Header)
 	if strings.HasPrefix(contentType, "application/json") {
 		debugInfo := hl.formatJSON(bs.Bytes())
 		if debugInfo != "" {
-			hl.Logger.Debugf("Response Body: %s", debugInfo)
+			entry.Debugf("Response Body: %s", debugInfo)
 		}
 	} else {
-		hl.Logger.Debugf("Not logging because response body isn't JSON")
+		entry.Debugf("Not logging because response body isn't JSON")
 	}
 
 	return ioutil.NopCloser(strings.NewReader(bs.String())), nil
 }
 
+// findRequestID returns the id assigned to the request by the API, e.g. from X-Openstack-Request-Id, if present
+func findRequestID(headers http.Header) string {
+	for key, value := range headers {
+		if strings.Contains(strings.ToLower(key), "request-id") && len(value) > 0 {
+			return value[0]
+		}
+	}
+	return ""
+}
+
 func (hl *HTTPLog) formatJSON(raw []byte) string {
 	var data map[string]interface{}
 
@@ -152,7 +205,7 @@ func (hl *HTTPLog) formatJSON(raw []byte) string {
 		return string(raw)
 	}
 
-	pretty, err := json.MarshalIndent(data, "", "  ")
+	pretty, err := json.MarshalIndent(redactJSON(data), "", "  ")
 	if err != nil {
 		return string(raw)
 	}
@@ -0,0 +1,195 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// TracePolicy controls tracing of API calls with --debug-http
+type TracePolicy struct {
+	// Enabled logs the method, URL, status, latency, headers and body of every API call
+	Enabled bool
+
+	// MaxBodySize is the maximum number of bytes of a body to log, the rest is truncated
+	MaxBodySize int
+}
+
+// HTTPTracePolicy is the trace policy used by the HTTP clients created with NewHTTPClient
+var HTTPTracePolicy = &TracePolicy{
+	MaxBodySize: 4096,
+}
+
+// redacted replaces secrets in the trace
+const redacted = "***"
+
+// sensitiveHeaders contain credentials and are never traced
+var sensitiveHeaders = map[string]bool{
+	"Authorization":     true,
+	"Cookie":            true,
+	"Set-Cookie":        true,
+	"X-Auth-Token":      true,
+	"X-Subject-Token":   true,
+	"X-Auth-Key":        true,
+	"X-Vault-Token":     true,
+	"X-Vault-Namespace": true,
+}
+
+// sensitiveFields are the JSON fields, matched case-insensitively by substring, whose values are never traced
+var sensitiveFields = []string{"password", "apikey", "api_key", "secret", "token", "key", "cert", "passphrase"}
+
+// traceTransport satisfies the http.RoundTripper interface and traces each attempt of an API call
+type traceTransport struct {
+	policy *TracePolicy
+	rt     http.RoundTripper
+	logger *logrus.Logger
+}
+
+// RoundTrip performs a round-trip HTTP request, tracing it when tracing is enabled
+func (tt *traceTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !tt.policy.Enabled {
+		return tt.rt.RoundTrip(request)
+	}
+
+	entry := tt.logger.WithFields(logrus.Fields{
+		"method": request.Method,
+		"url":    redactURL(request.URL),
+	})
+
+	if request.Body != nil {
+		body, err := ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		entry = entry.WithField("body", tt.formatBody(body, request.Header))
+	}
+	entry.WithField("headers", formatHeaders(request.Header)).Infof("HTTP request: %s %s", request.Method, redactURL(request.URL))
+
+	start := time.Now()
+	response, err := tt.rt.RoundTrip(request)
+	entry = entry.WithField("latency", time.Since(start).String())
+	if err != nil {
+		entry.WithField("error", err.Error()).Infof("HTTP error: %s %s", request.Method, redactURL(request.URL))
+		return response, err
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return response, err
+	}
+
+	entry.WithFields(logrus.Fields{
+		"status":  response.StatusCode,
+		"headers": formatHeaders(response.Header),
+		"body":    tt.formatBody(body, response.Header),
+	}).Infof("HTTP response: %s %s %d", request.Method, redactURL(request.URL), response.StatusCode)
+
+	return response, nil
+}
+
+// redactURL hides the token embedded in the URL of a cached auth token check, and the query string and user info,
+// which may hold credentials, such as the signature of a presigned URL
+func redactURL(u *url.URL) string {
+	if strings.Contains(u.Path, "tokens") {
+		return fmt.Sprintf("%s://%s/***", u.Scheme, u.Host)
+	}
+
+	redactedURL := *u
+	if redactedURL.User != nil {
+		redactedURL.User = url.User(redacted)
+	}
+	if redactedURL.RawQuery != "" {
+		redactedURL.RawQuery = redacted
+	}
+	return redactedURL.String()
+}
+
+// formatHeaders prints the headers sorted by name, with credentials redacted
+func formatHeaders(headers http.Header) string {
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		value := strings.Join(headers[name], ", ")
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			value = redacted
+		}
+		pairs = append(pairs, fmt.Sprintf("%s: %s", name, value))
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// formatBody prints a JSON body with its secrets redacted. Other bodies, such as a credentials zip, only have their size printed.
+func (tt *traceTransport) formatBody(body []byte, headers http.Header) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if !strings.Contains(headers.Get("Content-Type"), "json") {
+		return fmt.Sprintf("<%d bytes of %s>", len(body), headers.Get("Content-Type"))
+	}
+
+	var data interface{}
+	err := json.Unmarshal(body, &data)
+	if err != nil {
+		return fmt.Sprintf("<%d bytes of invalid JSON>", len(body))
+	}
+
+	redacted, err := json.Marshal(redactJSON(data))
+	if err != nil {
+		return fmt.Sprintf("<%d bytes of JSON>", len(body))
+	}
+
+	if tt.policy.MaxBodySize > 0 && len(redacted) > tt.policy.MaxBodySize {
+		return fmt.Sprintf("%s... <truncated %d bytes>", redacted[:tt.policy.MaxBodySize], len(redacted)-tt.policy.MaxBodySize)
+	}
+	return string(redacted)
+}
+
+// redactJSON replaces the values of sensitive fields in a decoded JSON document
+func redactJSON(data interface{}) interface{} {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for field, fieldValue := range value {
+			if isSensitiveField(field) {
+				value[field] = redacted
+			} else {
+				value[field] = redactJSON(fieldValue)
+			}
+		}
+		return value
+	case []interface{}:
+		for i := range value {
+			value[i] = redactJSON(value[i])
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+func isSensitiveField(field string) bool {
+	field = strings.ToLower(field)
+	for _, sensitive := range sensitiveFields {
+		if strings.Contains(field, sensitive) {
+			return true
+		}
+	}
+	return false
+}
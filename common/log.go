@@ -1,6 +1,7 @@
 package common
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -10,10 +11,10 @@ import (
 	"github.com/davecgh/go-spew/spew"
 )
 
-// Log prints formatted, colored logs to the console
+// Log prints formatted, colored logs to stderr, so that they don't interleave with command output
 var Log = &consoleLogger{
 	Logger: &logrus.Logger{
-		Out: os.Stdout,
+		Out: os.Stderr,
 		Formatter: &logrus.TextFormatter{
 			DisableTimestamp: true,
 		},
@@ -23,26 +24,84 @@ var Log = &consoleLogger{
 	ErrorContext: make(map[string]interface{}),
 }
 
+// LogFormatText prints each log entry as a line of key=value pairs
+const LogFormatText = "text"
+
+// LogFormatJSON prints each log entry as a JSON object, for shipping to a log aggregator
+const LogFormatJSON = "json"
+
 type consoleLogger struct {
 	*logrus.Logger
 	IsSilent     bool
 	ErrorContext map[string]interface{}
+
+	// logFile is where logs are written instead of the console, when set
+	logFile *os.File
 }
 
-// SetDebug sends debug messages to stdout
+// SetDebug logs debug messages
 func (log *consoleLogger) SetDebug() {
 	log.Level = logrus.DebugLevel
 }
 
+// SetLogLevel changes which messages are logged: debug, info, warn or error
+func (log *consoleLogger) SetLogLevel(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("Invalid --log-level %s. Allowed values: debug, info, warn, error", level)
+	}
+	log.Level = parsed
+	return nil
+}
+
+// SetLogFormat changes how log entries are printed: text or json
+func (log *consoleLogger) SetLogFormat(format string) error {
+	switch format {
+	case LogFormatText:
+		// Timestamps are only useful when the log is read later
+		log.Formatter = &logrus.TextFormatter{
+			DisableTimestamp: log.logFile == nil,
+			FullTimestamp:    log.logFile != nil,
+		}
+	case LogFormatJSON:
+		log.Formatter = &logrus.JSONFormatter{}
+	default:
+		return fmt.Errorf("Invalid --log-format %s. Allowed values: %s, %s", format, LogFormatText, LogFormatJSON)
+	}
+	return nil
+}
+
+// SetLogFile appends the logs to a file, instead of printing them to the console
+func (log *consoleLogger) SetLogFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("Unable to open the --log-file %s: %s", path, err)
+	}
+
+	if log.logFile != nil {
+		log.logFile.Close()
+	}
+	log.logFile = f
+	log.Out = f
+	return nil
+}
+
 // HasDebug returns if the Debug flag is enabled
 func (log *consoleLogger) DebugEnabled() bool {
 	return log.Level == logrus.DebugLevel
 }
 
-// SetSilent disables writing to stdout
+// InfoEnabled returns if informational messages, such as the --debug-http traces, are logged
+func (log *consoleLogger) InfoEnabled() bool {
+	return log.Level >= logrus.InfoLevel
+}
+
+// SetSilent disables writing to the console. Logs are still written to the log file.
 func (log *consoleLogger) SetSilent() {
 	log.IsSilent = true
-	log.Out = ioutil.Discard
+	if log.logFile == nil {
+		log.Out = ioutil.Discard
+	}
 }
 
 // Dump does a deep debug dump of a variable
@@ -61,7 +120,7 @@ func (log *consoleLogger) SDump(a ...interface{}) string {
 	return dumpper.Sdump(a...)
 }
 
-// WriteSetting dumps a client setting to stdout
+// WriteSetting dumps a client setting to the log
 func (log *consoleLogger) WriteSetting(setting string, source string, value string) {
 	s := strings.ToLower(setting)
 	if strings.Contains(s, "password") || strings.Contains(s, "key") {
@@ -71,22 +130,67 @@ func (log *consoleLogger) WriteSetting(setting string, source string, value stri
 	log.WriteDebug("%s: %s (%s)", setting, source, value)
 }
 
-// WriteDebug prints debug information to stdout
+// WriteDebug logs debug information
 func (log *consoleLogger) WriteDebug(format string, a ...interface{}) {
 	log.Debugf(format, a...)
 }
 
-// WriteInfo prints text to stdout
+// WriteInfo logs informational text
 func (log *consoleLogger) WriteInfo(format string, a ...interface{}) {
 	log.Infof(format, a...)
 }
 
-// WriteWarning prints highlighted text to stdout
+// WriteWarning logs highlighted text
 func (log *consoleLogger) WriteWarning(format string, a ...interface{}) {
 	log.Warnf(format, a...)
 }
 
-// WriteError prints highlighted text and an error to stderr
+// Warning codes identify the kind of a structured warning, so that scripts can detect it, see WriteStructuredWarning
+const (
+	// WarningCacheUnavailable means the cache couldn't be read, and carina continues without it
+	WarningCacheUnavailable = "cache-unavailable"
+
+	// WarningCachedResults means the results were read from the cache, and may be out of date
+	WarningCachedResults = "cached-results"
+
+	// WarningTemplateConstrained means the template has limited capacity, and clusters created with it may fail
+	WarningTemplateConstrained = "template-constrained"
+
+	// WarningTemplateDeprecated means the template will be removed in the future
+	WarningTemplateDeprecated = "template-deprecated"
+
+	// WarningCredentialsChanged means a cluster's credentials don't match the credentials downloaded previously
+	WarningCredentialsChanged = "credentials-changed"
+
+	// WarningCertificateExpiring means a cluster's certificate expires soon
+	WarningCertificateExpiring = "certificate-expiring"
+
+	// WarningInsecure means the API's certificate isn't verified
+	WarningInsecure = "insecure"
+
+	// WarningHome means CARINA_HOME is unusable or unreliable
+	WarningHome = "home"
+
+	// WarningQuota means a quota is almost or completely used
+	WarningQuota = "quota"
+
+	// WarningPlan is a warning about a change planned with --dry-run
+	WarningPlan = "plan"
+
+	// WarningDeprecated means a flag, command or setting will be removed in the future
+	WarningDeprecated = "deprecated"
+
+	// WarningInterrupted means a wait on a cluster was interrupted, and can be continued with carina resume
+	WarningInterrupted = "interrupted"
+)
+
+// WriteStructuredWarning logs a warning to stderr, never stdout, so that it can't break command substitution, e.g. $(carina env).
+// The code is attached to the log entry as a field, so that scripts can detect the warning with --log-format json.
+func (log *consoleLogger) WriteStructuredWarning(code string, format string, a ...interface{}) {
+	log.WithField("code", code).Warnf("WARNING: "+format, a...)
+}
+
+// WriteError logs highlighted text and an error
 func (log *consoleLogger) WriteError(format string, err error, a ...interface{}) {
 	log.Errorf(format, a...)
 
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogger() *consoleLogger {
+	return &consoleLogger{
+		Logger: &logrus.Logger{
+			Out:       ioutil.Discard,
+			Formatter: &logrus.TextFormatter{},
+			Hooks:     make(logrus.LevelHooks),
+			Level:     logrus.WarnLevel,
+		},
+		ErrorContext: make(map[string]interface{}),
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	log := newTestLogger()
+
+	assert.Nil(t, log.SetLogLevel("debug"))
+	assert.True(t, log.DebugEnabled())
+
+	assert.Nil(t, log.SetLogLevel("error"))
+	assert.Equal(t, logrus.ErrorLevel, log.Level)
+
+	assert.NotNil(t, log.SetLogLevel("loud"))
+}
+
+func TestLogFileWithJSONFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "carina-log")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	log := newTestLogger()
+	path := filepath.Join(dir, "carina.log")
+	assert.Nil(t, log.SetLogFile(path))
+	defer log.logFile.Close()
+	assert.Nil(t, log.SetLogFormat(LogFormatJSON))
+	assert.NotNil(t, log.SetLogFormat("xml"))
+
+	// Silent only quiets the console
+	log.SetSilent()
+	log.WithField("call", 1).Warn("Request: GET /clusters")
+
+	contents, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(contents, &entry))
+	assert.Equal(t, "Request: GET /clusters", entry["msg"])
+	assert.Equal(t, "warning", entry["level"])
+	assert.Equal(t, float64(1), entry["call"])
+}
+
+func TestWriteStructuredWarning(t *testing.T) {
+	var buf bytes.Buffer
+	log := newTestLogger()
+	log.Out = &buf
+	assert.Nil(t, log.SetLogFormat(LogFormatJSON))
+
+	log.WriteStructuredWarning(WarningCacheUnavailable, "Unable to initialize the cache, continuing without it: %s", "permission denied")
+
+	var entry map[string]interface{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "WARNING: Unable to initialize the cache, continuing without it: permission denied", entry["msg"])
+	assert.Equal(t, "warning", entry["level"])
+	assert.Equal(t, WarningCacheUnavailable, entry["code"])
+}
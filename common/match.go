@@ -0,0 +1,121 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/ryanuber/go-glob"
+)
+
+// MatchMode controls how a pattern, such as a name filter, is compared to a value
+type MatchMode string
+
+const (
+	// MatchGlob compares case-insensitively, where * matches any characters, e.g. test-*
+	MatchGlob MatchMode = "glob"
+
+	// MatchRegex compares the whole value to a regular expression, e.g. test-[0-9]+
+	MatchRegex MatchMode = "regex"
+
+	// MatchExact compares the whole value, including its case
+	MatchExact MatchMode = "exact"
+)
+
+// MatchPolicy controls how names are matched by the name filters and batch operations
+type MatchPolicy struct {
+	Mode MatchMode
+}
+
+// NameMatchPolicy is the match policy used by all name filters
+var NameMatchPolicy = &MatchPolicy{Mode: MatchGlob}
+
+// ParseMatchMode validates a match mode, defaulting to glob
+func ParseMatchMode(value string) (MatchMode, error) {
+	switch mode := MatchMode(strings.ToLower(value)); mode {
+	case "":
+		return MatchGlob, nil
+	case MatchGlob, MatchRegex, MatchExact:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("Invalid match mode: %s. Allowed values: %s, %s, %s", value, MatchGlob, MatchRegex, MatchExact)
+	}
+}
+
+// Matcher compares values to a pattern
+type Matcher interface {
+	// Matches returns if the value matches the pattern
+	Matches(value string) bool
+}
+
+// NewMatcher builds a Matcher for the pattern using the policy's match mode
+func (policy *MatchPolicy) NewMatcher(pattern string) (Matcher, error) {
+	return NewMatcher(policy.Mode, pattern)
+}
+
+// IsPattern returns if a name could match more than one value, e.g. test-* when using glob
+func (policy *MatchPolicy) IsPattern(name string) bool {
+	switch policy.Mode {
+	case MatchRegex:
+		// A name without any metacharacters only matches itself
+		return regexp.QuoteMeta(name) != name
+	case MatchExact:
+		return false
+	default:
+		return strings.Contains(name, "*")
+	}
+}
+
+// MatchAllPattern returns the pattern which matches every name, e.g. * when using glob.
+// Returns an error for the exact mode, which can't match more than one name.
+func (policy *MatchPolicy) MatchAllPattern() (string, error) {
+	switch policy.Mode {
+	case MatchRegex:
+		return ".*", nil
+	case MatchExact:
+		return "", fmt.Errorf("No pattern matches every name using the %s match mode", MatchExact)
+	default:
+		return "*", nil
+	}
+}
+
+// NewMatcher builds a Matcher for the pattern
+func NewMatcher(mode MatchMode, pattern string) (Matcher, error) {
+	switch mode {
+	case MatchGlob, "":
+		return globMatcher(pattern), nil
+	case MatchRegex:
+		// Anchor the expression, so that prod doesn't match preprod
+		r, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid regular expression: %s", pattern)
+		}
+		return regexMatcher{r}, nil
+	case MatchExact:
+		return exactMatcher(pattern), nil
+	default:
+		_, err := ParseMatchMode(string(mode))
+		return nil, err
+	}
+}
+
+type globMatcher string
+
+func (pattern globMatcher) Matches(value string) bool {
+	return glob.GlobI(string(pattern), value)
+}
+
+type regexMatcher struct {
+	*regexp.Regexp
+}
+
+func (r regexMatcher) Matches(value string) bool {
+	return r.MatchString(value)
+}
+
+type exactMatcher string
+
+func (pattern exactMatcher) Matches(value string) bool {
+	return string(pattern) == value
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchModes(t *testing.T) {
+	testcases := []struct {
+		mode    MatchMode
+		pattern string
+		value   string
+		matches bool
+	}{
+		{MatchGlob, "test-*", "TEST-1", true},
+		{MatchGlob, "test-*", "prod-1", false},
+		{MatchRegex, "^test-[0-9]+$", "test-12", true},
+		{MatchRegex, "^test-[0-9]+$", "test-a", false},
+		{MatchRegex, "test-[0-9]+", "test-12", true},
+		{MatchRegex, "prod", "prod", true},
+		{MatchRegex, "prod", "preprod", false},
+		{MatchRegex, "prod", "prod-2", false},
+		{MatchRegex, "prod|test", "test", true},
+		{MatchExact, "test-*", "test-1", false},
+		{MatchExact, "test-1", "test-1", true},
+		{MatchExact, "test-1", "TEST-1", false},
+	}
+
+	for _, tc := range testcases {
+		matcher, err := NewMatcher(tc.mode, tc.pattern)
+		if assert.NoError(t, err) {
+			assert.Equal(t, tc.matches, matcher.Matches(tc.value), "%s %s %s", tc.mode, tc.pattern, tc.value)
+		}
+	}
+}
+
+func TestInvalidRegex(t *testing.T) {
+	_, err := NewMatcher(MatchRegex, "test-[")
+	assert.Error(t, err)
+}
+
+func TestIsPattern(t *testing.T) {
+	policy := &MatchPolicy{Mode: MatchRegex}
+	assert.False(t, policy.IsPattern("prod"), "a name without metacharacters should not be a pattern")
+	assert.False(t, policy.IsPattern("prod-2"))
+	assert.True(t, policy.IsPattern("prod-[0-9]+"))
+	assert.True(t, policy.IsPattern("prod.*"))
+}
+
+func TestMatchAllPattern(t *testing.T) {
+	for _, mode := range []MatchMode{MatchGlob, MatchRegex} {
+		policy := &MatchPolicy{Mode: mode}
+		pattern, err := policy.MatchAllPattern()
+		if assert.NoError(t, err) {
+			matcher, _ := policy.NewMatcher(pattern)
+			assert.True(t, matcher.Matches("prod-1"), "%s %s should match every name", mode, pattern)
+		}
+	}
+
+	_, err := (&MatchPolicy{Mode: MatchExact}).MatchAllPattern()
+	assert.Error(t, err)
+}
+
+func TestParseMatchMode(t *testing.T) {
+	mode, err := ParseMatchMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, MatchGlob, mode)
+
+	_, err = ParseMatchMode("fuzzy")
+	assert.Error(t, err)
+}
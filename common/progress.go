@@ -0,0 +1,67 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress reports the status of long-running operations, such as waiting for a cluster to become active
+var Progress = &progressReporter{
+	Out:      os.Stderr,
+	Interval: 30 * time.Second,
+}
+
+type progressReporter struct {
+	// Out is where status lines are written, defaults to stderr so that it doesn't interfere with the command output
+	Out io.Writer
+
+	// Interval is how often the status is repeated while it is unchanged
+	Interval time.Duration
+
+	// lock serializes the status lines of operations tracked concurrently, such as the clusters in a batch
+	lock sync.Mutex
+}
+
+// SetQuiet disables progress reporting
+func (reporter *progressReporter) SetQuiet() {
+	reporter.Out = ioutil.Discard
+}
+
+// Track begins reporting the progress of an operation, e.g. "Waiting for cluster (mycluster) to become active"
+func (reporter *progressReporter) Track(format string, a ...interface{}) *ProgressTracker {
+	return &ProgressTracker{
+		reporter:    reporter,
+		description: fmt.Sprintf(format, a...),
+		started:     time.Now(),
+	}
+}
+
+// ProgressTracker reports the status of a single operation
+type ProgressTracker struct {
+	reporter    *progressReporter
+	description string
+	status      string
+	started     time.Time
+	lastReport  time.Time
+}
+
+// Update records the current status, printing it when the status changes, or periodically while it is unchanged
+func (tracker *ProgressTracker) Update(status string) {
+	now := time.Now()
+	changed := !strings.EqualFold(status, tracker.status)
+	if !changed && now.Sub(tracker.lastReport) < tracker.reporter.Interval {
+		return
+	}
+
+	tracker.status = status
+	tracker.lastReport = now
+	elapsed := now.Sub(tracker.started) / time.Second * time.Second
+	tracker.reporter.lock.Lock()
+	defer tracker.reporter.lock.Unlock()
+	fmt.Fprintf(tracker.reporter.Out, "%s: %s (%s)\n", tracker.description, status, elapsed)
+}
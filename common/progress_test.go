@@ -0,0 +1,36 @@
+package common
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressReportsStatusChanges(t *testing.T) {
+	out := &bytes.Buffer{}
+	reporter := &progressReporter{Out: out, Interval: time.Hour}
+
+	tracker := reporter.Track("Waiting for cluster (%s) to become active", "mycluster")
+	tracker.Update("creating")
+	tracker.Update("creating")
+	tracker.Update("active")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "Waiting for cluster (mycluster) to become active: creating")
+	assert.Contains(t, lines[1], "active")
+}
+
+func TestProgressRepeatsUnchangedStatus(t *testing.T) {
+	out := &bytes.Buffer{}
+	reporter := &progressReporter{Out: out, Interval: 0}
+
+	tracker := reporter.Track("Waiting")
+	tracker.Update("creating")
+	tracker.Update("creating")
+
+	assert.Equal(t, 2, strings.Count(out.String(), "creating"))
+}
@@ -0,0 +1,134 @@
+package common
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is the API rate limit reported by a host in the response headers of its last request
+type RateLimit struct {
+	Host      string
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimitTracker remembers the rate limit reported by each API host, so that polling can be paced to stay within it
+type RateLimitTracker struct {
+	lock   sync.Mutex
+	limits map[string]RateLimit
+}
+
+// APIRateLimits tracks the rate limits reported to the HTTP clients created with NewHTTPClient
+var APIRateLimits = &RateLimitTracker{}
+
+// pollingShare is the fraction of the remaining requests which polling may use, leaving the rest for the other API calls
+const pollingShare = 0.5
+
+// Record saves the rate limit reported by a host
+func (tracker *RateLimitTracker) Record(limit RateLimit) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	if tracker.limits == nil {
+		tracker.limits = make(map[string]RateLimit)
+	}
+	tracker.limits[limit.Host] = limit
+}
+
+// Get returns the rate limit last reported by a host
+func (tracker *RateLimitTracker) Get(host string) (RateLimit, bool) {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	limit, ok := tracker.limits[host]
+	return limit, ok
+}
+
+// PollSpacing returns how far apart the status checks should be, so that the remaining requests last until the rate limit resets.
+// The most constrained host is used, and 0 is returned when no host has reported a rate limit, or the limits have reset.
+func (tracker *RateLimitTracker) PollSpacing() time.Duration {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	var spacing time.Duration
+	now := time.Now()
+	for _, limit := range tracker.limits {
+		if !limit.Reset.After(now) {
+			continue
+		}
+
+		untilReset := limit.Reset.Sub(now)
+		hostSpacing := untilReset
+		if polls := int(float64(limit.Remaining) * pollingShare); polls > 0 {
+			hostSpacing = untilReset / time.Duration(polls)
+		}
+		if hostSpacing > spacing {
+			spacing = hostSpacing
+		}
+	}
+	return spacing
+}
+
+// parseRateLimit reads the rate limit from the X-RateLimit-* or RateLimit-* response headers, returning false when they are missing.
+// The reset header may be either the number of seconds until the limit resets, or when it resets as a unix timestamp.
+func parseRateLimit(response *http.Response, now time.Time) (RateLimit, bool) {
+	header := func(name string) string {
+		if value := response.Header.Get("X-RateLimit-" + name); value != "" {
+			return value
+		}
+		return response.Header.Get("RateLimit-" + name)
+	}
+
+	remaining, err := strconv.Atoi(header("Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	limit := RateLimit{Remaining: remaining}
+	if response.Request != nil && response.Request.URL != nil {
+		limit.Host = response.Request.URL.Host
+	}
+	limit.Limit, _ = strconv.Atoi(header("Limit"))
+
+	if reset, err := strconv.ParseInt(header("Reset"), 10, 64); err == nil && reset >= 0 {
+		// A delta of seconds is much smaller than a timestamp, e.g. 60 vs 1500000000
+		if reset < 1000000000 {
+			limit.Reset = now.Add(time.Duration(reset) * time.Second)
+		} else {
+			limit.Reset = time.Unix(reset, 0)
+		}
+	} else if response.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			limit.Reset = now.Add(time.Duration(seconds) * time.Second)
+		}
+	}
+
+	return limit, true
+}
+
+// rateLimitTransport satisfies the http.RoundTripper interface and records the rate limit reported in each response
+type rateLimitTransport struct {
+	tracker *RateLimitTracker
+	rt      http.RoundTripper
+}
+
+// RoundTrip performs a round-trip HTTP request, recording the rate limit reported by the API
+func (rt *rateLimitTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := rt.rt.RoundTrip(request)
+	if err != nil {
+		return response, err
+	}
+
+	if limit, ok := parseRateLimit(response, time.Now()); ok {
+		rt.tracker.Record(limit)
+		if limit.Reset.IsZero() {
+			Log.WriteDebug("Rate limit (%s): %d of %d requests remaining", limit.Host, limit.Remaining, limit.Limit)
+		} else {
+			Log.WriteDebug("Rate limit (%s): %d of %d requests remaining, resets in %s", limit.Host, limit.Remaining, limit.Limit, time.Until(limit.Reset).Truncate(time.Second))
+		}
+	}
+	return response, err
+}
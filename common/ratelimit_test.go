@@ -0,0 +1,88 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Now()
+	request := &http.Request{URL: &url.URL{Host: "api.dfw.getcarina.com"}}
+
+	response := &http.Response{Request: request, Header: http.Header{}}
+	response.Header.Set("X-RateLimit-Limit", "100")
+	response.Header.Set("X-RateLimit-Remaining", "42")
+	response.Header.Set("X-RateLimit-Reset", "60")
+	limit, ok := parseRateLimit(response, now)
+	if assert.True(t, ok) {
+		assert.Equal(t, RateLimit{Host: "api.dfw.getcarina.com", Limit: 100, Remaining: 42, Reset: now.Add(time.Minute)}, limit)
+	}
+
+	// The reset may be a unix timestamp instead
+	response.Header.Set("X-RateLimit-Reset", "1500000000")
+	limit, _ = parseRateLimit(response, now)
+	assert.Equal(t, time.Unix(1500000000, 0), limit.Reset)
+
+	// The IETF draft headers are also supported
+	response = &http.Response{Request: request, Header: http.Header{}}
+	response.Header.Set("RateLimit-Remaining", "7")
+	limit, ok = parseRateLimit(response, now)
+	assert.True(t, ok)
+	assert.Equal(t, 7, limit.Remaining)
+
+	// 429 Too Many Requests resets after Retry-After
+	response = &http.Response{Request: request, StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	response.Header.Set("X-RateLimit-Remaining", "0")
+	response.Header.Set("Retry-After", "30")
+	limit, _ = parseRateLimit(response, now)
+	assert.Equal(t, now.Add(30*time.Second), limit.Reset)
+
+	_, ok = parseRateLimit(&http.Response{Request: request, Header: http.Header{}}, now)
+	assert.False(t, ok, "Responses without rate limit headers should be ignored")
+}
+
+func TestRateLimitTransportRecordsLimits(t *testing.T) {
+	Log.RegisterTestLogger(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "99")
+		w.Header().Set("X-RateLimit-Reset", "60")
+	}))
+	defer server.Close()
+
+	tracker := &RateLimitTracker{}
+	client := &http.Client{Transport: &rateLimitTransport{tracker: tracker, rt: http.DefaultTransport}}
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	limit, ok := tracker.Get(serverURL.Host)
+	if assert.True(t, ok) {
+		assert.Equal(t, 99, limit.Remaining)
+	}
+}
+
+func TestPollingIsPacedByTheRateLimit(t *testing.T) {
+	tracker := &RateLimitTracker{}
+	assert.Equal(t, time.Duration(0), tracker.PollSpacing(), "Polling shouldn't be paced when no rate limit was reported")
+
+	tracker.Record(RateLimit{Host: "api", Limit: 100, Remaining: 20, Reset: time.Now().Add(time.Minute)})
+	spacing := tracker.PollSpacing()
+	assert.True(t, spacing > 5*time.Second && spacing <= 6*time.Second, "Half of the 20 remaining requests should be spread over the minute, got %s", spacing)
+
+	policy := &WaitPolicy{PollRate: 100, RateLimits: tracker}
+	policy.reservePoll()
+	assert.True(t, policy.reservePoll() > 5*time.Second, "The rate limit should space the polls further apart than the poll rate")
+
+	tracker.Record(RateLimit{Host: "api", Remaining: 0, Reset: time.Now().Add(-time.Second)})
+	assert.Equal(t, time.Duration(0), tracker.PollSpacing(), "A rate limit which has reset should be ignored")
+}
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+)
+
+// settingPattern matches a name and its value in a line of text, such as a TOML setting, a logrus field, a header or a JSON field,
+// which may be escaped when it is embedded in a log message
+var settingPattern = regexp.MustCompile(`([A-Za-z0-9_.-]+)(\\?"?\s*[:=]\s*)(\\?"(?:[^"\\]|\\[^"])*\\?"|[^\s,;"}&/?{\[][^\s,;"}&?]*)`)
+
+// RedactSecrets replaces the values of sensitive settings in text, such as a config file or a log, e.g. apikey = "abc" becomes apikey = "***"
+func RedactSecrets(text string) string {
+	return settingPattern.ReplaceAllStringFunc(text, func(setting string) string {
+		match := settingPattern.FindStringSubmatch(setting)
+		name, separator, value := match[1], match[2], match[3]
+		if !isSensitiveField(name) {
+			return setting
+		}
+
+		quote := ""
+		if strings.HasPrefix(value, `\"`) {
+			quote = `\"`
+		} else if strings.HasPrefix(value, `"`) {
+			quote = `"`
+		}
+		return name + separator + quote + redacted + quote
+	})
+}
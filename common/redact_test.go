@@ -0,0 +1,23 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	testcases := map[string]string{
+		`apikey = "abc123"`:                                                  `apikey = "***"`,
+		`password="ilovepuppies" username="bob"`:                             `password="***" username="bob"`,
+		`X-Auth-Token: abc123`:                                               `X-Auth-Token: ***`,
+		`msg="Request Options: {\"password\": \"ilovepuppies\"}"`:            `msg="Request Options: {\"password\": \"***\"}"`,
+		`{"apiKeyCredentials":{"apiKey":"abc123","username":"bob"}}`:         `{"apiKeyCredentials":{"apiKey":"***","username":"bob"}}`,
+		`level=debug msg="Request ID: req-123" requestID=req-123 status=500`: `level=debug msg="Request ID: req-123" requestID=req-123 status=500`,
+		`https://example.com/v2.0/tokens?token=abc123&limit=5`:               `https://example.com/v2.0/tokens?token=***&limit=5`,
+	}
+
+	for text, want := range testcases {
+		assert.Equal(t, want, RedactSecrets(text), text)
+	}
+}
@@ -0,0 +1,211 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	mathrand "math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how requests that fail with a transient API error are retried
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times a request is retried, 0 disables retries
+	MaxRetries int
+
+	// BaseWait is the initial amount of time to wait before retrying, which doubles on each attempt
+	BaseWait time.Duration
+
+	// MaxWait is the maximum amount of time to wait between attempts
+	MaxWait time.Duration
+}
+
+// HTTPRetryPolicy is the retry policy used by the HTTP clients created with NewHTTPClient
+var HTTPRetryPolicy = &RetryPolicy{
+	MaxRetries: 3,
+	BaseWait:   1 * time.Second,
+	MaxWait:    30 * time.Second,
+}
+
+// isTransientStatus returns if a response status code indicates that the request may succeed if retried.
+// A POST, such as creating a cluster, is only retried when the server didn't act on it, so that it isn't repeated
+// by an API which ignores the idempotency key.
+func isTransientStatus(method string, statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError,
+		http.StatusBadGateway:
+		return method != http.MethodPost
+	default:
+		return false
+	}
+}
+
+// isConnectionError returns if the request failed to connect to the server, before anything was sent,
+// so that it is safe to retry any request
+func isConnectionError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && (opErr.Op == "dial" || opErr.Op == "proxyconnect")
+}
+
+// backoff calculates how long to wait before the next attempt, using exponential backoff with full jitter.
+// When the server specifies a Retry-After header, it is honored as long as it doesn't exceed MaxWait.
+func (policy *RetryPolicy) backoff(attempt int, response *http.Response) time.Duration {
+	// There is no response when the request failed to connect
+	if response != nil {
+		if retryAfter := response.Header.Get("Retry-After"); retryAfter != "" {
+			if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+				wait := time.Duration(seconds) * time.Second
+				if wait > policy.MaxWait {
+					wait = policy.MaxWait
+				}
+				return wait
+			}
+		}
+	}
+
+	wait := policy.BaseWait << uint(attempt)
+	if wait <= 0 || wait > policy.MaxWait {
+		wait = policy.MaxWait
+	}
+	if wait <= 0 {
+		return 0
+	}
+
+	return time.Duration(mathrand.Int63n(int64(wait) + 1))
+}
+
+// IdempotencyKeyHeader is sent with POST requests, such as creating a cluster, so that an API which honors it
+// can recognize a retried request and not repeat the operation
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// newIdempotencyKey generates a random key which identifies a request across retries
+func newIdempotencyKey() string {
+	key := make([]byte, 16)
+	_, err := rand.Read(key)
+	if err != nil {
+		// Fall back to a less random key, it only needs to be unique for this account's recent requests
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(key)
+}
+
+// requestTimeout returns how long a request may take, including its retries and reading the response,
+// when each attempt may take up to attemptTimeout
+func (policy *RetryPolicy) requestTimeout(attemptTimeout time.Duration) time.Duration {
+	return time.Duration(policy.MaxRetries+1)*attemptTimeout + time.Duration(policy.MaxRetries)*policy.MaxWait
+}
+
+// retryTransport satisfies the http.RoundTripper interface and retries requests
+// which fail with a transient error, such as 503 Service Unavailable, or can't connect to the server.
+type retryTransport struct {
+	policy *RetryPolicy
+	rt     http.RoundTripper
+}
+
+// RoundTrip performs a round-trip HTTP request, retrying when a transient error is returned.
+func (rt *retryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	// Buffer the request body so that it can be replayed on each attempt
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Send the same key on every attempt, so that a retry can't repeat the operation
+	if request.Method == http.MethodPost && request.Header.Get(IdempotencyKeyHeader) == "" {
+		key := newIdempotencyKey()
+		request = copyRequest(request, nil)
+		request.Header.Set(IdempotencyKeyHeader, key)
+		Log.WriteDebug("%s: %s", IdempotencyKeyHeader, key)
+	}
+
+	// Stop on either the caller's cancellation or shutdown, the context is released once the response body is closed
+	ctx, cancel := WithShutdown(request.Context())
+	for attempt := 0; ; attempt++ {
+		response, err := rt.rt.RoundTrip(copyRequest(request, body).WithContext(ctx))
+		if attempt >= rt.policy.MaxRetries || ctx.Err() != nil {
+			return cancelOnClose(response, cancel), err
+		}
+		if err != nil {
+			if !isConnectionError(err) {
+				cancel()
+				return nil, err
+			}
+		} else if !isTransientStatus(request.Method, response.StatusCode) {
+			return cancelOnClose(response, cancel), nil
+		}
+
+		wait := rt.policy.backoff(attempt, response)
+		if err != nil {
+			Log.WriteDebug("Unable to connect: %s, retrying in %s (attempt %d of %d)", err, wait, attempt+1, rt.policy.MaxRetries)
+		} else {
+			Log.WriteDebug("Received %s, retrying in %s (attempt %d of %d)", response.Status, wait, attempt+1, rt.policy.MaxRetries)
+
+			// Drain the response so that the connection can be reused
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+}
+
+// cancelOnClose releases the request's context once the response body is closed, or immediately when there isn't a response
+func cancelOnClose(response *http.Response, cancel context.CancelFunc) *http.Response {
+	if response == nil || response.Body == nil {
+		cancel()
+		return response
+	}
+	response.Body = &cancelBody{ReadCloser: response.Body, cancel: cancel}
+	return response
+}
+
+// cancelBody is a response body which releases the request's context when it is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body *cancelBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
+
+// copyRequest makes a shallow copy of a request, with its own headers and a fresh body, so that it is safe to send again
+func copyRequest(request *http.Request, body []byte) *http.Request {
+	r := new(http.Request)
+	*r = *request
+
+	r.Header = make(http.Header, len(request.Header))
+	for key, values := range request.Header {
+		r.Header[key] = append([]string(nil), values...)
+	}
+
+	if body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
+
+	return r
+}
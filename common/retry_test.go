@@ -0,0 +1,189 @@
+package common
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryTransientErrors(t *testing.T) {
+	Log.RegisterTestLogger(t)
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, "payload", string(body), "The request body should be resent on each attempt")
+
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	rt := &retryTransport{
+		policy: &RetryPolicy{MaxRetries: 3, BaseWait: time.Millisecond, MaxWait: 5 * time.Millisecond},
+		rt:     http.DefaultTransport,
+	}
+	client := &http.Client{Transport: rt}
+
+	response, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryGivesUpAfterMaxRetries(t *testing.T) {
+	Log.RegisterTestLogger(t)
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	rt := &retryTransport{
+		policy: &RetryPolicy{MaxRetries: 2, BaseWait: time.Millisecond, MaxWait: 5 * time.Millisecond},
+		rt:     http.DefaultTransport,
+	}
+	client := &http.Client{Transport: rt}
+
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetrySkipsPermanentErrors(t *testing.T) {
+	Log.RegisterTestLogger(t)
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	rt := &retryTransport{
+		policy: &RetryPolicy{MaxRetries: 3, BaseWait: time.Millisecond, MaxWait: 5 * time.Millisecond},
+		rt:     http.DefaultTransport,
+	}
+	client := &http.Client{Transport: rt}
+
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRetrySendsTheSameIdempotencyKey(t *testing.T) {
+	Log.RegisterTestLogger(t)
+
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if len(keys) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	rt := &retryTransport{
+		policy: &RetryPolicy{MaxRetries: 3, BaseWait: time.Millisecond, MaxWait: 5 * time.Millisecond},
+		rt:     http.DefaultTransport,
+	}
+	client := &http.Client{Transport: rt}
+
+	response, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	assert.Len(t, keys, 2)
+	assert.NotEmpty(t, keys[0], "POST requests should have an idempotency key")
+	assert.Equal(t, keys[0], keys[1], "Retries should reuse the idempotency key")
+}
+
+func TestRetryPostOnlyWhenNotProcessed(t *testing.T) {
+	Log.RegisterTestLogger(t)
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	rt := &retryTransport{
+		policy: &RetryPolicy{MaxRetries: 3, BaseWait: time.Millisecond, MaxWait: 5 * time.Millisecond},
+		rt:     http.DefaultTransport,
+	}
+	client := &http.Client{Transport: rt}
+
+	response, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	assert.Equal(t, 1, attempts, "A POST which may have been processed should not be retried")
+
+	attempts = 0
+	response, err = client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	assert.Equal(t, 4, attempts, "A GET should be retried after a 500")
+}
+
+func TestRetryConnectionErrors(t *testing.T) {
+	Log.RegisterTestLogger(t)
+
+	// Find an address which refuses connections
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	address := server.URL
+	server.Close()
+
+	var attempts int
+	rt := &retryTransport{
+		policy: &RetryPolicy{MaxRetries: 2, BaseWait: time.Millisecond, MaxWait: 5 * time.Millisecond},
+		rt: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			attempts++
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+	client := &http.Client{Transport: rt}
+
+	_, err := client.Post(address, "application/json", strings.NewReader("{}"))
+	assert.Error(t, err)
+	assert.Equal(t, 3, attempts, "A request which couldn't connect should be retried")
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
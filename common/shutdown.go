@@ -0,0 +1,64 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrShuttingDown is returned by pending operations which were cancelled because the cli is shutting down
+var ErrShuttingDown = errors.New("The operation was cancelled because carina is shutting down")
+
+var shutdownContext, cancelShutdown = context.WithCancel(context.Background())
+
+// ShutdownContext returns a context which is cancelled when the cli is shutting down, e.g. after receiving SIGINT
+func ShutdownContext() context.Context {
+	return shutdownContext
+}
+
+// WithShutdown derives a context from the parent which is also cancelled when the cli begins shutting down,
+// so that an operation stops on either the caller's cancellation or shutdown. Call cancel once the operation is done.
+func WithShutdown(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	shutdown := shutdownContext
+	go func() {
+		select {
+		case <-shutdown.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// BeginShutdown cancels all pending operations
+func BeginShutdown() {
+	cancelShutdown()
+}
+
+// IsShuttingDown returns if pending operations have been cancelled
+func IsShuttingDown() bool {
+	return shutdownContext.Err() != nil
+}
+
+// Sleep pauses for the specified duration, returning ErrShuttingDown early if the cli begins shutting down
+func Sleep(duration time.Duration) error {
+	return sleepContext(shutdownContext, duration)
+}
+
+// sleepContext pauses for the specified duration, returning early when the context is cancelled,
+// with ErrShuttingDown if the cli began shutting down
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		if IsShuttingDown() {
+			return ErrShuttingDown
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetShutdown undoes BeginShutdown, so that a test can shut down without affecting the other tests
+func resetShutdown() {
+	shutdownContext, cancelShutdown = context.WithCancel(context.Background())
+}
+
+func TestWithShutdownKeepsTheCallersCancellation(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := WithShutdown(parent)
+	defer cancel()
+
+	cancelParent()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Cancelling the caller's context should cancel the operation")
+	}
+	assert.Equal(t, context.Canceled, sleepContext(ctx, time.Hour))
+}
+
+func TestWithShutdownCancelledByShutdown(t *testing.T) {
+	defer resetShutdown()
+
+	ctx, cancel := WithShutdown(context.Background())
+	defer cancel()
+
+	BeginShutdown()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Shutting down should cancel the operation")
+	}
+	assert.Equal(t, ErrShuttingDown, sleepContext(ctx, time.Hour))
+}
+
+func TestRetryStopsWhenTheCallerCancels(t *testing.T) {
+	Log.RegisterTestLogger(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	rt := &retryTransport{
+		policy: &RetryPolicy{MaxRetries: 3, BaseWait: time.Hour, MaxWait: time.Hour},
+		rt:     http.DefaultTransport,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request, err := http.NewRequest("GET", server.URL, nil)
+	require.NoError(t, err)
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	started := time.Now()
+	_, err = rt.RoundTrip(request.WithContext(ctx))
+	assert.Equal(t, context.Canceled, err, "The retry should stop waiting when the caller cancels the request")
+	assert.True(t, time.Since(started) < time.Minute)
+}
+
+func TestRetryResponseBodyOutlivesTheRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("clusters"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &retryTransport{policy: &RetryPolicy{}, rt: http.DefaultTransport}}
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err, "The request's context shouldn't be released until the body is closed")
+	assert.Equal(t, "clusters", string(body))
+}
@@ -0,0 +1,144 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxTemplateSuggestions is how many of the closest templates are suggested when a template isn't found
+const maxTemplateSuggestions = 5
+
+// TemplateNotFoundError indicates that no template matched a name or pattern, and suggests the closest templates instead
+type TemplateNotFoundError struct {
+	TemplatePattern string
+
+	// Suggestions are the templates whose names are closest to the pattern, closest first
+	Suggestions []ClusterTemplate
+}
+
+// Error returns the underlying error message
+func (error *TemplateNotFoundError) Error() string {
+	if len(error.Suggestions) == 0 {
+		return fmt.Sprintf("Could not find template named %s. Run carina templates to see the available templates", error.TemplatePattern)
+	}
+
+	suggestions := make([]string, len(error.Suggestions))
+	for i, template := range error.Suggestions {
+		suggestions[i] = DescribeTemplate(template)
+	}
+	return fmt.Sprintf("Could not find template named %s. Did you mean:\n  %s", error.TemplatePattern, strings.Join(suggestions, "\n  "))
+}
+
+// NewTemplateNotFoundError returns a NotFoundError for a template pattern, suggesting the closest of the available templates
+func NewTemplateNotFoundError(pattern string, templates []ClusterTemplate) error {
+	return NotFoundError{Err: &TemplateNotFoundError{TemplatePattern: pattern, Suggestions: SuggestTemplates(pattern, templates)}}
+}
+
+// DescribeTemplate returns the template name with its COE and host type, e.g. Kubernetes 1.5.2 on LXC (kubernetes, lxc)
+func DescribeTemplate(template ClusterTemplate) string {
+	var details []string
+	for _, detail := range []string{template.GetCOE(), template.GetHostType()} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if template.IsDeprecated() {
+		details = append(details, "deprecated")
+	}
+	if len(details) == 0 {
+		return template.GetName()
+	}
+	return fmt.Sprintf("%s (%s)", template.GetName(), strings.Join(details, ", "))
+}
+
+// SuggestTemplates ranks the templates by how closely their names match the pattern, ignoring case and wildcards,
+// returning the closest few. Templates which are too different from the pattern to be a likely typo are left out.
+func SuggestTemplates(pattern string, templates []ClusterTemplate) []ClusterTemplate {
+	search := strings.ToLower(strings.Map(func(r rune) rune {
+		if strings.ContainsRune("*?[]", r) {
+			return -1
+		}
+		return r
+	}, pattern))
+	if search == "" {
+		return nil
+	}
+
+	// Allow roughly one typo for every three characters
+	maxDistance := len(search) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	type candidate struct {
+		template ClusterTemplate
+		distance int
+	}
+	var candidates []candidate
+	for _, template := range templates {
+		distance := substringDistance(search, strings.ToLower(template.GetName()))
+		if distance <= maxDistance {
+			candidates = append(candidates, candidate{template, distance})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		// Prefer current templates to deprecated ones, then shorter names which are closer to the whole pattern
+		if candidates[i].template.IsDeprecated() != candidates[j].template.IsDeprecated() {
+			return !candidates[i].template.IsDeprecated()
+		}
+		ni, nj := candidates[i].template.GetName(), candidates[j].template.GetName()
+		if len(ni) != len(nj) {
+			return len(ni) < len(nj)
+		}
+		return ni < nj
+	})
+
+	if len(candidates) > maxTemplateSuggestions {
+		candidates = candidates[:maxTemplateSuggestions]
+	}
+	suggestions := make([]ClusterTemplate, len(candidates))
+	for i, c := range candidates {
+		suggestions[i] = c.template
+	}
+	return suggestions
+}
+
+// substringDistance returns the Levenshtein distance between the search and the part of the text which it is closest to,
+// so that a short search such as kubernets is close to Kubernetes 1.5.2 on LXC
+func substringDistance(search string, text string) int {
+	s, t := []rune(search), []rune(text)
+
+	// The search can start anywhere in the text for free
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j-1]+cost, minInt(previous[j]+1, current[j-1]+1))
+		}
+		previous, current = current, previous
+	}
+
+	// ... and end anywhere in the text for free
+	distance := previous[0]
+	for _, d := range previous {
+		distance = minInt(distance, d)
+	}
+	return distance
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
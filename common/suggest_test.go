@@ -0,0 +1,37 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type suggestedTemplate struct {
+	selectorTemplate
+	deprecated bool
+}
+
+func (template *suggestedTemplate) GetName() string    { return template.name }
+func (template *suggestedTemplate) IsDeprecated() bool { return template.deprecated }
+
+func TestSuggestTemplates(t *testing.T) {
+	k8s := &suggestedTemplate{selectorTemplate: selectorTemplate{name: "Kubernetes 1.5.2 on LXC", coe: "kubernetes", host: "lxc"}}
+	oldK8s := &suggestedTemplate{selectorTemplate: selectorTemplate{name: "Kubernetes 1.4.5 on LXC", coe: "kubernetes", host: "lxc"}, deprecated: true}
+	swarm := &suggestedTemplate{selectorTemplate: selectorTemplate{name: "Swarm 1.11.2 on VM", coe: "swarm", host: "vm"}}
+	templates := []ClusterTemplate{oldK8s, swarm, k8s}
+
+	suggestions := SuggestTemplates("Kubernets*", templates)
+	assert.Equal(t, []ClusterTemplate{k8s, oldK8s}, suggestions, "Current templates should be suggested before deprecated ones")
+	assert.Equal(t, []ClusterTemplate{swarm}, SuggestTemplates("swrm 1.11", templates))
+	assert.Empty(t, SuggestTemplates("mesos", templates))
+
+	err := NewTemplateNotFoundError("Kubernets*", templates)
+	assert.IsType(t, NotFoundError{}, err)
+	assert.Contains(t, err.Error(), "Kubernetes 1.4.5 on LXC (kubernetes, lxc, deprecated)")
+}
+
+func TestSubstringDistance(t *testing.T) {
+	assert.Equal(t, 0, substringDistance("swarm", "swarm 1.11.2 on vm"))
+	assert.Equal(t, 1, substringDistance("kubernets", "kubernetes 1.5.2 on lxc"))
+	assert.Equal(t, 2, substringDistance("kuberentes", "kubernetes"))
+}
@@ -0,0 +1,155 @@
+package common
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The categories of time recorded with --timing
+const (
+	// TimingAuth is the time spent authenticating, i.e. requesting or validating a token
+	TimingAuth = "auth"
+
+	// TimingAPI is the time spent calling the cluster API
+	TimingAPI = "api"
+
+	// TimingPolling is the time spent sleeping between cluster status checks while waiting
+	TimingPolling = "polling"
+)
+
+// TimingRecorder counts the API calls made by a command and how long they took, and how long was spent
+// waiting between status checks, so that --timing can show where the time went
+type TimingRecorder struct {
+	// Enabled records the timings, otherwise nothing is recorded
+	Enabled bool
+
+	lock    sync.Mutex
+	started time.Time
+	entries map[timingKey]*TimingEntry
+}
+
+type timingKey struct {
+	category string
+	method   string
+}
+
+// TimingEntry is the number of calls and time spent for a category and HTTP method
+type TimingEntry struct {
+	Category string
+
+	// Method is the HTTP method of the API calls, and is empty for polling
+	Method string
+
+	Count  int
+	Errors int
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// Mean is the average duration of the calls
+func (entry TimingEntry) Mean() time.Duration {
+	if entry.Count == 0 {
+		return 0
+	}
+	return entry.Total / time.Duration(entry.Count)
+}
+
+// TimingSummary is the time spent by a command, broken down by category
+type TimingSummary struct {
+	// Elapsed is the wall clock time since the recorder was started
+	Elapsed time.Duration
+
+	// Entries are sorted by category then method. The totals are cumulative, so concurrent operations may add up to more than Elapsed.
+	Entries []TimingEntry
+}
+
+// Timing records the timings of the current command
+var Timing = &TimingRecorder{}
+
+// Start enables the recorder, discarding anything recorded previously
+func (recorder *TimingRecorder) Start() {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	recorder.Enabled = true
+	recorder.started = time.Now()
+	recorder.entries = make(map[timingKey]*TimingEntry)
+}
+
+// RecordRequest records the duration of an attempt at an API call. A failed attempt has a status code of 0, or >= 400.
+func (recorder *TimingRecorder) RecordRequest(request *http.Request, statusCode int, duration time.Duration) {
+	category := TimingAPI
+	if isAuthRequest(request) {
+		category = TimingAuth
+	}
+	recorder.record(category, request.Method, duration, statusCode == 0 || statusCode >= 400)
+}
+
+// RecordPolling records the time spent sleeping before checking a cluster's status again
+func (recorder *TimingRecorder) RecordPolling(duration time.Duration) {
+	recorder.record(TimingPolling, "", duration, false)
+}
+
+func (recorder *TimingRecorder) record(category string, method string, duration time.Duration, failed bool) {
+	if !recorder.Enabled {
+		return
+	}
+
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	key := timingKey{category: category, method: method}
+	entry, ok := recorder.entries[key]
+	if !ok {
+		entry = &TimingEntry{Category: category, Method: method}
+		recorder.entries[key] = entry
+	}
+
+	entry.Count++
+	entry.Total += duration
+	if duration > entry.Max {
+		entry.Max = duration
+	}
+	if failed {
+		entry.Errors++
+	}
+}
+
+// Summary returns the timings recorded since the recorder was started
+func (recorder *TimingRecorder) Summary() TimingSummary {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	summary := TimingSummary{Elapsed: time.Since(recorder.started)}
+	for _, entry := range recorder.entries {
+		summary.Entries = append(summary.Entries, *entry)
+	}
+	sort.Slice(summary.Entries, func(i, j int) bool {
+		a, b := summary.Entries[i], summary.Entries[j]
+		if a.Category != b.Category {
+			return timingCategoryOrder(a.Category) < timingCategoryOrder(b.Category)
+		}
+		return a.Method < b.Method
+	})
+	return summary
+}
+
+// timingCategoryOrder lists authentication first, then the API calls and then polling
+func timingCategoryOrder(category string) int {
+	switch category {
+	case TimingAuth:
+		return 0
+	case TimingAPI:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// isAuthRequest identifies calls to the identity service, such as requesting a token
+func isAuthRequest(request *http.Request) bool {
+	return strings.Contains(request.URL.Path, "tokens")
+}
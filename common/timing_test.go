@@ -0,0 +1,37 @@
+package common
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimingRecorder(t *testing.T) {
+	recorder := &TimingRecorder{}
+	recorder.Start()
+
+	auth, _ := http.NewRequest("POST", "https://identity.example.com/v2.0/tokens", nil)
+	list, _ := http.NewRequest("GET", "https://api.example.com/clusters", nil)
+	recorder.RecordRequest(auth, 200, 300*time.Millisecond)
+	recorder.RecordRequest(list, 200, 100*time.Millisecond)
+	recorder.RecordRequest(list, 503, 300*time.Millisecond)
+	recorder.RecordPolling(10 * time.Second)
+
+	summary := recorder.Summary()
+	assert.Equal(t, []TimingEntry{
+		{Category: TimingAuth, Method: "POST", Count: 1, Total: 300 * time.Millisecond, Max: 300 * time.Millisecond},
+		{Category: TimingAPI, Method: "GET", Count: 2, Errors: 1, Total: 400 * time.Millisecond, Max: 300 * time.Millisecond},
+		{Category: TimingPolling, Count: 1, Total: 10 * time.Second, Max: 10 * time.Second},
+	}, summary.Entries)
+	assert.Equal(t, 200*time.Millisecond, summary.Entries[1].Mean())
+}
+
+func TestTimingRecorderDisabled(t *testing.T) {
+	recorder := &TimingRecorder{}
+	recorder.RecordPolling(time.Second)
+
+	recorder.Start()
+	assert.Empty(t, recorder.Summary().Entries)
+}
@@ -0,0 +1,93 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TransportPolicy controls how the HTTP clients created with NewHTTPClient connect to the APIs,
+// such as through a corporate proxy which intercepts TLS
+type TransportPolicy struct {
+	// InsecureSkipVerify disables verifying the API's certificate
+	InsecureSkipVerify bool
+
+	// proxyURL overrides the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables, when set
+	proxyURL *url.URL
+
+	// rootCAs are the trusted certificate authorities, when a custom CA is used. When nil, the system CAs are used.
+	rootCAs *x509.CertPool
+}
+
+// HTTPTransportPolicy is the transport policy used by the HTTP clients created with NewHTTPClient
+var HTTPTransportPolicy = &TransportPolicy{}
+
+// SetProxy sends all API requests through a proxy, e.g. http://proxy.example.com:3128
+func (policy *TransportPolicy) SetProxy(proxy string) error {
+	if proxy == "" {
+		policy.proxyURL = nil
+		return nil
+	}
+
+	// Accept a bare host:port, like curl
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		return fmt.Errorf("Invalid --proxy %s. Use the format http://host:port", proxy)
+	}
+	policy.proxyURL = proxyURL
+	return nil
+}
+
+// AddCACert trusts the PEM encoded certificate authorities in a file, in addition to the system CAs
+func (policy *TransportPolicy) AddCACert(path string) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "Unable to read the --cacert %s", path)
+	}
+
+	if policy.rootCAs == nil {
+		policy.rootCAs, err = x509.SystemCertPool()
+		if err != nil {
+			Log.WriteDebug("Unable to load the system CAs, only trusting --cacert: %s", err)
+			policy.rootCAs = x509.NewCertPool()
+		}
+	}
+
+	if !policy.rootCAs.AppendCertsFromPEM(contents) {
+		return fmt.Errorf("Invalid --cacert %s, it does not contain any PEM encoded certificates", path)
+	}
+	return nil
+}
+
+// proxy selects the proxy for a request, either from --proxy or the environment
+func (policy *TransportPolicy) proxy(request *http.Request) (*url.URL, error) {
+	if policy.proxyURL != nil {
+		return policy.proxyURL, nil
+	}
+	return http.ProxyFromEnvironment(request)
+}
+
+// tlsConfig builds the TLS settings used to connect to the APIs
+func (policy *TransportPolicy) tlsConfig() *tls.Config {
+	return &tls.Config{
+		RootCAs:            policy.rootCAs,
+		InsecureSkipVerify: policy.InsecureSkipVerify,
+	}
+}
+
+// verifiedTLSConfig builds the TLS settings like tlsConfig, but always verifies the server's certificate
+func (policy *TransportPolicy) verifiedTLSConfig() *tls.Config {
+	return &tls.Config{
+		RootCAs: policy.rootCAs,
+	}
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransportPolicy_SetProxy(t *testing.T) {
+	policy := &TransportPolicy{}
+
+	err := policy.SetProxy("proxy.example.com:3128")
+	assert.Nil(t, err)
+
+	request, _ := http.NewRequest("GET", "https://api.getcarina.com/clusters", nil)
+	proxyURL, err := policy.proxy(request)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://proxy.example.com:3128", proxyURL.String())
+
+	err = policy.SetProxy("http://")
+	assert.NotNil(t, err)
+}
+
+func TestTransportPolicy_AddCACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "carina-transport")
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	ioutil.WriteFile(caFile, ca, 0600)
+
+	policy := &TransportPolicy{}
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: policy.tlsConfig()}}
+	_, err := httpClient.Get(server.URL)
+	assert.NotNil(t, err, "The test server's certificate should not be trusted by default")
+
+	err = policy.AddCACert(caFile)
+	assert.Nil(t, err)
+	httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: policy.tlsConfig()}}
+	response, err := httpClient.Get(server.URL)
+	assert.Nil(t, err)
+	if err == nil {
+		assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	}
+
+	ioutil.WriteFile(caFile, []byte("not a certificate"), 0600)
+	err = policy.AddCACert(caFile)
+	assert.NotNil(t, err)
+}
+
+func TestTransportPolicy_VerifiedTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	policy := &TransportPolicy{InsecureSkipVerify: true}
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: policy.tlsConfig()}}
+	_, err := httpClient.Get(server.URL)
+	assert.Nil(t, err, "--insecure-skip-verify should skip verifying the certificate")
+
+	httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: policy.verifiedTLSConfig()}}
+	_, err = httpClient.Get(server.URL)
+	assert.NotNil(t, err, "Verified downloads should ignore --insecure-skip-verify")
+}
@@ -0,0 +1,204 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WaitPolicy controls how the backends poll a cluster while waiting for an operation to complete
+type WaitPolicy struct {
+	// PollInterval overrides how often the cluster status is checked, 0 uses the backend's default interval
+	PollInterval time.Duration
+
+	// Timeout is the maximum amount of time to wait for an operation, 0 waits forever
+	Timeout time.Duration
+
+	// PollRate is the maximum number of status checks per second, shared by all of the operations waiting concurrently.
+	// This keeps the load on the API bounded when waiting on many clusters, 0 doesn't limit the rate.
+	PollRate float64
+
+	// RateLimits are the API rate limits, which slow down the status checks further when the API reports that few requests remain
+	RateLimits *RateLimitTracker
+
+	throttle sync.Mutex
+	nextPoll time.Time
+}
+
+// defaultPollRate is the maximum number of status checks per second across all clusters being waited on
+const defaultPollRate = 2
+
+// ClusterWaitPolicy is the wait policy used by all backends
+var ClusterWaitPolicy = &WaitPolicy{PollRate: defaultPollRate, RateLimits: APIRateLimits}
+
+// TimeoutError is returned when an operation did not complete before the wait timeout
+type TimeoutError struct {
+	Description string
+	Timeout     time.Duration
+	Status      string
+}
+
+// Error returns the error message
+func (err TimeoutError) Error() string {
+	if err.Status == "" {
+		return fmt.Sprintf("Timed out after %s: %s", err.Timeout, err.Description)
+	}
+	return fmt.Sprintf("Timed out after %s: %s, currently in %s", err.Timeout, err.Description, err.Status)
+}
+
+// CheckTimeout returns a TimeoutError when an operation which started at the specified time has exceeded the timeout
+func (policy *WaitPolicy) CheckTimeout(started time.Time, description string, status string) error {
+	if policy.Timeout > 0 && time.Since(started) >= policy.Timeout {
+		return TimeoutError{Description: description, Timeout: policy.Timeout, Status: status}
+	}
+	return nil
+}
+
+// reservePoll reserves the next available slot to check a cluster status, returning how long to wait until the slot.
+// The slots are handed out in order and spaced evenly, so concurrent waiters take turns instead of polling at the same time.
+// The slots are spaced further apart when the API reports that it is running out of requests, see RateLimitTracker.PollSpacing.
+func (policy *WaitPolicy) reservePoll() time.Duration {
+	var spacing time.Duration
+	if policy.PollRate > 0 {
+		spacing = time.Duration(float64(time.Second) / policy.PollRate)
+	}
+	if policy.RateLimits != nil {
+		if paced := policy.RateLimits.PollSpacing(); paced > spacing {
+			Log.WriteDebug("Pacing the status checks %s apart to stay within the API rate limit", paced.Truncate(time.Millisecond))
+			spacing = paced
+		}
+	}
+	if spacing <= 0 {
+		return 0
+	}
+
+	policy.throttle.Lock()
+	defer policy.throttle.Unlock()
+
+	now := time.Now()
+	slot := policy.nextPoll
+	if slot.Before(now) {
+		slot = now
+	}
+	policy.nextPoll = slot.Add(spacing)
+	return slot.Sub(now)
+}
+
+// Throttle pauses until it's this caller's turn to check a cluster status, when other operations are also waiting
+func (policy *WaitPolicy) Throttle() error {
+	delay := policy.reservePoll()
+	if delay <= 0 {
+		return nil
+	}
+
+	Log.WriteDebug("Delaying the status check by %s to limit the polling rate", delay)
+	return Sleep(delay)
+}
+
+// Waiter polls a cluster until an operation completes, reporting progress and enforcing the wait policy
+type Waiter struct {
+	policy          *WaitPolicy
+	defaultInterval time.Duration
+	description     string
+	status          string
+	started         time.Time
+	progress        *ProgressTracker
+}
+
+// NewWaiter starts waiting on an operation, e.g. "Waiting for cluster (mycluster) to become active",
+// polling at the default interval unless it is overridden by the wait policy
+func NewWaiter(defaultInterval time.Duration, format string, a ...interface{}) *Waiter {
+	description := fmt.Sprintf(format, a...)
+	return &Waiter{
+		policy:          ClusterWaitPolicy,
+		defaultInterval: defaultInterval,
+		description:     description,
+		started:         time.Now(),
+		progress:        Progress.Track("%s", description),
+	}
+}
+
+// Update records the latest cluster status
+func (waiter *Waiter) Update(status string) {
+	waiter.status = status
+	waiter.progress.Update(status)
+}
+
+// UpdateCluster records the latest cluster status, and notifies the observers of the cluster, see ObserveClusterStatus
+func (waiter *Waiter) UpdateCluster(cluster Cluster) {
+	waiter.Update(cluster.GetStatus())
+	notifyStatusObservers(cluster)
+}
+
+// statusObserver is called with each status of a cluster observed while waiting
+type statusObserver struct {
+	clusterID string
+	observe   func(Cluster)
+}
+
+var statusObservers = struct {
+	sync.Mutex
+	observers map[*statusObserver]bool
+}{observers: make(map[*statusObserver]bool)}
+
+// ObserveClusterStatus calls observe with the cluster each time its status is checked while waiting,
+// until the returned function is called
+func ObserveClusterStatus(clusterID string, observe func(Cluster)) (stop func()) {
+	observer := &statusObserver{clusterID: clusterID, observe: observe}
+
+	statusObservers.Lock()
+	statusObservers.observers[observer] = true
+	statusObservers.Unlock()
+
+	return func() {
+		statusObservers.Lock()
+		delete(statusObservers.observers, observer)
+		statusObservers.Unlock()
+	}
+}
+
+func notifyStatusObservers(cluster Cluster) {
+	var observers []*statusObserver
+	statusObservers.Lock()
+	for observer := range statusObservers.observers {
+		if observer.clusterID == cluster.GetID() {
+			observers = append(observers, observer)
+		}
+	}
+	statusObservers.Unlock()
+
+	for _, observer := range observers {
+		observer.observe(cluster)
+	}
+}
+
+// Wait pauses until the cluster should be polled again, returning a TimeoutError when the timeout is exceeded
+func (waiter *Waiter) Wait() error {
+	err := waiter.policy.CheckTimeout(waiter.started, waiter.description, waiter.status)
+	if err != nil {
+		return err
+	}
+
+	interval := waiter.defaultInterval
+	if waiter.policy.PollInterval > 0 {
+		interval = waiter.policy.PollInterval
+	}
+
+	// Don't sleep past the timeout, so that the cluster is polled one last time at the deadline
+	if waiter.policy.Timeout > 0 {
+		remaining := waiter.policy.Timeout - time.Since(waiter.started)
+		if remaining < interval {
+			interval = remaining
+		}
+	}
+
+	start := time.Now()
+	defer func() { Timing.RecordPolling(time.Since(start)) }()
+
+	err = Sleep(interval)
+	if err != nil {
+		return err
+	}
+
+	return waiter.policy.Throttle()
+}
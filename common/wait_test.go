@@ -0,0 +1,109 @@
+package common
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaiterTimesOut(t *testing.T) {
+	policy := &WaitPolicy{PollInterval: time.Millisecond, Timeout: 20 * time.Millisecond}
+	waiter := &Waiter{
+		policy:      policy,
+		description: "Waiting for cluster (mycluster) to become active",
+		started:     time.Now(),
+		progress:    (&progressReporter{Out: nopWriter{}}).Track("test"),
+	}
+
+	var err error
+	for i := 0; i < 1000 && err == nil; i++ {
+		waiter.Update("creating")
+		err = waiter.Wait()
+	}
+
+	timeout, ok := err.(TimeoutError)
+	if assert.True(t, ok, "Expected a TimeoutError, got %v", err) {
+		assert.Equal(t, "creating", timeout.Status)
+	}
+}
+
+func TestWaitPolicyWithoutTimeout(t *testing.T) {
+	policy := &WaitPolicy{}
+	err := policy.CheckTimeout(time.Now().Add(-24*time.Hour), "Waiting", "creating")
+	assert.Nil(t, err)
+}
+
+func TestObserveClusterStatus(t *testing.T) {
+	waiter := &Waiter{
+		policy:   &WaitPolicy{},
+		progress: (&progressReporter{Out: nopWriter{}}).Track("test"),
+	}
+
+	var observed []string
+	stop := ObserveClusterStatus("123", func(cluster Cluster) {
+		observed = append(observed, cluster.GetStatus())
+	})
+
+	waiter.UpdateCluster(&observedCluster{id: "123", status: "creating"})
+	waiter.UpdateCluster(&observedCluster{id: "456", status: "error"})
+	waiter.UpdateCluster(&observedCluster{id: "123", status: "active"})
+	stop()
+	waiter.UpdateCluster(&observedCluster{id: "123", status: "deleting"})
+
+	assert.Equal(t, []string{"creating", "active"}, observed)
+}
+
+type observedCluster struct {
+	Cluster
+	id     string
+	status string
+}
+
+func (cluster *observedCluster) GetID() string     { return cluster.id }
+func (cluster *observedCluster) GetStatus() string { return cluster.status }
+
+type nopWriter struct{}
+
+func (nopWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestWaitPolicyThrottlesConcurrentPolls(t *testing.T) {
+	policy := &WaitPolicy{PollRate: 100}
+
+	delays := make(chan time.Duration, 5)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			delays <- policy.reservePoll()
+		}()
+	}
+	wg.Wait()
+	close(delays)
+
+	var longest time.Duration
+	for delay := range delays {
+		if delay > longest {
+			longest = delay
+		}
+	}
+	assert.True(t, longest > 30*time.Millisecond, "The polls should be spaced 10ms apart, got a longest delay of %s", longest)
+
+	unlimited := &WaitPolicy{}
+	assert.Equal(t, time.Duration(0), unlimited.reservePoll())
+}
+
+func TestWaiterReportsNamesWithPercent(t *testing.T) {
+	out := &bytes.Buffer{}
+	defer func(w io.Writer) { Progress.Out = w }(Progress.Out)
+	Progress.Out = out
+
+	waiter := NewWaiter(0, "Waiting for cluster (%s) to become active", "100%-prod")
+	waiter.Update("creating")
+
+	assert.Contains(t, out.String(), "Waiting for cluster (100%-prod) to become active: creating")
+}
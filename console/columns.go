@@ -0,0 +1,289 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/getcarina/carina/common"
+)
+
+// ColumnCluster is the cluster object used by custom column templates, e.g. {{ slice .ID 0 8 }}
+type ColumnCluster struct {
+	ID       string
+	Name     string
+	Status   string
+	Template string
+	COE      string
+	Host     string
+	Nodes    string
+	Labels   map[string]string
+	Details  string
+
+	// Created is when the cluster was created, which is zero when it is unknown, see SetClusterCreated
+	Created time.Time
+
+	// Updated is when the cluster was last changed, which is zero when the API doesn't report it
+	Updated time.Time
+}
+
+// column is a column which can be selected with carina clusters --columns
+type column struct {
+	header string
+	value  func(cluster ColumnCluster) (string, error)
+}
+
+// builtinColumns are the columns which are always available
+var builtinColumns = map[string]column{
+	"id":       {"ID", func(c ColumnCluster) (string, error) { return c.ID, nil }},
+	"name":     {"Name", func(c ColumnCluster) (string, error) { return c.Name, nil }},
+	"status":   {"Status", func(c ColumnCluster) (string, error) { return c.Status, nil }},
+	"template": {"Template", func(c ColumnCluster) (string, error) { return c.Template, nil }},
+	"coe":      {"COE", func(c ColumnCluster) (string, error) { return c.COE, nil }},
+	"host":     {"Host", func(c ColumnCluster) (string, error) { return c.Host, nil }},
+	"nodes":    {"Nodes", func(c ColumnCluster) (string, error) { return c.Nodes, nil }},
+	"labels":   {"Labels", func(c ColumnCluster) (string, error) { return formatLabels(c.Labels), nil }},
+	"details":  {"Details", func(c ColumnCluster) (string, error) { return c.Details, nil }},
+	"created":  {"Created", func(c ColumnCluster) (string, error) { return FormatTimestamp(c.Created), nil }},
+	"updated":  {"Updated", func(c ColumnCluster) (string, error) { return FormatTimestamp(c.Updated), nil }},
+}
+
+// defaultColumns are printed by carina clusters when --columns is not specified
+var defaultColumns = []string{"id", "name", "status", "template", "nodes"}
+
+// customColumns are the columns defined in the config file
+var customColumns = map[string]column{}
+
+// clusterColumns are the columns printed by WriteClusters
+var clusterColumns = defaultColumns
+
+// clusterCreated looks up when a cluster was created for the created column
+var clusterCreated = func(cluster common.Cluster) (time.Time, bool) { return time.Time{}, false }
+
+// SetClusterCreated sets how the created column looks up when a cluster was created, e.g. from the cache,
+// for the clusters whose API doesn't report it. The column is blank for the clusters where it isn't known.
+func SetClusterCreated(lookup func(cluster common.Cluster) (time.Time, bool)) {
+	clusterCreated = lookup
+}
+
+// RegisterColumn defines a custom column from a Go template over the cluster, e.g. {{ slice .ID 0 8 }}.
+// See ColumnCluster for the available fields.
+func RegisterColumn(name string, text string) error {
+	name = strings.ToLower(name)
+	if _, ok := builtinColumns[name]; ok {
+		return fmt.Errorf("Unable to define the column %s, it is a built-in column", name)
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return fmt.Errorf("Invalid template for the column %s: %s", name, err)
+	}
+
+	customColumns[name] = column{
+		header: name,
+		value: func(cluster ColumnCluster) (string, error) {
+			var buf bytes.Buffer
+			err := tmpl.Execute(&buf, cluster)
+			return buf.String(), err
+		},
+	}
+	return nil
+}
+
+// SetColumns selects the columns printed when listing clusters, using the names of built-in or custom columns
+func SetColumns(names []string) error {
+	if len(names) == 0 {
+		clusterColumns = defaultColumns
+		return nil
+	}
+
+	selected := make([]string, len(names))
+	for i, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := lookupColumn(name); !ok {
+			return fmt.Errorf("Unknown column %s. Allowed values: %s", name, strings.Join(ColumnNames(), ", "))
+		}
+		selected[i] = name
+	}
+	clusterColumns = selected
+	return nil
+}
+
+// ColumnNames returns the names of the built-in and custom columns, sorted alphabetically
+func ColumnNames() []string {
+	var names []string
+	for name := range builtinColumns {
+		names = append(names, name)
+	}
+	for name := range customColumns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupColumn(name string) (column, bool) {
+	if col, ok := builtinColumns[name]; ok {
+		return col, true
+	}
+	col, ok := customColumns[name]
+	return col, ok
+}
+
+func newColumnCluster(cluster common.Cluster) ColumnCluster {
+	return ColumnCluster{
+		ID:       cluster.GetID(),
+		Name:     cluster.GetName(),
+		Status:   cluster.GetStatus(),
+		Template: cluster.GetTemplate().GetName(),
+		COE:      cluster.GetTemplate().GetCOE(),
+		Host:     cluster.GetTemplate().GetHostType(),
+		Nodes:    cluster.GetNodes(),
+		Labels:   cluster.GetLabels(),
+		Details:  cluster.GetStatusDetails(),
+		Created:  getClusterCreated(cluster),
+		Updated:  cluster.GetUpdated(),
+	}
+}
+
+// getClusterCreated returns when a cluster was created, as reported by the API or otherwise from SetClusterCreated
+func getClusterCreated(cluster common.Cluster) time.Time {
+	if created := cluster.GetCreated(); !created.IsZero() {
+		return created
+	}
+	created, _ := clusterCreated(cluster)
+	return created
+}
+
+// buildColumns returns the header and the value of each selected column for a cluster.
+// A template which fails, such as slicing past the end of a short ID, prints the error in its column.
+func buildColumns(names []string, clusters []common.Cluster) (header []string, rows [][]string) {
+	for _, name := range names {
+		col, _ := lookupColumn(name)
+		header = append(header, col.header)
+	}
+
+	for _, cluster := range clusters {
+		data := newColumnCluster(cluster)
+		var row []string
+		for _, name := range names {
+			col, _ := lookupColumn(name)
+			value, err := col.value(data)
+			if err != nil {
+				common.Log.WriteDebug("Unable to render the column %s for %s: %s", name, data.Name, err)
+				value = "<error>"
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// templateColumn is a column which can be selected with carina templates --columns
+type templateColumn struct {
+	header string
+	value  func(template common.ClusterTemplate) string
+}
+
+// templateColumnRegistry are the columns which can be printed for a template
+var templateColumnRegistry = map[string]templateColumn{
+	"name":         {"Name", func(t common.ClusterTemplate) string { return t.GetName() }},
+	"coe":          {"COE", func(t common.ClusterTemplate) string { return t.GetCOE() }},
+	"version":      {"COE Version", func(t common.ClusterTemplate) string { return t.GetCOEVersion() }},
+	"host":         {"Host", func(t common.ClusterTemplate) string { return t.GetHostType() }},
+	"flavor":       {"Node Flavor", func(t common.ClusterTemplate) string { return t.GetNodeFlavor() }},
+	"deprecated":   {"Deprecated", func(t common.ClusterTemplate) string { return strconv.FormatBool(t.IsDeprecated()) }},
+	"max-nodes":    {"Max Nodes", func(t common.ClusterTemplate) string { return formatCapabilities(t.GetCapabilities())[0] }},
+	"autoscale":    {"Autoscale", func(t common.ClusterTemplate) string { return formatCapabilities(t.GetCapabilities())[1] }},
+	"gpu":          {"GPU", func(t common.ClusterTemplate) string { return formatCapabilities(t.GetCapabilities())[2] }},
+	"availability": {"Availability", func(t common.ClusterTemplate) string { return t.GetAvailability().String() }},
+	"zones":        {"Zones", func(t common.ClusterTemplate) string { return formatZones(t.GetAvailability()) }},
+}
+
+// templateColumns are the columns printed by WriteTemplates, or nil to pick the columns based on what the API reports
+var templateColumns []string
+
+// SetTemplateColumns selects the columns printed when listing templates. When no columns are selected, the name, COE and host
+// are printed, followed by the capabilities and availability when the API reports them.
+func SetTemplateColumns(names []string) error {
+	if len(names) == 0 {
+		templateColumns = nil
+		return nil
+	}
+
+	selected := make([]string, len(names))
+	for i, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := templateColumnRegistry[name]; !ok {
+			return fmt.Errorf("Unknown column %s. Allowed values: %s", name, strings.Join(TemplateColumnNames(), ", "))
+		}
+		selected[i] = name
+	}
+	templateColumns = selected
+	return nil
+}
+
+// TemplateColumnNames returns the names of the template columns, sorted alphabetically
+func TemplateColumnNames() []string {
+	var names []string
+	for name := range templateColumnRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defaultTemplateColumns only includes the capabilities and availability when the API reports them for a template
+func defaultTemplateColumns(templates []common.ClusterTemplate) []string {
+	showAvailability := false
+	showCapabilities := false
+	for _, template := range templates {
+		if template.GetAvailability() != nil {
+			showAvailability = true
+		}
+		if template.GetCapabilities() != nil {
+			showCapabilities = true
+		}
+	}
+
+	names := []string{"name", "coe", "host"}
+	if showCapabilities {
+		names = append(names, "max-nodes", "autoscale", "gpu")
+	}
+	if showAvailability {
+		names = append(names, "availability", "zones")
+	}
+	return names
+}
+
+// buildTemplateColumns returns the header and the value of each selected column for a template
+func buildTemplateColumns(names []string, templates []common.ClusterTemplate) (header []string, rows [][]string) {
+	if len(names) == 0 {
+		names = defaultTemplateColumns(templates)
+	}
+
+	for _, name := range names {
+		header = append(header, templateColumnRegistry[name].header)
+	}
+	for _, template := range templates {
+		var row []string
+		for _, name := range names {
+			row = append(row, templateColumnRegistry[name].value(template))
+		}
+		rows = append(rows, row)
+	}
+	return header, rows
+}
+
+// formatZones lists the availability zones of a template, which are blank when the API doesn't report them
+func formatZones(availability *common.TemplateAvailability) string {
+	if availability == nil {
+		return ""
+	}
+	return strings.Join(availability.AvailabilityZones, ",")
+}
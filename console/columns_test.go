@@ -0,0 +1,99 @@
+package console
+
+import (
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomColumns(t *testing.T) {
+	defer func() {
+		customColumns = map[string]column{}
+		clusterColumns = defaultColumns
+	}()
+
+	assert.Nil(t, RegisterColumn("shortid", "{{ slice .ID 0 8 }}"))
+	assert.Nil(t, RegisterColumn("env", `{{ index .Labels "env" }}`))
+	assert.NotNil(t, RegisterColumn("broken", "{{ .ID"))
+	assert.NotNil(t, RegisterColumn("name", "{{ .Name }}"), "built-in columns can't be replaced")
+
+	assert.NotNil(t, SetColumns([]string{"name", "missing"}))
+	assert.Nil(t, SetColumns([]string{"shortid", "Name", "env", "coe"}))
+
+	clusters := []common.Cluster{
+		&testsupport.FakeCluster{
+			ID:       "1234567890abcdef",
+			Name:     "web",
+			Template: &testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes"},
+			Labels:   map[string]string{"env": "prod"},
+		},
+		&testsupport.FakeCluster{ID: "123", Name: "short", Template: &testsupport.FakeClusterTemplate{}},
+	}
+
+	header, rows := buildColumns(clusterColumns, clusters)
+	assert.Equal(t, []string{"shortid", "Name", "env", "COE"}, header)
+	assert.Equal(t, []string{"12345678", "web", "prod", "kubernetes"}, rows[0])
+	assert.Equal(t, []string{"<error>", "short", "", ""}, rows[1])
+}
+
+func TestCreatedColumn(t *testing.T) {
+	defer func() {
+		clusterColumns = defaultColumns
+		SetClusterCreated(func(cluster common.Cluster) (time.Time, bool) { return time.Time{}, false })
+	}()
+
+	cached := time.Now().Add(-3 * time.Hour)
+	SetClusterCreated(func(cluster common.Cluster) (time.Time, bool) {
+		if cluster.GetName() == "web" {
+			return cached, true
+		}
+		return time.Time{}, false
+	})
+	assert.Nil(t, SetColumns([]string{"name", "created", "updated"}))
+
+	reported := time.Now().Add(-50 * time.Hour)
+	clusters := []common.Cluster{
+		&testsupport.FakeCluster{Name: "web", Template: &testsupport.FakeClusterTemplate{}},
+		&testsupport.FakeCluster{Name: "api", Template: &testsupport.FakeClusterTemplate{}, Created: reported, Updated: reported},
+		&testsupport.FakeCluster{Name: "db", Template: &testsupport.FakeClusterTemplate{}},
+	}
+
+	header, rows := buildColumns(clusterColumns, clusters)
+	assert.Equal(t, []string{"Name", "Created", "Updated"}, header)
+	assert.Equal(t, []string{"web", FormatTimestamp(cached), ""}, rows[0], "the created time should fall back to the lookup")
+	assert.Equal(t, []string{"api", FormatTimestamp(reported), FormatTimestamp(reported)}, rows[1])
+	assert.Equal(t, []string{"db", "", ""}, rows[2])
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	defer func() { UTC = false }()
+
+	UTC = true
+	created := time.Now().Add(-3*time.Hour - time.Minute).UTC()
+	assert.Equal(t, created.Format("2006-01-02 15:04")+" UTC (3h ago)", FormatTimestamp(created))
+	assert.Equal(t, "", FormatTimestamp(time.Time{}))
+
+	assert.Equal(t, "just now", FormatRelativeTime(-time.Minute))
+	assert.Equal(t, "5m ago", FormatRelativeTime(5*time.Minute))
+	assert.Equal(t, "2d ago", FormatRelativeTime(50*time.Hour))
+}
+
+func TestTemplateColumns(t *testing.T) {
+	defer SetTemplateColumns(nil)
+
+	templates := []common.ClusterTemplate{
+		&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes", HostType: "lxc", COEVersion: "1.5.2", NodeFlavor: "m1.large"},
+	}
+
+	header, _ := buildTemplateColumns(templateColumns, templates)
+	assert.Equal(t, []string{"Name", "COE", "Host"}, header, "capabilities and availability are only printed when reported")
+
+	assert.NotNil(t, SetTemplateColumns([]string{"name", "missing"}))
+	assert.Nil(t, SetTemplateColumns([]string{"Name", "version", "flavor"}))
+	header, rows := buildTemplateColumns(templateColumns, templates)
+	assert.Equal(t, []string{"Name", "COE Version", "Node Flavor"}, header)
+	assert.Equal(t, []string{"Kubernetes 1.5.2 on LXC", "1.5.2", "m1.large"}, rows[0])
+}
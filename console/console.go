@@ -2,11 +2,17 @@ package console
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/getcarina/carina/client"
 	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/version"
 	"github.com/pkg/errors"
 )
 
@@ -16,9 +22,13 @@ type Tuple struct {
 	Value interface{}
 }
 
-// Write prints text to the console
+// Quiet only prints the essential values, such as the cluster ID or the path to the credentials, for use in scripts.
+// Informational messages are not printed, but listings, such as WriteClusters, are printed in full.
+var Quiet bool
+
+// Write prints text to stdout, unless Quiet is set. Warnings are logged to stderr instead, see common.Log.WriteStructuredWarning
 func Write(format string, a ...interface{}) {
-	if common.Log.IsSilent {
+	if common.Log.IsSilent || Quiet {
 		return
 	}
 
@@ -29,10 +39,25 @@ func Write(format string, a ...interface{}) {
 	fmt.Printf(format, a...)
 }
 
+// WriteValue prints an essential value, such as the path to the credentials, or a message explaining the value when one is specified.
+// Only the value is printed when Quiet is set, so that it can be captured by a script.
+func WriteValue(value string, message ...string) {
+	if common.Log.IsSilent {
+		return
+	}
+
+	if Quiet || len(message) == 0 {
+		fmt.Println(value)
+		return
+	}
+	for _, line := range message {
+		fmt.Println(line)
+	}
+}
+
 // WriteTable prints rows of tabular data to the console
 func WriteTable(rows [][]string) {
-	output := new(tabwriter.Writer)
-	output.Init(os.Stdout, 5, 8, 2, ' ', 0)
+	output := newTable(os.Stdout)
 
 	for _, row := range rows {
 		writeInColumns(output, row)
@@ -42,6 +67,13 @@ func WriteTable(rows [][]string) {
 
 // WriteMap prints the cluster data to the console
 func WriteMap(items []Tuple) {
+	if resolveLayout() == LayoutTabs {
+		for _, item := range items {
+			fmt.Printf("%s\t%s\n", sanitizeTabField(item.Key), sanitizeTabField(fmt.Sprint(item.Value)))
+		}
+		return
+	}
+
 	output := new(tabwriter.Writer)
 	output.Init(os.Stdout, 5, 8, 2, ' ', 0)
 
@@ -51,38 +83,813 @@ func WriteMap(items []Tuple) {
 
 // WriteCluster prints the cluster data to the console
 func WriteCluster(cluster common.Cluster) {
+	if Format == FormatJSON {
+		writeClusterJSON(cluster)
+		return
+	}
+	if Quiet {
+		fmt.Println(cluster.GetID())
+		return
+	}
+
 	items := []Tuple{
 		{"ID", cluster.GetID()},
 		{"Name", cluster.GetName()},
 		{"Status", cluster.GetStatus()},
 		{"Template", cluster.GetTemplate().GetName()},
 		{"Nodes", cluster.GetNodes()},
+		{"Labels", formatLabels(cluster.GetLabels())},
 		{"Details", cluster.GetStatusDetails()},
 	}
+	if autoscale := cluster.GetAutoScale(); autoscale != nil {
+		items = append(items, Tuple{"AutoScale", autoscale.String()})
+	}
+	items = append(items, formatNodeSize(cluster)...)
+	if created := getClusterCreated(cluster); !created.IsZero() {
+		items = append(items, Tuple{"Created", FormatTimestamp(created)})
+	}
+	if updated := cluster.GetUpdated(); !updated.IsZero() {
+		items = append(items, Tuple{"Updated", FormatTimestamp(updated)})
+	}
+	WriteMap(items)
+}
+
+// formatNodeSize returns the flavor, resources and estimated cost of the cluster's nodes, omitting what the API doesn't report
+func formatNodeSize(cluster common.Cluster) []Tuple {
+	var items []Tuple
+	if flavor := cluster.GetFlavor(); flavor != "" {
+		items = append(items, Tuple{"Node Flavor", flavor})
+	}
+
+	size := cluster.GetNodeSize()
+	if resources := size.String(); resources != "" {
+		items = append(items, Tuple{"Node Size", resources})
+	}
+	if size != nil && size.HourlyCost > 0 {
+		// Clusters which are still being created may not report their size yet
+		nodes, _ := common.ParseNodeCount(cluster.GetNodes())
+		cost := size.EstimateCost(1) + " per node"
+		if total := size.EstimateCost(nodes); total != "" {
+			cost += ", " + total + " total"
+		}
+		items = append(items, Tuple{"Estimated Cost", cost})
+	}
+	return items
+}
+
+// WriteClusterIDs prints the id of each cluster on its own line, for use in scripts
+func WriteClusterIDs(clusters []common.Cluster) {
+	for _, cluster := range clusters {
+		fmt.Println(cluster.GetID())
+	}
+}
+
+// WriteClusterOperationResults prints the outcome of a batch operation for each cluster
+func WriteClusterOperationResults(results []client.ClusterOperationResult, successMessage string) {
+	output := newTable(os.Stdout)
+
+	writeInColumns(output, []string{"Name", "Result"})
+	for _, result := range results {
+		message := successMessage
+		if result.Message != "" {
+			message = result.Message
+		}
+		if result.Err != nil {
+			message = result.Err.Error()
+		}
+		writeInColumns(output, []string{result.Name, message})
+	}
+
+	output.Flush()
+}
+
+// WriteClusterHistory prints the status transitions of a cluster, oldest first
+func WriteClusterHistory(events []client.ClusterEvent) {
+	output := newTable(os.Stdout)
+
+	writeInColumns(output, []string{"Time", "Status", "Source", "Details"})
+	for _, event := range events {
+		writeInColumns(output, []string{event.Time.Local().Format(time.RFC3339), event.Status, event.Source, event.StatusDetails})
+	}
+
+	output.Flush()
+}
+
+// WriteCredentialsRefresh prints which clusters' credentials were downloaded again, and when they expire
+func WriteCredentialsRefresh(results []client.CredentialsRefresh) {
+	output := newTable(os.Stdout)
+
+	writeInColumns(output, []string{"Name", "Status", "Expires", "Details"})
+	for _, result := range results {
+		status := "current"
+		if result.Refreshed {
+			status = "refreshed"
+		}
+		var expires, details string
+		if !result.Expires.IsZero() {
+			expires = result.Expires.Local().Format(time.RFC822)
+		}
+		if result.Err != nil {
+			status = "failed"
+			details = result.Err.Error()
+		}
+		writeInColumns(output, []string{result.Name, status, expires, details})
+	}
+
+	output.Flush()
+}
+
+// WriteCacheInfo prints where the cache is stored, and how old the data cached for each account is
+func WriteCacheInfo(info *client.CacheInfo) {
+	schemaVersion := strconv.Itoa(info.SchemaVersion)
+	if info.SchemaVersion == 0 {
+		schemaVersion = "0 (written by an older carina)"
+	}
+
+	WriteMap([]Tuple{
+		{"Path", info.Path},
+		{"Size", fmt.Sprintf("%d bytes", info.Size)},
+		{"Encrypted", info.Encrypted},
+		{"Schema Version", schemaVersion},
+		{"Last Update Check", formatCacheAge(info.LastUpdateCheck)},
+		{"Accounts", len(info.Accounts)},
+	})
+
+	if len(info.Accounts) == 0 {
+		return
+	}
+
+	fmt.Println()
+	output := newTable(os.Stdout)
+	writeInColumns(output, []string{"Account", "Token", "Token Age", "Clusters Cached", "Templates Cached"})
+	for _, account := range info.Accounts {
+		token := "none"
+		if account.HasToken {
+			token = "cached"
+		}
+		writeInColumns(output, []string{account.ID, token, formatCacheAge(account.TokenUpdated), formatCacheAge(account.ClustersUpdated), formatCacheAge(account.TemplatesUpdated)})
+	}
+	output.Flush()
+}
+
+// WriteVersion prints the version and build metadata of carina, and the latest release when it was checked.
+// Only the version is printed when Quiet is set.
+func WriteVersion(info version.BuildInfo, update *client.UpdateResult, updateErr error) {
+	if Format == FormatJSON {
+		writeVersionJSON(info, update, updateErr)
+		return
+	}
+	if Quiet {
+		WriteValue(info.Version)
+		return
+	}
+
+	orUnknown := func(value string) string {
+		if value == "" {
+			return "unknown"
+		}
+		return value
+	}
+	items := []Tuple{
+		{"Version", orUnknown(info.Version)},
+		{"Commit", orUnknown(info.Commit)},
+		{"Build Date", orUnknown(info.BuildDate)},
+		{"Go Version", info.GoVersion},
+		{"Platform", info.Platform},
+	}
+	switch {
+	case updateErr != nil:
+		items = append(items, Tuple{"Latest Release", "unable to check, " + updateErr.Error()})
+	case update == nil:
+	case update.Available:
+		items = append(items, Tuple{"Latest Release", update.Latest + " is available, run carina update to install it"})
+	default:
+		items = append(items, Tuple{"Latest Release", update.Latest + ", up to date"})
+	}
 	WriteMap(items)
 }
 
-// WriteClusters prints the clusters data to the console
+// WriteAccountInfo prints who the account authenticates as, and what is cached for it
+func WriteAccountInfo(info client.AccountInfo) {
+	orUnknown := func(value string) string {
+		if value == "" {
+			return "unknown"
+		}
+		return value
+	}
+
+	profile := info.Profile
+	if profile == "" {
+		profile = "none, using flags and environment variables"
+	}
+
+	endpoint := info.Endpoint
+	if endpoint == "" {
+		endpoint = "unknown, run carina login"
+	}
+
+	token := "none"
+	expires := "unknown"
+	if info.TokenCached {
+		token = "cached " + formatCacheAge(info.TokenUpdated)
+		if !info.TokenExpires.IsZero() {
+			expires = info.TokenExpires.Local().Format(time.RFC822)
+			if info.TokenExpires.Before(time.Now()) {
+				expires += " (expired)"
+			}
+		}
+	}
+
+	cachePath := info.CachePath
+	if cachePath == "" {
+		cachePath = "disabled"
+	}
+
+	WriteMap([]Tuple{
+		{"Account", info.ID},
+		{"Profile", profile},
+		{"Cloud", orUnknown(info.Cloud)},
+		{"Username", orUnknown(info.Username)},
+		{"Project", orUnknown(info.Project)},
+		{"Region", orUnknown(info.Region)},
+		{"Auth Endpoint", orUnknown(info.AuthEndpoint)},
+		{"Endpoint", endpoint},
+		{"Token", token},
+		{"Token Expires", expires},
+		{"Cache", cachePath},
+	})
+}
+
+// formatCacheAge describes how long ago cached data was saved, e.g. 3 days ago
+func formatCacheAge(updated time.Time) string {
+	if updated.IsZero() {
+		return "unknown"
+	}
+	return FormatAge(time.Since(updated)) + " ago"
+}
+
+// WriteCredentialsHealth prints a summary of the checks on each cluster's downloaded credentials
+func WriteCredentialsHealth(results []client.CredentialsHealth) {
+	output := newTable(os.Stdout)
+
+	writeInColumns(output, []string{"Name", "Status", "Expires", "Endpoint", "Details"})
+	for _, result := range results {
+		var expires, details string
+		if !result.Expires.IsZero() {
+			expires = result.Expires.Local().Format(time.RFC822)
+		}
+		if result.Err != nil {
+			details = result.Err.Error()
+		}
+		writeInColumns(output, []string{result.Name, string(result.Status), expires, result.Endpoint, details})
+	}
+
+	output.Flush()
+}
+
+// WriteServiceStatus prints the overall status from a status page, followed by the components and the ongoing incidents
+func WriteServiceStatus(status *client.ServiceStatus) {
+	WriteMap([]Tuple{
+		{"Status Page", status.Page},
+		{"Status", status.Description},
+		{"Incidents", len(status.Incidents)},
+	})
+
+	if len(status.Components) > 0 {
+		fmt.Println()
+		output := newTable(os.Stdout)
+		writeInColumns(output, []string{"Component", "Status"})
+		for _, component := range status.Components {
+			writeInColumns(output, []string{component.Name, strings.Replace(component.Status, "_", " ", -1)})
+		}
+		output.Flush()
+	}
+
+	for _, incident := range status.Incidents {
+		fmt.Println()
+		WriteMap([]Tuple{
+			{"Incident", incident.Name},
+			{"Status", incident.Status},
+			{"Impact", incident.Impact},
+			{"Affects", strings.Join(incident.Components, ", ")},
+			{"Updated", incident.Updated.Local().Format(time.RFC822)},
+			{"Details", incident.URL},
+		})
+	}
+}
+
+// WriteCredentialsIssues prints the problems found in the credentials directory, and the result of fixing them when fixed is set
+func WriteCredentialsIssues(issues []client.CredentialsIssue, fixed bool) {
+	output := newTable(os.Stdout)
+
+	header := []string{"Issue", "Name", "Details", "Path"}
+	if fixed {
+		header = append(header, "Result")
+	}
+	writeInColumns(output, header)
+	for _, issue := range issues {
+		row := []string{string(issue.Kind), issue.Name, issue.Details, issue.Path}
+		if fixed {
+			result := "deleted"
+			if issue.Kind == client.CredentialsIssueExpired {
+				result = "downloaded"
+			}
+			if issue.Err != nil {
+				result = issue.Err.Error()
+			}
+			row = append(row, result)
+		}
+		writeInColumns(output, row)
+	}
+
+	output.Flush()
+}
+
+// WriteDeprecations prints the deprecated commands and flags, with the release which removes them and what to use instead
+func WriteDeprecations(deprecations []common.Deprecation) {
+	if Format == FormatJSON {
+		writeDeprecationsJSON(deprecations)
+		return
+	}
+
+	output := newTable(os.Stdout)
+	writeInColumns(output, []string{"Deprecated", "Removed In", "Replacement"})
+	for _, d := range deprecations {
+		writeInColumns(output, []string{d.Subject(), d.Sunset, d.Replacement})
+	}
+	output.Flush()
+}
+
+// WriteSmokeTestResult prints the outcome and duration of each step of the smoke test
+func WriteSmokeTestResult(result *client.SmokeTestResult) {
+	WriteMap([]Tuple{
+		{"Cluster", result.Cluster},
+		{"Template", result.Template},
+	})
+
+	fmt.Println()
+	output := newTable(os.Stdout)
+	writeInColumns(output, []string{"Step", "Result", "Duration"})
+	for _, step := range result.Steps {
+		outcome := "ok"
+		if step.Err != nil {
+			outcome = step.Err.Error()
+		}
+		writeInColumns(output, []string{step.Name, outcome, step.Duration.Truncate(time.Second).String()})
+	}
+	output.Flush()
+}
+
+// WriteConformanceResult prints the outcome of each conformance check, and why checks failed, were skipped or are unsupported
+func WriteConformanceResult(result *client.ConformanceResult) {
+	if Format == FormatJSON {
+		writeConformanceResultJSON(result)
+		return
+	}
+
+	WriteMap([]Tuple{
+		{"Cluster", result.Cluster},
+		{"Template", result.Template},
+		{"Failures", result.Failures()},
+	})
+
+	fmt.Println()
+	output := newTable(os.Stdout)
+	writeInColumns(output, []string{"Capability", "Result", "Duration", "Details"})
+	for _, check := range result.Checks {
+		var details string
+		if check.Err != nil {
+			details = check.Err.Error()
+		}
+		writeInColumns(output, []string{check.Capability, string(check.Status), check.Duration.Truncate(time.Second).String(), details})
+	}
+	output.Flush()
+}
+
+// WriteAuditLog prints the changes recorded in the audit log, oldest first
+func WriteAuditLog(entries []client.AuditEntry) {
+	if Format == FormatJSON {
+		writeAuditLogJSON(entries)
+		return
+	}
+
+	output := newTable(os.Stdout)
+	writeInColumns(output, []string{"Time", "Operation", "Cluster", "Result", "Account", "User", "Host", "Arguments", "Error"})
+	for _, entry := range entries {
+		var args []string
+		for key, value := range entry.Args {
+			args = append(args, key+"="+value)
+		}
+		sort.Strings(args)
+		writeInColumns(output, []string{entry.Time.Local().Format(time.RFC3339), entry.Operation, entry.Cluster, entry.Result,
+			entry.Account, entry.User, entry.Host, strings.Join(args, ","), entry.Error})
+	}
+	output.Flush()
+}
+
+// WriteBenchmarkResult prints the duration of each create/delete cycle of the benchmark, followed by a summary of the durations
+func WriteBenchmarkResult(result *client.BenchmarkResult) {
+	if Format == FormatJSON {
+		writeBenchmarkResultJSON(result)
+		return
+	}
+
+	formatDuration := func(d time.Duration) string {
+		return d.Truncate(time.Second).String()
+	}
+
+	WriteMap([]Tuple{
+		{"Template", result.Template},
+		{"Nodes", result.Nodes},
+		{"Cycles", len(result.Cycles)},
+		{"Failures", result.Failures()},
+	})
+
+	fmt.Println()
+	output := newTable(os.Stdout)
+	writeInColumns(output, []string{"Cluster", "Create", "Delete", "Result"})
+	for _, cycle := range result.Cycles {
+		outcome := "ok"
+		if cycle.Err != nil {
+			outcome = cycle.Err.Error()
+		}
+		writeInColumns(output, []string{cycle.Cluster, formatDuration(cycle.Create), formatDuration(cycle.Delete), outcome})
+	}
+	output.Flush()
+
+	fmt.Println()
+	output = newTable(os.Stdout)
+	writeInColumns(output, []string{"Operation", "Count", "Min", "Mean", "p50", "p95", "Max"})
+	for _, op := range []struct {
+		name  string
+		stats client.DurationStats
+	}{
+		{"create", result.CreateStats()},
+		{"delete", result.DeleteStats()},
+	} {
+		writeInColumns(output, []string{op.name, strconv.Itoa(op.stats.Count), formatDuration(op.stats.Min), formatDuration(op.stats.Mean),
+			formatDuration(op.stats.P50), formatDuration(op.stats.P95), formatDuration(op.stats.Max)})
+	}
+	output.Flush()
+}
+
+// WriteTimingSummary prints where the time was spent by the command to stderr, so that it doesn't mix with the command's output
+func WriteTimingSummary(summary common.TimingSummary) {
+	if Format == FormatJSON {
+		writeTimingSummaryJSON(summary)
+		return
+	}
+
+	formatDuration := func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	}
+
+	fmt.Fprintln(os.Stderr)
+	output := newTable(os.Stderr)
+	writeInColumns(output, []string{"Category", "Method", "Count", "Errors", "Total", "Mean", "Max"})
+	for _, entry := range summary.Entries {
+		writeInColumns(output, []string{entry.Category, entry.Method, strconv.Itoa(entry.Count), strconv.Itoa(entry.Errors),
+			formatDuration(entry.Total), formatDuration(entry.Mean()), formatDuration(entry.Max)})
+	}
+	output.Flush()
+	fmt.Fprintf(os.Stderr, "Elapsed: %s\n", formatDuration(summary.Elapsed))
+}
+
+// WriteEmptyState explains that there is nothing to list, followed by hints for what to do next, returning if it was printed.
+// It is only printed for tables on a terminal, so that scripts still get the usual output, e.g. a header or an empty json array.
+func WriteEmptyState(message string, hints ...string) bool {
+	return writeEmptyState(os.Stdout, message, hints...)
+}
+
+func writeEmptyState(out io.Writer, message string, hints ...string) bool {
+	if !showEmptyState() {
+		return false
+	}
+
+	fmt.Fprintln(out, message)
+	for _, hint := range hints {
+		fmt.Fprintln(out, hint)
+	}
+	return true
+}
+
+// showEmptyState returns if an empty list should be explained, instead of printing an empty table
+func showEmptyState() bool {
+	if Format == FormatJSON || common.Log.IsSilent || Quiet {
+		return false
+	}
+	_, isTerminal := terminalWidth()
+	return isTerminal
+}
+
+// WriteClusters prints the clusters data to the console, using the columns selected with SetColumns
 func WriteClusters(clusters []common.Cluster) {
-	output := new(tabwriter.Writer)
-	output.Init(os.Stdout, 5, 8, 2, ' ', 0)
+	if Format == FormatJSON {
+		writeClustersJSON(clusters)
+		return
+	}
+
+	output := newTable(os.Stdout)
+
+	header, rows := buildColumns(clusterColumns, clusters)
+	writeInColumns(output, header)
+	for _, row := range rows {
+		writeInColumns(output, row)
+	}
+
+	output.Flush()
+}
+
+// ClusterStream prints the clusters listed from multiple profiles. Tables print each profile's clusters as soon as they
+// are listed, so that the slowest profile doesn't hold up the output. JSON, which is a single document, is printed
+// when the stream is closed, sorted by profile.
+type ClusterStream struct {
+	output   *streamTable
+	profiles []client.ProfileClusters
+}
+
+// NewClusterStream starts printing the clusters listed from multiple profiles
+func NewClusterStream() *ClusterStream {
+	header, _ := buildColumns(clusterColumns, nil)
+	return &ClusterStream{output: newStreamTable(os.Stdout, append([]string{"Profile"}, header...))}
+}
+
+// Write prints the clusters listed from a profile
+func (stream *ClusterStream) Write(profile client.ProfileClusters) {
+	stream.profiles = append(stream.profiles, profile)
+	if Format == FormatJSON || len(profile.Clusters) == 0 {
+		return
+	}
+
+	_, rows := buildColumns(clusterColumns, profile.Clusters)
+	for i, row := range rows {
+		rows[i] = append([]string{profile.Profile}, row...)
+	}
+	stream.output.Write(rows)
+}
+
+// Close finishes printing the clusters
+func (stream *ClusterStream) Close() {
+	if Format == FormatJSON {
+		writeProfileClustersJSON(stream.profiles)
+		return
+	}
+
+	var count int
+	for _, profile := range stream.profiles {
+		count += len(profile.Clusters)
+	}
+	if count == 0 && WriteEmptyState("No clusters found in the profiles.") {
+		return
+	}
+	stream.output.Flush()
+}
+
+// WriteTemplateNames prints the name of each template on its own line, for use in scripts
+func WriteTemplateNames(templates []common.ClusterTemplate) {
+	for _, template := range templates {
+		fmt.Println(template.GetName())
+	}
+}
+
+// WriteTemplates prints the cluster templates to the console, using the columns selected with SetTemplateColumns
+func WriteTemplates(templates []common.ClusterTemplate) {
+	if Format == FormatJSON {
+		writeTemplatesJSON(templates)
+		return
+	}
+
+	header, rows := buildTemplateColumns(templateColumns, templates)
+	WriteTable(append([][]string{header}, rows...))
+}
+
+// formatCapabilities returns the max nodes, autoscale and GPU support of a template, which are blank when the API doesn't report them
+func formatCapabilities(capabilities *common.TemplateCapabilities) []string {
+	if capabilities == nil {
+		return []string{"", "", ""}
+	}
+
+	maxNodes := "unlimited"
+	if capabilities.MaxNodes > 0 {
+		maxNodes = strconv.Itoa(capabilities.MaxNodes)
+	}
+	return []string{maxNodes, strconv.FormatBool(capabilities.AutoScale), strconv.FormatBool(capabilities.GPU)}
+}
+
+// WriteTemplate prints the full details of a cluster template to the console
+func WriteTemplate(template common.ClusterTemplate) {
+	if Format == FormatJSON {
+		writeTemplateJSON(template)
+		return
+	}
+
+	items := []Tuple{
+		{"Name", template.GetName()},
+		{"COE", template.GetCOE()},
+		{"COE Version", template.GetCOEVersion()},
+		{"Host", template.GetHostType()},
+		{"Node Flavor", template.GetNodeFlavor()},
+		{"Deprecated", strconv.FormatBool(template.IsDeprecated())},
+	}
+	if availability := template.GetAvailability(); availability != nil {
+		items = append(items,
+			Tuple{"Availability", availability.String()},
+			Tuple{"Availability Zones", strings.Join(availability.AvailabilityZones, ", ")})
+	}
+	if capabilities := template.GetCapabilities(); capabilities != nil {
+		values := formatCapabilities(capabilities)
+		items = append(items,
+			Tuple{"Max Nodes", values[0]},
+			Tuple{"Autoscale", values[1]},
+			Tuple{"GPU", values[2]})
+	}
+
+	details := template.GetDetails()
+	var keys []string
+	for key := range details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		items = append(items, Tuple{key, details[key]})
+	}
+
+	WriteMap(items)
+}
+
+// WriteKeypairNames prints the name of each keypair on its own line, for use in scripts
+func WriteKeypairNames(keypairs []common.Keypair) {
+	for _, keypair := range keypairs {
+		fmt.Println(keypair.GetName())
+	}
+}
+
+// WriteKeypairs prints the SSH keypairs registered with the account
+func WriteKeypairs(keypairs []common.Keypair) {
+	output := newTable(os.Stdout)
+
+	writeInColumns(output, []string{"Name", "Fingerprint"})
+	for _, keypair := range keypairs {
+		writeInColumns(output, []string{keypair.GetName(), keypair.GetFingerprint()})
+	}
+
+	output.Flush()
+}
+
+// WriteClusterActions prints the backend-specific actions which can be invoked on a cluster to the console
+func WriteClusterActions(actions []common.ClusterAction) {
+	output := newTable(os.Stdout)
+
+	writeInColumns(output, []string{"Action", "Parameters", "Description"})
+	for _, action := range actions {
+		var params []string
+		for _, param := range action.Params {
+			if param.Required {
+				params = append(params, param.Name+" (required)")
+			} else {
+				params = append(params, param.Name)
+			}
+		}
+		writeInColumns(output, []string{action.Name, strings.Join(params, ", "), action.Description})
+	}
+
+	output.Flush()
+}
+
+// WriteQuotas prints the account quotas and how much of them is used to the console.
+// The usage of quotas which have reached the warning threshold is highlighted.
+func WriteQuotas(quotas *common.Quotas, usage common.QuotaUsage, warnings []client.QuotaWarning) {
+	if Format == FormatJSON {
+		writeQuotasJSON(quotas, usage, warnings)
+		return
+	}
+
+	highlight := func(quota string, text string) string {
+		for _, warning := range warnings {
+			if warning.Quota != quota {
+				continue
+			}
+			if warning.IsExhausted() {
+				return colorize(text, colorRed)
+			}
+			return colorize(text, colorYellow)
+		}
+		return text
+	}
+
+	WriteMap([]Tuple{
+		{"Max Clusters", strconv.Itoa(quotas.MaxClusters)},
+		{"Max Nodes per Cluster", strconv.Itoa(quotas.MaxNodesPerCluster)},
+		{"Clusters Used", highlight("clusters", formatUsage(usage.Clusters, quotas.MaxClusters))},
+		{"Nodes Used", strconv.Itoa(usage.Nodes)},
+		{"Largest Cluster", highlight("nodes-per-cluster", formatUsage(usage.LargestCluster, quotas.MaxNodesPerCluster))},
+	})
+
+	if usage.Clusters == 0 && showEmptyState() {
+		fmt.Println()
+		WriteEmptyState("No clusters are using the quotas yet.", "Create a cluster with carina create <cluster-name> --template <template>.")
+	}
+
+	if len(usage.COEs) > 0 {
+		fmt.Println()
+		output := newTable(os.Stdout)
+		writeInColumns(output, []string{"COE", "Clusters", "Nodes"})
+		for _, coe := range usage.COEs {
+			writeInColumns(output, []string{coe.COE, strconv.Itoa(coe.Clusters), strconv.Itoa(coe.Nodes)})
+		}
+		output.Flush()
+	}
+
+	for _, warning := range warnings {
+		common.Log.WriteStructuredWarning(common.WarningQuota, "%s", warning)
+	}
+}
+
+// formatUsage describes how much of a quota is used, e.g. 2 of 3. A quota of 0 is unknown.
+func formatUsage(used int, max int) string {
+	if max <= 0 {
+		return strconv.Itoa(used)
+	}
+	return fmt.Sprintf("%d of %d", used, max)
+}
+
+// WritePlan prints what an operation would do, either as a preview or when run with --dry-run
+func WritePlan(plan *client.OperationPlan, dryRun bool) {
+	if Format == FormatJSON {
+		writePlanJSON(plan, dryRun)
+		return
+	}
+
+	if dryRun {
+		Write("Dry run: carina %s would make the following changes", plan.Operation)
+	} else {
+		Write("carina %s will make the following changes", plan.Operation)
+	}
+	for _, step := range plan.Steps {
+		Write("  %s", step)
+	}
+	if len(plan.Steps) == 0 {
+		Write("  No changes")
+	}
+	for _, warning := range plan.Warnings {
+		common.Log.WriteStructuredWarning(common.WarningPlan, "%s", warning)
+	}
+}
+
+// WriteDiff prints the changes needed to make the clusters match a manifest, colored like a diff:
+// clusters to create are green, changes to existing clusters are yellow and clusters to delete are red.
+// Warnings, such as a cluster whose template differs from the manifest, are printed inline with a !.
+func WriteDiff(plan *client.ApplyPlan) {
+	if Format == FormatJSON {
+		writePlanJSON(plan.OperationPlan(), true)
+		return
+	}
+
+	for _, line := range formatDiff(plan) {
+		Write("%s", line)
+	}
+}
+
+// formatDiff returns each line of the diff for a plan, see WriteDiff
+func formatDiff(plan *client.ApplyPlan) []string {
+	var lines []string
+	for _, change := range plan.Changes {
+		color := colorYellow
+		switch change.Action {
+		case client.ChangeCreate:
+			color = colorGreen
+		case client.ChangeDelete:
+			color = colorRed
+		}
+		lines = append(lines, colorize(change.String(), color))
+	}
+	for _, warning := range plan.Warnings {
+		lines = append(lines, colorize("! "+warning, colorYellow))
+	}
+	if len(plan.Changes) == 0 {
+		lines = append(lines, "No changes, the clusters match the manifest")
+	}
+	return lines
+}
+
+// WriteNodes prints the cluster nodes to the console
+func WriteNodes(nodes []common.Node) {
+	output := newTable(os.Stdout)
 
 	headerFields := []string{
-		"ID",
 		"Name",
+		"Address",
+		"Role",
 		"Status",
-		"Template",
-		"Nodes",
 	}
 	writeInColumns(output, headerFields)
 
-	for _, cluster := range clusters {
+	for _, node := range nodes {
 		fields := []string{
-			cluster.GetID(),
-			cluster.GetName(),
-			cluster.GetStatus(),
-			cluster.GetTemplate().GetName(),
-			cluster.GetNodes(),
+			node.GetName(),
+			node.GetAddress(),
+			node.GetRole(),
+			node.GetStatus(),
 		}
 		writeInColumns(output, fields)
 	}
@@ -90,14 +897,18 @@ func WriteClusters(clusters []common.Cluster) {
 	output.Flush()
 }
 
-func writeInColumns(output *tabwriter.Writer, columns []string) {
-	s := strings.Join(columns, "\t")
-	b := []byte(s + "\n")
-	_, err := output.Write(b)
-	if err != nil {
-		err = errors.Wrap(err, "Unable to write to console.")
-		fmt.Println(err.Error())
+// formatLabels prints labels as a sorted list of key=value pairs
+func formatLabels(labels map[string]string) string {
+	var pairs []string
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
 	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+func writeInColumns(output *table, columns []string) {
+	output.rows = append(output.rows, columns)
 }
 
 func writeInRows(output *tabwriter.Writer, items []Tuple) {
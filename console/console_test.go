@@ -0,0 +1,153 @@
+package console
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/getcarina/carina/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteEmptyState(t *testing.T) {
+	defer func(format OutputFormat, terminalWidthFunc func() (int, bool)) {
+		Format = format
+		terminalWidth = terminalWidthFunc
+	}(Format, terminalWidth)
+	Format = FormatTable
+
+	var buf bytes.Buffer
+	terminalWidth = func() (int, bool) { return 80, true }
+	assert.True(t, writeEmptyState(&buf, "No clusters found.", "Create a cluster with carina create."))
+	assert.Equal(t, "No clusters found.\nCreate a cluster with carina create.\n", buf.String())
+
+	buf.Reset()
+	terminalWidth = func() (int, bool) { return 0, false }
+	assert.False(t, writeEmptyState(&buf, "No clusters found."), "Scripts should get the usual output when stdout is piped")
+	assert.Empty(t, buf.String())
+
+	terminalWidth = func() (int, bool) { return 80, true }
+	Format = FormatJSON
+	assert.False(t, writeEmptyState(&buf, "No clusters found."), "JSON should print an empty array instead")
+
+	Format = FormatTable
+	Quiet = true
+	defer func() { Quiet = false }()
+	assert.False(t, writeEmptyState(&buf, "No clusters found."), "--quiet should only print the essential values")
+}
+
+func TestFormatNodeSize(t *testing.T) {
+	cluster := &testsupport.FakeCluster{
+		Nodes:    3,
+		Flavor:   "m1.large",
+		NodeSize: &common.NodeSize{RAM: 4096, VCPUs: 2, HourlyCost: 0.12, Currency: "USD"},
+	}
+	assert.Equal(t, []Tuple{
+		{"Node Flavor", "m1.large"},
+		{"Node Size", "2 vCPUs, 4 GB RAM"},
+		{"Estimated Cost", "0.12 USD/hour per node, 0.36 USD/hour total"},
+	}, formatNodeSize(cluster))
+
+	assert.Empty(t, formatNodeSize(&testsupport.FakeCluster{}), "nothing is printed when the API doesn't report the size")
+}
+
+func TestFormatDiff(t *testing.T) {
+	defer func(color ColorMode) { Color = color }(Color)
+	Color = ColorAlways
+
+	plan := &client.ApplyPlan{
+		Changes: []client.ClusterChange{
+			{Action: client.ChangeCreate, Name: "web", Template: "Kubernetes 1.5.2 on LXC", Nodes: 3},
+			{Action: client.ChangeResize, Name: "api", Nodes: 2, CurrentNodes: 1},
+			{Action: client.ChangeDelete, Name: "old"},
+		},
+		Warnings: []string{"The cluster api uses the template Swarm 1.11.2 on LXC instead of Kubernetes*"},
+	}
+
+	lines := formatDiff(plan)
+	assert.Len(t, lines, 4)
+	assert.Equal(t, colorGreen+plan.Changes[0].String()+colorReset, lines[0])
+	assert.Equal(t, colorYellow+plan.Changes[1].String()+colorReset, lines[1])
+	assert.Equal(t, colorRed+plan.Changes[2].String()+colorReset, lines[2])
+	assert.Equal(t, colorYellow+"! "+plan.Warnings[0]+colorReset, lines[3])
+
+	assert.Equal(t, []string{"No changes, the clusters match the manifest"}, formatDiff(&client.ApplyPlan{}))
+}
+
+// captureStdout returns what the function prints to stdout
+func captureStdout(t *testing.T, write func()) string {
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	write()
+	w.Close()
+	output, _ := ioutil.ReadAll(r)
+	return string(output)
+}
+
+func TestWriteVersion(t *testing.T) {
+	defer func(format OutputFormat, terminalWidthFunc func() (int, bool)) {
+		Format = format
+		terminalWidth = terminalWidthFunc
+	}(Format, terminalWidth)
+	Format = FormatTable
+	terminalWidth = func() (int, bool) { return 0, false }
+
+	info := version.BuildInfo{Version: "v2.1.0", Commit: "abc123", GoVersion: "go1.12", Platform: "linux/amd64"}
+	build := "Version\tv2.1.0\nCommit\tabc123\nBuild Date\tunknown\nGo Version\tgo1.12\nPlatform\tlinux/amd64\n"
+
+	output := captureStdout(t, func() { WriteVersion(info, nil, nil) })
+	assert.Equal(t, build, output, "The latest release should only be shown with --check")
+
+	output = captureStdout(t, func() { WriteVersion(info, &client.UpdateResult{Latest: "v2.2.0", Available: true}, nil) })
+	assert.Equal(t, build+"Latest Release\tv2.2.0 is available, run carina update to install it\n", output)
+
+	output = captureStdout(t, func() { WriteVersion(info, &client.UpdateResult{Latest: "v2.1.0"}, nil) })
+	assert.Equal(t, build+"Latest Release\tv2.1.0, up to date\n", output)
+
+	output = captureStdout(t, func() { WriteVersion(info, nil, errors.New("rate limit exceeded")) })
+	assert.Equal(t, build+"Latest Release\tunable to check, rate limit exceeded\n", output)
+
+	Quiet = true
+	output = captureStdout(t, func() { WriteVersion(info, &client.UpdateResult{Latest: "v2.2.0", Available: true}, nil) })
+	Quiet = false
+	assert.Equal(t, "v2.1.0\n", output, "--quiet should only print the version")
+}
+
+func TestWriteVersionJSON(t *testing.T) {
+	defer func(format OutputFormat) { Format = format }(Format)
+	Format = FormatJSON
+	info := version.BuildInfo{Version: "v2.1.0", Commit: "abc123", GoVersion: "go1.12", Platform: "linux/amd64"}
+
+	var doc map[string]interface{}
+	output := captureStdout(t, func() { WriteVersion(info, nil, nil) })
+	require.NoError(t, json.Unmarshal([]byte(output), &doc))
+	assert.Equal(t, "v2.1.0", doc["version"])
+	assert.Equal(t, "abc123", doc["commit"])
+	assert.NotContains(t, doc, "update", "The update should only be included with --check")
+
+	for _, tc := range []struct {
+		update    *client.UpdateResult
+		updateErr error
+		expected  map[string]interface{}
+	}{
+		{&client.UpdateResult{Latest: "v2.2.0", Available: true}, nil, map[string]interface{}{"latest": "v2.2.0", "available": true}},
+		{&client.UpdateResult{Latest: "v2.1.0"}, nil, map[string]interface{}{"latest": "v2.1.0", "available": false}},
+		{nil, errors.New("rate limit exceeded"), map[string]interface{}{"available": false, "error": "rate limit exceeded"}},
+	} {
+		doc = nil
+		output = captureStdout(t, func() { WriteVersion(info, tc.update, tc.updateErr) })
+		require.NoError(t, json.Unmarshal([]byte(output), &doc))
+		assert.Equal(t, tc.expected, doc["update"])
+	}
+}
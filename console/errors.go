@@ -0,0 +1,130 @@
+package console
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+)
+
+// Error types group the error codes, so that orchestration systems can decide how to react to a failure
+const (
+	errorTypeAPI         = "api"
+	errorTypeCredentials = "credentials"
+	errorTypeInput       = "input"
+	errorTypeNetwork     = "network"
+	errorTypeTimeout     = "timeout"
+	errorTypeUnknown     = "unknown"
+)
+
+// describeError builds the structured description of an error printed with --format json.
+// The cause of the error is unwrapped to find the most specific type and code.
+func describeError(err error) errorOutput {
+	output := errorOutput{
+		Message: err.Error(),
+		Type:    errorTypeUnknown,
+		Code:    "error",
+		Context: common.Log.ErrorContext,
+	}
+
+	// Print the message without the context and troubleshooting hint appended by the client
+	if userErr, ok := err.(*client.UserError); ok {
+		output.Message = userErr.Cause().Error()
+		output.Context = userErr.Context
+		output.Type = errorTypeAPI
+		output.Code = "api-error"
+	}
+
+	for cause := err; cause != nil; cause = unwrapError(cause) {
+		if clusterErr, ok := cause.(common.ClusterError); ok {
+			output.Cluster = clusterErr.ClusterName
+			continue
+		}
+		if classifyError(cause, &output) {
+			break
+		}
+	}
+
+	if requestID, ok := output.Context["Request ID"].(string); ok {
+		output.RequestID = requestID
+	}
+	if len(output.Context) == 0 {
+		output.Context = nil
+	}
+	if !common.Log.DebugEnabled() {
+		output.Hints = append(output.Hints, "For additional troubleshooting, re-run the command with --debug specified.")
+	}
+
+	return output
+}
+
+// classifyError sets the type, code and hints for the errors which the cli recognizes, returning false for unknown errors
+func classifyError(err error, output *errorOutput) bool {
+	switch e := err.(type) {
+	case common.TimeoutError:
+		output.Type, output.Code = errorTypeTimeout, "wait-timeout"
+		output.Hints = append(output.Hints, "Increase --wait-timeout, or check the status of the cluster with carina get.")
+	case *common.MultipleMatchingTemplatesError:
+		output.Type, output.Code = errorTypeInput, "multiple-matching-templates"
+		output.Hints = append(output.Hints, fmt.Sprintf("Run carina templates --name %s to refine the search pattern.", e.TemplatePattern))
+	case common.MultipleMatchingTemplatesError:
+		output.Type, output.Code = errorTypeInput, "multiple-matching-templates"
+		output.Hints = append(output.Hints, fmt.Sprintf("Run carina templates --name %s to refine the search pattern.", e.TemplatePattern))
+	case *common.MultipleMatchingClustersError:
+		output.Type, output.Code = errorTypeInput, "multiple-matching-clusters"
+		output.Hints = append(output.Hints, "Use the id of the cluster instead of its name. delete, resize and credentials download accept --match-all to use every matching cluster.")
+	case common.DeprecatedTemplateError:
+		output.Type, output.Code = errorTypeInput, "deprecated-template"
+		output.Hints = append(output.Hints, "Select a newer template with carina templates, or use --allow-deprecated.")
+	case client.CredentialsChangedError:
+		output.Type, output.Code = errorTypeCredentials, "credentials-changed"
+		output.Hints = append(output.Hints, fmt.Sprintf("Run carina credentials diff %s to compare the certificates.", e.ClusterName))
+	case client.CertificateExpiringError:
+		output.Type, output.Code = errorTypeCredentials, "certificate-expiring"
+		output.Hints = append(output.Hints, fmt.Sprintf("Run carina credentials %s to download new credentials.", e.ClusterName))
+	case client.ClusterLockedError:
+		output.Type, output.Code = errorTypeInput, "cluster-locked"
+		output.Hints = append(output.Hints, "Wait for the other carina command to finish, or use --no-lock to override the lock.")
+	case client.CacheUnavailableError:
+		output.Type, output.Code = errorTypeCredentials, "cache-unavailable"
+		output.Hints = append(output.Hints, "Check the permissions on CARINA_HOME.")
+	case common.AuthError:
+		output.Type, output.Code = errorTypeCredentials, "unauthorized"
+		output.Hints = append(output.Hints, "Check your credentials, and that the account is allowed to perform the operation.")
+	case common.QuotaError:
+		output.Type, output.Code = errorTypeAPI, "quota-exceeded"
+		output.Hints = append(output.Hints, "Run carina quotas to compare your usage with the account's quotas.")
+	case common.NotFoundError:
+		output.Type, output.Code = errorTypeInput, "not-found"
+		if notFound, ok := e.Err.(*common.TemplateNotFoundError); ok && len(notFound.Suggestions) > 0 {
+			output.Hints = append(output.Hints, fmt.Sprintf("Did you mean --template \"%s\"? Run carina templates to list the available templates.", notFound.Suggestions[0].GetName()))
+		} else {
+			output.Hints = append(output.Hints, "Run carina clusters or carina templates to list the available names.")
+		}
+	case common.APIError:
+		output.Type, output.Code = errorTypeAPI, "api-error"
+	case net.Error:
+		output.Type, output.Code = errorTypeNetwork, "api-unreachable"
+		output.Hints = append(output.Hints, "Check the network connection to the API, or configure a proxy with --proxy.")
+	default:
+		return false
+	}
+	return true
+}
+
+// unwrapError returns the underlying cause of an error, or nil when it doesn't wrap another error
+func unwrapError(err error) error {
+	cause, ok := err.(interface {
+		Cause() error
+	})
+	if !ok {
+		return nil
+	}
+
+	next := cause.Cause()
+	if next == err {
+		return nil
+	}
+	return next
+}
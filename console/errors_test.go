@@ -0,0 +1,50 @@
+package console
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeError(t *testing.T) {
+	output := describeError(errors.New("A cluster name is required"))
+	assert.Equal(t, "A cluster name is required", output.Message)
+	assert.Equal(t, errorTypeUnknown, output.Type)
+	assert.Equal(t, "error", output.Code)
+
+	timeout := common.TimeoutError{Description: "Waiting for cluster (web) to become active", Timeout: time.Minute, Status: "creating"}
+	output = describeError(pkgerrors.Wrap(timeout, "Unable to create the cluster"))
+	assert.Equal(t, errorTypeTimeout, output.Type)
+	assert.Equal(t, "wait-timeout", output.Code)
+	assert.Contains(t, output.Hints[0], "--wait-timeout")
+
+	output = describeError(&common.MultipleMatchingTemplatesError{TemplatePattern: "Kubernetes*"})
+	assert.Equal(t, errorTypeInput, output.Type)
+	assert.Equal(t, "multiple-matching-templates", output.Code)
+
+	unreachable := &url.Error{Op: "Get", URL: "https://api.getcarina.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+	output = describeError(unreachable)
+	assert.Equal(t, errorTypeNetwork, output.Type)
+	assert.Equal(t, "api-unreachable", output.Code)
+
+	output = describeError(common.QuotaError{Err: errors.New("The maximum number of clusters has been reached")})
+	assert.Equal(t, errorTypeAPI, output.Type)
+	assert.Equal(t, "quota-exceeded", output.Code)
+
+	output = describeError(common.ClusterError{ClusterName: "web", Err: common.NotFoundError{Err: errors.New("Could not find cluster (web)")}})
+	assert.Equal(t, "web", output.Cluster)
+	assert.Equal(t, "not-found", output.Code)
+
+	template := &testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes", HostType: "lxc"}
+	output = describeError(common.NewTemplateNotFoundError("Kubernets*", []common.ClusterTemplate{template}))
+	assert.Equal(t, "not-found", output.Code)
+	assert.Contains(t, output.Message, "Did you mean:\n  Kubernetes 1.5.2 on LXC (kubernetes, lxc)")
+	assert.Contains(t, output.Hints[0], `--template "Kubernetes 1.5.2 on LXC"`)
+}
@@ -0,0 +1,665 @@
+package console
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/version"
+	"github.com/pkg/errors"
+)
+
+// SchemaVersion is the version of the JSON output schemas, printed by carina schema.
+// It must be incremented whenever a field is removed, renamed or changes type. Adding a field does not change the version.
+const SchemaVersion = 1
+
+// OutputFormat controls how command results are printed
+type OutputFormat string
+
+const (
+	// FormatTable prints human readable tables
+	FormatTable OutputFormat = "table"
+
+	// FormatJSON prints JSON documents which follow the schemas printed by carina schema
+	FormatJSON OutputFormat = "json"
+)
+
+// Format is the output format used when printing command results
+var Format = FormatTable
+
+// SetFormat validates and changes the output format
+func SetFormat(format string) error {
+	switch OutputFormat(format) {
+	case FormatTable, FormatJSON:
+		Format = OutputFormat(format)
+		return nil
+	default:
+		return fmt.Errorf("Invalid --format %s. Allowed values: %s, %s", format, FormatTable, FormatJSON)
+	}
+}
+
+type clusterOutput struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Status   string            `json:"status"`
+	Template string            `json:"template"`
+	Host     string            `json:"host"`
+	Nodes    int               `json:"nodes"`
+	Labels   map[string]string `json:"labels"`
+	Details  string            `json:"details"`
+
+	AutoScale *autoScaleOutput `json:"autoscale,omitempty"`
+
+	// Created and Updated are omitted when they are unknown
+	Created *time.Time `json:"created,omitempty"`
+	Updated *time.Time `json:"updated,omitempty"`
+
+	Flavor   string          `json:"flavor,omitempty"`
+	NodeSize *nodeSizeOutput `json:"nodeSize,omitempty"`
+
+	// Profile is only set when listing the clusters in multiple profiles
+	Profile string `json:"profile,omitempty"`
+}
+
+type nodeSizeOutput struct {
+	RAM        int     `json:"ramMB,omitempty"`
+	VCPUs      int     `json:"vcpus,omitempty"`
+	Disk       int     `json:"diskGB,omitempty"`
+	HourlyCost float64 `json:"hourlyCost,omitempty"`
+	Currency   string  `json:"currency,omitempty"`
+}
+
+type autoScaleOutput struct {
+	Enabled  bool `json:"enabled"`
+	MinNodes int  `json:"minNodes,omitempty"`
+	MaxNodes int  `json:"maxNodes,omitempty"`
+}
+
+type clusterDocument struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	Cluster       clusterOutput `json:"cluster"`
+}
+
+type clustersDocument struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Clusters      []clusterOutput `json:"clusters"`
+}
+
+type templateOutput struct {
+	Name         string              `json:"name"`
+	COE          string              `json:"coe"`
+	Host         string              `json:"host"`
+	Availability *availabilityOutput `json:"availability,omitempty"`
+	Capabilities *capabilitiesOutput `json:"capabilities,omitempty"`
+}
+
+type capabilitiesOutput struct {
+	// MaxNodes is 0 when the number of nodes is only limited by the account's quotas
+	MaxNodes  int  `json:"maxNodes"`
+	AutoScale bool `json:"autoscale"`
+	GPU       bool `json:"gpu"`
+}
+
+type availabilityOutput struct {
+	Constrained       bool     `json:"constrained"`
+	Reason            string   `json:"reason,omitempty"`
+	AvailabilityZones []string `json:"availabilityZones"`
+}
+
+type templateDetailsOutput struct {
+	Name       string            `json:"name"`
+	COE        string            `json:"coe"`
+	COEVersion string            `json:"coeVersion"`
+	Host       string            `json:"host"`
+	NodeFlavor string            `json:"nodeFlavor"`
+	Deprecated bool              `json:"deprecated"`
+	Details    map[string]string `json:"details"`
+
+	Availability *availabilityOutput `json:"availability,omitempty"`
+	Capabilities *capabilitiesOutput `json:"capabilities,omitempty"`
+}
+
+type templateDocument struct {
+	SchemaVersion int                   `json:"schemaVersion"`
+	Template      templateDetailsOutput `json:"template"`
+}
+
+type templatesDocument struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Templates     []templateOutput `json:"templates"`
+}
+
+type coeUsageOutput struct {
+	COE      string `json:"coe"`
+	Clusters int    `json:"clusters"`
+	Nodes    int    `json:"nodes"`
+}
+
+type usageOutput struct {
+	Clusters       int              `json:"clusters"`
+	Nodes          int              `json:"nodes"`
+	LargestCluster int              `json:"largestCluster"`
+	COEs           []coeUsageOutput `json:"coes"`
+}
+
+type quotaWarningOutput struct {
+	Quota     string `json:"quota"`
+	Used      int    `json:"used"`
+	Max       int    `json:"max"`
+	Exhausted bool   `json:"exhausted"`
+}
+
+type quotasDocument struct {
+	SchemaVersion      int                  `json:"schemaVersion"`
+	MaxClusters        int                  `json:"maxClusters"`
+	MaxNodesPerCluster int                  `json:"maxNodesPerCluster"`
+	Usage              usageOutput          `json:"usage"`
+	Warnings           []quotaWarningOutput `json:"warnings"`
+}
+
+type planDocument struct {
+	SchemaVersion int      `json:"schemaVersion"`
+	DryRun        bool     `json:"dryRun"`
+	Operation     string   `json:"operation"`
+	Steps         []string `json:"steps"`
+	Warnings      []string `json:"warnings"`
+}
+
+type deprecationOutput struct {
+	Command     string `json:"command"`
+	Flag        string `json:"flag,omitempty"`
+	Sunset      string `json:"sunset"`
+	Replacement string `json:"replacement"`
+}
+
+type deprecationsDocument struct {
+	SchemaVersion int                 `json:"schemaVersion"`
+	Deprecations  []deprecationOutput `json:"deprecations"`
+}
+
+type versionDocument struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	Version       string               `json:"version"`
+	Commit        string               `json:"commit"`
+	BuildDate     string               `json:"buildDate"`
+	GoVersion     string               `json:"goVersion"`
+	Platform      string               `json:"platform"`
+	Update        *versionUpdateOutput `json:"update,omitempty"`
+}
+
+type versionUpdateOutput struct {
+	Latest    string `json:"latest,omitempty"`
+	Available bool   `json:"available"`
+	Error     string `json:"error,omitempty"`
+}
+
+type benchmarkCycleOutput struct {
+	Cluster       string  `json:"cluster"`
+	CreateSeconds float64 `json:"createSeconds"`
+	DeleteSeconds float64 `json:"deleteSeconds"`
+	Error         string  `json:"error,omitempty"`
+}
+
+type durationStatsOutput struct {
+	Count       int     `json:"count"`
+	MinSeconds  float64 `json:"minSeconds"`
+	MeanSeconds float64 `json:"meanSeconds"`
+	P50Seconds  float64 `json:"p50Seconds"`
+	P95Seconds  float64 `json:"p95Seconds"`
+	MaxSeconds  float64 `json:"maxSeconds"`
+}
+
+type benchmarkDocument struct {
+	SchemaVersion int                    `json:"schemaVersion"`
+	Template      string                 `json:"template"`
+	Nodes         int                    `json:"nodes"`
+	Failures      int                    `json:"failures"`
+	Create        durationStatsOutput    `json:"create"`
+	Delete        durationStatsOutput    `json:"delete"`
+	Cycles        []benchmarkCycleOutput `json:"cycles"`
+}
+
+type conformanceCheckOutput struct {
+	Capability      string  `json:"capability"`
+	Description     string  `json:"description"`
+	Status          string  `json:"status"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Details         string  `json:"details,omitempty"`
+}
+
+type conformanceDocument struct {
+	SchemaVersion int                      `json:"schemaVersion"`
+	Cluster       string                   `json:"cluster"`
+	Template      string                   `json:"template"`
+	Failures      int                      `json:"failures"`
+	Checks        []conformanceCheckOutput `json:"checks"`
+}
+
+type auditEntryOutput struct {
+	Time      string            `json:"time"`
+	Account   string            `json:"account"`
+	Host      string            `json:"host"`
+	User      string            `json:"user"`
+	Operation string            `json:"operation"`
+	Cluster   string            `json:"cluster"`
+	ClusterID string            `json:"clusterId,omitempty"`
+	Args      map[string]string `json:"args"`
+	Result    string            `json:"result"`
+	Error     string            `json:"error,omitempty"`
+}
+
+type auditDocument struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	Entries       []auditEntryOutput `json:"entries"`
+}
+
+type timingEntryOutput struct {
+	Category     string  `json:"category"`
+	Method       string  `json:"method,omitempty"`
+	Count        int     `json:"count"`
+	Errors       int     `json:"errors"`
+	TotalSeconds float64 `json:"totalSeconds"`
+	MeanSeconds  float64 `json:"meanSeconds"`
+	MaxSeconds   float64 `json:"maxSeconds"`
+}
+
+type timingDocument struct {
+	SchemaVersion  int                 `json:"schemaVersion"`
+	ElapsedSeconds float64             `json:"elapsedSeconds"`
+	Entries        []timingEntryOutput `json:"entries"`
+}
+
+type errorOutput struct {
+	Message string `json:"message"`
+
+	// Type is the category of the error, such as network or timeout
+	Type string `json:"type"`
+
+	// Code identifies the error, such as wait-timeout, and does not change between releases
+	Code string `json:"code"`
+
+	// Cluster is the name of the cluster on which the operation failed, if any
+	Cluster string `json:"cluster,omitempty"`
+
+	// RequestID is the id assigned by the API to the request which failed, if any
+	RequestID string `json:"requestId,omitempty"`
+
+	// Hints suggest how to resolve the error
+	Hints []string `json:"hints,omitempty"`
+
+	// Context is additional information collected while the command ran, such as the API endpoint
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+type errorDocument struct {
+	SchemaVersion int         `json:"schemaVersion"`
+	Error         errorOutput `json:"error"`
+}
+
+func newClusterOutput(cluster common.Cluster) clusterOutput {
+	// Clusters which are still being created may not report their size yet
+	nodes, _ := common.ParseNodeCount(cluster.GetNodes())
+
+	labels := cluster.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	return clusterOutput{
+		ID:       cluster.GetID(),
+		Name:     cluster.GetName(),
+		Status:   cluster.GetStatus(),
+		Template: cluster.GetTemplate().GetName(),
+		Host:     cluster.GetTemplate().GetHostType(),
+		Nodes:    nodes,
+		Labels:   labels,
+		Details:  cluster.GetStatusDetails(),
+
+		AutoScale: newAutoScaleOutput(cluster.GetAutoScale()),
+		Created:   newTimestampOutput(getClusterCreated(cluster)),
+		Updated:   newTimestampOutput(cluster.GetUpdated()),
+		Flavor:    cluster.GetFlavor(),
+		NodeSize:  newNodeSizeOutput(cluster.GetNodeSize()),
+	}
+}
+
+// newNodeSizeOutput converts the resources of a cluster's nodes, omitting them when the API doesn't report them
+func newNodeSizeOutput(size *common.NodeSize) *nodeSizeOutput {
+	if size == nil {
+		return nil
+	}
+	return &nodeSizeOutput{
+		RAM:        size.RAM,
+		VCPUs:      size.VCPUs,
+		Disk:       size.Disk,
+		HourlyCost: size.HourlyCost,
+		Currency:   size.Currency,
+	}
+}
+
+// newTimestampOutput converts a time to UTC, omitting it when it is unknown
+func newTimestampOutput(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// newAutoScaleOutput converts a cluster's autoscaling settings, omitting them when the cluster doesn't support autoscaling
+func newAutoScaleOutput(autoscale *common.AutoScale) *autoScaleOutput {
+	if autoscale == nil {
+		return nil
+	}
+
+	return &autoScaleOutput{
+		Enabled:  autoscale.Enabled,
+		MinNodes: autoscale.MinNodes,
+		MaxNodes: autoscale.MaxNodes,
+	}
+}
+
+func writeClusterJSON(cluster common.Cluster) {
+	writeJSON(os.Stdout, clusterDocument{SchemaVersion: SchemaVersion, Cluster: newClusterOutput(cluster)})
+}
+
+func writeClustersJSON(clusters []common.Cluster) {
+	writeJSON(os.Stdout, newClustersDocument(clusters))
+}
+
+// newClustersDocument lists the clusters, which is an empty array, never null, when there are no clusters
+func newClustersDocument(clusters []common.Cluster) clustersDocument {
+	doc := clustersDocument{SchemaVersion: SchemaVersion, Clusters: make([]clusterOutput, len(clusters))}
+	for i, cluster := range clusters {
+		doc.Clusters[i] = newClusterOutput(cluster)
+	}
+	return doc
+}
+
+// writeProfileClustersJSON prints the clusters listed from multiple profiles as a single document, sorted by profile
+func writeProfileClustersJSON(profiles []client.ProfileClusters) {
+	sort.SliceStable(profiles, func(i, j int) bool { return profiles[i].Profile < profiles[j].Profile })
+
+	doc := clustersDocument{SchemaVersion: SchemaVersion, Clusters: []clusterOutput{}}
+	for _, profile := range profiles {
+		for _, cluster := range profile.Clusters {
+			output := newClusterOutput(cluster)
+			output.Profile = profile.Profile
+			doc.Clusters = append(doc.Clusters, output)
+		}
+	}
+	writeJSON(os.Stdout, doc)
+}
+
+func writeTemplateJSON(template common.ClusterTemplate) {
+	details := template.GetDetails()
+	if details == nil {
+		details = map[string]string{}
+	}
+
+	writeJSON(os.Stdout, templateDocument{
+		SchemaVersion: SchemaVersion,
+		Template: templateDetailsOutput{
+			Name:       template.GetName(),
+			COE:        template.GetCOE(),
+			COEVersion: template.GetCOEVersion(),
+			Host:       template.GetHostType(),
+			NodeFlavor: template.GetNodeFlavor(),
+			Deprecated: template.IsDeprecated(),
+			Details:    details,
+
+			Availability: newAvailabilityOutput(template.GetAvailability()),
+			Capabilities: newCapabilitiesOutput(template.GetCapabilities()),
+		},
+	})
+}
+
+func writeTemplatesJSON(templates []common.ClusterTemplate) {
+	writeJSON(os.Stdout, newTemplatesDocument(templates))
+}
+
+// newTemplatesDocument lists the templates, which is an empty array, never null, when there are no templates
+func newTemplatesDocument(templates []common.ClusterTemplate) templatesDocument {
+	doc := templatesDocument{SchemaVersion: SchemaVersion, Templates: make([]templateOutput, len(templates))}
+	for i, template := range templates {
+		doc.Templates[i] = templateOutput{
+			Name:         template.GetName(),
+			COE:          template.GetCOE(),
+			Host:         template.GetHostType(),
+			Availability: newAvailabilityOutput(template.GetAvailability()),
+			Capabilities: newCapabilitiesOutput(template.GetCapabilities()),
+		}
+	}
+	return doc
+}
+
+// newAvailabilityOutput converts a template's capacity hint, omitting it when the API doesn't report one
+func newAvailabilityOutput(availability *common.TemplateAvailability) *availabilityOutput {
+	if availability == nil {
+		return nil
+	}
+
+	zones := availability.AvailabilityZones
+	if zones == nil {
+		zones = []string{}
+	}
+	return &availabilityOutput{
+		Constrained:       availability.Constrained,
+		Reason:            availability.Reason,
+		AvailabilityZones: zones,
+	}
+}
+
+// newCapabilitiesOutput converts what a template supports, omitting it when the API doesn't report it
+func newCapabilitiesOutput(capabilities *common.TemplateCapabilities) *capabilitiesOutput {
+	if capabilities == nil {
+		return nil
+	}
+
+	return &capabilitiesOutput{
+		MaxNodes:  capabilities.MaxNodes,
+		AutoScale: capabilities.AutoScale,
+		GPU:       capabilities.GPU,
+	}
+}
+
+func writeQuotasJSON(quotas *common.Quotas, usage common.QuotaUsage, warnings []client.QuotaWarning) {
+	writeJSON(os.Stdout, newQuotasDocument(quotas, usage, warnings))
+}
+
+// newQuotasDocument describes the quotas and their usage. The lists are empty arrays, never null, when the account has no clusters.
+func newQuotasDocument(quotas *common.Quotas, usage common.QuotaUsage, warnings []client.QuotaWarning) quotasDocument {
+	doc := quotasDocument{
+		SchemaVersion:      SchemaVersion,
+		MaxClusters:        quotas.MaxClusters,
+		MaxNodesPerCluster: quotas.MaxNodesPerCluster,
+		Usage: usageOutput{
+			Clusters:       usage.Clusters,
+			Nodes:          usage.Nodes,
+			LargestCluster: usage.LargestCluster,
+			COEs:           make([]coeUsageOutput, len(usage.COEs)),
+		},
+		Warnings: make([]quotaWarningOutput, len(warnings)),
+	}
+	for i, coe := range usage.COEs {
+		doc.Usage.COEs[i] = coeUsageOutput{COE: coe.COE, Clusters: coe.Clusters, Nodes: coe.Nodes}
+	}
+	for i, warning := range warnings {
+		doc.Warnings[i] = quotaWarningOutput{Quota: warning.Quota, Used: warning.Used, Max: warning.Max, Exhausted: warning.IsExhausted()}
+	}
+	return doc
+}
+
+func writePlanJSON(plan *client.OperationPlan, dryRun bool) {
+	doc := planDocument{
+		SchemaVersion: SchemaVersion,
+		DryRun:        dryRun,
+		Operation:     plan.Operation,
+		Steps:         append([]string{}, plan.Steps...),
+		Warnings:      append([]string{}, plan.Warnings...),
+	}
+	writeJSON(os.Stdout, doc)
+}
+
+func writeDeprecationsJSON(deprecations []common.Deprecation) {
+	doc := deprecationsDocument{SchemaVersion: SchemaVersion, Deprecations: make([]deprecationOutput, len(deprecations))}
+	for i, d := range deprecations {
+		doc.Deprecations[i] = deprecationOutput{
+			Command:     d.Command,
+			Flag:        d.Flag,
+			Sunset:      d.Sunset,
+			Replacement: d.Replacement,
+		}
+	}
+	writeJSON(os.Stdout, doc)
+}
+
+func writeVersionJSON(info version.BuildInfo, update *client.UpdateResult, updateErr error) {
+	doc := versionDocument{
+		SchemaVersion: SchemaVersion,
+		Version:       info.Version,
+		Commit:        info.Commit,
+		BuildDate:     info.BuildDate,
+		GoVersion:     info.GoVersion,
+		Platform:      info.Platform,
+	}
+	if updateErr != nil {
+		doc.Update = &versionUpdateOutput{Error: updateErr.Error()}
+	} else if update != nil {
+		doc.Update = &versionUpdateOutput{Latest: update.Latest, Available: update.Available}
+	}
+	writeJSON(os.Stdout, doc)
+}
+
+// WriteError prints an error to stderr, as a JSON document when the output format is json
+func WriteError(err error) {
+	if Format == FormatJSON {
+		writeJSON(os.Stderr, errorDocument{SchemaVersion: SchemaVersion, Error: describeError(err)})
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "Error:", err.Error())
+}
+
+// streamJSON writes each document on a single line, so that a stream of documents is line-delimited JSON
+var streamJSON bool
+
+func writeJSON(w io.Writer, doc interface{}) {
+	marshal := func(v interface{}) ([]byte, error) { return json.MarshalIndent(v, "", "  ") }
+	if streamJSON {
+		marshal = json.Marshal
+	}
+
+	output, err := marshal(doc)
+	if err != nil {
+		err = errors.Wrap(err, "Unable to write to console.")
+		fmt.Println(err.Error())
+		return
+	}
+
+	fmt.Fprintln(w, string(output))
+}
+
+func newDurationStatsOutput(stats client.DurationStats) durationStatsOutput {
+	return durationStatsOutput{
+		Count:       stats.Count,
+		MinSeconds:  stats.Min.Seconds(),
+		MeanSeconds: stats.Mean.Seconds(),
+		P50Seconds:  stats.P50.Seconds(),
+		P95Seconds:  stats.P95.Seconds(),
+		MaxSeconds:  stats.Max.Seconds(),
+	}
+}
+
+func writeBenchmarkResultJSON(result *client.BenchmarkResult) {
+	doc := benchmarkDocument{
+		SchemaVersion: SchemaVersion,
+		Template:      result.Template,
+		Nodes:         result.Nodes,
+		Failures:      result.Failures(),
+		Create:        newDurationStatsOutput(result.CreateStats()),
+		Delete:        newDurationStatsOutput(result.DeleteStats()),
+		Cycles:        make([]benchmarkCycleOutput, len(result.Cycles)),
+	}
+	for i, cycle := range result.Cycles {
+		doc.Cycles[i] = benchmarkCycleOutput{
+			Cluster:       cycle.Cluster,
+			CreateSeconds: cycle.Create.Seconds(),
+			DeleteSeconds: cycle.Delete.Seconds(),
+		}
+		if cycle.Err != nil {
+			doc.Cycles[i].Error = cycle.Err.Error()
+		}
+	}
+	writeJSON(os.Stdout, doc)
+}
+
+func writeConformanceResultJSON(result *client.ConformanceResult) {
+	doc := conformanceDocument{
+		SchemaVersion: SchemaVersion,
+		Cluster:       result.Cluster,
+		Template:      result.Template,
+		Failures:      result.Failures(),
+		Checks:        make([]conformanceCheckOutput, len(result.Checks)),
+	}
+	for i, check := range result.Checks {
+		doc.Checks[i] = conformanceCheckOutput{
+			Capability:      check.Capability,
+			Description:     check.Description,
+			Status:          string(check.Status),
+			DurationSeconds: check.Duration.Seconds(),
+		}
+		if check.Err != nil {
+			doc.Checks[i].Details = check.Err.Error()
+		}
+	}
+	writeJSON(os.Stdout, doc)
+}
+
+func writeAuditLogJSON(entries []client.AuditEntry) {
+	doc := auditDocument{SchemaVersion: SchemaVersion, Entries: make([]auditEntryOutput, len(entries))}
+	for i, entry := range entries {
+		args := entry.Args
+		if args == nil {
+			args = map[string]string{}
+		}
+		doc.Entries[i] = auditEntryOutput{
+			Time:      entry.Time.UTC().Format(time.RFC3339),
+			Account:   entry.Account,
+			Host:      entry.Host,
+			User:      entry.User,
+			Operation: entry.Operation,
+			Cluster:   entry.Cluster,
+			ClusterID: entry.ClusterID,
+			Args:      args,
+			Result:    entry.Result,
+			Error:     entry.Error,
+		}
+	}
+	writeJSON(os.Stdout, doc)
+}
+
+func writeTimingSummaryJSON(summary common.TimingSummary) {
+	doc := timingDocument{
+		SchemaVersion:  SchemaVersion,
+		ElapsedSeconds: summary.Elapsed.Seconds(),
+		Entries:        make([]timingEntryOutput, len(summary.Entries)),
+	}
+	for i, entry := range summary.Entries {
+		doc.Entries[i] = timingEntryOutput{
+			Category:     entry.Category,
+			Method:       entry.Method,
+			Count:        entry.Count,
+			Errors:       entry.Errors,
+			TotalSeconds: entry.Total.Seconds(),
+			MeanSeconds:  entry.Mean().Seconds(),
+			MaxSeconds:   entry.Max.Seconds(),
+		}
+	}
+	writeJSON(os.Stderr, doc)
+}
@@ -0,0 +1,22 @@
+package console
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmptyListsAreArraysInJSON(t *testing.T) {
+	for name, doc := range map[string]interface{}{
+		"clusters":  newClustersDocument(nil),
+		"templates": newTemplatesDocument(nil),
+		"quotas":    newQuotasDocument(&common.Quotas{MaxClusters: 3}, common.QuotaUsage{}, nil),
+	} {
+		contents, err := json.Marshal(doc)
+		require.NoError(t, err)
+		assert.NotContains(t, string(contents), "null", "The %s document should use empty arrays instead of null", name)
+	}
+}
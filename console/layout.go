@@ -0,0 +1,303 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// TableLayout controls how tables are printed
+type TableLayout string
+
+const (
+	// LayoutAuto picks the layout based on stdout: tabs when piped, records when the terminal is too narrow, otherwise columns
+	LayoutAuto TableLayout = "auto"
+
+	// LayoutColumns prints aligned columns padded with spaces
+	LayoutColumns TableLayout = "columns"
+
+	// LayoutTabs prints tab-separated columns without padding, which is easy to parse with tools like awk and cut
+	LayoutTabs TableLayout = "tabs"
+
+	// LayoutRecords prints each row as a stack of "Header: value" lines, which fits in a narrow terminal
+	LayoutRecords TableLayout = "records"
+)
+
+// Layout is the layout used when printing tables
+var Layout = LayoutAuto
+
+// ANSI escape codes used to highlight values in the console output
+const (
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// ColorMode controls when values are highlighted in color
+type ColorMode string
+
+const (
+	// ColorAuto highlights values when stdout is a terminal, unless the NO_COLOR environment variable is set
+	ColorAuto ColorMode = "auto"
+
+	// ColorAlways highlights values, even when stdout is piped, e.g. to less -R
+	ColorAlways ColorMode = "always"
+
+	// ColorNever doesn't highlight values
+	ColorNever ColorMode = "never"
+)
+
+// Color is when values are highlighted in color
+var Color = ColorAuto
+
+// NoColorEnvVar disables color in the auto color mode when it is set to any value, see https://no-color.org
+const NoColorEnvVar = "NO_COLOR"
+
+// ParseColorMode validates a color mode
+func ParseColorMode(value string) (ColorMode, error) {
+	switch mode := ColorMode(value); mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	default:
+		return "", errors.Errorf("Invalid color: %s. Allowed values: %s, %s, %s", value, ColorAuto, ColorAlways, ColorNever)
+	}
+}
+
+// colorize highlights text in a color, according to the color mode
+func colorize(text string, color string) string {
+	switch Color {
+	case ColorNever:
+		return text
+	case ColorAlways:
+		return color + text + colorReset
+	}
+
+	if _, isTerminal := terminalWidth(); !isTerminal {
+		return text
+	}
+	if _, noColor := os.LookupEnv(NoColorEnvVar); noColor {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// terminalWidth returns the width of stdout, and if stdout is a terminal.
+// A width of 0 means that the width of the terminal is unknown.
+var terminalWidth = func() (width int, isTerminal bool) {
+	fd := int(os.Stdout.Fd())
+	if !terminal.IsTerminal(fd) {
+		return 0, false
+	}
+
+	width, _, err := terminal.GetSize(fd)
+	if err != nil {
+		return 0, true
+	}
+	return width, true
+}
+
+// table buffers rows so that the layout can be chosen once the width of the table is known.
+// The first row is the header.
+type table struct {
+	out  io.Writer
+	rows [][]string
+}
+
+func newTable(out io.Writer) *table {
+	return &table{out: out}
+}
+
+// Flush prints the table using the selected layout
+func (t *table) Flush() {
+	if len(t.rows) == 0 {
+		return
+	}
+
+	layout := Layout
+	columns := t.renderColumns()
+	if layout == LayoutAuto {
+		layout = detectLayout(columns)
+	}
+
+	var output []byte
+	switch layout {
+	case LayoutTabs:
+		output = t.renderTabs()
+	case LayoutRecords:
+		output = t.renderRecords()
+	default:
+		output = columns
+	}
+
+	_, err := t.out.Write(output)
+	if err != nil {
+		err = errors.Wrap(err, "Unable to write to console.")
+		fmt.Println(err.Error())
+	}
+}
+
+// resolveLayout returns the selected layout, using tabs when stdout is piped and columns otherwise
+func resolveLayout() TableLayout {
+	if Layout != LayoutAuto {
+		return Layout
+	}
+	if _, isTerminal := terminalWidth(); !isTerminal {
+		return LayoutTabs
+	}
+	return LayoutColumns
+}
+
+// detectLayout uses tabs when stdout is piped, and records when the columns are wider than the terminal
+func detectLayout(columns []byte) TableLayout {
+	width, isTerminal := terminalWidth()
+	if !isTerminal {
+		return LayoutTabs
+	}
+
+	if width > 0 {
+		for _, line := range strings.Split(string(columns), "\n") {
+			if utf8.RuneCountInString(line) > width {
+				return LayoutRecords
+			}
+		}
+	}
+	return LayoutColumns
+}
+
+func (t *table) renderColumns() []byte {
+	var buf bytes.Buffer
+	output := new(tabwriter.Writer)
+	output.Init(&buf, 5, 8, 2, ' ', 0)
+	for _, row := range t.rows {
+		fmt.Fprintln(output, strings.Join(row, "\t"))
+	}
+	output.Flush()
+	return buf.Bytes()
+}
+
+func (t *table) renderTabs() []byte {
+	var buf bytes.Buffer
+	for _, row := range t.rows {
+		fields := make([]string, len(row))
+		for i, field := range row {
+			fields[i] = sanitizeTabField(field)
+		}
+		fmt.Fprintln(&buf, strings.Join(fields, "\t"))
+	}
+	return buf.Bytes()
+}
+
+// sanitizeTabField replaces the tabs and newlines in a value, which would otherwise break up the row or record
+func sanitizeTabField(field string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(field)
+}
+
+func (t *table) renderRecords() []byte {
+	var buf bytes.Buffer
+	header := t.rows[0]
+	output := new(tabwriter.Writer)
+	output.Init(&buf, 5, 8, 1, ' ', 0)
+	for i, row := range t.rows[1:] {
+		if i > 0 {
+			fmt.Fprintln(output)
+		}
+		for j, field := range row {
+			var name string
+			if j < len(header) {
+				name = header[j]
+			}
+			fmt.Fprintf(output, "%s:\t%s\n", name, sanitizeTabField(field))
+		}
+	}
+	output.Flush()
+	return buf.Bytes()
+}
+
+// streamTable prints rows as they arrive, instead of buffering the whole table to align the columns.
+// The columns are padded to the widest value printed so far, so a later row with a wider value shifts the columns after it.
+type streamTable struct {
+	out           io.Writer
+	layout        TableLayout
+	header        []string
+	headerWritten bool
+	widths        []int
+}
+
+func newStreamTable(out io.Writer, header []string) *streamTable {
+	return &streamTable{out: out, layout: resolveLayout(), header: header}
+}
+
+// Write prints a batch of rows, after the header when it hasn't been printed yet
+func (t *streamTable) Write(rows [][]string) {
+	var output []byte
+	switch t.layout {
+	case LayoutTabs:
+		batch := &table{rows: rows}
+		if !t.headerWritten {
+			batch.rows = append([][]string{t.header}, rows...)
+		}
+		output = batch.renderTabs()
+	case LayoutRecords:
+		if len(rows) == 0 {
+			return
+		}
+		batch := &table{rows: append([][]string{t.header}, rows...)}
+		output = batch.renderRecords()
+		if t.headerWritten {
+			output = append([]byte("\n"), output...)
+		}
+	default:
+		output = t.renderColumns(rows)
+	}
+	t.headerWritten = true
+
+	_, err := t.out.Write(output)
+	if err != nil {
+		err = errors.Wrap(err, "Unable to write to console.")
+		fmt.Println(err.Error())
+	}
+}
+
+// Flush prints the header when no rows were printed
+func (t *streamTable) Flush() {
+	if !t.headerWritten && t.layout != LayoutRecords {
+		t.Write(nil)
+	}
+}
+
+func (t *streamTable) renderColumns(rows [][]string) []byte {
+	if !t.headerWritten {
+		rows = append([][]string{t.header}, rows...)
+	}
+	for _, row := range rows {
+		for i, field := range row {
+			if i == len(t.widths) {
+				t.widths = append(t.widths, 0)
+			}
+			if width := utf8.RuneCountInString(field); width > t.widths[i] {
+				t.widths[i] = width
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, row := range rows {
+		var line string
+		for i, field := range row {
+			line += field
+			if i < len(row)-1 {
+				line += strings.Repeat(" ", t.widths[i]-utf8.RuneCountInString(field)+2)
+			}
+		}
+		fmt.Fprintln(&buf, line)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,96 @@
+package console
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func renderTable(layout TableLayout, width int, isTerminal bool) string {
+	defer func(layout TableLayout, terminalWidthFunc func() (int, bool)) {
+		Layout = layout
+		terminalWidth = terminalWidthFunc
+	}(Layout, terminalWidth)
+
+	Layout = layout
+	terminalWidth = func() (int, bool) { return width, isTerminal }
+
+	var buf bytes.Buffer
+	output := newTable(&buf)
+	writeInColumns(output, []string{"Name", "Status", "Details"})
+	writeInColumns(output, []string{"web", "active", ""})
+	writeInColumns(output, []string{"api", "error", "quota\texceeded"})
+	output.Flush()
+	return buf.String()
+}
+
+func TestTableLayoutColumns(t *testing.T) {
+	assert.Equal(t, "Name  Status  Details\nweb   active  \napi   error   quota  exceeded\n", renderTable(LayoutColumns, 0, true))
+	assert.Equal(t, renderTable(LayoutColumns, 0, true), renderTable(LayoutAuto, 80, true), "Columns should be used when the table fits in the terminal")
+	assert.Equal(t, renderTable(LayoutColumns, 0, true), renderTable(LayoutAuto, 0, true), "Columns should be used when the terminal width is unknown")
+}
+
+func TestTableLayoutTabs(t *testing.T) {
+	expected := "Name\tStatus\tDetails\nweb\tactive\t\napi\terror\tquota exceeded\n"
+	assert.Equal(t, expected, renderTable(LayoutTabs, 0, true))
+	assert.Equal(t, expected, renderTable(LayoutAuto, 0, false), "Tabs should be used when stdout is piped")
+}
+
+func TestTableLayoutRecords(t *testing.T) {
+	expected := "Name:    web\nStatus:  active\nDetails: \n\nName:    api\nStatus:  error\nDetails: quota exceeded\n"
+	assert.Equal(t, expected, renderTable(LayoutRecords, 0, true))
+	assert.Equal(t, expected, renderTable(LayoutAuto, 20, true), "Records should be used when the table is wider than the terminal")
+}
+
+func renderStreamTable(layout TableLayout) string {
+	defer func(layout TableLayout) { Layout = layout }(Layout)
+	Layout = layout
+
+	var buf bytes.Buffer
+	output := newStreamTable(&buf, []string{"Profile", "Name", "Status"})
+	output.Write([][]string{{"dev", "web", "active"}})
+	output.Write([][]string{{"production", "api", "error"}})
+	output.Flush()
+	return buf.String()
+}
+
+func TestStreamTable(t *testing.T) {
+	// Each batch is printed as it arrives, and the columns widen for later rows
+	assert.Equal(t, "Profile  Name  Status\ndev      web   active\nproduction  api   error\n", renderStreamTable(LayoutColumns))
+	assert.Equal(t, "Profile\tName\tStatus\ndev\tweb\tactive\nproduction\tapi\terror\n", renderStreamTable(LayoutTabs))
+	assert.Equal(t, "Profile: dev\nName:    web\nStatus:  active\n\nProfile: production\nName:    api\nStatus:  error\n", renderStreamTable(LayoutRecords))
+
+	var buf bytes.Buffer
+	output := newStreamTable(&buf, []string{"Profile", "Name"})
+	output.layout = LayoutColumns
+	output.Flush()
+	assert.Equal(t, "Profile  Name\n", buf.String(), "The header should be printed when there are no rows")
+}
+
+func TestColorize(t *testing.T) {
+	defer func(color ColorMode, terminalWidthFunc func() (int, bool)) {
+		Color = color
+		terminalWidth = terminalWidthFunc
+	}(Color, terminalWidth)
+	terminalWidth = func() (int, bool) { return 0, false }
+
+	Color = ColorAuto
+	assert.Equal(t, "error", colorize("error", colorRed), "Piped output should not be colored by default")
+
+	Color = ColorAlways
+	assert.Equal(t, colorRed+"error"+colorReset, colorize("error", colorRed))
+
+	terminalWidth = func() (int, bool) { return 80, true }
+	Color = ColorNever
+	assert.Equal(t, "error", colorize("error", colorRed))
+
+	Color = ColorAuto
+	os.Setenv(NoColorEnvVar, "1")
+	defer os.Unsetenv(NoColorEnvVar)
+	assert.Equal(t, "error", colorize("error", colorRed), "NO_COLOR should disable color on a terminal")
+
+	_, err := ParseColorMode("sometimes")
+	assert.Error(t, err)
+}
@@ -0,0 +1,172 @@
+package console
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/getcarina/carina/common"
+)
+
+// ErrNothingPicked is returned by Pick when the user cancels without picking an item
+var ErrNothingPicked = errors.New("Nothing was selected")
+
+// maxPickerRows is how many items the picker lists at once, search to narrow down the rest
+const maxPickerRows = 20
+
+// Pick prompts on stderr for one of the rows, which the user selects by its number, or narrows down by typing part of it.
+// The first column of each row identifies the item. Returns the index of the selected row.
+func Pick(noun string, header []string, rows [][]string) (int, error) {
+	return pickFrom(os.Stdin, os.Stderr, noun, header, rows)
+}
+
+// PickAmbiguousCluster prompts for one of the clusters which share a name, listing their id, name and creation date.
+// created returns when a cluster was created, which is only known for clusters created by this client.
+func PickAmbiguousCluster(ambiguous *common.MultipleMatchingClustersError, created func(common.Cluster) (time.Time, bool)) (common.Cluster, error) {
+	fmt.Fprintf(os.Stderr, "Multiple clusters are named %s\n", ambiguous.ClusterName)
+	picked, err := Pick("cluster", []string{"ID", "Name", "Created"}, ambiguousClusterRows(ambiguous.MatchingClusters, created))
+	if err != nil {
+		return nil, err
+	}
+	return ambiguous.MatchingClusters[picked], nil
+}
+
+// PickSuggestedTemplate prompts for one of the templates suggested by an error, when the template wasn't found or the
+// pattern matched more than one template. Returns false when the error doesn't suggest any templates, or nothing was picked.
+func PickSuggestedTemplate(err error) (common.ClusterTemplate, bool) {
+	var heading string
+	var templates []common.ClusterTemplate
+	for cause := err; cause != nil && templates == nil; cause = unwrapError(cause) {
+		switch e := cause.(type) {
+		case *common.TemplateNotFoundError:
+			heading = fmt.Sprintf("Could not find template named %s, the closest templates are", e.TemplatePattern)
+			templates = e.Suggestions
+		case *common.MultipleMatchingTemplatesError:
+			heading = fmt.Sprintf("Multiple templates match %s", e.TemplatePattern)
+			templates = e.Candidates
+		}
+	}
+	if len(templates) == 0 {
+		return nil, false
+	}
+
+	fmt.Fprintln(os.Stderr, heading)
+	rows := make([][]string, len(templates))
+	for i, template := range templates {
+		deprecated := ""
+		if template.IsDeprecated() {
+			deprecated = "yes"
+		}
+		rows[i] = []string{template.GetName(), template.GetCOE(), template.GetHostType(), deprecated}
+	}
+	picked, pickErr := Pick("template", []string{"Name", "COE", "Host", "Deprecated"}, rows)
+	if pickErr != nil {
+		return nil, false
+	}
+	return templates[picked], true
+}
+
+// ambiguousClusterRows lists the id, name and creation date of each cluster
+func ambiguousClusterRows(clusters []common.Cluster, created func(common.Cluster) (time.Time, bool)) [][]string {
+	rows := make([][]string, len(clusters))
+	for i, cluster := range clusters {
+		createdAt := "unknown"
+		if timestamp, ok := created(cluster); ok {
+			createdAt = timestamp.Local().Format(time.RFC3339)
+		}
+		rows[i] = []string{cluster.GetID(), cluster.GetName(), createdAt}
+	}
+	return rows
+}
+
+func pickFrom(in io.Reader, out io.Writer, noun string, header []string, rows [][]string) (int, error) {
+	reader := bufio.NewReader(in)
+
+	var search string
+	matches := matchRows(rows, search)
+	for {
+		writePickerRows(out, header, rows, matches)
+
+		if len(matches) == 1 {
+			fmt.Fprintf(out, "Select a %s (number, text to search, enter to pick %s): ", noun, rows[matches[0]][0])
+		} else {
+			fmt.Fprintf(out, "Select a %s (number, text to search, enter to cancel): ", noun)
+		}
+		answer, err := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if err != nil && answer == "" {
+			fmt.Fprintln(out)
+			return -1, ErrNothingPicked
+		}
+
+		switch {
+		case answer == "" && len(matches) == 1:
+			return matches[0], nil
+		case answer == "":
+			return -1, ErrNothingPicked
+		}
+
+		if number, err := strconv.Atoi(answer); err == nil {
+			if number >= 1 && number <= len(matches) && number <= maxPickerRows {
+				return matches[number-1], nil
+			}
+			fmt.Fprintf(out, "%d is not one of the listed numbers\n", number)
+			continue
+		}
+
+		search = answer
+		found := matchRows(rows, search)
+		if len(found) == 0 {
+			fmt.Fprintf(out, "No %s matches %s\n", noun, search)
+			continue
+		}
+		matches = found
+	}
+}
+
+// writePickerRows lists the matching rows, numbered from 1
+func writePickerRows(out io.Writer, header []string, rows [][]string, matches []int) {
+	table := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(table, "\t%s\n", strings.Join(header, "\t"))
+	for i, row := range matches {
+		if i == maxPickerRows {
+			break
+		}
+		fmt.Fprintf(table, "%d\t%s\n", i+1, strings.Join(rows[row], "\t"))
+	}
+	table.Flush()
+
+	if len(matches) > maxPickerRows {
+		fmt.Fprintf(out, "... and %d more, type part of a name to narrow down the list\n", len(matches)-maxPickerRows)
+	}
+}
+
+// matchRows returns the indexes of the rows which fuzzy match the search
+func matchRows(rows [][]string, search string) []int {
+	var matches []int
+	for i, row := range rows {
+		if fuzzyMatch(search, strings.Join(row, " ")) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+// fuzzyMatch returns if the characters of the search appear in order in the text, ignoring case, e.g. pdk matches prod-k8s
+func fuzzyMatch(search string, text string) bool {
+	text = strings.ToLower(text)
+	for _, c := range strings.ToLower(search) {
+		i := strings.IndexRune(text, c)
+		if i < 0 {
+			return false
+		}
+		text = text[i+len(string(c)):]
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package console
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+var pickerRows = [][]string{
+	{"prod-k8s", "active", "kubernetes"},
+	{"prod-swarm", "active", "swarm"},
+	{"dev", "error", "kubernetes"},
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	assert.True(t, fuzzyMatch("pdk", "prod-k8s"))
+	assert.True(t, fuzzyMatch("PROD", "prod-k8s"))
+	assert.True(t, fuzzyMatch("", "prod-k8s"))
+	assert.False(t, fuzzyMatch("kp", "prod-k8s"))
+}
+
+func TestPickByNumber(t *testing.T) {
+	var out bytes.Buffer
+	picked, err := pickFrom(strings.NewReader("2\n"), &out, "cluster", []string{"Name", "Status", "COE"}, pickerRows)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, picked)
+	assert.Contains(t, out.String(), "prod-swarm")
+}
+
+func TestPickBySearch(t *testing.T) {
+	var out bytes.Buffer
+	picked, err := pickFrom(strings.NewReader("prod\nswm\n\n"), &out, "cluster", []string{"Name", "Status", "COE"}, pickerRows)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, picked)
+}
+
+func TestPickCanceled(t *testing.T) {
+	var out bytes.Buffer
+	_, err := pickFrom(strings.NewReader("\n"), &out, "cluster", []string{"Name", "Status", "COE"}, pickerRows)
+	assert.Equal(t, ErrNothingPicked, err)
+
+	_, err = pickFrom(strings.NewReader(""), &out, "cluster", []string{"Name", "Status", "COE"}, pickerRows)
+	assert.Equal(t, ErrNothingPicked, err)
+}
+
+func TestAmbiguousClusterRows(t *testing.T) {
+	created := time.Date(2016, 7, 1, 12, 0, 0, 0, time.Local)
+	clusters := []common.Cluster{
+		&testsupport.FakeCluster{ID: "1234", Name: "web"},
+		&testsupport.FakeCluster{ID: "5678", Name: "web"},
+	}
+
+	rows := ambiguousClusterRows(clusters, func(cluster common.Cluster) (time.Time, bool) {
+		return created, cluster.GetID() == "1234"
+	})
+	assert.Equal(t, [][]string{
+		{"1234", "web", created.Format(time.RFC3339)},
+		{"5678", "web", "unknown"},
+	}, rows)
+}
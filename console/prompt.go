@@ -0,0 +1,125 @@
+package console
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// Confirm asks the user a yes/no question on stderr, defaulting to no
+func Confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// ReadSecret prompts for a secret on stderr, without echoing it when stdin is a terminal
+func ReadSecret(prompt string) (string, error) {
+	if !IsInteractive() {
+		secret, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && secret == "" {
+			return "", err
+		}
+		return strings.TrimRight(secret, "\r\n"), nil
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	secret, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(secret), err
+}
+
+// ReadValue prompts for a value on stderr, such as a username, echoing what is typed
+func ReadValue(prompt string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && value == "" {
+		return "", err
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// IsInteractive returns if stdin is a terminal, so that the user can be prompted
+func IsInteractive() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// FormatAge describes a duration in its largest whole unit, e.g. 3 days
+func FormatAge(age time.Duration) string {
+	var value int
+	var unit string
+	switch {
+	case age >= 24*time.Hour:
+		value, unit = int(age/(24*time.Hour)), "day"
+	case age >= time.Hour:
+		value, unit = int(age/time.Hour), "hour"
+	case age >= time.Minute:
+		value, unit = int(age/time.Minute), "minute"
+	default:
+		return "less than a minute"
+	}
+
+	if value != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", value, unit)
+}
+
+// UTC prints timestamps in UTC, instead of the local time zone
+var UTC bool
+
+// FormatTimestamp prints a time both absolutely and relative to now, e.g. 2017-03-01 14:30 CST (3h ago),
+// or is blank when the time is unknown
+func FormatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	if UTC {
+		t = t.UTC()
+	} else {
+		t = t.Local()
+	}
+	return fmt.Sprintf("%s (%s)", t.Format("2006-01-02 15:04 MST"), FormatRelativeTime(time.Since(t)))
+}
+
+// FormatRelativeTime describes how long ago something happened in its largest whole unit, e.g. 3h ago
+func FormatRelativeTime(age time.Duration) string {
+	switch {
+	case age >= 24*time.Hour:
+		return fmt.Sprintf("%dd ago", age/(24*time.Hour))
+	case age >= time.Hour:
+		return fmt.Sprintf("%dh ago", age/time.Hour)
+	case age >= time.Minute:
+		return fmt.Sprintf("%dm ago", age/time.Minute)
+	default:
+		// Also covers times slightly in the future, when the API's clock is ahead
+		return "just now"
+	}
+}
+
+// Choose asks the user to pick one of the choices on stderr, returning false when nothing valid was chosen
+func Choose(question string, choices []string) (string, bool) {
+	fmt.Fprintf(os.Stderr, "%s [%s] ", question, strings.Join(choices, "/"))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	for _, choice := range choices {
+		if answer == strings.ToLower(choice) {
+			return choice, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,15 @@
+package console
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatAge(t *testing.T) {
+	assert.Equal(t, "less than a minute", FormatAge(30*time.Second))
+	assert.Equal(t, "1 minute", FormatAge(90*time.Second))
+	assert.Equal(t, "5 hours", FormatAge(5*time.Hour+10*time.Minute))
+	assert.Equal(t, "2 days", FormatAge(50*time.Hour))
+}
@@ -0,0 +1,427 @@
+package console
+
+import (
+	"fmt"
+	"sort"
+)
+
+const clusterSchema = `{
+    "type": "object",
+    "required": ["id", "name", "status", "template", "nodes", "labels", "details"],
+    "properties": {
+      "id": {"type": "string"},
+      "name": {"type": "string"},
+      "status": {"type": "string"},
+      "template": {"type": "string"},
+      "host": {"type": "string", "description": "The host type of the cluster's template, e.g. lxc or vm. Empty when unknown"},
+      "nodes": {"type": "integer"},
+      "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+      "details": {"type": "string"},
+      "autoscale": ` + autoScaleSchema + `,
+      "created": {"type": "string", "format": "date-time", "description": "When the cluster was created. Omitted when unknown"},
+      "updated": {"type": "string", "format": "date-time", "description": "When the cluster was last changed. Omitted when the API doesn't report it"},
+      "flavor": {"type": "string", "description": "The flavor of the nodes. Omitted when unknown"},
+      "nodeSize": ` + nodeSizeSchema + `,
+      "profile": {"type": "string", "description": "The profile of the cluster, only when listing the clusters in multiple profiles"}
+    }
+  }`
+
+// nodeSizeSchema is the resources and estimated cost of each node in a cluster, which are omitted when the API doesn't report them
+const nodeSizeSchema = `{
+        "type": "object",
+        "properties": {
+          "ramMB": {"type": "integer"},
+          "vcpus": {"type": "integer"},
+          "diskGB": {"type": "integer"},
+          "hourlyCost": {"type": "number", "description": "The estimated cost of a node per hour, in the currency"},
+          "currency": {"type": "string", "description": "e.g. USD"}
+        }
+      }`
+
+// autoScaleSchema is the autoscaling settings of a cluster, which are omitted when the cluster doesn't support autoscaling
+const autoScaleSchema = `{
+        "type": "object",
+        "required": ["enabled"],
+        "properties": {
+          "enabled": {"type": "boolean"},
+          "minNodes": {"type": "integer"},
+          "maxNodes": {"type": "integer"}
+        }
+      }`
+
+// durationStatsSchema summarizes the durations of the successful cycles of a benchmark
+const durationStatsSchema = `{
+      "type": "object",
+      "required": ["count", "minSeconds", "meanSeconds", "p50Seconds", "p95Seconds", "maxSeconds"],
+      "properties": {
+        "count": {"type": "integer"},
+        "minSeconds": {"type": "number"},
+        "meanSeconds": {"type": "number"},
+        "p50Seconds": {"type": "number"},
+        "p95Seconds": {"type": "number"},
+        "maxSeconds": {"type": "number"}
+      }
+    }`
+
+// availabilitySchema is the capacity hint of a template, which is omitted when the API doesn't report one
+const availabilitySchema = `{
+    "type": "object",
+    "required": ["constrained", "availabilityZones"],
+    "properties": {
+      "constrained": {"type": "boolean"},
+      "reason": {"type": "string"},
+      "availabilityZones": {"type": "array", "items": {"type": "string"}}
+    }
+  }`
+
+// capabilitiesSchema is what clusters created with a template support, which is omitted when the API doesn't report it
+const capabilitiesSchema = `{
+    "type": "object",
+    "required": ["maxNodes", "autoscale", "gpu"],
+    "properties": {
+      "maxNodes": {"type": "integer", "description": "0 when the number of nodes is only limited by the account's quotas"},
+      "autoscale": {"type": "boolean"},
+      "gpu": {"type": "boolean"}
+    }
+  }`
+
+// schemas are the JSON schemas of the documents printed with --format json, by name
+var schemas = map[string]string{
+	"cluster": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "cluster",
+  "type": "object",
+  "required": ["schemaVersion", "cluster"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "cluster": ` + clusterSchema + `
+  }
+}`,
+
+	"clusters": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "clusters",
+  "type": "object",
+  "required": ["schemaVersion", "clusters"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "clusters": {
+      "type": "array",
+      "items": ` + clusterSchema + `
+    }
+  }
+}`,
+
+	"template": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "template",
+  "type": "object",
+  "required": ["schemaVersion", "template"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "template": {
+      "type": "object",
+      "required": ["name", "coe", "coeVersion", "host", "nodeFlavor", "deprecated", "details"],
+      "properties": {
+        "name": {"type": "string"},
+        "coe": {"type": "string"},
+        "coeVersion": {"type": "string", "description": "Empty when the version is unknown"},
+        "host": {"type": "string"},
+        "nodeFlavor": {"type": "string", "description": "Empty when the flavor is unknown"},
+        "deprecated": {"type": "boolean"},
+        "details": {"type": "object", "additionalProperties": {"type": "string"}},
+        "availability": ` + availabilitySchema + `,
+        "capabilities": ` + capabilitiesSchema + `
+      }
+    }
+  }
+}`,
+
+	"templates": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "templates",
+  "type": "object",
+  "required": ["schemaVersion", "templates"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "templates": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name", "coe", "host"],
+        "properties": {
+          "name": {"type": "string"},
+          "coe": {"type": "string"},
+          "host": {"type": "string"},
+          "availability": ` + availabilitySchema + `,
+          "capabilities": ` + capabilitiesSchema + `
+        }
+      }
+    }
+  }
+}`,
+
+	"quotas": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "quotas",
+  "type": "object",
+  "required": ["schemaVersion", "maxClusters", "maxNodesPerCluster"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "maxClusters": {"type": "integer"},
+    "maxNodesPerCluster": {"type": "integer"},
+    "usage": {
+      "type": "object",
+      "required": ["clusters", "nodes", "coes"],
+      "properties": {
+        "clusters": {"type": "integer"},
+        "nodes": {"type": "integer"},
+        "largestCluster": {"type": "integer"},
+        "coes": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": ["coe", "clusters", "nodes"],
+            "properties": {
+              "coe": {"type": "string"},
+              "clusters": {"type": "integer"},
+              "nodes": {"type": "integer"}
+            }
+          }
+        }
+      }
+    },
+    "warnings": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["quota", "used", "max", "exhausted"],
+        "properties": {
+          "quota": {"type": "string"},
+          "used": {"type": "integer"},
+          "max": {"type": "integer"},
+          "exhausted": {"type": "boolean"}
+        }
+      }
+    }
+  }
+}`,
+
+	"plan": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "plan",
+  "type": "object",
+  "required": ["schemaVersion", "dryRun", "operation", "steps", "warnings"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "dryRun": {"type": "boolean"},
+    "operation": {"type": "string"},
+    "steps": {"type": "array", "items": {"type": "string"}},
+    "warnings": {"type": "array", "items": {"type": "string"}}
+  }
+}`,
+
+	"deprecations": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "deprecations",
+  "type": "object",
+  "required": ["schemaVersion", "deprecations"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "deprecations": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["command", "sunset", "replacement"],
+        "properties": {
+          "command": {"type": "string"},
+          "flag": {"type": "string"},
+          "sunset": {"type": "string"},
+          "replacement": {"type": "string"}
+        }
+      }
+    }
+  }
+}`,
+
+	"version": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "version",
+  "type": "object",
+  "required": ["schemaVersion", "version", "commit", "buildDate", "goVersion", "platform"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "version": {"type": "string"},
+    "commit": {"type": "string", "description": "Empty when the build didn't record it"},
+    "buildDate": {"type": "string", "description": "RFC 3339. Empty when the build didn't record it"},
+    "goVersion": {"type": "string"},
+    "platform": {"type": "string", "description": "e.g. linux/amd64"},
+    "update": {
+      "type": "object",
+      "description": "Only with --check",
+      "required": ["available"],
+      "properties": {
+        "latest": {"type": "string"},
+        "available": {"type": "boolean"},
+        "error": {"type": "string", "description": "Why the latest release couldn't be checked"}
+      }
+    }
+  }
+}`,
+
+	"timing": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "timing",
+  "description": "Written to stderr by --timing",
+  "type": "object",
+  "required": ["schemaVersion", "elapsedSeconds", "entries"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "elapsedSeconds": {"type": "number"},
+    "entries": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["category", "count", "errors", "totalSeconds", "meanSeconds", "maxSeconds"],
+        "properties": {
+          "category": {"type": "string", "enum": ["auth", "api", "polling"]},
+          "method": {"type": "string", "description": "The HTTP method of the API calls, omitted for polling"},
+          "count": {"type": "integer"},
+          "errors": {"type": "integer"},
+          "totalSeconds": {"type": "number", "description": "Cumulative, so concurrent operations may add up to more than elapsedSeconds"},
+          "meanSeconds": {"type": "number"},
+          "maxSeconds": {"type": "number"}
+        }
+      }
+    }
+  }
+}`,
+
+	"benchmark": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "benchmark",
+  "type": "object",
+  "required": ["schemaVersion", "template", "nodes", "failures", "create", "delete", "cycles"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "template": {"type": "string"},
+    "nodes": {"type": "integer"},
+    "failures": {"type": "integer"},
+    "create": ` + durationStatsSchema + `,
+    "delete": ` + durationStatsSchema + `,
+    "cycles": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["cluster", "createSeconds", "deleteSeconds"],
+        "properties": {
+          "cluster": {"type": "string"},
+          "createSeconds": {"type": "number"},
+          "deleteSeconds": {"type": "number", "description": "0 when the cluster was never created"},
+          "error": {"type": "string", "description": "Omitted when the cycle succeeded"}
+        }
+      }
+    }
+  }
+}`,
+
+	"conformance": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "conformance",
+  "type": "object",
+  "required": ["schemaVersion", "cluster", "template", "failures", "checks"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "cluster": {"type": "string", "description": "Empty when the checks stopped before a cluster name was generated"},
+    "template": {"type": "string"},
+    "failures": {"type": "integer"},
+    "checks": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["capability", "description", "status", "durationSeconds"],
+        "properties": {
+          "capability": {"type": "string"},
+          "description": {"type": "string"},
+          "status": {"type": "string", "enum": ["pass", "fail", "unsupported", "skipped"]},
+          "durationSeconds": {"type": "number"},
+          "details": {"type": "string", "description": "Why the check failed, was skipped or is unsupported"}
+        }
+      }
+    }
+  }
+}`,
+
+	"audit": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "audit",
+  "type": "object",
+  "required": ["schemaVersion", "entries"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "entries": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["time", "account", "host", "user", "operation", "cluster", "args", "result"],
+        "properties": {
+          "time": {"type": "string", "format": "date-time"},
+          "account": {"type": "string"},
+          "host": {"type": "string", "description": "The computer where carina ran"},
+          "user": {"type": "string", "description": "The user who ran carina"},
+          "operation": {"type": "string", "enum": ["create", "delete", "resize", "grow", "rebuild", "upgrade"]},
+          "cluster": {"type": "string"},
+          "clusterId": {"type": "string", "description": "Omitted when the cluster wasn't found"},
+          "args": {"type": "object", "additionalProperties": {"type": "string"}},
+          "result": {"type": "string", "enum": ["success", "failure"]},
+          "error": {"type": "string"}
+        }
+      }
+    }
+  }
+}`,
+
+	"error": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "error",
+  "type": "object",
+  "required": ["schemaVersion", "error"],
+  "properties": {
+    "schemaVersion": {"type": "integer"},
+    "error": {
+      "type": "object",
+      "required": ["message", "type", "code"],
+      "properties": {
+        "message": {"type": "string"},
+        "type": {"type": "string", "enum": ["api", "credentials", "input", "network", "timeout", "unknown"]},
+        "code": {"type": "string"},
+        "cluster": {"type": "string"},
+        "requestId": {"type": "string"},
+        "hints": {"type": "array", "items": {"type": "string"}},
+        "context": {"type": "object"}
+      }
+    }
+  }
+}`,
+}
+
+// SchemaNames returns the names of the JSON output schemas, sorted alphabetically
+func SchemaNames() []string {
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WriteSchema prints the JSON schema of a document printed with --format json
+func WriteSchema(name string) error {
+	schema, ok := schemas[name]
+	if !ok {
+		return fmt.Errorf("Unknown schema %s. Allowed values: %v", name, SchemaNames())
+	}
+
+	fmt.Println(schema)
+	return nil
+}
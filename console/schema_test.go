@@ -0,0 +1,100 @@
+package console
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jsonFields returns the json names of the fields in a struct
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		fields = append(fields, strings.Split(t.Field(i).Tag.Get("json"), ",")[0])
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// schemaFields returns the properties defined by a schema
+func schemaFields(t *testing.T, schema map[string]interface{}) []string {
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatal("The schema has no properties")
+	}
+
+	var fields []string
+	for field := range properties {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func parseSchema(t *testing.T, name string) map[string]interface{} {
+	var schema map[string]interface{}
+	err := json.Unmarshal([]byte(schemas[name]), &schema)
+	if err != nil {
+		t.Fatalf("The %s schema is invalid: %s", name, err)
+	}
+	return schema
+}
+
+func TestSchemasMatchOutput(t *testing.T) {
+	documents := map[string]reflect.Type{
+		"cluster":      reflect.TypeOf(clusterDocument{}),
+		"clusters":     reflect.TypeOf(clustersDocument{}),
+		"template":     reflect.TypeOf(templateDocument{}),
+		"templates":    reflect.TypeOf(templatesDocument{}),
+		"quotas":       reflect.TypeOf(quotasDocument{}),
+		"plan":         reflect.TypeOf(planDocument{}),
+		"error":        reflect.TypeOf(errorDocument{}),
+		"deprecations": reflect.TypeOf(deprecationsDocument{}),
+		"benchmark":    reflect.TypeOf(benchmarkDocument{}),
+		"conformance":  reflect.TypeOf(conformanceDocument{}),
+		"audit":        reflect.TypeOf(auditDocument{}),
+		"timing":       reflect.TypeOf(timingDocument{}),
+		"version":      reflect.TypeOf(versionDocument{}),
+	}
+	assert.Len(t, schemas, len(documents))
+
+	for name, doc := range documents {
+		schema := parseSchema(t, name)
+		assert.Equal(t, jsonFields(doc), schemaFields(t, schema), "The %s schema doesn't match the output", name)
+	}
+
+	cluster := parseSchema(t, "cluster")["properties"].(map[string]interface{})["cluster"].(map[string]interface{})
+	assert.Equal(t, jsonFields(reflect.TypeOf(clusterOutput{})), schemaFields(t, cluster))
+
+	template := parseSchema(t, "template")["properties"].(map[string]interface{})["template"].(map[string]interface{})
+	assert.Equal(t, jsonFields(reflect.TypeOf(templateDetailsOutput{})), schemaFields(t, template))
+
+	errorOutputSchema := parseSchema(t, "error")["properties"].(map[string]interface{})["error"].(map[string]interface{})
+	assert.Equal(t, jsonFields(reflect.TypeOf(errorOutput{})), schemaFields(t, errorOutputSchema))
+
+	deprecations := parseSchema(t, "deprecations")["properties"].(map[string]interface{})["deprecations"].(map[string]interface{})
+	assert.Equal(t, jsonFields(reflect.TypeOf(deprecationOutput{})), schemaFields(t, deprecations["items"].(map[string]interface{})))
+
+	benchmark := parseSchema(t, "benchmark")["properties"].(map[string]interface{})
+	assert.Equal(t, jsonFields(reflect.TypeOf(durationStatsOutput{})), schemaFields(t, benchmark["create"].(map[string]interface{})))
+	assert.Equal(t, jsonFields(reflect.TypeOf(benchmarkCycleOutput{})), schemaFields(t, benchmark["cycles"].(map[string]interface{})["items"].(map[string]interface{})))
+
+	conformance := parseSchema(t, "conformance")["properties"].(map[string]interface{})
+	assert.Equal(t, jsonFields(reflect.TypeOf(conformanceCheckOutput{})), schemaFields(t, conformance["checks"].(map[string]interface{})["items"].(map[string]interface{})))
+
+	audit := parseSchema(t, "audit")["properties"].(map[string]interface{})
+	assert.Equal(t, jsonFields(reflect.TypeOf(auditEntryOutput{})), schemaFields(t, audit["entries"].(map[string]interface{})["items"].(map[string]interface{})))
+
+	timing := parseSchema(t, "timing")["properties"].(map[string]interface{})["entries"].(map[string]interface{})
+	assert.Equal(t, jsonFields(reflect.TypeOf(timingEntryOutput{})), schemaFields(t, timing["items"].(map[string]interface{})))
+
+	update := parseSchema(t, "version")["properties"].(map[string]interface{})["update"].(map[string]interface{})
+	assert.Equal(t, jsonFields(reflect.TypeOf(versionUpdateOutput{})), schemaFields(t, update))
+
+	templates := parseSchema(t, "templates")["properties"].(map[string]interface{})["templates"].(map[string]interface{})
+	assert.Equal(t, jsonFields(reflect.TypeOf(templateOutput{})), schemaFields(t, templates["items"].(map[string]interface{})))
+}
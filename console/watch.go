@@ -0,0 +1,48 @@
+package console
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/getcarina/carina/common"
+)
+
+// clearScreen moves the cursor to the top left corner and clears the terminal
+const clearScreen = "\x1b[H\x1b[2J"
+
+// Watch calls refresh on an interval until carina is interrupted. On a terminal the screen is redrawn each time,
+// otherwise each refresh is appended to the output, and JSON documents are streamed as line-delimited JSON.
+// An error from the first refresh is returned, later errors are printed and the watch continues.
+func Watch(interval time.Duration, refresh func() error) error {
+	_, isTerminal := terminalWidth()
+	redraw := isTerminal && Format != FormatJSON
+
+	if Format == FormatJSON {
+		streamJSON = true
+		defer func() { streamJSON = false }()
+	}
+
+	for first := true; ; first = false {
+		if redraw {
+			fmt.Print(clearScreen)
+			Write("Every %s: %s\n", interval, time.Now().Format(time.RFC1123))
+		}
+
+		err := refresh()
+		if err != nil {
+			if first {
+				return err
+			}
+			common.Log.WriteWarning("WARNING: Unable to refresh, trying again in %s: %s", interval, err)
+		}
+
+		if !redraw && Format != FormatJSON {
+			fmt.Println()
+		}
+
+		err = common.Sleep(interval)
+		if err != nil {
+			return err
+		}
+	}
+}
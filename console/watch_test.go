@@ -0,0 +1,30 @@
+package console
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchReturnsFirstError(t *testing.T) {
+	var calls int
+	err := Watch(0, func() error {
+		calls++
+		return errors.New("unauthorized")
+	})
+
+	assert.EqualError(t, err, "unauthorized")
+	assert.Equal(t, 1, calls)
+}
+
+func TestStreamJSONIsLineDelimited(t *testing.T) {
+	defer func() { streamJSON = false }()
+	streamJSON = true
+
+	var buf bytes.Buffer
+	writeJSON(&buf, map[string]int{"a": 1, "b": 2})
+	writeJSON(&buf, map[string]int{"a": 3})
+	assert.Equal(t, "{\"a\":1,\"b\":2}\n{\"a\":3}\n", buf.String())
+}
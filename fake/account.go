@@ -0,0 +1,58 @@
+package fake
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+)
+
+// Account is a fake account, whose clusters and templates are read from a fixtures file
+type Account struct {
+	// Fixtures is the path to the JSON fixtures file
+	Fixtures string
+
+	// svc is shared by every operation, so that a change, such as creating a cluster, is seen by the rest of the command
+	svc  *testsupport.FakeClusterService
+	once sync.Once
+}
+
+// NewClusterService returns the ClusterService which serves the fixtures, which are read the first time it is called.
+// The fixtures should be validated with ReadFixtures when the account is configured, if they can't be read afterwards the account is empty.
+func (account *Account) NewClusterService() common.ClusterService {
+	account.once.Do(func() {
+		fixtures, err := ReadFixtures(account.Fixtures)
+		if err != nil {
+			common.Log.WriteWarning("%s", err)
+			fixtures = &Fixtures{}
+		}
+		account.svc = fixtures.NewClusterService()
+	})
+	return account.svc
+}
+
+// GetID returns a unique id for the account, e.g. fake-[fixtures path hash]
+func (account *Account) GetID() string {
+	path, err := filepath.Abs(account.Fixtures)
+	if err != nil {
+		path = account.Fixtures
+	}
+	hash := sha1.Sum([]byte(path))
+	return fmt.Sprintf("fake-%x", hash[:4])
+}
+
+// GetClusterPrefix returns a unique string to identity the account's clusters, which is the same as the account id
+func (account *Account) GetClusterPrefix() (string, error) {
+	return account.GetID(), nil
+}
+
+// BuildCache returns nil, the fake account doesn't have a token to cache
+func (account *Account) BuildCache() map[string]string {
+	return nil
+}
+
+// ApplyCache ignores the cached data
+func (account *Account) ApplyCache(c map[string]string) {}
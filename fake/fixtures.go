@@ -0,0 +1,169 @@
+// Package fake is a cloud which serves canned clusters and templates from a JSON fixtures file,
+// so that scripts and the cli can be tested without credentials for a real cloud
+package fake
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/pkg/errors"
+)
+
+// Fixtures are the quotas, templates and clusters of a fake account
+type Fixtures struct {
+	// Quotas default to 3 clusters with up to 10 nodes each
+	Quotas *QuotasFixture `json:"quotas,omitempty"`
+
+	Templates []TemplateFixture `json:"templates"`
+	Clusters  []ClusterFixture  `json:"clusters"`
+}
+
+// QuotasFixture is the quotas of a fake account
+type QuotasFixture struct {
+	MaxClusters        int `json:"maxClusters"`
+	MaxNodesPerCluster int `json:"maxNodesPerCluster"`
+}
+
+// TemplateFixture is a template offered by a fake account
+type TemplateFixture struct {
+	Name       string            `json:"name"`
+	COE        string            `json:"coe"`
+	COEVersion string            `json:"coeVersion,omitempty"`
+	HostType   string            `json:"hostType,omitempty"`
+	NodeFlavor string            `json:"nodeFlavor,omitempty"`
+	Deprecated bool              `json:"deprecated,omitempty"`
+	AutoScale  bool              `json:"autoscale,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+
+	// MaxNodes and GPU are reported as the template's capabilities, when either is set
+	MaxNodes int  `json:"maxNodes,omitempty"`
+	GPU      bool `json:"gpu,omitempty"`
+}
+
+// ClusterFixture is a cluster which already exists in a fake account. The ids are assigned in order, starting from 1.
+type ClusterFixture struct {
+	Name string `json:"name"`
+
+	// Template is the name of one of the templates in the fixtures
+	Template string `json:"template"`
+
+	// Nodes defaults to 1
+	Nodes int `json:"nodes,omitempty"`
+
+	// Status defaults to active
+	Status        string `json:"status,omitempty"`
+	StatusDetails string `json:"statusDetails,omitempty"`
+
+	// NodeSize is the resources and cost of each node, which are unknown when it isn't set
+	NodeSize *NodeSizeFixture `json:"nodeSize,omitempty"`
+}
+
+// NodeSizeFixture is the resources and estimated cost of each node in a fake cluster
+type NodeSizeFixture struct {
+	RAM        int     `json:"ram,omitempty"`
+	VCPUs      int     `json:"vcpus,omitempty"`
+	Disk       int     `json:"disk,omitempty"`
+	HourlyCost float64 `json:"hourlyCost,omitempty"`
+	Currency   string  `json:"currency,omitempty"`
+}
+
+// ReadFixtures reads and validates a fixtures file
+func ReadFixtures(path string) (*Fixtures, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "[fake] Unable to read the fixtures")
+	}
+
+	return ParseFixtures(contents)
+}
+
+// ParseFixtures parses and validates the contents of a fixtures file
+func ParseFixtures(contents []byte) (*Fixtures, error) {
+	var fixtures Fixtures
+	err := json.Unmarshal(contents, &fixtures)
+	if err != nil {
+		return nil, errors.Wrap(err, "[fake] Invalid fixtures")
+	}
+
+	templates := make(map[string]bool)
+	for _, template := range fixtures.Templates {
+		if template.Name == "" {
+			return nil, errors.New("[fake] Invalid fixtures, every template must have a name")
+		}
+		templates[template.Name] = true
+	}
+	for _, cluster := range fixtures.Clusters {
+		if cluster.Name == "" {
+			return nil, errors.New("[fake] Invalid fixtures, every cluster must have a name")
+		}
+		if !templates[cluster.Template] {
+			return nil, errors.Errorf("[fake] Invalid fixtures, the template of the cluster (%s), %q, is not one of the templates", cluster.Name, cluster.Template)
+		}
+	}
+
+	return &fixtures, nil
+}
+
+// NewClusterService serves the fixtures. Operations complete without waiting, and changes are not saved to the fixtures file.
+func (fixtures *Fixtures) NewClusterService() *testsupport.FakeClusterService {
+	templates := make(map[string]*testsupport.FakeClusterTemplate)
+	var fakeTemplates []*testsupport.FakeClusterTemplate
+	for _, template := range fixtures.Templates {
+		fakeTemplate := &testsupport.FakeClusterTemplate{
+			Name:              template.Name,
+			COE:               template.COE,
+			COEVersion:        template.COEVersion,
+			HostType:          template.HostType,
+			NodeFlavor:        template.NodeFlavor,
+			Deprecated:        template.Deprecated,
+			SupportsAutoScale: template.AutoScale,
+			Details:           template.Details,
+		}
+		if template.MaxNodes > 0 || template.GPU {
+			fakeTemplate.Capabilities = &common.TemplateCapabilities{MaxNodes: template.MaxNodes, AutoScale: template.AutoScale, GPU: template.GPU}
+		}
+		templates[template.Name] = fakeTemplate
+		fakeTemplates = append(fakeTemplates, fakeTemplate)
+	}
+
+	svc := testsupport.NewFakeClusterService(fakeTemplates...)
+	svc.PendingPolls = 0
+	if fixtures.Quotas != nil {
+		svc.Quotas = &common.Quotas{MaxClusters: fixtures.Quotas.MaxClusters, MaxNodesPerCluster: fixtures.Quotas.MaxNodesPerCluster}
+	}
+
+	for _, cluster := range fixtures.Clusters {
+		nodes := cluster.Nodes
+		if nodes < 1 {
+			nodes = 1
+		}
+
+		fakeCluster := testsupport.FakeCluster{
+			Name:          cluster.Name,
+			Template:      templates[cluster.Template],
+			Nodes:         nodes,
+			Status:        cluster.Status,
+			StatusDetails: cluster.StatusDetails,
+		}
+		if template, ok := templates[cluster.Template]; ok {
+			fakeCluster.Flavor = template.NodeFlavor
+			if template.SupportsAutoScale {
+				fakeCluster.AutoScale = &common.AutoScale{}
+			}
+		}
+		if size := cluster.NodeSize; size != nil {
+			fakeCluster.NodeSize = &common.NodeSize{
+				RAM:        size.RAM,
+				VCPUs:      size.VCPUs,
+				Disk:       size.Disk,
+				HourlyCost: size.HourlyCost,
+				Currency:   size.Currency,
+			}
+		}
+		svc.AddCluster(fakeCluster)
+	}
+
+	return svc
+}
@@ -0,0 +1,72 @@
+package fake
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFixtures = `{
+  "quotas": {"maxClusters": 5, "maxNodesPerCluster": 3},
+  "templates": [
+    {"name": "Kubernetes 1.5.2 on LXC", "coe": "kubernetes", "hostType": "lxc", "nodeFlavor": "m1.large", "autoscale": true},
+    {"name": "Swarm 1.11.2 on LXC", "coe": "swarm", "hostType": "lxc"}
+  ],
+  "clusters": [
+    {"name": "prod", "template": "Kubernetes 1.5.2 on LXC", "nodes": 3, "nodeSize": {"ram": 4096, "vcpus": 2, "hourlyCost": 0.12, "currency": "USD"}},
+    {"name": "broken", "template": "Swarm 1.11.2 on LXC", "status": "error", "statusDetails": "out of capacity"}
+  ]
+}`
+
+func TestFixturesClusterService(t *testing.T) {
+	fixtures, err := ParseFixtures([]byte(testFixtures))
+	require.NoError(t, err)
+	svc := fixtures.NewClusterService()
+
+	clusters, err := svc.ListClusters()
+	require.NoError(t, err)
+	require.Len(t, clusters, 2)
+	assert.Equal(t, "prod", clusters[0].GetName())
+	assert.Equal(t, "active", clusters[0].GetStatus())
+	assert.Equal(t, "error", clusters[1].GetStatus())
+	assert.Equal(t, "out of capacity", clusters[1].GetStatusDetails())
+	assert.Equal(t, "m1.large", clusters[0].GetFlavor())
+	assert.Equal(t, &common.NodeSize{RAM: 4096, VCPUs: 2, HourlyCost: 0.12, Currency: "USD"}, clusters[0].GetNodeSize())
+	assert.Nil(t, clusters[1].GetNodeSize())
+
+	templates, err := svc.ListClusterTemplates()
+	require.NoError(t, err)
+	assert.Len(t, templates, 2)
+
+	quotas, err := svc.GetQuotas()
+	require.NoError(t, err)
+	assert.Equal(t, 5, quotas.MaxClusters)
+}
+
+func TestParseInvalidFixtures(t *testing.T) {
+	_, err := ParseFixtures([]byte(`{"clusters": [{"name": "prod", "template": "missing"}]}`))
+	assert.Error(t, err)
+
+	_, err = ParseFixtures([]byte(`{"templates": [{"coe": "swarm"}]}`))
+	assert.Error(t, err)
+
+	_, err = ParseFixtures([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestFixturesConformance(t *testing.T) {
+	common.Log.RegisterTestLogger(t)
+
+	testsupport.RunConformanceTests(t, testsupport.ConformanceOptions{
+		NewService: func(t *testing.T) common.ClusterService {
+			fixtures, err := ParseFixtures([]byte(`{"templates": [{"name": "Kubernetes 1.5.2 on LXC", "coe": "kubernetes"}, {"name": "Swarm 1.11.2 on LXC", "coe": "swarm"}]}`))
+			require.NoError(t, err)
+			return fixtures.NewClusterService()
+		},
+		Template:                 "Kubernetes 1.5.2 on LXC",
+		AmbiguousTemplatePattern: "*LXC",
+	})
+}
@@ -16,9 +16,16 @@ func (mock *MockClusterService) ListClusterTemplates() ([]common.ClusterTemplate
 }
 
 type StubClusterTemplate struct {
-	Name     string
-	COE      string
-	HostType string
+	Name       string
+	COE        string
+	HostType   string
+	Deprecated bool
+	COEVersion string
+	NodeFlavor string
+	Details    map[string]string
+
+	Availability *common.TemplateAvailability
+	Capabilities *common.TemplateCapabilities
 }
 
 func (stub *StubClusterTemplate) GetName() string {
@@ -32,3 +39,27 @@ func (stub *StubClusterTemplate) GetCOE() string {
 func (stub *StubClusterTemplate) GetHostType() string {
 	return stub.HostType
 }
+
+func (stub *StubClusterTemplate) IsDeprecated() bool {
+	return stub.Deprecated
+}
+
+func (stub *StubClusterTemplate) GetCOEVersion() string {
+	return stub.COEVersion
+}
+
+func (stub *StubClusterTemplate) GetNodeFlavor() string {
+	return stub.NodeFlavor
+}
+
+func (stub *StubClusterTemplate) GetDetails() map[string]string {
+	return stub.Details
+}
+
+func (stub *StubClusterTemplate) GetAvailability() *common.TemplateAvailability {
+	return stub.Availability
+}
+
+func (stub *StubClusterTemplate) GetCapabilities() *common.TemplateCapabilities {
+	return stub.Capabilities
+}
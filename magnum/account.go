@@ -20,8 +20,28 @@ type Account struct {
 	Project          string
 	Domain           string
 	Region           string
-	token            string
-	endpoint         string
+
+	// ProjectID scopes the token to a project by its id, instead of by its name and domain
+	ProjectID string
+
+	// ProjectDomain and UserDomain are the Keystone v3 domains of the project and user, defaulting to Domain
+	ProjectDomain string
+	UserDomain    string
+
+	// ApplicationCredentialID, or ApplicationCredentialName with the UserName, selects a Keystone v3 application credential,
+	// which is used instead of the password when ApplicationCredentialSecret is set
+	ApplicationCredentialID     string
+	ApplicationCredentialName   string
+	ApplicationCredentialSecret string
+
+	token    string
+	endpoint string
+
+	// tokenExpires is when the token expires in RFC 3339 format, empty when unknown
+	tokenExpires string
+
+	// computeEndpoint is the OpenStack Compute (nova) endpoint, which manages the account's keypairs
+	computeEndpoint string
 }
 
 // NewClusterService create the appropriate ClusterService for the account
@@ -32,7 +52,7 @@ func (account *Account) NewClusterService() common.ClusterService {
 // GetID returns a unique id for the account, e.g. private-[authendpoint hash]-[username]
 func (account *Account) GetID() string {
 	hash := sha1.Sum([]byte(account.AuthEndpoint))
-	return fmt.Sprintf("private-%x-%s", hash[:4], account.UserName)
+	return fmt.Sprintf("private-%x-%s", hash[:4], account.getUser())
 }
 
 // GetClusterPrefix returns a unique string to identity the account's clusters, e.g. private-[endpoint hash]-[username]
@@ -43,7 +63,15 @@ func (account *Account) GetClusterPrefix() (string, error) {
 	}
 
 	hash := sha1.Sum([]byte(endpoint))
-	return fmt.Sprintf("private-%x-%s", hash[:4], account.UserName), nil
+	return fmt.Sprintf("private-%x-%s", hash[:4], account.getUser()), nil
+}
+
+// getUser identifies who the account authenticates as, the username or the id of the application credential
+func (account *Account) getUser() string {
+	if account.UserName == "" && account.ApplicationCredentialID != "" {
+		return account.ApplicationCredentialID
+	}
+	return account.UserName
 }
 
 func (account *Account) getEndpoint() string {
@@ -58,7 +86,11 @@ func (account *Account) Authenticate() (*gophercloud.ServiceClient, error) {
 	var magnumClient *gophercloud.ServiceClient
 
 	testAuth := func() error {
-		req, err := http.NewRequest("HEAD", account.AuthEndpoint+"/auth/tokens", nil)
+		tokensEndpoint := account.AuthEndpoint + "/auth/tokens"
+		if account.usesKeystoneV3() {
+			tokensEndpoint = account.getKeystoneV3Endpoint() + "/auth/tokens"
+		}
+		req, err := http.NewRequest("HEAD", tokensEndpoint, nil)
 		if err != nil {
 			return err
 		}
@@ -95,7 +127,7 @@ func (account *Account) Authenticate() (*gophercloud.ServiceClient, error) {
 			}
 
 			identity.TokenID = account.token
-			identity.ReauthFunc = reauthenticate(identity, authOptions)
+			identity.ReauthFunc = account.reauthenticate(identity, authOptions)
 			identity.UserAgent.Prepend(common.BuildUserAgent())
 			identity.HTTPClient = *common.NewHTTPClient()
 			identity.EndpointLocator = func(opts gophercloud.EndpointOpts) (string, error) {
@@ -112,8 +144,16 @@ func (account *Account) Authenticate() (*gophercloud.ServiceClient, error) {
 		// Clear cache and authenticate with the password
 		common.Log.WriteDebug("[magnum] Discarding expired cached token and endpoint")
 		account.token = ""
+		account.tokenExpires = ""
 		account.endpoint = ""
 		return account.Authenticate()
+	} else if account.usesKeystoneV3() {
+		common.Log.WriteDebug("[magnum] Attempting to authenticate with Keystone v3")
+		var err error
+		magnumClient, err = account.authenticateKeystoneV3(authOptions)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		common.Log.WriteDebug("[magnum] Attempting to authenticate with a password")
 		identity, err := openstack.AuthenticatedClient(*authOptions)
@@ -124,6 +164,13 @@ func (account *Account) Authenticate() (*gophercloud.ServiceClient, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "[magnum] Unable to create a Magnum client")
 		}
+
+		computeClient, err := openstack.NewComputeV2(identity, gophercloud.EndpointOpts{Region: account.Region})
+		if err != nil {
+			common.Log.WriteDebug("[magnum] Unable to find the compute endpoint, keypairs will not be available: %s", err)
+		} else {
+			account.computeEndpoint = computeClient.Endpoint
+		}
 	}
 	common.Log.WriteDebug("[magnum] Authentication sucessful")
 
@@ -141,8 +188,61 @@ func (account *Account) Authenticate() (*gophercloud.ServiceClient, error) {
 	return magnumClient, nil
 }
 
-func reauthenticate(identity *gophercloud.ProviderClient, authOptions *gophercloud.AuthOptions) func() error {
+// authenticateKeystoneV3 requests a token from Keystone v3, and creates a Magnum client using the endpoints from its service catalog
+func (account *Account) authenticateKeystoneV3(authOptions *gophercloud.AuthOptions) (*gophercloud.ServiceClient, error) {
+	token, err := account.requestKeystoneV3Token()
+	if err != nil {
+		return nil, err
+	}
+	account.tokenExpires = token.ExpiresAt
+
+	endpoint, err := token.findEndpoint(account.Region, magnumServiceTypes...)
+	if err != nil {
+		if account.EndpointOverride == "" {
+			return nil, errors.Wrap(err, "[magnum] Unable to create a Magnum client")
+		}
+		endpoint = account.EndpointOverride
+	}
+
+	computeEndpoint, err := token.findEndpoint(account.Region, computeServiceType)
+	if err != nil {
+		common.Log.WriteDebug("[magnum] Unable to find the compute endpoint, keypairs will not be available: %s", err)
+	} else {
+		account.computeEndpoint = computeEndpoint
+	}
+
+	identity, err := openstack.NewClient(account.AuthEndpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "[magnum] Unable to create a new OpenStack Identity client")
+	}
+
+	identity.TokenID = token.ID
+	identity.ReauthFunc = account.reauthenticate(identity, authOptions)
+	identity.UserAgent.Prepend(common.BuildUserAgent())
+	identity.HTTPClient = *common.NewHTTPClient()
+	identity.EndpointLocator = func(opts gophercloud.EndpointOpts) (string, error) {
+		// The service catalog was already read from the token response
+		return endpoint, nil
+	}
+
+	magnumClient, err := openstack.NewContainerOrchestrationV1(identity, gophercloud.EndpointOpts{Region: account.Region})
+	if err != nil {
+		return nil, errors.Wrap(err, "[magnum] Unable to create a Magnum client")
+	}
+	return magnumClient, nil
+}
+
+func (account *Account) reauthenticate(identity *gophercloud.ProviderClient, authOptions *gophercloud.AuthOptions) func() error {
 	return func() error {
+		if account.usesKeystoneV3() {
+			token, err := account.requestKeystoneV3Token()
+			if err != nil {
+				return err
+			}
+			identity.TokenID = token.ID
+			account.tokenExpires = token.ExpiresAt
+			return nil
+		}
 		return openstack.Authenticate(identity, *authOptions)
 	}
 }
@@ -150,8 +250,10 @@ func reauthenticate(identity *gophercloud.ProviderClient, authOptions *gopherclo
 // BuildCache builds the set of data to cache
 func (account *Account) BuildCache() map[string]string {
 	return map[string]string{
-		"endpoint": account.endpoint,
-		"token":    account.token,
+		"endpoint":         account.endpoint,
+		"token":            account.token,
+		"token-expires":    account.tokenExpires,
+		"compute-endpoint": account.computeEndpoint,
 	}
 }
 
@@ -159,4 +261,6 @@ func (account *Account) BuildCache() map[string]string {
 func (account *Account) ApplyCache(c map[string]string) {
 	account.endpoint = c["endpoint"]
 	account.token = c["token"]
+	account.tokenExpires = c["token-expires"]
+	account.computeEndpoint = c["compute-endpoint"]
 }
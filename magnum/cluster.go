@@ -3,6 +3,7 @@ package magnum
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/getcarina/carina/common"
 	"github.com/gophercloud/gophercloud/openstack/containerorchestration/v1/baymodels"
@@ -36,6 +37,10 @@ func (cluster *Cluster) GetTemplate() common.ClusterTemplate {
 
 // GetFlavor returns the flavor of the nodes in the cluster
 func (cluster *Cluster) GetFlavor() string {
+	// The template is only loaded when the cluster is retrieved with its template
+	if cluster.Template == nil {
+		return ""
+	}
 	return cluster.Template.FlavorID
 }
 
@@ -58,3 +63,28 @@ func (cluster *Cluster) GetStatusDetails() string {
 
 	return ""
 }
+
+// GetLabels is not supported by the API, labels are stored locally by the client
+func (cluster *Cluster) GetLabels() map[string]string {
+	return nil
+}
+
+// GetAutoScale is not supported
+func (cluster *Cluster) GetAutoScale() *common.AutoScale {
+	return nil
+}
+
+// GetCreated is not supported by gophercloud's bays, the client falls back to when it created the cluster
+func (cluster *Cluster) GetCreated() time.Time {
+	return time.Time{}
+}
+
+// GetUpdated is not supported by gophercloud's bays
+func (cluster *Cluster) GetUpdated() time.Time {
+	return time.Time{}
+}
+
+// GetNodeSize is not supported, only the flavor of the nodes is known
+func (cluster *Cluster) GetNodeSize() *common.NodeSize {
+	return nil
+}
@@ -1,6 +1,9 @@
 package magnum
 
 import (
+	"strconv"
+
+	"github.com/getcarina/carina/common"
 	"github.com/gophercloud/gophercloud/openstack/containerorchestration/v1/baymodels"
 )
 
@@ -23,3 +26,54 @@ func (template *ClusterTemplate) GetCOE() string {
 func (template *ClusterTemplate) GetHostType() string {
 	return template.ServerType
 }
+
+// IsDeprecated is not supported
+func (template *ClusterTemplate) IsDeprecated() bool {
+	return false
+}
+
+// GetCOEVersion returns the version in the template name, magnum does not report the COE version
+func (template *ClusterTemplate) GetCOEVersion() string {
+	return common.ParseTemplateVersion(template.Name)
+}
+
+// GetNodeFlavor returns the flavor of the nodes
+func (template *ClusterTemplate) GetNodeFlavor() string {
+	return template.FlavorID
+}
+
+// GetDetails returns the bay model's image, master flavor, storage and network settings
+func (template *ClusterTemplate) GetDetails() map[string]string {
+	details := map[string]string{
+		"ID":                 template.ID,
+		"Image":              template.ImageID,
+		"Master Flavor":      template.MasterFlavorID,
+		"Network Driver":     template.NetworkDriver,
+		"DNS Nameserver":     template.DNSNameServer,
+		"External Network":   template.ExternalNetworkID,
+		"Fixed Network":      template.FixedNetwork,
+		"Docker Volume Size": "",
+		"TLS Disabled":       strconv.FormatBool(template.TLSDisabled),
+	}
+	if template.DockerVolumeSize > 0 {
+		details["Docker Volume Size"] = strconv.Itoa(template.DockerVolumeSize) + " GB"
+	}
+
+	// Omit the settings which are not configured
+	for key, value := range details {
+		if value == "" {
+			delete(details, key)
+		}
+	}
+	return details
+}
+
+// GetAvailability is not supported, magnum doesn't report capacity for bay models
+func (template *ClusterTemplate) GetAvailability() *common.TemplateAvailability {
+	return nil
+}
+
+// GetCapabilities is not supported, magnum doesn't report what bay models support
+func (template *ClusterTemplate) GetCapabilities() *common.TemplateCapabilities {
+	return nil
+}
@@ -0,0 +1,287 @@
+package magnum
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMagnumAPI is a stateful stand-in for Keystone v3 and the Magnum bays API, with just enough behavior to run the conformance tests
+type fakeMagnumAPI struct {
+	lock      sync.Mutex
+	url       string
+	baymodels []map[string]interface{}
+	bays      map[string]map[string]interface{}
+	nextID    int
+
+	// ca signs the client certificates requested for the bays
+	ca    *x509.Certificate
+	caKey *ecdsa.PrivateKey
+	caPEM []byte
+}
+
+func newFakeMagnumAPI(t *testing.T) *fakeMagnumAPI {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake-magnum-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &fakeMagnumAPI{
+		baymodels: []map[string]interface{}{
+			{"uuid": "5a2c1f0e-0000-4000-8000-000000000001", "name": "Swarm 1.11.2 on VM", "coe": "swarm", "server_type": "vm", "tls_disabled": false},
+			{"uuid": "5a2c1f0e-0000-4000-8000-000000000002", "name": "Kubernetes 1.5.2 on VM", "coe": "kubernetes", "server_type": "vm", "tls_disabled": false},
+		},
+		bays:  make(map[string]map[string]interface{}),
+		ca:    ca,
+		caKey: key,
+		caPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// writeError responds with an error in the format used by Magnum
+func writeError(w http.ResponseWriter, status int, title string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]interface{}{{"status": status, "code": "client", "title": title, "detail": title}},
+	})
+}
+
+// withStatus copies a bay, so that a response can report a transitional status without changing the stored bay
+func withStatus(bay map[string]interface{}, status string) map[string]interface{} {
+	result := make(map[string]interface{}, len(bay))
+	for key, value := range bay {
+		result[key] = value
+	}
+	result["status"] = status
+	return result
+}
+
+// lookupBay finds a bay by its uuid or unique name
+func (api *fakeMagnumAPI) lookupBay(token string) (map[string]interface{}, bool) {
+	if bay, ok := api.bays[token]; ok {
+		return bay, true
+	}
+
+	var match map[string]interface{}
+	for _, bay := range api.bays {
+		if bay["name"] == token {
+			if match != nil {
+				return nil, false
+			}
+			match = bay
+		}
+	}
+	return match, match != nil
+}
+
+func (api *fakeMagnumAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/v3/auth/tokens":
+		w.Header().Set("X-Subject-Token", "fake-token")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"token": map[string]interface{}{
+			"expires_at": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			"catalog": []map[string]interface{}{
+				{"type": "container-infra", "endpoints": []map[string]interface{}{{"interface": "public", "region": "RegionOne", "url": api.url + "/v1"}}},
+			},
+		}})
+	case r.Method == "GET" && r.URL.Path == "/v1/baymodels":
+		json.NewEncoder(w).Encode(map[string]interface{}{"baymodels": api.baymodels})
+	case r.Method == "GET" && len(parts) == 3 && parts[1] == "baymodels":
+		for _, baymodel := range api.baymodels {
+			if baymodel["uuid"] == parts[2] || baymodel["name"] == parts[2] {
+				json.NewEncoder(w).Encode(baymodel)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, fmt.Sprintf("BayModel %s could not be found", parts[2]))
+	case r.Method == "GET" && r.URL.Path == "/v1/bays":
+		bays := []map[string]interface{}{}
+		for _, bay := range api.bays {
+			bays = append(bays, bay)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"bays": bays})
+	case r.Method == "POST" && r.URL.Path == "/v1/bays":
+		api.createBay(w, r)
+	case len(parts) == 3 && parts[1] == "bays":
+		bay, ok := api.lookupBay(parts[2])
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Bay %s could not be found", parts[2]))
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			json.NewEncoder(w).Encode(bay)
+			// A bay being deleted is reported once, then it is gone
+			if bay["status"] == "DELETE_IN_PROGRESS" {
+				delete(api.bays, bay["uuid"].(string))
+			}
+		case "DELETE":
+			bay["status"] = "DELETE_IN_PROGRESS"
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	case r.Method == "POST" && r.URL.Path == "/v1/certificates":
+		api.signCertificate(w, r)
+	case r.Method == "GET" && len(parts) == 3 && parts[1] == "certificates":
+		bay, ok := api.lookupBay(parts[2])
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("Bay %s could not be found", parts[2]))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"bay_uuid": bay["uuid"], "pem": string(api.caPEM)})
+	default:
+		writeError(w, http.StatusNotFound, "unexpected request: "+r.Method+" "+r.RequestURI)
+	}
+}
+
+func (api *fakeMagnumAPI) createBay(w http.ResponseWriter, r *http.Request) {
+	var opts struct {
+		Name       string `json:"name"`
+		BayModelID string `json:"baymodel_id"`
+		Nodes      int    `json:"node_count"`
+	}
+	json.NewDecoder(r.Body).Decode(&opts)
+
+	var found bool
+	for _, baymodel := range api.baymodels {
+		found = found || baymodel["uuid"] == opts.BayModelID
+	}
+	if !found {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("BayModel %s could not be found", opts.BayModelID))
+		return
+	}
+
+	api.nextID++
+	id := fmt.Sprintf("6b3d2a1f-0000-4000-8000-%012d", api.nextID)
+	bay := map[string]interface{}{
+		"uuid":         id,
+		"name":         opts.Name,
+		"baymodel_id":  opts.BayModelID,
+		"node_count":   opts.Nodes,
+		"master_count": 1,
+		"api_address":  "https://10.0.0.1:6443",
+		"status":       "CREATE_COMPLETE",
+	}
+	api.bays[id] = bay
+
+	// The bay is complete the next time that it is retrieved
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(withStatus(bay, "CREATE_IN_PROGRESS"))
+}
+
+// signCertificate signs a client certificate signing request with the bay's certificate authority
+func (api *fakeMagnumAPI) signCertificate(w http.ResponseWriter, r *http.Request) {
+	var opts struct {
+		BayID string `json:"bay_uuid"`
+		CSR   string `json:"csr"`
+	}
+	json.NewDecoder(r.Body).Decode(&opts)
+
+	bay, ok := api.lookupBay(opts.BayID)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Bay %s could not be found", opts.BayID))
+		return
+	}
+
+	block, _ := pem.Decode([]byte(opts.CSR))
+	if block == nil {
+		writeError(w, http.StatusBadRequest, "Invalid csr")
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(api.nextID) + 100),
+		Subject:      csr.Subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, api.ca, csr.PublicKey, api.caKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bay_uuid": bay["uuid"],
+		"csr":      opts.CSR,
+		"pem":      string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	})
+}
+
+func TestMagnumConformance(t *testing.T) {
+	common.Log.RegisterTestLogger(t)
+
+	var servers []*httptest.Server
+	defer func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	}()
+
+	testsupport.RunConformanceTests(t, testsupport.ConformanceOptions{
+		NewService: func(t *testing.T) common.ClusterService {
+			api := newFakeMagnumAPI(t)
+			server := httptest.NewServer(api)
+			api.url = server.URL
+			servers = append(servers, server)
+
+			// Application credentials authenticate with Keystone v3 directly, instead of through gophercloud
+			account := &Account{
+				AuthEndpoint:                server.URL,
+				Region:                      "RegionOne",
+				ApplicationCredentialID:     "fake-credential",
+				ApplicationCredentialSecret: "fake-secret",
+			}
+			return account.NewClusterService()
+		},
+		Template: "Kubernetes 1.5.2 on VM",
+		// Magnum looks up baymodels by their exact name
+		AmbiguousTemplatePattern: "",
+		IsActive: func(status string) bool {
+			return status == "CREATE_COMPLETE"
+		},
+	})
+}
@@ -0,0 +1,138 @@
+package magnum
+
+import (
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/gophercloud/gophercloud"
+	"github.com/pkg/errors"
+)
+
+// Keypair is an SSH keypair registered with OpenStack Compute (nova)
+type Keypair struct {
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	PublicKey   string `json:"public_key"`
+}
+
+// GetName returns the unique keypair name
+func (keypair *Keypair) GetName() string {
+	return keypair.Name
+}
+
+// GetFingerprint returns the fingerprint of the public key
+func (keypair *Keypair) GetFingerprint() string {
+	return keypair.Fingerprint
+}
+
+// computeClient returns a client for the compute API, which manages the account's keypairs.
+// It shares the authentication of the magnum client.
+func (magnum *Magnum) computeClient() (*gophercloud.ServiceClient, error) {
+	err := magnum.init()
+	if err != nil {
+		return nil, err
+	}
+
+	if magnum.Account.computeEndpoint == "" {
+		return nil, errors.New("[magnum] Keypairs are not available because the compute endpoint was not found in the service catalog")
+	}
+
+	return &gophercloud.ServiceClient{
+		ProviderClient: magnum.client.ProviderClient,
+		Endpoint:       strings.TrimSuffix(magnum.Account.computeEndpoint, "/") + "/",
+	}, nil
+}
+
+// ListKeypairs retrieves the SSH keypairs registered with the account
+func (magnum *Magnum) ListKeypairs() ([]common.Keypair, error) {
+	compute, err := magnum.computeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	common.Log.WriteDebug("[magnum] Listing keypairs")
+
+	// The keypairs are nested in a wrapper object, e.g. {"keypairs": [{"keypair": {...}}]}
+	var result struct {
+		Keypairs []struct {
+			Keypair Keypair `json:"keypair"`
+		} `json:"keypairs"`
+	}
+	_, err = compute.Get(compute.ServiceURL("os-keypairs"), &result, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "[magnum] Unable to list keypairs")
+	}
+
+	keypairs := make([]common.Keypair, len(result.Keypairs))
+	for i := range result.Keypairs {
+		keypairs[i] = &result.Keypairs[i].Keypair
+	}
+	return keypairs, nil
+}
+
+// CreateClusterWithSSHKey creates a new cluster with the keypair installed on its nodes.
+// A public key is registered as a keypair first, unless a keypair with the same key already exists.
+func (magnum *Magnum) CreateClusterWithSSHKey(name string, template string, nodes int, key common.SSHKey, driverOptions map[string]string) (common.Cluster, error) {
+	if template == "" {
+		return nil, errors.New("--template is required")
+	}
+
+	err := validateDriverOptions(driverOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	keypair := key.KeypairName
+	if key.PublicKey != "" {
+		keypair, err = magnum.importKeypair("carina-"+name, key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return magnum.createBay(name, template, nodes, keypair, driverOptions)
+}
+
+// importKeypair registers a public key, returning the name of the keypair
+func (magnum *Magnum) importKeypair(name string, publicKey string) (string, error) {
+	keypairs, err := magnum.ListKeypairs()
+	if err != nil {
+		return "", err
+	}
+	for _, keypair := range keypairs {
+		if sameSSHKey(keypair.(*Keypair).PublicKey, publicKey) {
+			common.Log.WriteDebug("[magnum] Using the existing keypair (%s) for the SSH key", keypair.GetName())
+			return keypair.GetName(), nil
+		}
+	}
+
+	compute, err := magnum.computeClient()
+	if err != nil {
+		return "", err
+	}
+
+	common.Log.WriteDebug("[magnum] Registering the SSH key as keypair (%s)", name)
+	body := map[string]interface{}{
+		"keypair": map[string]string{
+			"name":       name,
+			"public_key": publicKey,
+		},
+	}
+	_, err = compute.Post(compute.ServiceURL("os-keypairs"), body, nil, &gophercloud.RequestOpts{
+		OkCodes: []int{200, 201},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "[magnum] Unable to register the SSH key as keypair (%s)", name)
+	}
+	return name, nil
+}
+
+// sameSSHKey compares the type and key data of two public keys, ignoring the comment
+func sameSSHKey(a string, b string) bool {
+	fieldsA := strings.Fields(a)
+	fieldsB := strings.Fields(b)
+	if len(fieldsA) < 2 || len(fieldsB) < 2 {
+		return false
+	}
+	return fieldsA[0] == fieldsB[0] && fieldsA[1] == fieldsB[1]
+}
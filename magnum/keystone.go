@@ -0,0 +1,196 @@
+package magnum
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// magnumServiceTypes are the service catalog types used by Magnum, container-infra on current releases and container on older ones
+var magnumServiceTypes = []string{"container-infra", "container"}
+
+// computeServiceType is the service catalog type of OpenStack Compute (nova)
+const computeServiceType = "compute"
+
+// keystoneToken is a Keystone v3 token and the service catalog returned with it
+type keystoneToken struct {
+	ID        string
+	ExpiresAt string
+	Catalog   []keystoneService
+}
+
+type keystoneService struct {
+	Type      string             `json:"type"`
+	Endpoints []keystoneEndpoint `json:"endpoints"`
+}
+
+type keystoneEndpoint struct {
+	Interface string `json:"interface"`
+	Region    string `json:"region"`
+	RegionID  string `json:"region_id"`
+	URL       string `json:"url"`
+}
+
+// usesKeystoneV3 returns if the account must authenticate with Keystone v3 directly, instead of through gophercloud,
+// because it uses an application credential or settings which gophercloud can't scope the token with
+func (account *Account) usesKeystoneV3() bool {
+	return account.usesApplicationCredential() || account.ProjectID != "" ||
+		account.getProjectDomain() != account.Domain || account.getUserDomain() != account.Domain
+}
+
+// usesApplicationCredential returns if the account authenticates with an application credential instead of a password
+func (account *Account) usesApplicationCredential() bool {
+	return account.ApplicationCredentialSecret != ""
+}
+
+// getProjectDomain returns the domain of the project, defaulting to --domain
+func (account *Account) getProjectDomain() string {
+	if account.ProjectDomain != "" {
+		return account.ProjectDomain
+	}
+	return account.Domain
+}
+
+// getUserDomain returns the domain of the user, defaulting to --domain
+func (account *Account) getUserDomain() string {
+	if account.UserDomain != "" {
+		return account.UserDomain
+	}
+	return account.Domain
+}
+
+// getKeystoneV3Endpoint returns the Keystone v3 endpoint, adding the version when the auth endpoint is the unversioned root
+func (account *Account) getKeystoneV3Endpoint() string {
+	endpoint := strings.TrimRight(account.AuthEndpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v3") {
+		endpoint += "/v3"
+	}
+	return endpoint
+}
+
+// buildKeystoneV3AuthRequest builds the body of a Keystone v3 token request.
+// Application credentials are already scoped to a project, so only password authentication specifies the scope.
+func (account *Account) buildKeystoneV3AuthRequest() map[string]interface{} {
+	var identity map[string]interface{}
+	var scope map[string]interface{}
+
+	if account.usesApplicationCredential() {
+		credential := map[string]interface{}{"secret": account.ApplicationCredentialSecret}
+		if account.ApplicationCredentialID != "" {
+			credential["id"] = account.ApplicationCredentialID
+		} else {
+			credential["name"] = account.ApplicationCredentialName
+			credential["user"] = map[string]interface{}{
+				"name":   account.UserName,
+				"domain": map[string]string{"name": account.getUserDomain()},
+			}
+		}
+		identity = map[string]interface{}{
+			"methods":                []string{"application_credential"},
+			"application_credential": credential,
+		}
+	} else {
+		identity = map[string]interface{}{
+			"methods": []string{"password"},
+			"password": map[string]interface{}{
+				"user": map[string]interface{}{
+					"name":     account.UserName,
+					"password": account.Password,
+					"domain":   map[string]string{"name": account.getUserDomain()},
+				},
+			},
+		}
+
+		switch {
+		case account.ProjectID != "":
+			scope = map[string]interface{}{"project": map[string]string{"id": account.ProjectID}}
+		case account.Project != "":
+			scope = map[string]interface{}{"project": map[string]interface{}{
+				"name":   account.Project,
+				"domain": map[string]string{"name": account.getProjectDomain()},
+			}}
+		}
+	}
+
+	auth := map[string]interface{}{"identity": identity}
+	if scope != nil {
+		auth["scope"] = scope
+	}
+	return map[string]interface{}{"auth": auth}
+}
+
+// requestKeystoneV3Token authenticates with Keystone v3, returning the token and service catalog
+func (account *Account) requestKeystoneV3Token() (*keystoneToken, error) {
+	body, err := json.Marshal(account.buildKeystoneV3AuthRequest())
+	if err != nil {
+		return nil, errors.Wrap(err, "[magnum] Unable to build the Keystone v3 token request")
+	}
+
+	endpoint := account.getKeystoneV3Endpoint() + "/auth/tokens"
+	common.Log.WriteDebug("[magnum] Requesting a Keystone v3 token from %s", endpoint)
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "[magnum] Unable to build the Keystone v3 token request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", common.BuildUserAgent())
+
+	resp, err := common.NewHTTPClient().Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "[magnum] Authentication failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, common.NewHTTPError(resp.StatusCode, fmt.Errorf("[magnum] Authentication failed: %s", resp.Status))
+	}
+
+	var result struct {
+		Token struct {
+			ExpiresAt string            `json:"expires_at"`
+			Catalog   []keystoneService `json:"catalog"`
+		} `json:"token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return nil, errors.Wrap(err, "[magnum] Unable to read the Keystone v3 token response")
+	}
+
+	token := &keystoneToken{
+		ID:        resp.Header.Get("X-Subject-Token"),
+		ExpiresAt: result.Token.ExpiresAt,
+		Catalog:   result.Token.Catalog,
+	}
+	if token.ID == "" {
+		return nil, errors.New("[magnum] Authentication failed: Keystone didn't return a token")
+	}
+	return token, nil
+}
+
+// findEndpoint returns the public endpoint of the first service type found in the catalog, in the account's region
+func (token *keystoneToken) findEndpoint(region string, serviceTypes ...string) (string, error) {
+	for _, serviceType := range serviceTypes {
+		for _, service := range token.Catalog {
+			if service.Type != serviceType {
+				continue
+			}
+
+			for _, endpoint := range service.Endpoints {
+				if endpoint.Interface != "public" {
+					continue
+				}
+				if region != "" && endpoint.Region != region && endpoint.RegionID != region {
+					continue
+				}
+				return endpoint.URL, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("Unable to find a %s endpoint in the %s region of the service catalog. Use --endpoint to specify the endpoint", strings.Join(serviceTypes, " or "), region)
+}
@@ -0,0 +1,77 @@
+package magnum
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestKeystoneV3TokenWithApplicationCredential(t *testing.T) {
+	var request map[string]interface{}
+	identity := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/auth/tokens", r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&request)
+
+		w.Header().Set("X-Subject-Token", "fake-token")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintln(w, `{"token":{"expires_at":"2017-02-01T12:00:00.000000Z","catalog":[
+			{"type":"compute","endpoints":[{"interface":"public","region":"RegionOne","url":"https://example.com:8774"}]},
+			{"type":"container-infra","endpoints":[
+				{"interface":"internal","region":"RegionOne","url":"http://internal:9511"},
+				{"interface":"public","region":"RegionTwo","url":"https://two.example.com:9511"},
+				{"interface":"public","region":"RegionOne","url":"https://example.com:9511"}]}]}}`)
+	}))
+	defer identity.Close()
+
+	account := &Account{
+		AuthEndpoint:                identity.URL,
+		Domain:                      "default",
+		ApplicationCredentialID:     "fake-credential",
+		ApplicationCredentialSecret: "fake-secret",
+	}
+	assert.True(t, account.usesKeystoneV3())
+
+	token, err := account.requestKeystoneV3Token()
+	assert.Nil(t, err)
+	assert.Equal(t, "fake-token", token.ID)
+	assert.Equal(t, "2017-02-01T12:00:00.000000Z", token.ExpiresAt)
+
+	auth := request["auth"].(map[string]interface{})
+	assert.NotContains(t, auth, "scope", "Application credentials are already scoped")
+	credential := auth["identity"].(map[string]interface{})["application_credential"].(map[string]interface{})
+	assert.Equal(t, "fake-credential", credential["id"])
+	assert.Equal(t, "fake-secret", credential["secret"])
+
+	endpoint, err := token.findEndpoint("RegionOne", magnumServiceTypes...)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com:9511", endpoint)
+
+	_, err = token.findEndpoint("RegionThree", magnumServiceTypes...)
+	assert.NotNil(t, err)
+}
+
+func TestBuildKeystoneV3AuthRequestScopesToProject(t *testing.T) {
+	account := &Account{
+		UserName:      "fake-user",
+		Password:      "fake-password",
+		Project:       "fake-project",
+		Domain:        "default",
+		ProjectDomain: "fake-project-domain",
+	}
+	assert.True(t, account.usesKeystoneV3())
+
+	auth := account.buildKeystoneV3AuthRequest()["auth"].(map[string]interface{})
+	project := auth["scope"].(map[string]interface{})["project"].(map[string]interface{})
+	assert.Equal(t, "fake-project", project["name"])
+	assert.Equal(t, map[string]string{"name": "fake-project-domain"}, project["domain"])
+
+	user := auth["identity"].(map[string]interface{})["password"].(map[string]interface{})["user"].(map[string]interface{})
+	assert.Equal(t, map[string]string{"name": "default"}, user["domain"])
+
+	account.ProjectDomain = ""
+	assert.False(t, account.usesKeystoneV3(), "gophercloud handles a single domain")
+}
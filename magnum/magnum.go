@@ -3,6 +3,7 @@ package magnum
 import (
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
@@ -19,6 +20,8 @@ import (
 	"github.com/pkg/errors"
 )
 
+var driverOptionKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
 // Magnum is an adapter between the cli and the OpenStack COE API (Magnum)
 type Magnum struct {
 	client        *gophercloud.ServiceClient
@@ -37,9 +40,57 @@ func (magnum *Magnum) init() error {
 	return nil
 }
 
+// Authenticate authenticates with the account's credentials, returning the endpoint of the Magnum API
+func (magnum *Magnum) Authenticate() (string, error) {
+	err := magnum.init()
+	if err != nil {
+		return "", err
+	}
+	return magnum.client.Endpoint, nil
+}
+
+// operationMinVersions are the Magnum API versions which added the operations used by carina
+var operationMinVersions = map[string]string{
+	common.OperationUpgrade: "1.8",
+}
+
+// ProbeCapabilities reads the range of API versions supported by Magnum from the version headers.
+// Autoscaling, growing, resizing and rebuilding bays aren't supported from the carina cli.
+func (magnum *Magnum) ProbeCapabilities() (*common.APICapabilities, error) {
+	err := magnum.init()
+	if err != nil {
+		return nil, err
+	}
+
+	common.Log.WriteDebug("[magnum] Probing the API versions supported by %s", magnum.client.Endpoint)
+	resp, err := magnum.client.Get(magnum.client.Endpoint, nil, &gophercloud.RequestOpts{
+		OkCodes: []int{http.StatusOK},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "[magnum] Unable to probe the API version")
+	}
+
+	// e.g. OpenStack-API-Maximum-Version: container-infra 1.8
+	parseVersion := func(header string) string {
+		return strings.TrimSpace(strings.TrimPrefix(resp.Header.Get(header), "container-infra"))
+	}
+	capabilities := &common.APICapabilities{
+		Endpoint:    magnum.client.Endpoint,
+		MinVersion:  parseVersion("OpenStack-API-Minimum-Version"),
+		MaxVersion:  parseVersion("OpenStack-API-Maximum-Version"),
+		Unsupported: []string{common.OperationAutoScale, common.OperationGrow, common.OperationRebuild, common.OperationResize},
+	}
+	for operation, minVersion := range operationMinVersions {
+		if capabilities.MaxVersion != "" && common.CompareVersions(capabilities.MaxVersion, minVersion) < 0 {
+			capabilities.Unsupported = append(capabilities.Unsupported, operation)
+		}
+	}
+	return capabilities, nil
+}
+
 // GetQuotas retrieves the quotas set for the account
-func (magnum *Magnum) GetQuotas() (common.Quotas, error) {
-	return nil, errors.New("[magnum] Retrieving user quotas from the carina cli is not supported yet")
+func (magnum *Magnum) GetQuotas() (*common.Quotas, error) {
+	return nil, common.ErrQuotasUnsupported
 }
 
 // CreateCluster creates a new cluster and prints the cluster information
@@ -76,6 +127,75 @@ func (magnum *Magnum) CreateCluster(name string, template string, nodes int) (co
 	return cluster, err
 }
 
+// CreateClusterWithDriverOptions creates a new cluster, passing the driver options through as bay labels
+func (magnum *Magnum) CreateClusterWithDriverOptions(name string, template string, nodes int, driverOptions map[string]string) (common.Cluster, error) {
+	if len(driverOptions) == 0 {
+		return magnum.CreateCluster(name, template, nodes)
+	}
+
+	return magnum.createBay(name, template, nodes, "", driverOptions)
+}
+
+// createBay creates a new cluster with the optional keypair and labels, which aren't supported by the bays package
+func (magnum *Magnum) createBay(name string, template string, nodes int, keypair string, labels map[string]string) (common.Cluster, error) {
+	if template == "" {
+		return nil, errors.New("--template is required")
+	}
+
+	err := validateDriverOptions(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	err = magnum.init()
+	if err != nil {
+		return nil, err
+	}
+
+	common.Log.WriteDebug("[magnum] Creating %d-node %s cluster (%s) with keypair '%s' and labels %v", nodes, template, name, keypair, labels)
+
+	bayModel, err := magnum.lookupBayModelByName(template)
+	if err != nil {
+		return nil, err
+	}
+
+	// The bays package doesn't support labels or keypairs, so build the request by hand
+	body := map[string]interface{}{
+		"name":        name,
+		"baymodel_id": bayModel.ID,
+		"node_count":  nodes,
+	}
+	if keypair != "" {
+		body["keypair"] = keypair
+	}
+	if len(labels) > 0 {
+		body["labels"] = labels
+	}
+	var bay bays.Bay
+	_, err = magnum.client.Post(magnum.client.ServiceURL("bays"), body, &bay, &gophercloud.RequestOpts{
+		OkCodes: []int{201, 202},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "[magnum] Unable to create the cluster")
+	}
+
+	cluster := &Cluster{Bay: &bay, Template: bayModel}
+	return cluster, nil
+}
+
+// validateDriverOptions checks that the driver options can be used as magnum labels
+func validateDriverOptions(driverOptions map[string]string) error {
+	for key, value := range driverOptions {
+		if !driverOptionKeyPattern.MatchString(key) {
+			return fmt.Errorf("Invalid driver option: %s. Keys may only contain letters, numbers, underscores, dashes and periods", key)
+		}
+		if value == "" {
+			return fmt.Errorf("Invalid driver option: %s. A value is required", key)
+		}
+	}
+	return nil
+}
+
 // GetClusterCredentials retrieves the TLS certificates and configuration scripts for a cluster by its id or name (if unique)
 func (magnum *Magnum) GetClusterCredentials(token string) (*libcarina.CredentialsBundle, error) {
 	err := magnum.init()
@@ -154,6 +274,102 @@ func (magnum *Magnum) ListClusterTemplates() ([]common.ClusterTemplate, error) {
 	return templates, err
 }
 
+// CreateClusterTemplate creates a new baymodel
+func (magnum *Magnum) CreateClusterTemplate(options common.ClusterTemplateOptions) (common.ClusterTemplate, error) {
+	body, err := newBayModelBody(options)
+	if err != nil {
+		return nil, err
+	}
+
+	err = magnum.init()
+	if err != nil {
+		return nil, err
+	}
+
+	common.Log.WriteDebug("[magnum] Creating %s baymodel (%s) from image %s", options.COE, options.Name, options.Image)
+
+	// The baymodels package can only list baymodels, so build the request by hand
+	var bayModel baymodels.BayModel
+	_, err = magnum.client.Post(magnum.client.ServiceURL("baymodels"), body, &bayModel, &gophercloud.RequestOpts{
+		OkCodes: []int{201},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("[magnum] Unable to create baymodel (%s)", options.Name))
+	}
+
+	magnum.bayModelCache = nil
+	return &ClusterTemplate{BayModel: &bayModel}, nil
+}
+
+// newBayModelBody builds the request to create a baymodel, checking that the settings required by Magnum are set
+func newBayModelBody(options common.ClusterTemplateOptions) (map[string]interface{}, error) {
+	for _, required := range []struct{ flag, value string }{
+		{"--name", options.Name},
+		{"--coe", options.COE},
+		{"--image", options.Image},
+		{"--external-network", options.ExternalNetwork},
+	} {
+		if required.value == "" {
+			return nil, fmt.Errorf("%s is required", required.flag)
+		}
+	}
+	if options.DockerVolumeSize < 0 {
+		return nil, errors.New("--docker-volume-size must not be negative")
+	}
+
+	body := map[string]interface{}{
+		"name":                options.Name,
+		"coe":                 strings.ToLower(options.COE),
+		"image_id":            options.Image,
+		"external_network_id": options.ExternalNetwork,
+	}
+	for key, value := range map[string]string{
+		"flavor_id":        options.Flavor,
+		"master_flavor_id": options.MasterFlavor,
+		"network_driver":   options.NetworkDriver,
+		"dns_nameserver":   options.DNSNameServer,
+		"server_type":      options.ServerType,
+	} {
+		if value != "" {
+			body[key] = value
+		}
+	}
+	if options.MasterFlavor == "" && options.Flavor != "" {
+		body["master_flavor_id"] = options.Flavor
+	}
+	if options.DockerVolumeSize > 0 {
+		body["docker_volume_size"] = options.DockerVolumeSize
+	}
+	return body, nil
+}
+
+// DeleteClusterTemplate permanently deletes a baymodel by its id or name
+func (magnum *Magnum) DeleteClusterTemplate(token string) (common.ClusterTemplate, error) {
+	err := magnum.init()
+	if err != nil {
+		return nil, err
+	}
+
+	bayModel, err := magnum.lookupBayModelByID(token)
+	if err != nil {
+		bayModel, err = magnum.lookupBayModelByName(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	common.Log.WriteDebug("[magnum] Deleting baymodel (%s)", token)
+	_, err = magnum.client.Delete(magnum.client.ServiceURL("baymodels", bayModel.ID), &gophercloud.RequestOpts{
+		OkCodes: []int{204},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("[magnum] Unable to delete baymodel (%s). Clusters created from the baymodel must be deleted first", token))
+	}
+
+	magnum.bayModelCache = nil
+	return &ClusterTemplate{BayModel: bayModel}, nil
+}
+
 // GetCluster prints out a cluster's information to the console by its id or name (if unique)
 func (magnum *Magnum) GetCluster(token string) (common.Cluster, error) {
 	err := magnum.init()
@@ -219,10 +435,82 @@ func (magnum *Magnum) ResizeCluster(token string, nodes int) (common.Cluster, er
 }
 
 // SetAutoScale is not supported
-func (magnum *Magnum) SetAutoScale(token string, value bool) (common.Cluster, error) {
+func (magnum *Magnum) SetAutoScale(token string, autoscale common.AutoScale) (common.Cluster, error) {
 	return nil, errors.New("Magnum does not support autoscaling.")
 }
 
+// UpgradeCluster moves a cluster by its id or name (if unique) to another bay model, which requires Magnum API version 1.8
+func (magnum *Magnum) UpgradeCluster(token string, template string) (common.Cluster, error) {
+	cluster, err := magnum.GetCluster(token)
+	if err != nil {
+		return nil, err
+	}
+
+	bayModel, err := magnum.lookupBayModelByName(template)
+	if err != nil {
+		return nil, err
+	}
+
+	common.Log.WriteDebug("[magnum] Upgrading bay (%s) to %s", token, template)
+	body := map[string]interface{}{
+		"cluster_template": bayModel.ID,
+	}
+	_, err = magnum.client.Post(magnum.client.ServiceURL("clusters", cluster.GetID(), "actions", "upgrade"), body, nil, &gophercloud.RequestOpts{
+		OkCodes:     []int{http.StatusAccepted},
+		MoreHeaders: map[string]string{"OpenStack-API-Version": "container-infra 1.8"},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("[magnum] Unable to upgrade bay (%s)", token))
+	}
+
+	upgraded, err := magnum.waitForTaskInitiated(cluster.GetID(), "UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	return upgraded, nil
+}
+
+// rotateCAAction replaces the certificate authority of a cluster, which requires Magnum API version 1.5
+var rotateCAAction = common.ClusterAction{
+	Name:        "rotate-ca",
+	Description: "Replace the cluster's certificate authority, which invalidates everyone's credentials. Download the credentials again afterwards",
+	Destructive: true,
+}
+
+// ListActions returns the Magnum specific actions
+func (magnum *Magnum) ListActions() []common.ClusterAction {
+	return []common.ClusterAction{rotateCAAction}
+}
+
+// InvokeAction invokes a Magnum specific action on a cluster by its id or name (if unique)
+func (magnum *Magnum) InvokeAction(token string, action string, params map[string]string) (common.Cluster, error) {
+	switch action {
+	case rotateCAAction.Name:
+		return magnum.rotateCA(token)
+	default:
+		_, err := common.LookupClusterAction(magnum.ListActions(), action)
+		return nil, err
+	}
+}
+
+func (magnum *Magnum) rotateCA(token string) (common.Cluster, error) {
+	cluster, err := magnum.GetCluster(token)
+	if err != nil {
+		return nil, err
+	}
+
+	common.Log.WriteDebug("[magnum] Rotating the certificate authority of bay (%s)", token)
+	_, err = magnum.client.Patch(magnum.client.ServiceURL("certificates", cluster.GetID()), nil, nil, &gophercloud.RequestOpts{
+		OkCodes:     []int{http.StatusAccepted},
+		MoreHeaders: map[string]string{"OpenStack-API-Version": "container-infra 1.5"},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("[magnum] Unable to rotate the certificate authority of bay (%s)", token))
+	}
+
+	return magnum.GetCluster(cluster.GetID())
+}
+
 // WaitUntilClusterIsActive waits until the prior cluster operation is completed
 func (magnum *Magnum) WaitUntilClusterIsActive(cluster common.Cluster) (common.Cluster, error) {
 	isDone := func(cluster common.Cluster) bool {
@@ -234,19 +522,22 @@ func (magnum *Magnum) WaitUntilClusterIsActive(cluster common.Cluster) (common.C
 		return cluster, nil
 	}
 
-	pollingInterval := 10 * time.Second
+	waiter := common.NewWaiter(10*time.Second, "Waiting for cluster (%s) to become active", cluster.GetName())
 	for {
 		cluster, err := magnum.GetCluster(cluster.GetID())
 		if err != nil {
 			return cluster, err
 		}
 
+		waiter.UpdateCluster(cluster)
 		if isDone(cluster) {
 			return cluster, nil
 		}
 
-		common.Log.WriteDebug("[magnum] Waiting until cluster (%s) is active, currently in %s", cluster.GetName(), cluster.GetStatus())
-		time.Sleep(pollingInterval)
+		err = waiter.Wait()
+		if err != nil {
+			return cluster, err
+		}
 	}
 }
 
@@ -261,7 +552,7 @@ func (magnum *Magnum) WaitUntilClusterIsDeleted(cluster common.Cluster) error {
 		return nil
 	}
 
-	pollingInterval := 5 * time.Second
+	waiter := common.NewWaiter(5*time.Second, "Waiting for cluster (%s) to be deleted", cluster.GetName())
 	for {
 		cluster, err := magnum.GetCluster(cluster.GetID())
 
@@ -278,12 +569,15 @@ func (magnum *Magnum) WaitUntilClusterIsDeleted(cluster common.Cluster) error {
 			return err
 		}
 
+		waiter.UpdateCluster(cluster)
 		if isDone(cluster) {
 			return nil
 		}
 
-		common.Log.WriteDebug("[magnum] Waiting until cluster (%s) is deleted, currently in %s", cluster.GetName(), cluster.GetStatus())
-		time.Sleep(pollingInterval)
+		err = waiter.Wait()
+		if err != nil {
+			return err
+		}
 	}
 }
 
@@ -293,6 +587,7 @@ func (magnum *Magnum) WaitUntilClusterIsDeleted(cluster common.Cluster) error {
 func (magnum *Magnum) waitForTaskInitiated(token string, task string) (*Cluster, error) {
 	task = strings.ToLower(task)
 
+	started := time.Now()
 	pollingInterval := 1 * time.Second
 	for {
 		result, err := magnum.GetCluster(token)
@@ -307,7 +602,18 @@ func (magnum *Magnum) waitForTaskInitiated(token string, task string) (*Cluster,
 		}
 
 		common.Log.WriteDebug("[magnum] Waiting for %s_* currently in %s", task, status)
-		time.Sleep(pollingInterval)
+		err = common.ClusterWaitPolicy.CheckTimeout(started, fmt.Sprintf("Waiting for %s to start", task), status)
+		if err != nil {
+			return cluster, err
+		}
+		err = common.Sleep(pollingInterval)
+		if err != nil {
+			return cluster, err
+		}
+		err = common.ClusterWaitPolicy.Throttle()
+		if err != nil {
+			return cluster, err
+		}
 	}
 }
 
@@ -365,7 +671,7 @@ func (magnum *Magnum) lookupBayModelByID(bayModelID string) (*baymodels.BayModel
 
 	bayModel, exists := cache[bayModelID]
 	if !exists {
-		return nil, fmt.Errorf("Could not find baymodel with id %s", bayModelID)
+		return nil, common.NotFoundError{Err: fmt.Errorf("Could not find baymodel with id %s", bayModelID)}
 	}
 	return bayModel, nil
 }
@@ -376,17 +682,20 @@ func (magnum *Magnum) lookupBayModelByName(name string) (*baymodels.BayModel, er
 		return nil, err
 	}
 
-	name = strings.ToLower(name)
 	var bayModel *baymodels.BayModel
 	for _, m := range cache {
-		if strings.ToLower(m.Name) == name {
+		if strings.EqualFold(m.Name, name) {
 			bayModel = m
 			break
 		}
 	}
 
 	if bayModel == nil {
-		return nil, fmt.Errorf("Could not find template named %s", name)
+		templates := make([]common.ClusterTemplate, 0, len(cache))
+		for _, m := range cache {
+			templates = append(templates, &ClusterTemplate{BayModel: m})
+		}
+		return nil, common.NewTemplateNotFoundError(name, templates)
 	}
 
 	return bayModel, nil
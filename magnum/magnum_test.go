@@ -0,0 +1,48 @@
+package magnum
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateDriverOptions(t *testing.T) {
+	err := validateDriverOptions(map[string]string{"kube_tag": "v1.9.3", "docker_volume_size": "20"})
+	assert.Nil(t, err)
+
+	err = validateDriverOptions(map[string]string{"kube tag": "v1.9.3"})
+	assert.NotNil(t, err)
+
+	err = validateDriverOptions(map[string]string{"kube_tag": ""})
+	assert.NotNil(t, err)
+}
+
+func TestNewBayModelBody(t *testing.T) {
+	body, err := newBayModelBody(common.ClusterTemplateOptions{
+		Name:            "k8s-small",
+		COE:             "Kubernetes",
+		Image:           "fedora-atomic",
+		Flavor:          "m1.small",
+		ExternalNetwork: "public",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "kubernetes", body["coe"])
+	assert.Equal(t, "m1.small", body["master_flavor_id"], "the master flavor should default to the node flavor")
+	assert.NotContains(t, body, "docker_volume_size")
+
+	_, err = newBayModelBody(common.ClusterTemplateOptions{Name: "k8s-small", COE: "kubernetes", Image: "fedora-atomic"})
+	assert.EqualError(t, err, "--external-network is required")
+}
+
+func TestGetFlavorWithoutTemplate(t *testing.T) {
+	cluster := newCluster()
+	assert.Equal(t, "", cluster.GetFlavor(), "a cluster retrieved without its template should not panic")
+}
+
+func TestMagnumDoesNotListNodes(t *testing.T) {
+	// Bays only report the node addresses, not their names or status
+	_, ok := interface{}(&Magnum{}).(common.NodeLister)
+	assert.False(t, ok)
+}
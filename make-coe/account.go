@@ -2,11 +2,14 @@ package makecoe
 
 import (
 	"crypto/sha1"
+	"encoding/json"
 	"fmt"
 
 	"regexp"
 
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/getcarina/carina/common"
 	"github.com/getcarina/libcarina"
@@ -21,22 +24,56 @@ type Account struct {
 	APIKey           string
 	Region           string
 
-	// Testing only, not used by the cli
-	AuthEndpointOverride string
+	// Token is a pre-obtained auth token, e.g. injected by a CI system, which is used instead of the API key.
+	// See Authenticate for the order in which the credentials are used.
+	Token string
+
+	// IdentityEndpoint is the Rackspace Identity URL, defaults to the US endpoint. See ResolveIdentityEndpoint.
+	IdentityEndpoint string
 
 	// The endpoint from the service catalog
 	endpoint string
 	token    string
+
+	// The cluster types, cached with the token. See ClusterTypeCachePolicy.
+	// clusterTypesLock guards them, as the templates are resolved by concurrent operations in a batch.
+	clusterTypesLock     sync.Mutex
+	clusterTypes         []*clusterType
+	clusterTypesEndpoint string
+	clusterTypesExpires  time.Time
+
+	// clusterTypesListed is set when the cluster types were listed by this process, instead of read from the cache
+	clusterTypesListed bool
 }
 
+// Keys of the cached cluster types, see Account.BuildCache
+const (
+	cachedClusterTypesKey         = "cluster-types"
+	cachedClusterTypesEndpointKey = "cluster-types-endpoint"
+	cachedClusterTypesExpiresKey  = "cluster-types-expires"
+)
+
 // NewClusterService create the appropriate ClusterService for the account
 func (account *Account) NewClusterService() common.ClusterService {
 	return &MakeCOE{Account: account}
 }
 
-// GetID returns a unique id for the account, e.g. public-[username]
+// GetID returns a unique id for the account, e.g. public-[username] for the default identity endpoint,
+// or public-[identity]-[username] for other identity endpoints, so that their tokens are cached separately
 func (account *Account) GetID() string {
-	return fmt.Sprintf("public-%s", account.UserName)
+	if account.IdentityEndpoint == "" {
+		return fmt.Sprintf("public-%s", account.UserName)
+	}
+
+	alias, ok := lookupIdentityEndpointAlias(account.IdentityEndpoint)
+	if !ok {
+		hash := sha1.Sum([]byte(account.IdentityEndpoint))
+		alias = fmt.Sprintf("%x", hash[:4])
+	}
+	if alias == DefaultIdentityEndpoint {
+		return fmt.Sprintf("public-%s", account.UserName)
+	}
+	return fmt.Sprintf("public-%s-%s", alias, account.UserName)
 }
 
 // GetClusterPrefix returns a unique string to identity the account's clusters, e.g. public-[region]-[username]
@@ -73,14 +110,45 @@ func (account *Account) getEndpointRegion() string {
 	return match[1]
 }
 
-// Authenticate creates an authenticated client, ready to use to communicate with the Carina API
-func (account *Account) Authenticate() (*libcarina.CarinaClient, error) {
+// authCredentials returns the token and endpoint to authenticate with, which are the first available of:
+//  1. the token specified with CARINA_TOKEN, with the endpoint from --endpoint or the service catalog cached from the last command
+//  2. the token and endpoint cached from the last command
+//  3. neither, so that the username and API key are used
+// When a token is rejected, libcarina falls back to the username and API key.
+func (account *Account) authCredentials() (token string, endpoint string, err error) {
+	if account.Token != "" {
+		endpoint = account.getEndpoint()
+		if endpoint == "" && account.APIKey == "" {
+			return "", "", errors.New("[make-coe] Authenticating with only a token requires the API endpoint, which is otherwise looked up with the API key. Use --endpoint or set CARINA_ENDPOINT")
+		}
+		if endpoint != "" {
+			common.Log.WriteDebug("[make-coe] Attempting to authenticate with the specified token, falling back to the username and apikey if necessary")
+			return account.Token, endpoint, nil
+		}
+		common.Log.WriteDebug("[make-coe] Ignoring the specified token because the API endpoint is unknown")
+	}
+
 	if account.token != "" && account.endpoint != "" {
 		common.Log.WriteDebug("[make-coe] Attempting to authenticate with a cached token, falling back to the username and apikey if necessary")
-	} else {
-		common.Log.WriteDebug("[make-coe] Attempting to authenticate with a username and apikey")
+		return account.token, account.endpoint, nil
 	}
-	carinaClient, err := libcarina.NewClient(account.UserName, account.APIKey, account.Region, account.AuthEndpointOverride, account.token, account.endpoint)
+
+	if account.APIKey == "" {
+		return "", "", errors.New("[make-coe] An API key or token is required to authenticate")
+	}
+	common.Log.WriteDebug("[make-coe] Attempting to authenticate with a username and apikey")
+	return "", "", nil
+}
+
+// Authenticate creates an authenticated client, ready to use to communicate with the Carina API.
+// A token specified with CARINA_TOKEN is preferred, then the token cached from the last command, then the username and API key.
+func (account *Account) Authenticate() (*libcarina.CarinaClient, error) {
+	token, endpoint, err := account.authCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	carinaClient, err := libcarina.NewClient(account.UserName, account.APIKey, account.Region, account.IdentityEndpoint, token, endpoint)
 	if err != nil {
 		return nil, errors.Wrap(err, "[make-coe] Authentication failed")
 	}
@@ -90,9 +158,11 @@ func (account *Account) Authenticate() (*libcarina.CarinaClient, error) {
 	carinaClient.Client = common.NewHTTPClient()
 	carinaClient.UserAgent += common.BuildUserAgent()
 
-	// Cache data looked up from the service catalog
+	// Cache data looked up from the service catalog, but don't cache the overridden endpoint, e.g. when authenticating with a token
 	account.token = carinaClient.Token
-	account.endpoint = carinaClient.Endpoint // don't cache the overridden endpoint!
+	if carinaClient.Endpoint != account.EndpointOverride {
+		account.endpoint = carinaClient.Endpoint
+	}
 
 	// Override the endpoint from the service catalog
 	carinaClient.Endpoint = account.getEndpoint()
@@ -102,14 +172,48 @@ func (account *Account) Authenticate() (*libcarina.CarinaClient, error) {
 
 // BuildCache builds the set of data to cache
 func (account *Account) BuildCache() map[string]string {
-	return map[string]string{
+	c := map[string]string{
 		"token":    account.token,
 		"endpoint": account.endpoint,
 	}
+
+	account.clusterTypesLock.Lock()
+	defer account.clusterTypesLock.Unlock()
+	if account.clusterTypes != nil && time.Now().Before(account.clusterTypesExpires) {
+		clusterTypes, err := json.Marshal(account.clusterTypes)
+		if err != nil {
+			common.Log.WriteDebug("[make-coe] Unable to cache the cluster types: %s", err)
+			return c
+		}
+		c[cachedClusterTypesKey] = string(clusterTypes)
+		c[cachedClusterTypesEndpointKey] = account.clusterTypesEndpoint
+		c[cachedClusterTypesExpiresKey] = account.clusterTypesExpires.UTC().Format(time.RFC3339)
+	}
+
+	return c
 }
 
 // ApplyCache applies a set of cached data
 func (account *Account) ApplyCache(c map[string]string) {
 	account.token = c["token"]
 	account.endpoint = c["endpoint"]
+
+	if c[cachedClusterTypesKey] == "" {
+		return
+	}
+	expires, err := time.Parse(time.RFC3339, c[cachedClusterTypesExpiresKey])
+	if err != nil {
+		common.Log.WriteDebug("[make-coe] Ignoring the cached cluster types: %s", err)
+		return
+	}
+	var clusterTypes []*clusterType
+	err = json.Unmarshal([]byte(c[cachedClusterTypesKey]), &clusterTypes)
+	if err != nil {
+		common.Log.WriteDebug("[make-coe] Ignoring the cached cluster types: %s", err)
+		return
+	}
+
+	account.clusterTypes = clusterTypes
+	account.clusterTypesEndpoint = c[cachedClusterTypesEndpointKey]
+	account.clusterTypesExpires = expires
 }
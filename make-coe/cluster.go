@@ -1,15 +1,63 @@
 package makecoe
 
 import (
+	"encoding/json"
+	"io"
 	"strconv"
+	"time"
 
 	"github.com/getcarina/carina/common"
 	"github.com/getcarina/libcarina"
+	"github.com/pkg/errors"
 )
 
 // Cluster represents a cluster on make-coe
 type Cluster struct {
 	*libcarina.Cluster
+
+	// AutoScale is the autoscaling settings, which the API only includes when the cluster type supports autoscaling
+	AutoScale *autoScaleSettings `json:"autoscale,omitempty"`
+
+	// CreatedAt and UpdatedAt are when the cluster was created and last changed, which libcarina doesn't support
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// NodeSize is the resources and cost of each node, which the API only includes when retrieving a single cluster
+	NodeSize *nodeSize `json:"node_size,omitempty"`
+}
+
+// nodeSize is the resources and estimated cost of a node, which libcarina doesn't support
+type nodeSize struct {
+	RAM        int     `json:"ram"`
+	VCPUs      int     `json:"vcpus"`
+	Disk       int     `json:"disk"`
+	HourlyCost float64 `json:"hourly_cost,omitempty"`
+	Currency   string  `json:"currency,omitempty"`
+}
+
+// autoScaleSettings are the autoscaling settings of a cluster, which libcarina doesn't support
+type autoScaleSettings struct {
+	Enabled  bool `json:"enabled"`
+	MinNodes int  `json:"min_nodes,omitempty"`
+	MaxNodes int  `json:"max_nodes,omitempty"`
+}
+
+func newAutoScaleSettings(autoscale common.AutoScale) autoScaleSettings {
+	return autoScaleSettings{
+		Enabled:  autoscale.Enabled,
+		MinNodes: autoscale.MinNodes,
+		MaxNodes: autoscale.MaxNodes,
+	}
+}
+
+// decodeCluster reads a cluster from an API response, including the fields which libcarina doesn't support
+func decodeCluster(body io.Reader) (*Cluster, error) {
+	cluster := newCluster()
+	err := json.NewDecoder(body).Decode(cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "[make-coe] Unable to parse the cluster")
+	}
+	return cluster, nil
 }
 
 func newCluster() *Cluster {
@@ -50,3 +98,47 @@ func (cluster *Cluster) GetStatus() string {
 func (cluster *Cluster) GetStatusDetails() string {
 	return ""
 }
+
+// GetLabels is not supported by the API, labels are stored locally by the client
+func (cluster *Cluster) GetLabels() map[string]string {
+	return nil
+}
+
+// GetAutoScale returns the cluster's autoscaling settings, or nil when the cluster type doesn't support autoscaling
+func (cluster *Cluster) GetAutoScale() *common.AutoScale {
+	if cluster.AutoScale == nil {
+		return nil
+	}
+
+	return &common.AutoScale{
+		Enabled:  cluster.AutoScale.Enabled,
+		MinNodes: cluster.AutoScale.MinNodes,
+		MaxNodes: cluster.AutoScale.MaxNodes,
+	}
+}
+
+// GetCreated returns when the cluster was created, which is only known when the cluster was retrieved individually,
+// because libcarina doesn't include it when listing clusters
+func (cluster *Cluster) GetCreated() time.Time {
+	return cluster.CreatedAt
+}
+
+// GetUpdated returns when the cluster was last changed, which is only known when the cluster was retrieved individually
+func (cluster *Cluster) GetUpdated() time.Time {
+	return cluster.UpdatedAt
+}
+
+// GetNodeSize returns the resources and estimated cost of each node, which is only known when the cluster was retrieved individually
+func (cluster *Cluster) GetNodeSize() *common.NodeSize {
+	if cluster.NodeSize == nil {
+		return nil
+	}
+
+	return &common.NodeSize{
+		RAM:        cluster.NodeSize.RAM,
+		VCPUs:      cluster.NodeSize.VCPUs,
+		Disk:       cluster.NodeSize.Disk,
+		HourlyCost: cluster.NodeSize.HourlyCost,
+		Currency:   cluster.NodeSize.Currency,
+	}
+}
@@ -1,10 +1,46 @@
 package makecoe
 
-import "github.com/getcarina/libcarina"
+import (
+	"strconv"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/libcarina"
+)
 
 // ClusterTemplate represents a cluster template for make-coe
 type ClusterTemplate struct {
 	*libcarina.ClusterType
+
+	// capabilities is nil for the template of a cluster, the API only reports them when listing the cluster types
+	capabilities *common.TemplateCapabilities
+}
+
+// clusterType is a cluster type, with the capabilities reported by newer versions of the API
+type clusterType struct {
+	*libcarina.ClusterType
+	MaxNodes  *int  `json:"max_nodes,omitempty"`
+	AutoScale *bool `json:"autoscale,omitempty"`
+	GPU       *bool `json:"gpu,omitempty"`
+}
+
+// newClusterTemplate converts a cluster type into a template
+func newClusterTemplate(clusterType *clusterType) *ClusterTemplate {
+	template := &ClusterTemplate{ClusterType: clusterType.ClusterType}
+	if clusterType.MaxNodes == nil && clusterType.AutoScale == nil && clusterType.GPU == nil {
+		return template
+	}
+
+	template.capabilities = &common.TemplateCapabilities{}
+	if clusterType.MaxNodes != nil {
+		template.capabilities.MaxNodes = *clusterType.MaxNodes
+	}
+	if clusterType.AutoScale != nil {
+		template.capabilities.AutoScale = *clusterType.AutoScale
+	}
+	if clusterType.GPU != nil {
+		template.capabilities.GPU = *clusterType.GPU
+	}
+	return template
 }
 
 // GetName returns the unique template name
@@ -21,3 +57,35 @@ func (template *ClusterTemplate) GetCOE() string {
 func (template *ClusterTemplate) GetHostType() string {
 	return template.HostType
 }
+
+// IsDeprecated returns if the template is deprecated, make-coe flags retired cluster types as inactive
+func (template *ClusterTemplate) IsDeprecated() bool {
+	return !template.Active
+}
+
+// GetCOEVersion returns the version in the template name, e.g. 1.5.2 in "Kubernetes 1.5.2 on LXC"
+func (template *ClusterTemplate) GetCOEVersion() string {
+	return common.ParseTemplateVersion(template.Name)
+}
+
+// GetNodeFlavor is not supported, make-coe sizes the nodes according to the host type
+func (template *ClusterTemplate) GetNodeFlavor() string {
+	return ""
+}
+
+// GetDetails returns the cluster type identifier
+func (template *ClusterTemplate) GetDetails() map[string]string {
+	return map[string]string{
+		"ID": strconv.Itoa(template.ID),
+	}
+}
+
+// GetAvailability is not supported, make-coe doesn't report capacity for cluster types
+func (template *ClusterTemplate) GetAvailability() *common.TemplateAvailability {
+	return nil
+}
+
+// GetCapabilities returns the maximum number of nodes, and if autoscale and GPUs are supported, or nil when the API doesn't report them
+func (template *ClusterTemplate) GetCapabilities() *common.TemplateCapabilities {
+	return template.capabilities
+}
@@ -0,0 +1,149 @@
+package makecoe
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/carina/testsupport"
+)
+
+// fakeCarinaAPI is a stateful stand-in for the Carina API, with just enough behavior to run the conformance tests
+type fakeCarinaAPI struct {
+	lock         sync.Mutex
+	clusterTypes []map[string]interface{}
+	clusters     map[string]map[string]interface{}
+	nextID       int
+}
+
+func newFakeCarinaAPI() *fakeCarinaAPI {
+	return &fakeCarinaAPI{
+		clusterTypes: []map[string]interface{}{
+			{"id": 21, "name": "Swarm 1.11.2 on LXC", "coe": "swarm", "host_type": "lxc", "active": true},
+			{"id": 22, "name": "Kubernetes 1.5.2 on LXC", "coe": "kubernetes", "host_type": "lxc", "active": true},
+		},
+		clusters: make(map[string]map[string]interface{}),
+	}
+}
+
+// withStatus copies a cluster, so that a response can report a transitional status without changing the stored cluster
+func withStatus(cluster map[string]interface{}, status string) map[string]interface{} {
+	result := make(map[string]interface{}, len(cluster))
+	for key, value := range cluster {
+		result[key] = value
+	}
+	result["status"] = status
+	return result
+}
+
+func (api *fakeCarinaAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	api.lock.Lock()
+	defer api.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/cluster_types":
+		json.NewEncoder(w).Encode(map[string]interface{}{"cluster_types": api.clusterTypes})
+	case r.Method == "GET" && r.URL.Path == "/clusters":
+		clusters := []map[string]interface{}{}
+		for _, cluster := range api.clusters {
+			clusters = append(clusters, cluster)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"clusters": clusters})
+	case r.Method == "POST" && r.URL.Path == "/clusters":
+		api.createCluster(w, r)
+	case len(parts) >= 2 && parts[0] == "clusters":
+		cluster, ok := api.clusters[parts[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"errors":[{"code":"make-coe-api.cluster-not-found","status":404,"title":"Cluster not found"}]}`)
+			return
+		}
+
+		switch {
+		case r.Method == "GET" && len(parts) == 2:
+			json.NewEncoder(w).Encode(cluster)
+		case r.Method == "DELETE" && len(parts) == 2:
+			// The cluster is gone the next time that it is retrieved
+			delete(api.clusters, parts[1])
+			json.NewEncoder(w).Encode(withStatus(cluster, "deleting"))
+		case r.Method == "GET" && strings.Join(parts[2:], "/") == "credentials/zip":
+			w.Header().Set("Content-Type", "application/zip")
+			archive := zip.NewWriter(w)
+			for _, file := range []string{"ca.pem", "ca-key.pem", "cert.pem", "key.pem", "docker.env"} {
+				f, _ := archive.Create(file)
+				fmt.Fprintf(f, "fake %s for %s", file, cluster["name"])
+			}
+			archive.Close()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, "unexpected request: "+r.Method+" "+r.RequestURI)
+	}
+}
+
+func (api *fakeCarinaAPI) createCluster(w http.ResponseWriter, r *http.Request) {
+	var opts struct {
+		Name          string `json:"name"`
+		ClusterTypeID int    `json:"cluster_type_id"`
+		Nodes         int    `json:"node_count"`
+	}
+	json.NewDecoder(r.Body).Decode(&opts)
+
+	var clusterType map[string]interface{}
+	for _, t := range api.clusterTypes {
+		if t["id"] == opts.ClusterTypeID {
+			clusterType = t
+		}
+	}
+	if clusterType == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(w, `{"errors":[{"code":"make-coe-api.invalid-cluster-type","status":400,"title":"Invalid cluster type"}]}`)
+		return
+	}
+
+	api.nextID++
+	id := fmt.Sprintf("00000000-0000-4000-8000-%012d", api.nextID)
+	cluster := map[string]interface{}{
+		"id":           id,
+		"name":         opts.Name,
+		"node_count":   opts.Nodes,
+		"cluster_type": clusterType,
+		"status":       "active",
+	}
+	api.clusters[id] = cluster
+
+	// The cluster is active the next time that it is retrieved
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(withStatus(cluster, "creating"))
+}
+
+func TestMakeCOEConformance(t *testing.T) {
+	common.Log.RegisterTestLogger(t)
+
+	var servers []*httptest.Server
+	defer func() {
+		for _, server := range servers {
+			server.Close()
+		}
+	}()
+
+	testsupport.RunConformanceTests(t, testsupport.ConformanceOptions{
+		NewService: func(t *testing.T) common.ClusterService {
+			mockCarina, mockIdentity := httptest.NewServer(newFakeCarinaAPI()), httptest.NewServer(http.HandlerFunc(identityHandler))
+			servers = append(servers, mockCarina, mockIdentity)
+			return createMakeCOEService(mockIdentity, mockCarina)
+		},
+		Template:                 "Kubernetes 1.5.2 on LXC",
+		AmbiguousTemplatePattern: "*LXC",
+	})
+}
@@ -0,0 +1,54 @@
+package makecoe
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultIdentityEndpoint is the Rackspace Identity alias used when an identity endpoint isn't specified
+const DefaultIdentityEndpoint = "us"
+
+// IdentityEndpoints are the well-known Rackspace Identity endpoints, by alias
+var IdentityEndpoints = map[string]string{
+	"us": "https://identity.api.rackspacecloud.com/v2.0/",
+	"uk": "https://lon.identity.api.rackspacecloud.com/v2.0/",
+}
+
+// IdentityEndpointAliases returns the sorted aliases of the well-known identity endpoints
+func IdentityEndpointAliases() []string {
+	aliases := make([]string, 0, len(IdentityEndpoints))
+	for alias := range IdentityEndpoints {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// ResolveIdentityEndpoint returns the URL of an identity endpoint, which is either an alias, e.g. uk, or a URL.
+// The default endpoint is returned when the value is empty.
+func ResolveIdentityEndpoint(value string) (string, error) {
+	if value == "" {
+		value = DefaultIdentityEndpoint
+	}
+
+	if url, ok := IdentityEndpoints[strings.ToLower(value)]; ok {
+		return url, nil
+	}
+
+	if strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://") {
+		return value, nil
+	}
+
+	return "", fmt.Errorf("Invalid identity endpoint %s. Use a URL or one of: %s", value, strings.Join(IdentityEndpointAliases(), ", "))
+}
+
+// lookupIdentityEndpointAlias returns the alias of a well-known identity endpoint URL
+func lookupIdentityEndpointAlias(url string) (string, bool) {
+	for alias, u := range IdentityEndpoints {
+		if strings.TrimRight(u, "/") == strings.TrimRight(url, "/") {
+			return alias, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,39 @@
+package makecoe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveIdentityEndpoint(t *testing.T) {
+	url, err := ResolveIdentityEndpoint("")
+	assert.Nil(t, err)
+	assert.Equal(t, IdentityEndpoints["us"], url)
+
+	url, err = ResolveIdentityEndpoint("UK")
+	assert.Nil(t, err)
+	assert.Equal(t, IdentityEndpoints["uk"], url)
+
+	url, err = ResolveIdentityEndpoint("https://identity.example.com/v2.0/")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://identity.example.com/v2.0/", url)
+
+	_, err = ResolveIdentityEndpoint("mars")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "uk, us")
+}
+
+func TestAccountIDIncludesIdentityEndpoint(t *testing.T) {
+	account := &Account{UserName: "alice"}
+	assert.Equal(t, "public-alice", account.GetID())
+
+	account.IdentityEndpoint = IdentityEndpoints["us"]
+	assert.Equal(t, "public-alice", account.GetID(), "The default identity endpoint shouldn't change existing cache entries")
+
+	account.IdentityEndpoint = IdentityEndpoints["uk"]
+	assert.Equal(t, "public-uk-alice", account.GetID())
+
+	account.IdentityEndpoint = "https://identity.example.com/v2.0/"
+	assert.Regexp(t, "^public-[0-9a-f]{8}-alice$", account.GetID())
+}
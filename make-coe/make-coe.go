@@ -1,24 +1,42 @@
 package makecoe
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/getcarina/carina/common"
 	"github.com/getcarina/libcarina"
 	"github.com/pkg/errors"
-	"github.com/ryanuber/go-glob"
 )
 
 // MakeCOE is an adapter between the cli and Carina (make-coe)
 type MakeCOE struct {
-	client           *libcarina.CarinaClient
-	clusterTypeCache map[int]*libcarina.ClusterType
-	Account          *Account
+	// lock guards client, which is created when first used by concurrent operations in a batch
+	lock    sync.Mutex
+	client  *libcarina.CarinaClient
+	Account *Account
 }
 
+// DefaultClusterTypeCacheTTL is how long the cluster types are cached before they are listed again,
+// so that the cli and a long-lived adapter pick up new templates and don't keep retired ones
+const DefaultClusterTypeCacheTTL = time.Hour
+
+// ClusterTypeCachePolicy controls how long the cluster types are cached, with the account's auth token, so that
+// listing templates and resolving the template of a new cluster don't call the API every time
+var ClusterTypeCachePolicy = struct {
+	// TTL is how long the cluster types are cached, e.g. the template-cache-ttl setting. 0 disables the cache.
+	TTL time.Duration
+
+	// Refresh ignores the cached cluster types and lists them again, e.g. --refresh
+	Refresh bool
+}{TTL: DefaultClusterTypeCacheTTL}
+
 func handleNotAcceptable(err libcarina.HTTPErr) error {
 	return errors.Wrap(err, "Unable to communicate with the Carina API because the client is out-of-date. Update the carina client to the latest version. See https://getcarina.com/docs/tutorials/carina-cli#update for instructions.")
 }
@@ -26,9 +44,9 @@ func handleNotAcceptable(err libcarina.HTTPErr) error {
 func handleHTTPError(err libcarina.HTTPErr) error {
 	switch err.StatusCode {
 	default:
-		return err
+		return common.NewHTTPError(err.StatusCode, err)
 	case 406:
-		return handleNotAcceptable(err)
+		return common.APIError{StatusCode: err.StatusCode, Err: handleNotAcceptable(err)}
 	}
 }
 
@@ -41,6 +59,9 @@ func handleLibcarinaError(err error) error {
 }
 
 func (carina *MakeCOE) init() error {
+	carina.lock.Lock()
+	defer carina.lock.Unlock()
+
 	if carina.client == nil {
 		carinaClient, err := carina.Account.Authenticate()
 		if err != nil {
@@ -51,9 +72,91 @@ func (carina *MakeCOE) init() error {
 	return nil
 }
 
+// Authenticate authenticates with the account's credentials, returning the endpoint of the Carina API
+func (carina *MakeCOE) Authenticate() (string, error) {
+	err := carina.init()
+	if err != nil {
+		return "", err
+	}
+	return carina.client.Endpoint, nil
+}
+
+// clientAPIVersion is the microversion of the Carina API requested by libcarina
+const clientAPIVersion = "1.0"
+
+// ProbeCapabilities reads the range of API versions supported by the Carina API from its root document.
+// Upgrading clusters isn't supported by make-coe, and an error is returned when the server no longer accepts the client's API version.
+func (carina *MakeCOE) ProbeCapabilities() (*common.APICapabilities, error) {
+	err := carina.init()
+	if err != nil {
+		return nil, err
+	}
+
+	common.Log.WriteDebug("[make-coe] Probing the API versions supported by %s", carina.client.Endpoint)
+	resp, err := carina.client.NewRequest("GET", "/", nil)
+	if err != nil {
+		return nil, handleLibcarinaError(errors.Wrap(err, "[make-coe] Unable to probe the API version"))
+	}
+	defer resp.Body.Close()
+
+	var metadata struct {
+		Versions []struct {
+			MinVersion string `json:"min_version"`
+			MaxVersion string `json:"max_version"`
+		} `json:"versions"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&metadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "[make-coe] Unable to parse the API versions")
+	}
+
+	capabilities := &common.APICapabilities{
+		Endpoint:      carina.client.Endpoint,
+		ClientVersion: clientAPIVersion,
+		Unsupported:   []string{common.OperationUpgrade},
+	}
+	for _, version := range metadata.Versions {
+		if capabilities.MinVersion == "" || common.CompareVersions(version.MinVersion, capabilities.MinVersion) < 0 {
+			capabilities.MinVersion = version.MinVersion
+		}
+		if common.CompareVersions(version.MaxVersion, capabilities.MaxVersion) > 0 {
+			capabilities.MaxVersion = version.MaxVersion
+		}
+	}
+
+	if capabilities.MaxVersion != "" && (common.CompareVersions(clientAPIVersion, capabilities.MinVersion) < 0 || common.CompareVersions(clientAPIVersion, capabilities.MaxVersion) > 0) {
+		return nil, common.APIError{StatusCode: http.StatusNotAcceptable, Err: fmt.Errorf("Unable to communicate with the Carina API because the client is out-of-date. The client supports %s while the server supports %s - %s. Run carina update to install the latest version", clientAPIVersion, capabilities.MinVersion, capabilities.MaxVersion)}
+	}
+	return capabilities, nil
+}
+
 // GetQuotas retrieves the quotas set for the account
-func (carina *MakeCOE) GetQuotas() (common.Quotas, error) {
-	return &Quotas{}, nil
+func (carina *MakeCOE) GetQuotas() (*common.Quotas, error) {
+	err := carina.init()
+	if err != nil {
+		return nil, err
+	}
+
+	common.Log.WriteDebug("[make-coe] Retrieving the account quotas")
+	resp, err := carina.client.NewRequest("GET", "/quotas", nil)
+	if err != nil {
+		cause := errors.Cause(err)
+		// The quotas are unknown when the API doesn't report them, a quota of 0 isn't enforced or displayed
+		if httpErr, ok := cause.(libcarina.HTTPErr); ok && httpErr.StatusCode == http.StatusNotFound {
+			common.Log.WriteDebug("[make-coe] The API did not report the account quotas, they are unknown")
+			return &common.Quotas{}, nil
+		}
+		return nil, handleLibcarinaError(errors.Wrap(err, "[make-coe] Unable to retrieve the account quotas"))
+	}
+	defer resp.Body.Close()
+
+	var quotas Quotas
+	err = json.NewDecoder(resp.Body).Decode(&quotas)
+	if err != nil {
+		return nil, errors.Wrap(err, "[make-coe] Unable to parse the account quotas")
+	}
+
+	return quotas.toCommon(), nil
 }
 
 // CreateCluster creates a new cluster and prints the cluster information
@@ -90,6 +193,64 @@ func (carina *MakeCOE) CreateCluster(name string, template string, nodes int) (c
 	return cluster, nil
 }
 
+// CreateClusterWithSSHKey creates a new cluster with the SSH public key installed on its nodes.
+// Carina doesn't manage keypairs, so only a public key is supported.
+func (carina *MakeCOE) CreateClusterWithSSHKey(name string, template string, nodes int, key common.SSHKey, driverOptions map[string]string) (common.Cluster, error) {
+	if len(driverOptions) > 0 {
+		return nil, errors.New("Driver options are not supported by this cloud")
+	}
+	if key.PublicKey == "" {
+		return nil, errors.New("Keypairs are not supported by this cloud, use --ssh-key to specify a public key instead")
+	}
+	if template == "" {
+		return nil, errors.New("--template is required")
+	}
+
+	err := carina.init()
+	if err != nil {
+		return nil, err
+	}
+
+	common.Log.WriteDebug("[make-coe] Looking up a template matching '%s'", template)
+	clusterType, err := carina.lookupClusterTypeByName(template)
+	if err != nil {
+		return nil, err
+	}
+
+	common.Log.WriteDebug("[make-coe] Creating a %d-node %s cluster hosted on %s named %s with an SSH key", nodes, clusterType.COE, clusterType.HostType, name)
+
+	// libcarina doesn't support SSH keys, so build the request by hand
+	createOpts := struct {
+		*libcarina.CreateClusterOpts
+		SSHKey string `json:"ssh_key"`
+	}{
+		CreateClusterOpts: &libcarina.CreateClusterOpts{
+			Name:          name,
+			ClusterTypeID: clusterType.ID,
+			Nodes:         nodes,
+		},
+		SSHKey: key.PublicKey,
+	}
+	body, err := json.Marshal(createOpts)
+	if err != nil {
+		return nil, errors.Wrap(err, "[make-coe] Unable to create cluster")
+	}
+
+	resp, err := carina.client.NewRequest("POST", "/clusters", bytes.NewReader(body))
+	if err != nil {
+		return nil, handleLibcarinaError(errors.Wrap(err, "[make-coe] Unable to create cluster"))
+	}
+	defer resp.Body.Close()
+
+	result := &libcarina.Cluster{}
+	err = json.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return nil, errors.Wrap(err, "[make-coe] Unable to parse the cluster")
+	}
+
+	return &Cluster{Cluster: result}, nil
+}
+
 // GetClusterCredentials retrieves the TLS certificates and configuration scripts for a cluster by its id or name (if unique)
 func (carina *MakeCOE) GetClusterCredentials(token string) (*libcarina.CredentialsBundle, error) {
 	err := carina.init()
@@ -136,20 +297,60 @@ func (carina *MakeCOE) ListClusterTemplates() ([]common.ClusterTemplate, error)
 		return nil, err
 	}
 
-	results, err := carina.listClusterTypes()
+	results, err := carina.getClusterTypes()
 	if err != nil {
 		return nil, err
 	}
 
 	var templates []common.ClusterTemplate
 	for _, result := range results {
-		template := &ClusterTemplate{ClusterType: result}
-		templates = append(templates, template)
+		templates = append(templates, newClusterTemplate(result))
 	}
 
 	return templates, err
 }
 
+// ResolveTemplate finds the single active template matching the COE, host type and version, using the cached cluster types
+func (carina *MakeCOE) ResolveTemplate(selector common.TemplateSelector) (common.ClusterTemplate, error) {
+	err := carina.init()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterTypes, err := carina.getClusterTypes()
+	if err != nil {
+		return nil, err
+	}
+	return matchClusterType(clusterTypes, selector)
+}
+
+// matchClusterType selects the cluster type matching the selector, ignoring inactive cluster types
+func matchClusterType(clusterTypes []*clusterType, selector common.TemplateSelector) (common.ClusterTemplate, error) {
+	var matches []common.ClusterTemplate
+	for _, clusterType := range clusterTypes {
+		template := newClusterTemplate(clusterType)
+		if !clusterType.Active || !selector.Matches(template) {
+			continue
+		}
+
+		common.Log.WriteDebug("[make-coe] Matched template '%s' to %s", clusterType.Name, selector)
+		matches = append(matches, template)
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, common.NotFoundError{Err: fmt.Errorf("Could not find a template with %s. Run carina templates to see the available templates", selector)}
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, match := range matches {
+			names[i] = match.GetName()
+		}
+		return nil, &common.MultipleMatchingTemplatesError{TemplatePattern: selector.String(), MatchingTemplates: names}
+	}
+}
+
 // RebuildCluster destroys and recreates the cluster by its id or name (if unique)
 func (carina *MakeCOE) RebuildCluster(token string) (common.Cluster, error) {
 	return nil, errors.New("[make-coe] Rebuilding clusters from the carina cli is not supported yet")
@@ -162,14 +363,31 @@ func (carina *MakeCOE) GetCluster(token string) (common.Cluster, error) {
 		return nil, err
 	}
 
+	cluster, err := carina.getCluster(token)
+	if err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// getCluster retrieves a cluster, including its autoscale settings which libcarina doesn't support
+func (carina *MakeCOE) getCluster(token string) (*Cluster, error) {
 	common.Log.WriteDebug("[make-coe] Retrieving cluster (%s)", token)
+
+	// libcarina resolves the cluster name to its id
 	result, err := carina.client.Get(token)
 	if err != nil {
 		return nil, handleLibcarinaError(errors.Wrap(err, fmt.Sprintf("[make-coe] Unable to retrieve cluster (%s)", token)))
 	}
-	cluster := &Cluster{Cluster: result}
 
-	return cluster, nil
+	// Retrieve the cluster again by its id, to read the autoscale settings which aren't in libcarina.Cluster
+	resp, err := carina.client.NewRequest("GET", "/clusters/"+url.PathEscape(result.ID), nil)
+	if err != nil {
+		return nil, handleLibcarinaError(errors.Wrap(err, fmt.Sprintf("[make-coe] Unable to retrieve cluster (%s)", token)))
+	}
+	defer resp.Body.Close()
+
+	return decodeCluster(resp.Body)
 }
 
 // DeleteCluster permanently deletes a cluster by its id or name (if unique)
@@ -224,9 +442,53 @@ func (carina *MakeCOE) ResizeCluster(token string, nodes int) (common.Cluster, e
 	return cluster, nil
 }
 
-// SetAutoScale is not supported
-func (carina *MakeCOE) SetAutoScale(token string, value bool) (common.Cluster, error) {
-	return nil, errors.New("make-coe does not support autoscaling")
+// SetAutoScale changes the autoscaling settings on a cluster, when its cluster type supports autoscaling
+func (carina *MakeCOE) SetAutoScale(token string, autoscale common.AutoScale) (common.Cluster, error) {
+	err := carina.init()
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := carina.getCluster(token)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.AutoScale == nil {
+		clusterType := "unknown"
+		if cluster.Type != nil {
+			clusterType = cluster.Type.Name
+		}
+		return nil, fmt.Errorf("The cluster %s can't be autoscaled, its cluster type (%s) doesn't support autoscaling", token, clusterType)
+	}
+
+	common.Log.WriteDebug("[make-coe] Changing the autoscale setting on the cluster (%s) to %s", token, autoscale.String())
+	body, err := json.Marshal(newAutoScaleSettings(autoscale))
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("[make-coe] Unable to change the cluster's autoscale setting (%s)", token))
+	}
+
+	resp, err := carina.client.NewRequest("PUT", "/clusters/"+url.PathEscape(cluster.ID)+"/autoscale", bytes.NewReader(body))
+	if err != nil {
+		return nil, handleLibcarinaError(errors.Wrap(err, fmt.Sprintf("[make-coe] Unable to change the cluster's autoscale setting (%s)", token)))
+	}
+	defer resp.Body.Close()
+
+	cluster, err = decodeCluster(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// ListActions returns nil, the Carina API doesn't have any actions beyond the common cluster operations
+func (carina *MakeCOE) ListActions() []common.ClusterAction {
+	return nil
+}
+
+// InvokeAction is not supported
+func (carina *MakeCOE) InvokeAction(token string, action string, params map[string]string) (common.Cluster, error) {
+	_, err := common.LookupClusterAction(carina.ListActions(), action)
+	return nil, err
 }
 
 // WaitUntilClusterIsActive waits until the prior cluster operation is completed
@@ -240,19 +502,22 @@ func (carina *MakeCOE) WaitUntilClusterIsActive(cluster common.Cluster) (common.
 		return cluster, nil
 	}
 
-	pollingInterval := 5 * time.Second
+	waiter := common.NewWaiter(5*time.Second, "Waiting for cluster (%s) to become active", cluster.GetName())
 	for {
 		cluster, err := carina.GetCluster(cluster.GetID())
 		if err != nil {
 			return nil, err
 		}
 
+		waiter.UpdateCluster(cluster)
 		if isDone(cluster) {
 			return cluster, nil
 		}
 
-		common.Log.WriteDebug("[make-coe] Waiting until cluster (%s) is active, currently in %s", cluster.GetName(), cluster.GetStatus())
-		time.Sleep(pollingInterval)
+		err = waiter.Wait()
+		if err != nil {
+			return nil, err
+		}
 	}
 }
 
@@ -270,7 +535,7 @@ func (carina *MakeCOE) WaitUntilClusterIsDeleted(cluster common.Cluster) error {
 		return err
 	}
 
-	pollingInterval := 5 * time.Second
+	waiter := common.NewWaiter(5*time.Second, "Waiting for cluster (%s) to be deleted", cluster.GetName())
 	for {
 		cluster, err := carina.GetCluster(cluster.GetID())
 		if err != nil {
@@ -286,64 +551,102 @@ func (carina *MakeCOE) WaitUntilClusterIsDeleted(cluster common.Cluster) error {
 			return err
 		}
 
+		waiter.UpdateCluster(cluster)
 		if done, err := isDone(cluster); done {
 			return err
 		}
 
-		common.Log.WriteDebug("[make-coe] Waiting until cluster (%s) is deleted, currently in %s", cluster.GetName(), cluster.GetStatus())
-		time.Sleep(pollingInterval)
+		err = waiter.Wait()
+		if err != nil {
+			return err
+		}
 	}
 }
 
-func (carina *MakeCOE) listClusterTypes() ([]*libcarina.ClusterType, error) {
+// listClusterTypes lists the cluster types directly, instead of with libcarina, so that the capabilities reported by newer versions of the API are kept
+func (carina *MakeCOE) listClusterTypes() ([]*clusterType, error) {
 	common.Log.WriteDebug("[make-coe] Listing cluster types")
-	clusterTypes, err := carina.client.ListClusterTypes()
+	resp, err := carina.client.NewRequest("GET", "/cluster_types", nil)
+	if err != nil {
+		return nil, handleLibcarinaError(errors.Wrap(err, "[make-coe] Unable to list cluster types"))
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ClusterTypes []*clusterType `json:"cluster_types"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
 	if err != nil {
-		return nil, handleLibcarinaError(errors.Wrap(err, "[make-coe] Unabe to list cluster types"))
+		return nil, errors.Wrap(err, "[make-coe] Unable to parse the cluster types")
 	}
 
-	return clusterTypes, err
+	return result.ClusterTypes, nil
 }
 
-func (carina *MakeCOE) getClusterTypeCache() (map[int]*libcarina.ClusterType, error) {
-	if carina.clusterTypeCache == nil {
-		clusterTypes, err := carina.listClusterTypes()
-		if err != nil {
-			return nil, err
-		}
+// getClusterTypes returns the cluster types cached for the account, listing them again when they have expired,
+// were cached for a different endpoint, or --refresh was specified and they haven't been listed yet by this process
+func (carina *MakeCOE) getClusterTypes() ([]*clusterType, error) {
+	account := carina.Account
+	account.clusterTypesLock.Lock()
+	defer account.clusterTypesLock.Unlock()
 
-		carina.clusterTypeCache = make(map[int]*libcarina.ClusterType)
-		for _, clusterType := range clusterTypes {
-			carina.clusterTypeCache[clusterType.ID] = clusterType
-		}
+	cached := account.clusterTypes != nil && account.clusterTypesEndpoint == account.getEndpoint() && time.Now().Before(account.clusterTypesExpires)
+	if cached && (account.clusterTypesListed || !ClusterTypeCachePolicy.Refresh) {
+		common.Log.WriteDebug("[make-coe] Using %d cached cluster types", len(account.clusterTypes))
+		return account.clusterTypes, nil
+	}
+
+	clusterTypes, err := carina.listClusterTypes()
+	if err != nil {
+		return nil, err
 	}
 
-	return carina.clusterTypeCache, nil
+	// Replace rather than update the cache, so that removed cluster types are evicted
+	account.clusterTypes = clusterTypes
+	account.clusterTypesEndpoint = account.getEndpoint()
+	account.clusterTypesExpires = time.Now().Add(ClusterTypeCachePolicy.TTL)
+	account.clusterTypesListed = true
+
+	return clusterTypes, nil
 }
 
-func (carina *MakeCOE) lookupClusterTypeByName(pattern string) (*libcarina.ClusterType, error) {
-	cache, err := carina.getClusterTypeCache()
+func (carina *MakeCOE) lookupClusterTypeByName(pattern string) (*clusterType, error) {
+	clusterTypes, err := carina.getClusterTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	matcher, err := common.NameMatchPolicy.NewMatcher(pattern)
 	if err != nil {
 		return nil, err
 	}
 
-	var clusterType *libcarina.ClusterType
-	for _, m := range cache {
-		if !glob.GlobI(pattern, m.Name) {
+	var matches []*clusterType
+	for _, m := range clusterTypes {
+		if !matcher.Matches(m.Name) {
 			continue
 		}
 
 		common.Log.WriteDebug("Matched template '%s' to pattern '%s'", m.Name, pattern)
-		if clusterType == nil {
-			clusterType = m
-		} else {
-			return nil, &common.MultipleMatchingTemplatesError{TemplatePattern: pattern}
-		}
+		matches = append(matches, m)
 	}
 
-	if clusterType == nil {
-		return nil, fmt.Errorf("Could not find template named %s", pattern)
+	switch len(matches) {
+	case 0:
+		templates := make([]common.ClusterTemplate, len(clusterTypes))
+		for i, m := range clusterTypes {
+			templates[i] = newClusterTemplate(m)
+		}
+		return nil, common.NewTemplateNotFoundError(pattern, templates)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		candidates := make([]common.ClusterTemplate, len(matches))
+		for i, m := range matches {
+			names[i] = m.Name
+			candidates[i] = newClusterTemplate(m)
+		}
+		return nil, &common.MultipleMatchingTemplatesError{TemplatePattern: pattern, MatchingTemplates: names, Candidates: candidates}
 	}
-
-	return clusterType, nil
 }
@@ -1,14 +1,17 @@
 package makecoe
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/getcarina/carina/common"
+	"github.com/getcarina/libcarina"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,11 +59,11 @@ func createMockCarina(h handler) (*httptest.Server, *httptest.Server) {
 
 func createMakeCOEService(identityServer *httptest.Server, carinaServer *httptest.Server) *MakeCOE {
 	acct := &Account{
-		AuthEndpointOverride: identityServer.URL + identityAPIVersion,
-		EndpointOverride:     carinaServer.URL,
-		UserName:             "fake-user",
-		APIKey:               "fake-apikey",
-		Region:               "DFW",
+		IdentityEndpoint: identityServer.URL + identityAPIVersion,
+		EndpointOverride: carinaServer.URL,
+		UserName:         "fake-user",
+		APIKey:           "fake-apikey",
+		Region:           "DFW",
 	}
 
 	return &MakeCOE{Account: acct}
@@ -248,3 +251,218 @@ func TestCreateClusterWithWildcardTemplate(t *testing.T) {
 
 	assert.IsType(t, &common.MultipleMatchingTemplatesError{}, err, err.Error())
 }
+
+func TestGetQuotas(t *testing.T) {
+	common.Log.RegisterTestLogger(t)
+
+	mockCarina, mockIdentity := createMockCarina(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"max_clusters": 5, "max_nodes_per_cluster": 10}`)
+	})
+	defer mockCarina.Close()
+	defer mockIdentity.Close()
+
+	svc := createMakeCOEService(mockIdentity, mockCarina)
+
+	quotas, err := svc.GetQuotas()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 5, quotas.MaxClusters)
+		assert.Equal(t, 10, quotas.MaxNodesPerCluster)
+	}
+}
+
+func TestGetQuotasUnknownWhenNotFound(t *testing.T) {
+	common.Log.RegisterTestLogger(t)
+
+	mockCarina, mockIdentity := createMockCarina(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(404)
+	})
+	defer mockCarina.Close()
+	defer mockIdentity.Close()
+
+	svc := createMakeCOEService(mockIdentity, mockCarina)
+
+	quotas, err := svc.GetQuotas()
+	if assert.NoError(t, err) {
+		assert.Equal(t, &common.Quotas{}, quotas, "the quotas should be unknown, instead of guessing the limits")
+	}
+}
+
+func TestListClusterTemplatesWithCapabilities(t *testing.T) {
+	common.Log.RegisterTestLogger(t)
+
+	mockCarina, mockIdentity := createMockCarina(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"cluster_types": [{"active": true, "coe": "swarm", "host_type": "lxc", "name": "Swarm 1.11.2 on LXC", "id": 21}, {"active": true, "coe": "kubernetes", "host_type": "vm", "name": "Kubernetes 1.5.2 on VM", "id": 22, "max_nodes": 20, "autoscale": true, "gpu": true}]}`)
+	})
+	defer mockCarina.Close()
+	defer mockIdentity.Close()
+
+	svc := createMakeCOEService(mockIdentity, mockCarina)
+
+	templates, err := svc.ListClusterTemplates()
+	if assert.NoError(t, err) && assert.Len(t, templates, 2) {
+		assert.Nil(t, templates[0].GetCapabilities(), "Cluster types without capabilities should not report any")
+		assert.Equal(t, &common.TemplateCapabilities{MaxNodes: 20, AutoScale: true, GPU: true}, templates[1].GetCapabilities())
+		assert.Equal(t, "kubernetes", templates[1].GetCOE())
+	}
+}
+
+func TestMatchClusterType(t *testing.T) {
+	clusterTypes := []*clusterType{
+		{ClusterType: &libcarina.ClusterType{ID: 21, Name: "Swarm 1.11.2 on LXC", COE: "swarm", HostType: "lxc", Active: true}},
+		{ClusterType: &libcarina.ClusterType{ID: 22, Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes", HostType: "lxc", Active: true}},
+		{ClusterType: &libcarina.ClusterType{ID: 23, Name: "Kubernetes 1.4.5 on LXC", COE: "kubernetes", HostType: "lxc", Active: false}},
+		{ClusterType: &libcarina.ClusterType{ID: 24, Name: "Kubernetes 1.5.2 on VM", COE: "kubernetes", HostType: "vm", Active: true}},
+	}
+
+	template, err := matchClusterType(clusterTypes, common.TemplateSelector{COE: "kubernetes", HostType: "lxc", Version: "1.x"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "Kubernetes 1.5.2 on LXC", template.GetName(), "Inactive cluster types should be ignored")
+	}
+
+	_, err = matchClusterType(clusterTypes, common.TemplateSelector{COE: "kubernetes"})
+	if assert.IsType(t, &common.MultipleMatchingTemplatesError{}, err) {
+		assert.Len(t, err.(*common.MultipleMatchingTemplatesError).MatchingTemplates, 2)
+	}
+
+	_, err = matchClusterType(clusterTypes, common.TemplateSelector{COE: "mesos"})
+	assert.IsType(t, common.NotFoundError{}, err)
+}
+
+func TestSetAutoScale(t *testing.T) {
+	common.Log.RegisterTestLogger(t)
+
+	// The clusters are looked up by name, then retrieved by id
+	const scalableID = "2c3c2f1e-4d1a-4c3b-9a43-7f1e4b4a0a01"
+	const fixedID = "9e1f0c8a-6b2d-4f0e-8c51-3d2a7b9e0b02"
+
+	var requested autoScaleSettings
+	mockCarina, mockIdentity := createMockCarina(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/clusters":
+			fmt.Fprintln(w, `{"clusters": [{"id": "`+scalableID+`", "name": "scalable", "node_count": 3, "status": "active"}, {"id": "`+fixedID+`", "name": "fixed", "node_count": 3, "status": "active"}]}`)
+		case r.Method == "GET" && r.URL.Path == "/clusters/"+scalableID:
+			fmt.Fprintln(w, `{"id": "`+scalableID+`", "name": "scalable", "node_count": 3, "status": "active", "autoscale": {"enabled": false}}`)
+		case r.Method == "GET" && r.URL.Path == "/clusters/"+fixedID:
+			fmt.Fprintln(w, `{"id": "`+fixedID+`", "name": "fixed", "node_count": 3, "status": "active", "cluster_type": {"name": "Kubernetes 1.5.2 on LXC"}}`)
+		case r.Method == "PUT" && r.URL.Path == "/clusters/"+scalableID+"/autoscale":
+			json.NewDecoder(r.Body).Decode(&requested)
+			fmt.Fprintf(w, `{"id": "`+scalableID+`", "name": "scalable", "node_count": 3, "status": "active", "autoscale": {"enabled": %t, "min_nodes": %d, "max_nodes": %d}}`,
+				requested.Enabled, requested.MinNodes, requested.MaxNodes)
+		default:
+			w.WriteHeader(404)
+			fmt.Fprintln(w, "unexpected request: "+r.Method+" "+r.RequestURI)
+		}
+	})
+	defer mockCarina.Close()
+	defer mockIdentity.Close()
+
+	svc := createMakeCOEService(mockIdentity, mockCarina)
+
+	cluster, err := svc.GetCluster("scalable")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &common.AutoScale{Enabled: false}, cluster.GetAutoScale())
+	}
+
+	cluster, err = svc.SetAutoScale("scalable", common.AutoScale{Enabled: true, MinNodes: 3, MaxNodes: 10})
+	if assert.NoError(t, err) {
+		assert.Equal(t, autoScaleSettings{Enabled: true, MinNodes: 3, MaxNodes: 10}, requested)
+		assert.Equal(t, "on (3-10 nodes)", cluster.GetAutoScale().String())
+	}
+
+	_, err = svc.SetAutoScale("fixed", common.AutoScale{Enabled: true})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Kubernetes 1.5.2 on LXC")
+	}
+}
+
+func TestClusterTypesAreCachedWithTheAccount(t *testing.T) {
+	common.Log.RegisterTestLogger(t)
+
+	var listed int
+	mockCarina, mockIdentity := createMockCarina(func(w http.ResponseWriter, r *http.Request) {
+		listed++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"cluster_types": [{"active": true, "coe": "swarm", "host_type": "lxc", "name": "Swarm 1.11.2 on LXC", "id": 21}]}`)
+	})
+	defer mockCarina.Close()
+	defer mockIdentity.Close()
+
+	svc := createMakeCOEService(mockIdentity, mockCarina)
+	_, err := svc.ListClusterTemplates()
+	assert.NoError(t, err)
+	_, err = svc.ResolveTemplate(common.TemplateSelector{COE: "swarm"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, listed, "The cluster types should be listed once")
+
+	// A later command uses the cluster types cached with the token
+	cached := createMakeCOEService(mockIdentity, mockCarina)
+	cached.Account.ApplyCache(svc.Account.BuildCache())
+	templates, err := cached.ListClusterTemplates()
+	if assert.NoError(t, err) {
+		assert.Len(t, templates, 1)
+	}
+	assert.Equal(t, 1, listed, "The cached cluster types should be used")
+
+	// --refresh lists them again, once
+	ClusterTypeCachePolicy.Refresh = true
+	defer func() { ClusterTypeCachePolicy.Refresh = false }()
+	refreshed := createMakeCOEService(mockIdentity, mockCarina)
+	refreshed.Account.ApplyCache(svc.Account.BuildCache())
+	_, err = refreshed.ListClusterTemplates()
+	assert.NoError(t, err)
+	_, err = refreshed.ListClusterTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, listed, "--refresh should list the cluster types again")
+}
+
+func TestAuthCredentialsPrecedence(t *testing.T) {
+	// The username and API key are used when there is no token
+	account := &Account{UserName: "fake-user", APIKey: "fake-apikey"}
+	token, endpoint, err := account.authCredentials()
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+	assert.Empty(t, endpoint)
+
+	// The cached token is preferred over the API key
+	account.ApplyCache(map[string]string{"token": "cached-token", "endpoint": "https://api.dfw.getcarina.com"})
+	token, endpoint, err = account.authCredentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "cached-token", token)
+	assert.Equal(t, "https://api.dfw.getcarina.com", endpoint)
+
+	// The specified token is preferred over the cached token
+	account.Token = "ci-token"
+	token, _, err = account.authCredentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "ci-token", token)
+
+	// A token on its own needs to know the endpoint
+	tokenOnly := &Account{UserName: "fake-user", Token: "ci-token"}
+	_, _, err = tokenOnly.authCredentials()
+	assert.Error(t, err)
+
+	tokenOnly.EndpointOverride = "https://api.example.com"
+	token, endpoint, err = tokenOnly.authCredentials()
+	assert.NoError(t, err)
+	assert.Equal(t, "ci-token", token)
+	assert.Equal(t, "https://api.example.com", endpoint)
+
+	// Without a usable token, the API key is required
+	_, _, err = (&Account{UserName: "fake-user"}).authCredentials()
+	assert.Error(t, err)
+}
+
+func TestDecodeClusterNodeSize(t *testing.T) {
+	cluster, err := decodeCluster(strings.NewReader(`{"id": "sized", "name": "sized", "node_count": 3, "status": "active", "created_at": "2017-03-01T14:30:00Z", "node_size": {"ram": 4096, "vcpus": 2, "disk": 40, "hourly_cost": 0.12, "currency": "USD"}}`))
+	assert.Nil(t, err)
+	assert.Equal(t, &common.NodeSize{RAM: 4096, VCPUs: 2, Disk: 40, HourlyCost: 0.12, Currency: "USD"}, cluster.GetNodeSize())
+	assert.Equal(t, "2017-03-01T14:30:00Z", cluster.GetCreated().Format(time.RFC3339))
+
+	cluster, err = decodeCluster(strings.NewReader(`{"id": "unsized", "name": "unsized", "node_count": 3, "status": "active"}`))
+	assert.Nil(t, err)
+	assert.Nil(t, cluster.GetNodeSize())
+	assert.True(t, cluster.GetCreated().IsZero())
+}
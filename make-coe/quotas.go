@@ -1,14 +1,17 @@
 package makecoe
 
-// Quotas contains the quota information for a CarinaAccount
-type Quotas struct{}
+import "github.com/getcarina/carina/common"
 
-// GetMaxClusters returns the maximum number of clusters allowed on the account
-func (quotas *Quotas) GetMaxClusters() int {
-	return 3
+// Quotas contains the quota information for a CarinaAccount
+type Quotas struct {
+	MaxClusters        int `json:"max_clusters"`
+	MaxNodesPerCluster int `json:"max_nodes_per_cluster"`
 }
 
-// GetMaxNodesPerCluster returns the maximum number of nodes allowed in a cluster on the account
-func (quotas *Quotas) GetMaxNodesPerCluster() int {
-	return 1
+// toCommon converts the quotas returned by the API to the quotas shared by all cluster services
+func (quotas Quotas) toCommon() *common.Quotas {
+	return &common.Quotas{
+		MaxClusters:        quotas.MaxClusters,
+		MaxNodesPerCluster: quotas.MaxNodesPerCluster,
+	}
 }
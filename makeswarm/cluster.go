@@ -2,6 +2,7 @@ package makeswarm
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/getcarina/carina/common"
 	libcarina "github.com/getcarina/libmakeswarm"
@@ -54,3 +55,28 @@ func (cluster *Cluster) GetStatus() string {
 func (cluster *Cluster) GetStatusDetails() string {
 	return ""
 }
+
+// GetLabels is not supported by the API, labels are stored locally by the client
+func (cluster *Cluster) GetLabels() map[string]string {
+	return nil
+}
+
+// GetAutoScale returns if autoscaling is enabled, make-swarm doesn't support limiting the number of nodes
+func (cluster *Cluster) GetAutoScale() *common.AutoScale {
+	return &common.AutoScale{Enabled: cluster.AutoScale}
+}
+
+// GetCreated is not supported
+func (cluster *Cluster) GetCreated() time.Time {
+	return time.Time{}
+}
+
+// GetUpdated is not supported
+func (cluster *Cluster) GetUpdated() time.Time {
+	return time.Time{}
+}
+
+// GetNodeSize is not supported, only the flavor of the nodes is known
+func (cluster *Cluster) GetNodeSize() *common.NodeSize {
+	return nil
+}
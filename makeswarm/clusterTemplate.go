@@ -1,5 +1,7 @@
 package makeswarm
 
+import "github.com/getcarina/carina/common"
+
 // ClusterTemplate represents a cluster template for makeswarm
 type ClusterTemplate struct {
 }
@@ -18,3 +20,33 @@ func (template *ClusterTemplate) GetCOE() string {
 func (template *ClusterTemplate) GetHostType() string {
 	return "lxc"
 }
+
+// IsDeprecated is not supported
+func (template *ClusterTemplate) IsDeprecated() bool {
+	return false
+}
+
+// GetCOEVersion is not supported
+func (template *ClusterTemplate) GetCOEVersion() string {
+	return ""
+}
+
+// GetNodeFlavor is not supported
+func (template *ClusterTemplate) GetNodeFlavor() string {
+	return ""
+}
+
+// GetDetails is not supported
+func (template *ClusterTemplate) GetDetails() map[string]string {
+	return nil
+}
+
+// GetAvailability is not supported
+func (template *ClusterTemplate) GetAvailability() *common.TemplateAvailability {
+	return nil
+}
+
+// GetCapabilities is not supported
+func (template *ClusterTemplate) GetCapabilities() *common.TemplateCapabilities {
+	return nil
+}
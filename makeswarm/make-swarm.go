@@ -39,8 +39,17 @@ func (carina *MakeSwarm) init() error {
 	return nil
 }
 
+// Authenticate authenticates with the account's credentials, returning the endpoint of the Carina API
+func (carina *MakeSwarm) Authenticate() (string, error) {
+	err := carina.init()
+	if err != nil {
+		return "", err
+	}
+	return carina.client.Endpoint, nil
+}
+
 // GetQuotas retrieves the quotas set for the account
-func (carina *MakeSwarm) GetQuotas() (common.Quotas, error) {
+func (carina *MakeSwarm) GetQuotas() (*common.Quotas, error) {
 	err := carina.init()
 	if err != nil {
 		return nil, err
@@ -51,9 +60,11 @@ func (carina *MakeSwarm) GetQuotas() (common.Quotas, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "[make-swarm] Unable to retrieve account quotas")
 	}
-	quotas := CarinaQuotas(*result)
 
-	return &quotas, err
+	return &common.Quotas{
+		MaxClusters:        result.MaxClusters.Int(),
+		MaxNodesPerCluster: result.MaxNodesPerCluster.Int(),
+	}, nil
 }
 
 // CreateCluster creates a new cluster and prints the cluster information
@@ -205,14 +216,18 @@ func (carina *MakeSwarm) ResizeCluster(token string, nodes int) (common.Cluster,
 }
 
 // SetAutoScale enables or disables autoscaling on a cluster
-func (carina *MakeSwarm) SetAutoScale(name string, value bool) (common.Cluster, error) {
+func (carina *MakeSwarm) SetAutoScale(name string, autoscale common.AutoScale) (common.Cluster, error) {
+	if autoscale.MinNodes > 0 || autoscale.MaxNodes > 0 {
+		return nil, errors.New("[make-swarm] Limiting the number of nodes with --min and --max is not supported")
+	}
+
 	err := carina.init()
 	if err != nil {
 		return nil, err
 	}
 
-	common.Log.WriteDebug("[make-swarm] Changing the autoscale setting on the cluster (%s) to %t", name, value)
-	result, err := carina.client.SetAutoScale(name, value)
+	common.Log.WriteDebug("[make-swarm] Changing the autoscale setting on the cluster (%s) to %t", name, autoscale.Enabled)
+	result, err := carina.client.SetAutoScale(name, autoscale.Enabled)
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("[make-swarm] Unable to change the cluster's autoscale setting (%s)", name))
 	}
@@ -222,6 +237,17 @@ func (carina *MakeSwarm) SetAutoScale(name string, value bool) (common.Cluster,
 	return cluster, nil
 }
 
+// ListActions returns nil, make-swarm doesn't have any actions beyond the common cluster operations
+func (carina *MakeSwarm) ListActions() []common.ClusterAction {
+	return nil
+}
+
+// InvokeAction is not supported
+func (carina *MakeSwarm) InvokeAction(token string, action string, params map[string]string) (common.Cluster, error) {
+	_, err := common.LookupClusterAction(carina.ListActions(), action)
+	return nil, err
+}
+
 // WaitUntilClusterIsActive waits until the prior cluster operation is completed
 func (carina *MakeSwarm) WaitUntilClusterIsActive(cluster common.Cluster) (common.Cluster, error) {
 	isDone := func(cluster common.Cluster) bool {
@@ -234,18 +260,22 @@ func (carina *MakeSwarm) WaitUntilClusterIsActive(cluster common.Cluster) (commo
 		return cluster, nil
 	}
 
+	waiter := common.NewWaiter(clusterPollingInterval, "Waiting for cluster (%s) to become active", cluster.GetName())
 	for {
 		cluster, err := carina.GetCluster(cluster.GetName())
 		if err != nil {
 			return cluster, err
 		}
 
+		waiter.UpdateCluster(cluster)
 		if isDone(cluster) {
 			return cluster, nil
 		}
 
-		common.Log.WriteDebug("[make-swarm] Waiting until cluster (%s) is active, currently in %s", cluster.GetName(), cluster.GetStatus())
-		time.Sleep(clusterPollingInterval)
+		err = waiter.Wait()
+		if err != nil {
+			return cluster, err
+		}
 	}
 }
 
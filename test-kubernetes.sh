@@ -25,6 +25,6 @@ echo -e "\n6. Testing the cluster connection with kubectl..."
 kubectl cluster-info
 
 echo -e "\n7. Removing the cluster..."
-./carina --cloud=$CLOUD delete --wait ci
+./carina --cloud=$CLOUD delete --wait --yes ci
 
 echo -e "\n#######\nAll done!\n#######\n"
\ No newline at end of file
@@ -28,6 +28,6 @@ echo -e "\n6. Testing the cluster connection..."
 docker info
 
 echo -e "\n7. Removing the cluster..."
-./carina --cloud=$CLOUD delete --wait ci
+./carina --cloud=$CLOUD delete --wait --yes ci
 
 echo -e "\n#######\nAll done!\n#######\n"
\ No newline at end of file
@@ -0,0 +1,134 @@
+package testsupport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/getcarina/carina/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ConformanceOptions configures the conformance test suite for a common.ClusterService implementation
+type ConformanceOptions struct {
+	// NewService creates a ClusterService, backed by an isolated and empty account, for each test
+	NewService func(t *testing.T) common.ClusterService
+
+	// Template is the name of a template which exists on the backend
+	Template string
+
+	// AmbiguousTemplatePattern is a pattern which matches multiple templates.
+	// Leave empty when the implementation does not support template patterns.
+	AmbiguousTemplatePattern string
+
+	// IsActive returns if a cluster status means that the cluster is ready to use.
+	// Defaults to a case-insensitive comparison against "active".
+	IsActive func(status string) bool
+}
+
+// RunConformanceTests verifies that a ClusterService implementation honors the contracts of the common.ClusterService interface
+func RunConformanceTests(t *testing.T, opts ConformanceOptions) {
+	if opts.IsActive == nil {
+		opts.IsActive = func(status string) bool {
+			return strings.EqualFold(status, "active")
+		}
+	}
+
+	t.Run("ListClusterTemplates", func(t *testing.T) {
+		svc := opts.NewService(t)
+
+		templates, err := svc.ListClusterTemplates()
+		require.NoError(t, err)
+
+		var found bool
+		for _, template := range templates {
+			assert.NotEmpty(t, template.GetName(), "Templates must have a name")
+			if template.GetName() == opts.Template {
+				found = true
+			}
+		}
+		assert.True(t, found, "Expected template %s to be listed", opts.Template)
+	})
+
+	t.Run("CreateRequiresTemplate", func(t *testing.T) {
+		svc := opts.NewService(t)
+
+		cluster, err := svc.CreateCluster("conformance", "", 1)
+		assert.Error(t, err)
+		assert.Nil(t, cluster)
+	})
+
+	t.Run("CreateWithAmbiguousTemplate", func(t *testing.T) {
+		if opts.AmbiguousTemplatePattern == "" {
+			t.Skip("Template patterns are not supported")
+		}
+		svc := opts.NewService(t)
+
+		cluster, err := svc.CreateCluster("conformance", opts.AmbiguousTemplatePattern, 1)
+		if assert.IsType(t, &common.MultipleMatchingTemplatesError{}, err) {
+			assert.True(t, len(err.(*common.MultipleMatchingTemplatesError).MatchingTemplates) > 1, "Expected the error to list the matching templates")
+		}
+		assert.Nil(t, cluster)
+	})
+
+	t.Run("GetMissingCluster", func(t *testing.T) {
+		svc := opts.NewService(t)
+
+		cluster, err := svc.GetCluster("missing-cluster")
+		assert.Error(t, err)
+		assert.Nil(t, cluster)
+	})
+
+	t.Run("ClusterLifecycle", func(t *testing.T) {
+		svc := opts.NewService(t)
+
+		cluster, err := svc.CreateCluster("conformance", opts.Template, 1)
+		require.NoError(t, err)
+		require.NotNil(t, cluster)
+		assert.NotEmpty(t, cluster.GetID(), "Created clusters must have an id")
+		assert.Equal(t, "conformance", cluster.GetName())
+		assert.Equal(t, opts.Template, cluster.GetTemplate().GetName())
+
+		cluster, err = svc.WaitUntilClusterIsActive(cluster)
+		require.NoError(t, err)
+		assert.True(t, opts.IsActive(cluster.GetStatus()), "Expected the cluster to be active after waiting, got %s", cluster.GetStatus())
+
+		// Waiting on an active cluster returns immediately
+		again, err := svc.WaitUntilClusterIsActive(cluster)
+		require.NoError(t, err)
+		assert.Equal(t, cluster.GetStatus(), again.GetStatus())
+
+		byName, err := svc.GetCluster(cluster.GetName())
+		require.NoError(t, err)
+		assert.Equal(t, cluster.GetID(), byName.GetID(), "Clusters can be retrieved by name")
+
+		byID, err := svc.GetCluster(cluster.GetID())
+		require.NoError(t, err)
+		assert.Equal(t, cluster.GetName(), byID.GetName(), "Clusters can be retrieved by id")
+
+		clusters, err := svc.ListClusters()
+		require.NoError(t, err)
+		var listed bool
+		for _, c := range clusters {
+			if c.GetID() == cluster.GetID() {
+				listed = true
+			}
+		}
+		assert.True(t, listed, "Expected the new cluster to be listed")
+
+		creds, err := svc.GetClusterCredentials(cluster.GetID())
+		require.NoError(t, err)
+		require.NotNil(t, creds)
+		assert.NotEmpty(t, creds.Files["ca.pem"], "The credentials bundle must include ca.pem")
+
+		deleted, err := svc.DeleteCluster(cluster.GetID())
+		require.NoError(t, err)
+		require.NotNil(t, deleted, "DeleteCluster must return the cluster being deleted")
+
+		err = svc.WaitUntilClusterIsDeleted(deleted)
+		require.NoError(t, err)
+
+		_, err = svc.GetCluster(cluster.GetID())
+		assert.Error(t, err, "Deleted clusters should not be found")
+	})
+}
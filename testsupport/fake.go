@@ -0,0 +1,760 @@
+// Package testsupport contains test doubles and a conformance test suite for common.ClusterService implementations
+package testsupport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getcarina/carina/common"
+	"github.com/getcarina/libcarina"
+	"github.com/pkg/errors"
+)
+
+// StatusActive is the status of a fake cluster that is ready to use
+const StatusActive = "active"
+
+// StatusCreating is the status of a fake cluster that is being created
+const StatusCreating = "creating"
+
+// StatusResizing is the status of a fake cluster that is changing its number of nodes
+const StatusResizing = "resizing"
+
+// StatusUpgrading is the status of a fake cluster that is moving to another template
+const StatusUpgrading = "upgrading"
+
+// StatusDeleting is the status of a fake cluster that is being deleted
+const StatusDeleting = "deleting"
+
+// StatusError is the status of a fake cluster that failed
+const StatusError = "error"
+
+// FakeClusterService is an in-memory common.ClusterService, useful for testing code which consumes a ClusterService
+type FakeClusterService struct {
+	sync.Mutex
+	Templates []*FakeClusterTemplate
+	Quotas    *common.Quotas
+	Keypairs  []*FakeKeypair
+
+	// PendingPolls is the number of times a cluster is retrieved before a pending operation completes
+	PendingPolls int
+
+	// IgnoreResize accepts resize requests without changing the number of nodes, like a backend which silently drops the resize
+	IgnoreResize bool
+
+	// AuthErr is returned by Authenticate, simulating rejected credentials
+	AuthErr error
+
+	// Actions are the backend-specific actions which can be invoked on a cluster
+	Actions []common.ClusterAction
+
+	// Invocations records the actions invoked on clusters, in order
+	Invocations []FakeInvocation
+
+	// APIVersion is the API version reported by ProbeCapabilities
+	APIVersion string
+
+	// Unsupported are the operations which ProbeCapabilities reports aren't supported, e.g. common.OperationRebuild
+	Unsupported []string
+
+	// Probes is the number of times ProbeCapabilities was called
+	Probes int
+
+	clusters map[string]*fakeClusterState
+	lastID   int
+}
+
+// FakeInvocation is an action invoked on a cluster of a FakeClusterService
+type FakeInvocation struct {
+	ClusterID string
+	Action    string
+	Params    map[string]string
+}
+
+type fakeClusterState struct {
+	cluster      FakeCluster
+	pendingPolls int
+}
+
+// NewFakeClusterService creates an empty FakeClusterService with the specified templates
+func NewFakeClusterService(templates ...*FakeClusterTemplate) *FakeClusterService {
+	return &FakeClusterService{
+		Templates:    templates,
+		Quotas:       &common.Quotas{MaxClusters: 3, MaxNodesPerCluster: 10},
+		PendingPolls: 1,
+		clusters:     make(map[string]*fakeClusterState),
+	}
+}
+
+// FakeEndpoint is the endpoint returned by FakeClusterService.Authenticate
+const FakeEndpoint = "https://fake.example.com"
+
+// Authenticate returns AuthErr, or FakeEndpoint when it is not set
+func (svc *FakeClusterService) Authenticate() (string, error) {
+	if svc.AuthErr != nil {
+		return "", svc.AuthErr
+	}
+	return FakeEndpoint, nil
+}
+
+// ProbeCapabilities reports APIVersion and the Unsupported operations
+func (svc *FakeClusterService) ProbeCapabilities() (*common.APICapabilities, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	svc.Probes++
+	return &common.APICapabilities{Endpoint: FakeEndpoint, MinVersion: svc.APIVersion, MaxVersion: svc.APIVersion, Unsupported: svc.Unsupported}, nil
+}
+
+// GetQuotas retrieves the quotas set for the account
+func (svc *FakeClusterService) GetQuotas() (*common.Quotas, error) {
+	return svc.Quotas, nil
+}
+
+// CreateCluster creates a new cluster
+func (svc *FakeClusterService) CreateCluster(name string, template string, nodes int) (common.Cluster, error) {
+	return svc.createCluster(name, template, nodes, common.SSHKey{})
+}
+
+// CreateClusterWithSSHKey creates a new cluster, recording the SSH key on the cluster. Driver options are not supported.
+func (svc *FakeClusterService) CreateClusterWithSSHKey(name string, template string, nodes int, key common.SSHKey, driverOptions map[string]string) (common.Cluster, error) {
+	if len(driverOptions) > 0 {
+		return nil, errors.New("[fake] Driver options are not supported")
+	}
+	if key.KeypairName != "" && svc.lookupKeypair(key.KeypairName) == nil {
+		return nil, common.NotFoundError{Err: fmt.Errorf("Could not find keypair named %s", key.KeypairName)}
+	}
+	return svc.createCluster(name, template, nodes, key)
+}
+
+// ListKeypairs retrieves the SSH keypairs registered with the account
+func (svc *FakeClusterService) ListKeypairs() ([]common.Keypair, error) {
+	var keypairs []common.Keypair
+	for _, keypair := range svc.Keypairs {
+		keypairs = append(keypairs, keypair)
+	}
+	return keypairs, nil
+}
+
+func (svc *FakeClusterService) lookupKeypair(name string) *FakeKeypair {
+	for _, keypair := range svc.Keypairs {
+		if keypair.Name == name {
+			return keypair
+		}
+	}
+	return nil
+}
+
+func (svc *FakeClusterService) createCluster(name string, template string, nodes int, key common.SSHKey) (common.Cluster, error) {
+	if template == "" {
+		return nil, errors.New("--template is required")
+	}
+
+	clusterTemplate, err := svc.lookupTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.Lock()
+	defer svc.Unlock()
+
+	if len(svc.clusters) >= svc.Quotas.MaxClusters {
+		return nil, common.QuotaError{Err: fmt.Errorf("Unable to create cluster (%s), the maximum number of clusters (%d) has been reached", name, svc.Quotas.MaxClusters)}
+	}
+
+	svc.lastID++
+	now := time.Now()
+	state := &fakeClusterState{
+		cluster: FakeCluster{
+			ID:       strconv.Itoa(svc.lastID),
+			Name:     name,
+			Template: clusterTemplate,
+			Nodes:    nodes,
+			Status:   StatusCreating,
+			SSHKey:   key,
+			Created:  now,
+			Updated:  now,
+		},
+		pendingPolls: svc.PendingPolls,
+	}
+	if clusterTemplate.SupportsAutoScale {
+		state.cluster.AutoScale = &common.AutoScale{}
+	}
+	svc.clusters[state.cluster.ID] = state
+
+	return state.snapshot(), nil
+}
+
+// AddCluster adds an existing cluster, e.g. from a fixture, assigning it the next id. The cluster is active unless its status is set.
+func (svc *FakeClusterService) AddCluster(cluster FakeCluster) common.Cluster {
+	svc.Lock()
+	defer svc.Unlock()
+
+	svc.lastID++
+	cluster.ID = strconv.Itoa(svc.lastID)
+	if cluster.Status == "" {
+		cluster.Status = StatusActive
+	}
+	state := &fakeClusterState{cluster: cluster}
+	svc.clusters[cluster.ID] = state
+
+	return state.snapshot()
+}
+
+// ListClusters retrieves all clusters
+func (svc *FakeClusterService) ListClusters() ([]common.Cluster, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	var clusters []common.Cluster
+	for i := 1; i <= svc.lastID; i++ {
+		if state, ok := svc.clusters[strconv.Itoa(i)]; ok {
+			clusters = append(clusters, state.snapshot())
+		}
+	}
+	return clusters, nil
+}
+
+// ListClusterTemplates retrieves available templates for creating a new cluster
+func (svc *FakeClusterService) ListClusterTemplates() ([]common.ClusterTemplate, error) {
+	var templates []common.ClusterTemplate
+	for _, template := range svc.Templates {
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// GetCluster retrieves a cluster by its id or name (if unique)
+func (svc *FakeClusterService) GetCluster(token string) (common.Cluster, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	state, err := svc.lookupCluster(token)
+	if err != nil {
+		return nil, err
+	}
+
+	state.poll()
+	if state.cluster.Status == StatusDeleting && state.pendingPolls < 0 {
+		delete(svc.clusters, state.cluster.ID)
+		return nil, common.NotFoundError{Err: fmt.Errorf("Could not find cluster (%s)", token)}
+	}
+
+	return state.snapshot(), nil
+}
+
+// ListNodes retrieves the nodes in a cluster by its id or name (if unique)
+func (svc *FakeClusterService) ListNodes(token string) ([]common.Node, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	state, err := svc.lookupCluster(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []common.Node
+	for i := 0; i < state.cluster.Nodes; i++ {
+		nodes = append(nodes, &FakeNode{
+			Name:    fmt.Sprintf("%s-node-%d", state.cluster.Name, i),
+			Address: fmt.Sprintf("10.0.0.%d", i+1),
+			Role:    "node",
+			Status:  state.cluster.Status,
+		})
+	}
+	return nodes, nil
+}
+
+// GetClusterCredentials retrieves the TLS certificates and configuration scripts for a cluster by its id or name (if unique)
+func (svc *FakeClusterService) GetClusterCredentials(token string) (*libcarina.CredentialsBundle, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	state, err := svc.lookupCluster(token)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := libcarina.NewCredentialsBundle()
+	creds.Files["ca.pem"] = []byte("fake-ca")
+	creds.Files["cert.pem"] = []byte("fake-cert")
+	creds.Files["key.pem"] = []byte("fake-key")
+	creds.Files["docker.env"] = []byte(fmt.Sprintf("export DOCKER_HOST=tcp://%s.example.com:2376\n", state.cluster.ID))
+	return creds, nil
+}
+
+// ResizeCluster resizes the cluster to the specified number of nodes
+func (svc *FakeClusterService) ResizeCluster(token string, nodes int) (common.Cluster, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	state, err := svc.lookupCluster(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.IgnoreResize {
+		state.cluster.Nodes = nodes
+	}
+	state.cluster.Status = StatusResizing
+	state.cluster.Updated = time.Now()
+	state.pendingPolls = svc.PendingPolls
+	return state.snapshot(), nil
+}
+
+// RebuildCluster is not supported
+func (svc *FakeClusterService) RebuildCluster(token string) (common.Cluster, error) {
+	return nil, errors.New("[fake] Rebuilding clusters is not supported")
+}
+
+// DeleteCluster permanently deletes a cluster by its id or name (if unique)
+func (svc *FakeClusterService) DeleteCluster(token string) (common.Cluster, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	state, err := svc.lookupCluster(token)
+	if err != nil {
+		return nil, err
+	}
+
+	state.cluster.Status = StatusDeleting
+	state.cluster.Updated = time.Now()
+	state.pendingPolls = svc.PendingPolls
+	return state.snapshot(), nil
+}
+
+// GrowCluster is not supported
+func (svc *FakeClusterService) GrowCluster(token string, nodes int) (common.Cluster, error) {
+	return nil, errors.New("[fake] Grow command not supported. Please use 'resize'.")
+}
+
+// SetAutoScale changes the autoscaling settings on a cluster whose template supports autoscaling
+func (svc *FakeClusterService) SetAutoScale(token string, autoscale common.AutoScale) (common.Cluster, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	state, err := svc.lookupCluster(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.cluster.AutoScale == nil {
+		return nil, fmt.Errorf("[fake] The %s template doesn't support autoscaling", state.cluster.GetTemplate().GetName())
+	}
+
+	state.cluster.AutoScale = &autoscale
+	return state.snapshot(), nil
+}
+
+// CreateClusterTemplate adds a template
+func (svc *FakeClusterService) CreateClusterTemplate(options common.ClusterTemplateOptions) (common.ClusterTemplate, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	for _, template := range svc.Templates {
+		if strings.EqualFold(template.Name, options.Name) {
+			return nil, fmt.Errorf("[fake] The template %s already exists", options.Name)
+		}
+	}
+
+	template := &FakeClusterTemplate{Name: options.Name, COE: options.COE, HostType: options.ServerType, NodeFlavor: options.Flavor}
+	svc.Templates = append(svc.Templates, template)
+	return template, nil
+}
+
+// DeleteClusterTemplate removes a template by its name, which must not be used by any clusters
+func (svc *FakeClusterService) DeleteClusterTemplate(token string) (common.ClusterTemplate, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	for i, template := range svc.Templates {
+		if !strings.EqualFold(template.Name, token) {
+			continue
+		}
+		for _, state := range svc.clusters {
+			if state.cluster.Template == template {
+				return nil, fmt.Errorf("[fake] The template %s is used by %s", template.Name, state.cluster.Name)
+			}
+		}
+		svc.Templates = append(svc.Templates[:i], svc.Templates[i+1:]...)
+		return template, nil
+	}
+	return nil, common.NotFoundError{Err: fmt.Errorf("Could not find template named %s", token)}
+}
+
+// UpgradeCluster moves a cluster to another template
+func (svc *FakeClusterService) UpgradeCluster(token string, template string) (common.Cluster, error) {
+	clusterTemplate, err := svc.lookupTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.Lock()
+	defer svc.Unlock()
+
+	state, err := svc.lookupCluster(token)
+	if err != nil {
+		return nil, err
+	}
+
+	state.cluster.Template = clusterTemplate
+	state.cluster.Status = StatusUpgrading
+	state.pendingPolls = svc.PendingPolls
+	return state.snapshot(), nil
+}
+
+// ListActions returns Actions
+func (svc *FakeClusterService) ListActions() []common.ClusterAction {
+	return svc.Actions
+}
+
+// InvokeAction records the invocation of one of the Actions, without changing the cluster
+func (svc *FakeClusterService) InvokeAction(token string, action string, params map[string]string) (common.Cluster, error) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	state, err := svc.lookupCluster(token)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = common.LookupClusterAction(svc.Actions, action)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.Invocations = append(svc.Invocations, FakeInvocation{ClusterID: state.cluster.GetID(), Action: action, Params: params})
+	return state.snapshot(), nil
+}
+
+// WaitUntilClusterIsActive polls the cluster status until either an active or error state is hit,
+// reporting each status to the observers of the cluster like the real services
+func (svc *FakeClusterService) WaitUntilClusterIsActive(cluster common.Cluster) (common.Cluster, error) {
+	waiter := common.NewWaiter(0, "Waiting for cluster (%s) to become active", cluster.GetName())
+	for {
+		waiter.UpdateCluster(cluster)
+		status := cluster.GetStatus()
+		if status == StatusActive || status == StatusError {
+			return cluster, nil
+		}
+
+		var err error
+		cluster, err = svc.GetCluster(cluster.GetID())
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// WaitUntilClusterIsDeleted polls the cluster status until either the cluster is gone or an error state is hit
+func (svc *FakeClusterService) WaitUntilClusterIsDeleted(cluster common.Cluster) error {
+	for {
+		if cluster.GetStatus() == StatusError {
+			return errors.New("Unable to delete cluster, an error occured while deleting.")
+		}
+
+		var err error
+		cluster, err = svc.GetCluster(cluster.GetID())
+		if err != nil {
+			// The fake only errors on a lookup when the cluster is gone
+			return nil
+		}
+	}
+}
+
+func (svc *FakeClusterService) lookupTemplate(pattern string) (*FakeClusterTemplate, error) {
+	matcher, err := common.NameMatchPolicy.NewMatcher(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []*FakeClusterTemplate
+	for _, template := range svc.Templates {
+		if matcher.Matches(template.Name) {
+			matches = append(matches, template)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		templates := make([]common.ClusterTemplate, len(svc.Templates))
+		for i, template := range svc.Templates {
+			templates[i] = template
+		}
+		return nil, common.NewTemplateNotFoundError(pattern, templates)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		candidates := make([]common.ClusterTemplate, len(matches))
+		for i, template := range matches {
+			names[i] = template.Name
+			candidates[i] = template
+		}
+		return nil, &common.MultipleMatchingTemplatesError{TemplatePattern: pattern, MatchingTemplates: names, Candidates: candidates}
+	}
+}
+
+func (svc *FakeClusterService) lookupCluster(token string) (*fakeClusterState, error) {
+	if state, ok := svc.clusters[token]; ok {
+		return state, nil
+	}
+
+	var matches []*fakeClusterState
+	for _, state := range svc.clusters {
+		if strings.EqualFold(state.cluster.Name, token) {
+			matches = append(matches, state)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, common.NotFoundError{Err: fmt.Errorf("Could not find cluster (%s)", token)}
+	case 1:
+		return matches[0], nil
+	default:
+		clusters := make([]common.Cluster, len(matches))
+		for i, state := range matches {
+			clusters[i] = state.snapshot()
+		}
+		return nil, &common.MultipleMatchingClustersError{ClusterName: token, MatchingClusters: clusters}
+	}
+}
+
+// FailCluster puts a cluster into the error state, with the reason reported by GetStatusDetails
+func (svc *FakeClusterService) FailCluster(token string, reason string) error {
+	svc.Lock()
+	defer svc.Unlock()
+
+	state, err := svc.lookupCluster(token)
+	if err != nil {
+		return err
+	}
+
+	state.cluster.Status = StatusError
+	state.cluster.StatusDetails = reason
+	state.cluster.Updated = time.Now()
+	state.pendingPolls = 0
+	return nil
+}
+
+// poll advances a pending operation on the cluster
+func (state *fakeClusterState) poll() {
+	state.pendingPolls--
+	if state.pendingPolls >= 0 {
+		return
+	}
+
+	switch state.cluster.Status {
+	case StatusCreating, StatusResizing, StatusUpgrading:
+		state.cluster.Status = StatusActive
+		state.cluster.Updated = time.Now()
+	}
+}
+
+// snapshot returns a copy of the cluster, so that callers don't see later changes
+func (state *fakeClusterState) snapshot() *FakeCluster {
+	cluster := state.cluster
+	if cluster.AutoScale != nil {
+		autoscale := *cluster.AutoScale
+		cluster.AutoScale = &autoscale
+	}
+	return &cluster
+}
+
+// FakeCluster is an in-memory cluster, returned by FakeClusterService
+type FakeCluster struct {
+	ID            string
+	Name          string
+	Template      *FakeClusterTemplate
+	Nodes         int
+	Status        string
+	StatusDetails string
+	Labels        map[string]string
+	SSHKey        common.SSHKey
+
+	// AutoScale is the autoscaling settings, nil when the cluster's template doesn't support autoscaling
+	AutoScale *common.AutoScale
+
+	// Created and Updated are when the cluster was created and last changed, zero when unknown
+	Created time.Time
+	Updated time.Time
+
+	// Flavor and NodeSize are the size of the nodes, empty when unknown
+	Flavor   string
+	NodeSize *common.NodeSize
+}
+
+// GetID returns the cluster identifier
+func (cluster *FakeCluster) GetID() string {
+	return cluster.ID
+}
+
+// GetName returns the cluster name
+func (cluster *FakeCluster) GetName() string {
+	return cluster.Name
+}
+
+// GetTemplate returns the template used to create the cluster
+func (cluster *FakeCluster) GetTemplate() common.ClusterTemplate {
+	if cluster.Template == nil {
+		return &FakeClusterTemplate{}
+	}
+	return cluster.Template
+}
+
+// GetFlavor returns the flavor of the nodes in the cluster
+func (cluster *FakeCluster) GetFlavor() string {
+	return cluster.Flavor
+}
+
+// GetNodes returns the number of nodes in the cluster
+func (cluster *FakeCluster) GetNodes() string {
+	return strconv.Itoa(cluster.Nodes)
+}
+
+// GetStatus returns the status of the cluster
+func (cluster *FakeCluster) GetStatus() string {
+	return cluster.Status
+}
+
+// GetStatusDetails returns additional information about the cluster's status
+func (cluster *FakeCluster) GetStatusDetails() string {
+	return cluster.StatusDetails
+}
+
+// GetLabels returns the key/value pairs used to organize the cluster
+func (cluster *FakeCluster) GetLabels() map[string]string {
+	return cluster.Labels
+}
+
+// GetAutoScale returns the cluster's autoscaling settings
+func (cluster *FakeCluster) GetAutoScale() *common.AutoScale {
+	return cluster.AutoScale
+}
+
+// GetCreated returns when the cluster was created
+func (cluster *FakeCluster) GetCreated() time.Time {
+	return cluster.Created
+}
+
+// GetUpdated returns when the cluster was last changed
+func (cluster *FakeCluster) GetUpdated() time.Time {
+	return cluster.Updated
+}
+
+// GetNodeSize returns the resources of each node in the cluster
+func (cluster *FakeCluster) GetNodeSize() *common.NodeSize {
+	return cluster.NodeSize
+}
+
+// FakeNode is an in-memory cluster node, returned by FakeClusterService
+type FakeNode struct {
+	Name    string
+	Address string
+	Role    string
+	Status  string
+}
+
+// GetName returns the node name
+func (node *FakeNode) GetName() string {
+	return node.Name
+}
+
+// GetAddress returns the IP address of the node
+func (node *FakeNode) GetAddress() string {
+	return node.Address
+}
+
+// GetRole returns the role of the node in the cluster
+func (node *FakeNode) GetRole() string {
+	return node.Role
+}
+
+// GetStatus returns the status of the node
+func (node *FakeNode) GetStatus() string {
+	return node.Status
+}
+
+// FakeClusterTemplate is an in-memory cluster template, returned by FakeClusterService
+type FakeClusterTemplate struct {
+	Name       string
+	COE        string
+	HostType   string
+	Deprecated bool
+	COEVersion string
+	NodeFlavor string
+	Details    map[string]string
+
+	// Availability is the capacity hint reported for the template, nil when it isn't reported
+	Availability *common.TemplateAvailability
+
+	// Capabilities is what clusters created with the template support, nil when it isn't reported
+	Capabilities *common.TemplateCapabilities
+
+	// SupportsAutoScale is set when clusters created with the template can be autoscaled
+	SupportsAutoScale bool
+}
+
+// GetName returns the unique template name
+func (template *FakeClusterTemplate) GetName() string {
+	return template.Name
+}
+
+// GetCOE returns the container orchestration engine used by the cluster
+func (template *FakeClusterTemplate) GetCOE() string {
+	return template.COE
+}
+
+// GetHostType returns the underlying type of the host nodes, such as lxc or vm
+func (template *FakeClusterTemplate) GetHostType() string {
+	return template.HostType
+}
+
+// IsDeprecated returns if the template is deprecated and should not be used for new clusters
+func (template *FakeClusterTemplate) IsDeprecated() bool {
+	return template.Deprecated
+}
+
+// GetCOEVersion returns the version of the container orchestration engine
+func (template *FakeClusterTemplate) GetCOEVersion() string {
+	return template.COEVersion
+}
+
+// GetNodeFlavor returns the flavor of the host nodes
+func (template *FakeClusterTemplate) GetNodeFlavor() string {
+	return template.NodeFlavor
+}
+
+// GetDetails returns additional settings of the template
+func (template *FakeClusterTemplate) GetDetails() map[string]string {
+	return template.Details
+}
+
+// GetAvailability returns the capacity hint reported for the template
+func (template *FakeClusterTemplate) GetAvailability() *common.TemplateAvailability {
+	return template.Availability
+}
+
+// GetCapabilities returns what clusters created with the template support
+func (template *FakeClusterTemplate) GetCapabilities() *common.TemplateCapabilities {
+	return template.Capabilities
+}
+
+// FakeKeypair is an in-memory SSH keypair, returned by FakeClusterService
+type FakeKeypair struct {
+	Name        string
+	Fingerprint string
+}
+
+// GetName returns the unique keypair name
+func (keypair *FakeKeypair) GetName() string {
+	return keypair.Name
+}
+
+// GetFingerprint returns the fingerprint of the public key
+func (keypair *FakeKeypair) GetFingerprint() string {
+	return keypair.Fingerprint
+}
@@ -0,0 +1,22 @@
+package testsupport
+
+import (
+	"testing"
+
+	"github.com/getcarina/carina/common"
+)
+
+func TestFakeClusterServiceConformance(t *testing.T) {
+	common.Log.RegisterTestLogger(t)
+
+	RunConformanceTests(t, ConformanceOptions{
+		NewService: func(t *testing.T) common.ClusterService {
+			return NewFakeClusterService(
+				&FakeClusterTemplate{Name: "Swarm 1.11.2 on LXC", COE: "swarm", HostType: "lxc"},
+				&FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes", HostType: "lxc"},
+			)
+		},
+		Template:                 "Kubernetes 1.5.2 on LXC",
+		AmbiguousTemplatePattern: "*LXC",
+	})
+}
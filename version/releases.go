@@ -10,7 +10,24 @@ import (
 
 // Release is the minimal set of release data carina needs from the GitHub API
 type Release struct {
-	TagName string `json:"tag_name"`
+	TagName string         `json:"tag_name"`
+	Assets  []ReleaseAsset `json:"assets"`
+}
+
+// ReleaseAsset is a file attached to a release, such as a binary or the checksums
+type ReleaseAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// FindAsset returns the file attached to the release with the specified name
+func (rel *Release) FindAsset(name string) (*ReleaseAsset, bool) {
+	for i := range rel.Assets {
+		if rel.Assets[i].Name == name {
+			return &rel.Assets[i], true
+		}
+	}
+	return nil, false
 }
 
 func githubGet(uri string, rel *Release) error {
@@ -1,9 +1,42 @@
 package version
 
+import (
+	"runtime"
+)
+
 var (
 	// Version is the current CLI version
 	Version string
 
 	// Commit is the current commit this build comes from (if set)
 	Commit string
+
+	// BuildDate is when this build was made, in RFC 3339 format (if set)
+	BuildDate string
+
+	// ReleaseSigningKey is the base64 encoded ECDSA public key (PKIX, DER) which signs the release checksums, set by make release.
+	// carina update requires a valid signature in addition to a matching checksum, and refuses to update when it isn't set.
+	ReleaseSigningKey string
 )
+
+// BuildInfo identifies exactly which build of carina is running
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	GoVersion string
+
+	// Platform is the operating system and architecture, e.g. linux/amd64
+	Platform string
+}
+
+// GetBuildInfo returns the version and build metadata of the running carina
+func GetBuildInfo() BuildInfo {
+	return BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
@@ -0,0 +1,267 @@
+// Package web serves a minimal dashboard for managing clusters from a browser
+package web
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/common"
+	"github.com/pkg/errors"
+)
+
+// Server handles requests from the dashboard, using the client to manage the account's clusters
+type Server struct {
+	Client  *client.Client
+	Account client.Account
+
+	// Host is the host:port the server listens on, and requests for any other host are rejected to prevent DNS rebinding
+	Host string
+
+	// ConfirmationPolicy is the confirm rules from the config file, which are explained when confirming a delete
+	ConfirmationPolicy client.ConfirmationPolicy
+
+	// token protects the forms against cross-site request forgery
+	token string
+
+	// lock serializes calls to the client, which shares the account's cached token between requests
+	lock sync.Mutex
+}
+
+// NewServer builds a dashboard server for the account
+func NewServer(c *client.Client, account client.Account, host string) (*Server, error) {
+	token := make([]byte, 16)
+	_, err := rand.Read(token)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to generate the form token")
+	}
+
+	return &Server{Client: c, Account: account, Host: host, token: hex.EncodeToString(token)}, nil
+}
+
+// IsLoopback returns if the listen address only accepts connections from the local machine
+func IsLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ServeHTTP routes the dashboard requests
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !server.allowedHost(r.Host) {
+		http.Error(w, "Invalid host", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodPost && !server.validToken(r.PostFormValue("token")) {
+		http.Error(w, "Invalid form token, reload the page and try again", http.StatusForbidden)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/" && r.Method == http.MethodGet:
+		server.showDashboard(w, r)
+	case r.URL.Path == "/clusters" && r.Method == http.MethodPost:
+		server.createCluster(w, r)
+	case strings.HasPrefix(r.URL.Path, "/clusters/") && strings.HasSuffix(r.URL.Path, "/delete") && r.Method == http.MethodPost:
+		server.deleteCluster(w, r, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/clusters/"), "/delete"))
+	case strings.HasPrefix(r.URL.Path, "/clusters/") && strings.HasSuffix(r.URL.Path, "/credentials.zip") && r.Method == http.MethodGet:
+		server.downloadCredentials(w, r, strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/clusters/"), "/credentials.zip"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// allowedHost checks the Host header, so that another site can't reach the dashboard by rebinding its DNS to this machine.
+// When listening on all interfaces, the server can be reached by any name and every host is allowed.
+func (server *Server) allowedHost(host string) bool {
+	if strings.EqualFold(host, server.Host) {
+		return true
+	}
+
+	listenHost, listenPort, err := net.SplitHostPort(server.Host)
+	if err != nil {
+		return false
+	}
+	if ip := net.ParseIP(listenHost); listenHost == "" || (ip != nil && ip.IsUnspecified()) {
+		return true
+	}
+	if IsLoopback(server.Host) {
+		requestHost, requestPort, err := net.SplitHostPort(host)
+		return err == nil && requestPort == listenPort && IsLoopback(net.JoinHostPort(requestHost, requestPort))
+	}
+	return false
+}
+
+func (server *Server) validToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(server.token)) == 1
+}
+
+type dashboardCluster struct {
+	Name     string
+	Status   string
+	Template string
+	Nodes    string
+}
+
+type dashboardData struct {
+	Token     string
+	Clusters  []dashboardCluster
+	Templates []string
+	Message   string
+	Error     string
+}
+
+func (server *Server) showDashboard(w http.ResponseWriter, r *http.Request) {
+	data := dashboardData{
+		Token:   server.token,
+		Message: r.URL.Query().Get("message"),
+		Error:   r.URL.Query().Get("error"),
+	}
+
+	server.lock.Lock()
+	clusters, err := server.Client.ListClusters(server.Account, client.ListClustersOptions{})
+	if err == nil {
+		var templates []common.ClusterTemplate
+		templates, err = server.Client.ListClusterTemplates(server.Account, client.ListTemplatesOptions{})
+		for _, template := range templates {
+			data.Templates = append(data.Templates, template.GetName())
+		}
+	}
+	server.lock.Unlock()
+
+	if err != nil {
+		data.Error = err.Error()
+	}
+
+	for _, cluster := range clusters {
+		data.Clusters = append(data.Clusters, dashboardCluster{
+			Name:     cluster.GetName(),
+			Status:   cluster.GetStatus(),
+			Template: cluster.GetTemplate().GetName(),
+			Nodes:    cluster.GetNodes(),
+		})
+	}
+	sort.Strings(data.Templates)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = dashboardTemplate.Execute(w, data)
+	if err != nil {
+		common.Log.WriteDebug("Unable to render the dashboard: %s", err)
+	}
+}
+
+func (server *Server) createCluster(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.PostFormValue("name"))
+	template := r.PostFormValue("template")
+	nodes, err := strconv.Atoi(r.PostFormValue("nodes"))
+	if err != nil || nodes < 1 {
+		redirect(w, r, "", "Nodes must be >= 1")
+		return
+	}
+	if name == "" {
+		redirect(w, r, "", "A cluster name is required")
+		return
+	}
+
+	server.lock.Lock()
+	_, err = server.Client.CreateCluster(server.Account, name, template, nodes, client.CreateClusterOptions{})
+	server.lock.Unlock()
+
+	if err != nil {
+		redirect(w, r, "", err.Error())
+		return
+	}
+	redirect(w, r, fmt.Sprintf("Creating cluster %s", name), "")
+}
+
+func (server *Server) deleteCluster(w http.ResponseWriter, r *http.Request, name string) {
+	// Deleting is destructive, so like carina delete, it must be confirmed by typing the cluster name
+	if r.PostFormValue("confirm") != name {
+		redirect(w, r, "", server.describeDeleteConfirmation(name))
+		return
+	}
+
+	server.lock.Lock()
+	err := server.Client.DeleteCluster(server.Account, name, false)
+	server.lock.Unlock()
+
+	if err != nil {
+		redirect(w, r, "", err.Error())
+		return
+	}
+	redirect(w, r, fmt.Sprintf("Deleting cluster %s", name), "")
+}
+
+// describeDeleteConfirmation explains how to confirm deleting a cluster, including the confirm rule which applies to it
+func (server *Server) describeDeleteConfirmation(name string) string {
+	message := fmt.Sprintf("Type %s to confirm deleting the cluster", name)
+	if !server.ConfirmationPolicy.AppliesTo("delete") {
+		return message
+	}
+
+	server.lock.Lock()
+	cluster, err := server.Client.GetCluster(server.Account, name, false)
+	server.lock.Unlock()
+	if err != nil {
+		return message
+	}
+
+	if rule, ok := server.ConfirmationPolicy.Find("delete", cluster, 0); ok {
+		message = fmt.Sprintf("Confirmation is required to %s. %s", rule, message)
+	}
+	return message
+}
+
+func (server *Server) downloadCredentials(w http.ResponseWriter, r *http.Request, name string) {
+	// Build the whole archive before responding, so that an error isn't appended to a partial download
+	var archive bytes.Buffer
+	server.lock.Lock()
+	err := server.Client.WriteClusterCredentialsArchive(server.Account, name, "", &archive)
+	server.lock.Unlock()
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".zip"))
+	_, err = archive.WriteTo(w)
+	if err != nil {
+		common.Log.WriteDebug("Unable to send the credentials archive: %s", err)
+	}
+}
+
+// redirect returns to the dashboard after a form is submitted, displaying the result
+func redirect(w http.ResponseWriter, r *http.Request, message string, errorMessage string) {
+	query := url.Values{}
+	if message != "" {
+		query.Set("message", message)
+	}
+	if errorMessage != "" {
+		query.Set("error", errorMessage)
+	}
+
+	location := "/"
+	if len(query) > 0 {
+		location += "?" + query.Encode()
+	}
+	http.Redirect(w, r, location, http.StatusSeeOther)
+}
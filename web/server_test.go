@@ -0,0 +1,159 @@
+package web
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/getcarina/carina/client"
+	"github.com/getcarina/carina/internal/testhelpers"
+	"github.com/getcarina/carina/testsupport"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) (*Server, *testsupport.FakeClusterService) {
+	service := testsupport.NewFakeClusterService(&testsupport.FakeClusterTemplate{Name: "Kubernetes 1.5.2 on LXC", COE: "kubernetes", HostType: "lxc"})
+	account := new(testhelpers.MockAccount)
+	account.On("NewClusterService").Return(service)
+
+	server, err := NewServer(client.NewClient(false), account, "localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, service
+}
+
+func postForm(server *Server, path string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "http://localhost:8080"+path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	return w
+}
+
+func TestDashboardListsClusters(t *testing.T) {
+	server, service := newTestServer(t)
+	_, err := service.CreateCluster("mycluster", "Kubernetes*", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:8080/", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "mycluster")
+	assert.Contains(t, w.Body.String(), "Kubernetes 1.5.2 on LXC")
+}
+
+func TestCreateClusterRequiresToken(t *testing.T) {
+	server, service := newTestServer(t)
+
+	w := postForm(server, "/clusters", url.Values{"name": {"mycluster"}, "template": {"Kubernetes 1.5.2 on LXC"}, "nodes": {"1"}})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	clusters, _ := service.ListClusters()
+	assert.Empty(t, clusters)
+}
+
+func TestCreateCluster(t *testing.T) {
+	server, service := newTestServer(t)
+
+	w := postForm(server, "/clusters", url.Values{"token": {server.token}, "name": {"mycluster"}, "template": {"Kubernetes 1.5.2 on LXC"}, "nodes": {"2"}})
+
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	clusters, _ := service.ListClusters()
+	if assert.Len(t, clusters, 1) {
+		assert.Equal(t, "mycluster", clusters[0].GetName())
+		assert.Equal(t, "2", clusters[0].GetNodes())
+	}
+}
+
+func TestRejectsUnknownHost(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://attacker.example.com:8080/", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAllowsLoopbackAliases(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	assert.True(t, server.allowedHost("127.0.0.1:8080"))
+	assert.False(t, server.allowedHost("127.0.0.1:9090"))
+}
+
+func TestDeleteClusterRequiresConfirmation(t *testing.T) {
+	server, service := newTestServer(t)
+	server.ConfirmationPolicy = client.ConfirmationPolicy{{Operation: "delete", Labels: map[string]string{"env": "prod"}}}
+	service.AddCluster(testsupport.FakeCluster{Name: "mycluster", Template: service.Templates[0], Nodes: 1, Labels: map[string]string{"env": "prod"}})
+
+	w := postForm(server, "/clusters/mycluster/delete", url.Values{"token": {server.token}})
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	location, _ := url.Parse(w.Header().Get("Location"))
+	assert.Equal(t, "Confirmation is required to delete on clusters labeled env=prod. Type mycluster to confirm deleting the cluster", location.Query().Get("error"))
+
+	w = postForm(server, "/clusters/mycluster/delete", url.Values{"token": {server.token}, "confirm": {"othercluster"}})
+	assert.NotEmpty(t, w.Header().Get("Location"))
+	cluster, err := service.GetCluster("mycluster")
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, testsupport.StatusDeleting, cluster.GetStatus(), "The cluster shouldn't be deleted without confirmation")
+	}
+
+	w = postForm(server, "/clusters/mycluster/delete", url.Values{"token": {server.token}, "confirm": {"mycluster"}})
+	location, _ = url.Parse(w.Header().Get("Location"))
+	assert.Equal(t, "Deleting cluster mycluster", location.Query().Get("message"))
+	cluster, err = service.GetCluster("mycluster")
+	if assert.NoError(t, err) {
+		assert.Equal(t, testsupport.StatusDeleting, cluster.GetStatus())
+	}
+}
+
+func TestDownloadCredentialsError(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:8080/clusters/missing/credentials.zip", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Disposition"))
+	assert.NotEqual(t, "application/zip", w.Header().Get("Content-Type"))
+}
+
+func TestDownloadCredentials(t *testing.T) {
+	home, err := ioutil.TempDir("", "carina-web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv(client.CarinaHomeDirEnvVar, home)
+	defer os.Unsetenv(client.CarinaHomeDirEnvVar)
+
+	server, service := newTestServer(t)
+	_, err = service.CreateCluster("mycluster", "Kubernetes*", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = server.Client.DownloadClusterCredentials(server.Account, "mycluster", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:8080/clusters/mycluster/credentials.zip", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if assert.NoError(t, err) {
+		assert.NotEmpty(t, archive.File)
+	}
+}
@@ -0,0 +1,51 @@
+package web
+
+import "html/template"
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Carina</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.4em 0.8em; text-align: left; }
+.message { color: #060; }
+.error { color: #a00; }
+form.inline { display: inline; }
+</style>
+</head>
+<body>
+<h1>Clusters</h1>
+{{if .Message}}<p class="message">{{.Message}}</p>{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<table>
+<tr><th>Name</th><th>Template</th><th>Nodes</th><th>Status</th><th></th></tr>
+{{range .Clusters}}
+<tr>
+<td>{{.Name}}</td><td>{{.Template}}</td><td>{{.Nodes}}</td><td>{{.Status}}</td>
+<td>
+<a href="/clusters/{{.Name}}/credentials.zip">Credentials</a>
+<form class="inline" method="post" action="/clusters/{{.Name}}/delete">
+<input type="hidden" name="token" value="{{$.Token}}">
+<input name="confirm" placeholder="Type {{.Name}} to confirm" required>
+<button type="submit">Delete</button>
+</form>
+</td>
+</tr>
+{{else}}
+<tr><td colspan="5">No clusters</td></tr>
+{{end}}
+</table>
+<h2>Create a cluster</h2>
+<form method="post" action="/clusters">
+<input type="hidden" name="token" value="{{.Token}}">
+<label>Name <input name="name" required></label>
+<label>Template <select name="template">{{range .Templates}}<option>{{.}}</option>{{end}}</select></label>
+<label>Nodes <input name="nodes" type="number" min="1" value="1"></label>
+<button type="submit">Create</button>
+</form>
+</body>
+</html>
+`))